@@ -10,17 +10,24 @@ import (
 	"ci-cd-orchestrator/internal/repository"
 )
 
-// SetupRouter 设置路由
-func SetupRouter(dbConn *sql.DB) http.Handler {
-	// 创建路由器
-	mux := http.NewServeMux()
-
-	// 初始化执行管理器和引擎
-	executionManager := execution.NewManager()
+// NewExecutionManager 初始化执行管理器和引擎，执行记录持久化到数据库
+func NewExecutionManager(dbConn *sql.DB) execution.Manager {
+	executionManager := execution.NewManager(repository.NewExecutionRepository(dbConn), repository.NewMetricRepository(dbConn))
 	mockEngine := execution.NewMockEngine()
 	githubEngine := execution.NewGitHubActionsEngine()
+	gitlabEngine := execution.NewGitLabCIEngine()
+	localEngine := execution.NewLocalEngine()
 	executionManager.RegisterEngine("mock", mockEngine)
 	executionManager.RegisterEngine("github_actions", githubEngine)
+	executionManager.RegisterEngine("gitlab_ci", gitlabEngine)
+	executionManager.RegisterEngine("local", localEngine)
+	return executionManager
+}
+
+// SetupRouter 设置路由，API 与定时任务等后台任务共用同一个执行管理器
+func SetupRouter(dbConn *sql.DB, executionManager execution.Manager) http.Handler {
+	// 创建路由器
+	mux := http.NewServeMux()
 
 	// 初始化仓库
 	templateRepo := repository.NewTemplateRepository(dbConn)
@@ -33,6 +40,9 @@ func SetupRouter(dbConn *sql.DB) http.Handler {
 	executionHandler := handlers.NewExecutionHandler(executionManager)
 	metricHandler := handlers.NewMetricHandler()
 	optimizationHandler := handlers.NewOptimizationHandler(executionManager)
+	securityHandler := handlers.NewSecurityHandler()
+	webhookHandler := handlers.NewWebhookHandler(executionManager)
+	scheduleHandler := handlers.NewScheduleHandler()
 
 	// API 版本前缀
 	apiPrefix := "/api/v1"
@@ -64,6 +74,39 @@ func SetupRouter(dbConn *sql.DB) http.Handler {
 		"GET": pipelineHandler.GetPipeline,
 		"PUT": pipelineHandler.UpdatePipeline,
 	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/revisions", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": pipelineHandler.ListPipelineRevisions,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/revisions/{revision}", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": pipelineHandler.GetPipelineRevision,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/revisions/{revision}/rollback", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": pipelineHandler.RollbackPipeline,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/drift", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": pipelineHandler.CheckPipelineDrift,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/drift/resolve", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": pipelineHandler.ResolvePipelineDrift,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/triggers/check", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": pipelineHandler.CheckPipelineTrigger,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/pipeline/diff", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": pipelineHandler.DiffPipelineRevisions,
+	}))
+
+	// 安全检查路由
+	mux.HandleFunc(apiPrefix+"/projects/{id}/security/findings", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": securityHandler.ListFindings,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/security/rules", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": securityHandler.ListRules,
+		"PUT": securityHandler.UpdateRules,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/security/lint", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": securityHandler.LintPipelines,
+	}))
 
 	// 执行历史路由
 	mux.HandleFunc(apiPrefix+"/projects/{id}/execute", middleware.MethodHandler(map[string]http.HandlerFunc{
@@ -72,18 +115,55 @@ func SetupRouter(dbConn *sql.DB) http.Handler {
 	mux.HandleFunc(apiPrefix+"/projects/{id}/executions", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"GET": executionHandler.ListExecutions,
 	}))
+	mux.HandleFunc(apiPrefix+"/executions/queue", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": executionHandler.GetExecutionQueue,
+	}))
 	mux.HandleFunc(apiPrefix+"/executions/{id}", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"GET": executionHandler.GetExecution,
 	}))
 	mux.HandleFunc(apiPrefix+"/executions/{id}/stop", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"POST": executionHandler.StopExecution,
 	}))
+	mux.HandleFunc(apiPrefix+"/executions/{id}/rerun", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": executionHandler.RerunExecution,
+	}))
 	mux.HandleFunc(apiPrefix+"/executions/{id}/metrics", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"GET": executionHandler.GetExecutionMetrics,
 	}))
 	mux.HandleFunc(apiPrefix+"/executions/{id}/logs", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"GET": executionHandler.GetExecutionLogs,
 	}))
+	mux.HandleFunc(apiPrefix+"/executions/{id}/logs/stream", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": executionHandler.StreamExecutionLogs,
+	}))
+	mux.HandleFunc(apiPrefix+"/executions/{id}/logs/ws", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": executionHandler.StreamExecutionLogsWebSocket,
+	}))
+
+	// 定时任务路由
+	mux.HandleFunc(apiPrefix+"/projects/{id}/schedules", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET":  scheduleHandler.ListSchedules,
+		"POST": scheduleHandler.CreateSchedule,
+	}))
+	mux.HandleFunc(apiPrefix+"/projects/{id}/schedules/{scheduleId}", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET":    scheduleHandler.GetSchedule,
+		"PUT":    scheduleHandler.UpdateSchedule,
+		"DELETE": scheduleHandler.DeleteSchedule,
+	}))
+
+	// webhook 路由
+	mux.HandleFunc(apiPrefix+"/webhooks/{provider}", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": webhookHandler.ReceiveWebhook,
+	}))
+	mux.HandleFunc(apiPrefix+"/webhooks/deliveries", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": webhookHandler.ListDeliveries,
+	}))
+	mux.HandleFunc(apiPrefix+"/webhooks/deliveries/{id}", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": webhookHandler.GetDelivery,
+	}))
+	mux.HandleFunc(apiPrefix+"/webhooks/deliveries/{id}/redeliver", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": webhookHandler.RedeliverDelivery,
+	}))
 
 	// 指标路由
 	mux.HandleFunc(apiPrefix+"/projects/{id}/metrics", middleware.MethodHandler(map[string]http.HandlerFunc{
@@ -106,6 +186,12 @@ func SetupRouter(dbConn *sql.DB) http.Handler {
 		"GET":  templateHandler.ListTemplates,
 		"POST": templateHandler.CreateTemplate,
 	}))
+	mux.HandleFunc(apiPrefix+"/templates/export", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": templateHandler.ExportTemplates,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/import", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": templateHandler.ImportTemplates,
+	}))
 	mux.HandleFunc(apiPrefix+"/templates/{id}", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"GET":    templateHandler.GetTemplate,
 		"PUT":    templateHandler.UpdateTemplate,
@@ -114,6 +200,27 @@ func SetupRouter(dbConn *sql.DB) http.Handler {
 	mux.HandleFunc(apiPrefix+"/templates/{id}/reset", middleware.MethodHandler(map[string]http.HandlerFunc{
 		"POST": templateHandler.ResetTemplate,
 	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/render", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": templateHandler.RenderTemplate,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/versions", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": templateHandler.ListTemplateVersions,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/versions/{version}", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": templateHandler.GetTemplateVersion,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/versions/{version}/rollback", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"POST": templateHandler.RollbackTemplate,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/diff", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": templateHandler.DiffTemplateVersions,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/outdated", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": templateHandler.ListOutdatedPipelines,
+	}))
+	mux.HandleFunc(apiPrefix+"/templates/{id}/dependents", middleware.MethodHandler(map[string]http.HandlerFunc{
+		"GET": templateHandler.ListTemplateDependents,
+	}))
 
 	return mux
 }
@@ -1,26 +1,58 @@
 package handlers
 
 import (
+	"ci-cd-orchestrator/internal/db"
 	"ci-cd-orchestrator/internal/execution"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/trigger"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 )
 
 // ExecutionHandler 执行处理器
 type ExecutionHandler struct {
-	manager execution.Manager
+	manager        execution.Manager
+	projectRepo    *repository.ProjectRepository
+	pipelineRepo   *repository.PipelineRepository
+	allowedOrigins []string // 除同源外允许建立 WebSocket 连接的来源
 }
 
-// NewExecutionHandler 创建执行处理器实例
+// NewExecutionHandler 创建执行处理器实例，允许建立 WebSocket 连接的其他来源从环境变量
+// WEBSOCKET_ALLOWED_ORIGINS 读取，多个来源以逗号分隔
 func NewExecutionHandler(manager execution.Manager) *ExecutionHandler {
+	var allowedOrigins []string
+	for _, origin := range strings.Split(os.Getenv("WEBSOCKET_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			allowedOrigins = append(allowedOrigins, origin)
+		}
+	}
+
 	return &ExecutionHandler{
-		manager: manager,
+		manager:        manager,
+		projectRepo:    repository.NewProjectRepository(db.GetDB()),
+		pipelineRepo:   repository.NewPipelineRepository(db.GetDB()),
+		allowedOrigins: allowedOrigins,
 	}
 }
 
-// ExecutePipeline 执行管道
+// executeRequest 执行管道的请求体，所有字段可选。结果、失败阶段和时长只对模拟执行有效
+type executeRequest struct {
+	Revision       int            `json:"revision"`        // 执行已保存管道配置的指定修订
+	Result         string         `json:"result"`          // 模拟执行的结果，success 或 failed
+	FailureStage   string         `json:"failure_stage"`   // 模拟失败的 job 或矩阵 job 名称
+	FailureReason  string         `json:"failure_reason"`  // 模拟失败的原因
+	TotalDuration  int            `json:"total_duration"`  // 模拟执行的总时长（秒），平均分配给每个 job
+	StageDurations map[string]int `json:"stage_durations"` // 每个 job 的模拟时长（秒）
+	Priority       string         `json:"priority"`        // 排队的优先级类别，默认为 default
+}
+
+// ExecutePipeline 执行管道，请求体可选，格式见 executeRequest
 func (h *ExecutionHandler) ExecutePipeline(w http.ResponseWriter, r *http.Request) {
 	// 从请求中获取平台参数，默认为 GitHub Actions
 	platform := r.URL.Query().Get("platform")
@@ -40,48 +72,131 @@ func (h *ExecutionHandler) ExecutePipeline(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	// 读取 CI 配置文件内容
-	ciConfigContent := ""
-	if platform == "mock" {
-		// 这里可以从项目目录中读取 CI 配置文件
-		// 简化实现，直接使用默认的 Go 项目 CI 配置
-		ciConfigContent = `name: CI
-
-on:
-  push:
-    branches: [ main ]
-  pull_request:
-    branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
-    # 资源配置
-    resources:
-      limits:
-        cpu: 2
-        memory: 4G
-      requests:
-        cpu: 1
-        memory: 2G
-    steps:
-    - uses: actions/checkout@v2
-    - name: Set up Go
-      uses: actions/setup-go@v2
-      with:
-        go-version: 1.20
-    - name: Build
-      run: go build -v ./...
-    - name: Test
-      run: go test -v ./...
-`
+	var req executeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+	if req.Result != "" && req.Result != execution.StatusSuccess && req.Result != execution.StatusFailed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的执行结果，可选值为 success 和 failed"}`))
+		return
+	}
+	if req.Priority != "" && !execution.ValidPriority(req.Priority) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的优先级，可选值为 release、pull_request、default 和 scheduled"}`))
+		return
+	}
+	negative := req.Revision < 0 || req.TotalDuration < 0
+	for _, duration := range req.StageDurations {
+		negative = negative || duration < 0
+	}
+	if negative {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"修订号和时长不能为负数"}`))
+		return
+	}
+
+	id, err := strconv.Atoi(projectID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目ID"}`))
+		return
+	}
+
+	project, err := h.projectRepo.GetByID(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取项目信息失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 本地执行需要项目路径作为工作目录，远程平台需要项目的仓库地址
+	workDir := ""
+	repoPath := ""
+	switch platform {
+	case "local":
+		if project.Path == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"项目路径为空"}`))
+			return
+		}
+		workDir = project.Path
+	case "github_actions", "gitlab_ci":
+		if req.Revision != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"远程平台运行仓库中的 workflow，不能指定修订"}`))
+			return
+		}
+		repoPath, err = execution.RepositoryPath(project.RepositoryURL)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的仓库地址: ` + err.Error() + `"}`))
+			return
+		}
+	}
+
+	// 确定执行使用的 CI 配置并记录对应的管道配置修订
+	config, err := trigger.ResolveConfig(h.pipelineRepo, project, platform, req.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"管道配置修订不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+	if platform == "local" && config.Content == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"没有已保存的管道配置，项目目录中也没有 CI 配置文件"}`))
+		return
+	}
+	// 从查询参数中获取最大并行 job 数，0 表示不限制
+	maxParallel := 0
+	if value := r.URL.Query().Get("max_parallel"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的最大并行数"}`))
+			return
+		}
+		maxParallel = parsed
 	}
 
 	// 创建执行
 	executionID, err := h.manager.CreateExecution(projectID, platform, "manual", execution.ExecutionOptions{
-		TotalDuration:   10,
-		GenerateMetrics: true,
-		GenerateLogs:    true,
-		CIConfigContent: ciConfigContent,
+		TotalDuration:    req.TotalDuration,
+		StageDurations:   req.StageDurations,
+		Result:           req.Result,
+		FailureStage:     req.FailureStage,
+		FailureReason:    req.FailureReason,
+		GenerateMetrics:  true,
+		GenerateLogs:     true,
+		CIConfigContent:  config.Content,
+		WorkDir:          workDir,
+		MaxParallel:      maxParallel,
+		Repository:       repoPath,
+		Workflow:         r.URL.Query().Get("workflow"),
+		Ref:              r.URL.Query().Get("ref"),
+		PipelineID:       config.PipelineID(),
+		PipelineRevision: config.Revision,
+		Priority:         req.Priority,
 	})
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -90,7 +205,7 @@ jobs:
 		return
 	}
 
-	// 启动执行
+	// 启动执行，并发数达到限制时执行在队列中等待
 	if err := h.manager.StartExecution(executionID); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -98,17 +213,28 @@ jobs:
 		return
 	}
 
+	result := map[string]interface{}{
+		"execution_id":      executionID,
+		"platform":          platform,
+		"status":            execution.StatusRunning,
+		"config_source":     config.Source,
+		"pipeline_revision": config.Revision,
+	}
+	if current, err := h.manager.GetExecution(executionID); err == nil {
+		result["status"] = current.Status
+		result["priority"] = current.Priority
+		if current.Queue != nil {
+			result["queue"] = current.Queue
+		}
+	}
+
 	// 返回成功响应
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	response := map[string]interface{}{
-		"status": "success",
-		"data": map[string]interface{}{
-			"execution_id": executionID,
-			"platform":     platform,
-			"status":       "running",
-		},
+		"status":  "success",
+		"data":    result,
 		"message": "执行管道成功",
 	}
 
@@ -116,6 +242,22 @@ jobs:
 	w.Write(data)
 }
 
+// GetExecutionQueue 获取执行队列的并发限制、正在运行的执行数量，以及排队中的执行的位置和预计启动时间
+func (h *ExecutionHandler) GetExecutionQueue(w http.ResponseWriter, r *http.Request) {
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    h.manager.GetQueue(),
+		"message": "获取执行队列成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
 // GetExecution 获取执行详情
 func (h *ExecutionHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
 	// 从路径参数中获取执行 ID
@@ -174,6 +316,91 @@ func (h *ExecutionHandler) StopExecution(w http.ResponseWriter, r *http.Request)
 	w.Write(data)
 }
 
+// RerunExecution 重新运行已结束的执行，请求体可选，格式见 execution.RerunOptions。
+// 新执行使用相同的管道配置修订和触发信息，作为同一逻辑运行的下一次尝试
+func (h *ExecutionHandler) RerunExecution(w http.ResponseWriter, r *http.Request) {
+	// 从路径参数中获取执行 ID
+	executionID := r.URL.Path[len("/api/v1/executions/") : len("/api/v1/executions/")+36]
+
+	var options execution.RerunOptions
+	if err := json.NewDecoder(r.Body).Decode(&options); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+	if options.Mode != "" && !execution.ValidRerunMode(options.Mode) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的重新运行模式，可选值为 all、failed 和 job"}`))
+		return
+	}
+	if options.Mode == execution.RerunJob && options.Job == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"重新运行单个 job 时需要指定 job"}`))
+		return
+	}
+
+	if _, err := h.manager.GetExecution(executionID); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"执行不存在"}`))
+		return
+	}
+
+	// 创建重新运行的执行，执行未结束、没有失败的 job 或 job 不存在时返回错误
+	rerunID, err := h.manager.RerunExecution(executionID, options)
+	if errors.Is(err, execution.ErrRerunUnsupported) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"status":"error","data":null,"message":"执行的平台不支持该重新运行模式: ` + err.Error() + `"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"重新运行执行失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 启动执行，并发数达到限制时执行在队列中等待
+	if err := h.manager.StartExecution(rerunID); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"启动执行失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	result := map[string]interface{}{
+		"execution_id": rerunID,
+		"rerun_of":     executionID,
+		"status":       execution.StatusRunning,
+	}
+	if current, err := h.manager.GetExecution(rerunID); err == nil {
+		result["status"] = current.Status
+		result["run_id"] = current.RunID
+		result["attempt"] = current.Attempt
+		result["debug"] = current.Debug
+		if current.Queue != nil {
+			result["queue"] = current.Queue
+		}
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    result,
+		"message": "重新运行执行成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
 // ListExecutions 获取执行历史
 func (h *ExecutionHandler) ListExecutions(w http.ResponseWriter, r *http.Request) {
 	// 从路径参数中获取项目 ID
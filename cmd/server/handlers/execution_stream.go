@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ci-cd-orchestrator/internal/execution"
+	"ci-cd-orchestrator/pkg/websocket"
+)
+
+// streamHeartbeatInterval 推送流的心跳间隔，避免空闲连接被代理断开
+const streamHeartbeatInterval = 15 * time.Second
+
+// eventEnd 执行结束后推送的最后一个事件
+const eventEnd = "end"
+
+// StreamExecutionLogs 通过 Server-Sent Events 推送执行日志和状态变化。
+// 支持通过 Last-Event-ID 请求头或 last_id 查询参数从指定日志之后续传
+func (h *ExecutionHandler) StreamExecutionLogs(w http.ResponseWriter, r *http.Request) {
+	// 从路径参数中获取执行 ID
+	executionID := r.PathValue("id")
+
+	lastLogID := r.Header.Get("Last-Event-ID")
+	if lastLogID == "" {
+		lastLogID = r.URL.Query().Get("last_id")
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"服务器不支持流式响应"}`))
+		return
+	}
+
+	// 订阅执行事件
+	subscription, err := h.manager.SubscribeExecution(executionID, lastLogID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"执行不存在"}`))
+		return
+	}
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event execution.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		// 只有日志事件带 ID，客户端重连时浏览器会自动带上最后一条日志的 ID
+		if event.Type == execution.EventLog {
+			if _, err := fmt.Fprintf(w, "id: %s\n", event.Log.ID); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	finished := streamExecution(r, subscription, writeEvent, func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+
+	// 执行结束时通知客户端不再重连
+	if finished {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventEnd)
+		flusher.Flush()
+	}
+}
+
+// StreamExecutionLogsWebSocket 通过 WebSocket 推送执行日志和状态变化，
+// 每条消息为一个 JSON 编码的执行事件，支持 last_id 查询参数续传。
+// 只接受与服务同源或在 WEBSOCKET_ALLOWED_ORIGINS 中配置的来源的浏览器连接
+func (h *ExecutionHandler) StreamExecutionLogsWebSocket(w http.ResponseWriter, r *http.Request) {
+	// 从路径参数中获取执行 ID
+	executionID := r.PathValue("id")
+	lastLogID := r.URL.Query().Get("last_id")
+
+	if !websocket.IsUpgradeRequest(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"需要 WebSocket 升级请求"}`))
+		return
+	}
+
+	// 订阅执行事件，握手前订阅以便返回错误响应
+	subscription, err := h.manager.SubscribeExecution(executionID, lastLogID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"执行不存在"}`))
+		return
+	}
+	defer subscription.Close()
+
+	conn, err := websocket.Upgrade(w, r, h.allowedOrigins)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// 读取客户端消息以处理 ping 和 close 帧，连接断开时取消订阅
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				subscription.Close()
+				return
+			}
+		}
+	}()
+
+	writeEvent := func(event execution.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return conn.WriteText(data)
+	}
+
+	if streamExecution(r, subscription, writeEvent, conn.Ping) {
+		conn.WriteText([]byte(`{"type":"` + eventEnd + `"}`))
+	}
+}
+
+// streamExecution 依次推送执行快照、已有日志和后续事件，直到执行结束、客户端断开或订阅被断开。
+// 返回执行是否已经结束
+func streamExecution(r *http.Request, subscription *execution.Subscription, writeEvent func(execution.Event) error, heartbeat func() error) bool {
+	status := subscription.Execution.Status
+
+	if err := writeEvent(execution.Event{Type: execution.EventStatus, Execution: subscription.Execution}); err != nil {
+		return false
+	}
+	for i := range subscription.Backlog {
+		if err := writeEvent(execution.Event{Type: execution.EventLog, Log: &subscription.Backlog[i]}); err != nil {
+			return false
+		}
+	}
+
+	ticker := time.NewTicker(streamHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return false
+			}
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// 订阅因消费过慢被断开时执行仍未结束，客户端可通过最后一条日志 ID 续传
+				return execution.IsFinished(status)
+			}
+			if event.Type == execution.EventStatus {
+				status = event.Execution.Status
+			}
+			if err := writeEvent(event); err != nil {
+				return false
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ci-cd-orchestrator/internal/execution"
+)
+
+// newStreamServer 创建带有一个已结束执行的测试服务器，返回执行 ID
+func newStreamServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	t.Setenv("WEBSOCKET_ALLOWED_ORIGINS", "https://dashboard.example.com, ")
+
+	manager := execution.NewManager(nil, nil)
+	manager.RegisterEngine("local", execution.NewLocalEngine())
+	executionID, err := manager.CreateExecution("1", "local", "manual", execution.ExecutionOptions{
+		CIConfigContent: "jobs:\n  build:\n    steps:\n    - run: echo hello\n",
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		current, err := manager.GetExecution(executionID)
+		if err != nil {
+			t.Fatalf("获取执行失败: %v", err)
+		}
+		if execution.IsFinished(current.Status) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("等待执行结束超时")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	handler := NewExecutionHandler(manager)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/executions/{id}/logs/stream", handler.StreamExecutionLogs)
+	mux.HandleFunc("GET /api/v1/executions/{id}/logs/ws", handler.StreamExecutionLogsWebSocket)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, executionID
+}
+
+func TestStreamExecutionLogs(t *testing.T) {
+	server, executionID := newStreamServer(t)
+
+	resp, err := http.Get(server.URL + "/api/v1/executions/" + executionID + "/logs/stream")
+	if err != nil {
+		t.Fatalf("请求日志流失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("日志流响应不匹配: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("读取日志流失败: %v", err)
+	}
+	stream := string(body)
+	if !strings.HasPrefix(stream, "event: status\n") || !strings.Contains(stream, `"message":"hello"`) || !strings.HasSuffix(stream, "event: end\ndata: {}\n\n") {
+		t.Errorf("日志流内容不匹配: %s", stream)
+	}
+	if !strings.Contains(stream, "id: ") {
+		t.Errorf("日志事件应带 id 字段: %s", stream)
+	}
+
+	// 执行 ID 长度不足或不存在时返回 404
+	for _, id := range []string{"abc", strings.Repeat("0", 36)} {
+		resp, err := http.Get(server.URL + "/api/v1/executions/" + id + "/logs/stream")
+		if err != nil {
+			t.Fatalf("请求日志流失败: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("执行 %s 不存在时应返回 404: %d", id, resp.StatusCode)
+		}
+	}
+}
+
+// dialWebSocket 向测试服务器发起 WebSocket 握手
+func dialWebSocket(t *testing.T, server *httptest.Server, path, origin string) (*bufio.Reader, *http.Response) {
+	t.Helper()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET " + path + " HTTP/1.1\r\nHost: " + host + "\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nOrigin: " + origin + "\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("发送握手请求失败: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("读取握手响应失败: %v", err)
+	}
+	return reader, resp
+}
+
+func TestStreamExecutionLogsWebSocket(t *testing.T) {
+	server, executionID := newStreamServer(t)
+	path := "/api/v1/executions/" + executionID + "/logs/ws"
+
+	for _, origin := range []string{server.URL, "https://dashboard.example.com"} {
+		reader, resp := dialWebSocket(t, server, path, origin)
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("来源 %s 的握手状态码不匹配: %d", origin, resp.StatusCode)
+		}
+
+		// 依次读取文本消息直到 end 事件
+		var types []string
+		for {
+			var head [2]byte
+			if _, err := io.ReadFull(reader, head[:]); err != nil {
+				t.Fatalf("读取消息失败: %v", err)
+			}
+			length := int(head[1] & 0x7F)
+			if length == 126 {
+				var ext [2]byte
+				io.ReadFull(reader, ext[:])
+				length = int(binary.BigEndian.Uint16(ext[:]))
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				t.Fatalf("读取消息内容失败: %v", err)
+			}
+
+			var event struct {
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(payload, &event); err != nil {
+				t.Fatalf("解析消息失败: %v %s", err, payload)
+			}
+			types = append(types, event.Type)
+			if event.Type == "end" {
+				break
+			}
+		}
+		if types[0] != execution.EventStatus || len(types) < 3 {
+			t.Errorf("消息类型不匹配: %v", types)
+		}
+	}
+
+	// 跨站来源被拒绝
+	if _, resp := dialWebSocket(t, server, path, "https://evil.example.net"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("跨站来源应被拒绝: %d", resp.StatusCode)
+	}
+	// 执行不存在时返回 404
+	if _, resp := dialWebSocket(t, server, "/api/v1/executions/abc/logs/ws", server.URL); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("执行不存在时应返回 404: %d", resp.StatusCode)
+	}
+}
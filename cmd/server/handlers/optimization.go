@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 
+	"ci-cd-orchestrator/internal/cicd"
+	"ci-cd-orchestrator/internal/cicd/validator"
 	"ci-cd-orchestrator/internal/db"
 	"ci-cd-orchestrator/internal/execution"
 	"ci-cd-orchestrator/internal/models"
@@ -18,6 +20,9 @@ type OptimizationHandler struct {
 	optimizationRepo *repository.OptimizationRepository
 	executionRepo    *repository.ExecutionRepository
 	metricRepo       *repository.MetricRepository
+	pipelineRepo     *repository.PipelineRepository
+	findingRepo      *repository.SecurityFindingRepository
+	ruleRepo         *repository.SecurityRuleRepository
 	executionManager execution.Manager
 }
 
@@ -27,6 +32,9 @@ func NewOptimizationHandler(executionManager execution.Manager) *OptimizationHan
 		optimizationRepo: repository.NewOptimizationRepository(db.GetDB()),
 		executionRepo:    repository.NewExecutionRepository(db.GetDB()),
 		metricRepo:       repository.NewMetricRepository(db.GetDB()),
+		pipelineRepo:     repository.NewPipelineRepository(db.GetDB()),
+		findingRepo:      repository.NewSecurityFindingRepository(db.GetDB()),
+		ruleRepo:         repository.NewSecurityRuleRepository(db.GetDB()),
 		executionManager: executionManager,
 	}
 }
@@ -159,12 +167,13 @@ func (h *OptimizationHandler) ListOptimizationSuggestions(w http.ResponseWriter,
 	w.Write(data)
 }
 
-// ApplyOptimization 应用优化建议
+// ApplyOptimization 应用优化建议。请求体中提供 config 时，验证后将其保存为管道配置的新修订，
+// 来源记为 optimization，reason 默认为优化建议的描述；platform 默认为 github_actions
 func (h *OptimizationHandler) ApplyOptimization(w http.ResponseWriter, r *http.Request) {
 	// 从路径中获取项目ID
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/projects/")
 	idStr = strings.TrimSuffix(idStr, "/apply-optimization")
-	_, err := strconv.Atoi(idStr)
+	projectID, err := strconv.Atoi(idStr)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
@@ -174,7 +183,11 @@ func (h *OptimizationHandler) ApplyOptimization(w http.ResponseWriter, r *http.R
 
 	// 解析请求体
 	var request struct {
-		OptimizationID int `json:"optimization_id"`
+		OptimizationID int    `json:"optimization_id"`
+		Config         string `json:"config"`
+		Platform       string `json:"platform"`
+		Reason         string `json:"reason"`
+		Author         string `json:"author"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -183,6 +196,62 @@ func (h *OptimizationHandler) ApplyOptimization(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// 优化建议必须属于该项目
+	optimizations, err := h.optimizationRepo.GetByProjectID(projectID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取优化建议失败: ` + err.Error() + `"}`))
+		return
+	}
+	var optimization *models.Optimization
+	for _, item := range optimizations {
+		if item.ID == request.OptimizationID {
+			optimization = item
+		}
+	}
+	if optimization == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"优化建议不存在"}`))
+		return
+	}
+
+	// 保存应用优化后的管道配置
+	var pipeline *models.Pipeline
+	if request.Config != "" {
+		// 保留已保存配置的文件路径
+		config := cicd.PipelineConfig{Platform: cicd.Platform(request.Platform), Content: request.Config}
+		setPipelineDefaults(&config)
+		if existing, err := h.pipelineRepo.GetByProjectAndPlatform(projectID, string(config.Platform)); err == nil && existing.Filename != "" {
+			config.Filename = existing.Filename
+		}
+		if writeValidationError(w, "应用优化建议失败", validator.NewValidator().Validate(&config).Err()) {
+			return
+		}
+
+		change := repository.PipelineChange{Source: repository.PipelineSourceOptimization, Reason: request.Reason, Author: request.Author}
+		if change.Reason == "" {
+			change.Reason = optimization.Description
+		}
+		if change.Author == "" {
+			change.Author = "anonymous"
+		}
+		pipeline, err = savePipeline(h.pipelineRepo, projectID, &config, change)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","data":null,"message":"保存管道配置失败: ` + err.Error() + `"}`))
+			return
+		}
+		if _, err := lintPipeline(h.ruleRepo, h.findingRepo, pipeline); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","data":null,"message":"安全检查失败: ` + err.Error() + `"}`))
+			return
+		}
+	}
+
 	// 标记优化建议为已应用
 	if err := h.optimizationRepo.MarkAsApplied(request.OptimizationID); err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -191,13 +260,13 @@ func (h *OptimizationHandler) ApplyOptimization(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// 返回成功响应
+	// 返回成功响应，保存了管道配置时返回新的管道配置
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	response := map[string]interface{}{
 		"status":  "success",
-		"data":    nil,
+		"data":    pipeline,
 		"message": "应用优化建议成功",
 	}
 
@@ -353,6 +422,10 @@ func (h *OptimizationHandler) analyzeExecutions(executions []*execution.Executio
 		}
 	}
 
+	// 分析矩阵实例的耗时和稳定性
+	legSuggestions, _ := analyzeMatrixLegs(executions)
+	suggestions = append(suggestions, legSuggestions...)
+
 	// 总是生成一些优化建议，即使没有明显的问题
 	if len(suggestions) == 0 {
 		suggestions = append(suggestions, map[string]interface{}{
@@ -391,9 +464,9 @@ func (h *OptimizationHandler) calculateExecutionMetrics(executions []*execution.
 	metrics["success_rate"] = successRate
 	metrics["failure_rate"] = float64(failureCount) / float64(len(executions))
 
-	// 计算最近 5 次执行的趋势
+	// 计算最近 5 次执行的趋势（执行历史按开始时间倒序）
 	if len(executions) >= 5 {
-		recentExecutions := executions[len(executions)-5:]
+		recentExecutions := executions[:5]
 		recentDurations := make([]int64, len(recentExecutions))
 		recentCpuUsages := make([]float64, len(recentExecutions))
 		recentMemoryUsages := make([]float64, len(recentExecutions))
@@ -433,9 +506,90 @@ func (h *OptimizationHandler) calculateExecutionMetrics(executions []*execution.
 		metrics["recent_memory_usages"] = recentMemoryUsages
 	}
 
+	// 矩阵实例统计
+	if _, legStats := analyzeMatrixLegs(executions); len(legStats) > 0 {
+		metrics["matrix_legs"] = legStats
+	}
+
 	return metrics
 }
 
+// matrixLegStats 矩阵实例的历史统计
+type matrixLegStats struct {
+	Name            string                 `json:"name"`
+	Group           string                 `json:"group"`
+	Matrix          map[string]interface{} `json:"matrix"`
+	Runs            int                    `json:"runs"`
+	Failures        int                    `json:"failures"`
+	AverageDuration float64                `json:"average_duration"`
+	totalDuration   int64
+}
+
+// analyzeMatrixLegs 统计各矩阵实例的耗时和失败次数，找出明显慢于同组其他实例或时而成功时而失败的实例
+func analyzeMatrixLegs(executions []*execution.Execution) ([]map[string]interface{}, []*matrixLegStats) {
+	statsByName := make(map[string]*matrixLegStats)
+	var stats []*matrixLegStats
+
+	for _, exec := range executions {
+		for _, job := range exec.Jobs {
+			if job.Group == "" {
+				continue
+			}
+			// 只统计真正运行结束的实例
+			if job.Status != execution.StatusSuccess && job.Status != execution.StatusFailed {
+				continue
+			}
+
+			leg, exists := statsByName[job.Name]
+			if !exists {
+				leg = &matrixLegStats{Name: job.Name, Group: job.Group, Matrix: job.Matrix}
+				statsByName[job.Name] = leg
+				stats = append(stats, leg)
+			}
+			leg.Runs++
+			leg.totalDuration += job.Duration
+			if job.Status == execution.StatusFailed {
+				leg.Failures++
+			}
+		}
+	}
+
+	// 计算每组的平均耗时
+	groupTotal := make(map[string]float64)
+	groupLegs := make(map[string]int)
+	for _, leg := range stats {
+		leg.AverageDuration = float64(leg.totalDuration) / float64(leg.Runs)
+		groupTotal[leg.Group] += leg.AverageDuration
+		groupLegs[leg.Group]++
+	}
+
+	var suggestions []map[string]interface{}
+	for _, leg := range stats {
+		// 耗时超过同组平均值 1.5 倍的实例
+		if groupLegs[leg.Group] > 1 {
+			groupAverage := groupTotal[leg.Group] / float64(groupLegs[leg.Group])
+			if groupAverage > 0 && leg.AverageDuration > groupAverage*1.5 {
+				suggestions = append(suggestions, map[string]interface{}{
+					"type":        "performance",
+					"description": "矩阵实例耗时过长",
+					"suggestion":  fmt.Sprintf("矩阵实例 %s 平均耗时 %.2f 秒，明显高于 %s 各实例的平均耗时 %.2f 秒，考虑为该组合启用缓存或拆分任务", leg.Name, leg.AverageDuration, leg.Group, groupAverage),
+				})
+			}
+		}
+
+		// 多次运行中既有成功也有失败的实例
+		if leg.Runs >= 2 && leg.Failures > 0 && leg.Failures < leg.Runs {
+			suggestions = append(suggestions, map[string]interface{}{
+				"type":        "reliability",
+				"description": "矩阵实例不稳定",
+				"suggestion":  fmt.Sprintf("矩阵实例 %s 在最近 %d 次运行中失败 %d 次，可能存在不稳定的测试或环境问题", leg.Name, leg.Runs, leg.Failures),
+			})
+		}
+	}
+
+	return suggestions, stats
+}
+
 // calculateKeyMetrics 计算关键指标
 func (h *OptimizationHandler) calculateKeyMetrics(executions []*models.Execution) map[string]interface{} {
 	metrics := make(map[string]interface{})
@@ -2,9 +2,19 @@ package handlers
 
 import (
 	"ci-cd-orchestrator/internal/cicd"
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/linter"
+	"ci-cd-orchestrator/internal/cicd/template"
+	"ci-cd-orchestrator/internal/cicd/validator"
+	"ci-cd-orchestrator/internal/db"
+	"ci-cd-orchestrator/internal/drift"
+	"ci-cd-orchestrator/internal/models"
 	"ci-cd-orchestrator/internal/repository"
 	"ci-cd-orchestrator/internal/techstack"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -13,23 +23,153 @@ import (
 
 // PipelineHandler 管道配置处理器
 type PipelineHandler struct {
-	templateRepo repository.TemplateRepository
+	templateRepo  repository.TemplateRepository
+	projectRepo   *repository.ProjectRepository
+	pipelineRepo  *repository.PipelineRepository
+	executionRepo *repository.ExecutionRepository
+	findingRepo   *repository.SecurityFindingRepository
+	ruleRepo      *repository.SecurityRuleRepository
+	detector      *drift.Detector
 }
 
 // NewPipelineHandler 创建管道配置处理器实例
 func NewPipelineHandler(templateRepo repository.TemplateRepository) *PipelineHandler {
+	projectRepo := repository.NewProjectRepository(db.GetDB())
+	pipelineRepo := repository.NewPipelineRepository(db.GetDB())
 	return &PipelineHandler{
-		templateRepo: templateRepo,
+		templateRepo:  templateRepo,
+		projectRepo:   projectRepo,
+		pipelineRepo:  pipelineRepo,
+		executionRepo: repository.NewExecutionRepository(db.GetDB()),
+		findingRepo:   repository.NewSecurityFindingRepository(db.GetDB()),
+		ruleRepo:      repository.NewSecurityRuleRepository(db.GetDB()),
+		detector:      drift.NewDetector(projectRepo, pipelineRepo),
 	}
 }
 
-// GeneratePipeline 生成管道配置
+// pipelineRequest 修改管道配置的请求，reason 和 author 记录在新修订中
+type pipelineRequest struct {
+	cicd.PipelineConfig
+	Reason string `json:"reason"`
+	Author string `json:"author"`
+}
+
+// change 返回请求中的修改信息，未提供作者时记为 anonymous
+func (r *pipelineRequest) change(source string) repository.PipelineChange {
+	change := repository.PipelineChange{Source: source, Reason: r.Reason, Author: r.Author}
+	if change.Author == "" {
+		change.Author = "anonymous"
+	}
+	return change
+}
+
+// writeValidationError 配置验证失败时返回 422 和全部诊断，err 不是验证错误时返回 false
+func writeValidationError(w http.ResponseWriter, message string, err error) bool {
+	var validationErr *validator.Error
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+
+	response := map[string]interface{}{
+		"status":  "error",
+		"data":    map[string]interface{}{"diagnostics": validationErr.Diagnostics},
+		"message": message + ": " + err.Error(),
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+	return true
+}
+
+// writeRenderError 模板参数无效或渲染失败时返回 400，err 不是渲染错误时返回 false
+func writeRenderError(w http.ResponseWriter, message string, err error) bool {
+	var renderErr *template.RenderError
+	if !errors.As(err, &renderErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+
+	response := map[string]interface{}{
+		"status":  "error",
+		"data":    nil,
+		"message": message + ": " + err.Error(),
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+	return true
+}
+
+// savePipeline 保存项目的管道配置，项目已有同平台的配置时更新，否则新建，修改保存为新修订。
+// 配置来自模板时记录模板 ID 和版本，否则保留原有的模板信息
+func savePipeline(pipelineRepo *repository.PipelineRepository, projectID int, config *cicd.PipelineConfig, change repository.PipelineChange) (*models.Pipeline, error) {
+	pipeline, err := pipelineRepo.GetByProjectAndPlatform(projectID, string(config.Platform))
+	if errors.Is(err, sql.ErrNoRows) {
+		pipeline = &models.Pipeline{
+			ProjectID: projectID,
+			Platform:  string(config.Platform),
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	pipeline.Config = config.Content
+	if config.Filename != "" {
+		pipeline.Filename = config.Filename
+	}
+	if config.TemplateID != 0 {
+		pipeline.TemplateID = config.TemplateID
+		pipeline.TemplateVersion = config.TemplateVersion
+	}
+
+	if pipeline.ID != 0 {
+		err = pipelineRepo.Update(pipeline, change)
+	} else {
+		err = pipelineRepo.Create(pipeline, change)
+	}
+	if err != nil {
+		return nil, err
+	}
+	config.Revision = pipeline.Revision
+	return pipeline, nil
+}
+
+// setPipelineDefaults 为管道配置中未指定的字段设置默认值，平台默认为 GitHub Actions
+func setPipelineDefaults(config *cicd.PipelineConfig) {
+	if config.Platform == "" {
+		config.Platform = cicd.PlatformGitHubActions
+	}
+	if config.ConfigType == "" {
+		config.ConfigType = cicd.ConfigTypeYAML
+	}
+	if config.Filename == "" {
+		config.Filename = common.DefaultFilename(config.Platform)
+	}
+}
+
+// GeneratePipeline 生成管道配置，请求体可选，格式为 {"parameters": {"<name>": <value>}}，用于覆盖模板参数
 func (h *PipelineHandler) GeneratePipeline(w http.ResponseWriter, r *http.Request) {
+	// 从路径参数中获取项目 ID
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
+	}
+
 	// 从请求中获取平台参数，默认为 GitHub Actions
 	platformStr := r.URL.Query().Get("platform")
 	platform := cicd.PlatformGitHubActions
-	if platformStr == "mock" {
+	switch platformStr {
+	case "mock":
 		platform = cicd.PlatformMock
+	case "gitlab_ci":
+		platform = cicd.PlatformGitLabCI
 	}
 
 	// 从请求中获取项目路径参数
@@ -39,14 +179,26 @@ func (h *PipelineHandler) GeneratePipeline(w http.ResponseWriter, r *http.Reques
 	}
 
 	// 从请求中获取模板ID参数
-	var templateID int
+	var options cicd.GenerateOptions
 	templateIDStr := r.URL.Query().Get("template_id")
 	if templateIDStr != "" {
 		if id, err := strconv.Atoi(templateIDStr); err == nil {
-			templateID = id
+			options.TemplateID = id
 		}
 	}
 
+	// 从请求体中获取模板参数，请求体为空时使用默认值和技术栈中的值
+	var req struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的请求参数: ` + err.Error() + `"}`))
+		return
+	}
+	options.Parameters = req.Parameters
+
 	// 调用技术栈识别模块获取实际的技术栈信息
 	recognizer := techstack.NewRecognizer()
 	techStackResult, err := recognizer.Recognize(projectPath)
@@ -59,14 +211,13 @@ func (h *PipelineHandler) GeneratePipeline(w http.ResponseWriter, r *http.Reques
 
 	// 使用 CI/CD 生成器生成配置
 	generator := cicd.NewGenerator(h.templateRepo)
-	var config *cicd.PipelineConfig
-
-	if templateID > 0 {
-		config, err = generator.GenerateConfig(&techStackResult.TechStack, platform, templateID)
-	} else {
-		config, err = generator.GenerateConfig(&techStackResult.TechStack, platform)
+	config, err := generator.GenerateConfig(&techStackResult.TechStack, platform, options)
+	if writeRenderError(w, "渲染模板失败", err) {
+		return
+	}
+	if writeValidationError(w, "生成的管道配置验证失败", err) {
+		return
 	}
-
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -81,14 +232,29 @@ func (h *PipelineHandler) GeneratePipeline(w http.ResponseWriter, r *http.Reques
 
 	// 检查文件是否存在
 	if _, err := os.Stat(configPath); err == nil {
-		// 文件已存在，不生成新配置
+		// 文件已存在，不生成新配置，也不保存。按项目的规则开关进行安全检查，检查结果只随响应返回
+		disabled, _ := h.ruleRepo.DisabledRules(projectID)
+		if findings, err := linter.Lint(config.Platform, config.Content, disabled); err == nil {
+			config.Findings = findings
+		}
+
+		// 已保存过配置时比较配置文件是否与其一致，比较结果随响应返回
+		message := "配置文件已存在，跳过生成"
+		var report *drift.Report
+		if existing, err := h.pipelineRepo.GetByProjectAndPlatform(projectID, string(config.Platform)); err == nil {
+			if report, err = h.detector.Check(existing); err == nil && report.Status == drift.StatusDrifted {
+				message = "配置文件已存在且与已保存的配置不一致，跳过生成"
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
 		response := map[string]interface{}{
 			"status":  "success",
 			"data":    config,
-			"message": "配置文件已存在，跳过生成",
+			"drift":   report,
+			"message": message,
 		}
 
 		data, _ := json.Marshal(response)
@@ -113,6 +279,25 @@ func (h *PipelineHandler) GeneratePipeline(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// 保存配置，记录生成时使用的模板及版本
+	change := repository.PipelineChange{Source: repository.PipelineSourceGenerated, Reason: "generated from template", Author: "system"}
+	pipeline, err := savePipeline(h.pipelineRepo, projectID, config, change)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"保存管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 安全检查，结果按管道配置保存
+	config.Findings, err = lintPipeline(h.ruleRepo, h.findingRepo, pipeline)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"安全检查失败: ` + err.Error() + `"}`))
+		return
+	}
+
 	// 返回成功响应
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -132,8 +317,11 @@ func (h *PipelineHandler) GetPipeline(w http.ResponseWriter, r *http.Request) {
 	// 从请求中获取平台参数，默认为 GitHub Actions
 	platformStr := r.URL.Query().Get("platform")
 	platform := cicd.PlatformGitHubActions
-	if platformStr == "mock" {
+	switch platformStr {
+	case "mock":
 		platform = cicd.PlatformMock
+	case "gitlab_ci":
+		platform = cicd.PlatformGitLabCI
 	}
 
 	// 从请求中获取技术栈信息（这里简化处理，暂时使用一个模拟的技术栈）
@@ -151,7 +339,7 @@ func (h *PipelineHandler) GetPipeline(w http.ResponseWriter, r *http.Request) {
 
 	// 使用 CI/CD 生成器生成配置
 	generator := cicd.NewGenerator(h.templateRepo)
-	config, err := generator.GenerateConfig(mockTechStack, platform)
+	config, err := generator.GenerateConfig(mockTechStack, platform, cicd.GenerateOptions{})
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -175,36 +363,49 @@ func (h *PipelineHandler) GetPipeline(w http.ResponseWriter, r *http.Request) {
 
 // UpdatePipeline 更新管道配置
 func (h *PipelineHandler) UpdatePipeline(w http.ResponseWriter, r *http.Request) {
-	// 从请求中获取管道配置（这里简化处理，暂时使用一个模拟的配置）
-	// 实际应用中，应该从请求体中解析配置
-	mockConfig := &cicd.PipelineConfig{
-		Platform:   cicd.PlatformGitHubActions,
-		ConfigType: cicd.ConfigTypeYAML,
-		Content: `name: Updated CI
-
-on:
-  push:
-    branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v2
-    - name: Build
-      run: go build -v ./...
-    - name: Test
-      run: go test -v ./...
-`,
-		Filename: ".github/workflows/ci.yml",
+	// 从路径参数中获取项目 ID
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
 	}
 
+	// 从请求体中解析管道配置和修改原因，未指定的字段使用 GitHub Actions 的默认值
+	var req pipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的请求参数: ` + err.Error() + `"}`))
+		return
+	}
+	config := req.PipelineConfig
+	setPipelineDefaults(&config)
+
 	// 使用 CI/CD 生成器验证配置
 	generator := cicd.NewGenerator(h.templateRepo)
-	err := generator.ValidateConfig(mockConfig)
+	diagnostics := generator.ValidateConfig(&config)
+	if writeValidationError(w, "更新管道配置失败", diagnostics.Err()) {
+		return
+	}
+	config.Diagnostics = diagnostics
+
+	// 保存配置，项目已有同平台的配置时更新，否则新建
+	pipeline, err := savePipeline(h.pipelineRepo, projectID, &config, req.change(repository.PipelineSourceManual))
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"status":"error","data":null,"message":"更新管道配置失败: ` + err.Error() + `"}`))
+		w.Write([]byte(`{"status":"error","data":null,"message":"保存管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 安全检查，结果按管道配置保存，发现的问题不阻止保存
+	config.Findings, err = lintPipeline(h.ruleRepo, h.findingRepo, pipeline)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"安全检查失败: ` + err.Error() + `"}`))
 		return
 	}
 
@@ -214,7 +415,7 @@ jobs:
 
 	response := map[string]interface{}{
 		"status":  "success",
-		"data":    mockConfig,
+		"data":    config,
 		"message": "更新管道配置成功",
 	}
 
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"ci-cd-orchestrator/internal/drift"
+	"ci-cd-orchestrator/internal/repository"
+)
+
+// driftRequest 处理漂移的请求，reason 和 author 在采用配置文件时记录在新修订中
+type driftRequest struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	Author string `json:"author"`
+}
+
+// CheckPipelineDrift 比较管道配置与项目目录中的配置文件，返回 job 和步骤的语义差异并保存比较结果。
+// platform 查询参数默认为 github_actions
+func (h *PipelineHandler) CheckPipelineDrift(w http.ResponseWriter, r *http.Request) {
+	pipeline := h.requestPipeline(w, r)
+	if pipeline == nil {
+		return
+	}
+
+	report, err := h.detector.Check(pipeline)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"检查配置漂移失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    report,
+		"message": "检查配置漂移成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// ResolvePipelineDrift 处理管道配置的漂移。action 为 adopt 时将项目目录中的配置文件验证后保存为新修订，
+// 为 overwrite 时用已保存的配置覆盖配置文件。返回处理后的比较结果
+func (h *PipelineHandler) ResolvePipelineDrift(w http.ResponseWriter, r *http.Request) {
+	var req driftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+	if req.Action != drift.ActionAdopt && req.Action != drift.ActionOverwrite {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的处理方式，可选值为 adopt 和 overwrite"}`))
+		return
+	}
+
+	pipeline := h.requestPipeline(w, r)
+	if pipeline == nil {
+		return
+	}
+
+	var report *drift.Report
+	var err error
+	if req.Action == drift.ActionAdopt {
+		change := (&pipelineRequest{Reason: req.Reason, Author: req.Author}).change(repository.PipelineSourceDisk)
+		if change.Reason == "" {
+			change.Reason = "adopted from config file"
+		}
+		report, err = h.detector.Adopt(pipeline, change)
+		if writeValidationError(w, "配置文件验证失败", err) {
+			return
+		}
+		if err == nil {
+			_, err = lintPipeline(h.ruleRepo, h.findingRepo, pipeline)
+		}
+	} else {
+		report, err = h.detector.Overwrite(pipeline)
+	}
+	if errors.Is(err, drift.ErrNoProjectPath) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"项目路径为空"}`))
+		return
+	}
+	if errors.Is(err, drift.ErrNoConfigFile) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"项目目录中没有配置文件"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"处理配置漂移失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	message := "已采用配置文件"
+	if req.Action == drift.ActionOverwrite {
+		message = "已覆盖配置文件"
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    report,
+		"message": message,
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
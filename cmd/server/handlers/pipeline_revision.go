@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/pkg/diff"
+)
+
+// pipelineRevisionSummary 管道配置修订及使用该修订的执行统计
+type pipelineRevisionSummary struct {
+	*models.PipelineRevision
+	Stats *repository.RevisionStats `json:"stats"`
+}
+
+// pipelineDiff 管道配置两个修订之间的差异
+type pipelineDiff struct {
+	PipelineID int    `json:"pipeline_id"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Diff       string `json:"diff"` // 配置的 unified diff，两个修订相同时为空
+}
+
+// requestPipeline 获取请求中项目在查询参数 platform 指定的平台（默认为 github_actions）上的管道配置，
+// 失败时写入错误响应并返回 nil
+func (h *PipelineHandler) requestPipeline(w http.ResponseWriter, r *http.Request) *models.Pipeline {
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return nil
+	}
+
+	platform := r.URL.Query().Get("platform")
+	if platform == "" {
+		platform = "github_actions"
+	}
+
+	pipeline, err := h.pipelineRepo.GetByProjectAndPlatform(projectID, platform)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"管道配置不存在"}`))
+		return nil
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取管道配置失败: ` + err.Error() + `"}`))
+		return nil
+	}
+	return pipeline
+}
+
+// ListPipelineRevisions 获取管道配置的所有修订，按修订号从新到旧排序，
+// 每个修订附带使用该修订的执行次数、成功率和平均耗时
+func (h *PipelineHandler) ListPipelineRevisions(w http.ResponseWriter, r *http.Request) {
+	pipeline := h.requestPipeline(w, r)
+	if pipeline == nil {
+		return
+	}
+
+	revisions, err := h.pipelineRepo.ListRevisions(pipeline.ID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取管道配置修订失败: ` + err.Error() + `"}`))
+		return
+	}
+	stats, err := h.executionRepo.GetRevisionStats(pipeline.ID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"统计修订执行情况失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	summaries := make([]pipelineRevisionSummary, 0, len(revisions))
+	for _, revision := range revisions {
+		summary := pipelineRevisionSummary{PipelineRevision: revision, Stats: stats[revision.Revision]}
+		if summary.Stats == nil {
+			summary.Stats = &repository.RevisionStats{Revision: revision.Revision}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    summaries,
+		"message": "获取管道配置修订成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// GetPipelineRevision 获取管道配置的指定修订
+func (h *PipelineHandler) GetPipelineRevision(w http.ResponseWriter, r *http.Request) {
+	revisionNumber, err := pathID(r.URL.Path, "revisions")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的修订号"}`))
+		return
+	}
+
+	pipeline := h.requestPipeline(w, r)
+	if pipeline == nil {
+		return
+	}
+
+	revision, err := h.pipelineRepo.GetRevision(pipeline.ID, revisionNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"管道配置修订不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取管道配置修订失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    revision,
+		"message": "获取管道配置修订成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// DiffPipelineRevisions 比较管道配置的两个修订，查询参数 from 和 to 为修订号，
+// to 默认为当前修订，from 默认为 to 的前一个修订
+func (h *PipelineHandler) DiffPipelineRevisions(w http.ResponseWriter, r *http.Request) {
+	pipeline := h.requestPipeline(w, r)
+	if pipeline == nil {
+		return
+	}
+
+	var err error
+	query := r.URL.Query()
+	to := pipeline.Revision
+	if value := query.Get("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的修订号: ` + value + `"}`))
+			return
+		}
+	}
+	from := to - 1
+	if value := query.Get("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的修订号: ` + value + `"}`))
+			return
+		}
+	}
+
+	revisions := make([]*models.PipelineRevision, 0, 2)
+	for _, number := range []int{from, to} {
+		revision, err := h.pipelineRepo.GetRevision(pipeline.ID, number)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":"error","data":null,"message":"管道配置修订 ` + strconv.Itoa(number) + ` 不存在"}`))
+			return
+		}
+		revisions = append(revisions, revision)
+	}
+
+	result := &pipelineDiff{
+		PipelineID: pipeline.ID,
+		From:       from,
+		To:         to,
+		Diff:       diff.Unified(revisions[0].Config, revisions[1].Config, fmt.Sprintf("revision %d", from), fmt.Sprintf("revision %d", to)),
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    result,
+		"message": "比较管道配置修订成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// RollbackPipeline 将管道配置回滚到指定修订，回滚后的配置保存为新修订并重新进行安全检查。
+// 请求体可选，格式为 {"reason": "...", "author": "..."}。项目目录中的配置文件不会被修改
+func (h *PipelineHandler) RollbackPipeline(w http.ResponseWriter, r *http.Request) {
+	revision, err := pathID(r.URL.Path, "revisions")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的修订号"}`))
+		return
+	}
+
+	var req pipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+	change := req.change(repository.PipelineSourceRollback)
+	if change.Reason == "" {
+		change.Reason = fmt.Sprintf("rollback to revision %d", revision)
+	}
+
+	current := h.requestPipeline(w, r)
+	if current == nil {
+		return
+	}
+
+	pipeline, err := h.pipelineRepo.Rollback(current.ID, revision, change)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"管道配置修订不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"回滚管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 安全检查结果按回滚后的配置保存
+	if _, err := lintPipeline(h.ruleRepo, h.findingRepo, pipeline); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"安全检查失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    pipeline,
+		"message": "回滚管道配置成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
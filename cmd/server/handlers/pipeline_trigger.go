@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ci-cd-orchestrator/internal/trigger"
+)
+
+// triggerCheckRequest 检查触发条件的请求。config 为空时使用已保存的管道配置，revision 指定其修订，0 表示当前修订。
+// 事件没有 changed_files 时根据 before、sha 或 base_ref 在项目目录的 git 仓库中计算
+type triggerCheckRequest struct {
+	Event    trigger.Event `json:"event"`
+	Config   string        `json:"config"`
+	Revision int           `json:"revision"`
+}
+
+// triggerCheckResult 触发条件的检查结果，event 包含计算出的变更文件
+type triggerCheckResult struct {
+	trigger.Decision
+	Event             trigger.Event `json:"event"`
+	ChangedFilesError string        `json:"changed_files_error,omitempty"` // 无法计算变更文件的原因，此时不检查路径过滤
+	PipelineRevision  int           `json:"pipeline_revision,omitempty"`
+}
+
+// CheckPipelineTrigger 用模拟的事件检查管道配置的 on 触发条件，返回是否触发执行及原因，不启动执行。
+// platform 查询参数默认为 github_actions
+func (h *PipelineHandler) CheckPipelineTrigger(w http.ResponseWriter, r *http.Request) {
+	var req triggerCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
+	}
+	project, err := h.projectRepo.GetByID(projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"项目不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取项目信息失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	platform := r.URL.Query().Get("platform")
+	if platform == "" {
+		platform = "github_actions"
+	}
+
+	result := &triggerCheckResult{}
+	content := req.Config
+	if content == "" {
+		pipeline := h.requestPipeline(w, r)
+		if pipeline == nil {
+			return
+		}
+		content = pipeline.Config
+		result.PipelineRevision = pipeline.Revision
+		if req.Revision != 0 {
+			revision, err := h.pipelineRepo.GetRevision(pipeline.ID, req.Revision)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"status":"error","data":null,"message":"管道配置修订不存在"}`))
+				return
+			}
+			content = revision.Config
+			result.PipelineRevision = revision.Revision
+		}
+	}
+
+	if req.Event.ChangedFiles == nil && project.Path != "" {
+		if err := req.Event.LoadChangedFiles(project.Path); err != nil {
+			result.ChangedFilesError = err.Error()
+		}
+	}
+	result.Event = req.Event
+
+	decision, err := trigger.EvaluateConfig(platform, content, req.Event)
+	if errors.Is(err, trigger.ErrUnsupportedEvent) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"不支持的事件类型，可选值为 push、tag、pull_request、schedule 和 manual"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"解析管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+	result.Decision = *decision
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    result,
+		"message": "检查触发条件成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
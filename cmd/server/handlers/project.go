@@ -7,23 +7,33 @@ import (
 	"strings"
 
 	"ci-cd-orchestrator/internal/db"
+	"ci-cd-orchestrator/internal/drift"
 	"ci-cd-orchestrator/internal/models"
 	"ci-cd-orchestrator/internal/repository"
 )
 
 // ProjectHandler 项目处理器
 type ProjectHandler struct {
-	projectRepo *repository.ProjectRepository
+	projectRepo  *repository.ProjectRepository
+	pipelineRepo *repository.PipelineRepository
+}
+
+// projectSummary 项目及其管道配置的漂移情况
+type projectSummary struct {
+	*models.Project
+	Drifted          bool     `json:"drifted"`                     // 是否有管道配置与项目目录中的配置文件不一致
+	DriftedPlatforms []string `json:"drifted_platforms,omitempty"` // 漂移或配置文件缺失的平台
 }
 
 // NewProjectHandler 创建项目处理器实例
 func NewProjectHandler() *ProjectHandler {
 	return &ProjectHandler{
-		projectRepo: repository.NewProjectRepository(db.GetDB()),
+		projectRepo:  repository.NewProjectRepository(db.GetDB()),
+		pipelineRepo: repository.NewPipelineRepository(db.GetDB()),
 	}
 }
 
-// ListProjects 获取项目列表
+// ListProjects 获取项目列表，每个项目附带最近一次漂移检查的结果
 func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 	projects, err := h.projectRepo.GetAll()
 	if err != nil {
@@ -32,6 +42,19 @@ func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"status":"error","data":null,"message":"获取项目列表失败: ` + err.Error() + `"}`))
 		return
 	}
+	drifted, err := h.pipelineRepo.GetDriftedPlatforms(drift.StatusDrifted, drift.StatusMissing)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取配置漂移状态失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	summaries := make([]projectSummary, 0, len(projects))
+	for _, project := range projects {
+		platforms := drifted[project.ID]
+		summaries = append(summaries, projectSummary{Project: project, Drifted: len(platforms) > 0, DriftedPlatforms: platforms})
+	}
 
 	// 返回成功响应
 	w.Header().Set("Content-Type", "application/json")
@@ -39,7 +62,7 @@ func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 
 	response := map[string]interface{}{
 		"status":  "success",
-		"data":    projects,
+		"data":    summaries,
 		"message": "获取项目列表成功",
 	}
 
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"ci-cd-orchestrator/internal/db"
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/scheduler"
+)
+
+// ScheduleHandler 定时任务处理器
+type ScheduleHandler struct {
+	projectRepo  *repository.ProjectRepository
+	scheduleRepo *repository.ScheduleRepository
+}
+
+// NewScheduleHandler 创建定时任务处理器实例
+func NewScheduleHandler() *ScheduleHandler {
+	return &ScheduleHandler{
+		projectRepo:  repository.NewProjectRepository(db.GetDB()),
+		scheduleRepo: repository.NewScheduleRepository(db.GetDB()),
+	}
+}
+
+// scheduleRequest 创建或修改定时任务的请求，修改时只更新提供的字段。
+// 来自管道配置的定时任务的名称、cron 表达式和时区由管道配置决定，不能修改
+type scheduleRequest struct {
+	Name     *string `json:"name"`
+	Cron     *string `json:"cron"`
+	Timezone *string `json:"timezone"`
+	Platform *string `json:"platform"`
+	Ref      *string `json:"ref"`
+	CatchUp  *string `json:"catch_up"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// apply 将请求的字段应用到定时任务，修改了 cron 表达式、时区或重新启用时从当前时间重新计算下次触发时间。
+// 请求无效时返回错误信息
+func (req *scheduleRequest) apply(schedule *models.Schedule) string {
+	if schedule.Source == repository.ScheduleSourcePipeline && (req.Name != nil || req.Cron != nil || req.Timezone != nil) {
+		return "来自管道配置的定时任务不能修改名称、cron 表达式和时区"
+	}
+
+	reschedule := schedule.NextRunAt.IsZero()
+	if req.Name != nil {
+		schedule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Cron != nil {
+		schedule.Cron = strings.TrimSpace(*req.Cron)
+		reschedule = true
+	}
+	if req.Timezone != nil {
+		schedule.Timezone = *req.Timezone
+		reschedule = true
+	}
+	if req.Platform != nil {
+		schedule.Platform = *req.Platform
+	}
+	if req.Ref != nil {
+		schedule.Ref = *req.Ref
+	}
+	if req.CatchUp != nil {
+		schedule.CatchUp = *req.CatchUp
+	}
+	if req.Enabled != nil {
+		// 禁用期间错过的触发不补充
+		reschedule = reschedule || (*req.Enabled && !schedule.Enabled)
+		schedule.Enabled = *req.Enabled
+	}
+
+	if schedule.Cron == "" {
+		return "cron 表达式不能为空"
+	}
+	if !triggerPlatforms[schedule.Platform] {
+		return "不支持的执行平台"
+	}
+	if !scheduler.ValidCatchUp(schedule.CatchUp) {
+		return "无效的错过触发处理策略，可选值为 skip、latest 和 all"
+	}
+	next, err := scheduler.NextRun(schedule.Cron, schedule.Timezone, time.Now())
+	if err != nil {
+		return "无效的定时设置: " + err.Error()
+	}
+	if reschedule {
+		schedule.NextRunAt = next
+	}
+	return ""
+}
+
+// writeScheduleError 写入错误响应，message 可能包含 cron 表达式等用户输入，因此编码为 JSON
+func writeScheduleError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := map[string]interface{}{
+		"status":  "error",
+		"data":    nil,
+		"message": message,
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// requestProject 获取路径中的项目，失败时写入错误响应并返回 nil
+func (h *ScheduleHandler) requestProject(w http.ResponseWriter, r *http.Request) *models.Project {
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		writeScheduleError(w, http.StatusBadRequest, "无效的项目 ID")
+		return nil
+	}
+
+	project, err := h.projectRepo.GetByID(projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeScheduleError(w, http.StatusNotFound, "项目不存在")
+		return nil
+	}
+	if err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, "获取项目信息失败: "+err.Error())
+		return nil
+	}
+	return project
+}
+
+// requestSchedule 获取路径中属于该项目的定时任务，失败时写入错误响应并返回 nil
+func (h *ScheduleHandler) requestSchedule(w http.ResponseWriter, r *http.Request) *models.Schedule {
+	project := h.requestProject(w, r)
+	if project == nil {
+		return nil
+	}
+
+	id, err := pathID(r.URL.Path, "schedules")
+	if err != nil {
+		writeScheduleError(w, http.StatusBadRequest, "无效的定时任务 ID")
+		return nil
+	}
+
+	schedule, err := h.scheduleRepo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && schedule.ProjectID != project.ID) {
+		writeScheduleError(w, http.StatusNotFound, "定时任务不存在")
+		return nil
+	}
+	if err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, "获取定时任务失败: "+err.Error())
+		return nil
+	}
+	return schedule
+}
+
+// ListSchedules 获取项目的定时任务，包括管道配置中的 on.schedule 和通过 API 创建的定时任务
+func (h *ScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	project := h.requestProject(w, r)
+	if project == nil {
+		return
+	}
+
+	schedules, err := h.scheduleRepo.GetByProjectID(project.ID)
+	if err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, "获取定时任务失败: "+err.Error())
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    schedules,
+		"message": "获取定时任务成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// CreateSchedule 创建定时任务。cron 为必填的 5 段 cron 表达式；timezone 默认为 UTC；platform 默认为 mock；
+// catch_up 为错过触发的处理策略，默认为 latest
+func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeScheduleError(w, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	project := h.requestProject(w, r)
+	if project == nil {
+		return
+	}
+
+	schedule := &models.Schedule{
+		ProjectID: project.ID,
+		Source:    repository.ScheduleSourceAPI,
+		Timezone:  "UTC",
+		Platform:  "mock",
+		CatchUp:   scheduler.CatchUpLatest,
+		Enabled:   true,
+	}
+	if message := req.apply(schedule); message != "" {
+		writeScheduleError(w, http.StatusBadRequest, message)
+		return
+	}
+	if schedule.Name == "" {
+		schedule.Name = schedule.Cron
+	}
+
+	if _, err := h.scheduleRepo.Create(schedule); err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, "创建定时任务失败: "+err.Error())
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    schedule,
+		"message": "创建定时任务成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// GetSchedule 获取定时任务详情，包括下次和最近一次触发时间
+func (h *ScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule := h.requestSchedule(w, r)
+	if schedule == nil {
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    schedule,
+		"message": "获取定时任务成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// UpdateSchedule 修改定时任务，只更新请求中提供的字段
+func (h *ScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeScheduleError(w, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	schedule := h.requestSchedule(w, r)
+	if schedule == nil {
+		return
+	}
+	if message := req.apply(schedule); message != "" {
+		writeScheduleError(w, http.StatusBadRequest, message)
+		return
+	}
+	if schedule.Name == "" {
+		schedule.Name = schedule.Cron
+	}
+
+	if err := h.scheduleRepo.Update(schedule); err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, "修改定时任务失败: "+err.Error())
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    schedule,
+		"message": "修改定时任务成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// DeleteSchedule 删除通过 API 创建的定时任务。来自管道配置的定时任务会在同步时重新创建，只能禁用
+func (h *ScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule := h.requestSchedule(w, r)
+	if schedule == nil {
+		return
+	}
+	if schedule.Source == repository.ScheduleSourcePipeline {
+		writeScheduleError(w, http.StatusBadRequest, "来自管道配置的定时任务不能删除，可以修改 enabled 禁用")
+		return
+	}
+
+	if err := h.scheduleRepo.Delete(schedule.ID); err != nil {
+		writeScheduleError(w, http.StatusInternalServerError, "删除定时任务失败: "+err.Error())
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"success","data":null,"message":"删除定时任务成功"}`))
+}
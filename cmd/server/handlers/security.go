@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/linter"
+	"ci-cd-orchestrator/internal/db"
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SecurityHandler 管道配置安全检查处理器
+type SecurityHandler struct {
+	pipelineRepo *repository.PipelineRepository
+	findingRepo  *repository.SecurityFindingRepository
+	ruleRepo     *repository.SecurityRuleRepository
+}
+
+// NewSecurityHandler 创建安全检查处理器实例
+func NewSecurityHandler() *SecurityHandler {
+	return &SecurityHandler{
+		pipelineRepo: repository.NewPipelineRepository(db.GetDB()),
+		findingRepo:  repository.NewSecurityFindingRepository(db.GetDB()),
+		ruleRepo:     repository.NewSecurityRuleRepository(db.GetDB()),
+	}
+}
+
+// projectIDFromPath 从 /api/v1/projects/{id}/... 形式的路径中获取项目 ID
+func projectIDFromPath(path string) (int, error) {
+	return pathID(path, "projects")
+}
+
+// pathID 获取路径中紧跟在 segment 之后的数字 ID
+func pathID(path, segment string) (int, error) {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == segment && i+1 < len(parts) {
+			return strconv.Atoi(parts[i+1])
+		}
+	}
+	return 0, fmt.Errorf("%s id not found in path %s", segment, path)
+}
+
+// lintPipeline 按项目的规则开关检查管道配置，并用检查结果替换该管道配置已有的结果
+func lintPipeline(ruleRepo *repository.SecurityRuleRepository, findingRepo *repository.SecurityFindingRepository, pipeline *models.Pipeline) ([]common.Finding, error) {
+	disabled, err := ruleRepo.DisabledRules(pipeline.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := linter.Lint(common.Platform(pipeline.Platform), pipeline.Config, disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*models.SecurityFinding, 0, len(findings))
+	for _, finding := range findings {
+		records = append(records, &models.SecurityFinding{
+			ProjectID: pipeline.ProjectID,
+			Rule:      finding.Rule,
+			Severity:  string(finding.Severity),
+			Job:       finding.Job,
+			Line:      finding.Line,
+			Column:    finding.Column,
+			Message:   finding.Message,
+		})
+	}
+	if err := findingRepo.ReplaceForPipeline(pipeline.ID, records); err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// ListFindings 查询项目管道配置的安全检查结果，支持 pipeline_id、rule 和 severity 过滤
+func (h *SecurityHandler) ListFindings(w http.ResponseWriter, r *http.Request) {
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.SecurityFindingFilter{
+		ProjectID: projectID,
+		Rule:      query.Get("rule"),
+		Severity:  query.Get("severity"),
+	}
+	if pipelineIDStr := query.Get("pipeline_id"); pipelineIDStr != "" {
+		pipelineID, err := strconv.Atoi(pipelineIDStr)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的管道配置 ID"}`))
+			return
+		}
+		filter.PipelineID = pipelineID
+	}
+
+	findings, err := h.findingRepo.List(filter)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取安全检查结果失败: ` + err.Error() + `"}`))
+		return
+	}
+	if findings == nil {
+		findings = []*models.SecurityFinding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    findings,
+		"message": "获取安全检查结果成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// securityRuleState 规则及其在项目中的开关状态
+type securityRuleState struct {
+	linter.Rule
+	Enabled bool `json:"enabled"`
+}
+
+// ruleStates 获取项目中所有规则的开关状态
+func (h *SecurityHandler) ruleStates(projectID int) ([]securityRuleState, error) {
+	disabled, err := h.ruleRepo.DisabledRules(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]securityRuleState, 0, len(linter.Rules))
+	for _, rule := range linter.Rules {
+		states = append(states, securityRuleState{Rule: rule, Enabled: !disabled[rule.ID]})
+	}
+	return states, nil
+}
+
+// ListRules 获取项目的安全检查规则及开关状态
+func (h *SecurityHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
+	}
+
+	states, err := h.ruleStates(projectID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取安全检查规则失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    states,
+		"message": "获取安全检查规则成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// UpdateRules 启用或关闭项目的安全检查规则，请求体为 {"rules": {"<rule>": true|false}}，
+// 修改后不会自动重新检查，需要调用 LintPipelines 或重新保存管道配置
+func (h *SecurityHandler) UpdateRules(w http.ResponseWriter, r *http.Request) {
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
+	}
+
+	var req struct {
+		Rules map[string]bool `json:"rules"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的请求参数: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 先检查所有规则，避免只更新了一部分
+	for rule := range req.Rules {
+		if _, ok := linter.FindRule(rule); !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"未知的安全检查规则: ` + rule + `"}`))
+			return
+		}
+	}
+	for rule, enabled := range req.Rules {
+		setting := &models.SecurityRuleSetting{ProjectID: projectID, Rule: rule, Enabled: enabled}
+		if err := h.ruleRepo.Set(setting); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","data":null,"message":"更新安全检查规则失败: ` + err.Error() + `"}`))
+			return
+		}
+	}
+
+	states, err := h.ruleStates(projectID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取安全检查规则失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    states,
+		"message": "更新安全检查规则成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// LintPipelines 按当前的规则开关重新检查项目的所有管道配置并保存结果
+func (h *SecurityHandler) LintPipelines(w http.ResponseWriter, r *http.Request) {
+	projectID, err := projectIDFromPath(r.URL.Path)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的项目 ID"}`))
+		return
+	}
+
+	pipelines, err := h.pipelineRepo.GetByProjectID(projectID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	for _, pipeline := range pipelines {
+		if _, err := lintPipeline(h.ruleRepo, h.findingRepo, pipeline); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","data":null,"message":"检查管道配置 ` + strconv.Itoa(pipeline.ID) + ` 失败: ` + err.Error() + `"}`))
+			return
+		}
+	}
+
+	findings, err := h.findingRepo.List(repository.SecurityFindingFilter{ProjectID: projectID})
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取安全检查结果失败: ` + err.Error() + `"}`))
+		return
+	}
+	if findings == nil {
+		findings = []*models.SecurityFinding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    findings,
+		"message": "检查管道配置成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
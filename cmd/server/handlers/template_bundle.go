@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	cicdtemplate "ci-cd-orchestrator/internal/cicd/template"
+)
+
+// maxBundleSize 导入的模板包的最大大小
+const maxBundleSize = 32 << 20
+
+// writeImportError 模板包中的模板无效时返回 422 和全部问题，err 不是导入错误时返回 false
+func writeImportError(w http.ResponseWriter, message string, err error) bool {
+	var importErr *cicdtemplate.ImportError
+	if !errors.As(err, &importErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+
+	response := map[string]interface{}{
+		"status":  "error",
+		"data":    map[string]interface{}{"problems": importErr.Problems},
+		"message": message + ": " + err.Error(),
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+	return true
+}
+
+// ExportTemplates 导出模板包。ids 指定要导出的模板（逗号分隔，为空时导出所有模板），
+// 被继承或引用的模板会一并导出；format 为 json（默认）或 tar.gz
+func (h *TemplateHandler) ExportTemplates(w http.ResponseWriter, r *http.Request) {
+	if len(h.bundleKey) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"未配置模板包签名密钥 TEMPLATE_BUNDLE_KEY"}`))
+		return
+	}
+
+	var ids []int
+	if value := r.URL.Query().Get("ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID: ` + part + `"}`))
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = cicdtemplate.BundleFormatJSON
+	}
+	if format != cicdtemplate.BundleFormatJSON && format != cicdtemplate.BundleFormatTarGz {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板包格式，可选值为 json 和 tar.gz"}`))
+		return
+	}
+
+	bundle, err := cicdtemplate.ExportBundle(h.templateRepo, ids)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"导出模板失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	data, err := bundle.Encode(format, h.bundleKey)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"导出模板失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	filename := "templates-" + bundle.ExportedAt.Format("20060102-150405")
+	if format == cicdtemplate.BundleFormatTarGz {
+		w.Header().Set("Content-Type", "application/gzip")
+		filename += ".tar.gz"
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		filename += ".json"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ImportTemplates 导入模板包，请求体为导出的 JSON 或 tar.gz 模板包。
+// strategy 指定与已有模板冲突时的处理方式（skip、overwrite 或 rename，默认为 skip），
+// dry_run 为 true 时只检查模板包并返回导入结果，author 记录在导入产生的版本中
+func (h *TemplateHandler) ImportTemplates(w http.ResponseWriter, r *http.Request) {
+	if len(h.bundleKey) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"未配置模板包签名密钥 TEMPLATE_BUNDLE_KEY"}`))
+		return
+	}
+
+	query := r.URL.Query()
+	options := cicdtemplate.ImportOptions{
+		Strategy: query.Get("strategy"),
+		Author:   query.Get("author"),
+	}
+	switch options.Strategy {
+	case "", cicdtemplate.ConflictSkip, cicdtemplate.ConflictOverwrite, cicdtemplate.ConflictRename:
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的冲突处理策略，可选值为 skip、overwrite 和 rename"}`))
+		return
+	}
+	if options.Author == "" {
+		options.Author = "anonymous"
+	}
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的 dry_run 参数"}`))
+			return
+		}
+		options.DryRun = dryRun
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBundleSize))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"读取模板包失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	bundle, err := cicdtemplate.DecodeBundle(data, h.bundleKey)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板包: ` + err.Error() + `"}`))
+		return
+	}
+
+	result, err := cicdtemplate.ImportBundle(bundle, h.templateRepo, options)
+	if err != nil {
+		if writeImportError(w, "导入模板失败", err) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"导入模板失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	message := "导入模板成功"
+	if options.DryRun {
+		message = "模板包检查通过"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    result,
+		"message": message,
+	}
+
+	responseData, _ := json.Marshal(response)
+	w.Write(responseData)
+}
@@ -1,48 +1,114 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"ci-cd-orchestrator/internal/cicd"
+	cicdtemplate "ci-cd-orchestrator/internal/cicd/template"
+	"ci-cd-orchestrator/internal/db"
 	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/techstack"
+	"ci-cd-orchestrator/pkg/diff"
 )
 
 // TemplateHandler 模板处理器
 type TemplateHandler struct {
 	templateRepo repository.TemplateRepository
+	pipelineRepo *repository.PipelineRepository
+	bundleKey    []byte // 模板包的签名密钥，导入和导出的实例需要使用相同的密钥
 }
 
 // NewTemplateHandler 创建模板处理器实例
 func NewTemplateHandler(templateRepo repository.TemplateRepository) *TemplateHandler {
 	return &TemplateHandler{
 		templateRepo: templateRepo,
+		pipelineRepo: repository.NewPipelineRepository(db.GetDB()),
+		bundleKey:    []byte(os.Getenv("TEMPLATE_BUNDLE_KEY")),
 	}
 }
 
+// templateRequest 创建和更新模板的请求体，author 和 note 记录在新版本中
+type templateRequest struct {
+	repository.Template
+	Author string `json:"author"`
+	Note   string `json:"note"`
+}
+
+// change 返回请求中的修改信息，未提供作者时记为 anonymous
+func (r *templateRequest) change() repository.TemplateChange {
+	change := repository.TemplateChange{Author: r.Author, Note: r.Note}
+	if change.Author == "" {
+		change.Author = "anonymous"
+	}
+	return change
+}
+
+// requiredFieldsMessage 检查必填字段，缺少时返回错误信息。片段只需要名称和内容
+func requiredFieldsMessage(template *repository.Template) string {
+	if template.Kind == repository.TemplateKindFragment {
+		if template.Name == "" || template.Content == "" {
+			return "片段的名称和内容为必填字段"
+		}
+		return ""
+	}
+	if template.Platform == "" || template.Content == "" || template.Filename == "" || template.ConfigType == "" {
+		return "平台、内容、文件名和配置类型为必填字段"
+	}
+	return ""
+}
+
+// validateTemplate 检查模板的参数定义、模板语法以及继承和引用关系
+func (h *TemplateHandler) validateTemplate(template *repository.Template) error {
+	if err := cicdtemplate.ValidateParameters(template.Parameters); err != nil {
+		return err
+	}
+	if err := cicdtemplate.ValidateContent(template.Content, template.Parameters); err != nil {
+		return err
+	}
+	return cicdtemplate.ValidateComposition(template, h.templateRepo)
+}
+
 // CreateTemplate 创建模板
 func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
-	var template repository.Template
-	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+	var req templateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
 		return
 	}
+	template := &req.Template
+	template.ID = 0
 
 	// 验证必要字段
-	if template.Platform == "" || template.Content == "" || template.Filename == "" || template.ConfigType == "" {
+	if message := requiredFieldsMessage(template); message != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"` + message + `"}`))
+		return
+	}
+
+	// 验证参数定义、模板语法以及继承和引用关系
+	if err := h.validateTemplate(template); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"status":"error","data":null,"message":"平台、内容、文件名和配置类型为必填字段"}`))
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板无效: ` + err.Error() + `"}`))
 		return
 	}
 
 	// 非内置模板
 	template.IsBuiltin = false
 
-	if err := h.templateRepo.Create(&template); err != nil {
+	if err := h.templateRepo.Create(template, req.change()); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"status":"error","data":null,"message":"创建模板失败: ` + err.Error() + `"}`))
@@ -113,7 +179,7 @@ func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// ListTemplates 获取所有模板
+// ListTemplates 获取所有模板，包括片段
 func (h *TemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
 	templates, err := h.templateRepo.GetAll()
 	if err != nil {
@@ -123,6 +189,17 @@ func (h *TemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// 按类型过滤，例如 ?kind=fragment 只返回片段
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		filtered := []*repository.Template{}
+		for _, template := range templates {
+			if template.Kind == kind {
+				filtered = append(filtered, template)
+			}
+		}
+		templates = filtered
+	}
+
 	// 返回成功响应
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -165,26 +242,19 @@ func (h *TemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var template repository.Template
-	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+	var req templateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
 		return
 	}
-
-	// 验证必要字段
-	if template.Platform == "" || template.Content == "" || template.Filename == "" || template.ConfigType == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"status":"error","data":null,"message":"平台、内容、文件名和配置类型为必填字段"}`))
-		return
-	}
+	template := &req.Template
 
 	// 确保ID一致
 	template.ID = id
 
-	// 保留内置模板标记
+	// 保留内置模板标记，未指定类型时保留原有类型
 	existingTemplate, err := h.templateRepo.GetByID(id)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -193,8 +263,27 @@ func (h *TemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	template.IsBuiltin = existingTemplate.IsBuiltin
+	if template.Kind == "" {
+		template.Kind = existingTemplate.Kind
+	}
 
-	if err := h.templateRepo.Update(&template); err != nil {
+	// 验证必要字段
+	if message := requiredFieldsMessage(template); message != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"` + message + `"}`))
+		return
+	}
+
+	// 验证参数定义、模板语法以及继承和引用关系
+	if err := h.validateTemplate(template); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板无效: ` + err.Error() + `"}`))
+		return
+	}
+
+	if err := h.templateRepo.Update(template, req.change()); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"status":"error","data":null,"message":"更新模板失败: ` + err.Error() + `"}`))
@@ -243,6 +332,36 @@ func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// 被其他模板继承或引用的模板不能删除
+	template, err := h.templateRepo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取模板失败: ` + err.Error() + `"}`))
+		return
+	}
+	if template.Name != "" {
+		templates, err := h.templateRepo.GetAll()
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","data":null,"message":"获取模板列表失败: ` + err.Error() + `"}`))
+			return
+		}
+		if users := cicdtemplate.Dependents(template.Name, templates); len(users) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"status":"error","data":null,"message":"模板被 ` + strconv.Itoa(len(users)) + ` 个模板继承或引用，不能删除"}`))
+			return
+		}
+	}
+
 	if err := h.templateRepo.Delete(id); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -301,6 +420,479 @@ func (h *TemplateHandler) ResetTemplate(w http.ResponseWriter, r *http.Request)
 	w.Write(data)
 }
 
+// RenderTemplate 预览模板渲染结果，不写入任何文件。请求体均为可选字段：
+// {"tech_stack": {...}, "path": "<项目路径>", "parameters": {"<name>": <value>}}，
+// 指定 path 时识别该目录的技术栈，否则使用 tech_stack，都没有时只使用默认值和 parameters
+func (h *TemplateHandler) RenderTemplate(w http.ResponseWriter, r *http.Request) {
+	// 从路径参数中获取模板ID
+	path := r.URL.Path
+	parts := strings.Split(path, "/")
+	var templateID string
+	for i, part := range parts {
+		if part == "templates" && i+1 < len(parts) {
+			templateID = parts[i+1]
+			break
+		}
+	}
+
+	id, err := strconv.Atoi(templateID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+
+	var req struct {
+		TechStack  *techstack.TechStack   `json:"tech_stack"`
+		Path       string                 `json:"path"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+
+	techStack := req.TechStack
+	if req.Path != "" {
+		result, err := techstack.NewRecognizer().Recognize(req.Path)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"分析项目技术栈失败: ` + err.Error() + `"}`))
+			return
+		}
+		techStack = &result.TechStack
+	}
+
+	if _, err := h.templateRepo.GetByID(id); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+
+	generator := cicd.NewGenerator(h.templateRepo)
+	config, err := generator.RenderTemplate(id, techStack, req.Parameters)
+	if writeRenderError(w, "渲染模板失败", err) {
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"渲染模板失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    config,
+		"message": "渲染模板成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// ListTemplateVersions 获取模板的所有版本，按版本号从新到旧排序
+func (h *TemplateHandler) ListTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r.URL.Path, "templates")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+
+	versions, err := h.templateRepo.ListVersions(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取模板版本失败: ` + err.Error() + `"}`))
+		return
+	}
+	if len(versions) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    versions,
+		"message": "获取模板版本成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// GetTemplateVersion 获取模板的指定版本
+func (h *TemplateHandler) GetTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r.URL.Path, "templates")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+	version, err := pathID(r.URL.Path, "versions")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的版本号"}`))
+		return
+	}
+
+	templateVersion, err := h.templateRepo.GetVersion(id, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板版本不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取模板版本失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    templateVersion,
+		"message": "获取模板版本成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// templateFieldChange 两个模板版本之间内容以外的字段变化
+type templateFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// templateDiff 两个模板版本之间的差异，diff 为模板内容的 unified diff
+type templateDiff struct {
+	TemplateID int                   `json:"template_id"`
+	From       int                   `json:"from"`
+	To         int                   `json:"to"`
+	Changes    []templateFieldChange `json:"changes"`
+	Diff       string                `json:"diff"`
+}
+
+// diffTemplateVersions 比较两个模板版本
+func diffTemplateVersions(from, to *repository.TemplateVersion) *templateDiff {
+	result := &templateDiff{
+		TemplateID: from.TemplateID,
+		From:       from.Version,
+		To:         to.Version,
+		Changes:    []templateFieldChange{},
+		Diff:       diff.Unified(from.Content, to.Content, fmt.Sprintf("version %d", from.Version), fmt.Sprintf("version %d", to.Version)),
+	}
+
+	fields := []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"platform", from.Platform, to.Platform},
+		{"language", from.Language, to.Language},
+		{"framework", from.Framework, to.Framework},
+		{"filename", from.Filename, to.Filename},
+		{"config_type", from.ConfigType, to.ConfigType},
+		{"parameters", from.Parameters, to.Parameters},
+	}
+	for _, field := range fields {
+		fromJSON, _ := json.Marshal(field.from)
+		toJSON, _ := json.Marshal(field.to)
+		if string(fromJSON) != string(toJSON) {
+			result.Changes = append(result.Changes, templateFieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	return result
+}
+
+// DiffTemplateVersions 比较模板的两个版本，查询参数 from 和 to 为版本号，
+// to 默认为当前版本，from 默认为 to 的前一个版本
+func (h *TemplateHandler) DiffTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r.URL.Path, "templates")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+
+	template, err := h.templateRepo.GetByID(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+
+	query := r.URL.Query()
+	to := template.Version
+	if value := query.Get("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的版本号: ` + value + `"}`))
+			return
+		}
+	}
+	from := to - 1
+	if value := query.Get("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","data":null,"message":"无效的版本号: ` + value + `"}`))
+			return
+		}
+	}
+
+	versions := make([]*repository.TemplateVersion, 0, 2)
+	for _, version := range []int{from, to} {
+		templateVersion, err := h.templateRepo.GetVersion(id, version)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":"error","data":null,"message":"模板版本 ` + strconv.Itoa(version) + ` 不存在"}`))
+			return
+		}
+		versions = append(versions, templateVersion)
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    diffTemplateVersions(versions[0], versions[1]),
+		"message": "比较模板版本成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// RollbackTemplate 将模板回滚到指定版本，回滚结果保存为新版本。
+// 请求体可选，格式为 {"author": "...", "note": "..."}
+func (h *TemplateHandler) RollbackTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r.URL.Path, "templates")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+	version, err := pathID(r.URL.Path, "versions")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的版本号"}`))
+		return
+	}
+
+	var req templateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"请求参数错误: ` + err.Error() + `"}`))
+		return
+	}
+	change := req.change()
+	if change.Note == "" {
+		change.Note = fmt.Sprintf("rollback to version %d", version)
+	}
+
+	// 回滚后的模板需要满足与更新相同的验证规则，例如回滚到的版本继承的模板已被删除时不能回滚
+	current, err := h.templateRepo.GetByID(id)
+	if err == nil {
+		var target *repository.TemplateVersion
+		if target, err = h.templateRepo.GetVersion(id, version); err == nil {
+			candidate := *current
+			candidate.Extends = target.Extends
+			candidate.Content = target.Content
+			candidate.Parameters = target.Parameters
+			if err := h.validateTemplate(&candidate); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":"error","data":null,"message":"不能回滚到该版本: ` + err.Error() + `"}`))
+				return
+			}
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板版本不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"回滚模板失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	template, err := h.templateRepo.Rollback(id, version, change)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板版本不存在"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"回滚模板失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    template,
+		"message": "回滚模板成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// outdatedPipeline 使用旧版本模板生成的管道配置
+type outdatedPipeline struct {
+	PipelineID      int       `json:"pipeline_id"`
+	ProjectID       int       `json:"project_id"`
+	Platform        string    `json:"platform"`
+	TemplateVersion int       `json:"template_version"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ListOutdatedPipelines 获取使用该模板的旧版本生成、尚未更新到当前版本的管道配置
+func (h *TemplateHandler) ListOutdatedPipelines(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r.URL.Path, "templates")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+
+	template, err := h.templateRepo.GetByID(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+
+	pipelines, err := h.pipelineRepo.GetByTemplateID(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取管道配置失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	outdated := []outdatedPipeline{}
+	for _, pipeline := range pipelines {
+		if pipeline.TemplateVersion < template.Version {
+			outdated = append(outdated, outdatedPipeline{
+				PipelineID:      pipeline.ID,
+				ProjectID:       pipeline.ProjectID,
+				Platform:        pipeline.Platform,
+				TemplateVersion: pipeline.TemplateVersion,
+				UpdatedAt:       pipeline.UpdatedAt,
+			})
+		}
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"template_id":     id,
+			"current_version": template.Version,
+			"pipelines":       outdated,
+		},
+		"message": "获取使用旧版本模板的管道配置成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// ListTemplateDependents 获取直接或间接继承、引用该模板的模板，修改该模板会影响这些模板下次渲染的结果
+func (h *TemplateHandler) ListTemplateDependents(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r.URL.Path, "templates")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的模板ID"}`))
+		return
+	}
+
+	template, err := h.templateRepo.GetByID(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"模板不存在"}`))
+		return
+	}
+
+	dependents := []*repository.Template{}
+	if template.Name != "" {
+		templates, err := h.templateRepo.GetAll()
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","data":null,"message":"获取模板列表失败: ` + err.Error() + `"}`))
+			return
+		}
+		dependents = append(dependents, cicdtemplate.Dependents(template.Name, templates)...)
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    dependents,
+		"message": "获取使用该模板的模板成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
 // InitializeBuiltinTemplates 初始化内置模板
 func (h *TemplateHandler) InitializeBuiltinTemplates() error {
 	// 这里将在后续实现，用于初始化内置模板
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"ci-cd-orchestrator/internal/db"
+	"ci-cd-orchestrator/internal/execution"
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/trigger"
+	"ci-cd-orchestrator/internal/webhook"
+)
+
+// maxWebhookPayload webhook 负载的最大长度，与 GitHub 的限制相同
+const maxWebhookPayload = 25 << 20
+
+// triggerPlatforms webhook 和定时任务可以触发的执行平台
+var triggerPlatforms = map[string]bool{"mock": true, "local": true, "github_actions": true, "gitlab_ci": true}
+
+// WebhookHandler 代码托管平台 webhook 处理器
+type WebhookHandler struct {
+	projectRepo  *repository.ProjectRepository
+	deliveryRepo *repository.WebhookDeliveryRepository
+	runner       *trigger.Runner
+}
+
+// NewWebhookHandler 创建 webhook 处理器实例
+func NewWebhookHandler(manager execution.Manager) *WebhookHandler {
+	return &WebhookHandler{
+		projectRepo:  repository.NewProjectRepository(db.GetDB()),
+		deliveryRepo: repository.NewWebhookDeliveryRepository(db.GetDB()),
+		runner:       trigger.NewRunner(manager, repository.NewPipelineRepository(db.GetDB())),
+	}
+}
+
+// webhookSecret 代码托管平台的 webhook 密钥，由环境变量 WEBHOOK_SECRET_<PROVIDER> 设置，未设置时使用 WEBHOOK_SECRET
+func webhookSecret(provider string) string {
+	if secret := os.Getenv("WEBHOOK_SECRET_" + strings.ToUpper(provider)); secret != "" {
+		return secret
+	}
+	return os.Getenv("WEBHOOK_SECRET")
+}
+
+// ReceiveWebhook 接收代码托管平台的 webhook。验证签名后保存投递，按仓库地址匹配项目并启动执行。
+// 查询参数 platform 指定执行平台，默认为 mock。同一投递 ID 只处理一次
+func (h *WebhookHandler) ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks/"), "/")
+	provider, ok := webhook.GetProvider(name)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"不支持的代码托管平台"}`))
+		return
+	}
+
+	platform := r.URL.Query().Get("platform")
+	if platform == "" {
+		platform = "mock"
+	}
+	if !triggerPlatforms[platform] {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"不支持的执行平台"}`))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayload))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"读取请求失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 未验证的请求不保存，避免伪造的投递 ID 导致真实投递被当作重复投递
+	if err := provider.Verify(webhookSecret(provider.Name), r.Header, body); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status":"error","data":null,"message":"签名验证失败"}`))
+		return
+	}
+
+	// 没有投递 ID 时使用负载的摘要，相同的负载只处理一次
+	deliveryID := r.Header.Get(provider.DeliveryHeader)
+	if deliveryID == "" {
+		sum := sha256.Sum256(body)
+		deliveryID = hex.EncodeToString(sum[:])
+	}
+
+	delivery := &models.WebhookDelivery{
+		Provider:   provider.Name,
+		DeliveryID: deliveryID,
+		Event:      r.Header.Get(provider.EventHeader),
+		Platform:   platform,
+		Payload:    string(body),
+	}
+	created, err := h.deliveryRepo.Create(delivery)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"保存投递失败: ` + err.Error() + `"}`))
+		return
+	}
+	if !created {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success","data":null,"message":"重复的投递，已忽略"}`))
+		return
+	}
+
+	if err := h.processDelivery(provider, delivery); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"处理投递失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    delivery,
+		"message": "接收 webhook 成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// processDelivery 解析投递的事件，在仓库地址匹配且触发条件满足的项目上启动执行，并保存处理结果。
+// 单个项目启动失败不影响其他项目，只有保存结果失败时返回错误
+func (h *WebhookHandler) processDelivery(provider *webhook.Provider, delivery *models.WebhookDelivery) error {
+	delivery.ExecutionIDs = nil
+	delivery.Message = ""
+
+	event, err := provider.Parse(delivery.Event, []byte(delivery.Payload))
+	switch {
+	case errors.Is(err, webhook.ErrIgnoredEvent):
+		delivery.Status = repository.DeliveryStatusIgnored
+		delivery.Message = fmt.Sprintf("event %q does not trigger executions", delivery.Event)
+		return h.deliveryRepo.UpdateResult(delivery)
+	case err != nil:
+		delivery.Status = repository.DeliveryStatusFailed
+		delivery.Message = err.Error()
+		return h.deliveryRepo.UpdateResult(delivery)
+	}
+
+	projects, err := h.projectRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	matched := 0
+	var failures, skipped []string
+	for _, project := range projects {
+		if !event.MatchRepository(project.RepositoryURL) {
+			continue
+		}
+		matched++
+
+		info := event.TriggerInfo()
+		info["delivery_id"] = delivery.DeliveryID
+		executionID, err := h.runner.Start(project, trigger.Request{
+			Platform: delivery.Platform,
+			Type:     event.Type,
+			Info:     info,
+			Ref:      event.Ref,
+			Event:    triggerEvent(event),
+		})
+		if executionID != "" {
+			delivery.ExecutionIDs = append(delivery.ExecutionIDs, executionID)
+		}
+		switch {
+		case errors.Is(err, trigger.ErrNotTriggered):
+			skipped = append(skipped, fmt.Sprintf("project %d: %v", project.ID, err))
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("project %d: %v", project.ID, err))
+		}
+	}
+
+	switch {
+	case matched == 0:
+		delivery.Status = repository.DeliveryStatusUnmatched
+		delivery.Message = fmt.Sprintf("no project matches repository %s", event.Repository)
+	case len(delivery.ExecutionIDs) == 0 && len(failures) == 0:
+		delivery.Status = repository.DeliveryStatusFiltered
+	case len(delivery.ExecutionIDs) == 0:
+		delivery.Status = repository.DeliveryStatusFailed
+	default:
+		delivery.Status = repository.DeliveryStatusProcessed
+	}
+	if messages := append(failures, skipped...); len(messages) > 0 {
+		delivery.Message = strings.Join(messages, "; ")
+	}
+	return h.deliveryRepo.UpdateResult(delivery)
+}
+
+// triggerEvent 判断管道配置触发条件使用的事件，变更的文件在各项目的目录中计算
+func triggerEvent(event *webhook.Event) *trigger.Event {
+	return &trigger.Event{
+		Type:    event.Type,
+		Ref:     event.Ref,
+		BaseRef: event.BaseRef,
+		Before:  event.Before,
+		SHA:     event.SHA,
+	}
+}
+
+// ListDeliveries 获取 webhook 投递，按接收时间从新到旧排序，可按 provider 过滤并用 limit 和 offset 分页
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := h.deliveryRepo.List(r.URL.Query().Get("provider"), limit, offset)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取投递列表失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    deliveries,
+		"message": "获取投递列表成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// requestDelivery 获取路径中的投递，失败时写入错误响应并返回 nil
+func (h *WebhookHandler) requestDelivery(w http.ResponseWriter, r *http.Request) *models.WebhookDelivery {
+	id, err := pathID(r.URL.Path, "deliveries")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"无效的投递 ID"}`))
+		return nil
+	}
+
+	delivery, err := h.deliveryRepo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","data":null,"message":"投递不存在"}`))
+		return nil
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"获取投递失败: ` + err.Error() + `"}`))
+		return nil
+	}
+	return delivery
+}
+
+// GetDelivery 获取 webhook 投递详情，包括原始负载和触发的执行
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery := h.requestDelivery(w, r)
+	if delivery == nil {
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    delivery,
+		"message": "获取投递成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
+
+// RedeliverDelivery 重新处理已保存的投递，不再验证签名和去重，重新匹配项目并启动新的执行
+func (h *WebhookHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery := h.requestDelivery(w, r)
+	if delivery == nil {
+		return
+	}
+
+	provider, ok := webhook.GetProvider(delivery.Provider)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","data":null,"message":"不支持的代码托管平台"}`))
+		return
+	}
+
+	delivery.Redeliveries++
+	if err := h.processDelivery(provider, delivery); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"error","data":null,"message":"处理投递失败: ` + err.Error() + `"}`))
+		return
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"data":    delivery,
+		"message": "重新投递成功",
+	}
+
+	data, _ := json.Marshal(response)
+	w.Write(data)
+}
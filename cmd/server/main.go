@@ -5,11 +5,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"ci-cd-orchestrator/cmd/server/api"
 	"ci-cd-orchestrator/internal/cicd/template"
 	"ci-cd-orchestrator/internal/db"
+	"ci-cd-orchestrator/internal/drift"
+	"ci-cd-orchestrator/internal/execution"
 	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/scheduler"
 	"ci-cd-orchestrator/pkg/migration"
 )
 
@@ -31,8 +38,22 @@ func main() {
 	// 初始化内置模板
 	initBuiltinTemplates(dbConn)
 
+	// 定期检查管道配置与项目目录中的配置文件是否一致
+	startDriftDetector(dbConn)
+
+	// 创建执行管理器，定时启动的执行与 API 启动的执行由同一个管理器管理
+	executionManager := api.NewExecutionManager(dbConn)
+
+	// 设置并发限制，同时启动上次服务停止时排队的执行
+	limits := queueLimits()
+	executionManager.SetQueueLimits(limits)
+	log.Printf("执行队列已启动，全局并发限制 %d，项目并发限制 %d", limits.Global, limits.PerProject)
+
+	// 定期触发到期的定时任务
+	startScheduler(dbConn, executionManager)
+
 	// 创建路由
-	router := api.SetupRouter(dbConn)
+	router := api.SetupRouter(dbConn, executionManager)
 
 	// 启动服务器
 	port := 8080 // 使用端口 8080
@@ -44,6 +65,81 @@ func main() {
 	}
 }
 
+// startDriftDetector 启动漂移检测，检查间隔由环境变量 DRIFT_CHECK_INTERVAL 设置（例如 30m），默认为 10 分钟，设置为 0 时不检查
+func startDriftDetector(dbConn *sql.DB) {
+	interval := 10 * time.Minute
+	if value := os.Getenv("DRIFT_CHECK_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("无效的漂移检查间隔 %q: %v", value, err)
+		}
+		interval = parsed
+	}
+	if interval <= 0 {
+		log.Println("漂移检测已禁用")
+		return
+	}
+
+	detector := drift.NewDetector(repository.NewProjectRepository(dbConn), repository.NewPipelineRepository(dbConn))
+	go detector.Run(interval, nil)
+	log.Printf("漂移检测已启动，检查间隔 %s", interval)
+}
+
+// startScheduler 启动定时任务调度，检查间隔由环境变量 SCHEDULE_CHECK_INTERVAL 设置（例如 1m），默认为 30 秒，设置为 0 时不调度。
+// 多个服务实例可以同时调度，同一个触发时间只会启动一次执行
+func startScheduler(dbConn *sql.DB, manager execution.Manager) {
+	interval := 30 * time.Second
+	if value := os.Getenv("SCHEDULE_CHECK_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("无效的定时任务检查间隔 %q: %v", value, err)
+		}
+		interval = parsed
+	}
+	if interval <= 0 {
+		log.Println("定时任务调度已禁用")
+		return
+	}
+
+	projectRepo := repository.NewProjectRepository(dbConn)
+	pipelineRepo := repository.NewPipelineRepository(dbConn)
+	taskScheduler := scheduler.NewScheduler(manager, projectRepo, pipelineRepo, repository.NewScheduleRepository(dbConn))
+	go taskScheduler.Run(interval, nil)
+	log.Printf("定时任务调度已启动，检查间隔 %s", interval)
+}
+
+// queueLimits 读取执行的并发限制。EXECUTION_MAX_CONCURRENT 为全局限制，默认为 10；
+// EXECUTION_MAX_PER_PROJECT 为每个项目的限制，默认不限制；EXECUTION_PLATFORM_LIMITS 为每个平台的限制，
+// 格式为 platform=limit，多个平台用逗号分隔，例如 local=2,github_actions=5。限制为 0 表示不限制
+func queueLimits() execution.QueueLimits {
+	limits := execution.QueueLimits{Global: 10, Platforms: map[string]int{}}
+	if value := os.Getenv("EXECUTION_MAX_CONCURRENT"); value != "" {
+		limits.Global = parseLimit("EXECUTION_MAX_CONCURRENT", value)
+	}
+	if value := os.Getenv("EXECUTION_MAX_PER_PROJECT"); value != "" {
+		limits.PerProject = parseLimit("EXECUTION_MAX_PER_PROJECT", value)
+	}
+	if value := os.Getenv("EXECUTION_PLATFORM_LIMITS"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			platform, limit, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok || platform == "" {
+				log.Fatalf("无效的平台并发限制 %q，格式为 platform=limit", item)
+			}
+			limits.Platforms[platform] = parseLimit("EXECUTION_PLATFORM_LIMITS", limit)
+		}
+	}
+	return limits
+}
+
+// parseLimit 解析并发限制，无效时退出
+func parseLimit(name, value string) int {
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || limit < 0 {
+		log.Fatalf("无效的 %s %q", name, value)
+	}
+	return limit
+}
+
 // initBuiltinTemplates 初始化内置模板
 func initBuiltinTemplates(dbConn *sql.DB) {
 	// 创建模板仓库
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/model"
 )
 
 // Platform 平台类型
@@ -19,6 +20,22 @@ type Adapter interface {
 	GetPlatform() Platform
 }
 
+// convert 通过管道模型将配置转换为目标平台配置，无法等价转换的内容记录在 Warnings 中
+func convert(config *PipelineConfig, target Platform, filename string) (*PipelineConfig, error) {
+	content, warnings, err := model.Convert(config.Content, config.Platform, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PipelineConfig{
+		Platform:   target,
+		ConfigType: common.ConfigTypeYAML,
+		Content:    content,
+		Filename:   filename,
+		Warnings:   warnings,
+	}, nil
+}
+
 // GitHubActionsAdapter GitHub Actions 适配器
 type GitHubActionsAdapter struct{}
 
@@ -34,14 +51,7 @@ func (a *GitHubActionsAdapter) ConvertToPlatform(config *PipelineConfig) (*Pipel
 		return config, nil
 	}
 
-	// 这里可以实现从其他平台转换为 GitHub Actions 配置的逻辑
-	// 现在暂时返回一个新的 GitHub Actions 配置
-	return &PipelineConfig{
-		Platform:   common.PlatformGitHubActions,
-		ConfigType: common.ConfigTypeYAML,
-		Content:    config.Content, // 这里应该转换内容
-		Filename:   ".github/workflows/ci.yml",
-	}, nil
+	return convert(config, common.PlatformGitHubActions, ".github/workflows/ci.yml")
 }
 
 // ConvertFromPlatform 从 GitHub Actions 配置转换
@@ -51,8 +61,7 @@ func (a *GitHubActionsAdapter) ConvertFromPlatform(config *PipelineConfig) (*Pip
 		return nil, fmt.Errorf("config is not a GitHub Actions config")
 	}
 
-	// 这里可以实现从 GitHub Actions 转换为其他平台配置的逻辑
-	// 现在暂时返回原配置
+	// GitHub Actions 是管道模型的规范格式，无需转换
 	return config, nil
 }
 
@@ -61,6 +70,39 @@ func (a *GitHubActionsAdapter) GetPlatform() Platform {
 	return common.PlatformGitHubActions
 }
 
+// GitLabCIAdapter GitLab CI 适配器
+type GitLabCIAdapter struct{}
+
+// NewGitLabCIAdapter 创建 GitLab CI 适配器实例
+func NewGitLabCIAdapter() Adapter {
+	return &GitLabCIAdapter{}
+}
+
+// ConvertToPlatform 转换为 GitLab CI 配置
+func (a *GitLabCIAdapter) ConvertToPlatform(config *PipelineConfig) (*PipelineConfig, error) {
+	// 如果已经是 GitLab CI 配置，直接返回
+	if config.Platform == common.PlatformGitLabCI {
+		return config, nil
+	}
+
+	return convert(config, common.PlatformGitLabCI, ".gitlab-ci.yml")
+}
+
+// ConvertFromPlatform 从 GitLab CI 配置转换为 GitHub Actions 配置
+func (a *GitLabCIAdapter) ConvertFromPlatform(config *PipelineConfig) (*PipelineConfig, error) {
+	// 检查配置是否是 GitLab CI 配置
+	if config.Platform != common.PlatformGitLabCI {
+		return nil, fmt.Errorf("config is not a GitLab CI config")
+	}
+
+	return convert(config, common.PlatformGitHubActions, ".github/workflows/ci.yml")
+}
+
+// GetPlatform 获取平台类型
+func (a *GitLabCIAdapter) GetPlatform() Platform {
+	return common.PlatformGitLabCI
+}
+
 // MockAdapter Mock 平台适配器
 type MockAdapter struct{}
 
@@ -76,26 +118,17 @@ func (a *MockAdapter) ConvertToPlatform(config *PipelineConfig) (*PipelineConfig
 		return config, nil
 	}
 
-	// 这里可以实现从其他平台转换为 Mock 平台配置的逻辑
-	// 现在暂时返回一个新的 Mock 平台配置
-	return &PipelineConfig{
-		Platform:   common.PlatformMock,
-		ConfigType: common.ConfigTypeYAML,
-		Content:    config.Content, // 这里应该转换内容
-		Filename:   "mock-ci.yml",
-	}, nil
+	return convert(config, common.PlatformMock, "mock-ci.yml")
 }
 
-// ConvertFromPlatform 从 Mock 平台配置转换
+// ConvertFromPlatform 从 Mock 平台配置转换为 GitHub Actions 配置
 func (a *MockAdapter) ConvertFromPlatform(config *PipelineConfig) (*PipelineConfig, error) {
 	// 检查配置是否是 Mock 平台配置
 	if config.Platform != common.PlatformMock {
 		return nil, fmt.Errorf("config is not a Mock config")
 	}
 
-	// 这里可以实现从 Mock 平台转换为其他平台配置的逻辑
-	// 现在暂时返回原配置
-	return config, nil
+	return convert(config, common.PlatformGitHubActions, ".github/workflows/ci.yml")
 }
 
 // GetPlatform 获取平台类型
@@ -108,6 +141,8 @@ func NewAdapter(platform Platform) (Adapter, error) {
 	switch platform {
 	case common.PlatformGitHubActions:
 		return NewGitHubActionsAdapter(), nil
+	case common.PlatformGitLabCI:
+		return NewGitLabCIAdapter(), nil
 	case common.PlatformMock:
 		return NewMockAdapter(), nil
 	default:
@@ -0,0 +1,178 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/validator"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGitHubActionsToGitLabCI(t *testing.T) {
+	config := &PipelineConfig{
+		Platform:   common.PlatformGitHubActions,
+		ConfigType: common.ConfigTypeYAML,
+		Filename:   ".github/workflows/ci.yml",
+		Content: `name: CI
+on:
+  push:
+    branches: [ main, 'release/*' ]
+  pull_request:
+env:
+  CGO_ENABLED: "0"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+    - uses: actions/checkout@v4
+    - uses: actions/setup-go@v5
+      with:
+        go-version: "1.22"
+    - name: Build
+      run: go build ./...
+  test:
+    runs-on: ubuntu-latest
+    needs: build
+    timeout-minutes: 15
+    strategy:
+      matrix:
+        os: [linux, darwin]
+    steps:
+    - name: Test
+      env:
+        GOOS: ${{ matrix.os }}
+      run: go test ./... -run ${{ github.sha }}
+`,
+	}
+
+	converted, err := NewGitLabCIAdapter().ConvertToPlatform(config)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if converted.Platform != common.PlatformGitLabCI || converted.Filename != ".gitlab-ci.yml" {
+		t.Errorf("平台或文件名不匹配: %s %s", converted.Platform, converted.Filename)
+	}
+	if err := validator.NewValidator().ValidateGitLabCIConfig(converted.Content).Err(); err != nil {
+		t.Fatalf("转换结果未通过验证: %v\n%s", err, converted.Content)
+	}
+
+	var result map[string]interface{}
+	if err := yaml.Unmarshal([]byte(converted.Content), &result); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+
+	build := result["build"].(map[string]interface{})
+	if build["image"] != "golang:1.22" {
+		t.Errorf("镜像不匹配: %v", build["image"])
+	}
+	if needs, ok := build["needs"].([]interface{}); !ok || len(needs) != 0 {
+		t.Errorf("没有依赖的 job 应使用 needs: [], 实际 %v", build["needs"])
+	}
+
+	test := result["test"].(map[string]interface{})
+	if needs := test["needs"].([]interface{}); len(needs) != 1 || needs[0] != "build" {
+		t.Errorf("needs 不匹配: %v", test["needs"])
+	}
+	if test["timeout"] != "15 minutes" {
+		t.Errorf("超时时间不匹配: %v", test["timeout"])
+	}
+	if _, ok := test["parallel"]; !ok {
+		t.Error("矩阵应转换为 parallel:matrix")
+	}
+	script := test["script"].([]interface{})
+	if script[0] != `export GOOS="$os"` || script[1] != "go test ./... -run $CI_COMMIT_SHA" {
+		t.Errorf("脚本不匹配: %v", script)
+	}
+
+	if !strings.Contains(converted.Content, `$CI_COMMIT_BRANCH =~ /^release\/[^\/]*$/`) {
+		t.Errorf("分支通配符未转换为正则规则:\n%s", converted.Content)
+	}
+}
+
+func TestGitLabCIToGitHubActions(t *testing.T) {
+	config := &PipelineConfig{
+		Platform:   common.PlatformGitLabCI,
+		ConfigType: common.ConfigTypeYAML,
+		Filename:   ".gitlab-ci.yml",
+		Content: `stages: [build, test, deploy]
+default:
+  image: golang:1.22
+.go-cache:
+  variables:
+    GOPATH: $CI_PROJECT_DIR/.go
+build:
+  extends: .go-cache
+  stage: build
+  script:
+    - go build ./...
+lint:
+  stage: build
+  script: golangci-lint run
+test:
+  stage: test
+  parallel:
+    matrix:
+      - GOOS: [linux, darwin]
+  script:
+    - go test ./...
+deploy:
+  stage: deploy
+  needs: [test]
+  timeout: 1h 30m
+  allow_failure: true
+  script:
+    - ./deploy.sh $CI_COMMIT_SHA
+`,
+	}
+
+	converted, err := NewGitHubActionsAdapter().ConvertToPlatform(config)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if err := validator.NewValidator().ValidateGitHubActionsConfig(converted.Content).Err(); err != nil {
+		t.Fatalf("转换结果未通过验证: %v\n%s", err, converted.Content)
+	}
+
+	var result struct {
+		Jobs map[string]struct {
+			Container       string            `yaml:"container"`
+			Needs           []string          `yaml:"needs"`
+			Env             map[string]string `yaml:"env"`
+			TimeoutMinutes  int               `yaml:"timeout-minutes"`
+			ContinueOnError bool              `yaml:"continue-on-error"`
+			Steps           []struct {
+				Run string `yaml:"run"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal([]byte(converted.Content), &result); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+
+	// 没有 needs 的 job 依赖上一个阶段的所有 job
+	if needs := result.Jobs["test"].Needs; len(needs) != 2 || needs[0] != "build" || needs[1] != "lint" {
+		t.Errorf("阶段依赖不匹配: %v", needs)
+	}
+	if needs := result.Jobs["build"].Needs; len(needs) != 0 {
+		t.Errorf("第一个阶段的 job 不应有依赖: %v", needs)
+	}
+	if result.Jobs["build"].Container != "golang:1.22" {
+		t.Errorf("默认镜像未继承: %v", result.Jobs["build"].Container)
+	}
+	if result.Jobs["build"].Env["GOPATH"] != "$GITHUB_WORKSPACE/.go" {
+		t.Errorf("extends 的变量未合并: %v", result.Jobs["build"].Env)
+	}
+	if result.Jobs["test"].Env["GOOS"] != "${{ matrix.GOOS }}" {
+		t.Errorf("矩阵变量未转换为 env: %v", result.Jobs["test"].Env)
+	}
+
+	deploy := result.Jobs["deploy"]
+	if deploy.TimeoutMinutes != 90 || !deploy.ContinueOnError {
+		t.Errorf("超时或 allow_failure 未转换: %+v", deploy)
+	}
+	if last := deploy.Steps[len(deploy.Steps)-1].Run; last != "./deploy.sh $GITHUB_SHA" {
+		t.Errorf("脚本不匹配: %s", last)
+	}
+}
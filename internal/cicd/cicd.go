@@ -1,6 +1,9 @@
 package cicd
 
 import (
+	"fmt"
+
+	"ci-cd-orchestrator/internal/cicd/adapter"
 	"ci-cd-orchestrator/internal/cicd/common"
 	"ci-cd-orchestrator/internal/cicd/template"
 	"ci-cd-orchestrator/internal/cicd/validator"
@@ -14,6 +17,7 @@ type Platform = common.Platform
 // 支持的 CI/CD 平台
 const (
 	PlatformGitHubActions Platform = common.PlatformGitHubActions
+	PlatformGitLabCI      Platform = common.PlatformGitLabCI
 	PlatformMock          Platform = common.PlatformMock
 )
 
@@ -29,10 +33,17 @@ const (
 // PipelineConfig CI/CD 管道配置
 type PipelineConfig = common.PipelineConfig
 
+// GenerateOptions 生成配置的选项
+type GenerateOptions struct {
+	TemplateID int                    // 指定使用的模板，为 0 时根据技术栈和平台选择
+	Parameters map[string]interface{} // 覆盖模板参数的值
+}
+
 // Generator CI/CD 管道配置生成器接口
 type Generator interface {
-	GenerateConfig(techStack *techstack.TechStack, platform Platform, templateID ...int) (*PipelineConfig, error)
-	ValidateConfig(config *PipelineConfig) error
+	GenerateConfig(techStack *techstack.TechStack, platform Platform, options GenerateOptions) (*PipelineConfig, error)
+	RenderTemplate(templateID int, techStack *techstack.TechStack, parameters map[string]interface{}) (*PipelineConfig, error)
+	ValidateConfig(config *PipelineConfig) validator.Diagnostics
 }
 
 // generatorImpl CI/CD 管道配置生成器实现
@@ -50,13 +61,13 @@ func NewGenerator(templateRepo repository.TemplateRepository) Generator {
 }
 
 // GenerateConfig 生成 CI/CD 管道配置
-func (g *generatorImpl) GenerateConfig(techStack *techstack.TechStack, platform Platform, templateID ...int) (*PipelineConfig, error) {
+func (g *generatorImpl) GenerateConfig(techStack *techstack.TechStack, platform Platform, options GenerateOptions) (*PipelineConfig, error) {
 	var tmpl *template.Template
 	var err error
 
 	// 如果提供了 templateID，直接使用该模板
-	if len(templateID) > 0 && templateID[0] > 0 {
-		tmpl, err = g.templateManager.GetTemplateByID(templateID[0])
+	if options.TemplateID > 0 {
+		tmpl, err = g.templateManager.GetTemplateByID(options.TemplateID)
 		if err != nil {
 			return nil, err
 		}
@@ -68,23 +79,79 @@ func (g *generatorImpl) GenerateConfig(techStack *techstack.TechStack, platform
 		}
 	}
 
-	// 生成配置
-	config := &PipelineConfig{
-		Platform:   platform,
-		ConfigType: tmpl.ConfigType,
-		Content:    tmpl.Content,
-		Filename:   tmpl.Filename,
+	// 片段只能被其他模板继承或引用
+	if tmpl.Kind == template.KindFragment {
+		return nil, &template.RenderError{Err: fmt.Errorf("template %d is a fragment and cannot generate a pipeline", tmpl.ID)}
+	}
+
+	// 使用参数渲染模板生成配置
+	config, err := g.render(tmpl, techStack, options.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// 模板平台与目标平台不一致时通过适配器转换
+	if config.Platform != platform {
+		platformAdapter, err := adapter.NewAdapter(platform)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := platformAdapter.ConvertToPlatform(config)
+		if err != nil {
+			return nil, err
+		}
+		converted.Parameters = config.Parameters
+		converted.TemplateID = config.TemplateID
+		converted.TemplateVersion = config.TemplateVersion
+		config = converted
+	}
+
+	// 验证配置，存在错误时返回 *validator.Error，警告随配置一起返回
+	diagnostics := g.validator.Validate(config)
+	if err := diagnostics.Err(); err != nil {
+		return nil, err
+	}
+	config.Diagnostics = diagnostics
+
+	return config, nil
+}
+
+// RenderTemplate 使用参数渲染指定模板，用于预览。渲染结果不做平台转换，
+// 验证错误不会导致失败，与警告一起随配置返回
+func (g *generatorImpl) RenderTemplate(templateID int, techStack *techstack.TechStack, parameters map[string]interface{}) (*PipelineConfig, error) {
+	tmpl, err := g.templateManager.GetTemplateByID(templateID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 验证配置
-	if err := g.validator.Validate(config); err != nil {
+	config, err := g.render(tmpl, techStack, parameters)
+	if err != nil {
 		return nil, err
 	}
+	config.Diagnostics = g.validator.Validate(config)
 
 	return config, nil
 }
 
-// ValidateConfig 验证 CI/CD 管道配置
-func (g *generatorImpl) ValidateConfig(config *PipelineConfig) error {
+// render 渲染模板并记录使用的参数值和模板版本，继承的基础模板和引用的片段使用其最新内容
+func (g *generatorImpl) render(tmpl *template.Template, techStack *techstack.TechStack, parameters map[string]interface{}) (*PipelineConfig, error) {
+	content, resolved, err := tmpl.Render(g.templateManager, techStack, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PipelineConfig{
+		Platform:        tmpl.Platform,
+		ConfigType:      tmpl.ConfigType,
+		Content:         content,
+		Filename:        tmpl.Filename,
+		Parameters:      resolved,
+		TemplateID:      tmpl.ID,
+		TemplateVersion: tmpl.Version,
+	}, nil
+}
+
+// ValidateConfig 验证 CI/CD 管道配置，返回所有诊断
+func (g *generatorImpl) ValidateConfig(config *PipelineConfig) validator.Diagnostics {
 	return g.validator.Validate(config)
 }
@@ -6,9 +6,22 @@ type Platform string
 // 支持的 CI/CD 平台
 const (
 	PlatformGitHubActions Platform = "github_actions"
+	PlatformGitLabCI      Platform = "gitlab_ci"
 	PlatformMock          Platform = "mock"
 )
 
+// DefaultFilename 平台配置文件在项目目录中的默认路径
+func DefaultFilename(platform Platform) string {
+	switch platform {
+	case PlatformGitLabCI:
+		return ".gitlab-ci.yml"
+	case PlatformMock:
+		return ".mock/workflows/ci.yaml"
+	default:
+		return ".github/workflows/ci.yml"
+	}
+}
+
 // ConfigType 配置类型
 type ConfigType string
 
@@ -20,8 +33,64 @@ const (
 
 // PipelineConfig CI/CD 管道配置
 type PipelineConfig struct {
-	Platform   Platform   `json:"platform"`
-	ConfigType ConfigType `json:"config_type"`
-	Content    string     `json:"content"`
-	Filename   string     `json:"filename"`
+	Platform    Platform     `json:"platform"`
+	ConfigType  ConfigType   `json:"config_type"`
+	Content     string       `json:"content"`
+	Filename    string       `json:"filename"`
+	Warnings    []Warning    `json:"warnings,omitempty"`    // 跨平台转换时无法等价转换的内容
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"` // 配置验证的诊断信息
+	Findings    []Finding    `json:"findings,omitempty"`    // 安全检查发现的问题
+	Parameters  []Parameter  `json:"parameters,omitempty"`  // 渲染模板时使用的参数值
+
+	TemplateID      int `json:"template_id,omitempty"`      // 生成配置使用的模板
+	TemplateVersion int `json:"template_version,omitempty"` // 生成配置使用的模板版本
+	Revision        int `json:"revision,omitempty"`         // 保存后的管道配置修订
+}
+
+// Warning 配置转换警告
+type Warning struct {
+	Job     string `json:"job,omitempty"`
+	Message string `json:"message"`
+}
+
+// Severity 诊断级别
+type Severity string
+
+// 支持的诊断级别
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic 配置验证诊断，行号和列号从 1 开始，无法定位时为 0
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// Finding 安全检查发现的问题，行号和列号从 1 开始，无法定位时为 0
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Job      string   `json:"job,omitempty"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Message  string   `json:"message"`
+}
+
+// 参数值的来源
+const (
+	ParameterSourceDefault   = "default"
+	ParameterSourceTechStack = "tech_stack"
+	ParameterSourceOverride  = "override"
+)
+
+// Parameter 渲染模板时使用的参数值及其来源
+type Parameter struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
 }
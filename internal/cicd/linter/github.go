@@ -0,0 +1,215 @@
+package linter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedActions 官方 action 的最低推荐主版本，更早的主版本运行在已弃用的 Node.js 版本上
+var deprecatedActions = map[string]int{
+	"actions/checkout":          4,
+	"actions/setup-go":          5,
+	"actions/setup-java":        4,
+	"actions/setup-python":      5,
+	"actions/setup-node":        4,
+	"actions/cache":             4,
+	"actions/upload-artifact":   4,
+	"actions/download-artifact": 4,
+	"actions/github-script":     7,
+}
+
+// commitSHA 完整的 commit SHA
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// untrustedExpression run 脚本中不可信的表达式，事件中的编号和 ID 是数字，不会被注入
+var untrustedExpression = regexp.MustCompile(`\$\{\{[^}]*?\b(github\.event\.[A-Za-z0-9_.*\[\]'-]+|github\.head_ref)[^}]*\}\}`)
+
+// prHeadReference 指向 PR 代码的 checkout 参数
+var prHeadReference = regexp.MustCompile(`github\.event\.pull_request\.head\.|github\.head_ref|refs/pull/`)
+
+// lintGitHubActions 检查 GitHub Actions workflow
+func (l *linter) lintGitHubActions(document *yaml.Node) {
+	pullRequestTarget := hasTrigger(mappingValue(document, "on"), "pull_request_target")
+
+	// workflow 级别的 permissions 对所有 job 生效
+	workflowPermissions := mappingValue(document, "permissions")
+	if workflowPermissions != nil {
+		l.checkPermissions(workflowPermissions, "", true)
+	}
+
+	jobs := mappingValue(document, "jobs")
+	if jobs != nil && jobs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobs.Content); i += 2 {
+			key, job := jobs.Content[i], resolveAlias(jobs.Content[i+1])
+			l.lintGitHubJob(key, job, workflowPermissions == nil, pullRequestTarget)
+		}
+	}
+
+	l.checkSecrets(document, func(path []string) string {
+		if len(path) >= 2 && path[0] == "jobs" {
+			return path[1]
+		}
+		return ""
+	})
+}
+
+// lintGitHubJob 检查单个 job
+func (l *linter) lintGitHubJob(key, job *yaml.Node, inheritsPermissions, pullRequestTarget bool) {
+	name := key.Value
+	if job == nil || job.Kind != yaml.MappingNode {
+		return
+	}
+
+	if permissions := mappingValue(job, "permissions"); permissions != nil {
+		l.checkPermissions(permissions, name, false)
+	} else if inheritsPermissions {
+		l.report(RuleMissingPermissions, key, name, "job '%s' does not declare permissions and the workflow has no top-level permissions, the GITHUB_TOKEN gets the repository default", name)
+	}
+
+	// 调用可复用 workflow 的 job
+	if uses := mappingValue(job, "uses"); uses != nil {
+		l.checkUses(uses, name)
+	}
+
+	steps := mappingValue(job, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, step := range steps.Content {
+		step = resolveAlias(step)
+		if uses := mappingValue(step, "uses"); uses != nil {
+			l.checkUses(uses, name)
+			if pullRequestTarget && actionName(uses.Value) == "actions/checkout" {
+				l.checkPullRequestTargetCheckout(mappingValue(step, "with"), name)
+			}
+			// actions/github-script 的 script 与 run 一样会执行表达式展开后的代码
+			if actionName(uses.Value) == "actions/github-script" {
+				l.checkInjection(mappingValue(mappingValue(step, "with"), "script"), name)
+			}
+		}
+		l.checkInjection(mappingValue(step, "run"), name)
+	}
+}
+
+// checkUses 检查 action 是否固定到 commit SHA 以及主版本是否已弃用
+func (l *linter) checkUses(node *yaml.Node, job string) {
+	uses := strings.TrimSpace(node.Value)
+	// 本地 action 和 workflow 随仓库代码一起变化
+	if strings.HasPrefix(uses, "./") {
+		return
+	}
+	if strings.HasPrefix(uses, "docker://") {
+		if !strings.Contains(uses, "@sha256:") {
+			l.report(RuleUnpinnedAction, node, job, "docker action '%s' is not pinned to an image digest", uses)
+		}
+		return
+	}
+
+	index := strings.LastIndex(uses, "@")
+	if index < 0 {
+		l.report(RuleUnpinnedAction, node, job, "action '%s' has no version, pin it to a full commit SHA", uses)
+		return
+	}
+	ref := uses[index+1:]
+	if commitSHA.MatchString(ref) {
+		return
+	}
+	l.report(RuleUnpinnedAction, node, job, "action '%s' is pinned to '%s' instead of a full commit SHA", uses[:index], ref)
+
+	name := actionName(uses)
+	if minimum, ok := deprecatedActions[name]; ok {
+		if major, ok := majorVersion(ref); ok && major < minimum {
+			l.report(RuleDeprecatedAction, node, job, "%s@%s is deprecated, use v%d or later", name, ref, minimum)
+		}
+	}
+}
+
+// checkInjection 检查脚本中是否直接使用了不可信的表达式
+func (l *linter) checkInjection(node *yaml.Node, job string) {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return
+	}
+	for _, match := range untrustedExpression.FindAllStringSubmatch(node.Value, -1) {
+		field := match[1]
+		if strings.HasSuffix(field, ".number") || strings.HasSuffix(field, ".id") {
+			continue
+		}
+		l.reportIn(RuleScriptInjection, node, match[0], job, "'%s' is expanded directly into the script and can inject commands, pass it through an environment variable instead", field)
+	}
+}
+
+// checkPermissions 检查 permissions 是否过宽
+func (l *linter) checkPermissions(node *yaml.Node, job string, workflowLevel bool) {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value == "write-all" {
+			l.report(RuleBroadPermissions, node, job, "'write-all' grants the GITHUB_TOKEN write access to every scope, list only the scopes that are needed")
+		}
+		return
+	}
+	if !workflowLevel || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if resolveAlias(node.Content[i+1]).Value == "write" {
+			l.report(RuleBroadPermissions, node.Content[i], job, "workflow-level '%s: write' applies to every job, grant it only on the jobs that need it", node.Content[i].Value)
+		}
+	}
+}
+
+// checkPullRequestTargetCheckout 检查 pull_request_target 触发时是否检出了 PR 的代码。
+// pull_request_target 使用基础分支的权限和 secrets 运行，执行 PR 中的代码会泄露 secrets
+func (l *linter) checkPullRequestTargetCheckout(with *yaml.Node, job string) {
+	for _, key := range []string{"ref", "repository"} {
+		value := mappingValue(with, key)
+		if value != nil && prHeadReference.MatchString(value.Value) {
+			l.report(RulePullRequestTargetCheckout, value, job, "workflow triggered by pull_request_target checks out the pull request head with '%s: %s', untrusted code runs with access to secrets", key, value.Value)
+			return
+		}
+	}
+}
+
+// hasTrigger 判断 on 中是否包含指定事件
+func hasTrigger(on *yaml.Node, event string) bool {
+	if on == nil {
+		return false
+	}
+	switch on.Kind {
+	case yaml.ScalarNode:
+		return on.Value == event
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			if resolveAlias(item).Value == event {
+				return true
+			}
+		}
+	case yaml.MappingNode:
+		key, _ := mappingEntry(on, event)
+		return key != nil
+	}
+	return false
+}
+
+// actionName 获取 action 名称（不含版本和子目录），例如 actions/checkout@v4 → actions/checkout
+func actionName(uses string) string {
+	if index := strings.Index(uses, "@"); index >= 0 {
+		uses = uses[:index]
+	}
+	parts := strings.Split(uses, "/")
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, "/")
+}
+
+// majorVersion 解析 v2、v2.1.0 形式的版本号中的主版本
+func majorVersion(ref string) (int, bool) {
+	ref = strings.TrimPrefix(ref, "v")
+	if index := strings.Index(ref, "."); index >= 0 {
+		ref = ref[:index]
+	}
+	major, err := strconv.Atoi(ref)
+	return major, err == nil
+}
@@ -0,0 +1,62 @@
+package linter
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabGlobalKeywords .gitlab-ci.yml 中不是 job 的顶层关键字
+var gitlabGlobalKeywords = map[string]bool{
+	"default":       true,
+	"include":       true,
+	"stages":        true,
+	"variables":     true,
+	"workflow":      true,
+	"image":         true,
+	"services":      true,
+	"cache":         true,
+	"before_script": true,
+	"after_script":  true,
+}
+
+// lintGitLabCI 检查 GitLab CI 配置。GitLab CI 没有 action 和 GITHUB_TOKEN 权限的概念，
+// 只检查其他项目的 include 是否固定到 commit SHA 以及硬编码的密钥
+func (l *linter) lintGitLabCI(document *yaml.Node) {
+	if include := mappingValue(document, "include"); include != nil {
+		items := []*yaml.Node{include}
+		if include.Kind == yaml.SequenceNode {
+			items = include.Content
+		}
+		for _, item := range items {
+			l.checkGitLabInclude(resolveAlias(item))
+		}
+	}
+
+	l.checkSecrets(document, func(path []string) string {
+		if len(path) >= 1 && !gitlabGlobalKeywords[path[0]] {
+			return path[0]
+		}
+		return ""
+	})
+}
+
+// checkGitLabInclude 检查 include 的其他项目或远程文件是否固定版本
+func (l *linter) checkGitLabInclude(item *yaml.Node) {
+	if item.Kind != yaml.MappingNode {
+		return
+	}
+	if remote := mappingValue(item, "remote"); remote != nil {
+		l.report(RuleUnpinnedAction, remote, "", "remote include '%s' can change at any time, include it from a project pinned to a commit SHA instead", remote.Value)
+		return
+	}
+	project := mappingValue(item, "project")
+	if project == nil {
+		return
+	}
+	ref := mappingValue(item, "ref")
+	switch {
+	case ref == nil:
+		l.report(RuleUnpinnedAction, project, "", "include from project '%s' has no ref and follows the default branch, pin it to a full commit SHA", project.Value)
+	case !commitSHA.MatchString(ref.Value):
+		l.report(RuleUnpinnedAction, ref, "", "include from project '%s' is pinned to '%s' instead of a full commit SHA", project.Value, ref.Value)
+	}
+}
@@ -0,0 +1,153 @@
+// Package linter 对 CI/CD 管道配置进行安全检查
+package linter
+
+import (
+	"fmt"
+	"strings"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Finding 安全检查发现的问题
+type Finding = common.Finding
+
+// 安全检查规则 ID
+const (
+	RuleUnpinnedAction            = "unpinned-action"
+	RuleDeprecatedAction          = "deprecated-action"
+	RuleScriptInjection           = "script-injection"
+	RuleMissingPermissions        = "missing-permissions"
+	RuleBroadPermissions          = "broad-permissions"
+	RulePullRequestTargetCheckout = "pull-request-target-checkout"
+	RuleHardcodedSecret           = "hardcoded-secret"
+)
+
+// Rule 安全检查规则
+type Rule struct {
+	ID          string          `json:"id"`
+	Severity    common.Severity `json:"severity"`
+	Description string          `json:"description"`
+}
+
+// Rules 所有安全检查规则，默认全部启用
+var Rules = []Rule{
+	{RuleUnpinnedAction, common.SeverityWarning, "action 或可复用 workflow 没有固定到完整的 commit SHA"},
+	{RuleDeprecatedAction, common.SeverityWarning, "使用了已弃用的 action 主版本"},
+	{RuleScriptInjection, common.SeverityError, "run 脚本中直接使用了 ${{ github.event.* }} 等不可信输入"},
+	{RuleMissingPermissions, common.SeverityWarning, "workflow 和 job 都没有声明 permissions，GITHUB_TOKEN 使用仓库的默认权限"},
+	{RuleBroadPermissions, common.SeverityWarning, "使用 write-all 或在 workflow 级别授予写权限"},
+	{RulePullRequestTargetCheckout, common.SeverityError, "pull_request_target 触发的 workflow 检出了 PR 的代码"},
+	{RuleHardcodedSecret, common.SeverityError, "配置中包含硬编码的密钥或令牌"},
+}
+
+// FindRule 根据 ID 获取规则
+func FindRule(id string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Lint 检查配置中的安全问题，disabled 中的规则不会检查。配置不是合法的 YAML 时返回错误
+func Lint(platform common.Platform, content string, disabled map[string]bool) ([]Finding, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	l := &linter{lines: strings.Split(content, "\n"), disabled: disabled}
+	document := root.Content[0]
+	switch platform {
+	case common.PlatformGitHubActions, common.PlatformMock:
+		l.lintGitHubActions(document)
+	case common.PlatformGitLabCI:
+		l.lintGitLabCI(document)
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", platform)
+	}
+	return l.findings, nil
+}
+
+// linter 单次检查的状态
+type linter struct {
+	lines    []string // 原始配置的各行，用于定位多行脚本中的问题
+	disabled map[string]bool
+	findings []Finding
+}
+
+// enabled 规则是否启用
+func (l *linter) enabled(rule string) bool {
+	return !l.disabled[rule]
+}
+
+// report 记录问题，级别使用规则的默认级别
+func (l *linter) report(rule string, node *yaml.Node, job, format string, args ...interface{}) {
+	if !l.enabled(rule) {
+		return
+	}
+	finding := Finding{Rule: rule, Job: job, Message: fmt.Sprintf(format, args...)}
+	if definition, ok := FindRule(rule); ok {
+		finding.Severity = definition.Severity
+	}
+	if node != nil {
+		finding.Line, finding.Column = node.Line, node.Column
+	}
+	l.findings = append(l.findings, finding)
+}
+
+// reportIn 记录标量中某段文本的问题，尽量定位到文本所在的行和列（多行脚本中的问题定位到具体的行）
+func (l *linter) reportIn(rule string, node *yaml.Node, text, job, format string, args ...interface{}) {
+	if !l.enabled(rule) {
+		return
+	}
+	l.report(rule, node, job, format, args...)
+	finding := &l.findings[len(l.findings)-1]
+
+	// 块标量的内容从下一行开始，多行的普通标量从当前行开始
+	start := node.Line - 1
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		start++
+	}
+	end := start + strings.Count(node.Value, "\n") + 1
+	for i := start; i >= 0 && i < len(l.lines) && i <= end; i++ {
+		if index := strings.Index(l.lines[i], text); index >= 0 {
+			finding.Line, finding.Column = i+1, index+1
+			return
+		}
+	}
+}
+
+// mappingEntry 获取映射节点中指定键的键节点和值节点
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil, nil
+}
+
+// mappingValue 获取映射节点中指定键的值节点
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
+
+// resolveAlias 解析 YAML 别名
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
@@ -0,0 +1,81 @@
+package linter
+
+import (
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretPatterns 常见令牌和密钥的格式
+var secretPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+}
+
+// secretKey 通常保存密钥的键名
+var secretKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)$`)
+
+// variableReference 引用变量或表达式的值，不是硬编码的密钥
+var variableReference = regexp.MustCompile(`\$\{\{|^\$\{?[A-Za-z_][A-Za-z0-9_]*\}?$`)
+
+// checkSecrets 递归检查配置中的硬编码密钥，jobOf 根据节点路径返回所属的 job
+func (l *linter) checkSecrets(document *yaml.Node, jobOf func(path []string) string) {
+	l.walkSecrets(document, nil, "", jobOf)
+}
+
+// walkSecrets 遍历节点，path 为从根节点到当前节点的键
+func (l *linter) walkSecrets(node *yaml.Node, path []string, key string, jobOf func(path []string) string) {
+	if !l.enabled(RuleHardcodedSecret) {
+		return
+	}
+	node = resolveAlias(node)
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			l.walkSecrets(node.Content[i+1], append(path[:len(path):len(path)], childKey), childKey, jobOf)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			l.walkSecrets(item, path, key, jobOf)
+		}
+	case yaml.ScalarNode:
+		l.checkSecretValue(node, key, jobOf(path))
+	}
+}
+
+// checkSecretValue 检查单个值是否为硬编码的密钥
+func (l *linter) checkSecretValue(node *yaml.Node, key, job string) {
+	for _, secret := range secretPatterns {
+		if match := secret.pattern.FindString(node.Value); match != "" {
+			l.reportIn(RuleHardcodedSecret, node, match, job, "hardcoded %s '%s', store it as a CI/CD secret instead", secret.name, mask(match))
+			return
+		}
+	}
+
+	// 键名表明是密钥且值是字面量
+	value := strings.TrimSpace(node.Value)
+	if secretKey.MatchString(key) && node.Tag == "!!str" && len(value) >= 8 &&
+		!strings.ContainsAny(value, " \n") && !variableReference.MatchString(value) {
+		l.report(RuleHardcodedSecret, node, job, "'%s' has a hardcoded value '%s', store it as a CI/CD secret instead", key, mask(value))
+	}
+}
+
+// mask 隐藏密钥的大部分内容，只保留前 4 个字符
+func mask(value string) string {
+	if len(value) <= 4 {
+		return "****"
+	}
+	return value[:4] + strings.Repeat("*", min(len(value)-4, 8))
+}
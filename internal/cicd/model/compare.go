@@ -0,0 +1,243 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 差异类型
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Diff 两个管道之间的语义差异，只比较解析后的模型，格式、注释和键的顺序不影响结果
+type Diff struct {
+	Fields []string    `json:"fields,omitempty"` // 发生变化的管道级字段
+	Jobs   []JobChange `json:"jobs,omitempty"`
+}
+
+// JobChange job 的差异
+type JobChange struct {
+	Job    string       `json:"job"`
+	Change string       `json:"change"`
+	Fields []string     `json:"fields,omitempty"` // 发生变化的 job 字段，不包括 steps
+	Steps  []StepChange `json:"steps,omitempty"`
+}
+
+// StepChange 步骤的差异。From 和 To 为步骤在两个 job 中的位置（从 1 开始），新增的步骤 From 为 0，删除的步骤 To 为 0
+type StepChange struct {
+	Step   string   `json:"step"`
+	Change string   `json:"change"`
+	From   int      `json:"from,omitempty"`
+	To     int      `json:"to,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Empty 判断两个管道是否语义相同
+func (d *Diff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Jobs) == 0
+}
+
+// Compare 比较两个管道。job 按 ID 对应；步骤按名称（没有名称时按 uses 或 run）对齐，
+// 对齐后两个相同步骤之间删除和新增的步骤依次配对为修改
+func Compare(from, to *Pipeline) *Diff {
+	diff := &Diff{
+		Fields: changedFields([]field{
+			{"name", from.Name, to.Name},
+			{"triggers", from.Triggers, to.Triggers},
+			{"env", from.Env, to.Env},
+			{"stages", from.Stages, to.Stages},
+		}),
+	}
+
+	for _, job := range from.Jobs {
+		target := to.Job(job.ID)
+		if target == nil {
+			diff.Jobs = append(diff.Jobs, JobChange{Job: job.ID, Change: ChangeRemoved})
+			continue
+		}
+		if change := compareJob(job, target); change != nil {
+			diff.Jobs = append(diff.Jobs, *change)
+		}
+	}
+	for _, job := range to.Jobs {
+		if from.Job(job.ID) == nil {
+			diff.Jobs = append(diff.Jobs, JobChange{Job: job.ID, Change: ChangeAdded})
+		}
+	}
+
+	return diff
+}
+
+// field 参与比较的字段
+type field struct {
+	name     string
+	from, to interface{}
+}
+
+// changedFields 返回值不同的字段名称
+func changedFields(fields []field) []string {
+	var changed []string
+	for _, f := range fields {
+		if !sameValue(f.from, f.to) {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+// sameValue 比较两个值，nil 与空的切片或映射视为相同
+func sameValue(a, b interface{}) bool {
+	if isEmpty(a) && isEmpty(b) {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isEmpty 判断值是否为零值或空的切片、映射
+func isEmpty(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// compareJob 比较同一 ID 的两个 job，相同时返回 nil
+func compareJob(from, to *Job) *JobChange {
+	change := &JobChange{
+		Job:    from.ID,
+		Change: ChangeChanged,
+		Fields: changedFields([]field{
+			{"name", from.Name, to.Name},
+			{"stage", from.Stage, to.Stage},
+			{"runs-on", from.RunsOn, to.RunsOn},
+			{"image", from.Image, to.Image},
+			{"services", from.Services, to.Services},
+			{"needs", from.Needs, to.Needs},
+			{"env", from.Env, to.Env},
+			{"matrix", from.Matrix, to.Matrix},
+			{"caches", from.Caches, to.Caches},
+			{"artifacts", from.Artifacts, to.Artifacts},
+			{"resources", from.Resources, to.Resources},
+			{"timeout-minutes", from.TimeoutMinutes, to.TimeoutMinutes},
+			{"continue-on-error", from.ContinueOnError, to.ContinueOnError},
+			{"if", from.If, to.If},
+		}),
+		Steps: compareSteps(from.Steps, to.Steps),
+	}
+
+	if len(change.Fields) == 0 && len(change.Steps) == 0 {
+		return nil
+	}
+	return change
+}
+
+// compareSteps 按最长公共子序列对齐两个步骤列表
+func compareSteps(from, to []Step) []StepChange {
+	// lengths[i][j] 为 from[i:] 和 to[j:] 的最长公共子序列长度
+	lengths := make([][]int, len(from)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if stepKey(from[i]) == stepKey(to[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var changes []StepChange
+	var removed, added []int
+
+	// flush 将两个对齐步骤之间删除和新增的步骤依次配对为修改，多出的记为删除或新增
+	flush := func() {
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			switch {
+			case k < len(removed) && k < len(added):
+				changes = append(changes, stepChange(from, to, removed[k], added[k]))
+			case k < len(removed):
+				changes = append(changes, StepChange{Step: stepLabel(from[removed[k]]), Change: ChangeRemoved, From: removed[k] + 1})
+			default:
+				changes = append(changes, StepChange{Step: stepLabel(to[added[k]]), Change: ChangeAdded, To: added[k] + 1})
+			}
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && stepKey(from[i]) == stepKey(to[j]):
+			flush()
+			if change := stepChange(from, to, i, j); len(change.Fields) > 0 {
+				changes = append(changes, change)
+			}
+			i++
+			j++
+		case j == len(to) || (i < len(from) && lengths[i+1][j] >= lengths[i][j+1]):
+			removed = append(removed, i)
+			i++
+		default:
+			added = append(added, j)
+			j++
+		}
+	}
+	flush()
+
+	return changes
+}
+
+// stepChange 比较两个对应的步骤
+func stepChange(from, to []Step, i, j int) StepChange {
+	a, b := from[i], to[j]
+	return StepChange{
+		Step:   stepLabel(b),
+		Change: ChangeChanged,
+		From:   i + 1,
+		To:     j + 1,
+		Fields: changedFields([]field{
+			{"name", a.Name, b.Name},
+			{"run", strings.TrimSpace(a.Run), strings.TrimSpace(b.Run)},
+			{"uses", a.Uses, b.Uses},
+			{"with", a.With, b.With},
+			{"env", a.Env, b.Env},
+			{"working-directory", a.WorkingDirectory, b.WorkingDirectory},
+			{"if", a.If, b.If},
+		}),
+	}
+}
+
+// stepKey 对齐步骤时使用的标识
+func stepKey(step Step) string {
+	switch {
+	case step.Name != "":
+		return "name:" + step.Name
+	case step.Uses != "":
+		return "uses:" + step.Uses
+	default:
+		return "run:" + strings.TrimSpace(step.Run)
+	}
+}
+
+// stepLabel 步骤的显示名称，没有名称时为 uses 或 run 的第一行
+func stepLabel(step Step) string {
+	switch {
+	case step.Name != "":
+		return step.Name
+	case step.Uses != "":
+		return step.Uses
+	default:
+		line, _, _ := strings.Cut(strings.TrimSpace(step.Run), "\n")
+		return line
+	}
+}
@@ -0,0 +1,683 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubWorkflow GitHub Actions workflow
+type githubWorkflow struct {
+	Name string    `yaml:"name"`
+	On   yaml.Node `yaml:"on"`
+	Env  stringMap `yaml:"env"`
+	Jobs yaml.Node `yaml:"jobs"`
+}
+
+// githubJob GitHub Actions job
+type githubJob struct {
+	Name            string                     `yaml:"name"`
+	RunsOn          stringList                 `yaml:"runs-on"`
+	Needs           stringList                 `yaml:"needs"`
+	If              string                     `yaml:"if"`
+	Env             stringMap                  `yaml:"env"`
+	Container       githubContainer            `yaml:"container"`
+	Services        map[string]githubContainer `yaml:"services"`
+	Strategy        githubStrategy             `yaml:"strategy"`
+	Resources       githubResources            `yaml:"resources"`
+	TimeoutMinutes  int                        `yaml:"timeout-minutes"`
+	ContinueOnError bool                       `yaml:"continue-on-error"`
+	Steps           []githubStep               `yaml:"steps"`
+}
+
+// githubContainer 容器或服务配置，可以写成镜像名或映射
+type githubContainer struct {
+	Image string     `yaml:"image"`
+	Env   stringMap  `yaml:"env"`
+	Ports stringList `yaml:"ports"`
+}
+
+// UnmarshalYAML 解析镜像名或映射
+func (c *githubContainer) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Image = value.Value
+		return nil
+	}
+	type plain githubContainer
+	return value.Decode((*plain)(c))
+}
+
+// githubStrategy 矩阵策略
+type githubStrategy struct {
+	Matrix      yaml.Node `yaml:"matrix"`
+	FailFast    *bool     `yaml:"fail-fast"`
+	MaxParallel int       `yaml:"max-parallel"`
+}
+
+// githubResources 资源配置（本系统对 workflow 的扩展）
+type githubResources struct {
+	Limits   githubResourceSpec `yaml:"limits"`
+	Requests githubResourceSpec `yaml:"requests"`
+}
+
+// githubResourceSpec CPU 和内存配置
+type githubResourceSpec struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+// githubStep GitHub Actions step
+type githubStep struct {
+	Name             string    `yaml:"name"`
+	Uses             string    `yaml:"uses"`
+	Run              string    `yaml:"run"`
+	With             stringMap `yaml:"with"`
+	Env              stringMap `yaml:"env"`
+	WorkingDirectory string    `yaml:"working-directory"`
+	If               string    `yaml:"if"`
+}
+
+// githubWorkflowKeys 模型支持的 workflow 顶层字段
+var githubWorkflowKeys = map[string]bool{"name": true, "on": true, "env": true, "jobs": true}
+
+// githubJobKeys 模型支持的 job 字段
+var githubJobKeys = map[string]bool{
+	"name": true, "runs-on": true, "needs": true, "if": true, "env": true, "container": true, "services": true,
+	"strategy": true, "resources": true, "timeout-minutes": true, "continue-on-error": true, "steps": true,
+}
+
+// ParseGitHubActions 将 GitHub Actions workflow 解析为管道模型
+func ParseGitHubActions(content string) (*Pipeline, []Warning, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse GitHub Actions config: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("GitHub Actions config must be a mapping")
+	}
+	document := root.Content[0]
+
+	var workflow githubWorkflow
+	if err := document.Decode(&workflow); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse GitHub Actions config: %w", err)
+	}
+	if workflow.Jobs.Kind != yaml.MappingNode || len(workflow.Jobs.Content) == 0 {
+		return nil, nil, fmt.Errorf("GitHub Actions config must have a 'jobs' field")
+	}
+
+	var warns warnings
+	for _, key := range mappingKeys(document) {
+		if !githubWorkflowKeys[key] {
+			warns.add("", "workflow key '%s' is not supported and was dropped", key)
+		}
+	}
+
+	pipeline := &Pipeline{
+		Name:     workflow.Name,
+		Triggers: parseGitHubTriggers(&workflow.On, &warns),
+		Env:      workflow.Env,
+	}
+
+	for i := 0; i+1 < len(workflow.Jobs.Content); i += 2 {
+		id := workflow.Jobs.Content[i].Value
+		node := workflow.Jobs.Content[i+1]
+		for _, key := range mappingKeys(node) {
+			if !githubJobKeys[key] {
+				warns.add(id, "job key '%s' is not supported and was dropped", key)
+			}
+		}
+
+		var source githubJob
+		if err := node.Decode(&source); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse job %s: %w", id, err)
+		}
+		pipeline.Jobs = append(pipeline.Jobs, parseGitHubJob(id, source, &warns))
+	}
+
+	if _, err := topologicalOrder(pipeline.Jobs); err != nil {
+		return nil, nil, err
+	}
+
+	return pipeline, warns, nil
+}
+
+// parseGitHubJob 转换单个 job，actions/cache 和 actions/upload-artifact 步骤转换为缓存和产物
+func parseGitHubJob(id string, source githubJob, warns *warnings) *Job {
+	job := &Job{
+		ID:              id,
+		Name:            source.Name,
+		RunsOn:          source.RunsOn,
+		Image:           source.Container.Image,
+		Needs:           source.Needs,
+		Env:             source.Env,
+		TimeoutMinutes:  source.TimeoutMinutes,
+		ContinueOnError: source.ContinueOnError,
+		If:              source.If,
+	}
+
+	for _, name := range sortedKeys(source.Services) {
+		service := source.Services[name]
+		job.Services = append(job.Services, Service{Name: name, Image: service.Image, Env: service.Env, Ports: service.Ports})
+	}
+
+	if source.Strategy.Matrix.Kind == yaml.MappingNode {
+		job.Matrix = parseGitHubMatrix(id, &source.Strategy.Matrix, warns)
+		job.Matrix.FailFast = source.Strategy.FailFast
+		job.Matrix.MaxParallel = source.Strategy.MaxParallel
+	}
+
+	resources := source.Resources
+	if resources != (githubResources{}) {
+		job.Resources = &Resources{
+			Requests: ResourceSpec{CPU: resources.Requests.CPU, Memory: resources.Requests.Memory},
+			Limits:   ResourceSpec{CPU: resources.Limits.CPU, Memory: resources.Limits.Memory},
+		}
+	}
+
+	for _, step := range source.Steps {
+		switch actionName(step.Uses) {
+		case "actions/cache":
+			job.Caches = append(job.Caches, Cache{Key: step.With["key"], Paths: splitLines(step.With["path"])})
+			if step.With["restore-keys"] != "" {
+				warns.add(id, "cache restore-keys are not supported and were dropped")
+			}
+			continue
+		case "actions/upload-artifact":
+			artifact := Artifact{Name: step.With["name"], Paths: splitLines(step.With["path"])}
+			if days := step.With["retention-days"]; days != "" {
+				artifact.ExpireIn = days + " days"
+			}
+			job.Artifacts = append(job.Artifacts, artifact)
+			continue
+		}
+
+		job.Steps = append(job.Steps, Step{
+			Name:             step.Name,
+			Run:              step.Run,
+			Uses:             step.Uses,
+			With:             step.With,
+			Env:              step.Env,
+			WorkingDirectory: step.WorkingDirectory,
+			If:               step.If,
+		})
+	}
+
+	return job
+}
+
+// parseGitHubMatrix 解析 strategy.matrix，保留维度的声明顺序
+func parseGitHubMatrix(id string, node *yaml.Node, warns *warnings) *Matrix {
+	matrix := &Matrix{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		value := node.Content[i+1]
+		switch key {
+		case "include", "exclude":
+			var entries []stringMap
+			if err := value.Decode(&entries); err != nil {
+				warns.add(id, "matrix %s must be a list of scalar mappings and was dropped", key)
+				continue
+			}
+			for _, entry := range entries {
+				if key == "include" {
+					matrix.Include = append(matrix.Include, entry)
+				} else {
+					matrix.Exclude = append(matrix.Exclude, entry)
+				}
+			}
+		default:
+			var values stringList
+			if err := value.Decode(&values); err != nil {
+				warns.add(id, "matrix axis '%s' must be a list of scalars and was dropped", key)
+				continue
+			}
+			matrix.Axes = append(matrix.Axes, MatrixAxis{Name: key, Values: values})
+		}
+	}
+	return matrix
+}
+
+// parseGitHubTriggers 解析 on 触发器
+func parseGitHubTriggers(on *yaml.Node, warns *warnings) []Trigger {
+	var events []string
+	filters := map[string]*yaml.Node{}
+	switch on.Kind {
+	case yaml.ScalarNode:
+		events = []string{on.Value}
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			events = append(events, item.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(on.Content); i += 2 {
+			events = append(events, on.Content[i].Value)
+			filters[on.Content[i].Value] = on.Content[i+1]
+		}
+	}
+
+	var triggers []Trigger
+	for _, event := range events {
+		node := filters[event]
+		switch event {
+		case "push", "pull_request", "pull_request_target":
+			var filter struct {
+				Branches       stringList `yaml:"branches"`
+				BranchesIgnore stringList `yaml:"branches-ignore"`
+				Tags           stringList `yaml:"tags"`
+				TagsIgnore     stringList `yaml:"tags-ignore"`
+				Paths          stringList `yaml:"paths"`
+				PathsIgnore    stringList `yaml:"paths-ignore"`
+				Types          stringList `yaml:"types"`
+			}
+			if node != nil && node.Kind == yaml.MappingNode {
+				node.Decode(&filter)
+			}
+			if event == "pull_request_target" {
+				warns.add("", "pull_request_target was converted to pull_request")
+			}
+			if len(filter.Types) > 0 {
+				warns.add("", "%s types filter is not supported and was dropped", event)
+			}
+			trigger := Trigger{
+				Event:          EventPush,
+				Branches:       filter.Branches,
+				BranchesIgnore: filter.BranchesIgnore,
+				Tags:           filter.Tags,
+				TagsIgnore:     filter.TagsIgnore,
+				Paths:          filter.Paths,
+				PathsIgnore:    filter.PathsIgnore,
+			}
+			if event != "push" {
+				trigger.Event = EventPullRequest
+			}
+			triggers = append(triggers, trigger)
+		case "schedule":
+			var schedules []struct {
+				Cron string `yaml:"cron"`
+			}
+			if node != nil {
+				node.Decode(&schedules)
+			}
+			for _, schedule := range schedules {
+				triggers = append(triggers, Trigger{Event: EventSchedule, Cron: schedule.Cron})
+			}
+		case "workflow_dispatch":
+			if mappingValue(node, "inputs") != nil {
+				warns.add("", "workflow_dispatch inputs are not supported and were dropped")
+			}
+			triggers = append(triggers, Trigger{Event: EventManual})
+		default:
+			warns.add("", "trigger '%s' is not supported and was dropped", event)
+		}
+	}
+
+	return triggers
+}
+
+// EmitGitHubActions 由管道模型生成 GitHub Actions workflow
+func EmitGitHubActions(pipeline *Pipeline) (string, []Warning, error) {
+	if len(pipeline.Jobs) == 0 {
+		return "", nil, fmt.Errorf("pipeline must have at least one job")
+	}
+	if _, err := topologicalOrder(pipeline.Jobs); err != nil {
+		return "", nil, err
+	}
+
+	var warns warnings
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	name := pipeline.Name
+	if name == "" {
+		name = "CI"
+	}
+	addPair(document, "name", name)
+	addPair(document, "on", emitGitHubTriggers(pipeline.Triggers, &warns))
+	if len(pipeline.Env) > 0 {
+		addPair(document, "env", mappingNode(pipeline.Env))
+	}
+
+	jobs := &yaml.Node{Kind: yaml.MappingNode}
+	for _, job := range pipeline.Jobs {
+		addPair(jobs, job.ID, emitGitHubJob(job))
+	}
+	addPair(document, "jobs", jobs)
+
+	output, err := encodeYAML(document)
+	if err != nil {
+		return "", nil, err
+	}
+	return output, warns, nil
+}
+
+// emitGitHubTriggers 生成 on 触发器，同类事件合并，没有触发器时在 push 时触发
+func emitGitHubTriggers(triggers []Trigger, warns *warnings) *yaml.Node {
+	on := &yaml.Node{Kind: yaml.MappingNode}
+	if len(triggers) == 0 {
+		addPair(on, "push", nil)
+		return on
+	}
+
+	var order []string
+	merged := map[string]*Trigger{}
+	var crons []string
+	for _, trigger := range triggers {
+		event := trigger.Event
+		switch event {
+		case EventSchedule:
+			if trigger.Cron == "" {
+				warns.add("", "schedule trigger without cron expression was dropped, configure the schedule manually")
+				continue
+			}
+			crons = append(crons, trigger.Cron)
+		case EventManual:
+			event = "workflow_dispatch"
+		}
+
+		existing, exists := merged[event]
+		if !exists {
+			copied := trigger
+			merged[event] = &copied
+			order = append(order, event)
+			continue
+		}
+		existing.Branches = append(existing.Branches, trigger.Branches...)
+		existing.BranchesIgnore = append(existing.BranchesIgnore, trigger.BranchesIgnore...)
+		existing.Tags = append(existing.Tags, trigger.Tags...)
+		existing.TagsIgnore = append(existing.TagsIgnore, trigger.TagsIgnore...)
+		existing.Paths = append(existing.Paths, trigger.Paths...)
+		existing.PathsIgnore = append(existing.PathsIgnore, trigger.PathsIgnore...)
+	}
+
+	for _, event := range order {
+		trigger := merged[event]
+		switch event {
+		case EventSchedule:
+			var schedules []map[string]string
+			for _, cron := range crons {
+				schedules = append(schedules, map[string]string{"cron": cron})
+			}
+			addPair(on, "schedule", schedules)
+		case EventPush, EventPullRequest:
+			filter := &yaml.Node{Kind: yaml.MappingNode}
+			if len(trigger.Branches) > 0 {
+				addPair(filter, "branches", trigger.Branches)
+			}
+			if len(trigger.BranchesIgnore) > 0 {
+				addPair(filter, "branches-ignore", trigger.BranchesIgnore)
+			}
+			if len(trigger.Tags) > 0 || len(trigger.TagsIgnore) > 0 {
+				if event == EventPush {
+					if len(trigger.Tags) > 0 {
+						addPair(filter, "tags", trigger.Tags)
+					}
+					if len(trigger.TagsIgnore) > 0 {
+						addPair(filter, "tags-ignore", trigger.TagsIgnore)
+					}
+				} else {
+					warns.add("", "pull_request trigger cannot filter tags, tag filter was dropped")
+				}
+			}
+			if len(trigger.Paths) > 0 {
+				addPair(filter, "paths", trigger.Paths)
+			}
+			if len(trigger.PathsIgnore) > 0 {
+				addPair(filter, "paths-ignore", trigger.PathsIgnore)
+			}
+			if len(filter.Content) == 0 {
+				addPair(on, event, nil)
+			} else {
+				addPair(on, event, filter)
+			}
+		default:
+			addPair(on, event, nil)
+		}
+	}
+
+	return on
+}
+
+// emitGitHubJob 生成单个 job，缓存生成在检出步骤之后，产物上传生成在最后
+func emitGitHubJob(job *Job) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if job.Name != "" {
+		addPair(node, "name", job.Name)
+	}
+	switch len(job.RunsOn) {
+	case 0:
+		addPair(node, "runs-on", "ubuntu-latest")
+	case 1:
+		addPair(node, "runs-on", job.RunsOn[0])
+	default:
+		addPair(node, "runs-on", job.RunsOn)
+	}
+	if job.Image != "" {
+		addPair(node, "container", job.Image)
+	}
+	if len(job.Services) > 0 {
+		services := &yaml.Node{Kind: yaml.MappingNode}
+		for _, service := range job.Services {
+			spec := &yaml.Node{Kind: yaml.MappingNode}
+			addPair(spec, "image", service.Image)
+			if len(service.Env) > 0 {
+				addPair(spec, "env", mappingNode(service.Env))
+			}
+			if len(service.Ports) > 0 {
+				ports := &yaml.Node{Kind: yaml.SequenceNode}
+				for _, port := range service.Ports {
+					ports.Content = append(ports.Content, scalarNode(port))
+				}
+				addPair(spec, "ports", ports)
+			}
+			addPair(services, service.Name, spec)
+		}
+		addPair(node, "services", services)
+	}
+	if len(job.Needs) > 0 {
+		addPair(node, "needs", job.Needs)
+	}
+	if job.If != "" {
+		addPair(node, "if", job.If)
+	}
+	if len(job.Env) > 0 {
+		addPair(node, "env", mappingNode(job.Env))
+	}
+	if job.Matrix != nil {
+		addPair(node, "strategy", emitGitHubStrategy(job.Matrix))
+	}
+	if job.Resources != nil {
+		resources := &yaml.Node{Kind: yaml.MappingNode}
+		for _, part := range []struct {
+			key  string
+			spec ResourceSpec
+		}{{"limits", job.Resources.Limits}, {"requests", job.Resources.Requests}} {
+			if part.spec == (ResourceSpec{}) {
+				continue
+			}
+			spec := &yaml.Node{Kind: yaml.MappingNode}
+			if part.spec.CPU != "" {
+				addPair(spec, "cpu", resourceValue(part.spec.CPU))
+			}
+			if part.spec.Memory != "" {
+				addPair(spec, "memory", part.spec.Memory)
+			}
+			addPair(resources, part.key, spec)
+		}
+		addPair(node, "resources", resources)
+	}
+	if job.TimeoutMinutes > 0 {
+		addPair(node, "timeout-minutes", job.TimeoutMinutes)
+	}
+	if job.ContinueOnError {
+		addPair(node, "continue-on-error", true)
+	}
+
+	var cacheSteps []Step
+	for _, cache := range job.Caches {
+		cacheSteps = append(cacheSteps, Step{
+			Uses: "actions/cache@v4",
+			With: map[string]string{"path": strings.Join(cache.Paths, "\n"), "key": cache.Key},
+		})
+	}
+	// 缓存步骤放在开头的检出和 setup-* 步骤之后
+	position := 0
+	for position < len(job.Steps) && (actionName(job.Steps[position].Uses) == "actions/checkout" || isSetupAction(job.Steps[position].Uses)) {
+		position++
+	}
+	steps := append(append(append([]Step{}, job.Steps[:position]...), cacheSteps...), job.Steps[position:]...)
+	for _, artifact := range job.Artifacts {
+		with := map[string]string{"path": strings.Join(artifact.Paths, "\n")}
+		if artifact.Name != "" {
+			with["name"] = artifact.Name
+		}
+		if days := expireDays(artifact.ExpireIn); days > 0 {
+			with["retention-days"] = strconv.Itoa(days)
+		}
+		steps = append(steps, Step{Uses: "actions/upload-artifact@v4", With: with})
+	}
+
+	stepsNode := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, step := range steps {
+		stepsNode.Content = append(stepsNode.Content, emitGitHubStep(step))
+	}
+	addPair(node, "steps", stepsNode)
+
+	return node
+}
+
+// emitGitHubStrategy 生成 strategy
+func emitGitHubStrategy(matrix *Matrix) *yaml.Node {
+	strategy := &yaml.Node{Kind: yaml.MappingNode}
+	matrixNode := &yaml.Node{Kind: yaml.MappingNode}
+	for _, axis := range matrix.Axes {
+		values := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, value := range axis.Values {
+			values.Content = append(values.Content, scalarNode(value))
+		}
+		addPair(matrixNode, axis.Name, values)
+	}
+	for _, part := range []struct {
+		key     string
+		entries []map[string]string
+	}{{"include", matrix.Include}, {"exclude", matrix.Exclude}} {
+		if len(part.entries) == 0 {
+			continue
+		}
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, entry := range part.entries {
+			item := &yaml.Node{Kind: yaml.MappingNode}
+			for _, key := range sortedKeys(entry) {
+				addPair(item, key, scalarNode(entry[key]))
+			}
+			list.Content = append(list.Content, item)
+		}
+		addPair(matrixNode, part.key, list)
+	}
+	addPair(strategy, "matrix", matrixNode)
+	if matrix.FailFast != nil {
+		addPair(strategy, "fail-fast", *matrix.FailFast)
+	}
+	if matrix.MaxParallel > 0 {
+		addPair(strategy, "max-parallel", matrix.MaxParallel)
+	}
+	return strategy
+}
+
+// emitGitHubStep 生成单个 step
+func emitGitHubStep(step Step) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if step.Name != "" {
+		addPair(node, "name", step.Name)
+	}
+	if step.If != "" {
+		addPair(node, "if", step.If)
+	}
+	if step.Uses != "" {
+		addPair(node, "uses", step.Uses)
+	}
+	if len(step.With) > 0 {
+		with := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range sortedKeys(step.With) {
+			addPair(with, key, scalarNode(step.With[key]))
+		}
+		addPair(node, "with", with)
+	}
+	if len(step.Env) > 0 {
+		addPair(node, "env", mappingNode(step.Env))
+	}
+	if step.WorkingDirectory != "" {
+		addPair(node, "working-directory", step.WorkingDirectory)
+	}
+	if step.Run != "" {
+		run := &yaml.Node{Kind: yaml.ScalarNode, Value: step.Run}
+		if strings.Contains(step.Run, "\n") {
+			run.Style = yaml.LiteralStyle
+		}
+		addPair(node, "run", run)
+	}
+	return node
+}
+
+// scalarNode 生成标量节点，可以无损表示为数字或布尔值的按原样输出，其余按字符串输出
+func scalarNode(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value, Tag: "!!str"}
+	if _, err := strconv.Atoi(value); err == nil {
+		node.Tag = "!!int"
+	} else if number, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(number, 'f', -1, 64) == value {
+		node.Tag = "!!float"
+	} else if value == "true" || value == "false" {
+		node.Tag = "!!bool"
+	}
+	return node
+}
+
+// resourceValue CPU 配置为数字时按数字输出
+func resourceValue(value string) interface{} {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number
+	}
+	return value
+}
+
+// isSetupAction 判断是否为 actions/setup-* 步骤
+func isSetupAction(uses string) bool {
+	return strings.HasPrefix(actionName(uses), "actions/setup-")
+}
+
+// actionName 去除 action 引用中的版本，例如 actions/checkout@v4 -> actions/checkout
+func actionName(uses string) string {
+	return strings.SplitN(uses, "@", 2)[0]
+}
+
+// splitLines 按行拆分并去除空行
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// expireDays 将保留时间转换为天数，例如 "7 days"、"1 week"，无法识别时返回 0
+func expireDays(expireIn string) int {
+	fields := strings.Fields(expireIn)
+	if len(fields) != 2 {
+		return 0
+	}
+	value, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0
+	}
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "day":
+		return value
+	case "week":
+		return value * 7
+	case "month":
+		return value * 30
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,1208 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabJob GitLab CI job
+type gitlabJob struct {
+	Stage        string                    `yaml:"stage"`
+	Image        gitlabImage               `yaml:"image"`
+	Services     []gitlabImage             `yaml:"services"`
+	Tags         []string                  `yaml:"tags"`
+	Needs        []gitlabNeed              `yaml:"needs"`
+	Variables    map[string]gitlabVariable `yaml:"variables"`
+	BeforeScript stringList                `yaml:"before_script"`
+	Script       stringList                `yaml:"script"`
+	AfterScript  stringList                `yaml:"after_script"`
+	Parallel     yaml.Node                 `yaml:"parallel"`
+	Cache        yaml.Node                 `yaml:"cache"`
+	Artifacts    gitlabArtifacts           `yaml:"artifacts"`
+	Timeout      string                    `yaml:"timeout"`
+	AllowFailure yaml.Node                 `yaml:"allow_failure"`
+}
+
+// gitlabImage 镜像配置，可以写成镜像名或包含 name 的映射
+type gitlabImage struct {
+	Name  string `yaml:"name"`
+	Alias string `yaml:"alias"`
+}
+
+// UnmarshalYAML 解析镜像名或包含 name 的映射
+func (i *gitlabImage) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		i.Name = value.Value
+		return nil
+	}
+	type plain gitlabImage
+	return value.Decode((*plain)(i))
+}
+
+// gitlabNeed needs 项，可以写成 job 名称或包含 job 的映射
+type gitlabNeed struct {
+	Job      string `yaml:"job"`
+	Project  string `yaml:"project"`
+	Pipeline string `yaml:"pipeline"`
+}
+
+// UnmarshalYAML 解析 job 名称或包含 job 的映射
+func (n *gitlabNeed) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		n.Job = value.Value
+		return nil
+	}
+	type plain gitlabNeed
+	return value.Decode((*plain)(n))
+}
+
+// gitlabVariable 变量，可以写成值或包含 value 的映射
+type gitlabVariable struct {
+	Value string `yaml:"value"`
+}
+
+// UnmarshalYAML 解析值或包含 value 的映射
+func (v *gitlabVariable) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		v.Value = value.Value
+		return nil
+	}
+	type plain gitlabVariable
+	return value.Decode((*plain)(v))
+}
+
+// gitlabCache 缓存配置
+type gitlabCache struct {
+	Key   yaml.Node  `yaml:"key"`
+	Paths stringList `yaml:"paths"`
+}
+
+// gitlabArtifacts 产物配置
+type gitlabArtifacts struct {
+	Name     string     `yaml:"name"`
+	Paths    stringList `yaml:"paths"`
+	ExpireIn string     `yaml:"expire_in"`
+	Reports  yaml.Node  `yaml:"reports"`
+}
+
+// gitlabRule workflow:rules 中的规则
+type gitlabRule struct {
+	If      string     `yaml:"if"`
+	When    string     `yaml:"when"`
+	Changes stringList `yaml:"changes"`
+}
+
+// gitlabReservedKeys GitLab CI 中不是 job 的顶层关键字
+var gitlabReservedKeys = map[string]bool{
+	"default": true, "include": true, "stages": true, "variables": true, "workflow": true,
+	"image": true, "services": true, "cache": true, "before_script": true, "after_script": true,
+}
+
+// gitlabJobKeys 模型支持的 job 字段，其他字段会产生警告
+var gitlabJobKeys = map[string]bool{
+	"stage": true, "image": true, "services": true, "tags": true, "needs": true, "variables": true,
+	"before_script": true, "script": true, "after_script": true, "parallel": true, "cache": true,
+	"artifacts": true, "timeout": true, "allow_failure": true, "extends": true,
+}
+
+// gitlabResourceVariables Kubernetes executor 的资源变量
+var gitlabResourceVariables = []string{
+	"KUBERNETES_CPU_REQUEST", "KUBERNETES_MEMORY_REQUEST", "KUBERNETES_CPU_LIMIT", "KUBERNETES_MEMORY_LIMIT",
+}
+
+// gitlabScriptVariables GitLab 预定义变量对应的 GitHub Actions 环境变量
+var gitlabScriptVariables = map[string]string{
+	"CI_COMMIT_SHA":      "GITHUB_SHA",
+	"CI_COMMIT_REF_NAME": "GITHUB_REF_NAME",
+	"CI_PIPELINE_ID":     "GITHUB_RUN_ID",
+	"CI_PIPELINE_IID":    "GITHUB_RUN_NUMBER",
+	"CI_PROJECT_DIR":     "GITHUB_WORKSPACE",
+	"CI_PROJECT_PATH":    "GITHUB_REPOSITORY",
+	"GITLAB_USER_LOGIN":  "GITHUB_ACTOR",
+	"CI_JOB_NAME":        "GITHUB_JOB",
+}
+
+// githubScriptVariables GitHub Actions 环境变量对应的 GitLab 预定义变量
+var githubScriptVariables = func() map[string]string {
+	result := make(map[string]string, len(gitlabScriptVariables))
+	for gitlab, github := range gitlabScriptVariables {
+		result[github] = gitlab
+	}
+	return result
+}()
+
+// githubContextVariables GitHub 上下文对应的 GitLab 预定义变量
+var githubContextVariables = map[string]string{
+	"github.sha":        "CI_COMMIT_SHA",
+	"github.ref":        "CI_COMMIT_REF_NAME",
+	"github.ref_name":   "CI_COMMIT_REF_NAME",
+	"github.run_id":     "CI_PIPELINE_ID",
+	"github.run_number": "CI_PIPELINE_IID",
+	"github.workspace":  "CI_PROJECT_DIR",
+	"github.repository": "CI_PROJECT_PATH",
+	"github.actor":      "GITLAB_USER_LOGIN",
+	"github.job":        "CI_JOB_NAME",
+}
+
+// setupActionImages setup-* action 对应的 GitLab 镜像及版本参数
+var setupActionImages = map[string]struct {
+	image   string
+	version string
+}{
+	"actions/setup-go":     {"golang", "go-version"},
+	"actions/setup-node":   {"node", "node-version"},
+	"actions/setup-python": {"python", "python-version"},
+	"actions/setup-java":   {"eclipse-temurin", "java-version"},
+}
+
+var (
+	// variableReference 匹配 $VAR 和 ${VAR} 变量引用
+	variableReference = regexp.MustCompile(`\$\{?([A-Z_][A-Z0-9_]*)\}?`)
+	// githubExpression 匹配 ${{ <表达式> }}
+	githubExpression = regexp.MustCompile(`\$\{\{\s*([^}]+?)\s*\}\}`)
+	// refRule 识别 workflow:rules 中的分支、标签和合并请求目标分支条件
+	refRule = regexp.MustCompile(`^\$(CI_COMMIT_BRANCH|CI_COMMIT_TAG|CI_MERGE_REQUEST_TARGET_BRANCH_NAME)\s*(==|=~)\s*(?:"([^"]*)"|'([^']*)'|/(.*)/)$`)
+	// sourceRule 识别 $CI_PIPELINE_SOURCE 条件
+	sourceRule = regexp.MustCompile(`^\$CI_PIPELINE_SOURCE\s*==\s*["']([a-z_]+)["']$`)
+	// hashFilesKey 匹配 [prefix-]${{ hashFiles(...) }} 形式的缓存键
+	hashFilesKey = regexp.MustCompile(`^(?:(.+)-)?\$\{\{\s*hashFiles\(([^)]*)\)\s*\}\}$`)
+	// timeoutPart 匹配超时时间中的小时或分钟部分
+	timeoutPart = regexp.MustCompile(`(\d+)\s*(h|hours?|m|min|mins|minutes?)\b`)
+)
+
+// ParseGitLabCI 将 GitLab CI 配置解析为管道模型。
+// 没有 needs 的 job 依赖上一个阶段的所有 job，与 GitLab 按阶段调度的语义一致
+func ParseGitLabCI(content string) (*Pipeline, []Warning, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse GitLab CI config: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("GitLab CI config must be a mapping")
+	}
+	document := root.Content[0]
+
+	var global struct {
+		Stages    []string                  `yaml:"stages"`
+		Variables map[string]gitlabVariable `yaml:"variables"`
+		Image     gitlabImage               `yaml:"image"`
+		Services  []gitlabImage             `yaml:"services"`
+		Cache     yaml.Node                 `yaml:"cache"`
+		Default   struct {
+			Image        gitlabImage   `yaml:"image"`
+			Services     []gitlabImage `yaml:"services"`
+			Cache        yaml.Node     `yaml:"cache"`
+			Tags         []string      `yaml:"tags"`
+			BeforeScript stringList    `yaml:"before_script"`
+			AfterScript  stringList    `yaml:"after_script"`
+		} `yaml:"default"`
+		BeforeScript stringList `yaml:"before_script"`
+		AfterScript  stringList `yaml:"after_script"`
+		Workflow     struct {
+			Name  string       `yaml:"name"`
+			Rules []gitlabRule `yaml:"rules"`
+		} `yaml:"workflow"`
+	}
+	if err := document.Decode(&global); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse GitLab CI config: %w", err)
+	}
+
+	var warns warnings
+	if mappingValue(document, "include") != nil {
+		warns.add("", "include is not supported, included jobs were not converted")
+	}
+
+	stages := global.Stages
+	if len(stages) == 0 {
+		stages = []string{"build", "test", "deploy"}
+	}
+	allStages := append(append([]string{".pre"}, stages...), ".post")
+
+	pipeline := &Pipeline{
+		Name:     global.Workflow.Name,
+		Triggers: parseGitLabRules(global.Workflow.Rules, &warns),
+		Env:      convertGitLabVariableMap(global.Variables),
+		Stages:   stages,
+	}
+
+	// 收集 job 和隐藏模板
+	templates := make(map[string]*yaml.Node)
+	var names []string
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		name := document.Content[i].Value
+		templates[name] = document.Content[i+1]
+		if gitlabReservedKeys[name] || strings.HasPrefix(name, ".") {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("GitLab CI config must define at least one job")
+	}
+
+	stageJobs := make(map[string][]string)
+	for _, name := range names {
+		node, err := resolveExtends(name, templates, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, key := range mappingKeys(node) {
+			if !gitlabJobKeys[key] {
+				warns.add(name, "job key '%s' is not supported and was dropped", key)
+			}
+		}
+
+		source := gitlabJob{Stage: "test"}
+		if err := node.Decode(&source); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse job %s: %w", name, err)
+		}
+		if indexOf(allStages, source.Stage) < 0 {
+			return nil, nil, fmt.Errorf("job %s uses undefined stage %s", name, source.Stage)
+		}
+
+		// 继承全局默认配置
+		if mappingValue(node, "before_script") == nil {
+			source.BeforeScript = firstNonEmpty(global.Default.BeforeScript, global.BeforeScript)
+		}
+		if mappingValue(node, "after_script") == nil {
+			source.AfterScript = firstNonEmpty(global.Default.AfterScript, global.AfterScript)
+		}
+		if source.Image.Name == "" {
+			source.Image = global.Default.Image
+			if source.Image.Name == "" {
+				source.Image = global.Image
+			}
+		}
+		if mappingValue(node, "services") == nil {
+			source.Services = global.Default.Services
+			if source.Services == nil {
+				source.Services = global.Services
+			}
+		}
+		if mappingValue(node, "tags") == nil {
+			source.Tags = global.Default.Tags
+		}
+		if mappingValue(node, "cache") == nil {
+			source.Cache = global.Default.Cache
+			if source.Cache.Kind == 0 {
+				source.Cache = global.Cache
+			}
+		}
+
+		job, err := parseGitLabJob(name, source, &warns)
+		if err != nil {
+			return nil, nil, err
+		}
+		// needs 未声明时按阶段顺序推导，needs: [] 表示不依赖任何 job
+		if mappingValue(node, "needs") == nil {
+			job.ImplicitNeeds = true
+		}
+		pipeline.Jobs = append(pipeline.Jobs, job)
+		stageJobs[job.Stage] = append(stageJobs[job.Stage], name)
+	}
+
+	// 按阶段语义计算依赖：依赖上一个有 job 的阶段中的所有 job
+	for _, job := range pipeline.Jobs {
+		if !job.ImplicitNeeds {
+			continue
+		}
+		for i := indexOf(allStages, job.Stage) - 1; i >= 0; i-- {
+			if previous := stageJobs[allStages[i]]; len(previous) > 0 {
+				job.Needs = append([]string(nil), previous...)
+				break
+			}
+		}
+	}
+
+	if _, err := topologicalOrder(pipeline.Jobs); err != nil {
+		return nil, nil, err
+	}
+
+	return pipeline, warns, nil
+}
+
+// parseGitLabJob 转换单个 job，脚本转换为步骤，矩阵变量通过 env 引用矩阵取值
+func parseGitLabJob(name string, source gitlabJob, warns *warnings) (*Job, error) {
+	job := &Job{
+		ID:             name,
+		Stage:          source.Stage,
+		Image:          source.Image.Name,
+		Env:            map[string]string{},
+		TimeoutMinutes: parseTimeoutMinutes(source.Timeout),
+	}
+	if len(source.Tags) > 0 {
+		job.RunsOn = append([]string{"self-hosted"}, source.Tags...)
+	}
+	for _, service := range source.Services {
+		serviceID := service.Alias
+		if serviceID == "" {
+			serviceID = serviceName(service.Name)
+		}
+		job.Services = append(job.Services, Service{Name: serviceID, Image: service.Name})
+	}
+	for _, need := range source.Needs {
+		if need.Project != "" || need.Pipeline != "" {
+			warns.add(name, "cross-pipeline needs are not supported and were dropped")
+			continue
+		}
+		job.Needs = append(job.Needs, need.Job)
+	}
+
+	// Kubernetes 资源变量转换为资源配置，其他变量转换为 env
+	resources := &Resources{}
+	for key, variable := range source.Variables {
+		switch key {
+		case "KUBERNETES_CPU_REQUEST":
+			resources.Requests.CPU = variable.Value
+		case "KUBERNETES_MEMORY_REQUEST":
+			resources.Requests.Memory = variable.Value
+		case "KUBERNETES_CPU_LIMIT":
+			resources.Limits.CPU = variable.Value
+		case "KUBERNETES_MEMORY_LIMIT":
+			resources.Limits.Memory = variable.Value
+		default:
+			job.Env[key] = convertGitLabVariables(variable.Value)
+		}
+	}
+	if *resources != (Resources{}) {
+		job.Resources = resources
+	}
+
+	switch source.Parallel.Kind {
+	case yaml.ScalarNode:
+		warns.add(name, "parallel: %s has no equivalent and was dropped", source.Parallel.Value)
+	case yaml.MappingNode:
+		var parallel struct {
+			Matrix []yaml.Node `yaml:"matrix"`
+		}
+		if err := source.Parallel.Decode(&parallel); err != nil || len(parallel.Matrix) == 0 {
+			return nil, fmt.Errorf("job %s: invalid parallel matrix", name)
+		}
+		matrix, keys, err := parseGitLabMatrix(name, parallel.Matrix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			job.Env[key] = fmt.Sprintf("${{ matrix.%s }}", key)
+		}
+		job.Matrix = matrix
+	}
+	if len(job.Env) == 0 {
+		job.Env = nil
+	}
+
+	caches, err := parseGitLabCache(name, &source.Cache, warns)
+	if err != nil {
+		return nil, err
+	}
+	job.Caches = caches
+
+	if len(source.Artifacts.Paths) > 0 {
+		job.Artifacts = []Artifact{{Name: source.Artifacts.Name, Paths: source.Artifacts.Paths, ExpireIn: source.Artifacts.ExpireIn}}
+	}
+	if source.Artifacts.Reports.Kind != 0 {
+		warns.add(name, "artifacts reports are not supported and were dropped")
+	}
+
+	switch source.AllowFailure.Kind {
+	case yaml.ScalarNode:
+		job.ContinueOnError = source.AllowFailure.Value == "true"
+	case yaml.MappingNode:
+		job.ContinueOnError = true
+		warns.add(name, "allow_failure exit_codes are not supported, the job is allowed to fail with any exit code")
+	}
+
+	// GitLab 会自动检出代码
+	job.Steps = []Step{{Uses: "actions/checkout@v4"}}
+	if len(source.BeforeScript) > 0 {
+		job.Steps = append(job.Steps, scriptStep("Before script", source.BeforeScript))
+	}
+	if len(source.Script) > 0 {
+		job.Steps = append(job.Steps, scriptStep("Script", source.Script))
+	}
+	if len(source.AfterScript) > 0 {
+		step := scriptStep("After script", source.AfterScript)
+		step.If = "always()"
+		job.Steps = append(job.Steps, step)
+	}
+
+	return job, nil
+}
+
+// scriptStep 将脚本行转换为步骤，保留原始命令列表
+func scriptStep(name string, lines stringList) Step {
+	commands := make([]string, len(lines))
+	for i, line := range lines {
+		commands[i] = convertGitLabVariables(line)
+	}
+	return Step{Name: name, Run: strings.Join(commands, "\n"), Commands: commands}
+}
+
+// parseGitLabMatrix 解析 parallel:matrix：只有一项时转换为矩阵维度，多项时展开为 include
+func parseGitLabMatrix(name string, entries []yaml.Node) (*Matrix, []string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	var axes []MatrixAxis
+	var combos []map[string]string
+
+	for _, entry := range entries {
+		if entry.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("job %s: parallel matrix entry must be a mapping", name)
+		}
+
+		partial := []map[string]string{{}}
+		axes = nil
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			key := entry.Content[i].Value
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+			var values stringList
+			if err := entry.Content[i+1].Decode(&values); err != nil {
+				return nil, nil, fmt.Errorf("job %s: invalid parallel matrix values for %s", name, key)
+			}
+			axes = append(axes, MatrixAxis{Name: key, Values: values})
+
+			var next []map[string]string
+			for _, combo := range partial {
+				for _, value := range values {
+					expanded := make(map[string]string, len(combo)+1)
+					for k, v := range combo {
+						expanded[k] = v
+					}
+					expanded[key] = value
+					next = append(next, expanded)
+				}
+			}
+			partial = next
+		}
+		combos = append(combos, partial...)
+	}
+
+	if len(entries) == 1 {
+		return &Matrix{Axes: axes}, keys, nil
+	}
+	return &Matrix{Include: combos}, keys, nil
+}
+
+// parseGitLabCache 解析 cache，可以是单个缓存或缓存列表
+func parseGitLabCache(name string, node *yaml.Node, warns *warnings) ([]Cache, error) {
+	var entries []gitlabCache
+	switch node.Kind {
+	case 0:
+		return nil, nil
+	case yaml.MappingNode:
+		var entry gitlabCache
+		if err := node.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("job %s: invalid cache: %w", name, err)
+		}
+		entries = append(entries, entry)
+	default:
+		if err := node.Decode(&entries); err != nil {
+			return nil, fmt.Errorf("job %s: invalid cache: %w", name, err)
+		}
+	}
+
+	var caches []Cache
+	for _, entry := range entries {
+		cache := Cache{Paths: entry.Paths}
+		switch entry.Key.Kind {
+		case yaml.ScalarNode:
+			cache.Key = convertGitLabVariables(entry.Key.Value)
+		case yaml.MappingNode:
+			// key:files 对应 hashFiles
+			var key struct {
+				Files  []string `yaml:"files"`
+				Prefix string   `yaml:"prefix"`
+			}
+			entry.Key.Decode(&key)
+			quoted := make([]string, len(key.Files))
+			for i, file := range key.Files {
+				quoted[i] = "'" + file + "'"
+			}
+			cache.Key = fmt.Sprintf("${{ hashFiles(%s) }}", strings.Join(quoted, ", "))
+			if key.Prefix != "" {
+				cache.Key = convertGitLabVariables(key.Prefix) + "-" + cache.Key
+			}
+		}
+		caches = append(caches, cache)
+	}
+	return caches, nil
+}
+
+// hashFilesArguments 解析 hashFiles 的参数列表，例如 'go.sum', 'go.mod'
+func hashFilesArguments(arguments string) []string {
+	var files []string
+	for _, argument := range strings.Split(arguments, ",") {
+		if file := strings.Trim(strings.TrimSpace(argument), `'"`); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// parseGitLabRules 将 workflow:rules 转换为触发器，没有规则时在 push 和 pull_request 时触发
+func parseGitLabRules(rules []gitlabRule, warns *warnings) []Trigger {
+	if len(rules) == 0 {
+		return []Trigger{{Event: EventPush}, {Event: EventPullRequest}}
+	}
+
+	var order []string
+	triggers := map[string]*Trigger{}
+	trigger := func(event string) *Trigger {
+		if existing, ok := triggers[event]; ok {
+			return existing
+		}
+		triggers[event] = &Trigger{Event: event}
+		order = append(order, event)
+		return triggers[event]
+	}
+
+	for _, rule := range rules {
+		condition := strings.TrimSpace(rule.If)
+		never := rule.When == "never"
+
+		if match := sourceRule.FindStringSubmatch(condition); match != nil && !never {
+			switch match[1] {
+			case "push":
+				trigger(EventPush).Paths = append(trigger(EventPush).Paths, rule.Changes...)
+			case "merge_request_event":
+				trigger(EventPullRequest).Paths = append(trigger(EventPullRequest).Paths, rule.Changes...)
+			case "schedule":
+				trigger(EventSchedule)
+			case "web":
+				trigger(EventManual)
+			default:
+				warns.add("", "workflow rule '%s' is not supported and was dropped", condition)
+			}
+			continue
+		}
+
+		if condition == "$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH" && !never {
+			warns.add("", "$CI_DEFAULT_BRANCH was assumed to be main")
+			push := trigger(EventPush)
+			push.Branches = append(push.Branches, "main")
+			push.Paths = append(push.Paths, rule.Changes...)
+			continue
+		}
+
+		match := refRule.FindStringSubmatch(condition)
+		if match == nil {
+			warns.add("", "workflow rule '%s' is not supported and was dropped", condition)
+			continue
+		}
+		pattern := match[3] + match[4]
+		if match[2] == "=~" {
+			glob, ok := regexToGlob(match[5])
+			if !ok {
+				warns.add("", "workflow rule regex /%s/ cannot be expressed as a glob and was dropped", match[5])
+				continue
+			}
+			pattern = glob
+		}
+
+		switch {
+		case match[1] == "CI_MERGE_REQUEST_TARGET_BRANCH_NAME" && !never:
+			pullRequest := trigger(EventPullRequest)
+			pullRequest.Branches = append(pullRequest.Branches, pattern)
+			pullRequest.Paths = append(pullRequest.Paths, rule.Changes...)
+		case match[1] == "CI_MERGE_REQUEST_TARGET_BRANCH_NAME":
+			pullRequest := trigger(EventPullRequest)
+			pullRequest.BranchesIgnore = append(pullRequest.BranchesIgnore, pattern)
+		case match[1] == "CI_COMMIT_TAG" && !never:
+			push := trigger(EventPush)
+			push.Tags = append(push.Tags, pattern)
+			push.Paths = append(push.Paths, rule.Changes...)
+		case match[1] == "CI_COMMIT_TAG":
+			push := trigger(EventPush)
+			push.TagsIgnore = append(push.TagsIgnore, pattern)
+		case !never:
+			push := trigger(EventPush)
+			push.Branches = append(push.Branches, pattern)
+			push.Paths = append(push.Paths, rule.Changes...)
+		default:
+			push := trigger(EventPush)
+			push.BranchesIgnore = append(push.BranchesIgnore, pattern)
+		}
+	}
+
+	result := make([]Trigger, 0, len(order))
+	for _, event := range order {
+		item := *triggers[event]
+		item.Paths = uniqueStrings(item.Paths)
+		result = append(result, item)
+	}
+	return result
+}
+
+// EmitGitLabCI 由管道模型生成 GitLab CI 配置。
+// 模型带有阶段时按阶段输出，由阶段推导的依赖不输出 needs；
+// 否则每个 job 使用与 job 同名的 stage 并保留 needs，没有依赖的 job 使用 needs: []，使 GitLab 按相同的依赖图调度
+func EmitGitLabCI(pipeline *Pipeline) (string, []Warning, error) {
+	if len(pipeline.Jobs) == 0 {
+		return "", nil, fmt.Errorf("pipeline must have at least one job")
+	}
+	order, err := topologicalOrder(pipeline.Jobs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	useStages := len(pipeline.Stages) > 0
+	allStages := append(append([]string{".pre"}, pipeline.Stages...), ".post")
+	for _, job := range pipeline.Jobs {
+		if job.Stage == "" || indexOf(allStages, job.Stage) < 0 {
+			useStages = false
+		}
+	}
+	stages := pipeline.Stages
+	if !useStages {
+		stages = order
+	}
+
+	var warns warnings
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	addPair(document, "stages", stages)
+
+	rules := emitGitLabRules(pipeline.Triggers, &warns)
+	if pipeline.Name != "" || len(rules.Content) > 0 {
+		workflow := &yaml.Node{Kind: yaml.MappingNode}
+		if pipeline.Name != "" {
+			addPair(workflow, "name", pipeline.Name)
+		}
+		if len(rules.Content) > 0 {
+			addPair(workflow, "rules", rules)
+		}
+		addPair(document, "workflow", workflow)
+	}
+	if len(pipeline.Env) > 0 {
+		addPair(document, "variables", convertGitHubMap(pipeline.Env))
+	}
+
+	for _, job := range pipeline.Jobs {
+		stage := job.ID
+		if useStages {
+			stage = job.Stage
+		}
+		addPair(document, job.ID, emitGitLabJob(job, stage, !useStages || !job.ImplicitNeeds, &warns))
+	}
+
+	output, err := encodeYAML(document)
+	if err != nil {
+		return "", nil, err
+	}
+	return output, warns, nil
+}
+
+// emitGitLabJob 生成单个 job
+func emitGitLabJob(job *Job, stage string, explicitNeeds bool, warns *warnings) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	addPair(node, "stage", stage)
+
+	// 镜像优先使用 container，否则根据 setup-* action 推断
+	image := job.Image
+	var beforeScript, script, afterScript []string
+	for i, step := range job.Steps {
+		if step.Uses != "" {
+			action := actionName(step.Uses)
+			switch {
+			case action == "actions/checkout" || action == "actions/cache" || action == "actions/download-artifact":
+				// GitLab 会自动检出代码，缓存使用 cache 关键字，产物在后续阶段自动下载
+			case setupActionImages[action].image != "":
+				setup := setupActionImages[action]
+				if version := step.With[setup.version]; image == "" && version != "" {
+					image = setup.image + ":" + version
+				}
+			default:
+				warns.add(job.ID, "action %s has no GitLab CI equivalent and was dropped, replace it with script commands", step.Uses)
+			}
+			continue
+		}
+		if step.Run == "" {
+			continue
+		}
+
+		lines := gitlabScriptLines(step)
+		switch {
+		case step.If == "always()" && i == len(job.Steps)-1:
+			afterScript = lines
+		case step.Name == "Before script" && len(script) == 0 && beforeScript == nil:
+			beforeScript = lines
+		default:
+			if step.If != "" {
+				warns.add(job.ID, "step condition '%s' is not supported, the step always runs", step.If)
+			}
+			script = append(script, lines...)
+		}
+	}
+	if len(script) == 0 {
+		script = []string{"echo \"Job " + job.ID + " has no commands\""}
+	}
+
+	if image != "" {
+		addPair(node, "image", image)
+	}
+	if len(job.Services) > 0 {
+		services := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, service := range job.Services {
+			if len(service.Env) > 0 || len(service.Ports) > 0 {
+				warns.add(job.ID, "env and ports of service %s are not supported and were dropped", service.Name)
+			}
+			if service.Name == serviceName(service.Image) {
+				services.Content = append(services.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: service.Image})
+				continue
+			}
+			item := &yaml.Node{Kind: yaml.MappingNode}
+			addPair(item, "name", service.Image)
+			addPair(item, "alias", service.Name)
+			services.Content = append(services.Content, item)
+		}
+		addPair(node, "services", services)
+	}
+	if tags := runnerTags(job.RunsOn); len(tags) > 0 {
+		addPair(node, "tags", tags)
+	}
+	if explicitNeeds {
+		needs := job.Needs
+		if needs == nil {
+			needs = []string{}
+		}
+		addPair(node, "needs", needs)
+	}
+	if job.If != "" {
+		warns.add(job.ID, "job condition '%s' is not supported and was dropped", job.If)
+	}
+
+	variables := make(map[string]string, len(job.Env))
+	for key, value := range job.Env {
+		// parallel:matrix 的取值在 GitLab 中本身就是环境变量
+		if value == fmt.Sprintf("${{ matrix.%s }}", key) {
+			continue
+		}
+		variables[key] = convertGitHubExpressions(value)
+	}
+	if job.Resources != nil {
+		for i, value := range []string{job.Resources.Requests.CPU, job.Resources.Requests.Memory, job.Resources.Limits.CPU, job.Resources.Limits.Memory} {
+			if value != "" {
+				variables[gitlabResourceVariables[i]] = value
+			}
+		}
+	}
+	if len(variables) > 0 {
+		addPair(node, "variables", mappingNode(variables))
+	}
+
+	if job.Matrix != nil {
+		if entries := emitGitLabMatrix(job.ID, job.Matrix, warns); len(entries) > 0 {
+			addPair(node, "parallel", map[string]interface{}{"matrix": entries})
+		}
+	}
+
+	if len(job.Caches) > 0 {
+		var caches []map[string]interface{}
+		for _, cache := range job.Caches {
+			entry := map[string]interface{}{"paths": cache.Paths}
+			if match := hashFilesKey.FindStringSubmatch(cache.Key); match != nil {
+				// hashFiles 对应 key:files，GitLab 最多支持两个文件
+				key := map[string]interface{}{"files": hashFilesArguments(match[2])}
+				if match[1] != "" {
+					key["prefix"] = convertGitHubExpressions(match[1])
+				}
+				entry["key"] = key
+			} else if cache.Key != "" {
+				if strings.Contains(cache.Key, "hashFiles(") {
+					warns.add(job.ID, "cache key '%s' uses hashFiles, which has no GitLab CI equivalent", cache.Key)
+				}
+				entry["key"] = convertGitHubExpressions(cache.Key)
+			}
+			caches = append(caches, entry)
+		}
+		if len(caches) == 1 {
+			addPair(node, "cache", caches[0])
+		} else {
+			addPair(node, "cache", caches)
+		}
+	}
+
+	if len(job.Artifacts) > 0 {
+		if len(job.Artifacts) > 1 {
+			warns.add(job.ID, "GitLab CI supports one artifact per job, %d artifacts were merged", len(job.Artifacts))
+		}
+		artifacts := &yaml.Node{Kind: yaml.MappingNode}
+		var paths []string
+		for _, artifact := range job.Artifacts {
+			paths = append(paths, artifact.Paths...)
+		}
+		if name := job.Artifacts[0].Name; name != "" {
+			addPair(artifacts, "name", name)
+		}
+		addPair(artifacts, "paths", paths)
+		if expireIn := job.Artifacts[0].ExpireIn; expireIn != "" {
+			addPair(artifacts, "expire_in", expireIn)
+		}
+		addPair(node, "artifacts", artifacts)
+	}
+
+	if job.TimeoutMinutes > 0 {
+		addPair(node, "timeout", fmt.Sprintf("%d minutes", job.TimeoutMinutes))
+	}
+	if job.ContinueOnError {
+		addPair(node, "allow_failure", true)
+	}
+	if len(beforeScript) > 0 {
+		addPair(node, "before_script", beforeScript)
+	}
+	addPair(node, "script", script)
+	if len(afterScript) > 0 {
+		addPair(node, "after_script", afterScript)
+	}
+
+	return node
+}
+
+// gitlabScriptLines 将步骤转换为脚本行，保留从 GitLab CI 解析的原始命令列表
+func gitlabScriptLines(step Step) []string {
+	var lines []string
+	for _, key := range sortedKeys(step.Env) {
+		lines = append(lines, fmt.Sprintf("export %s=\"%s\"", key, convertGitHubExpressions(step.Env[key])))
+	}
+
+	if len(step.Commands) > 0 && strings.Join(step.Commands, "\n") == step.Run && step.WorkingDirectory == "" {
+		for _, command := range step.Commands {
+			lines = append(lines, convertGitHubExpressions(command))
+		}
+		return lines
+	}
+
+	run := strings.TrimRight(convertGitHubExpressions(step.Run), "\n")
+	if step.WorkingDirectory != "" {
+		run = fmt.Sprintf("cd %s\n%s\ncd $CI_PROJECT_DIR", step.WorkingDirectory, run)
+	}
+	return append(lines, run)
+}
+
+// emitGitLabMatrix 生成 parallel:matrix，维度作为第一项，include 的每个组合作为额外的项
+func emitGitLabMatrix(id string, matrix *Matrix, warns *warnings) []*yaml.Node {
+	if len(matrix.Exclude) > 0 {
+		warns.add(id, "matrix exclude has no GitLab CI equivalent and was dropped")
+	}
+	if matrix.FailFast != nil {
+		warns.add(id, "matrix fail-fast has no GitLab CI equivalent and was dropped")
+	}
+	if matrix.MaxParallel > 0 {
+		warns.add(id, "matrix max-parallel has no GitLab CI equivalent and was dropped")
+	}
+
+	var entries []*yaml.Node
+	if len(matrix.Axes) > 0 {
+		axes := &yaml.Node{Kind: yaml.MappingNode}
+		for _, axis := range matrix.Axes {
+			values := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, value := range axis.Values {
+				values.Content = append(values.Content, scalarNode(value))
+			}
+			addPair(axes, axis.Name, values)
+		}
+		entries = append(entries, axes)
+	}
+	for _, include := range matrix.Include {
+		entry := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range sortedKeys(include) {
+			addPair(entry, key, &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: []*yaml.Node{scalarNode(include[key])}})
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// emitGitLabRules 将触发器转换为 workflow:rules，排除条件放在最前面
+func emitGitLabRules(triggers []Trigger, warns *warnings) *yaml.Node {
+	rules := &yaml.Node{Kind: yaml.SequenceNode}
+	var excludes []*yaml.Node
+	addRule := func(condition string, changes []string) {
+		rule := &yaml.Node{Kind: yaml.MappingNode}
+		addPair(rule, "if", condition)
+		if len(changes) > 0 {
+			addPair(rule, "changes", changes)
+		}
+		rules.Content = append(rules.Content, rule)
+	}
+	addExclude := func(condition string) {
+		rule := &yaml.Node{Kind: yaml.MappingNode}
+		addPair(rule, "if", condition)
+		addPair(rule, "when", "never")
+		excludes = append(excludes, rule)
+	}
+
+	for _, trigger := range triggers {
+		if len(trigger.PathsIgnore) > 0 {
+			warns.add("", "%s paths-ignore filter has no GitLab CI equivalent and was dropped", trigger.Event)
+		}
+		switch trigger.Event {
+		case EventPush:
+			for _, branch := range trigger.BranchesIgnore {
+				addExclude(refCondition("$CI_COMMIT_BRANCH", branch))
+			}
+			for _, tag := range trigger.TagsIgnore {
+				addExclude(refCondition("$CI_COMMIT_TAG", tag))
+			}
+			switch {
+			case len(trigger.Branches) > 0 || len(trigger.Tags) > 0:
+			case len(trigger.TagsIgnore) > 0 && len(trigger.BranchesIgnore) == 0:
+				// 只有标签过滤时推送分支不触发
+				addRule(`$CI_COMMIT_TAG`, trigger.Paths)
+			default:
+				addRule(`$CI_PIPELINE_SOURCE == "push"`, trigger.Paths)
+			}
+			for _, branch := range trigger.Branches {
+				addRule(refCondition("$CI_COMMIT_BRANCH", branch), trigger.Paths)
+			}
+			for _, tag := range trigger.Tags {
+				addRule(refCondition("$CI_COMMIT_TAG", tag), trigger.Paths)
+			}
+		case EventPullRequest:
+			for _, branch := range trigger.BranchesIgnore {
+				addExclude(refCondition("$CI_MERGE_REQUEST_TARGET_BRANCH_NAME", branch))
+			}
+			if len(trigger.Branches) == 0 {
+				addRule(`$CI_PIPELINE_SOURCE == "merge_request_event"`, trigger.Paths)
+			}
+			for _, branch := range trigger.Branches {
+				addRule(refCondition("$CI_MERGE_REQUEST_TARGET_BRANCH_NAME", branch), trigger.Paths)
+			}
+			if len(trigger.Tags) > 0 || len(trigger.TagsIgnore) > 0 {
+				warns.add("", "pull_request trigger cannot filter tags, tag filter was dropped")
+			}
+		case EventSchedule:
+			// GitLab 的定时计划配置在项目设置中
+			if trigger.Cron != "" {
+				warns.add("", "schedule '%s' must be configured in the GitLab project's pipeline schedules", trigger.Cron)
+			}
+			addRule(`$CI_PIPELINE_SOURCE == "schedule"`, nil)
+		case EventManual:
+			addRule(`$CI_PIPELINE_SOURCE == "web"`, nil)
+		}
+	}
+
+	rules.Content = append(excludes, rules.Content...)
+	return rules
+}
+
+// resolveExtends 合并 extends 引用的模板，job 自身的键覆盖模板中的键
+func resolveExtends(name string, templates map[string]*yaml.Node, visiting []string) (*yaml.Node, error) {
+	node, exists := templates[name]
+	if !exists {
+		return nil, fmt.Errorf("job %s extends unknown template", name)
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("job %s must be a mapping", name)
+	}
+	if indexOf(visiting, name) >= 0 {
+		return nil, fmt.Errorf("extends cycle detected: %s", strings.Join(append(visiting, name), " -> "))
+	}
+
+	extends := mappingValue(node, "extends")
+	if extends == nil {
+		return node, nil
+	}
+	var parents stringList
+	if err := extends.Decode(&parents); err != nil {
+		return nil, fmt.Errorf("job %s has invalid extends: %w", name, err)
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode}
+	for _, parent := range parents {
+		if _, exists := templates[parent]; !exists {
+			return nil, fmt.Errorf("job %s extends unknown template %s", name, parent)
+		}
+		resolved, err := resolveExtends(parent, templates, append(visiting, name))
+		if err != nil {
+			return nil, err
+		}
+		mergeMapping(merged, resolved)
+	}
+	mergeMapping(merged, node)
+	return merged, nil
+}
+
+// mergeMapping 将 source 的键合并到 target，同名键被覆盖，extends 键不合并
+func mergeMapping(target, source *yaml.Node) {
+	for i := 0; i+1 < len(source.Content); i += 2 {
+		key := source.Content[i].Value
+		if key == "extends" {
+			continue
+		}
+		replaced := false
+		for j := 0; j+1 < len(target.Content); j += 2 {
+			if target.Content[j].Value == key {
+				target.Content[j+1] = source.Content[i+1]
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			target.Content = append(target.Content, source.Content[i], source.Content[i+1])
+		}
+	}
+}
+
+// refCondition 生成分支或标签的匹配条件，包含通配符时转换为正则表达式
+func refCondition(variable, pattern string) string {
+	if !strings.ContainsAny(pattern, "*?") {
+		return fmt.Sprintf(`%s == "%s"`, variable, pattern)
+	}
+	regex := regexp.QuoteMeta(pattern)
+	regex = strings.ReplaceAll(regex, `\*\*`, ".*")
+	regex = strings.ReplaceAll(regex, `\*`, "[^/]*")
+	regex = strings.ReplaceAll(regex, `\?`, ".")
+	regex = strings.ReplaceAll(regex, "/", `\/`)
+	return fmt.Sprintf("%s =~ /^%s$/", variable, regex)
+}
+
+// regexToGlob 将 refCondition 生成的正则表达式还原为通配符，无法还原时返回 false
+func regexToGlob(regex string) (string, bool) {
+	if !strings.HasPrefix(regex, "^") || !strings.HasSuffix(regex, "$") {
+		return "", false
+	}
+	regex = strings.TrimSuffix(strings.TrimPrefix(regex, "^"), "$")
+
+	var glob strings.Builder
+	for i := 0; i < len(regex); i++ {
+		rest := regex[i:]
+		switch {
+		case strings.HasPrefix(rest, `[^\/]*`):
+			glob.WriteByte('*')
+			i += len(`[^\/]*`) - 1
+		case strings.HasPrefix(rest, `[^/]*`):
+			glob.WriteByte('*')
+			i += len(`[^/]*`) - 1
+		case strings.HasPrefix(rest, ".*"):
+			glob.WriteString("**")
+			i++
+		case rest[0] == '.':
+			glob.WriteByte('?')
+		case rest[0] == '\\' && len(rest) > 1:
+			glob.WriteByte(rest[1])
+			i++
+		case strings.ContainsRune(`[](){}|+*?^$`, rune(rest[0])):
+			return "", false
+		default:
+			glob.WriteByte(rest[0])
+		}
+	}
+	return glob.String(), true
+}
+
+// runnerTags 将 self-hosted runner 标签转换为 GitLab runner tags，GitHub 托管的 runner 不需要标签
+func runnerTags(runsOn []string) []string {
+	var tags []string
+	for _, label := range runsOn {
+		if label == "self-hosted" || strings.HasPrefix(label, "ubuntu-") || strings.HasPrefix(label, "windows-") || strings.HasPrefix(label, "macos-") {
+			continue
+		}
+		tags = append(tags, label)
+	}
+	return tags
+}
+
+// convertGitHubExpressions 将 ${{ matrix.x }}、${{ env.X }}、${{ secrets.X }}、常用 github 上下文
+// 以及 $GITHUB_* 环境变量转换为 GitLab 变量引用
+func convertGitHubExpressions(s string) string {
+	s = githubExpression.ReplaceAllStringFunc(s, func(expr string) string {
+		inner := githubExpression.FindStringSubmatch(expr)[1]
+		if variable, ok := githubContextVariables[inner]; ok {
+			return "$" + variable
+		}
+		for _, prefix := range []string{"matrix.", "env.", "secrets.", "vars."} {
+			if strings.HasPrefix(inner, prefix) {
+				return "$" + strings.TrimPrefix(inner, prefix)
+			}
+		}
+		return expr
+	})
+	return replaceVariables(s, githubScriptVariables)
+}
+
+// convertGitHubMap 转换 map 值中的表达式
+func convertGitHubMap(values map[string]string) map[string]string {
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		result[k] = convertGitHubExpressions(v)
+	}
+	return result
+}
+
+// convertGitLabVariables 将 GitLab 预定义变量替换为对应的 GitHub Actions 环境变量
+func convertGitLabVariables(s string) string {
+	return replaceVariables(s, gitlabScriptVariables)
+}
+
+// convertGitLabVariableMap 转换变量映射，没有变量时返回 nil
+func convertGitLabVariableMap(variables map[string]gitlabVariable) map[string]string {
+	if len(variables) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(variables))
+	for key, variable := range variables {
+		result[key] = convertGitLabVariables(variable.Value)
+	}
+	return result
+}
+
+// replaceVariables 按映射替换 $VAR 和 ${VAR} 变量引用
+func replaceVariables(s string, mapping map[string]string) string {
+	return variableReference.ReplaceAllStringFunc(s, func(reference string) string {
+		name := variableReference.FindStringSubmatch(reference)[1]
+		if variable, ok := mapping[name]; ok {
+			return strings.Replace(reference, name, variable, 1)
+		}
+		return reference
+	})
+}
+
+// parseTimeoutMinutes 解析 GitLab 超时时间，例如 "30 minutes"、"1h 30m"、"2 hours"
+func parseTimeoutMinutes(timeout string) int {
+	minutes := 0
+	for _, match := range timeoutPart.FindAllStringSubmatch(timeout, -1) {
+		value, _ := strconv.Atoi(match[1])
+		if strings.HasPrefix(match[2], "h") {
+			value *= 60
+		}
+		minutes += value
+	}
+	return minutes
+}
+
+// serviceName 根据镜像名生成服务名，例如 postgres:15 -> postgres
+func serviceName(image string) string {
+	name := strings.SplitN(image, ":", 2)[0]
+	if index := strings.LastIndex(name, "/"); index >= 0 {
+		name = name[index+1:]
+	}
+	return name
+}
+
+// firstNonEmpty 返回第一个非空的列表
+func firstNonEmpty(lists ...stringList) stringList {
+	for _, list := range lists {
+		if len(list) > 0 {
+			return list
+		}
+	}
+	return nil
+}
+
+// uniqueStrings 去除重复项，保持原有顺序
+func uniqueStrings(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if indexOf(result, value) < 0 {
+			result = append(result, value)
+		}
+	}
+	return result
+}
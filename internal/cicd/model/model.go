@@ -0,0 +1,181 @@
+// Package model 定义与平台无关的管道中间表示，各平台的配置先解析为该模型，再由模型生成目标平台的配置。
+//
+// 模型中的表达式统一使用 GitHub Actions 语法（例如 ${{ matrix.os }}、$GITHUB_SHA），
+// 其他平台的解析器和生成器负责与该语法互相转换
+package model
+
+import (
+	"fmt"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+)
+
+// 触发事件
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventSchedule    = "schedule"
+	EventManual      = "manual"
+)
+
+// Pipeline 管道
+type Pipeline struct {
+	Name     string
+	Triggers []Trigger
+	Env      map[string]string
+	Stages   []string // GitLab CI 的阶段顺序，其他平台为空
+	Jobs     []*Job
+}
+
+// Trigger 触发条件
+type Trigger struct {
+	Event          string
+	Branches       []string
+	BranchesIgnore []string
+	Tags           []string
+	TagsIgnore     []string
+	Paths          []string
+	PathsIgnore    []string
+	Cron           string // 定时触发的 cron 表达式
+}
+
+// Job 任务
+type Job struct {
+	ID              string
+	Name            string   // 显示名称
+	Stage           string   // GitLab CI 阶段
+	RunsOn          []string // runner 标签，GitLab CI 的 tags 以 self-hosted 开头表示
+	Image           string
+	Services        []Service
+	Needs           []string
+	ImplicitNeeds   bool // needs 由 GitLab CI 的阶段顺序推导，生成 GitLab CI 配置时不输出
+	Env             map[string]string
+	Matrix          *Matrix
+	Steps           []Step
+	Caches          []Cache
+	Artifacts       []Artifact
+	Resources       *Resources
+	TimeoutMinutes  int
+	ContinueOnError bool
+	If              string
+}
+
+// Step 步骤
+type Step struct {
+	Name             string
+	Run              string
+	Uses             string
+	With             map[string]string
+	Env              map[string]string
+	WorkingDirectory string
+	If               string
+	Commands         []string // 从 GitLab CI 解析时的原始命令列表，Run 为其换行拼接
+}
+
+// Service 服务容器
+type Service struct {
+	Name  string
+	Image string
+	Env   map[string]string
+	Ports []string
+}
+
+// Matrix 矩阵策略
+type Matrix struct {
+	Axes        []MatrixAxis
+	Include     []map[string]string
+	Exclude     []map[string]string
+	FailFast    *bool
+	MaxParallel int
+}
+
+// MatrixAxis 矩阵维度
+type MatrixAxis struct {
+	Name   string
+	Values []string
+}
+
+// Cache 缓存
+type Cache struct {
+	Key   string
+	Paths []string
+}
+
+// Artifact 产物
+type Artifact struct {
+	Name     string
+	Paths    []string
+	ExpireIn string // 保留时间，例如 "7 days"
+}
+
+// Resources 资源配置
+type Resources struct {
+	Requests ResourceSpec
+	Limits   ResourceSpec
+}
+
+// ResourceSpec CPU 和内存配置
+type ResourceSpec struct {
+	CPU    string
+	Memory string
+}
+
+// Warning 转换警告
+type Warning = common.Warning
+
+// Job 根据 ID 获取 job
+func (p *Pipeline) Job(id string) *Job {
+	for _, job := range p.Jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// Parse 将指定平台的配置解析为管道模型，Mock 平台使用 GitHub Actions 格式
+func Parse(platform common.Platform, content string) (*Pipeline, []Warning, error) {
+	switch platform {
+	case common.PlatformGitHubActions, common.PlatformMock:
+		return ParseGitHubActions(content)
+	case common.PlatformGitLabCI:
+		return ParseGitLabCI(content)
+	default:
+		return nil, nil, fmt.Errorf("unsupported platform: %s", platform)
+	}
+}
+
+// Emit 将管道模型生成为指定平台的配置
+func Emit(platform common.Platform, pipeline *Pipeline) (string, []Warning, error) {
+	switch platform {
+	case common.PlatformGitHubActions, common.PlatformMock:
+		return EmitGitHubActions(pipeline)
+	case common.PlatformGitLabCI:
+		return EmitGitLabCI(pipeline)
+	default:
+		return "", nil, fmt.Errorf("unsupported platform: %s", platform)
+	}
+}
+
+// Convert 将配置从一个平台转换到另一个平台，返回解析和生成过程中的所有警告
+func Convert(content string, from, to common.Platform) (string, []Warning, error) {
+	pipeline, warnings, err := Parse(from, content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	output, emitWarnings, err := Emit(to, pipeline)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return output, append(warnings, emitWarnings...), nil
+}
+
+// warnings 收集转换警告
+type warnings []Warning
+
+// add 添加警告
+func (w *warnings) add(job, format string, args ...interface{}) {
+	*w = append(*w, Warning{Job: job, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,312 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+)
+
+const githubWorkflowContent = `name: CI
+on:
+  push:
+    branches: [ main, 'release/*' ]
+    paths-ignore: ['docs/**']
+  pull_request:
+  schedule:
+    - cron: '0 3 * * 1'
+env:
+  CGO_ENABLED: "0"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+    - uses: actions/checkout@v4
+    - uses: actions/setup-go@v5
+      with:
+        go-version: "1.22"
+    - uses: actions/cache@v4
+      with:
+        path: ~/go/pkg/mod
+        key: go-${{ hashFiles('go.sum') }}
+    - name: Build
+      run: |
+        go build ./...
+        go vet ./...
+    - uses: actions/upload-artifact@v4
+      with:
+        name: bin
+        path: bin/
+        retention-days: 5
+  test:
+    runs-on: [self-hosted, linux]
+    needs: build
+    timeout-minutes: 15
+    strategy:
+      fail-fast: false
+      matrix:
+        go: ["1.21", "1.22"]
+        exclude:
+          - go: "1.21"
+    services:
+      redis:
+        image: redis:7
+        ports: [6379]
+    resources:
+      limits:
+        cpu: 2
+        memory: 4Gi
+    steps:
+    - name: Test
+      working-directory: src
+      env:
+        GOVERSION: ${{ matrix.go }}
+      run: go test ./... -run ${{ github.sha }}
+`
+
+const gitlabCIContent = `stages: [build, test, deploy]
+workflow:
+  rules:
+    - if: $CI_COMMIT_BRANCH =~ /^feature\/[^\/]*$/
+      when: never
+    - if: $CI_COMMIT_BRANCH == "main"
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+variables:
+  GOFLAGS: -mod=mod
+default:
+  image: golang:1.22
+.go-cache:
+  variables:
+    GOPATH: $CI_PROJECT_DIR/.go
+  cache:
+    key: go-$CI_COMMIT_REF_NAME
+    paths: [.go/pkg/mod]
+build:
+  extends: .go-cache
+  stage: build
+  script:
+    - go build -o bin/app ./...
+  artifacts:
+    paths: [bin/]
+    expire_in: 7 days
+test:
+  stage: test
+  services: [postgres:15]
+  variables:
+    KUBERNETES_CPU_LIMIT: "2"
+  parallel:
+    matrix:
+      - GOOS: [linux, darwin]
+  script:
+    - go test ./...
+  after_script:
+    - echo done
+deploy:
+  stage: deploy
+  needs: [test]
+  script:
+    - ./deploy.sh $CI_COMMIT_SHA
+`
+
+func TestGitHubActionsRoundTrip(t *testing.T) {
+	pipeline, _, err := ParseGitHubActions(githubWorkflowContent)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	build := pipeline.Job("build")
+	if len(build.Caches) != 1 || build.Caches[0].Key != "go-${{ hashFiles('go.sum') }}" {
+		t.Errorf("缓存步骤未转换为缓存: %+v", build.Caches)
+	}
+	if len(build.Artifacts) != 1 || build.Artifacts[0].ExpireIn != "5 days" {
+		t.Errorf("上传步骤未转换为产物: %+v", build.Artifacts)
+	}
+	test := pipeline.Job("test")
+	if test.Resources == nil || test.Resources.Limits.Memory != "4Gi" {
+		t.Errorf("资源配置未解析: %+v", test.Resources)
+	}
+
+	output, warnings, err := EmitGitHubActions(pipeline)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("同平台转换不应产生警告: %v", warnings)
+	}
+
+	reparsed, _, err := ParseGitHubActions(output)
+	if err != nil {
+		t.Fatalf("重新解析失败: %v\n%s", err, output)
+	}
+	if !reflect.DeepEqual(pipeline, reparsed) {
+		t.Errorf("往返转换后模型不一致:\n%+v\n%+v\n%s", pipeline, reparsed, output)
+	}
+}
+
+func TestGitLabCIRoundTrip(t *testing.T) {
+	pipeline, _, err := ParseGitLabCI(gitlabCIContent)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	if test := pipeline.Job("test"); !test.ImplicitNeeds || !reflect.DeepEqual(test.Needs, []string{"build"}) {
+		t.Errorf("阶段依赖未推导: %+v", test.Needs)
+	}
+	if deploy := pipeline.Job("deploy"); deploy.ImplicitNeeds {
+		t.Error("显式声明的 needs 不应标记为推导")
+	}
+	if triggers := pipeline.Triggers; len(triggers) != 2 || !reflect.DeepEqual(triggers[0].BranchesIgnore, []string{"feature/*"}) {
+		t.Errorf("workflow:rules 未转换为触发器: %+v", triggers)
+	}
+
+	output, warnings, err := EmitGitLabCI(pipeline)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("同平台转换不应产生警告: %v", warnings)
+	}
+	if strings.Contains(output, "needs: []") {
+		t.Errorf("由阶段推导的依赖不应输出 needs:\n%s", output)
+	}
+
+	reparsed, _, err := ParseGitLabCI(output)
+	if err != nil {
+		t.Fatalf("重新解析失败: %v\n%s", err, output)
+	}
+	if !reflect.DeepEqual(pipeline, reparsed) {
+		t.Errorf("往返转换后模型不一致:\n%+v\n%+v\n%s", pipeline, reparsed, output)
+	}
+}
+
+func TestConvertAcrossPlatforms(t *testing.T) {
+	// GitLab CI -> GitHub Actions -> GitLab CI 保留 job 结构
+	github, warnings, err := Convert(gitlabCIContent, common.PlatformGitLabCI, common.PlatformGitHubActions)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("不应产生警告: %v", warnings)
+	}
+	gitlab, _, err := Convert(github, common.PlatformGitHubActions, common.PlatformGitLabCI)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+
+	original, _, _ := ParseGitLabCI(gitlabCIContent)
+	converted, _, err := ParseGitLabCI(gitlab)
+	if err != nil {
+		t.Fatalf("解析转换结果失败: %v\n%s", err, gitlab)
+	}
+	for _, job := range original.Jobs {
+		result := converted.Job(job.ID)
+		if result == nil {
+			t.Fatalf("job %s 丢失", job.ID)
+		}
+		if !reflect.DeepEqual(job.Needs, result.Needs) || !reflect.DeepEqual(job.Steps, result.Steps) ||
+			!reflect.DeepEqual(job.Caches, result.Caches) || !reflect.DeepEqual(job.Artifacts, result.Artifacts) ||
+			!reflect.DeepEqual(job.Matrix, result.Matrix) || !reflect.DeepEqual(job.Resources, result.Resources) {
+			t.Errorf("job %s 转换后不一致:\n%+v\n%+v", job.ID, job, result)
+		}
+	}
+}
+
+func TestConvertWarnings(t *testing.T) {
+	content := githubWorkflowContent + `    - uses: docker/login-action@v3
+permissions:
+  contents: read
+`
+	_, warnings, err := Convert(content, common.PlatformGitHubActions, common.PlatformGitLabCI)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+
+	expected := []string{
+		"workflow key 'permissions' is not supported",
+		"paths-ignore filter has no GitLab CI equivalent",
+		"must be configured in the GitLab project's pipeline schedules",
+		"action docker/login-action@v3 has no GitLab CI equivalent",
+		"matrix exclude has no GitLab CI equivalent",
+		"matrix fail-fast has no GitLab CI equivalent",
+	}
+	for _, message := range expected {
+		found := false
+		for _, warning := range warnings {
+			if strings.Contains(warning.Message, message) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("缺少警告 %q: %v", message, warnings)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from, _, err := ParseGitHubActions(githubWorkflowContent)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	// 只修改格式和注释时没有差异
+	reformatted := strings.Replace(githubWorkflowContent, "runs-on: [self-hosted, linux]", "# runner\n    runs-on:\n      - self-hosted\n      - linux", 1)
+	same, _, err := ParseGitHubActions(reformatted)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if diff := Compare(from, same); !diff.Empty() {
+		t.Errorf("格式变化不应产生差异: %+v", diff)
+	}
+
+	content := strings.NewReplacer(
+		"    - uses: actions/checkout@v4\n", "",
+		"        go vet ./...\n", "        go vet ./...\n    - name: Lint\n      run: golangci-lint run\n",
+		"timeout-minutes: 15", "timeout-minutes: 30",
+		"      run: go test ./... -run ${{ github.sha }}\n", "      run: go test -race ./...\n  release:\n    runs-on: ubuntu-latest\n    steps:\n    - run: make release\n",
+	).Replace(githubWorkflowContent)
+	to, _, err := ParseGitHubActions(content)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	diff := Compare(from, to)
+	expected := []JobChange{
+		{Job: "build", Change: ChangeChanged, Steps: []StepChange{
+			{Step: "actions/checkout@v4", Change: ChangeRemoved, From: 1},
+			{Step: "Lint", Change: ChangeAdded, To: 3},
+		}},
+		{Job: "test", Change: ChangeChanged, Fields: []string{"timeout-minutes"}, Steps: []StepChange{
+			{Step: "Test", Change: ChangeChanged, From: 1, To: 1, Fields: []string{"run"}},
+		}},
+		{Job: "release", Change: ChangeAdded},
+	}
+	if len(diff.Fields) != 0 || !reflect.DeepEqual(diff.Jobs, expected) {
+		t.Errorf("差异不正确:\n实际 %+v\n期望 %+v", diff, expected)
+	}
+
+	// 两个相同步骤之间删除和新增的步骤配对为修改
+	from.Jobs[0].Steps[0].Uses = "actions/checkout@v3"
+	diff = Compare(from, to)
+	if steps := diff.Jobs[0].Steps; len(steps) != 2 || steps[0].Change != ChangeRemoved {
+		t.Errorf("步骤差异不正确: %+v", steps)
+	}
+	to.Jobs[0].Steps = append([]Step{{Uses: "actions/checkout@v4"}}, to.Jobs[0].Steps...)
+	diff = Compare(from, to)
+	expectedSteps := []StepChange{
+		{Step: "actions/checkout@v4", Change: ChangeChanged, From: 1, To: 1, Fields: []string{"uses"}},
+		{Step: "Lint", Change: ChangeAdded, To: 4},
+	}
+	if !reflect.DeepEqual(diff.Jobs[0].Steps, expectedSteps) {
+		t.Errorf("步骤差异不正确:\n实际 %+v\n期望 %+v", diff.Jobs[0].Steps, expectedSteps)
+	}
+
+	// 删除 job 和修改触发条件
+	to.Jobs = to.Jobs[:1]
+	to.Triggers = to.Triggers[:1]
+	diff = Compare(from, to)
+	if !reflect.DeepEqual(diff.Fields, []string{"triggers"}) || !reflect.DeepEqual(diff.Jobs[len(diff.Jobs)-1], JobChange{Job: "test", Change: ChangeRemoved}) {
+		t.Errorf("差异不正确: %+v", diff)
+	}
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stringList 既可以写成单个字符串也可以写成字符串列表的配置项
+type stringList []string
+
+// UnmarshalYAML 解析单个字符串或字符串列表
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Tag == "!!null" {
+			*l = nil
+			return nil
+		}
+		*l = stringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// stringMap 值为标量的映射，数字和布尔值按原样转换为字符串
+type stringMap map[string]string
+
+// UnmarshalYAML 解析标量映射
+func (m *stringMap) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", value.Line)
+	}
+	result := make(stringMap, len(value.Content)/2)
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		item := value.Content[i+1]
+		if item.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: value of %s must be a scalar", item.Line, value.Content[i].Value)
+		}
+		result[value.Content[i].Value] = item.Value
+	}
+	*m = result
+	return nil
+}
+
+// addPair 向映射节点追加键值对
+func addPair(node *yaml.Node, key string, value interface{}) {
+	valueNode, ok := value.(*yaml.Node)
+	if !ok {
+		valueNode = &yaml.Node{}
+		if value == nil {
+			valueNode.Kind = yaml.ScalarNode
+			valueNode.Tag = "!!null"
+		} else {
+			valueNode.Encode(value)
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, valueNode)
+}
+
+// mappingNode 按键排序生成映射节点，用于稳定输出 map
+func mappingNode(values map[string]string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range sortedKeys(values) {
+		addPair(node, key, values[key])
+	}
+	return node
+}
+
+// mappingValue 获取映射节点中指定键的值节点
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingKeys 获取映射节点的键，保持声明顺序
+func mappingKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// encodeYAML 以两个空格缩进输出 YAML
+func encodeYAML(node *yaml.Node) (string, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	encoder.Close()
+	return buffer.String(), nil
+}
+
+// sortedKeys 获取 map 的键并排序
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indexOf 返回字符串在切片中的位置，不存在时返回 -1
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// topologicalOrder 按依赖关系计算 job 顺序，依赖不存在或存在循环时返回错误
+func topologicalOrder(jobs []*Job) ([]string, error) {
+	byID := make(map[string]*Job, len(jobs))
+	for _, job := range jobs {
+		byID[job.ID] = job
+	}
+
+	var order []string
+	state := make(map[string]int, len(jobs))
+	var visit func(job *Job, path []string) error
+	visit = func(job *Job, path []string) error {
+		switch state[job.ID] {
+		case 1:
+			return fmt.Errorf("job dependency cycle detected: %s", strings.Join(append(path, job.ID), " -> "))
+		case 2:
+			return nil
+		}
+		state[job.ID] = 1
+		for _, need := range job.Needs {
+			needed, exists := byID[need]
+			if !exists {
+				return fmt.Errorf("job %s needs unknown job %s", job.ID, need)
+			}
+			if err := visit(needed, append(path, job.ID)); err != nil {
+				return err
+			}
+		}
+		state[job.ID] = 2
+		order = append(order, job.ID)
+		return nil
+	}
+
+	for _, job := range jobs {
+		if err := visit(job, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,777 @@
+package template
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/validator"
+	"ci-cd-orchestrator/internal/repository"
+)
+
+// BundleVersion 模板包格式的版本
+const BundleVersion = 1
+
+// 模板包的编码格式
+const (
+	BundleFormatJSON  = "json"   // 包含签名和内容的 JSON 文档
+	BundleFormatTarGz = "tar.gz" // 包含 bundle.json 和 bundle.sig 的压缩包
+)
+
+// bundleAlgorithm 模板包的签名算法
+const bundleAlgorithm = "hmac-sha256"
+
+// tar.gz 模板包中的文件
+const (
+	bundleFile    = "bundle.json"
+	signatureFile = "bundle.sig"
+)
+
+// maxBundleFileSize tar.gz 模板包中单个文件的最大大小
+const maxBundleFileSize = 32 << 20
+
+// 导入时与已有模板冲突的处理策略
+const (
+	ConflictSkip      = "skip"      // 保留已有模板，不导入
+	ConflictOverwrite = "overwrite" // 用导入的内容覆盖已有模板，保存为新版本
+	ConflictRename    = "rename"    // 以新名称创建模板，包中继承或引用该模板的模板随之修改
+)
+
+// 模板的导入结果
+const (
+	ImportCreated     = "created"
+	ImportOverwritten = "overwritten"
+	ImportRenamed     = "renamed"
+	ImportSkipped     = "skipped"
+	ImportUnchanged   = "unchanged" // 覆盖时内容与已有模板相同，没有保存新版本
+)
+
+// importNote 导入时保存的版本说明
+const importNote = "imported from bundle"
+
+// ErrBundleSignature 模板包的签名无效，内容被修改过或使用了不同的签名密钥
+var ErrBundleSignature = errors.New("invalid bundle signature")
+
+// ErrBundleKey 没有配置签名密钥
+var ErrBundleKey = errors.New("bundle signing key is not configured")
+
+// Bundle 模板包，包含导出的模板及其所有版本。被依赖的模板在前
+type Bundle struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Templates  []BundleTemplate `json:"templates"`
+}
+
+// BundleTemplate 模板包中的模板，ID 为导出时的模板ID，导入时不使用。Versions 按版本号从旧到新排序
+type BundleTemplate struct {
+	repository.Template
+	Versions []*repository.TemplateVersion `json:"versions"`
+}
+
+// signedBundle JSON 格式的模板包，签名为 payload 紧凑格式的 HMAC-SHA256
+type signedBundle struct {
+	Algorithm string          `json:"algorithm"`
+	Signature string          `json:"signature"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// ExportBundle 导出指定的模板，以及它们直接或间接继承、引用的模板。ids 为空时导出所有模板
+func ExportBundle(templateRepo repository.TemplateRepository, ids []int) (*Bundle, error) {
+	all, err := templateRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*repository.Template)
+	for _, t := range all {
+		if t.Name != "" {
+			byName[t.Name] = t
+		}
+	}
+
+	selected := all
+	if len(ids) > 0 {
+		selected = nil
+		for _, id := range ids {
+			t, err := templateRepo.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, t)
+		}
+	}
+
+	// 依赖的模板排在使用它的模板之前，导入时按顺序即可找到
+	var ordered []*repository.Template
+	added := make(map[int]bool)
+	var add func(t *repository.Template)
+	add = func(t *repository.Template) {
+		if added[t.ID] {
+			return
+		}
+		added[t.ID] = true
+		for _, name := range references(fromRepository(t)) {
+			if dependency, ok := byName[name]; ok {
+				add(dependency)
+			}
+		}
+		ordered = append(ordered, t)
+	}
+	for _, t := range selected {
+		add(t)
+	}
+
+	bundle := &Bundle{Version: BundleVersion, ExportedAt: time.Now().UTC()}
+	for _, t := range ordered {
+		versions, err := templateRepo.ListVersions(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+		bundle.Templates = append(bundle.Templates, BundleTemplate{Template: *t, Versions: versions})
+	}
+	return bundle, nil
+}
+
+// Encode 使用密钥签名并按指定格式编码模板包
+func (b *Bundle) Encode(format string, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrBundleKey
+	}
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	signature := hex.EncodeToString(sign(payload, key))
+
+	switch format {
+	case "", BundleFormatJSON:
+		return json.MarshalIndent(signedBundle{Algorithm: bundleAlgorithm, Signature: signature, Payload: payload}, "", "  ")
+	case BundleFormatTarGz:
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, payload, "", "  "); err != nil {
+			return nil, err
+		}
+		return writeArchive(b.ExportedAt, map[string][]byte{
+			bundleFile:    indented.Bytes(),
+			signatureFile: []byte(bundleAlgorithm + " " + signature + "\n"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown bundle format '%s'", format)
+	}
+}
+
+// DecodeBundle 解析 JSON 或 tar.gz 格式的模板包并使用密钥验证签名
+func DecodeBundle(data []byte, key []byte) (*Bundle, error) {
+	if len(key) == 0 {
+		return nil, ErrBundleKey
+	}
+
+	var signed signedBundle
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		files, err := readArchive(data)
+		if err != nil {
+			return nil, fmt.Errorf("read bundle archive: %w", err)
+		}
+		if files[bundleFile] == nil || files[signatureFile] == nil {
+			return nil, fmt.Errorf("bundle archive must contain %s and %s", bundleFile, signatureFile)
+		}
+		fields := strings.Fields(string(files[signatureFile]))
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed %s", signatureFile)
+		}
+		signed = signedBundle{Algorithm: fields[0], Signature: fields[1], Payload: files[bundleFile]}
+	} else if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+
+	if signed.Algorithm != bundleAlgorithm {
+		return nil, fmt.Errorf("unsupported bundle signature algorithm '%s'", signed.Algorithm)
+	}
+	// 签名基于紧凑格式的内容，与缩进和换行无关
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, signed.Payload); err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+	signature, err := hex.DecodeString(signed.Signature)
+	if err != nil || !hmac.Equal(signature, sign(payload.Bytes(), key)) {
+		return nil, ErrBundleSignature
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(payload.Bytes(), &bundle); err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+	return &bundle, nil
+}
+
+// sign 计算内容的 HMAC-SHA256
+func sign(payload, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// writeArchive 将文件按名称顺序写入 tar.gz 压缩包
+func writeArchive(modTime time.Time, files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readArchive 读取 tar.gz 压缩包中的普通文件
+func readArchive(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxBundleFileSize {
+			return nil, fmt.Errorf("%s is too large", header.Name)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(header.Name, "./")] = content
+	}
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Strategy string // 与已有模板冲突时的处理策略，默认为 skip
+	Author   string // 导入产生的新版本的作者
+	DryRun   bool   // 只检查并返回导入结果，不修改模板
+}
+
+// ImportedTemplate 模板包中一个模板的导入结果
+type ImportedTemplate struct {
+	Name       string `json:"name,omitempty"`
+	Kind       string `json:"kind"`
+	Platform   string `json:"platform,omitempty"`
+	Language   string `json:"language,omitempty"`
+	Framework  string `json:"framework,omitempty"`
+	Action     string `json:"action"`                // created、overwritten、renamed、skipped 或 unchanged
+	TemplateID int    `json:"template_id,omitempty"` // 导入后的模板，跳过时为已有的模板，试运行时新建的模板没有ID
+	ConflictID int    `json:"conflict_id,omitempty"` // 冲突的已有模板
+	RenamedTo  string `json:"renamed_to,omitempty"`  // 重命名后的名称
+	Version    int    `json:"version,omitempty"`     // 导入后的当前版本
+}
+
+// ImportResult 导入结果，顺序与模板包中的模板一致
+type ImportResult struct {
+	DryRun    bool               `json:"dry_run"`
+	Templates []ImportedTemplate `json:"templates"`
+}
+
+// ImportError 模板包中的模板无效或导入后会导致其他模板无效，没有导入任何模板
+type ImportError struct {
+	Problems []string
+}
+
+// Error 实现 error 接口
+func (e *ImportError) Error() string {
+	return "invalid bundle: " + strings.Join(e.Problems, "; ")
+}
+
+// ImportBundle 导入模板包。同名的模板，或平台、语言和框架相同的管道模板视为冲突，按策略跳过、覆盖或重命名。
+// 所有模板先在导入后的模板集合上检查参数、语法、继承和引用关系，无需项目信息即可渲染的管道模板还会验证渲染结果，
+// 全部有效后在一个事务中写入
+func ImportBundle(bundle *Bundle, templateRepo repository.TemplateRepository, options ImportOptions) (*ImportResult, error) {
+	strategy := options.Strategy
+	if strategy == "" {
+		strategy = ConflictSkip
+	}
+	if strategy != ConflictSkip && strategy != ConflictOverwrite && strategy != ConflictRename {
+		return nil, &ImportError{Problems: []string{fmt.Sprintf("unknown conflict strategy '%s'", strategy)}}
+	}
+	change := repository.TemplateChange{Author: options.Author, Note: importNote}
+
+	existing, err := templateRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// 已使用的名称，重命名时避开
+	var problems []string
+	names := make(map[string]bool)
+	for _, t := range existing {
+		if t.Name != "" {
+			names[t.Name] = true
+		}
+	}
+	seen := make(map[string]bool)
+	for _, source := range bundle.Templates {
+		if source.Name == "" {
+			continue
+		}
+		if seen[source.Name] {
+			problems = append(problems, fmt.Sprintf("bundle contains more than one template named '%s'", source.Name))
+		}
+		seen[source.Name] = true
+		names[source.Name] = true
+	}
+	if len(problems) > 0 {
+		return nil, &ImportError{Problems: uniqueProblems(problems)}
+	}
+
+	result := &ImportResult{DryRun: options.DryRun}
+	var imports []*repository.TemplateImport
+	var labels []string
+	renames := make(map[string]string)
+	overwritten := make(map[int]*repository.Template)
+	for i := range bundle.Templates {
+		source := &bundle.Templates[i]
+		template := source.Template
+		if template.Kind == "" {
+			template.Kind = KindPipeline
+		}
+		item := ImportedTemplate{
+			Name:      template.Name,
+			Kind:      template.Kind,
+			Platform:  template.Platform,
+			Language:  template.Language,
+			Framework: template.Framework,
+		}
+		label := bundleLabel(&template)
+
+		conflict := findConflict(&template, existing)
+		if conflict != nil {
+			item.ConflictID = conflict.ID
+		}
+		switch {
+		case conflict == nil:
+			item.Action = ImportCreated
+			template.ID = 0
+			imports = append(imports, &repository.TemplateImport{Template: &template, Versions: source.Versions})
+		case strategy == ConflictSkip, strategy == ConflictOverwrite && unchanged(&template, conflict):
+			item.Action = ImportSkipped
+			if strategy == ConflictOverwrite {
+				item.Action = ImportUnchanged
+			}
+			item.TemplateID = conflict.ID
+			item.Version = conflict.Version
+			// 包中的其他模板改为使用已有的模板
+			if template.Name != "" && conflict.Name != "" && template.Name != conflict.Name {
+				renames[template.Name] = conflict.Name
+			}
+		case strategy == ConflictOverwrite:
+			if overwritten[conflict.ID] != nil {
+				problems = append(problems, fmt.Sprintf("%s: template #%d is already overwritten by another template in the bundle", label, conflict.ID))
+				continue
+			}
+			overwritten[conflict.ID] = conflict
+			item.Action = ImportOverwritten
+			item.TemplateID = conflict.ID
+			template.ID = conflict.ID
+			template.IsBuiltin = conflict.IsBuiltin
+			if template.Name == "" {
+				template.Name = conflict.Name
+			}
+			imports = append(imports, &repository.TemplateImport{Template: &template})
+		default:
+			name := availableName(renameBase(&template)+"-imported", names)
+			names[name] = true
+			if template.Name != "" {
+				renames[template.Name] = name
+			}
+			item.Action = ImportRenamed
+			item.RenamedTo = name
+			template.ID = 0
+			template.Name = name
+			imports = append(imports, &repository.TemplateImport{Template: &template, Versions: source.Versions})
+		}
+		result.Templates = append(result.Templates, item)
+		labels = append(labels, label)
+	}
+
+	for _, imported := range imports {
+		rewriteReferences(imported.Template, renames)
+		if imported.Template.ID == 0 {
+			imported.Versions = importVersions(imported.Template, imported.Versions, change)
+		}
+	}
+
+	problems = append(problems, validateImports(imports, labels, existing, overwritten)...)
+	if len(problems) > 0 {
+		return nil, &ImportError{Problems: uniqueProblems(problems)}
+	}
+
+	if !options.DryRun {
+		if err := templateRepo.Import(imports, change); err != nil {
+			return nil, err
+		}
+	}
+
+	// 补充导入后的模板ID和版本
+	next := 0
+	for i := range result.Templates {
+		item := &result.Templates[i]
+		if item.Action == ImportSkipped || item.Action == ImportUnchanged {
+			continue
+		}
+		template := imports[next].Template
+		next++
+		item.TemplateID = template.ID
+		item.Version = template.Version
+		if options.DryRun && item.Action == ImportOverwritten {
+			item.Version = overwritten[template.ID].Version + 1
+		}
+	}
+	return result, nil
+}
+
+// uniqueProblems 去掉重复的问题，保持原有顺序
+func uniqueProblems(problems []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, problem := range problems {
+		if !seen[problem] {
+			seen[problem] = true
+			result = append(result, problem)
+		}
+	}
+	return result
+}
+
+// bundleLabel 问题说明中模板包里模板的名称
+func bundleLabel(t *repository.Template) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("%s/%s/%s", t.Platform, t.Language, t.Framework)
+}
+
+// findConflict 查找与导入的模板冲突的已有模板：先按名称查找，管道模板再按平台、语言和框架查找
+func findConflict(t *repository.Template, existing []*repository.Template) *repository.Template {
+	if t.Name != "" {
+		for _, e := range existing {
+			if e.Name == t.Name {
+				return e
+			}
+		}
+	}
+	if t.Kind != KindPipeline {
+		return nil
+	}
+	for _, e := range existing {
+		if e.Kind == KindPipeline && e.Platform == t.Platform && e.Language == t.Language && e.Framework == t.Framework {
+			return e
+		}
+	}
+	return nil
+}
+
+// invalidNameChars 模板名称中不允许的字符
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// renameBase 重命名时使用的基础名称，没有名称的模板使用平台、语言和框架
+func renameBase(t *repository.Template) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	base := invalidNameChars.ReplaceAllString(strings.ToLower(strings.Join([]string{t.Platform, t.Language, t.Framework}, "-")), "-")
+	base = strings.Trim(base, "-._")
+	if base == "" {
+		return "template"
+	}
+	return base
+}
+
+// availableName 返回未被使用的名称，名称已被使用时添加数字后缀
+func availableName(base string, names map[string]bool) string {
+	if !names[base] {
+		return base
+	}
+	for i := 2; ; i++ {
+		name := fmt.Sprintf("%s-%d", base, i)
+		if !names[name] {
+			return name
+		}
+	}
+}
+
+// rewriteReferences 将模板继承和引用的名称替换为新名称，模板语法有误时只替换继承的名称
+func rewriteReferences(t *repository.Template, renames map[string]string) {
+	if len(renames) == 0 {
+		return
+	}
+	if name, ok := renames[t.Extends]; ok {
+		t.Extends = name
+	}
+
+	tmpl, err := newTemplate(setName(fromRepository(t)), new(*texttemplate.Template)).Parse(t.Content)
+	if err != nil {
+		return
+	}
+	nodes, _, err := includeNodes(tmpl)
+	if err != nil {
+		return
+	}
+	// 从后向前替换，前面节点的位置不受影响
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Pos > nodes[j].Pos })
+	content := t.Content
+	for _, node := range nodes {
+		name, ok := renames[node.Text]
+		start := int(node.Pos)
+		end := start + len(node.Quoted)
+		if !ok || end > len(content) || content[start:end] != node.Quoted {
+			continue
+		}
+		content = content[:start] + strconv.Quote(name) + content[end:]
+	}
+	t.Content = content
+}
+
+// importVersions 返回新建模板要保存的历史版本。当前内容与包中的当前版本不一致时（如引用的模板被重命名），
+// 追加一个新版本并更新模板的版本号
+func importVersions(t *repository.Template, versions []*repository.TemplateVersion, change repository.TemplateChange) []*repository.TemplateVersion {
+	var history []*repository.TemplateVersion
+	latest := 0
+	var current *repository.TemplateVersion
+	for _, v := range versions {
+		if v == nil || v.Version <= 0 {
+			continue
+		}
+		copied := *v
+		history = append(history, &copied)
+		if v.Version > latest {
+			latest = v.Version
+		}
+		if v.Version == t.Version {
+			current = &copied
+		}
+	}
+	if current != nil && sameContent(current, t) {
+		return history
+	}
+
+	if t.Version > latest {
+		latest = t.Version
+	}
+	t.Version = latest + 1
+	return append(history, &repository.TemplateVersion{
+		Version:    t.Version,
+		Extends:    t.Extends,
+		Platform:   t.Platform,
+		Language:   t.Language,
+		Framework:  t.Framework,
+		Content:    t.Content,
+		Filename:   t.Filename,
+		ConfigType: t.ConfigType,
+		Parameters: t.Parameters,
+		Author:     change.Author,
+		Note:       change.Note,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// sameContent 检查版本的内容是否与模板一致
+func sameContent(v *repository.TemplateVersion, t *repository.Template) bool {
+	if v.Extends != t.Extends || v.Platform != t.Platform || v.Language != t.Language || v.Framework != t.Framework ||
+		v.Content != t.Content || v.Filename != t.Filename || v.ConfigType != t.ConfigType {
+		return false
+	}
+	a, _ := json.Marshal(v.Parameters)
+	b, _ := json.Marshal(t.Parameters)
+	return bytes.Equal(a, b) || len(v.Parameters) == 0 && len(t.Parameters) == 0
+}
+
+// unchanged 检查导入的模板与已有模板的名称和内容是否相同
+func unchanged(t, existing *repository.Template) bool {
+	if t.Name != "" && t.Name != existing.Name || t.Kind != existing.Kind {
+		return false
+	}
+	return sameContent(&repository.TemplateVersion{
+		Extends:    existing.Extends,
+		Platform:   existing.Platform,
+		Language:   existing.Language,
+		Framework:  existing.Framework,
+		Content:    existing.Content,
+		Filename:   existing.Filename,
+		ConfigType: existing.ConfigType,
+		Parameters: existing.Parameters,
+	}, t)
+}
+
+// nameIndex 按名称查找模板
+type nameIndex map[string]*Template
+
+// GetTemplateByName 实现 Resolver 接口
+func (n nameIndex) GetTemplateByName(name string) (*Template, error) {
+	if t, ok := n[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// validateImports 在导入后的模板集合上检查导入的模板，以及继承或引用被覆盖模板的已有模板
+func validateImports(imports []*repository.TemplateImport, labels []string, existing []*repository.Template, overwritten map[int]*repository.Template) []string {
+	var problems []string
+	index := make(nameIndex)
+	var others []*Template
+	for _, t := range existing {
+		if overwritten[t.ID] != nil {
+			continue
+		}
+		converted := fromRepository(t)
+		others = append(others, converted)
+		if t.Name != "" {
+			index[t.Name] = converted
+		}
+	}
+
+	candidates := make([]*Template, len(imports))
+	for i, imported := range imports {
+		candidates[i] = fromRepository(imported.Template)
+		if name := imported.Template.Name; name != "" {
+			if previous, ok := index[name]; ok && !contains(candidates, previous) {
+				problems = append(problems, fmt.Sprintf("%s: template name '%s' is already used by template %s", labels[i], name, displayName(previous)))
+			}
+			index[name] = candidates[i]
+		}
+	}
+
+	configValidator := validator.NewValidator()
+	for i, t := range candidates {
+		if problem := validateImport(t, index, configValidator); problem != "" {
+			problems = append(problems, labels[i]+": "+problem)
+		}
+	}
+
+	// 覆盖已有模板后（包括改名），继承或引用它的其他模板必须仍然有效
+	affected := make(map[string]bool)
+	for _, t := range candidates {
+		if t.Name != "" {
+			affected[t.Name] = true
+		}
+	}
+	for _, t := range overwritten {
+		if t.Name != "" {
+			affected[t.Name] = true
+		}
+	}
+	checked := make(map[*Template]bool)
+	for name := range affected {
+		for _, user := range dependents(name, others) {
+			if checked[user] {
+				continue
+			}
+			checked[user] = true
+			if _, err := compose(user, index); err != nil {
+				problems = append(problems, fmt.Sprintf("template %s would become invalid: %v", displayName(user), err))
+			}
+		}
+	}
+	return problems
+}
+
+// validateImport 检查一个导入的模板，返回问题说明，没有问题时返回空字符串
+func validateImport(t *Template, index nameIndex, configValidator validator.Validator) string {
+	switch t.Kind {
+	case KindPipeline:
+		if t.Platform == "" || t.Content == "" || t.Filename == "" || t.ConfigType == "" {
+			return "platform, content, filename and config_type are required"
+		}
+	case KindFragment:
+		if t.Name == "" || t.Content == "" {
+			return "fragment requires a name and content"
+		}
+	default:
+		return fmt.Sprintf("unknown template kind '%s'", t.Kind)
+	}
+	if t.Name != "" {
+		if err := ValidateName(t.Name); err != nil {
+			return err.Error()
+		}
+	}
+	if err := ValidateParameters(t.Parameters); err != nil {
+		return err.Error()
+	}
+	if err := ValidateContent(t.Content, t.Parameters); err != nil {
+		return err.Error()
+	}
+	c, err := compose(t, index)
+	if err != nil {
+		return err.Error()
+	}
+	if t.Kind != KindPipeline {
+		return ""
+	}
+	switch t.Platform {
+	case common.PlatformGitHubActions, common.PlatformGitLabCI, common.PlatformMock:
+	default:
+		return ""
+	}
+
+	// 所有参数都有默认值时渲染模板并验证生成的配置，需要项目信息的模板只检查模板本身
+	if _, err := ResolveParameters(c.parameters(), nil, nil); err != nil {
+		return ""
+	}
+	content, _, err := t.Render(index, nil, nil)
+	if err != nil {
+		return err.Error()
+	}
+	diagnostics := configValidator.Validate(&common.PipelineConfig{
+		Platform:   t.Platform,
+		ConfigType: t.ConfigType,
+		Content:    content,
+		Filename:   t.Filename,
+	})
+	if err := diagnostics.Err(); err != nil {
+		return "rendered config is invalid: " + err.Error()
+	}
+	return ""
+}
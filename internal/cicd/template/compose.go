@@ -0,0 +1,552 @@
+package template
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	parsetree "text/template/parse"
+
+	"ci-cd-orchestrator/internal/repository"
+)
+
+// 模板类型
+const (
+	KindPipeline = repository.TemplateKindPipeline
+	KindFragment = repository.TemplateKindFragment
+)
+
+// ErrTemplateNotFound 按名称找不到模板
+var ErrTemplateNotFound = errors.New("template not found")
+
+// templateName 模板名称，在 extends 和 include 中引用
+var templateName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// rootName 没有名称的模板在模板集合中使用的名称，不是合法的模板名称，不会与片段冲突
+const rootName = "(template)"
+
+// Resolver 按名称查找被继承或引用的模板，找不到时返回 ErrTemplateNotFound
+type Resolver interface {
+	GetTemplateByName(name string) (*Template, error)
+}
+
+// overlay 优先返回指定的模板，用于在保存之前检查修改后的模板
+type overlay struct {
+	template *Template
+	resolver Resolver
+}
+
+// GetTemplateByName 实现 Resolver 接口
+func (o overlay) GetTemplateByName(name string) (*Template, error) {
+	if o.template.Name == name {
+		return o.template, nil
+	}
+	if o.resolver == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return o.resolver.GetTemplateByName(name)
+}
+
+// funcMap 模板中可用的函数。include 渲染指定名称的片段或 define 定义的模板，结果去掉末尾的换行；
+// indent 为每个非空行添加缩进，nindent 在 indent 的结果前加一个换行
+func funcMap(set **texttemplate.Template) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			if *set == nil || (*set).Lookup(name) == nil {
+				return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+			}
+			var buf bytes.Buffer
+			if err := (*set).ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return strings.TrimRight(buf.String(), "\n"), nil
+		},
+		"indent": indent,
+		"nindent": func(spaces int, text string) string {
+			return "\n" + indent(spaces, text)
+		},
+	}
+}
+
+// indent 为每个非空行添加指定数量的空格
+func indent(spaces int, text string) string {
+	prefix := strings.Repeat(" ", spaces)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newTemplate 创建模板，set 在执行前指向完整的模板集合，供 include 使用
+func newTemplate(name string, set **texttemplate.Template) *texttemplate.Template {
+	return texttemplate.New(name).Delims(leftDelim, rightDelim).Funcs(funcMap(set)).Option("missingkey=error")
+}
+
+// setName 模板在模板集合中的名称
+func setName(t *Template) string {
+	if t.Name == "" {
+		return rootName
+	}
+	return t.Name
+}
+
+// displayName 错误信息中模板的名称
+func displayName(t *Template) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if t.ID != 0 {
+		return fmt.Sprintf("#%d", t.ID)
+	}
+	return "template"
+}
+
+// ValidateName 检查模板名称是否有效
+func ValidateName(name string) error {
+	if !templateName.MatchString(name) {
+		return fmt.Errorf("invalid template name '%s': use lowercase letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// includes 返回模板集合中 include 引用的名称（按首次出现的顺序）和 define、block 定义的名称
+func includes(tmpl *texttemplate.Template) ([]string, map[string]bool, error) {
+	nodes, defined, err := includeNodes(tmpl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		if !seen[node.Text] {
+			seen[node.Text] = true
+			names = append(names, node.Text)
+		}
+	}
+	return names, defined, nil
+}
+
+// includeNodes 返回模板集合中 include 的名称参数节点（节点位置为其在模板内容中的偏移）和 define、block 定义的名称
+func includeNodes(tmpl *texttemplate.Template) ([]*parsetree.StringNode, map[string]bool, error) {
+	var nodes []*parsetree.StringNode
+
+	var walk func(node parsetree.Node) error
+	walk = func(node parsetree.Node) error {
+		switch n := node.(type) {
+		case *parsetree.ListNode:
+			if n == nil {
+				return nil
+			}
+			for _, child := range n.Nodes {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		case *parsetree.ActionNode:
+			return walk(n.Pipe)
+		case *parsetree.IfNode:
+			return walkBranch(walk, &n.BranchNode)
+		case *parsetree.RangeNode:
+			return walkBranch(walk, &n.BranchNode)
+		case *parsetree.WithNode:
+			return walkBranch(walk, &n.BranchNode)
+		case *parsetree.TemplateNode:
+			return walk(n.Pipe)
+		case *parsetree.ChainNode:
+			return walk(n.Node)
+		case *parsetree.PipeNode:
+			if n == nil {
+				return nil
+			}
+			for _, cmd := range n.Cmds {
+				if err := walk(cmd); err != nil {
+					return err
+				}
+			}
+		case *parsetree.CommandNode:
+			if ident, ok := n.Args[0].(*parsetree.IdentifierNode); ok && ident.Ident == "include" {
+				var name *parsetree.StringNode
+				if len(n.Args) > 1 {
+					name, _ = n.Args[1].(*parsetree.StringNode)
+				}
+				if name == nil {
+					return fmt.Errorf("include requires a quoted template name: %s", n)
+				}
+				nodes = append(nodes, name)
+			}
+			for _, arg := range n.Args {
+				if err := walk(arg); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// 先遍历模板主体，再按名称遍历 define 定义的模板，保证结果的顺序稳定
+	defined := make(map[string]bool)
+	templates := tmpl.Templates()
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name() < templates[j].Name() })
+	if tmpl.Tree != nil {
+		if err := walk(tmpl.Tree.Root); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, t := range templates {
+		if t.Name() == tmpl.Name() || t.Tree == nil {
+			continue
+		}
+		defined[t.Name()] = true
+		if err := walk(t.Tree.Root); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nodes, defined, nil
+}
+
+// walkBranch 遍历 if、range、with 节点
+func walkBranch(walk func(parsetree.Node) error, n *parsetree.BranchNode) error {
+	if err := walk(n.Pipe); err != nil {
+		return err
+	}
+	if err := walk(n.List); err != nil {
+		return err
+	}
+	return walk(n.ElseList)
+}
+
+// composition 模板与其继承的基础模板和引用的片段
+type composition struct {
+	chain     []*Template // 继承链，从当前模板到最底层的基础模板
+	fragments []*Template // 引用的片段，按首次引用的顺序
+	order     []*Template // 所有模板，被依赖的模板在前
+	static    bool        // 模板原样输出，不作为模板渲染
+}
+
+// 模板的遍历状态
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// composer 遍历模板的继承和引用关系，检查引用的模板是否存在以及是否有循环
+type composer struct {
+	root      *Template
+	resolver  Resolver
+	templates map[string]*Template
+	state     map[*Template]int
+	path      []string
+	defined   map[string]bool
+	missing   []reference
+	result    *composition
+}
+
+// reference 暂时找不到的引用，可能是 define 定义的模板
+type reference struct {
+	from string
+	name string
+}
+
+// compose 解析模板的继承和引用关系。没有声明参数、没有继承基础模板、也没有引用片段的模板原样输出
+func compose(root *Template, resolver Resolver) (*composition, error) {
+	if root.Kind != KindFragment && root.Extends == "" && len(root.Parameters) == 0 {
+		tmpl, err := newTemplate(setName(root), new(*texttemplate.Template)).Parse(root.Content)
+		if err != nil {
+			return &composition{chain: []*Template{root}, order: []*Template{root}, static: true}, nil
+		}
+		if names, _, err := includes(tmpl); err != nil || len(names) == 0 {
+			return &composition{chain: []*Template{root}, order: []*Template{root}, static: true}, nil
+		}
+	}
+
+	c := &composer{
+		root:      root,
+		resolver:  resolver,
+		templates: make(map[string]*Template),
+		state:     make(map[*Template]int),
+		defined:   make(map[string]bool),
+		result:    &composition{},
+	}
+	if err := c.visit(root); err != nil {
+		return nil, err
+	}
+	for _, ref := range c.missing {
+		if !c.defined[ref.name] {
+			return nil, fmt.Errorf("%s includes '%s': %w", ref.from, ref.name, ErrTemplateNotFound)
+		}
+	}
+
+	for t := root; ; {
+		c.result.chain = append(c.result.chain, t)
+		if t.Extends == "" {
+			break
+		}
+		t = c.templates[t.Extends]
+	}
+	return c.result, nil
+}
+
+// lookup 按名称查找模板，同一个名称只查找一次
+func (c *composer) lookup(name string) (*Template, error) {
+	if name == c.root.Name {
+		return c.root, nil
+	}
+	if t, ok := c.templates[name]; ok {
+		return t, nil
+	}
+	if c.resolver == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	t, err := c.resolver.GetTemplateByName(name)
+	if err != nil {
+		return nil, err
+	}
+	c.templates[name] = t
+	return t, nil
+}
+
+// visit 深度优先遍历模板依赖的基础模板和片段
+func (c *composer) visit(t *Template) error {
+	switch c.state[t] {
+	case visiting:
+		return fmt.Errorf("template cycle: %s -> %s", strings.Join(c.path, " -> "), displayName(t))
+	case visited:
+		return nil
+	}
+	c.state[t] = visiting
+	c.path = append(c.path, displayName(t))
+
+	tmpl, err := newTemplate(setName(t), new(*texttemplate.Template)).Parse(t.Content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(t), err)
+	}
+	names, defined, err := includes(tmpl)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(t), err)
+	}
+	for name := range defined {
+		c.defined[name] = true
+	}
+
+	if t.Extends != "" {
+		// 继承基础模板时只能通过 define 覆盖基础模板中的 block，其余内容不会输出
+		if tmpl.Tree != nil && !parsetree.IsEmptyTree(tmpl.Tree.Root) {
+			return fmt.Errorf("%s extends '%s' and may only contain define blocks", displayName(t), t.Extends)
+		}
+		base, err := c.lookup(t.Extends)
+		if errors.Is(err, ErrTemplateNotFound) || errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s extends '%s': %w", displayName(t), t.Extends, ErrTemplateNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.visit(base); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		fragment, err := c.lookup(name)
+		if errors.Is(err, ErrTemplateNotFound) || errors.Is(err, sql.ErrNoRows) {
+			c.missing = append(c.missing, reference{from: displayName(t), name: name})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if fragment.Extends != "" {
+			return fmt.Errorf("%s includes '%s', which extends '%s' and cannot be included", displayName(t), name, fragment.Extends)
+		}
+		if err := c.visit(fragment); err != nil {
+			return err
+		}
+		if !contains(c.result.fragments, fragment) {
+			c.result.fragments = append(c.result.fragments, fragment)
+		}
+	}
+
+	c.path = c.path[:len(c.path)-1]
+	c.state[t] = visited
+	c.result.order = append(c.result.order, t)
+	return nil
+}
+
+// contains 检查模板是否在列表中
+func contains(templates []*Template, t *Template) bool {
+	for _, template := range templates {
+		if template == t {
+			return true
+		}
+	}
+	return false
+}
+
+// parameters 合并所有模板的参数定义，同名参数以当前模板的定义为准，其次是依赖关系上更近的模板
+func (c *composition) parameters() []Parameter {
+	seen := make(map[string]bool)
+	var merged []Parameter
+	for i := len(c.order) - 1; i >= 0; i-- {
+		for _, param := range c.order[i].Parameters {
+			if !seen[param.Name] {
+				seen[param.Name] = true
+				merged = append(merged, param)
+			}
+		}
+	}
+	return merged
+}
+
+// execute 渲染组合后的模板：以最底层的基础模板为主体，依次加入片段和继承链上的模板，
+// 子模板中 define 的定义覆盖基础模板中同名的 block
+func (c *composition) execute(values map[string]interface{}) (string, error) {
+	var set *texttemplate.Template
+	base := c.chain[len(c.chain)-1]
+	tmpl, err := newTemplate(setName(base), &set).Parse(base.Content)
+	if err != nil {
+		return "", err
+	}
+	for _, fragment := range c.fragments {
+		if contains(c.chain, fragment) {
+			continue
+		}
+		if _, err := tmpl.New(setName(fragment)).Parse(fragment.Content); err != nil {
+			return "", err
+		}
+	}
+	for i := len(c.chain) - 2; i >= 0; i-- {
+		if _, err := tmpl.New(setName(c.chain[i])).Parse(c.chain[i].Content); err != nil {
+			return "", err
+		}
+	}
+	set = tmpl
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// references 返回模板直接继承和引用的模板名称，模板语法有误时只返回继承的模板
+func references(t *Template) []string {
+	var names []string
+	if t.Extends != "" {
+		names = append(names, t.Extends)
+	}
+	tmpl, err := newTemplate(setName(t), new(*texttemplate.Template)).Parse(t.Content)
+	if err != nil {
+		return names
+	}
+	included, _, _ := includes(tmpl)
+	return append(names, included...)
+}
+
+// dependents 返回直接或间接继承、引用指定名称模板的模板
+func dependents(name string, templates []*Template) []*Template {
+	var result []*Template
+	names := map[string]bool{name: true}
+	for changed := true; changed; {
+		changed = false
+		for _, t := range templates {
+			if contains(result, t) {
+				continue
+			}
+			for _, ref := range references(t) {
+				if names[ref] {
+					result = append(result, t)
+					if t.Name != "" {
+						names[t.Name] = true
+					}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Dependents 返回直接或间接继承、引用指定名称模板的模板，修改该模板会影响这些模板下次渲染的结果
+func Dependents(name string, templates []*repository.Template) []*repository.Template {
+	converted := make([]*Template, len(templates))
+	for i, t := range templates {
+		converted[i] = fromRepository(t)
+	}
+
+	var result []*repository.Template
+	for _, t := range dependents(name, converted) {
+		for i := range converted {
+			if converted[i] == t {
+				result = append(result, templates[i])
+			}
+		}
+	}
+	return result
+}
+
+// ValidateComposition 检查模板的类型、名称以及继承和引用关系：名称唯一，引用的模板存在，没有循环引用，
+// 且修改后使用该模板的其他模板仍然有效。templateRepo 用于查找其他模板
+func ValidateComposition(template *repository.Template, templateRepo repository.TemplateRepository) error {
+	switch template.Kind {
+	case "", KindPipeline:
+	case KindFragment:
+		if template.Name == "" {
+			return fmt.Errorf("fragment requires a name")
+		}
+	default:
+		return fmt.Errorf("unknown template kind '%s'", template.Kind)
+	}
+	if template.Name != "" {
+		if err := ValidateName(template.Name); err != nil {
+			return err
+		}
+	}
+
+	all, err := templateRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	var stored []*Template
+	var previous *Template
+	for _, t := range all {
+		if t.ID == template.ID {
+			previous = fromRepository(t)
+			continue
+		}
+		if template.Name != "" && t.Name == template.Name {
+			return fmt.Errorf("template name '%s' is already used by template %d", template.Name, t.ID)
+		}
+		stored = append(stored, fromRepository(t))
+	}
+
+	// 重命名后，继承或引用原名称的模板将找不到该模板
+	if previous != nil && previous.Name != "" && previous.Name != template.Name {
+		if users := dependents(previous.Name, stored); len(users) > 0 {
+			return fmt.Errorf("template '%s' is used by %s and cannot be renamed", previous.Name, displayName(users[0]))
+		}
+	}
+
+	candidate := fromRepository(template)
+	resolver := overlay{template: candidate, resolver: &TemplateManager{templateRepo: templateRepo}}
+	if _, err := compose(candidate, resolver); err != nil {
+		return err
+	}
+	if candidate.Name == "" {
+		return nil
+	}
+	for _, user := range dependents(candidate.Name, stored) {
+		if _, err := compose(user, resolver); err != nil {
+			return fmt.Errorf("template %s, which uses '%s', would become invalid: %w", displayName(user), candidate.Name, err)
+		}
+	}
+	return nil
+}
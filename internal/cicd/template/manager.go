@@ -1,6 +1,8 @@
 package template
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"ci-cd-orchestrator/internal/cicd/common"
@@ -10,18 +12,64 @@ import (
 
 // Template 配置模板
 type Template struct {
+	ID         int               `json:"id"`
+	Name       string            `json:"name,omitempty"`
+	Kind       string            `json:"kind"`
+	Extends    string            `json:"extends,omitempty"`
 	Platform   common.Platform   `json:"platform"`
 	Language   string            `json:"language"`
 	Framework  string            `json:"framework"`
 	Content    string            `json:"content"`
 	Filename   string            `json:"filename"`
 	ConfigType common.ConfigType `json:"config_type"`
+	Parameters []Parameter       `json:"parameters,omitempty"`
+	Version    int               `json:"version"`
+}
+
+// builtinChange 内置模板初始版本的作者和说明
+var builtinChange = repository.TemplateChange{Author: "system", Note: "builtin template"}
+
+// fromRepository 将数据库中的模板转换为Template类型
+func fromRepository(dbTemplate *repository.Template) *Template {
+	return &Template{
+		ID:         dbTemplate.ID,
+		Name:       dbTemplate.Name,
+		Kind:       dbTemplate.Kind,
+		Extends:    dbTemplate.Extends,
+		Platform:   common.Platform(dbTemplate.Platform),
+		Language:   dbTemplate.Language,
+		Framework:  dbTemplate.Framework,
+		Content:    dbTemplate.Content,
+		Filename:   dbTemplate.Filename,
+		ConfigType: common.ConfigType(dbTemplate.ConfigType),
+		Parameters: dbTemplate.Parameters,
+		Version:    dbTemplate.Version,
+	}
+}
+
+// toRepository 将Template转换为数据库模板
+func toRepository(template *Template, isBuiltin bool) *repository.Template {
+	return &repository.Template{
+		ID:         template.ID,
+		Name:       template.Name,
+		Kind:       template.Kind,
+		Extends:    template.Extends,
+		Platform:   string(template.Platform),
+		Language:   template.Language,
+		Framework:  template.Framework,
+		Content:    template.Content,
+		Filename:   template.Filename,
+		ConfigType: string(template.ConfigType),
+		Parameters: template.Parameters,
+		IsBuiltin:  isBuiltin,
+	}
 }
 
 // Manager 模板管理器接口
 type Manager interface {
 	GetTemplate(techStack *techstack.TechStack, platform common.Platform) (*Template, error)
 	GetTemplateByID(id int) (*Template, error)
+	GetTemplateByName(name string) (*Template, error)
 	AddTemplate(template *Template) error
 	ListTemplates() []*Template
 }
@@ -50,14 +98,15 @@ func NewTemplateManager(templateRepo repository.TemplateRepository) Manager {
 
 // GetTemplate 获取适合的模板
 func (m *TemplateManager) GetTemplate(techStack *techstack.TechStack, platform common.Platform) (*Template, error) {
-	// 按优先级查找模板：
+	// 按优先级查找模板，片段不能直接生成配置：
 	// 1. 语言 + 框架 + 平台
 	// 2. 语言 + 平台
 	// 3. 平台默认模板
 
 	// 查找语言 + 框架 + 平台的模板
 	for _, template := range m.templates {
-		if template.Platform == platform &&
+		if template.Kind != KindFragment &&
+			template.Platform == platform &&
 			template.Language == techStack.Language &&
 			template.Framework == techStack.Framework {
 			return template, nil
@@ -66,7 +115,8 @@ func (m *TemplateManager) GetTemplate(techStack *techstack.TechStack, platform c
 
 	// 查找语言 + 平台的模板
 	for _, template := range m.templates {
-		if template.Platform == platform &&
+		if template.Kind != KindFragment &&
+			template.Platform == platform &&
 			template.Language == techStack.Language &&
 			template.Framework == "" {
 			return template, nil
@@ -75,7 +125,8 @@ func (m *TemplateManager) GetTemplate(techStack *techstack.TechStack, platform c
 
 	// 查找平台默认模板
 	for _, template := range m.templates {
-		if template.Platform == platform &&
+		if template.Kind != KindFragment &&
+			template.Platform == platform &&
 			template.Language == "" &&
 			template.Framework == "" {
 			return template, nil
@@ -87,14 +138,7 @@ func (m *TemplateManager) GetTemplate(techStack *techstack.TechStack, platform c
 		dbTemplate, err := m.templateRepo.GetByPlatformAndLanguage(string(platform), techStack.Language, techStack.Framework)
 		if err == nil {
 			// 转换为Template类型
-			template := &Template{
-				Platform:   common.Platform(dbTemplate.Platform),
-				Language:   dbTemplate.Language,
-				Framework:  dbTemplate.Framework,
-				Content:    dbTemplate.Content,
-				Filename:   dbTemplate.Filename,
-				ConfigType: common.ConfigType(dbTemplate.ConfigType),
-			}
+			template := fromRepository(dbTemplate)
 			// 添加到内存中
 			m.templates = append(m.templates, template)
 			return template, nil
@@ -117,15 +161,7 @@ func (m *TemplateManager) loadTemplatesFromDB() {
 
 	// 转换为Template类型并添加到内存中
 	for _, dbTemplate := range dbTemplates {
-		template := &Template{
-			Platform:   common.Platform(dbTemplate.Platform),
-			Language:   dbTemplate.Language,
-			Framework:  dbTemplate.Framework,
-			Content:    dbTemplate.Content,
-			Filename:   dbTemplate.Filename,
-			ConfigType: common.ConfigType(dbTemplate.ConfigType),
-		}
-		m.templates = append(m.templates, template)
+		m.templates = append(m.templates, fromRepository(dbTemplate))
 	}
 }
 
@@ -136,16 +172,12 @@ func (m *TemplateManager) AddTemplate(template *Template) error {
 
 	// 保存到数据库中
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(template.Platform),
-			Language:   template.Language,
-			Framework:  template.Framework,
-			Content:    template.Content,
-			Filename:   template.Filename,
-			ConfigType: string(template.ConfigType),
-			IsBuiltin:  false,
+		dbTemplate := toRepository(template, false)
+		if err := m.templateRepo.Create(dbTemplate, repository.TemplateChange{}); err != nil {
+			return err
 		}
-		return m.templateRepo.Create(dbTemplate)
+		template.ID = dbTemplate.ID
+		template.Version = dbTemplate.Version
 	}
 
 	return nil
@@ -163,14 +195,7 @@ func (m *TemplateManager) GetTemplateByID(id int) (*Template, error) {
 		dbTemplate, err := m.templateRepo.GetByID(id)
 		if err == nil {
 			// 转换为Template类型
-			template := &Template{
-				Platform:   common.Platform(dbTemplate.Platform),
-				Language:   dbTemplate.Language,
-				Framework:  dbTemplate.Framework,
-				Content:    dbTemplate.Content,
-				Filename:   dbTemplate.Filename,
-				ConfigType: common.ConfigType(dbTemplate.ConfigType),
-			}
+			template := fromRepository(dbTemplate)
 			return template, nil
 		}
 	}
@@ -178,137 +203,176 @@ func (m *TemplateManager) GetTemplateByID(id int) (*Template, error) {
 	return nil, fmt.Errorf("template not found with ID: %d", id)
 }
 
+// GetTemplateByName 根据名称获取模板，优先从数据库中查找，保证片段的修改在下次渲染时生效
+func (m *TemplateManager) GetTemplateByName(name string) (*Template, error) {
+	if m.templateRepo != nil {
+		dbTemplate, err := m.templateRepo.GetByName(name)
+		if err == nil {
+			return fromRepository(dbTemplate), nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	for _, template := range m.templates {
+		if template.Name == name {
+			return template, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
 // initDefaultTemplates 初始化默认模板
 func (m *TemplateManager) initDefaultTemplates() {
-	// 检查数据库中是否已经有内置模板
+	// 检查数据库中哪些平台已经有内置模板，新增的平台在升级后也能获得内置模板
+	builtinPlatforms := make(map[common.Platform]bool)
 	if m.templateRepo != nil {
 		allTemplates, err := m.templateRepo.GetAll()
 		if err == nil {
-			hasBuiltin := false
 			for _, template := range allTemplates {
 				if template.IsBuiltin {
-					hasBuiltin = true
-					break
+					builtinPlatforms[common.Platform(template.Platform)] = true
 				}
 			}
-			if hasBuiltin {
-				// 已经有内置模板，不需要初始化
-				return
-			}
 		}
 	}
 
+	// 内置片段，其他内置模板继承或引用这些片段
+	m.initBuiltinFragments()
+
 	// GitHub Actions 模板
-	m.initGitHubActionsTemplates()
+	if !builtinPlatforms[common.PlatformGitHubActions] {
+		m.initGitHubActionsTemplates()
+	}
+
+	// GitLab CI 模板
+	if !builtinPlatforms[common.PlatformGitLabCI] {
+		m.initGitLabCITemplates()
+	}
 
 	// Mock 模板
-	m.initMockTemplates()
+	if !builtinPlatforms[common.PlatformMock] {
+		m.initMockTemplates()
+	}
+}
+
+// initBuiltinFragments 初始化内置片段。片段按名称检查是否已存在，
+// 升级前已有内置模板的数据库也能获得新增的片段
+func (m *TemplateManager) initBuiltinFragments() {
+	for _, fragment := range builtinFragments() {
+		fragment.Kind = KindFragment
+		fragment.ConfigType = common.ConfigTypeYAML
+
+		// 保存到数据库，已存在的片段从数据库加载
+		if m.templateRepo != nil {
+			if _, err := m.templateRepo.GetByName(fragment.Name); err == nil {
+				continue
+			}
+			dbTemplate := toRepository(fragment, true)
+			if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+				fragment.ID = dbTemplate.ID
+				fragment.Version = dbTemplate.Version
+			}
+		}
+		m.templates = append(m.templates, fragment)
+	}
 }
 
 // initGitHubActionsTemplates 初始化 GitHub Actions 模板
 func (m *TemplateManager) initGitHubActionsTemplates() {
 	// Go 项目模板
 	goTemplate := &Template{
+		Extends:    "github-actions-base",
 		Platform:   common.PlatformGitHubActions,
 		Language:   "Go",
 		Framework:  "",
 		Content:    getGoGitHubActionsTemplate(),
 		Filename:   ".github/workflows/ci.yml",
 		ConfigType: common.ConfigTypeYAML,
+		Parameters: goParameters(),
 	}
 	m.templates = append(m.templates, goTemplate)
 
 	// 保存到数据库
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(goTemplate.Platform),
-			Language:   goTemplate.Language,
-			Framework:  goTemplate.Framework,
-			Content:    goTemplate.Content,
-			Filename:   goTemplate.Filename,
-			ConfigType: string(goTemplate.ConfigType),
-			IsBuiltin:  true,
+		dbTemplate := toRepository(goTemplate, true)
+		if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+			goTemplate.ID = dbTemplate.ID
+			goTemplate.Version = dbTemplate.Version
 		}
-		m.templateRepo.Create(dbTemplate)
 	}
 
 	// Java 项目模板
 	javaTemplate := &Template{
+		Extends:    "github-actions-base",
 		Platform:   common.PlatformGitHubActions,
 		Language:   "Java",
 		Framework:  "",
 		Content:    getJavaGitHubActionsTemplate(),
 		Filename:   ".github/workflows/ci.yml",
 		ConfigType: common.ConfigTypeYAML,
+		Parameters: javaGitHubActionsParameters(),
 	}
 	m.templates = append(m.templates, javaTemplate)
 
 	// 保存到数据库
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(javaTemplate.Platform),
-			Language:   javaTemplate.Language,
-			Framework:  javaTemplate.Framework,
-			Content:    javaTemplate.Content,
-			Filename:   javaTemplate.Filename,
-			ConfigType: string(javaTemplate.ConfigType),
-			IsBuiltin:  true,
+		dbTemplate := toRepository(javaTemplate, true)
+		if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+			javaTemplate.ID = dbTemplate.ID
+			javaTemplate.Version = dbTemplate.Version
 		}
-		m.templateRepo.Create(dbTemplate)
 	}
 
 	// Python 项目模板
 	pythonTemplate := &Template{
+		Extends:    "github-actions-base",
 		Platform:   common.PlatformGitHubActions,
 		Language:   "Python",
 		Framework:  "",
 		Content:    getPythonGitHubActionsTemplate(),
 		Filename:   ".github/workflows/ci.yml",
 		ConfigType: common.ConfigTypeYAML,
+		Parameters: pythonParameters(),
 	}
 	m.templates = append(m.templates, pythonTemplate)
 
 	// 保存到数据库
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(pythonTemplate.Platform),
-			Language:   pythonTemplate.Language,
-			Framework:  pythonTemplate.Framework,
-			Content:    pythonTemplate.Content,
-			Filename:   pythonTemplate.Filename,
-			ConfigType: string(pythonTemplate.ConfigType),
-			IsBuiltin:  true,
+		dbTemplate := toRepository(pythonTemplate, true)
+		if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+			pythonTemplate.ID = dbTemplate.ID
+			pythonTemplate.Version = dbTemplate.Version
 		}
-		m.templateRepo.Create(dbTemplate)
 	}
 
 	// JavaScript 项目模板
 	jsTemplate := &Template{
+		Extends:    "github-actions-base",
 		Platform:   common.PlatformGitHubActions,
 		Language:   "JavaScript",
 		Framework:  "",
 		Content:    getJavaScriptGitHubActionsTemplate(),
 		Filename:   ".github/workflows/ci.yml",
 		ConfigType: common.ConfigTypeYAML,
+		Parameters: javaScriptParameters(),
 	}
 	m.templates = append(m.templates, jsTemplate)
 
 	// 保存到数据库
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(jsTemplate.Platform),
-			Language:   jsTemplate.Language,
-			Framework:  jsTemplate.Framework,
-			Content:    jsTemplate.Content,
-			Filename:   jsTemplate.Filename,
-			ConfigType: string(jsTemplate.ConfigType),
-			IsBuiltin:  true,
+		dbTemplate := toRepository(jsTemplate, true)
+		if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+			jsTemplate.ID = dbTemplate.ID
+			jsTemplate.Version = dbTemplate.Version
 		}
-		m.templateRepo.Create(dbTemplate)
 	}
 
 	// GitHub Actions 默认模板
 	defaultTemplate := &Template{
+		Extends:    "github-actions-base",
 		Platform:   common.PlatformGitHubActions,
 		Language:   "",
 		Framework:  "",
@@ -320,16 +384,44 @@ func (m *TemplateManager) initGitHubActionsTemplates() {
 
 	// 保存到数据库
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(defaultTemplate.Platform),
-			Language:   defaultTemplate.Language,
-			Framework:  defaultTemplate.Framework,
-			Content:    defaultTemplate.Content,
-			Filename:   defaultTemplate.Filename,
-			ConfigType: string(defaultTemplate.ConfigType),
-			IsBuiltin:  true,
+		dbTemplate := toRepository(defaultTemplate, true)
+		if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+			defaultTemplate.ID = dbTemplate.ID
+			defaultTemplate.Version = dbTemplate.Version
+		}
+	}
+}
+
+// initGitLabCITemplates 初始化 GitLab CI 模板
+func (m *TemplateManager) initGitLabCITemplates() {
+	templates := []*Template{
+		// Go 项目模板
+		{Language: "Go", Content: getGoGitLabCITemplate(), Parameters: goParameters()},
+		// Java 项目模板
+		{Language: "Java", Content: getJavaGitLabCITemplate(), Parameters: javaGitLabCIParameters()},
+		// Python 项目模板
+		{Language: "Python", Content: getPythonGitLabCITemplate(), Parameters: pythonParameters()},
+		// JavaScript 项目模板
+		{Language: "JavaScript", Content: getJavaScriptGitLabCITemplate(), Parameters: javaScriptParameters()},
+		// GitLab CI 默认模板
+		{Language: "", Content: getDefaultGitLabCITemplate()},
+	}
+
+	for _, template := range templates {
+		template.Extends = "gitlab-ci-base"
+		template.Platform = common.PlatformGitLabCI
+		template.Filename = ".gitlab-ci.yml"
+		template.ConfigType = common.ConfigTypeYAML
+		m.templates = append(m.templates, template)
+
+		// 保存到数据库
+		if m.templateRepo != nil {
+			dbTemplate := toRepository(template, true)
+			if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+				template.ID = dbTemplate.ID
+				template.Version = dbTemplate.Version
+			}
 		}
-		m.templateRepo.Create(dbTemplate)
 	}
 }
 
@@ -348,31 +440,186 @@ func (m *TemplateManager) initMockTemplates() {
 
 	// 保存到数据库
 	if m.templateRepo != nil {
-		dbTemplate := &repository.Template{
-			Platform:   string(mockTemplate.Platform),
-			Language:   mockTemplate.Language,
-			Framework:  mockTemplate.Framework,
-			Content:    mockTemplate.Content,
-			Filename:   mockTemplate.Filename,
-			ConfigType: string(mockTemplate.ConfigType),
-			IsBuiltin:  true,
+		dbTemplate := toRepository(mockTemplate, true)
+		if m.templateRepo.Create(dbTemplate, builtinChange) == nil {
+			mockTemplate.ID = dbTemplate.ID
+			mockTemplate.Version = dbTemplate.Version
 		}
-		m.templateRepo.Create(dbTemplate)
 	}
 }
 
-// getGoGitHubActionsTemplate 获取 Go 项目的 GitHub Actions 模板
-func getGoGitHubActionsTemplate() string {
+// goParameters Go 项目模板的参数
+func goParameters() []Parameter {
+	return []Parameter{
+		{Name: "go_version", Type: ParameterTypeString, Default: "1.22", Source: SourceLanguageVersion, Description: "Go 版本，默认使用 go.mod 中的 go 指令"},
+		{Name: "build_command", Type: ParameterTypeString, Default: "go build -v ./...", Source: SourceBuildCommand, Description: "构建命令"},
+		{Name: "test_command", Type: ParameterTypeString, Default: "go test -v ./...", Source: SourceTestCommand, Description: "测试命令"},
+	}
+}
+
+// javaParameters Java 项目模板的公共参数
+func javaParameters() []Parameter {
+	return []Parameter{
+		{Name: "java_version", Type: ParameterTypeString, Default: "17", Source: SourceLanguageVersion, Description: "JDK 版本，默认使用 pom.xml 或 build.gradle 中的版本"},
+		{Name: "build_command", Type: ParameterTypeString, Default: "mvn -B package --file pom.xml -DskipTests", Source: SourceBuildCommand, Description: "构建命令"},
+		{Name: "test_command", Type: ParameterTypeString, Default: "mvn -B test", Source: SourceTestCommand, Description: "测试命令"},
+	}
+}
+
+// javaGitHubActionsParameters Java 项目 GitHub Actions 模板的参数
+func javaGitHubActionsParameters() []Parameter {
+	return append(javaParameters(), Parameter{
+		Name: "java_distribution", Type: ParameterTypeString, Default: "temurin",
+		Enum:        []interface{}{"temurin", "zulu", "corretto", "microsoft", "liberica"},
+		Description: "actions/setup-java 使用的 JDK 发行版",
+	})
+}
+
+// javaGitLabCIParameters Java 项目 GitLab CI 模板的参数，按构建工具选择镜像和缓存目录
+func javaGitLabCIParameters() []Parameter {
+	return append(javaParameters(), Parameter{
+		Name: "build_tool", Type: ParameterTypeString, Default: "Maven", Source: SourceBuildTool,
+		Enum:        []interface{}{"Maven", "Gradle"},
+		Description: "构建工具",
+	})
+}
+
+// pythonParameters Python 项目模板的参数
+func pythonParameters() []Parameter {
+	return []Parameter{
+		{Name: "python_version", Type: ParameterTypeString, Default: "3.12", Source: SourceLanguageVersion, Description: "Python 版本，默认使用 .python-version、runtime.txt 或 pyproject.toml 中的版本"},
+		{Name: "test_command", Type: ParameterTypeString, Default: "pytest", Source: SourceTestCommand, Description: "测试命令"},
+	}
+}
+
+// javaScriptParameters JavaScript 项目模板的参数
+func javaScriptParameters() []Parameter {
+	return []Parameter{
+		{Name: "node_version", Type: ParameterTypeString, Default: "20", Source: SourceLanguageVersion, Description: "Node.js 版本，默认使用 .nvmrc、.node-version 或 package.json engines 中的版本"},
+		{Name: "build_command", Type: ParameterTypeString, Default: "npm run build --if-present", Source: SourceBuildCommand, Description: "构建命令"},
+		{Name: "test_command", Type: ParameterTypeString, Default: "npm test", Source: SourceTestCommand, Description: "测试命令"},
+	}
+}
+
+// builtinFragments 内置片段：各平台的基础模板和常用的触发条件、步骤、Job
+func builtinFragments() []*Template {
+	return []*Template{
+		{
+			Name: "github-actions-base", Platform: common.PlatformGitHubActions,
+			Content: getGitHubActionsBaseFragment(), Filename: ".github/workflows/ci.yml",
+		},
+		{Name: "github-actions-on", Platform: common.PlatformGitHubActions, Content: getGitHubActionsOnFragment()},
+		{
+			Name: "setup-go", Platform: common.PlatformGitHubActions, Content: getSetupGoFragment(),
+			Parameters: []Parameter{
+				{Name: "go_version", Type: ParameterTypeString, Default: "1.22", Source: SourceLanguageVersion, Description: "Go 版本，默认使用 go.mod 中的 go 指令"},
+			},
+		},
+		{
+			Name: "docker-build-push", Platform: common.PlatformGitHubActions, Content: getDockerBuildPushFragment(),
+			Parameters: []Parameter{
+				{Name: "docker_registry", Type: ParameterTypeString, Default: "ghcr.io", Description: "镜像仓库地址"},
+			},
+		},
+		{
+			Name: "gitlab-ci-base", Platform: common.PlatformGitLabCI,
+			Content: getGitLabCIBaseFragment(), Filename: ".gitlab-ci.yml",
+		},
+		{Name: "gitlab-ci-workflow", Platform: common.PlatformGitLabCI, Content: getGitLabCIWorkflowFragment()},
+	}
+}
+
+// getGitHubActionsBaseFragment 获取 GitHub Actions 基础模板，子模板通过 define 覆盖其中的 block：
+// job 为 build Job 的附加配置，steps 为检出代码之后的步骤，jobs 为附加的 Job。
+// 覆盖的内容以换行开头，不以换行结尾
+func getGitHubActionsBaseFragment() string {
 	return `name: CI
 
-on:
+[[ include "github-actions-on" . ]]
+permissions:
+  contents: read
+jobs:
+  build:
+    runs-on: ubuntu-latest
+[[- block "job" . ]][[ end ]]
+    steps:
+    - uses: actions/checkout@v4
+[[- block "steps" . ]][[ end ]]
+[[- block "jobs" . ]][[ end ]]
+`
+}
+
+// getGitHubActionsOnFragment 获取 GitHub Actions 的标准触发条件
+func getGitHubActionsOnFragment() string {
+	return `on:
   push:
     branches: [ main ]
   pull_request:
     branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
+`
+}
+
+// getSetupGoFragment 获取安装 Go 并启用依赖缓存的步骤
+func getSetupGoFragment() string {
+	return `- name: Set up Go
+  uses: actions/setup-go@v5
+  with:
+    go-version: '[[ .go_version ]]'
+    cache: true
+`
+}
+
+// getDockerBuildPushFragment 获取构建并推送 Docker 镜像的 Job，在 build Job 成功后执行，只在 push 事件时推送
+func getDockerBuildPushFragment() string {
+	return `docker:
+  needs: build
+  runs-on: ubuntu-latest
+  permissions:
+    contents: read
+    packages: write
+  steps:
+  - uses: actions/checkout@v4
+  - name: Log in to registry
+    uses: docker/login-action@v3
+    with:
+      registry: [[ .docker_registry ]]
+      username: ${{ github.actor }}
+      password: ${{ secrets.GITHUB_TOKEN }}
+  - name: Build and push image
+    uses: docker/build-push-action@v6
+    with:
+      context: .
+      push: ${{ github.event_name == 'push' }}
+      tags: [[ .docker_registry ]]/${{ github.repository }}:${{ github.sha }}
+`
+}
+
+// getGitLabCIBaseFragment 获取 GitLab CI 基础模板，子模板通过 define 覆盖其中的 block：
+// stages 为阶段列表，jobs 为 workflow 之后的全部内容。覆盖的内容以换行开头，不以换行结尾
+func getGitLabCIBaseFragment() string {
+	return `stages:
+[[- block "stages" . ]]
+  - build
+  - test
+[[- end ]]
+
+[[ include "gitlab-ci-workflow" . ]]
+[[- block "jobs" . ]][[ end ]]
+`
+}
+
+// getGitLabCIWorkflowFragment 获取 GitLab CI 的标准触发条件：合并请求和 main 分支
+func getGitLabCIWorkflowFragment() string {
+	return `workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+    - if: $CI_COMMIT_BRANCH == "main"
+`
+}
+
+// getGoGitHubActionsTemplate 获取 Go 项目的 GitHub Actions 模板
+func getGoGitHubActionsTemplate() string {
+	return `[[ define "job" ]]
     # 资源配置
     resources:
       limits:
@@ -381,117 +628,220 @@ jobs:
       requests:
         cpu: 1
         memory: 2G
-    steps:
-    - uses: actions/checkout@v2
-    - name: Set up Go
-      uses: actions/setup-go@v2
-      with:
-        go-version: 1.20
+[[- end ]]
+[[ define "steps" ]]
+[[ include "setup-go" . | indent 4 ]]
     - name: Build
-      run: go build -v ./...
+      run: [[ .build_command ]]
     - name: Test
-      run: go test -v ./...
+      run: [[ .test_command ]]
+[[- end ]]
 `
 }
 
 // getJavaGitHubActionsTemplate 获取 Java 项目的 GitHub Actions 模板
 func getJavaGitHubActionsTemplate() string {
-	return `name: CI
-
-on:
-  push:
-    branches: [ main ]
-  pull_request:
-    branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v2
-    - name: Set up JDK 11
-      uses: actions/setup-java@v2
+	return `[[ define "steps" ]]
+    - name: Set up JDK [[ .java_version ]]
+      uses: actions/setup-java@v4
       with:
-        java-version: '11'
-        distribution: 'adopt'
-    - name: Build with Maven
-      run: mvn -B package --file pom.xml
+        java-version: '[[ .java_version ]]'
+        distribution: '[[ .java_distribution ]]'
+    - name: Build
+      run: [[ .build_command ]]
     - name: Test
-      run: mvn test
+      run: [[ .test_command ]]
+[[- end ]]
 `
 }
 
 // getPythonGitHubActionsTemplate 获取 Python 项目的 GitHub Actions 模板
 func getPythonGitHubActionsTemplate() string {
-	return `name: CI
-
-on:
-  push:
-    branches: [ main ]
-  pull_request:
-    branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v2
+	return `[[ define "steps" ]]
     - name: Set up Python
-      uses: actions/setup-python@v2
+      uses: actions/setup-python@v5
       with:
-        python-version: 3.9
+        python-version: '[[ .python_version ]]'
     - name: Install dependencies
       run: |
         python -m pip install --upgrade pip
         if [ -f requirements.txt ]; then pip install -r requirements.txt; fi
-    - name: Test with pytest
-      run: pytest
+    - name: Test
+      run: [[ .test_command ]]
+[[- end ]]
 `
 }
 
 // getJavaScriptGitHubActionsTemplate 获取 JavaScript 项目的 GitHub Actions 模板
 func getJavaScriptGitHubActionsTemplate() string {
-	return `name: CI
-
-on:
-  push:
-    branches: [ main ]
-  pull_request:
-    branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v2
+	return `[[ define "steps" ]]
     - name: Set up Node.js
-      uses: actions/setup-node@v2
+      uses: actions/setup-node@v4
       with:
-        node-version: 16
+        node-version: '[[ .node_version ]]'
     - name: Install dependencies
       run: npm install
     - name: Build
-      run: npm run build --if-present
+      run: [[ .build_command ]]
     - name: Test
-      run: npm test
+      run: [[ .test_command ]]
+[[- end ]]
 `
 }
 
 // getDefaultGitHubActionsTemplate 获取默认的 GitHub Actions 模板
 func getDefaultGitHubActionsTemplate() string {
-	return `name: CI
-
-on:
-  push:
-    branches: [ main ]
-  pull_request:
-    branches: [ main ]
-jobs:
-  build:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v2
+	return `[[ define "steps" ]]
     - name: Build and test
       run: |
         echo "Building and testing project..."
         # Add your build and test commands here
+[[- end ]]
+`
+}
+
+// getGoGitLabCITemplate 获取 Go 项目的 GitLab CI 模板
+func getGoGitLabCITemplate() string {
+	return `[[ define "jobs" ]]
+
+default:
+  image: golang:[[ .go_version ]]
+
+variables:
+  GOPATH: $CI_PROJECT_DIR/.go
+
+cache:
+  key: $CI_COMMIT_REF_SLUG
+  paths:
+    - .go/pkg/mod/
+
+build:
+  stage: build
+  script:
+    - [[ .build_command ]]
+
+test:
+  stage: test
+  needs: [build]
+  script:
+    - [[ .test_command ]]
+[[- end ]]
+`
+}
+
+// getJavaGitLabCITemplate 获取 Java 项目的 GitLab CI 模板
+func getJavaGitLabCITemplate() string {
+	return `[[ define "jobs" ]]
+
+[[ if eq .build_tool "Gradle" -]]
+default:
+  image: gradle:jdk[[ .java_version ]]
+
+variables:
+  GRADLE_USER_HOME: $CI_PROJECT_DIR/.gradle
+
+cache:
+  key: $CI_COMMIT_REF_SLUG
+  paths:
+    - .gradle/caches/
+[[- else -]]
+default:
+  image: maven:3-eclipse-temurin-[[ .java_version ]]
+
+variables:
+  MAVEN_OPTS: -Dmaven.repo.local=$CI_PROJECT_DIR/.m2/repository
+
+cache:
+  key: $CI_COMMIT_REF_SLUG
+  paths:
+    - .m2/repository/
+[[- end ]]
+
+build:
+  stage: build
+  script:
+    - [[ .build_command ]]
+
+test:
+  stage: test
+  needs: [build]
+  script:
+    - [[ .test_command ]]
+[[- end ]]
+`
+}
+
+// getPythonGitLabCITemplate 获取 Python 项目的 GitLab CI 模板
+func getPythonGitLabCITemplate() string {
+	return `[[ define "stages" ]]
+  - test
+[[- end ]]
+[[ define "jobs" ]]
+
+default:
+  image: python:[[ .python_version ]]
+
+variables:
+  PIP_CACHE_DIR: $CI_PROJECT_DIR/.cache/pip
+
+cache:
+  key: $CI_COMMIT_REF_SLUG
+  paths:
+    - .cache/pip/
+
+test:
+  stage: test
+  script:
+    - python -m pip install --upgrade pip
+    - if [ -f requirements.txt ]; then pip install -r requirements.txt; fi
+    - [[ .test_command ]]
+[[- end ]]
+`
+}
+
+// getJavaScriptGitLabCITemplate 获取 JavaScript 项目的 GitLab CI 模板
+func getJavaScriptGitLabCITemplate() string {
+	return `[[ define "jobs" ]]
+
+default:
+  image: node:[[ .node_version ]]
+
+cache:
+  key:
+    files:
+      - package-lock.json
+  paths:
+    - node_modules/
+
+build:
+  stage: build
+  script:
+    - npm install
+    - [[ .build_command ]]
+
+test:
+  stage: test
+  needs: [build]
+  script:
+    - npm install
+    - [[ .test_command ]]
+[[- end ]]
+`
+}
+
+// getDefaultGitLabCITemplate 获取默认的 GitLab CI 模板
+func getDefaultGitLabCITemplate() string {
+	return `[[ define "stages" ]]
+  - build
+[[- end ]]
+[[ define "jobs" ]]
+
+build:
+  stage: build
+  script:
+    - echo "Building and testing project..."
+    # Add your build and test commands here
+[[- end ]]
 `
 }
 
@@ -503,6 +853,9 @@ on:
   push:
     branches: [ main ]
 
+permissions:
+  contents: read
+
 jobs:
   build:
     runs-on: mock-runner
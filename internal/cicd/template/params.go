@@ -0,0 +1,318 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/techstack"
+)
+
+// Parameter 模板参数定义
+type Parameter = repository.TemplateParameter
+
+// 参数类型
+const (
+	ParameterTypeString  = "string"
+	ParameterTypeNumber  = "number"
+	ParameterTypeBoolean = "boolean"
+)
+
+// 可以自动填充参数的技术栈字段
+const (
+	SourceLanguage        = "language"
+	SourceLanguageVersion = "language_version"
+	SourceFramework       = "framework"
+	SourceBuildTool       = "build_tool"
+	SourceTestFramework   = "test_framework"
+	SourceBuildCommand    = "build_command"
+	SourceTestCommand     = "test_command"
+)
+
+// 模板中引用参数的分隔符，使用 [[ ]] 避免与 GitHub Actions 的 ${{ }} 表达式冲突
+const (
+	leftDelim  = "[["
+	rightDelim = "]]"
+)
+
+// parameterName 参数名需要能在模板中以 .name 的形式引用
+var parameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sources 支持的自动填充来源
+var sources = map[string]bool{
+	SourceLanguage:        true,
+	SourceLanguageVersion: true,
+	SourceFramework:       true,
+	SourceBuildTool:       true,
+	SourceTestFramework:   true,
+	SourceBuildCommand:    true,
+	SourceTestCommand:     true,
+}
+
+// buildCommands 各构建工具的构建命令
+var buildCommands = map[string]string{
+	"go mod": "go build -v ./...",
+	"Maven":  "mvn -B package --file pom.xml -DskipTests",
+	"Gradle": "./gradlew build -x test",
+	"npm":    "npm run build --if-present",
+	"cargo":  "cargo build --verbose",
+}
+
+// testCommands 各测试框架的测试命令，找不到时按构建工具查找
+var testCommands = map[string]string{
+	"Go testing": "go test -v ./...",
+	"pytest":     "pytest",
+	"Jest":       "npm test",
+	"Mocha":      "npm test",
+	"Vitest":     "npm test",
+	"go mod":     "go test -v ./...",
+	"Maven":      "mvn -B test",
+	"Gradle":     "./gradlew test",
+	"npm":        "npm test",
+	"cargo":      "cargo test --verbose",
+}
+
+// RenderError 参数无效或模板渲染失败，通常是请求中的参数或模板内容有误
+type RenderError struct {
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *RenderError) Error() string {
+	return "render template: " + e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateParameters 检查参数定义是否有效
+func ValidateParameters(parameters []Parameter) error {
+	names := make(map[string]bool)
+	for _, param := range parameters {
+		if !parameterName.MatchString(param.Name) {
+			return fmt.Errorf("invalid parameter name '%s'", param.Name)
+		}
+		if names[param.Name] {
+			return fmt.Errorf("duplicate parameter '%s'", param.Name)
+		}
+		names[param.Name] = true
+
+		switch param.Type {
+		case ParameterTypeString, ParameterTypeNumber, ParameterTypeBoolean:
+		default:
+			return fmt.Errorf("parameter '%s' has unsupported type '%s'", param.Name, param.Type)
+		}
+		if param.Source != "" && !sources[param.Source] {
+			return fmt.Errorf("parameter '%s' has unknown source '%s'", param.Name, param.Source)
+		}
+		for _, value := range param.Enum {
+			if _, err := convert(param, value); err != nil {
+				return fmt.Errorf("parameter '%s' enum: %w", param.Name, err)
+			}
+		}
+		if param.Default != nil {
+			if _, err := normalize(param, param.Default); err != nil {
+				return fmt.Errorf("parameter '%s' default: %w", param.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateContent 检查模板内容的语法，只有声明了参数的模板才会作为模板渲染
+func ValidateContent(content string, parameters []Parameter) error {
+	if len(parameters) == 0 {
+		return nil
+	}
+	_, err := parse(content)
+	return err
+}
+
+// ResolveParameters 计算参数值，优先级从低到高为默认值、技术栈、请求中的覆盖值。
+// 技术栈中的值不符合参数定义时使用默认值，覆盖值不符合定义或参数不存在时返回错误
+func ResolveParameters(parameters []Parameter, techStack *techstack.TechStack, overrides map[string]interface{}) ([]common.Parameter, error) {
+	defined := make(map[string]bool, len(parameters))
+	for _, param := range parameters {
+		defined[param.Name] = true
+	}
+	var unknown []string
+	for name := range overrides {
+		if !defined[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+
+	resolved := make([]common.Parameter, 0, len(parameters))
+	for _, param := range parameters {
+		value, source, err := resolveParameter(param, techStack, overrides)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, common.Parameter{Name: param.Name, Value: value, Source: source})
+	}
+	return resolved, nil
+}
+
+// resolveParameter 计算单个参数的值及其来源
+func resolveParameter(param Parameter, techStack *techstack.TechStack, overrides map[string]interface{}) (interface{}, string, error) {
+	if raw, ok := overrides[param.Name]; ok {
+		value, err := normalize(param, raw)
+		if err != nil {
+			return nil, "", fmt.Errorf("parameter '%s': %w", param.Name, err)
+		}
+		return value, common.ParameterSourceOverride, nil
+	}
+
+	if raw := techStackValue(param.Source, techStack); raw != "" {
+		if value, err := normalize(param, raw); err == nil {
+			return value, common.ParameterSourceTechStack, nil
+		}
+	}
+
+	if param.Default != nil {
+		value, err := normalize(param, param.Default)
+		if err != nil {
+			return nil, "", fmt.Errorf("parameter '%s' default: %w", param.Name, err)
+		}
+		return value, common.ParameterSourceDefault, nil
+	}
+	if param.Required {
+		return nil, "", fmt.Errorf("parameter '%s' is required", param.Name)
+	}
+
+	// 可选参数没有默认值时使用类型的零值
+	switch param.Type {
+	case ParameterTypeNumber:
+		return float64(0), common.ParameterSourceDefault, nil
+	case ParameterTypeBoolean:
+		return false, common.ParameterSourceDefault, nil
+	default:
+		return "", common.ParameterSourceDefault, nil
+	}
+}
+
+// techStackValue 获取技术栈中对应来源的值
+func techStackValue(source string, techStack *techstack.TechStack) string {
+	if techStack == nil {
+		return ""
+	}
+	switch source {
+	case SourceLanguage:
+		return techStack.Language
+	case SourceLanguageVersion:
+		return techStack.LanguageVersion
+	case SourceFramework:
+		return techStack.Framework
+	case SourceBuildTool:
+		return techStack.BuildTool
+	case SourceTestFramework:
+		return techStack.TestFramework
+	case SourceBuildCommand:
+		return buildCommands[techStack.BuildTool]
+	case SourceTestCommand:
+		if command, ok := testCommands[techStack.TestFramework]; ok && command != "" {
+			return command
+		}
+		return testCommands[techStack.BuildTool]
+	}
+	return ""
+}
+
+// normalize 将值转换为参数类型并检查是否在可选值中
+func normalize(param Parameter, raw interface{}) (interface{}, error) {
+	value, err := convert(param, raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(param.Enum) == 0 {
+		return value, nil
+	}
+
+	options := make([]string, 0, len(param.Enum))
+	for _, option := range param.Enum {
+		option, _ := convert(param, option)
+		if option == value {
+			return value, nil
+		}
+		options = append(options, fmt.Sprint(option))
+	}
+	return nil, fmt.Errorf("value '%v' is not one of %s", value, strings.Join(options, ", "))
+}
+
+// convert 将值转换为参数类型，数字和布尔值也可以使用字符串表示
+func convert(param Parameter, raw interface{}) (interface{}, error) {
+	switch param.Type {
+	case ParameterTypeString:
+		// 数字转换为字符串会丢失版本号末尾的 0（1.20 → 1.2），因此只接受字符串
+		if value, ok := raw.(string); ok {
+			return value, nil
+		}
+	case ParameterTypeNumber:
+		switch value := raw.(type) {
+		case float64:
+			return value, nil
+		case int:
+			return float64(value), nil
+		case json.Number:
+			return value.Float64()
+		case string:
+			if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				return number, nil
+			}
+		}
+	case ParameterTypeBoolean:
+		switch value := raw.(type) {
+		case bool:
+			return value, nil
+		case string:
+			if boolean, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+				return boolean, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("value '%v' is not a %s", raw, param.Type)
+}
+
+// parse 解析模板内容，引用未定义的参数时渲染失败
+func parse(content string) (*texttemplate.Template, error) {
+	return newTemplate(rootName, new(*texttemplate.Template)).Parse(content)
+}
+
+// Render 使用参数渲染模板内容，返回渲染结果和实际使用的参数值。
+// 继承的基础模板和引用的片段通过 resolver 按名称查找，参数定义与当前模板的参数合并。
+// 没有声明参数、没有继承也没有引用片段的模板原样返回，其中的 [[ ]] 不会被当作模板语法
+func (t *Template) Render(resolver Resolver, techStack *techstack.TechStack, overrides map[string]interface{}) (string, []common.Parameter, error) {
+	c, err := compose(t, resolver)
+	if err != nil {
+		return "", nil, &RenderError{Err: err}
+	}
+	resolved, err := ResolveParameters(c.parameters(), techStack, overrides)
+	if err != nil {
+		return "", nil, &RenderError{Err: err}
+	}
+	if c.static {
+		return t.Content, nil, nil
+	}
+
+	values := make(map[string]interface{}, len(resolved))
+	for _, param := range resolved {
+		values[param.Name] = param.Value
+	}
+	content, err := c.execute(values)
+	if err != nil {
+		return "", nil, &RenderError{Err: err}
+	}
+	return content, resolved, nil
+}
@@ -0,0 +1,596 @@
+package template
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/validator"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/techstack"
+)
+
+// parameterValue 查找指定名称的参数值
+func parameterValue(t *testing.T, resolved []common.Parameter, name string) common.Parameter {
+	t.Helper()
+	for _, param := range resolved {
+		if param.Name == name {
+			return param
+		}
+	}
+	t.Fatalf("parameter %s not resolved: %+v", name, resolved)
+	return common.Parameter{}
+}
+
+func TestResolveParameters(t *testing.T) {
+	parameters := []Parameter{
+		{Name: "go_version", Type: ParameterTypeString, Default: "1.22", Source: SourceLanguageVersion},
+		{Name: "test_command", Type: ParameterTypeString, Default: "make test", Source: SourceTestCommand},
+		{Name: "runner", Type: ParameterTypeString, Default: "ubuntu-latest", Enum: []interface{}{"ubuntu-latest", "macos-latest"}},
+		{Name: "timeout", Type: ParameterTypeNumber, Default: 30},
+		{Name: "race", Type: ParameterTypeBoolean},
+	}
+	techStack := &techstack.TechStack{Language: "Go", LanguageVersion: "1.21", BuildTool: "go mod", TestFramework: "Go testing"}
+
+	// 优先级：默认值 < 技术栈 < 覆盖值
+	resolved, err := ResolveParameters(parameters, techStack, map[string]interface{}{"runner": "macos-latest", "timeout": "45", "race": true})
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	expected := map[string]common.Parameter{
+		"go_version":   {Name: "go_version", Value: "1.21", Source: common.ParameterSourceTechStack},
+		"test_command": {Name: "test_command", Value: "go test -v ./...", Source: common.ParameterSourceTechStack},
+		"runner":       {Name: "runner", Value: "macos-latest", Source: common.ParameterSourceOverride},
+		"timeout":      {Name: "timeout", Value: float64(45), Source: common.ParameterSourceOverride},
+		"race":         {Name: "race", Value: true, Source: common.ParameterSourceOverride},
+	}
+	for name, want := range expected {
+		if got := parameterValue(t, resolved, name); got != want {
+			t.Errorf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+
+	// 技术栈中没有对应的值时使用默认值，可选参数没有默认值时使用零值
+	resolved, err = ResolveParameters(parameters, &techstack.TechStack{Language: "Go"}, nil)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if got := parameterValue(t, resolved, "go_version"); got.Value != "1.22" || got.Source != common.ParameterSourceDefault {
+		t.Errorf("expected default go_version, got %+v", got)
+	}
+	if got := parameterValue(t, resolved, "race"); got.Value != false {
+		t.Errorf("expected zero value for race, got %+v", got)
+	}
+}
+
+func TestResolveParametersErrors(t *testing.T) {
+	parameters := []Parameter{
+		{Name: "distribution", Type: ParameterTypeString, Default: "temurin", Enum: []interface{}{"temurin", "zulu"}, Source: SourceFramework},
+		{Name: "token", Type: ParameterTypeString, Required: true},
+	}
+
+	// 技术栈中的值不在可选值中时使用默认值
+	resolved, err := ResolveParameters(parameters, &techstack.TechStack{Framework: "Spring Boot"}, map[string]interface{}{"token": "x"})
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if got := parameterValue(t, resolved, "distribution"); got.Value != "temurin" || got.Source != common.ParameterSourceDefault {
+		t.Errorf("expected default distribution, got %+v", got)
+	}
+
+	cases := map[string]map[string]interface{}{
+		"missing required": {},
+		"not in enum":      {"token": "x", "distribution": "oracle"},
+		"wrong type":       {"token": 1.20},
+		"unknown":          {"token": "x", "go_version": "1.22"},
+	}
+	for name, overrides := range cases {
+		if _, err := ResolveParameters(parameters, nil, overrides); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	invalid := map[string][]Parameter{
+		"name":      {{Name: "go-version", Type: ParameterTypeString}},
+		"duplicate": {{Name: "a", Type: ParameterTypeString}, {Name: "a", Type: ParameterTypeString}},
+		"type":      {{Name: "a", Type: "list"}},
+		"source":    {{Name: "a", Type: ParameterTypeString, Source: "os"}},
+		"default":   {{Name: "a", Type: ParameterTypeNumber, Default: "fast"}},
+		"enum":      {{Name: "a", Type: ParameterTypeString, Default: "c", Enum: []interface{}{"a", "b"}}},
+	}
+	for name, parameters := range invalid {
+		if err := ValidateParameters(parameters); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if err := ValidateContent("run: [[ .a ", []Parameter{{Name: "a", Type: ParameterTypeString}}); err == nil {
+		t.Error("expected syntax error")
+	}
+	// 没有参数的模板不作为模板解析
+	if err := ValidateContent("run: [[ -f go.mod ]] && make", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl := &Template{
+		Content:    "image: golang:[[ .go_version ]]\nscript: ${{ matrix.os }}\n",
+		Parameters: []Parameter{{Name: "go_version", Type: ParameterTypeString, Default: "1.22", Source: SourceLanguageVersion}},
+	}
+	content, resolved, err := tmpl.Render(nil, &techstack.TechStack{LanguageVersion: "1.21.5"}, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if content != "image: golang:1.21.5\nscript: ${{ matrix.os }}\n" {
+		t.Errorf("unexpected content %q", content)
+	}
+	if len(resolved) != 1 {
+		t.Errorf("expected 1 resolved parameter, got %+v", resolved)
+	}
+
+	// 覆盖值无效时返回 RenderError
+	_, _, err = tmpl.Render(nil, nil, map[string]interface{}{"node_version": "20"})
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Errorf("expected RenderError, got %v", err)
+	}
+
+	// 没有参数的模板原样返回
+	static := &Template{Content: "run: [[ -f go.mod ]]\n"}
+	if content, _, err := static.Render(nil, nil, nil); err != nil || content != static.Content {
+		t.Errorf("expected static content, got %q, %v", content, err)
+	}
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	manager := NewTemplateManager(nil)
+	v := validator.NewValidator()
+
+	techStacks := []*techstack.TechStack{
+		{},
+		{Language: "Go", LanguageVersion: "1.23", BuildTool: "go mod", TestFramework: "Go testing"},
+		{Language: "Java", LanguageVersion: "21", BuildTool: "Gradle", TestFramework: "JUnit"},
+		{Language: "Python", LanguageVersion: "3.11", BuildTool: "pip", TestFramework: "pytest"},
+		{Language: "JavaScript", LanguageVersion: "18", BuildTool: "npm", TestFramework: "Jest"},
+	}
+	for _, tmpl := range manager.ListTemplates() {
+		if err := ValidateParameters(tmpl.Parameters); err != nil {
+			t.Errorf("%s %s: invalid parameters: %v", tmpl.Platform, tmpl.Language, err)
+		}
+		// 片段只能被其他模板继承或引用，不单独生成配置
+		if tmpl.Kind == KindFragment {
+			continue
+		}
+		for _, techStack := range techStacks {
+			content, _, err := tmpl.Render(manager, techStack, nil)
+			if err != nil {
+				t.Errorf("%s %s: render failed: %v", tmpl.Platform, tmpl.Language, err)
+				continue
+			}
+			config := &common.PipelineConfig{Platform: tmpl.Platform, ConfigType: tmpl.ConfigType, Content: content, Filename: tmpl.Filename}
+			if diagnostics := v.Validate(config); diagnostics.HasErrors() {
+				t.Errorf("%s %s: rendered config is invalid: %+v\n%s", tmpl.Platform, tmpl.Language, diagnostics, content)
+			}
+		}
+	}
+
+	// Gradle 项目的 GitLab CI 配置使用 Gradle 镜像和命令
+	tmpl, err := manager.GetTemplate(techStacks[2], common.PlatformGitLabCI)
+	if err != nil {
+		t.Fatalf("get template failed: %v", err)
+	}
+	content, _, err := tmpl.Render(manager, techStacks[2], nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	for _, want := range []string{"image: gradle:jdk21", "./gradlew build -x test", "./gradlew test"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in:\n%s", want, content)
+		}
+	}
+}
+
+// templates 按名称查找模板的 Resolver
+type templates map[string]*Template
+
+// GetTemplateByName 实现 Resolver 接口
+func (t templates) GetTemplateByName(name string) (*Template, error) {
+	if template, ok := t[name]; ok {
+		return template, nil
+	}
+	return nil, ErrTemplateNotFound
+}
+
+func TestCompose(t *testing.T) {
+	resolver := templates{
+		"base": {Name: "base", Kind: KindFragment, Content: "name: CI\n[[ include \"trigger\" . ]]\njobs:\n  build:\n    steps:\n    - run: checkout\n[[- block \"steps\" . ]][[ end ]]\n"},
+		"trigger": {Name: "trigger", Kind: KindFragment, Content: "on:\n  push:\n    branches: [ [[ .branch ]] ]\n",
+			Parameters: []Parameter{{Name: "branch", Type: ParameterTypeString, Default: "main"}}},
+		"setup": {Name: "setup", Kind: KindFragment, Content: "- name: Set up\n  run: setup [[ .version ]]\n",
+			Parameters: []Parameter{{Name: "version", Type: ParameterTypeString, Default: "1"}}},
+	}
+	child := &Template{
+		Extends:    "base",
+		Content:    "[[ define \"steps\" ]]\n[[ include \"setup\" . | indent 4 ]]\n    - run: test\n[[- end ]]\n",
+		Parameters: []Parameter{{Name: "version", Type: ParameterTypeString, Default: "2"}},
+	}
+
+	// 子模板覆盖基础模板的 block，片段按 indent 缩进，同名参数以子模板的定义为准
+	content, resolved, err := child.Render(resolver, nil, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	expected := "name: CI\non:\n  push:\n    branches: [ main ]\njobs:\n  build:\n    steps:\n    - run: checkout\n    - name: Set up\n      run: setup 2\n    - run: test\n"
+	if content != expected {
+		t.Errorf("unexpected content:\n%s\nexpected:\n%s", content, expected)
+	}
+	if len(resolved) != 2 || resolved[0].Name != "version" {
+		t.Errorf("expected merged parameters, got %+v", resolved)
+	}
+
+	// 片段的修改在下次渲染时生效，片段的参数可以被覆盖
+	resolver["setup"].Content = "- name: Set up with cache\n  run: setup [[ .version ]] --cache\n"
+	content, _, err = child.Render(resolver, nil, map[string]interface{}{"branch": "develop"})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	for _, want := range []string{"branches: [ develop ]", "    - name: Set up with cache\n      run: setup 2 --cache\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in:\n%s", want, content)
+		}
+	}
+
+	// 没有参数但引用了片段的模板也会被渲染
+	plain := &Template{Content: "[[ include \"trigger\" . ]]\n"}
+	if content, _, err := plain.Render(resolver, nil, nil); err != nil || !strings.Contains(content, "branches: [ main ]") {
+		t.Errorf("expected included trigger, got %q, %v", content, err)
+	}
+}
+
+func TestComposeErrors(t *testing.T) {
+	resolver := templates{
+		"a":    {Name: "a", Kind: KindFragment, Content: "[[ include \"b\" . ]]"},
+		"b":    {Name: "b", Kind: KindFragment, Content: "[[ include \"a\" . ]]"},
+		"x":    {Name: "x", Kind: KindFragment, Extends: "y", Content: ""},
+		"y":    {Name: "y", Kind: KindFragment, Extends: "x", Content: ""},
+		"base": {Name: "base", Kind: KindFragment, Content: "[[ block \"steps\" . ]][[ end ]]"},
+	}
+	cases := map[string]*Template{
+		"include cycle":   {Content: "[[ include \"a\" . ]]"},
+		"extends cycle":   {Extends: "x", Content: ""},
+		"self include":    {Name: "self", Kind: KindFragment, Content: "[[ include \"self\" . ]]"},
+		"missing base":    {Extends: "missing", Content: ""},
+		"missing include": {Content: "[[ include \"missing\" . ]]"},
+		"content outside": {Extends: "base", Content: "steps: []\n"},
+		"include extends": {Content: "[[ include \"x\" . ]]"},
+		"dynamic include": {Kind: KindFragment, Name: "dynamic", Content: "[[ include .name . ]]"},
+	}
+	for name, tmpl := range cases {
+		_, _, err := tmpl.Render(resolver, nil, nil)
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) {
+			t.Errorf("%s: expected RenderError, got %v", name, err)
+		}
+	}
+
+	_, _, err := cases["include cycle"].Render(resolver, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "template cycle: template -> a -> b -> a") {
+		t.Errorf("expected cycle path, got %v", err)
+	}
+	_, _, err = cases["missing include"].Render(resolver, nil, nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+
+	// include 可以引用 define 定义的模板
+	local := &Template{Kind: KindFragment, Name: "local", Content: "[[ define \"greeting\" ]]hello[[ end ]][[ include \"greeting\" . ]]"}
+	if content, _, err := local.Render(resolver, nil, nil); err != nil || content != "hello" {
+		t.Errorf("expected local define, got %q, %v", content, err)
+	}
+}
+
+func TestDependents(t *testing.T) {
+	all := []*Template{
+		{Name: "setup", Kind: KindFragment, Content: "- run: setup"},
+		{Name: "base", Kind: KindFragment, Content: "steps:\n[[ include \"setup\" . ]]"},
+		{ID: 3, Extends: "base", Content: ""},
+		{ID: 4, Content: "static [[ -f go.mod ]]"},
+	}
+	users := dependents("setup", all)
+	if len(users) != 2 || users[0] != all[1] || users[1] != all[2] {
+		t.Errorf("expected base and template 3, got %+v", users)
+	}
+	if users := dependents("base", all); len(users) != 1 || users[0].ID != 3 {
+		t.Errorf("expected template 3, got %+v", users)
+	}
+}
+
+// memoryRepository 内存中的模板仓库，用于测试导入
+type memoryRepository struct {
+	templates []*repository.Template
+	versions  map[int][]*repository.TemplateVersion
+}
+
+func newMemoryRepository(templates ...*repository.Template) *memoryRepository {
+	r := &memoryRepository{versions: make(map[int][]*repository.TemplateVersion)}
+	for _, t := range templates {
+		r.Create(t, repository.TemplateChange{Author: "test"})
+	}
+	return r
+}
+
+func (r *memoryRepository) Create(template *repository.Template, change repository.TemplateChange) error {
+	template.ID = len(r.templates) + 1
+	template.Version = 1
+	if template.Kind == "" {
+		template.Kind = KindPipeline
+	}
+	r.templates = append(r.templates, template)
+	r.versions[template.ID] = []*repository.TemplateVersion{{TemplateID: template.ID, Version: 1, Content: template.Content, Author: change.Author}}
+	return nil
+}
+
+func (r *memoryRepository) GetByID(id int) (*repository.Template, error) {
+	for _, t := range r.templates {
+		if t.ID == id {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetByName(name string) (*repository.Template, error) {
+	for _, t := range r.templates {
+		if t.Name == name {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetAll() ([]*repository.Template, error) {
+	var all []*repository.Template
+	for _, t := range r.templates {
+		copied := *t
+		all = append(all, &copied)
+	}
+	return all, nil
+}
+
+func (r *memoryRepository) Update(template *repository.Template, change repository.TemplateChange) error {
+	for i, t := range r.templates {
+		if t.ID == template.ID {
+			template.Version = t.Version + 1
+			r.templates[i] = template
+			r.versions[t.ID] = append(r.versions[t.ID], &repository.TemplateVersion{TemplateID: t.ID, Version: template.Version, Content: template.Content, Author: change.Author, Note: change.Note})
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *memoryRepository) Delete(id int) error { return nil }
+
+func (r *memoryRepository) GetByPlatformAndLanguage(platform string, language, framework string) (*repository.Template, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) ResetBuiltinTemplates() error { return nil }
+
+func (r *memoryRepository) ListVersions(templateID int) ([]*repository.TemplateVersion, error) {
+	return r.versions[templateID], nil
+}
+
+func (r *memoryRepository) GetVersion(templateID, version int) (*repository.TemplateVersion, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) Rollback(templateID, version int, change repository.TemplateChange) (*repository.Template, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) Import(imports []*repository.TemplateImport, change repository.TemplateChange) error {
+	for _, imported := range imports {
+		if imported.Template.ID != 0 {
+			if err := r.Update(imported.Template, change); err != nil {
+				return err
+			}
+			continue
+		}
+		imported.Template.ID = len(r.templates) + 1
+		r.templates = append(r.templates, imported.Template)
+		r.versions[imported.Template.ID] = imported.Versions
+	}
+	return nil
+}
+
+// bundleTemplates 测试模板包使用的模板：基础模板、片段和使用它们的管道模板
+func bundleTemplates() []*repository.Template {
+	return []*repository.Template{
+		{Name: "base", Kind: KindFragment, Content: "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n    - uses: actions/checkout@v4\n[[- block \"steps\" . ]][[ end ]]\n"},
+		{Name: "lint", Kind: KindFragment, Content: "- run: make lint"},
+		{Name: "go", Extends: "base", Platform: "github_actions", Language: "Go", Filename: ".github/workflows/ci.yml", ConfigType: "yaml",
+			Content: "[[ define \"steps\" ]]\n[[ include \"lint\" . | indent 4 ]]\n[[- end ]]"},
+		{Platform: "mock", Filename: "mock.yml", ConfigType: "yaml", Content: mockConfig("mock")},
+	}
+}
+
+// mockConfig 测试使用的 mock 平台配置
+func mockConfig(name string) string {
+	return "name: " + name + "\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n    - run: echo\n"
+}
+
+func TestBundleEncoding(t *testing.T) {
+	source := newMemoryRepository(bundleTemplates()...)
+	bundle, err := ExportBundle(source, []int{3})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var names []string
+	for _, bt := range bundle.Templates {
+		names = append(names, bt.Name)
+	}
+	// 依赖的模板在前，没有被引用的模板不导出
+	if strings.Join(names, ",") != "base,lint,go" {
+		t.Fatalf("expected base,lint,go, got %v", names)
+	}
+
+	key := []byte("secret")
+	for _, format := range []string{BundleFormatJSON, BundleFormatTarGz} {
+		data, err := bundle.Encode(format, key)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		decoded, err := DecodeBundle(data, key)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", format, err)
+		}
+		if len(decoded.Templates) != 3 || decoded.Templates[2].Content != bundle.Templates[2].Content || len(decoded.Templates[2].Versions) != 1 {
+			t.Errorf("%s: bundle changed after decoding: %+v", format, decoded.Templates)
+		}
+		if _, err := DecodeBundle(data, []byte("other")); !errors.Is(err, ErrBundleSignature) {
+			t.Errorf("%s: expected signature error with another key, got %v", format, err)
+		}
+	}
+
+	data, _ := bundle.Encode(BundleFormatJSON, key)
+	tampered := strings.Replace(string(data), "make lint", "make deploy", 1)
+	if _, err := DecodeBundle([]byte(tampered), key); !errors.Is(err, ErrBundleSignature) {
+		t.Errorf("expected signature error for modified bundle, got %v", err)
+	}
+	if _, err := bundle.Encode(BundleFormatJSON, nil); !errors.Is(err, ErrBundleKey) {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+}
+
+func TestRewriteReferences(t *testing.T) {
+	template := &repository.Template{
+		Extends: "base",
+		Content: "[[ define \"steps\" ]]\n[[ include \"lint\" . ]]\n[[ include \"base\" . | indent 2 ]][[ \"lint\" ]]\n[[- end ]]",
+	}
+	rewriteReferences(template, map[string]string{"base": "base-imported", "lint": "lint-2"})
+	expected := "[[ define \"steps\" ]]\n[[ include \"lint-2\" . ]]\n[[ include \"base-imported\" . | indent 2 ]][[ \"lint\" ]]\n[[- end ]]"
+	if template.Extends != "base-imported" || template.Content != expected {
+		t.Errorf("unexpected rewrite: %s\n%s", template.Extends, template.Content)
+	}
+}
+
+func TestImportBundle(t *testing.T) {
+	source := newMemoryRepository(bundleTemplates()...)
+	source.Update(&repository.Template{ID: 2, Name: "lint", Kind: KindFragment, Content: "- run: make lint vet"}, repository.TemplateChange{Author: "dev"})
+	bundle, err := ExportBundle(source, nil)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	// 导入到空的实例，保留历史版本
+	target := newMemoryRepository()
+	result, err := ImportBundle(bundle, target, ImportOptions{Author: "ops"})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	for _, item := range result.Templates {
+		if item.Action != ImportCreated {
+			t.Errorf("expected %s to be created, got %s", item.Name, item.Action)
+		}
+	}
+	if lint, _ := target.GetByName("lint"); lint == nil || lint.Version != 2 || len(target.versions[lint.ID]) != 2 {
+		t.Errorf("expected lint with 2 versions, got %+v", lint)
+	}
+
+	// 目标实例中已有同名片段和同平台、语言、框架的管道模板
+	existing := func() *memoryRepository {
+		return newMemoryRepository(
+			&repository.Template{Name: "lint", Kind: KindFragment, Content: "- run: lint"},
+			&repository.Template{Platform: "mock", Filename: "mock.yml", ConfigType: "yaml", Content: mockConfig("old")},
+		)
+	}
+
+	target = existing()
+	result, err = ImportBundle(bundle, target, ImportOptions{Strategy: ConflictSkip})
+	if err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+	actions := map[string]string{}
+	for _, item := range result.Templates {
+		actions[item.Name+item.Platform] = item.Action
+	}
+	if actions["lint"] != ImportSkipped || actions["mock"] != ImportSkipped || actions["gogithub_actions"] != ImportCreated {
+		t.Errorf("unexpected skip result: %v", actions)
+	}
+	if lint, _ := target.GetByName("lint"); lint.Content != "- run: lint" {
+		t.Errorf("skipped template was modified: %+v", lint)
+	}
+
+	target = existing()
+	if _, err := ImportBundle(bundle, target, ImportOptions{Strategy: ConflictOverwrite}); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if lint, _ := target.GetByID(1); lint.Content != "- run: make lint vet" || lint.Version != 2 {
+		t.Errorf("expected lint to be overwritten, got %+v", lint)
+	}
+	if mock, _ := target.GetByID(2); mock.Content != mockConfig("mock") {
+		t.Errorf("expected mock template to be overwritten, got %+v", mock)
+	}
+
+	target = existing()
+	result, err = ImportBundle(bundle, target, ImportOptions{Strategy: ConflictRename})
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if lint, _ := target.GetByID(1); lint.Content != "- run: lint" {
+		t.Errorf("renamed import modified the existing template: %+v", lint)
+	}
+	renamed, err := target.GetByName("lint-imported")
+	if err != nil || renamed.Content != "- run: make lint vet" {
+		t.Fatalf("expected lint-imported, got %+v, %v", renamed, err)
+	}
+	goTemplate, _ := target.GetByName("go")
+	if goTemplate == nil || !strings.Contains(goTemplate.Content, "include \"lint-imported\"") || goTemplate.Version != 2 {
+		t.Errorf("expected go to include lint-imported in a new version, got %+v", goTemplate)
+	}
+
+	// 试运行不修改模板
+	target = existing()
+	if _, err := ImportBundle(bundle, target, ImportOptions{Strategy: ConflictOverwrite, DryRun: true}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if lint, _ := target.GetByID(1); lint.Version != 1 {
+		t.Errorf("dry run modified template: %+v", lint)
+	}
+}
+
+func TestImportBundleErrors(t *testing.T) {
+	bundle := &Bundle{Version: BundleVersion, Templates: []BundleTemplate{
+		{Template: repository.Template{Name: "broken", Kind: KindFragment, Content: "[[ include \"missing\" . ]]"}},
+		{Template: repository.Template{Platform: "github_actions", Filename: "ci.yml", ConfigType: "yaml", Content: "jobs: [\n"}},
+	}}
+	target := newMemoryRepository()
+	_, err := ImportBundle(bundle, target, ImportOptions{})
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", err)
+	}
+	if len(target.templates) != 0 {
+		t.Errorf("invalid bundle was partially imported: %+v", target.templates)
+	}
+
+	// 覆盖后使用该片段的已有模板会失效
+	target = newMemoryRepository(
+		&repository.Template{Name: "setup", Kind: KindFragment, Content: "- run: setup"},
+		&repository.Template{Platform: "mock", Filename: "mock.yml", ConfigType: "yaml", Content: "[[ include \"setup\" . ]]"},
+	)
+	bundle = &Bundle{Version: BundleVersion, Templates: []BundleTemplate{
+		{Template: repository.Template{Name: "setup", Kind: KindFragment, Extends: "missing", Content: ""}},
+	}}
+	if _, err := ImportBundle(bundle, target, ImportOptions{Strategy: ConflictOverwrite}); !errors.As(err, &importErr) {
+		t.Errorf("expected import error, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic 配置验证诊断
+type Diagnostic = common.Diagnostic
+
+// 诊断规则 ID
+const (
+	RuleYAMLSyntax        = "yaml-syntax"
+	RuleUnknownKey        = "unknown-key"
+	RuleInvalidType       = "invalid-type"
+	RuleRequiredKey       = "required-key"
+	RuleMissingRunsOn     = "missing-runs-on"
+	RuleStepUsesAndRun    = "step-uses-and-run"
+	RuleStepMissingAction = "step-missing-uses-or-run"
+	RuleInvalidNeeds      = "invalid-needs"
+	RuleNeedsCycle        = "needs-cycle"
+	RuleInvalidCron       = "invalid-cron"
+	RuleUnknownEvent      = "unknown-event"
+	RuleInvalidJobID      = "invalid-job-id"
+	RuleMissingScript     = "missing-script"
+	RuleUndefinedStage    = "undefined-stage"
+	RuleDeprecatedKey     = "deprecated-key"
+)
+
+// Diagnostics 诊断列表
+type Diagnostics []Diagnostic
+
+// HasErrors 是否包含错误级别的诊断
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == common.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err 包含错误级别的诊断时返回 *Error，否则返回 nil
+func (d Diagnostics) Err() error {
+	if !d.HasErrors() {
+		return nil
+	}
+	return &Error{Diagnostics: d}
+}
+
+// errorf 添加错误级别的诊断，node 为空时不记录位置
+func (d *Diagnostics) errorf(node *yaml.Node, rule, format string, args ...interface{}) {
+	d.add(common.SeverityError, node, rule, format, args...)
+}
+
+// warnf 添加警告级别的诊断
+func (d *Diagnostics) warnf(node *yaml.Node, rule, format string, args ...interface{}) {
+	d.add(common.SeverityWarning, node, rule, format, args...)
+}
+
+// add 添加诊断
+func (d *Diagnostics) add(severity common.Severity, node *yaml.Node, rule, format string, args ...interface{}) {
+	diagnostic := Diagnostic{Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		diagnostic.Line = node.Line
+		diagnostic.Column = node.Column
+	}
+	*d = append(*d, diagnostic)
+}
+
+// Error 配置验证失败时返回的错误，包含所有诊断
+type Error struct {
+	Diagnostics Diagnostics
+}
+
+// Error 返回第一个错误级别的诊断，其余错误只给出数量
+func (e *Error) Error() string {
+	var first *Diagnostic
+	count := 0
+	for i := range e.Diagnostics {
+		if e.Diagnostics[i].Severity != common.SeverityError {
+			continue
+		}
+		if first == nil {
+			first = &e.Diagnostics[i]
+		}
+		count++
+	}
+	if first == nil {
+		return "config is invalid"
+	}
+
+	message := first.Message
+	if first.Line > 0 {
+		message = fmt.Sprintf("line %d, column %d: %s", first.Line, first.Column, message)
+	}
+	message += " (" + first.Rule + ")"
+	if count > 1 {
+		message += fmt.Sprintf(" and %d more error(s)", count-1)
+	}
+	return message
+}
+
+// yamlErrorLine 匹配 yaml 错误信息中的行号
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// parseDocument 解析 YAML 文档，返回根映射节点。语法错误转换为诊断
+func parseDocument(content string, diagnostics *Diagnostics) *yaml.Node {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		diagnostic := Diagnostic{Severity: common.SeverityError, Rule: RuleYAMLSyntax, Message: message}
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			diagnostic.Line, _ = strconv.Atoi(match[1])
+			diagnostic.Column = 1
+			diagnostic.Message = match[2]
+		}
+		*diagnostics = append(*diagnostics, diagnostic)
+		return nil
+	}
+	if len(root.Content) == 0 {
+		diagnostics.errorf(nil, RuleInvalidType, "config is empty")
+		return nil
+	}
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		diagnostics.errorf(document, RuleInvalidType, "config must be a mapping")
+		return nil
+	}
+	return document
+}
@@ -0,0 +1,350 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+
+	"ci-cd-orchestrator/pkg/cron"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubEvents GitHub Actions 支持的触发事件
+var githubEvents = map[string]bool{
+	"branch_protection_rule": true, "check_run": true, "check_suite": true, "create": true, "delete": true,
+	"deployment": true, "deployment_status": true, "discussion": true, "discussion_comment": true, "fork": true,
+	"gollum": true, "issue_comment": true, "issues": true, "label": true, "merge_group": true, "milestone": true,
+	"page_build": true, "public": true, "pull_request": true, "pull_request_review": true,
+	"pull_request_review_comment": true, "pull_request_target": true, "push": true, "registry_package": true,
+	"release": true, "repository_dispatch": true, "schedule": true, "status": true, "watch": true,
+	"workflow_call": true, "workflow_dispatch": true, "workflow_run": true,
+}
+
+// githubJobID job ID 只能包含字母、数字、- 和 _，且必须以字母或 _ 开头
+var githubJobID = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// githubWorkflowSchema GitHub Actions workflow 的结构定义
+var githubWorkflowSchema = func() *schema {
+	stringList := either(stringType(), listOf(stringType()))
+	env := mapOf(stringType())
+	permissions := either(stringType(), mapOf(stringType()))
+	concurrency := either(stringType(), mappingOf(map[string]*schema{
+		"group":              stringType(),
+		"cancel-in-progress": boolType(),
+	}))
+	defaults := mappingOf(map[string]*schema{
+		"run": mappingOf(map[string]*schema{"shell": stringType(), "working-directory": stringType()}),
+	})
+	refFilter := orNull(mappingOf(map[string]*schema{
+		"branches": stringList, "branches-ignore": stringList, "tags": stringList, "tags-ignore": stringList,
+		"paths": stringList, "paths-ignore": stringList, "types": stringList,
+	}))
+
+	events := map[string]*schema{}
+	for event := range githubEvents {
+		events[event] = orNull(mapOf(anything))
+	}
+	events["push"] = refFilter
+	events["pull_request"] = refFilter
+	events["pull_request_target"] = refFilter
+	events["schedule"] = listOf(mappingOf(map[string]*schema{"cron": stringType()}))
+	events["workflow_dispatch"] = orNull(mappingOf(map[string]*schema{"inputs": mapOf(anything)}))
+
+	container := either(stringType(), mappingOf(map[string]*schema{
+		"image":       stringType(),
+		"credentials": mapOf(stringType()),
+		"env":         env,
+		"ports":       listOf(stringType()),
+		"volumes":     listOf(stringType()),
+		"options":     stringType(),
+	}))
+	resourceSpec := mappingOf(map[string]*schema{"cpu": stringType(), "memory": stringType()})
+
+	step := mappingOf(map[string]*schema{
+		"id":                stringType(),
+		"if":                stringType(),
+		"name":              stringType(),
+		"uses":              stringType(),
+		"run":               stringType(),
+		"shell":             stringType(),
+		"with":              mapOf(stringType()),
+		"env":               env,
+		"continue-on-error": boolType(),
+		"timeout-minutes":   numberType(),
+		"working-directory": stringType(),
+		// 重试策略是本系统对 workflow 的扩展，由执行引擎使用
+		"retry": either(numberType(), mappingOf(map[string]*schema{
+			"max-attempts":      numberType(),
+			"delay-seconds":     numberType(),
+			"backoff":           numberType(),
+			"max-delay-seconds": numberType(),
+		})),
+	})
+
+	job := mappingOf(map[string]*schema{
+		"name":        stringType(),
+		"runs-on":     either(stringType(), listOf(stringType()), mappingOf(map[string]*schema{"group": stringType(), "labels": stringList})),
+		"needs":       stringList,
+		"if":          stringType(),
+		"permissions": permissions,
+		"environment": either(stringType(), mappingOf(map[string]*schema{"name": stringType(), "url": stringType()})),
+		"concurrency": concurrency,
+		"outputs":     mapOf(stringType()),
+		"env":         env,
+		"defaults":    defaults,
+		"strategy": mappingOf(map[string]*schema{
+			"matrix":       either(stringType(), mapOf(anything)),
+			"fail-fast":    boolType(),
+			"max-parallel": numberType(),
+		}),
+		"timeout-minutes":   numberType(),
+		"continue-on-error": boolType(),
+		"container":         container,
+		"services":          mapOf(container),
+		"steps":             listOf(step),
+		"uses":              stringType(),
+		"with":              mapOf(stringType()),
+		"secrets":           either(stringType(), mapOf(stringType())),
+		// 资源配置是本系统对 workflow 的扩展，由执行引擎使用
+		"resources": mappingOf(map[string]*schema{"limits": resourceSpec, "requests": resourceSpec}),
+	})
+
+	return mappingOf(map[string]*schema{
+		"name":        stringType(),
+		"run-name":    stringType(),
+		"on":          either(stringType(), listOf(stringType()), mappingOf(events)),
+		"permissions": permissions,
+		"env":         env,
+		"defaults":    defaults,
+		"concurrency": concurrency,
+		"jobs":        mapOf(job),
+	})
+}()
+
+// ValidateGitHubActionsConfig 验证 GitHub Actions 配置：
+// 解析 YAML 并按 workflow 结构检查未知键和类型，job 必须有 runs-on（调用可复用 workflow 的除外）和 steps，
+// step 不能同时使用 uses 和 run，needs 引用的 job 必须存在且不能循环依赖，定时触发的 cron 表达式必须有效
+func (v *ConfigValidator) ValidateGitHubActionsConfig(content string) Diagnostics {
+	return validateWorkflow(content)
+}
+
+// ValidateMockConfig 验证 Mock 平台配置，Mock 平台使用 GitHub Actions 格式
+func (v *ConfigValidator) ValidateMockConfig(content string) Diagnostics {
+	return validateWorkflow(content)
+}
+
+// validateWorkflow 验证 GitHub Actions 格式的 workflow
+func validateWorkflow(content string) Diagnostics {
+	var diagnostics Diagnostics
+	document := parseDocument(content, &diagnostics)
+	if document == nil {
+		return diagnostics
+	}
+
+	githubWorkflowSchema.check(document, "", &diagnostics)
+
+	if _, on := mappingEntry(document, "on"); on == nil {
+		diagnostics.errorf(document, RuleRequiredKey, "workflow must have an 'on' field")
+	} else {
+		checkGitHubTriggers(on, &diagnostics)
+	}
+
+	jobsKey, jobs := mappingEntry(document, "jobs")
+	if jobs == nil {
+		diagnostics.errorf(document, RuleRequiredKey, "workflow must have a 'jobs' field")
+		return diagnostics
+	}
+	if jobs.Kind != yaml.MappingNode {
+		return diagnostics
+	}
+	if len(jobs.Content) == 0 {
+		diagnostics.errorf(jobsKey, RuleRequiredKey, "workflow must define at least one job")
+		return diagnostics
+	}
+
+	graph := newNeedsGraph()
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		key, job := jobs.Content[i], resolveAlias(jobs.Content[i+1])
+		graph.addJob(key.Value, key)
+		if !githubJobID.MatchString(key.Value) {
+			diagnostics.errorf(key, RuleInvalidJobID, "job ID '%s' must start with a letter or '_' and contain only letters, digits, '-' and '_'", key.Value)
+		}
+		if job.Kind != yaml.MappingNode {
+			continue
+		}
+		checkGitHubJob(key, job, &diagnostics)
+
+		if needs := mappingValue(job, "needs"); needs != nil {
+			for _, need := range scalarItems(needs) {
+				graph.addNeed(key.Value, need.Value, need)
+			}
+		}
+	}
+	graph.check(&diagnostics)
+
+	return diagnostics
+}
+
+// checkGitHubJob 检查 job 的必填字段和 step
+func checkGitHubJob(key, job *yaml.Node, diagnostics *Diagnostics) {
+	// 调用可复用 workflow 的 job 不需要 runs-on 和 steps
+	if mappingValue(job, "uses") != nil {
+		return
+	}
+	if mappingValue(job, "runs-on") == nil {
+		diagnostics.errorf(key, RuleMissingRunsOn, "job '%s' must have a 'runs-on' field", key.Value)
+	}
+
+	steps := mappingValue(job, "steps")
+	if steps == nil {
+		diagnostics.errorf(key, RuleRequiredKey, "job '%s' must have a 'steps' field", key.Value)
+		return
+	}
+	if steps.Kind == yaml.SequenceNode && len(steps.Content) == 0 {
+		diagnostics.errorf(steps, RuleRequiredKey, "job '%s' must have at least one step", key.Value)
+	}
+	for i, step := range steps.Content {
+		step = resolveAlias(step)
+		if step.Kind != yaml.MappingNode {
+			continue
+		}
+		uses, run := mappingValue(step, "uses"), mappingValue(step, "run")
+		switch {
+		case uses != nil && run != nil:
+			diagnostics.errorf(step, RuleStepUsesAndRun, "step %d of job '%s' cannot have both 'uses' and 'run'", i+1, key.Value)
+		case uses == nil && run == nil:
+			diagnostics.errorf(step, RuleStepMissingAction, "step %d of job '%s' must have either 'uses' or 'run'", i+1, key.Value)
+		}
+	}
+}
+
+// checkGitHubTriggers 检查事件名称和定时触发的 cron 表达式
+func checkGitHubTriggers(on *yaml.Node, diagnostics *Diagnostics) {
+	on = resolveAlias(on)
+	switch on.Kind {
+	case yaml.ScalarNode, yaml.SequenceNode:
+		// 映射形式的事件名称由结构定义检查
+		for _, event := range scalarItems(on) {
+			if !githubEvents[event.Value] {
+				diagnostics.errorf(event, RuleUnknownEvent, "unknown event '%s'", event.Value)
+			}
+		}
+	case yaml.MappingNode:
+		schedule := mappingValue(on, "schedule")
+		if schedule == nil || schedule.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, item := range schedule.Content {
+			expr := mappingValue(resolveAlias(item), "cron")
+			if expr == nil {
+				diagnostics.errorf(item, RuleRequiredKey, "schedule entry must have a 'cron' field")
+				continue
+			}
+			if _, err := cron.Parse(expr.Value); err != nil {
+				diagnostics.errorf(expr, RuleInvalidCron, "invalid cron expression '%s': %v", expr.Value, err)
+			}
+		}
+	}
+}
+
+// needsGraph job 依赖图，用于检查 needs 引用和循环依赖
+type needsGraph struct {
+	order []string
+	nodes map[string]*yaml.Node
+	needs map[string][]*yaml.Node
+}
+
+// newNeedsGraph 创建依赖图
+func newNeedsGraph() *needsGraph {
+	return &needsGraph{nodes: map[string]*yaml.Node{}, needs: map[string][]*yaml.Node{}}
+}
+
+// addJob 添加 job
+func (g *needsGraph) addJob(name string, node *yaml.Node) {
+	g.order = append(g.order, name)
+	g.nodes[name] = node
+}
+
+// addNeed 添加依赖，node 为 needs 中引用的节点
+func (g *needsGraph) addNeed(job, need string, node *yaml.Node) {
+	g.needs[job] = append(g.needs[job], node)
+}
+
+// check 检查依赖是否存在、是否依赖自身以及是否存在循环
+func (g *needsGraph) check(diagnostics *Diagnostics) {
+	for _, job := range g.order {
+		for _, need := range g.needs[job] {
+			switch {
+			case need.Value == job:
+				diagnostics.errorf(need, RuleInvalidNeeds, "job '%s' cannot need itself", job)
+			case g.nodes[need.Value] == nil:
+				diagnostics.errorf(need, RuleInvalidNeeds, "job '%s' needs unknown job '%s'", job, need.Value)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.order))
+	var path []string
+	var visit func(job string) []string
+	visit = func(job string) []string {
+		state[job] = visiting
+		path = append(path, job)
+		for _, need := range g.needs[job] {
+			if need.Value == job || g.nodes[need.Value] == nil {
+				continue
+			}
+			switch state[need.Value] {
+			case visiting:
+				for i, name := range path {
+					if name == need.Value {
+						return append(append([]string{}, path[i:]...), need.Value)
+					}
+				}
+			case unvisited:
+				if cycle := visit(need.Value); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[job] = visited
+		return nil
+	}
+
+	for _, job := range g.order {
+		if state[job] != unvisited {
+			continue
+		}
+		if cycle := visit(job); cycle != nil {
+			diagnostics.errorf(g.nodes[cycle[0]], RuleNeedsCycle, "needs cycle detected: %s", strings.Join(cycle, " -> "))
+			return
+		}
+	}
+}
+
+// scalarItems 返回标量或标量列表中的所有标量节点
+func scalarItems(node *yaml.Node) []*yaml.Node {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		return []*yaml.Node{node}
+	case yaml.SequenceNode:
+		var items []*yaml.Node
+		for _, item := range node.Content {
+			if item = resolveAlias(item); item.Kind == yaml.ScalarNode {
+				items = append(items, item)
+			}
+		}
+		return items
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,322 @@
+package validator
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabGlobalKeywords .gitlab-ci.yml 中不是 job 的顶层关键字
+var gitlabGlobalKeywords = map[string]bool{
+	"default":       true,
+	"include":       true,
+	"stages":        true,
+	"variables":     true,
+	"workflow":      true,
+	"image":         true,
+	"services":      true,
+	"cache":         true,
+	"before_script": true,
+	"after_script":  true,
+}
+
+// gitlabDefaultStages 未声明 stages 时 GitLab 使用的默认阶段
+var gitlabDefaultStages = []string{"build", "test", "deploy"}
+
+// gitlabDeprecatedKeywords 已弃用的 job 关键字及替代写法
+var gitlabDeprecatedKeywords = map[string]string{
+	"only":   "rules",
+	"except": "rules",
+}
+
+// gitlabJobSchema GitLab CI job 的结构定义，default 和全局关键字复用其中的部分定义
+var gitlabJobSchema, gitlabConfigSchema = func() (*schema, *schema) {
+	stringList := either(stringType(), listOf(stringType()))
+	script := either(stringType(), listOf(either(stringType(), listOf(stringType()))))
+	image := either(stringType(), mapOf(anything))
+	services := listOf(either(stringType(), mapOf(anything)))
+	cache := either(mapOf(anything), listOf(mapOf(anything)))
+	variables := mapOf(either(stringType(), mappingOf(map[string]*schema{
+		"value": stringType(), "description": stringType(), "options": listOf(stringType()), "expand": boolType(),
+	})))
+	rules := listOf(mapOf(anything))
+
+	job := mappingOf(map[string]*schema{
+		"after_script":        script,
+		"allow_failure":       either(boolType(), mapOf(anything)),
+		"artifacts":           mapOf(anything),
+		"before_script":       script,
+		"cache":               cache,
+		"coverage":            stringType(),
+		"dast_configuration":  mapOf(anything),
+		"dependencies":        listOf(stringType()),
+		"environment":         either(stringType(), mapOf(anything)),
+		"except":              either(stringList, mapOf(anything)),
+		"extends":             stringList,
+		"hooks":               mapOf(anything),
+		"id_tokens":           mapOf(anything),
+		"identity":            stringType(),
+		"image":               image,
+		"inherit":             mapOf(anything),
+		"interruptible":       boolType(),
+		"manual_confirmation": stringType(),
+		"needs":               listOf(either(stringType(), mapOf(anything))),
+		"only":                either(stringList, mapOf(anything)),
+		"pages":               either(boolType(), mapOf(anything)),
+		"parallel":            either(numberType(), mappingOf(map[string]*schema{"matrix": listOf(mapOf(anything))})),
+		"release":             mapOf(anything),
+		"resource_group":      stringType(),
+		"retry":               either(numberType(), mapOf(anything)),
+		"rules":               rules,
+		"run":                 listOf(mapOf(anything)),
+		"script":              script,
+		"secrets":             mapOf(anything),
+		"services":            services,
+		"stage":               stringType(),
+		"tags":                listOf(stringType()),
+		"timeout":             stringType(),
+		"trigger":             either(stringType(), mapOf(anything)),
+		"variables":           variables,
+		"when":                stringType(),
+	})
+
+	config := mappingOf(map[string]*schema{
+		"default": mappingOf(map[string]*schema{
+			"after_script": script, "artifacts": mapOf(anything), "before_script": script, "cache": cache,
+			"hooks": mapOf(anything), "id_tokens": mapOf(anything), "image": image, "interruptible": boolType(),
+			"retry": either(numberType(), mapOf(anything)), "services": services, "tags": listOf(stringType()),
+			"timeout": stringType(),
+		}),
+		"include":   either(stringType(), listOf(either(stringType(), mapOf(anything))), mapOf(anything)),
+		"stages":    listOf(stringType()),
+		"variables": variables,
+		"workflow": mappingOf(map[string]*schema{
+			"name": stringType(), "rules": rules, "auto_cancel": mapOf(anything),
+		}),
+		"image":         image,
+		"services":      services,
+		"cache":         cache,
+		"before_script": script,
+		"after_script":  script,
+	})
+	return job, config
+}()
+
+// gitlabJob 验证所需的 job 字段
+type gitlabJob struct {
+	name  string
+	key   *yaml.Node
+	stage string
+	needs []gitlabNeed
+}
+
+// gitlabNeed needs 中引用的 job
+type gitlabNeed struct {
+	job      string
+	optional bool
+	node     *yaml.Node
+}
+
+// ValidateGitLabCIConfig 验证 GitLab CI 配置：
+// 解析 YAML 并检查全局关键字和 job 的未知键和类型，每个 job 必须有 script（使用 trigger 或 extends 的除外），
+// job 的 stage 必须已声明，needs 引用的 job 必须存在且不能位于更晚的阶段，needs 之间不能循环依赖
+func (v *ConfigValidator) ValidateGitLabCIConfig(content string) Diagnostics {
+	var diagnostics Diagnostics
+	document := parseDocument(content, &diagnostics)
+	if document == nil {
+		return diagnostics
+	}
+
+	// 解析 stages，.pre 和 .post 始终可用
+	stages := gitlabDefaultStages
+	if node := mappingValue(document, "stages"); node != nil && node.Kind == yaml.SequenceNode {
+		stages = nil
+		for _, item := range scalarItems(node) {
+			stages = append(stages, item.Value)
+		}
+	}
+	stageIndex := map[string]int{".pre": -1, ".post": len(stages)}
+	for i, stage := range stages {
+		stageIndex[stage] = i
+	}
+
+	// 全局关键字和 job 分开检查，以 . 开头的隐藏 job 只作为模板使用
+	globals := &yaml.Node{Kind: yaml.MappingNode}
+	var jobs []*gitlabJob
+	jobsByName := make(map[string]*gitlabJob)
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		key, node := document.Content[i], resolveAlias(document.Content[i+1])
+		name := key.Value
+		if gitlabGlobalKeywords[name] {
+			globals.Content = append(globals.Content, key, node)
+			continue
+		}
+		if node.Kind != yaml.MappingNode {
+			diagnostics.errorf(node, RuleInvalidType, "job '%s' must be a mapping", name)
+			continue
+		}
+		gitlabJobSchema.check(node, name, &diagnostics)
+		for _, keyword := range sortedKeys(gitlabDeprecatedKeywords) {
+			if entryKey, _ := mappingEntry(node, keyword); entryKey != nil {
+				diagnostics.warnf(entryKey, RuleDeprecatedKey, "'%s' is deprecated, use '%s' instead", keyword, gitlabDeprecatedKeywords[keyword])
+			}
+		}
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		job := parseGitLabJob(key, node, &diagnostics)
+		if _, exists := stageIndex[job.stage]; !exists {
+			stageNode := mappingValue(node, "stage")
+			if stageNode == nil {
+				stageNode = key
+			}
+			diagnostics.errorf(stageNode, RuleUndefinedStage, "job '%s' uses undefined stage '%s'", name, job.stage)
+		}
+		jobs = append(jobs, job)
+		jobsByName[name] = job
+	}
+	gitlabConfigSchema.check(globals, "", &diagnostics)
+
+	if len(jobs) == 0 {
+		diagnostics.errorf(document, RuleRequiredKey, "config must define at least one job")
+		return diagnostics
+	}
+
+	// 检查 needs 引用，不存在的 job 不加入依赖图
+	graph := newNeedsGraph()
+	for _, job := range jobs {
+		graph.addJob(job.name, job.key)
+	}
+	for _, job := range jobs {
+		for _, need := range job.needs {
+			needed, exists := jobsByName[need.job]
+			if !exists {
+				if !need.optional {
+					diagnostics.errorf(need.node, RuleInvalidNeeds, "job '%s' needs unknown job '%s'", job.name, need.job)
+				}
+				continue
+			}
+			if stageIndex[needed.stage] > stageIndex[job.stage] {
+				diagnostics.errorf(need.node, RuleInvalidNeeds, "job '%s' needs job '%s' from later stage '%s'", job.name, need.job, needed.stage)
+			}
+			graph.addNeed(job.name, need.job, need.node)
+		}
+	}
+	graph.check(&diagnostics)
+
+	return diagnostics
+}
+
+// parseGitLabJob 解析单个 job 的 stage 和 needs，并检查 script
+func parseGitLabJob(key, node *yaml.Node, diagnostics *Diagnostics) *gitlabJob {
+	name := key.Value
+	job := &gitlabJob{name: name, key: key, stage: "test"}
+
+	if stage := mappingValue(node, "stage"); stage != nil && stage.Kind == yaml.ScalarNode {
+		job.stage = stage.Value
+	}
+
+	script := mappingValue(node, "script")
+	if script == nil {
+		// trigger job 不需要 script，extends 的模板可能提供 script
+		if mappingValue(node, "trigger") == nil && mappingValue(node, "extends") == nil {
+			diagnostics.errorf(key, RuleMissingScript, "job '%s' must have a 'script' field", name)
+		}
+	} else if !validScript(script) {
+		diagnostics.errorf(script, RuleMissingScript, "job '%s' script must be a non-empty string or list of strings", name)
+	}
+
+	needs := mappingValue(node, "needs")
+	if needs == nil || needs.Kind != yaml.SequenceNode {
+		return job
+	}
+	for _, item := range needs.Content {
+		item = resolveAlias(item)
+		switch item.Kind {
+		case yaml.ScalarNode:
+			job.needs = append(job.needs, gitlabNeed{job: item.Value, node: item})
+		case yaml.MappingNode:
+			// 跨项目或父 pipeline 的依赖不在当前配置中校验
+			if mappingValue(item, "project") != nil || mappingValue(item, "pipeline") != nil {
+				continue
+			}
+			ref := mappingValue(item, "job")
+			if ref == nil || ref.Value == "" {
+				diagnostics.errorf(item, RuleRequiredKey, "needs entry of job '%s' must have a 'job' field", name)
+				continue
+			}
+			optional := mappingValue(item, "optional")
+			job.needs = append(job.needs, gitlabNeed{
+				job:      ref.Value,
+				optional: optional != nil && optional.Value == "true",
+				node:     ref,
+			})
+		}
+	}
+
+	return job
+}
+
+// validScript 判断 script 是否为非空字符串或字符串列表（允许嵌套一层列表）
+func validScript(node *yaml.Node) bool {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return strings.TrimSpace(node.Value) != ""
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			return false
+		}
+		for _, item := range node.Content {
+			item = resolveAlias(item)
+			if item.Kind == yaml.SequenceNode {
+				if !validScript(item) {
+					return false
+				}
+				continue
+			}
+			if item.Kind != yaml.ScalarNode {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// mappingValue 获取映射节点中指定键的值节点，支持 <<: *anchor 合并的键
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
+
+// mappingEntry 获取映射节点中指定键的键节点和值节点，支持 <<: *anchor 合并的键
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], resolveAlias(node.Content[i+1])
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "<<" {
+			continue
+		}
+		merged := resolveAlias(node.Content[i+1])
+		sources := []*yaml.Node{merged}
+		if merged.Kind == yaml.SequenceNode {
+			sources = merged.Content
+		}
+		for _, source := range sources {
+			if k, v := mappingEntry(source, key); v != nil {
+				return k, v
+			}
+		}
+	}
+	return nil, nil
+}
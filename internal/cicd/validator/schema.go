@@ -0,0 +1,251 @@
+package validator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scalarType 标量类型
+type scalarType int
+
+// 支持的标量类型
+const (
+	scalarNone scalarType = iota
+	scalarString
+	scalarBool
+	scalarNumber
+)
+
+// schema 配置节点的结构定义，同一节点可以同时允许标量、映射和列表等多种写法
+type schema struct {
+	any      bool               // 不检查
+	scalar   scalarType         // 允许的标量类型，scalarNone 表示不允许标量
+	nullable bool               // 允许空值
+	keys     map[string]*schema // 映射允许的键
+	values   *schema            // 映射中任意键的值，keys 为空时使用
+	items    *schema            // 列表元素
+}
+
+// anything 任意值
+var anything = &schema{any: true}
+
+// stringType 字符串（数字等标量也会按字符串处理）
+func stringType() *schema { return &schema{scalar: scalarString} }
+
+// boolType 布尔值或表达式
+func boolType() *schema { return &schema{scalar: scalarBool} }
+
+// numberType 数字或表达式
+func numberType() *schema { return &schema{scalar: scalarNumber} }
+
+// mappingOf 只允许指定键的映射
+func mappingOf(keys map[string]*schema) *schema { return &schema{keys: keys} }
+
+// mapOf 任意键的映射
+func mapOf(values *schema) *schema { return &schema{values: values} }
+
+// listOf 列表
+func listOf(items *schema) *schema { return &schema{items: items} }
+
+// either 合并多种写法
+func either(schemas ...*schema) *schema {
+	merged := &schema{}
+	for _, s := range schemas {
+		merged.any = merged.any || s.any
+		merged.nullable = merged.nullable || s.nullable
+		if s.scalar != scalarNone {
+			merged.scalar = s.scalar
+		}
+		if s.keys != nil {
+			merged.keys = s.keys
+		}
+		if s.values != nil {
+			merged.values = s.values
+		}
+		if s.items != nil {
+			merged.items = s.items
+		}
+	}
+	return merged
+}
+
+// orNull 允许空值
+func orNull(s *schema) *schema {
+	copied := *s
+	copied.nullable = true
+	return &copied
+}
+
+// isMapping 是否允许映射
+func (s *schema) isMapping() bool { return s.keys != nil || s.values != nil }
+
+// describe 描述允许的写法，用于错误信息
+func (s *schema) describe() string {
+	var kinds []string
+	switch s.scalar {
+	case scalarString:
+		kinds = append(kinds, "a string")
+	case scalarBool:
+		kinds = append(kinds, "a boolean")
+	case scalarNumber:
+		kinds = append(kinds, "a number")
+	}
+	if s.isMapping() {
+		kinds = append(kinds, "a mapping")
+	}
+	if s.items != nil {
+		kinds = append(kinds, "a list")
+	}
+	return strings.Join(kinds, " or ")
+}
+
+// check 按结构定义递归检查节点，path 为节点路径，例如 jobs.build.steps[0]
+func (s *schema) check(node *yaml.Node, path string, diagnostics *Diagnostics) {
+	if s.any || node == nil {
+		return
+	}
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			if !s.nullable {
+				diagnostics.errorf(node, RuleInvalidType, "'%s' must be %s, got null", path, s.describe())
+			}
+			return
+		}
+		if !s.matchScalar(node) {
+			diagnostics.errorf(node, RuleInvalidType, "'%s' must be %s, got %s", path, s.describe(), scalarName(node))
+		}
+	case yaml.MappingNode:
+		if !s.isMapping() {
+			diagnostics.errorf(node, RuleInvalidType, "'%s' must be %s, got a mapping", path, s.describe())
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Value == "<<" {
+				continue
+			}
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			if s.keys == nil {
+				s.values.check(node.Content[i+1], childPath, diagnostics)
+				continue
+			}
+			child, known := s.keys[key.Value]
+			if !known {
+				message := "unknown key '" + key.Value + "' in '" + displayPath(path) + "'"
+				if suggestion := closestKey(key.Value, s.keys); suggestion != "" {
+					message += ", did you mean '" + suggestion + "'?"
+				}
+				diagnostics.errorf(key, RuleUnknownKey, "%s", message)
+				continue
+			}
+			child.check(node.Content[i+1], childPath, diagnostics)
+		}
+	case yaml.SequenceNode:
+		if s.items == nil {
+			diagnostics.errorf(node, RuleInvalidType, "'%s' must be %s, got a list", path, s.describe())
+			return
+		}
+		for i, item := range node.Content {
+			s.items.check(item, path+"["+strconv.Itoa(i)+"]", diagnostics)
+		}
+	}
+}
+
+// matchScalar 判断标量是否符合类型，布尔值和数字也可以写成 ${{ }} 表达式
+func (s *schema) matchScalar(node *yaml.Node) bool {
+	switch s.scalar {
+	case scalarString:
+		return true
+	case scalarBool:
+		return node.Tag == "!!bool" || isExpression(node.Value)
+	case scalarNumber:
+		return node.Tag == "!!int" || node.Tag == "!!float" || isExpression(node.Value)
+	default:
+		return false
+	}
+}
+
+// isExpression 判断是否为 ${{ }} 表达式
+func isExpression(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "${{") && strings.HasSuffix(value, "}}")
+}
+
+// scalarName 标量类型名称
+func scalarName(node *yaml.Node) string {
+	switch node.Tag {
+	case "!!bool":
+		return "a boolean"
+	case "!!int", "!!float":
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+// displayPath 错误信息中的路径，顶层显示为 root
+func displayPath(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
+
+// resolveAlias 解析 YAML 别名
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// sortedKeys 获取 map 的键并排序
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// closestKey 查找与 key 编辑距离不超过 2 的已知键，用于提示拼写错误
+func closestKey(key string, keys map[string]*schema) string {
+	best, bestDistance := "", 3
+	for _, candidate := range sortedKeys(keys) {
+		if distance := editDistance(key, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance 计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,182 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+)
+
+// location 诊断的规则和位置
+type location struct {
+	rule   string
+	line   int
+	column int
+}
+
+// locations 提取诊断的规则和位置
+func locations(diagnostics Diagnostics) []location {
+	var result []location
+	for _, diagnostic := range diagnostics {
+		result = append(result, location{diagnostic.Rule, diagnostic.Line, diagnostic.Column})
+	}
+	return result
+}
+
+// assertDiagnostic 断言诊断列表包含指定规则和位置的诊断
+func assertDiagnostic(t *testing.T, diagnostics Diagnostics, rule string, line, column int) {
+	t.Helper()
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Rule == rule && diagnostic.Line == line && diagnostic.Column == column {
+			return
+		}
+	}
+	t.Errorf("expected %s at %d:%d, got %v", rule, line, column, locations(diagnostics))
+}
+
+func TestValidateGitHubActionsConfig(t *testing.T) {
+	content := `name: CI
+on:
+  push:
+  schedule:
+    - cron: "61 * * * *"
+jobs:
+  build:
+    runs_on: ubuntu-latest
+    timeout-minutes: soon
+    needs: [deploy, missing]
+    steps:
+      - uses: actions/checkout@v4
+        run: echo checkout
+      - name: Nothing
+  deploy:
+    runs-on: ubuntu-latest
+    needs: build
+    steps:
+      - run: echo deploy
+`
+	diagnostics := NewValidator().ValidateGitHubActionsConfig(content)
+
+	assertDiagnostic(t, diagnostics, RuleInvalidCron, 5, 13)
+	assertDiagnostic(t, diagnostics, RuleUnknownKey, 8, 5)
+	assertDiagnostic(t, diagnostics, RuleInvalidType, 9, 22)
+	assertDiagnostic(t, diagnostics, RuleMissingRunsOn, 7, 3)
+	assertDiagnostic(t, diagnostics, RuleStepUsesAndRun, 12, 9)
+	assertDiagnostic(t, diagnostics, RuleStepMissingAction, 14, 9)
+	assertDiagnostic(t, diagnostics, RuleInvalidNeeds, 10, 21)
+	assertDiagnostic(t, diagnostics, RuleNeedsCycle, 7, 3)
+
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Rule == RuleUnknownKey && !strings.Contains(diagnostic.Message, "did you mean 'runs-on'") {
+			t.Errorf("expected suggestion for runs_on, got %q", diagnostic.Message)
+		}
+	}
+}
+
+func TestValidateGitHubActionsConfigValid(t *testing.T) {
+	content := `name: CI
+on:
+  push:
+    branches: [main]
+  schedule:
+    - cron: "0 3 * * MON-FRI"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: ${{ fromJSON(vars.TIMEOUT) }}
+    steps:
+      - uses: actions/checkout@v4
+      - run: go build ./...
+  release:
+    needs: build
+    uses: ./.github/workflows/release.yml
+`
+	if diagnostics := NewValidator().ValidateGitHubActionsConfig(content); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diagnostics)
+	}
+}
+
+func TestValidateGitLabCIConfig(t *testing.T) {
+	content := `stages: [build, test]
+.template: &template
+  image: golang:1.22
+build:
+  <<: *template
+  stage: build
+  needs: [test]
+  script: make
+test:
+  stage: deploy
+  only: [main]
+lint:
+  script: []
+  scriptt: golangci-lint run
+`
+	diagnostics := NewValidator().ValidateGitLabCIConfig(content)
+
+	assertDiagnostic(t, diagnostics, RuleDeprecatedKey, 11, 3)
+	assertDiagnostic(t, diagnostics, RuleMissingScript, 9, 1)
+	assertDiagnostic(t, diagnostics, RuleUndefinedStage, 10, 10)
+	assertDiagnostic(t, diagnostics, RuleUnknownKey, 14, 3)
+	assertDiagnostic(t, diagnostics, RuleMissingScript, 13, 11)
+
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Rule == RuleDeprecatedKey && diagnostic.Severity != common.SeverityWarning {
+			t.Errorf("expected deprecated-key to be a warning, got %s", diagnostic.Severity)
+		}
+	}
+}
+
+func TestValidateGitLabCINeeds(t *testing.T) {
+	content := `stages: [build, test]
+build:
+  stage: build
+  needs: [test, missing, {job: optional, optional: true}]
+  script: make
+test:
+  stage: test
+  needs: [build]
+  script: make test
+`
+	diagnostics := NewValidator().ValidateGitLabCIConfig(content)
+
+	assertDiagnostic(t, diagnostics, RuleInvalidNeeds, 4, 11)
+	assertDiagnostic(t, diagnostics, RuleInvalidNeeds, 4, 17)
+	assertDiagnostic(t, diagnostics, RuleNeedsCycle, 2, 1)
+	for _, diagnostic := range diagnostics {
+		if strings.Contains(diagnostic.Message, "'optional'") {
+			t.Errorf("optional needs should not be reported: %s", diagnostic.Message)
+		}
+	}
+}
+
+func TestValidateSyntaxError(t *testing.T) {
+	diagnostics := NewValidator().ValidateGitLabCIConfig("build:\n  script: [make\n")
+	if len(diagnostics) != 1 || diagnostics[0].Rule != RuleYAMLSyntax || diagnostics[0].Line == 0 {
+		t.Fatalf("expected a single yaml-syntax diagnostic with a line, got %v", diagnostics)
+	}
+}
+
+func TestValidateErr(t *testing.T) {
+	config := &common.PipelineConfig{
+		Platform:   common.PlatformGitHubActions,
+		ConfigType: common.ConfigTypeYAML,
+		Filename:   ".github/workflows/ci.yml",
+		Content:    "name: CI\non: push\njobs:\n  build:\n    steps:\n      - run: make\n",
+	}
+	err := NewValidator().Validate(config).Err()
+
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if want := "line 4, column 3: job 'build' must have a 'runs-on' field (missing-runs-on)"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+
+	config.Content = "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"
+	if err := NewValidator().Validate(config).Err(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+}
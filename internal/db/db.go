@@ -23,9 +23,9 @@ func Init() error {
 	// 数据库文件路径
 	dbPath := filepath.Join(dataDir, "cicd.db")
 
-	// 打开数据库连接
+	// 打开数据库连接，执行引擎会并发写入日志，设置忙等待避免 database is locked
 	var err error
-	DB, err = sql.Open("sqlite3", dbPath)
+	DB, err = sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
 	if err != nil {
 		return fmt.Errorf("打开数据库失败: %w", err)
 	}
@@ -0,0 +1,263 @@
+// Package drift 检测已保存的管道配置与项目目录中的配置文件之间的差异。
+//
+// 配置文件生成后可能在项目仓库中被直接修改，也可能只修改了已保存的配置，两者不一致时称为漂移。
+// 比较基于解析后的管道模型，只修改格式或注释不算漂移
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/model"
+	"ci-cd-orchestrator/internal/cicd/validator"
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/pkg/diff"
+)
+
+// 比较结果
+const (
+	StatusInSync  = "in_sync" // 配置文件与已保存的配置语义相同
+	StatusDrifted = "drifted" // 配置文件与已保存的配置不同
+	StatusMissing = "missing" // 项目目录中没有配置文件
+	StatusUnknown = "unknown" // 项目没有设置路径或无法读取配置文件
+)
+
+// 漂移的处理方式
+const (
+	ActionAdopt     = "adopt"     // 将配置文件保存为管道配置的新修订
+	ActionOverwrite = "overwrite" // 用已保存的配置覆盖配置文件
+)
+
+var (
+	// ErrNoProjectPath 项目没有设置路径
+	ErrNoProjectPath = errors.New("project path is empty")
+	// ErrNoConfigFile 项目目录中没有配置文件
+	ErrNoConfigFile = errors.New("config file not found in project path")
+)
+
+// Report 管道配置与项目目录中配置文件的比较结果
+type Report struct {
+	ProjectID   int         `json:"project_id"`
+	PipelineID  int         `json:"pipeline_id"`
+	Platform    string      `json:"platform"`
+	Revision    int         `json:"revision"` // 参与比较的已保存配置的修订
+	Path        string      `json:"path"`     // 配置文件的完整路径
+	Status      string      `json:"status"`
+	TextChanged bool        `json:"text_changed"`      // 文本是否不同，只修改格式或注释时为 true 但状态为 in_sync
+	Changes     *model.Diff `json:"changes,omitempty"` // 已保存的配置到配置文件的语义差异
+	Diff        string      `json:"diff,omitempty"`    // 已保存的配置到配置文件的 unified diff
+	Message     string      `json:"message,omitempty"` // 无法比较或无法解析的原因
+	CheckedAt   time.Time   `json:"checked_at"`
+}
+
+// Detector 漂移检测器
+type Detector struct {
+	projectRepo  *repository.ProjectRepository
+	pipelineRepo *repository.PipelineRepository
+}
+
+// NewDetector 创建漂移检测器实例
+func NewDetector(projectRepo *repository.ProjectRepository, pipelineRepo *repository.PipelineRepository) *Detector {
+	return &Detector{
+		projectRepo:  projectRepo,
+		pipelineRepo: pipelineRepo,
+	}
+}
+
+// Check 比较管道配置与项目目录中的配置文件，并保存比较结果
+func (d *Detector) Check(pipeline *models.Pipeline) (*Report, error) {
+	project, err := d.projectRepo.GetByID(pipeline.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := Compare(project, pipeline)
+	if err := d.pipelineRepo.UpdateDriftStatus(pipeline.ID, report.Status, report.CheckedAt); err != nil {
+		return nil, err
+	}
+	pipeline.DriftStatus = report.Status
+	pipeline.DriftCheckedAt = report.CheckedAt
+	return report, nil
+}
+
+// CheckAll 检查所有管道配置，返回漂移或配置文件缺失的管道配置数量，单个管道配置检查失败时记录日志并继续
+func (d *Detector) CheckAll() (int, error) {
+	pipelines, err := d.pipelineRepo.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	drifted := 0
+	for _, pipeline := range pipelines {
+		report, err := d.Check(pipeline)
+		if err != nil {
+			// 单个管道配置检查失败（例如项目已被删除）不影响其他管道配置
+			log.Printf("检查管道配置漂移失败 (%d): %v", pipeline.ID, err)
+			continue
+		}
+		if report.Status == StatusDrifted || report.Status == StatusMissing {
+			drifted++
+		}
+	}
+	return drifted, nil
+}
+
+// Run 立即检查一次所有管道配置，之后每隔 interval 检查一次，直到 stop 被关闭
+func (d *Detector) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if drifted, err := d.CheckAll(); err != nil {
+			log.Printf("检查管道配置漂移失败: %v", err)
+		} else if drifted > 0 {
+			log.Printf("%d 个管道配置与项目目录中的配置文件不一致", drifted)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Adopt 将项目目录中的配置文件验证后保存为管道配置的新修订
+func (d *Detector) Adopt(pipeline *models.Pipeline, change repository.PipelineChange) (*Report, error) {
+	project, err := d.projectRepo.GetByID(pipeline.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	path, err := configPath(project, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoConfigFile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := &common.PipelineConfig{
+		Platform:   common.Platform(pipeline.Platform),
+		ConfigType: common.ConfigTypeYAML,
+		Content:    string(content),
+		Filename:   filename(pipeline),
+	}
+	if err := validator.NewValidator().Validate(config).Err(); err != nil {
+		return nil, err
+	}
+
+	if change.Source == "" {
+		change.Source = repository.PipelineSourceDisk
+	}
+	pipeline.Config = string(content)
+	pipeline.Filename = filename(pipeline)
+	if err := d.pipelineRepo.Update(pipeline, change); err != nil {
+		return nil, err
+	}
+	return d.Check(pipeline)
+}
+
+// Overwrite 用已保存的配置覆盖项目目录中的配置文件，配置文件不存在时创建
+func (d *Detector) Overwrite(pipeline *models.Pipeline) (*Report, error) {
+	project, err := d.projectRepo.GetByID(pipeline.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	path, err := configPath(project, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(pipeline.Config), 0644); err != nil {
+		return nil, err
+	}
+	return d.Check(pipeline)
+}
+
+// Compare 比较管道配置与项目目录中的配置文件，不保存比较结果
+func Compare(project *models.Project, pipeline *models.Pipeline) *Report {
+	report := &Report{
+		ProjectID:  pipeline.ProjectID,
+		PipelineID: pipeline.ID,
+		Platform:   pipeline.Platform,
+		Revision:   pipeline.Revision,
+		CheckedAt:  time.Now(),
+	}
+
+	path, err := configPath(project, pipeline)
+	if err != nil {
+		report.Status = StatusUnknown
+		report.Message = err.Error()
+		return report
+	}
+	report.Path = path
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		report.Status = StatusMissing
+		return report
+	}
+	if err != nil {
+		report.Status = StatusUnknown
+		report.Message = err.Error()
+		return report
+	}
+
+	report.Status = StatusInSync
+	if string(content) == pipeline.Config {
+		return report
+	}
+	report.TextChanged = true
+	report.Diff = diff.Unified(pipeline.Config, string(content), fmt.Sprintf("revision %d", pipeline.Revision), filename(pipeline))
+
+	// 任意一方无法解析时只能按文本比较
+	platform := common.Platform(pipeline.Platform)
+	stored, _, err := model.Parse(platform, pipeline.Config)
+	if err != nil {
+		report.Status = StatusDrifted
+		report.Message = fmt.Sprintf("stored config cannot be parsed: %v", err)
+		return report
+	}
+	disk, _, err := model.Parse(platform, string(content))
+	if err != nil {
+		report.Status = StatusDrifted
+		report.Message = fmt.Sprintf("config file cannot be parsed: %v", err)
+		return report
+	}
+
+	if changes := model.Compare(stored, disk); !changes.Empty() {
+		report.Status = StatusDrifted
+		report.Changes = changes
+	}
+	return report
+}
+
+// configPath 管道配置在项目目录中的配置文件路径
+func configPath(project *models.Project, pipeline *models.Pipeline) (string, error) {
+	if project.Path == "" {
+		return "", ErrNoProjectPath
+	}
+	return filepath.Join(project.Path, filename(pipeline)), nil
+}
+
+// filename 管道配置的文件路径，升级前保存的配置没有记录时使用平台的默认路径
+func filename(pipeline *models.Pipeline) string {
+	if pipeline.Filename != "" {
+		return pipeline.Filename
+	}
+	return common.DefaultFilename(common.Platform(pipeline.Platform))
+}
@@ -0,0 +1,88 @@
+package drift
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ci-cd-orchestrator/internal/cicd/model"
+	"ci-cd-orchestrator/internal/models"
+)
+
+const storedConfig = `name: CI
+on: [push]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+    - uses: actions/checkout@v4
+    - name: Build
+      run: go build ./...
+`
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	project := &models.Project{ID: 1, Path: dir}
+	pipeline := &models.Pipeline{ID: 2, ProjectID: 1, Platform: "github_actions", Config: storedConfig, Revision: 3}
+	path := filepath.Join(dir, ".github/workflows/ci.yml")
+
+	write := func(content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 没有记录文件路径时使用平台的默认路径
+	if report := Compare(project, pipeline); report.Status != StatusMissing || report.Path != path {
+		t.Errorf("配置文件不存在时应为 missing: %+v", report)
+	}
+
+	write(storedConfig)
+	if report := Compare(project, pipeline); report.Status != StatusInSync || report.TextChanged {
+		t.Errorf("内容相同时应为 in_sync: %+v", report)
+	}
+
+	// 只修改格式和注释不算漂移
+	write("# 由模板生成\n" + strings.Replace(storedConfig, "on: [push]", "on:\n  - push", 1))
+	report := Compare(project, pipeline)
+	if report.Status != StatusInSync || !report.TextChanged || report.Diff == "" {
+		t.Errorf("只修改格式时应为 in_sync 且 text_changed: %+v", report)
+	}
+
+	write(strings.Replace(storedConfig, "go build ./...", "go build -v ./...", 1) + "  lint:\n    runs-on: ubuntu-latest\n    steps:\n    - run: golangci-lint run\n")
+	report = Compare(project, pipeline)
+	if report.Status != StatusDrifted || report.Changes == nil {
+		t.Fatalf("修改 job 后应为 drifted: %+v", report)
+	}
+	expected := []model.JobChange{
+		{Job: "build", Change: model.ChangeChanged, Steps: []model.StepChange{{Step: "Build", Change: model.ChangeChanged, From: 2, To: 2, Fields: []string{"run"}}}},
+		{Job: "lint", Change: model.ChangeAdded},
+	}
+	if !reflect.DeepEqual(report.Changes.Jobs, expected) {
+		t.Errorf("语义差异不正确: %+v", report.Changes)
+	}
+	if !strings.Contains(report.Diff, "--- revision 3") {
+		t.Errorf("文本差异应以已保存的修订为基准: %s", report.Diff)
+	}
+
+	// 无法解析的配置文件按文本比较
+	write("jobs: [")
+	if report := Compare(project, pipeline); report.Status != StatusDrifted || report.Message == "" {
+		t.Errorf("无法解析的配置文件应为 drifted 并说明原因: %+v", report)
+	}
+
+	// 记录了文件路径时使用记录的路径
+	pipeline.Filename = ".github/workflows/build.yml"
+	if report := Compare(project, pipeline); report.Status != StatusMissing || !strings.HasSuffix(report.Path, "build.yml") {
+		t.Errorf("应使用记录的文件路径: %+v", report)
+	}
+
+	if report := Compare(&models.Project{ID: 1}, pipeline); report.Status != StatusUnknown {
+		t.Errorf("项目没有路径时应为 unknown: %+v", report)
+	}
+}
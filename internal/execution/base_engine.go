@@ -0,0 +1,382 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// baseEngine 执行引擎公共部分，负责执行记录的登记、查询、日志追加和事件推送
+type baseEngine struct {
+	executions map[string]*Execution
+	cancels    map[string]context.CancelCauseFunc // 运行尚未退出的执行的取消函数
+	released   map[string]bool                    // 已释放但运行尚未退出的执行
+	recorder   Recorder
+	broker     *broker
+	jobGroups  *jobGroups
+	mutex      sync.RWMutex
+}
+
+// newBaseEngine 创建执行引擎公共部分
+func newBaseEngine() *baseEngine {
+	return &baseEngine{
+		executions: make(map[string]*Execution),
+		cancels:    make(map[string]context.CancelCauseFunc),
+		released:   make(map[string]bool),
+		broker:     newBroker(),
+		jobGroups:  newJobGroups(),
+	}
+}
+
+// RegisterExecution 注册执行记录
+func (e *baseEngine) RegisterExecution(execution *Execution) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.executions[execution.ID] = execution
+}
+
+// ReleaseExecution 从内存中移除已结束并由记录器保存的执行，运行尚未退出时在退出后移除，
+// 退出前追加的日志仍会记录
+func (e *baseEngine) ReleaseExecution(executionID string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, running := e.cancels[executionID]; running {
+		e.released[executionID] = true
+		return
+	}
+	delete(e.executions, executionID)
+}
+
+// SetRecorder 设置执行记录器
+func (e *baseEngine) SetRecorder(recorder Recorder) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.recorder = recorder
+}
+
+// SetJobGroups 设置 job 级并发组，执行管理器让所有引擎共享同一组并发组
+func (e *baseEngine) SetJobGroups(groups *jobGroups) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.jobGroups = groups
+}
+
+// GetStatus 获取执行状态
+func (e *baseEngine) GetStatus(executionID string) (*Execution, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return nil, fmt.Errorf("execution not found: %s", executionID)
+	}
+
+	// 返回执行的副本，避免并发修改问题
+	snapshot := snapshotExecution(execution)
+	if execution.Logs != nil {
+		snapshot.Logs = make([]LogEntry, len(execution.Logs))
+		copy(snapshot.Logs, execution.Logs)
+	}
+
+	return snapshot, nil
+}
+
+// Subscribe 订阅执行的日志和状态事件，返回 lastLogID 之后的已有日志和后续事件。
+// lastLogID 为空或不存在时返回全部已有日志
+func (e *baseEngine) Subscribe(executionID, lastLogID string) (*Subscription, error) {
+	// 持有读锁期间 appendLog 和 notify 无法执行，保证已有日志与后续事件之间不丢不重
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return nil, fmt.Errorf("execution not found: %s", executionID)
+	}
+
+	subscription := &Subscription{
+		Execution: snapshotExecution(execution),
+		Backlog:   logsAfter(execution.Logs, lastLogID),
+	}
+
+	// 已结束的执行不会再有新事件
+	if IsFinished(execution.Status) {
+		events := make(chan Event)
+		close(events)
+		subscription.Events = events
+		return subscription, nil
+	}
+
+	events, cancel := e.broker.subscribe(executionID)
+	subscription.Events = events
+	subscription.cancel = cancel
+	return subscription, nil
+}
+
+// SetStatus 设置引擎尚未运行的执行的状态，例如进入队列、在队列中被取消或启动失败，不合法的状态变化返回 TransitionError。
+// message 不为空时追加到执行日志，结束状态同时记录结束时间，并将 message 作为结束原因
+func (e *baseEngine) SetStatus(executionID, status, actor, message string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+
+	if err := e.transition(execution, status, actor, message); err != nil {
+		return err
+	}
+	if message != "" {
+		level := "info"
+		if status == StatusFailed {
+			level = "error"
+		}
+		e.appendLog(execution, LogEntry{
+			Level:   level,
+			Stage:   "queue",
+			Message: message,
+		})
+	}
+	e.notify(execution)
+
+	return nil
+}
+
+// startRun 从上级上下文创建执行的取消上下文，调用方需持有写锁。
+// 返回的函数在运行退出时调用，取消上下文，运行期间被释放的执行随之从内存中移除
+func (e *baseEngine) startRun(parent context.Context, executionID string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	e.cancels[executionID] = cancel
+	return ctx, func() {
+		cancel(nil)
+
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		delete(e.cancels, executionID)
+		if e.released[executionID] {
+			delete(e.released, executionID)
+			delete(e.executions, executionID)
+		}
+	}
+}
+
+// cancelRun 以 cause 取消执行上下文，运行中的 job 和子进程随之停止，调用方需持有写锁
+func (e *baseEngine) cancelRun(executionID string, cause error) {
+	if cancel, ok := e.cancels[executionID]; ok {
+		cancel(cause)
+	}
+}
+
+// Stop 停止执行，ctx 携带的操作者记录到状态变化中
+func (e *baseEngine) Stop(ctx context.Context, executionID string) error {
+	return e.Cancel(ctx, executionID, "Execution cancelled by user")
+}
+
+// Cancel 以指定原因取消运行中的执行，原因记录到执行日志和执行记录
+func (e *baseEngine) Cancel(ctx context.Context, executionID, reason string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	return e.cancelExecution(execution, actorFrom(ctx, ActorSystem), reason)
+}
+
+// cancelExecution 取消执行上下文并将执行标记为已取消，调用方需持有写锁
+func (e *baseEngine) cancelExecution(execution *Execution, actor, reason string) error {
+	if execution.Status != StatusRunning {
+		return fmt.Errorf("execution is not running: %s", execution.Status)
+	}
+
+	e.cancelRun(execution.ID, &cancellation{actor: actor, reason: reason})
+	if err := e.transition(execution, StatusCancelled, actor, reason); err != nil {
+		return err
+	}
+
+	// 未结束的 job 随执行取消
+	for i := range execution.Jobs {
+		if job := &execution.Jobs[i]; job.Status == StatusPending || job.Status == StatusRunning {
+			finishJob(job, StatusCancelled, reason, execution.EndTime)
+		}
+	}
+
+	e.appendLog(execution, LogEntry{
+		Level:   "info",
+		Stage:   "cancellation",
+		Message: reason,
+	})
+	e.notify(execution)
+	return nil
+}
+
+// stopped 在执行上下文被取消后结束执行。由 Cancel 取消时执行已被标记为已取消；
+// 上级上下文被取消时，以其 Cause 为原因通过 cancel 取消执行，远程引擎同时取消远程的运行
+func (e *baseEngine) stopped(ctx context.Context, executionID string, cancel func(ctx context.Context, executionID, reason string) error) {
+	var cancelled *cancellation
+	if ctx.Err() == nil || errors.As(context.Cause(ctx), &cancelled) {
+		return
+	}
+	e.mutex.RLock()
+	execution, exists := e.executions[executionID]
+	running := exists && execution.Status == StatusRunning
+	e.mutex.RUnlock()
+	if !running {
+		return
+	}
+
+	reason := "Execution cancelled: " + cancelReason(ctx)
+	if err := cancel(WithActor(context.Background(), ActorSystem), executionID, reason); err != nil && !IsTransitionError(err) {
+		e.addLog(executionID, "error", "cancellation", fmt.Sprintf("Failed to cancel execution: %v", err))
+	}
+}
+
+// addLogWithStep 添加带步骤信息的日志条目
+func (e *baseEngine) addLogWithStep(executionID, level, stage, step, message string) {
+	e.addLogEntry(executionID, LogEntry{
+		Level:   level,
+		Stage:   stage,
+		Step:    step,
+		Message: message,
+	})
+}
+
+// addLog 添加日志条目
+func (e *baseEngine) addLog(executionID, level, stage, message string) {
+	e.addLogEntry(executionID, LogEntry{
+		Level:   level,
+		Stage:   stage,
+		Message: message,
+	})
+}
+
+// addDebugLog 执行开启了调试日志时添加 debug 级别的日志条目，context 记录相关的配置和运行细节
+func (e *baseEngine) addDebugLog(executionID, stage, step, message string, context map[string]interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists || !execution.Debug {
+		return
+	}
+
+	e.appendLog(execution, LogEntry{
+		Level:   "debug",
+		Stage:   stage,
+		Step:    step,
+		Message: message,
+		Context: context,
+	})
+}
+
+// debugEnabled 判断执行是否开启了调试日志
+func (e *baseEngine) debugEnabled(executionID string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	execution, exists := e.executions[executionID]
+	return exists && execution.Debug
+}
+
+// addLogEntry 补全日志条目的 ID、执行 ID 和时间后追加到执行记录
+func (e *baseEngine) addLogEntry(executionID string, entry LogEntry) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return
+	}
+
+	e.appendLog(execution, entry)
+}
+
+// appendLog 向执行记录追加日志条目，调用方需持有写锁。记录器只缓冲日志，不在锁内等待持久化
+func (e *baseEngine) appendLog(execution *Execution, entry LogEntry) {
+	if execution.Logs == nil {
+		execution.Logs = []LogEntry{}
+	}
+
+	entry.ID = uuid.New().String()
+	entry.ExecutionID = execution.ID
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	execution.Logs = append(execution.Logs, entry)
+
+	if e.recorder != nil {
+		e.recorder.RecordLog(entry)
+	}
+
+	e.broker.publish(execution.ID, Event{Type: EventLog, Log: &entry})
+}
+
+// notify 通知记录器和订阅者执行状态发生变化，调用方需持有写锁
+func (e *baseEngine) notify(execution *Execution) {
+	snapshot := snapshotExecution(execution)
+
+	if e.recorder != nil {
+		e.recorder.RecordExecution(snapshot)
+	}
+
+	e.broker.publish(execution.ID, Event{Type: EventStatus, Execution: snapshot})
+	if IsFinished(execution.Status) {
+		e.broker.closeAll(execution.ID)
+	}
+}
+
+// snapshotExecution 复制执行记录（不含日志），引擎后续修改不会影响快照
+func snapshotExecution(execution *Execution) *Execution {
+	snapshot := *execution
+	snapshot.Logs = nil
+	snapshot.TriggerInfo = copyMap(execution.TriggerInfo)
+	snapshot.PlatformData = copyMap(execution.PlatformData)
+	snapshot.Jobs = copyJobs(execution.Jobs)
+	snapshot.Transitions = append([]Transition(nil), execution.Transitions...)
+	if execution.Metrics.StageDurations != nil {
+		snapshot.Metrics.StageDurations = make(map[string]int64, len(execution.Metrics.StageDurations))
+		for stage, duration := range execution.Metrics.StageDurations {
+			snapshot.Metrics.StageDurations[stage] = duration
+		}
+	}
+	return &snapshot
+}
+
+// copyMap 浅拷贝 map
+func copyMap(source map[string]interface{}) map[string]interface{} {
+	if source == nil {
+		return nil
+	}
+	target := make(map[string]interface{}, len(source))
+	for key, value := range source {
+		target[key] = value
+	}
+	return target
+}
+
+// logsAfter 返回 lastLogID 之后的日志副本，lastLogID 为空或不存在时返回全部日志
+func logsAfter(logs []LogEntry, lastLogID string) []LogEntry {
+	start := 0
+	if lastLogID != "" {
+		for i, log := range logs {
+			if log.ID == lastLogID {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	backlog := make([]LogEntry, len(logs)-start)
+	copy(backlog, logs[start:])
+	return backlog
+}
@@ -0,0 +1,116 @@
+package execution
+
+import (
+	"sync"
+)
+
+// 执行事件类型
+const (
+	EventLog    = "log"
+	EventStatus = "status"
+)
+
+// subscriberBufferSize 订阅者事件缓冲大小，缓冲写满的订阅者会被断开
+const subscriberBufferSize = 256
+
+// Event 执行事件，日志追加或执行状态变化时推送给订阅者
+type Event struct {
+	Type      string     `json:"type"`
+	Log       *LogEntry  `json:"log,omitempty"`
+	Execution *Execution `json:"execution,omitempty"`
+}
+
+// Subscription 执行事件订阅
+type Subscription struct {
+	// Execution 订阅时的执行快照，不包含日志
+	Execution *Execution
+	// Backlog 订阅时已存在、且在 lastLogID 之后的日志
+	Backlog []LogEntry
+	// Events 后续事件，执行结束或订阅被断开时关闭
+	Events <-chan Event
+
+	once   sync.Once
+	cancel func()
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
+	})
+}
+
+// broker 按执行 ID 分发事件的发布/订阅中心
+type broker struct {
+	subscribers map[string]map[chan Event]struct{}
+	mutex       sync.Mutex
+}
+
+// newBroker 创建发布/订阅中心
+func newBroker() *broker {
+	return &broker{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// subscribe 订阅执行事件，返回事件通道和取消函数
+func (b *broker) subscribe(executionID string) (chan Event, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ch := make(chan Event, subscriberBufferSize)
+	if b.subscribers[executionID] == nil {
+		b.subscribers[executionID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[executionID][ch] = struct{}{}
+
+	return ch, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.remove(executionID, ch)
+	}
+}
+
+// publish 向执行的所有订阅者推送事件，不会阻塞发布方
+func (b *broker) publish(executionID string, event Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ch := range b.subscribers[executionID] {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者消费过慢，断开后由客户端通过 lastLogID 续传
+			b.remove(executionID, ch)
+		}
+	}
+}
+
+// closeAll 关闭执行的所有订阅，执行结束时调用
+func (b *broker) closeAll(executionID string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ch := range b.subscribers[executionID] {
+		b.remove(executionID, ch)
+	}
+}
+
+// remove 移除并关闭订阅通道，调用方需持有锁
+func (b *broker) remove(executionID string, ch chan Event) {
+	subscribers, exists := b.subscribers[executionID]
+	if !exists {
+		return
+	}
+	if _, exists := subscribers[ch]; !exists {
+		return
+	}
+
+	delete(subscribers, ch)
+	close(ch)
+	if len(subscribers) == 0 {
+		delete(b.subscribers, executionID)
+	}
+}
@@ -0,0 +1,302 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Concurrency workflow 或 job 的并发组配置，同一并发组中同时只有一个成员运行，最多一个成员等待。
+// 新成员加入时，等待中的旧成员被取消；cancel-in-progress 为真时运行中的成员也被取消，否则新成员等待其结束
+type Concurrency struct {
+	Group            string `yaml:"group"`
+	CancelInProgress Flag   `yaml:"cancel-in-progress,omitempty"`
+}
+
+// UnmarshalYAML 解析并发组配置，既可以写成组名字符串，也可以写成 group 和 cancel-in-progress
+func (c *Concurrency) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Group = value.Value
+		return nil
+	}
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: concurrency must be a string or a mapping", value.Line)
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		node := value.Content[i+1]
+		switch value.Content[i].Value {
+		case "group":
+			c.Group = node.Value
+		case "cancel-in-progress":
+			c.CancelInProgress = Flag(node.Value)
+		}
+	}
+	if strings.TrimSpace(c.Group) == "" {
+		return fmt.Errorf("line %d: concurrency group is required", value.Line)
+	}
+	return nil
+}
+
+// resolve 计算并发组名称和是否取消运行中的成员，没有配置或组名为空时返回空字符串
+func (c *Concurrency) resolve(context map[string]interface{}) (string, bool, error) {
+	if c == nil {
+		return "", false, nil
+	}
+
+	group, err := interpolate(c.Group, context)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid concurrency group %q: %w", c.Group, err)
+	}
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return "", false, nil
+	}
+
+	cancelInProgress, err := c.CancelInProgress.value(context)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid cancel-in-progress: %w", err)
+	}
+	return group, cancelInProgress, nil
+}
+
+// groupKey 并发组在项目内生效，不同项目的同名并发组互不影响
+func groupKey(projectID, group string) string {
+	return projectID + "/" + group
+}
+
+// expressionContext 构建计算并发组表达式的上下文。github 上下文与 GitHub Actions 的字段含义相同，
+// 例如 github.ref、github.head_ref 和 github.event.pull_request.number；trigger 上下文为执行的触发信息，
+// 另外提供 trigger.type、trigger.branch 和 trigger.pr_number
+func expressionContext(execution *Execution, options ExecutionOptions, workflow string, matrix map[string]interface{}) map[string]interface{} {
+	info := execution.TriggerInfo
+	text := func(key string) string {
+		if value, ok := info[key]; ok && value != nil {
+			return expressionString(value)
+		}
+		return ""
+	}
+
+	// 触发信息中没有 ref 时使用执行选项中的分支或标签
+	ref := text("ref")
+	if ref == "" {
+		ref = options.Ref
+	}
+	if ref != "" && !strings.HasPrefix(ref, "refs/") {
+		if execution.TriggerType == "tag" {
+			ref = "refs/tags/" + ref
+		} else {
+			ref = "refs/heads/" + ref
+		}
+	}
+	refName := strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/tags/")
+	branch := ""
+	if strings.HasPrefix(ref, "refs/heads/") {
+		branch = refName
+	}
+
+	repository := options.Repository
+	if repository == "" {
+		repository = text("repository")
+	}
+	if workflow == "" {
+		workflow = options.Workflow
+	}
+
+	// 重新运行的执行属于原执行的逻辑运行
+	runID, attempt := execution.RunID, execution.Attempt
+	if runID == "" {
+		runID = execution.ID
+	}
+	if attempt == 0 {
+		attempt = 1
+	}
+
+	eventName := execution.TriggerType
+	switch eventName {
+	case "tag":
+		eventName = "push"
+	case "manual":
+		eventName = "workflow_dispatch"
+	}
+
+	event := map[string]interface{}{
+		"ref":   ref,
+		"after": text("sha"),
+	}
+	github := map[string]interface{}{
+		"workflow":    workflow,
+		"event_name":  eventName,
+		"ref":         ref,
+		"ref_name":    refName,
+		"head_ref":    "",
+		"base_ref":    "",
+		"sha":         text("sha"),
+		"actor":       text("author"),
+		"repository":  repository,
+		"run_id":      runID,
+		"run_attempt": strconv.Itoa(attempt),
+		"event":       event,
+	}
+
+	trigger := copyMap(info)
+	if trigger == nil {
+		trigger = map[string]interface{}{}
+	}
+	trigger["type"] = execution.TriggerType
+	trigger["branch"] = branch
+
+	// pull request 的 ref 与 GitHub Actions 相同为合并 ref，源分支为 head_ref
+	if number := text("pr_number"); number != "" && eventName == "pull_request" {
+		github["ref"] = fmt.Sprintf("refs/pull/%s/merge", number)
+		github["ref_name"] = number + "/merge"
+		github["head_ref"] = branch
+		github["base_ref"] = text("base_ref")
+		prNumber := toNumber(number)
+		event["number"] = prNumber
+		event["action"] = text("action")
+		event["pull_request"] = map[string]interface{}{
+			"number": prNumber,
+			"head":   map[string]interface{}{"ref": branch, "sha": text("sha")},
+			"base":   map[string]interface{}{"ref": text("base_ref")},
+		}
+		trigger["pr_number"] = prNumber
+	}
+
+	inputs := make(map[string]interface{}, len(options.Inputs))
+	for key, value := range options.Inputs {
+		inputs[key] = value
+	}
+	if matrix == nil {
+		matrix = map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"github":  github,
+		"trigger": trigger,
+		"inputs":  inputs,
+		"matrix":  matrix,
+	}
+}
+
+// supersededError 并发组中的成员被更新的成员取代
+type supersededError struct {
+	reason string
+}
+
+func (e *supersededError) Error() string {
+	return e.reason
+}
+
+// supersededReason 返回 job 被并发组取消的原因，没有被取消时返回空字符串
+func supersededReason(err error) string {
+	var superseded *supersededError
+	if errors.As(err, &superseded) {
+		return superseded.reason
+	}
+	return ""
+}
+
+// jobGroups job 级并发组，由执行管理器在所有引擎之间共享
+type jobGroups struct {
+	holders map[string]*jobGroupMember // 并发组 -> 运行中的 job
+	pending map[string]*jobGroupMember // 并发组 -> 等待中的 job
+	mutex   sync.Mutex
+}
+
+// jobGroupMember 并发组中的 job
+type jobGroupMember struct {
+	executionID string
+	job         string
+	cancel      context.CancelCauseFunc
+	ready       chan error // 等待结束时收到 nil，被取代时收到 supersededError
+}
+
+// newJobGroups 创建 job 级并发组
+func newJobGroups() *jobGroups {
+	return &jobGroups{
+		holders: make(map[string]*jobGroupMember),
+		pending: make(map[string]*jobGroupMember),
+	}
+}
+
+// acquire 获取并发组，组中有运行中的 job 时等待其结束，cancelInProgress 为真时先取消它。
+// 等待中被更新的 job 取代时返回 supersededError。返回的上下文在 job 被取代时以 supersededError 取消，
+// job 结束后需调用 release
+func (g *jobGroups) acquire(ctx context.Context, group, label string, cancelInProgress bool, executionID, job string, waiting func(holder *jobGroupMember)) (context.Context, func(), error) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	member := &jobGroupMember{executionID: executionID, job: job, cancel: cancel, ready: make(chan error, 1)}
+	release := func() {
+		g.release(group, member)
+		cancel(nil)
+	}
+
+	g.mutex.Lock()
+	holder := g.holders[group]
+	if holder == nil {
+		g.holders[group] = member
+		g.mutex.Unlock()
+		return jobCtx, release, nil
+	}
+
+	if previous := g.pending[group]; previous != nil {
+		previous.ready <- &supersededError{reason: fmt.Sprintf("superseded by job %s of execution %s in concurrency group %s", job, executionID, label)}
+	}
+	g.pending[group] = member
+	if cancelInProgress {
+		holder.cancel(&supersededError{reason: fmt.Sprintf("cancelled by job %s of execution %s in concurrency group %s (cancel-in-progress)", job, executionID, label)})
+	}
+	g.mutex.Unlock()
+
+	if waiting != nil {
+		waiting(holder)
+	}
+
+	var err error
+	select {
+	case err = <-member.ready:
+	case <-ctx.Done():
+		g.mutex.Lock()
+		if g.pending[group] == member {
+			delete(g.pending, group)
+			g.mutex.Unlock()
+			cancel(nil)
+			return nil, nil, ctx.Err()
+		}
+		g.mutex.Unlock()
+
+		// 取消的同时已获取到并发组或被取代
+		if err = <-member.ready; err == nil {
+			release()
+			return nil, nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, nil, err
+	}
+	return jobCtx, release, nil
+}
+
+// release 释放并发组，由等待中的 job 获取
+func (g *jobGroups) release(group string, member *jobGroupMember) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.holders[group] != member {
+		return
+	}
+	next := g.pending[group]
+	if next == nil {
+		delete(g.holders, group)
+		return
+	}
+	delete(g.pending, group)
+	g.holders[group] = next
+	next.ready <- nil
+}
@@ -0,0 +1,221 @@
+package execution
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	context := map[string]interface{}{
+		"github": map[string]interface{}{
+			"ref":        "refs/heads/main",
+			"event_name": "push",
+			"event":      map[string]interface{}{},
+		},
+		"matrix": map[string]interface{}{"os": "linux", "versions": []interface{}{"1.24", "1.25"}},
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"github.ref", "refs/heads/main"},
+		{"github.event.pull_request.number || github.ref", "refs/heads/main"},
+		{"github.event_name == 'PUSH'", "true"},
+		{"github.event_name != 'push' && 'x'", "false"},
+		{"!(github.ref == 'refs/heads/main')", "false"},
+		{"startsWith(github.ref, 'refs/heads/')", "true"},
+		{"contains(matrix.versions, '1.25')", "true"},
+		{"format('{0}-{1}', github.event_name, matrix.OS)", "push-linux"},
+		{"matrix.versions[1]", "1.25"},
+		{"'it''s'", "it's"},
+		{"1 < 2", "true"},
+	}
+	for _, tt := range tests {
+		value, err := evaluate(tt.expr, context)
+		if err != nil {
+			t.Errorf("计算 %q 失败: %v", tt.expr, err)
+			continue
+		}
+		if got := expressionString(value); got != tt.want {
+			t.Errorf("%q = %q, 期望 %q", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"github.ref ==", "unknown(1)", "'unterminated"} {
+		if _, err := evaluate(expr, context); err == nil {
+			t.Errorf("无效表达式 %q 应返回错误", expr)
+		}
+	}
+}
+
+func TestConcurrencyResolve(t *testing.T) {
+	concurrency := &Concurrency{
+		Group:            "${{ github.workflow }}-${{ github.event.pull_request.number || github.ref }}",
+		CancelInProgress: "${{ github.event_name == 'pull_request' }}",
+	}
+
+	pullRequest := &Execution{
+		ID:          "e1",
+		TriggerType: "pull_request",
+		TriggerInfo: map[string]interface{}{"ref": "refs/heads/feature", "pr_number": 42, "base_ref": "main"},
+	}
+	group, cancelInProgress, err := concurrency.resolve(expressionContext(pullRequest, ExecutionOptions{}, "CI", nil))
+	if err != nil || group != "CI-42" || !cancelInProgress {
+		t.Errorf("pull request 的并发组不匹配: %q %v %v", group, cancelInProgress, err)
+	}
+
+	// 触发信息中没有 ref 时使用执行选项中的分支
+	manual := &Execution{ID: "e2", TriggerType: "manual", TriggerInfo: map[string]interface{}{}}
+	group, cancelInProgress, err = concurrency.resolve(expressionContext(manual, ExecutionOptions{Ref: "main"}, "CI", nil))
+	if err != nil || group != "CI-refs/heads/main" || cancelInProgress {
+		t.Errorf("手动执行的并发组不匹配: %q %v %v", group, cancelInProgress, err)
+	}
+
+	branch := &Concurrency{Group: "deploy-${{ trigger.branch }}-${{ matrix.env }}", CancelInProgress: "true"}
+	group, cancelInProgress, err = branch.resolve(expressionContext(pullRequest, ExecutionOptions{}, "", map[string]interface{}{"env": "prod"}))
+	if err != nil || group != "deploy-feature-prod" || !cancelInProgress {
+		t.Errorf("trigger 和 matrix 上下文的并发组不匹配: %q %v %v", group, cancelInProgress, err)
+	}
+
+	invalid := &Concurrency{Group: "ci", CancelInProgress: "sometimes"}
+	if _, _, err := invalid.resolve(expressionContext(manual, ExecutionOptions{}, "", nil)); err == nil {
+		t.Error("无效的 cancel-in-progress 应返回错误")
+	}
+}
+
+func TestManagerConcurrencyGroup(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("mock", NewMockEngine())
+
+	config := func(cancelInProgress bool) string {
+		return `name: Deploy
+concurrency:
+  group: ${{ github.workflow }}-${{ trigger.branch }}
+  cancel-in-progress: ` + map[bool]string{true: "true", false: "false"}[cancelInProgress] + `
+jobs:
+  deploy:
+    steps:
+    - run: make deploy
+`
+	}
+	start := func(branch string, cancelInProgress bool) string {
+		t.Helper()
+		executionID, err := manager.CreateExecution("1", "mock", "push", ExecutionOptions{
+			CIConfigContent: config(cancelInProgress),
+			StageDurations:  map[string]int{"deploy": 1},
+			TriggerInfo:     map[string]interface{}{"ref": "refs/heads/" + branch},
+		})
+		if err != nil {
+			t.Fatalf("创建执行失败: %v", err)
+		}
+		if err := manager.StartExecution(executionID); err != nil {
+			t.Fatalf("启动执行失败: %v", err)
+		}
+		return executionID
+	}
+
+	// 同一分支的执行等待运行中的执行结束，排队中的执行被更新的执行取代
+	first := start("main", false)
+	superseded := start("main", false)
+	other := start("release", false)
+	latest := start("main", false)
+
+	current, err := manager.GetExecution(latest)
+	if err != nil {
+		t.Fatalf("获取执行详情失败: %v", err)
+	}
+	if current.Status != StatusQueued || current.Queue == nil || current.Queue.WaitingFor != WaitingGroup || current.Queue.ConcurrencyGroup != "Deploy-main" {
+		t.Errorf("执行应等待并发组: %+v, %+v", current, current.Queue)
+	}
+
+	results := map[string]*Execution{}
+	for _, executionID := range []string{first, superseded, other, latest} {
+		results[executionID] = waitForStatus(t, manager, executionID)
+	}
+	if results[superseded].Status != StatusCancelled || !strings.Contains(results[superseded].Reason, latest) {
+		t.Errorf("被取代的执行应已取消并记录原因: %s %q", results[superseded].Status, results[superseded].Reason)
+	}
+	for _, executionID := range []string{first, other, latest} {
+		if results[executionID].Status != StatusSuccess {
+			t.Errorf("执行 %s 应成功: %s", executionID, results[executionID].Status)
+		}
+	}
+	if results[latest].StartTime.Before(results[first].EndTime) {
+		t.Error("同一并发组的执行不应同时运行")
+	}
+	if results[other].StartTime.After(results[first].EndTime) {
+		t.Error("不同并发组的执行应同时运行")
+	}
+
+	// cancel-in-progress 时取消运行中的执行
+	running := start("main", true)
+	time.Sleep(100 * time.Millisecond)
+	replacement := start("main", true)
+
+	cancelled := waitForStatus(t, manager, running)
+	if cancelled.Status != StatusCancelled || !strings.Contains(cancelled.Reason, replacement) {
+		t.Errorf("运行中的执行应被取消并记录原因: %s %q", cancelled.Status, cancelled.Reason)
+	}
+	logged := false
+	for _, entry := range cancelled.Logs {
+		logged = logged || (entry.Stage == "cancellation" && entry.Message == cancelled.Reason)
+	}
+	if !logged {
+		t.Error("取消原因应记录到执行日志")
+	}
+	if result := waitForStatus(t, manager, replacement); result.Status != StatusSuccess {
+		t.Errorf("新执行应成功: %s", result.Status)
+	}
+}
+
+func TestJobConcurrencyGroup(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("mock", NewMockEngine())
+
+	config := `jobs:
+  build:
+    steps:
+    - run: make
+  deploy:
+    needs: build
+    concurrency:
+      group: deploy-${{ trigger.branch }}
+      cancel-in-progress: true
+    steps:
+    - run: make deploy
+`
+	start := func() string {
+		t.Helper()
+		executionID, err := manager.CreateExecution("1", "mock", "push", ExecutionOptions{
+			CIConfigContent: config,
+			StageDurations:  map[string]int{"build": 0, "deploy": 2},
+			TriggerInfo:     map[string]interface{}{"ref": "refs/heads/main"},
+		})
+		if err != nil {
+			t.Fatalf("创建执行失败: %v", err)
+		}
+		if err := manager.StartExecution(executionID); err != nil {
+			t.Fatalf("启动执行失败: %v", err)
+		}
+		return executionID
+	}
+
+	first := start()
+	time.Sleep(500 * time.Millisecond)
+	second := start()
+
+	result := waitForStatus(t, manager, first)
+	if result.Status != StatusFailed {
+		t.Errorf("deploy 被取消的执行应失败: %s", result.Status)
+	}
+	for _, job := range result.Jobs {
+		if job.Name == "deploy" && (job.Status != StatusCancelled || !strings.Contains(job.Reason, second)) {
+			t.Errorf("deploy 应被新执行取消: %+v", job)
+		}
+	}
+	if result := waitForStatus(t, manager, second); result.Status != StatusSuccess {
+		t.Errorf("新执行应成功: %s", result.Status)
+	}
+}
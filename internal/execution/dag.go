@@ -0,0 +1,545 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StringList 既可以写成单个字符串也可以写成字符串列表的配置项，例如 needs: build 或 needs: [build, lint]
+type StringList []string
+
+// UnmarshalYAML 解析单个字符串或字符串列表
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// jobGraph 由 jobs.*.needs 构成的 job 依赖图，矩阵 job 已展开为具体实例
+type jobGraph struct {
+	jobs       map[string]Job
+	order      []string                // 拓扑顺序
+	dependents map[string][]string     // job 名称 -> 依赖它的 job
+	groups     map[string]*matrixGroup // 矩阵 job 名称 -> 展开信息
+	workflow   string                  // 配置中的 name，用于并发组表达式中的 github.workflow
+}
+
+// parseCIConfig 解析 CI 配置并构建 job 依赖图
+func parseCIConfig(content string) (CIConfig, *jobGraph, error) {
+	var config CIConfig
+	if err := yaml.Unmarshal([]byte(content), &config); err != nil {
+		return config, nil, fmt.Errorf("failed to parse CI config: %w", err)
+	}
+
+	// 先按配置中的 job 校验依赖关系，错误信息中使用用户编写的 job 名称
+	if _, err := buildJobGraph(config.Jobs); err != nil {
+		return config, nil, err
+	}
+
+	jobs, groups, err := expandMatrixJobs(config.Jobs)
+	if err != nil {
+		return config, nil, err
+	}
+
+	graph, err := buildJobGraph(jobs)
+	if err != nil {
+		return config, nil, err
+	}
+	graph.groups = groups
+	graph.workflow = config.Name
+
+	return config, graph, nil
+}
+
+// buildJobGraph 构建 job 依赖图，依赖不存在的 job 或存在循环依赖时返回错误
+func buildJobGraph(jobs map[string]Job) (*jobGraph, error) {
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	graph := &jobGraph{
+		jobs:       jobs,
+		dependents: make(map[string][]string),
+	}
+
+	for _, name := range names {
+		for _, need := range jobs[name].Needs {
+			if _, exists := jobs[need]; !exists {
+				return nil, fmt.Errorf("job %s needs unknown job %s", name, need)
+			}
+			graph.dependents[need] = append(graph.dependents[need], name)
+		}
+	}
+
+	if cycle := findCycle(jobs, names); cycle != nil {
+		return nil, fmt.Errorf("job dependency cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	// Kahn 算法计算拓扑顺序，同一层按名称排序保证顺序稳定
+	inDegree := make(map[string]int, len(jobs))
+	var ready []string
+	for _, name := range names {
+		inDegree[name] = len(jobs[name].Needs)
+		if inDegree[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		graph.order = append(graph.order, name)
+
+		var next []string
+		for _, dependent := range graph.dependents[name] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				next = append(next, dependent)
+			}
+		}
+		sort.Strings(next)
+		ready = append(ready, next...)
+	}
+
+	return graph, nil
+}
+
+// findCycle 深度优先查找循环依赖，返回构成循环的 job 路径
+func findCycle(jobs map[string]Job, names []string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(jobs))
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, need := range jobs[name].Needs {
+			switch state[need] {
+			case visiting:
+				// 从路径中截取循环部分
+				for i, n := range path {
+					if n == need {
+						cycle := append([]string{}, path[i:]...)
+						return append(cycle, need)
+					}
+				}
+			case unvisited:
+				if cycle := visit(need); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// jobRunner 运行单个 job，返回 nil 表示成功
+type jobRunner func(ctx context.Context, name string, job Job) error
+
+// jobResult job 运行结果
+type jobResult struct {
+	name       string
+	err        error
+	superseded string // job 被并发组中更新的 job 取消或取代的原因
+}
+
+// initJobs 按拓扑顺序初始化执行记录中的 job 状态，调用方需持有写锁
+func (e *baseEngine) initJobs(execution *Execution, graph *jobGraph) {
+	execution.Jobs = make([]JobExecution, 0, len(graph.order))
+	for _, name := range graph.order {
+		job := graph.jobs[name]
+		execution.Jobs = append(execution.Jobs, JobExecution{
+			Name:   name,
+			Needs:  job.Needs,
+			Status: StatusPending,
+			Matrix: job.MatrixValues,
+			Group:  job.MatrixJob,
+			Steps:  initSteps(job),
+		})
+	}
+}
+
+// runJobGraph 按依赖关系运行 job，互不依赖的 job 并发运行，options.MaxParallel 限制同时运行的 job 数（0 表示不限制）。
+// 上游 job 未成功时下游 job 被跳过；矩阵实例受 max-parallel 限制，开启 fail-fast 时一个实例失败会取消同组其余实例；
+// 配置了 concurrency 的 job 等待同一并发组中的其他 job 结束，被取消或取代时视为失败；超过 timeout-minutes 的 job 被取消，状态为 timed_out。
+// options.ReuseJobs 中的 job 不再运行，直接使用上一次尝试的结果。返回失败的 job 名称
+func (e *baseEngine) runJobGraph(ctx context.Context, executionID string, graph *jobGraph, options ExecutionOptions, runJob jobRunner) []string {
+	parallelism := options.MaxParallel
+	remaining := make(map[string]int, len(graph.order))
+	blockedBy := make(map[string]string)
+	var queue []string
+	for _, name := range graph.order {
+		remaining[name] = len(graph.jobs[name].Needs)
+		if remaining[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	// 每个矩阵 job 使用独立的上下文，fail-fast 时取消
+	groupContexts := make(map[string]context.Context, len(graph.groups))
+	groupCancels := make(map[string]context.CancelFunc, len(graph.groups))
+	groupRunning := make(map[string]int, len(graph.groups))
+	groupFailed := make(map[string]string, len(graph.groups))
+	for name := range graph.groups {
+		groupContexts[name], groupCancels[name] = context.WithCancel(ctx)
+	}
+	defer func() {
+		for _, cancel := range groupCancels {
+			cancel()
+		}
+	}()
+
+	results := make(chan jobResult)
+	running := 0
+	settled := 0
+	var failed []string
+
+	// release 在 job 结束后更新下游 job，所有上游都结束时决定运行还是跳过
+	var release func(name string, succeeded bool)
+	release = func(name string, succeeded bool) {
+		for _, dependent := range graph.dependents[name] {
+			if !succeeded && blockedBy[dependent] == "" {
+				blockedBy[dependent] = name
+			}
+			remaining[dependent]--
+			if remaining[dependent] > 0 {
+				continue
+			}
+
+			if upstream := blockedBy[dependent]; upstream != "" {
+				e.updateJob(executionID, dependent, StatusSkipped, fmt.Sprintf("upstream job %s did not succeed", upstream))
+				e.addLog(executionID, "warn", dependent, fmt.Sprintf("Skipping job %s: upstream job %s did not succeed", dependent, upstream))
+				settled++
+				release(dependent, false)
+				continue
+			}
+			queue = append(queue, dependent)
+		}
+	}
+
+	// next 从队列中取出下一个可以启动的 job，矩阵 job 达到 max-parallel 时跳过其实例
+	next := func() (string, bool) {
+		for i, name := range queue {
+			group := graph.jobs[name].MatrixJob
+			if group != "" && graph.groups[group].maxParallel > 0 && groupRunning[group] >= graph.groups[group].maxParallel {
+				continue
+			}
+			queue = append(queue[:i:i], queue[i+1:]...)
+			return name, true
+		}
+		return "", false
+	}
+
+	for settled < len(graph.order) {
+		for ctx.Err() == nil && (parallelism <= 0 || running < parallelism) {
+			name, ok := next()
+			if !ok {
+				break
+			}
+
+			if previous, reused := options.ReuseJobs[name]; reused {
+				succeeded := e.reuseJob(executionID, name, previous)
+				if !succeeded {
+					failed = append(failed, name)
+				}
+				settled++
+				release(name, succeeded)
+				continue
+			}
+
+			group := graph.jobs[name].MatrixJob
+			jobCtx := ctx
+			if group != "" {
+				// fail-fast 已触发的矩阵实例不再启动
+				if leg := groupFailed[group]; leg != "" {
+					e.updateJob(executionID, name, StatusCancelled, fmt.Sprintf("fail-fast: matrix job %s failed", leg))
+					settled++
+					release(name, false)
+					continue
+				}
+				jobCtx = groupContexts[group]
+				groupRunning[group]++
+			}
+
+			running++
+			go func(name string) {
+				results <- e.runGraphJob(jobCtx, executionID, graph, options, name, runJob)
+			}(name)
+		}
+
+		// 没有运行中的 job 时说明执行已被取消
+		if running == 0 {
+			if len(queue) > 0 && ctx.Err() == nil {
+				continue
+			}
+			break
+		}
+
+		result := <-results
+		running--
+		settled++
+
+		group := graph.jobs[result.name].MatrixJob
+		if group != "" {
+			groupRunning[group]--
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			e.updateJob(executionID, result.name, StatusCancelled, cancelReason(ctx))
+		case group != "" && groupFailed[group] != "" && groupContexts[group].Err() != nil:
+			e.updateJob(executionID, result.name, StatusCancelled, fmt.Sprintf("fail-fast: matrix job %s failed", groupFailed[group]))
+			release(result.name, false)
+		case result.superseded != "":
+			e.updateJob(executionID, result.name, StatusCancelled, result.superseded)
+			e.addLog(executionID, "warn", result.name, fmt.Sprintf("Job %s cancelled: %s", result.name, result.superseded))
+			failed = append(failed, result.name)
+			release(result.name, false)
+		case result.err != nil:
+			status := StatusFailed
+			var timeout *timeoutError
+			if errors.As(result.err, &timeout) && timeout.kind == "job" {
+				status = StatusTimedOut
+			}
+			e.updateJob(executionID, result.name, status, result.err.Error())
+			failed = append(failed, result.name)
+			if group != "" && graph.groups[group].failFast && groupFailed[group] == "" {
+				groupFailed[group] = result.name
+				groupCancels[group]()
+				e.addLog(executionID, "warn", result.name, fmt.Sprintf("Cancelling remaining %s matrix jobs: fail-fast is enabled", group))
+			}
+			release(result.name, false)
+		default:
+			e.updateJob(executionID, result.name, StatusSuccess, "")
+			release(result.name, true)
+		}
+	}
+
+	// 取消后未开始的 job 标记为已取消
+	if ctx.Err() != nil {
+		for _, name := range graph.order {
+			e.updateJob(executionID, name, StatusCancelled, cancelReason(ctx))
+		}
+	}
+
+	return failed
+}
+
+// runGraphJob 运行单个 job，job 配置了 concurrency 时先获取并发组，组中有其他 job 运行时等待
+func (e *baseEngine) runGraphJob(ctx context.Context, executionID string, graph *jobGraph, options ExecutionOptions, name string, runJob jobRunner) jobResult {
+	job := graph.jobs[name]
+	if job.Concurrency == nil {
+		return jobResult{name: name, err: e.runTimedJob(ctx, executionID, name, job, runJob)}
+	}
+
+	e.mutex.RLock()
+	execution, exists := e.executions[executionID]
+	var snapshot *Execution
+	if exists {
+		snapshot = snapshotExecution(execution)
+	}
+	groups := e.jobGroups
+	e.mutex.RUnlock()
+	if snapshot == nil {
+		return jobResult{name: name, err: fmt.Errorf("execution not found: %s", executionID)}
+	}
+
+	group, cancelInProgress, err := job.Concurrency.resolve(expressionContext(snapshot, options, graph.workflow, job.MatrixValues))
+	if err != nil || group == "" {
+		if err == nil {
+			err = e.runTimedJob(ctx, executionID, name, job, runJob)
+		}
+		return jobResult{name: name, err: err}
+	}
+
+	jobCtx, release, err := groups.acquire(ctx, groupKey(snapshot.ProjectID, group), group, cancelInProgress, executionID, name, func(holder *jobGroupMember) {
+		e.addLog(executionID, "info", name, fmt.Sprintf("Job %s is waiting for job %s of execution %s in concurrency group %s", name, holder.job, holder.executionID, group))
+	})
+	if err != nil {
+		return jobResult{name: name, err: err, superseded: supersededReason(err)}
+	}
+	defer release()
+
+	result := jobResult{name: name, err: e.runTimedJob(jobCtx, executionID, name, job, runJob)}
+	if result.err != nil {
+		result.superseded = supersededReason(context.Cause(jobCtx))
+	}
+	return result
+}
+
+// runTimedJob 将 job 标记为运行中并运行，超过 timeout-minutes 时取消 job 并返回 timeoutError。
+// 没有配置 timeout-minutes、配置为 0 或表达式无效时使用 DefaultJobTimeout
+func (e *baseEngine) runTimedJob(ctx context.Context, executionID, name string, job Job, runJob jobRunner) error {
+	e.updateJob(executionID, name, StatusRunning, "")
+
+	timeout, err := job.Timeout.duration(jobContext(job))
+	if err != nil {
+		e.addLog(executionID, "warn", name, fmt.Sprintf("Invalid timeout-minutes for job %s, using the default %s: %v", name, DefaultJobTimeout, err))
+	}
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	e.addDebugLog(executionID, name, "", fmt.Sprintf("Running job %s", name), map[string]interface{}{
+		"needs":   job.Needs,
+		"matrix":  job.MatrixValues,
+		"timeout": timeout.String(),
+		"steps":   len(job.Steps),
+	})
+
+	jobCtx, cancel := withTimeout(ctx, "job", name, timeout)
+	defer cancel()
+
+	err = runJob(jobCtx, name, job)
+	if timeout := timedOut(jobCtx); err != nil && timeout != nil {
+		e.addLog(executionID, "error", name, fmt.Sprintf("Job %s cancelled: timed out after %s", name, timeout.timeout))
+		return timeout
+	}
+	return err
+}
+
+// reuseJob 将上一次尝试中 job 的结果复制到尚未运行的 job，返回复用的结果是否成功
+func (e *baseEngine) reuseJob(executionID, name string, previous JobExecution) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return false
+	}
+
+	for i := range execution.Jobs {
+		job := &execution.Jobs[i]
+		if job.Name != name || job.Status != StatusPending {
+			continue
+		}
+
+		reused := copyJobs([]JobExecution{previous})[0]
+		job.Status = reused.Status
+		job.Reason = reused.Reason
+		job.StartTime = reused.StartTime
+		job.EndTime = reused.EndTime
+		job.Duration = reused.Duration
+		job.Steps = reused.Steps
+		job.Outputs = reused.Outputs
+		job.ReusedFrom = reused.ReusedFrom
+
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   name,
+			Message: fmt.Sprintf("Reusing %s result of job %s from execution %s", reused.Status, name, reused.ReusedFrom),
+		})
+		e.notify(execution)
+		return reused.Status == StatusSuccess
+	}
+	return false
+}
+
+// updateJob 更新 job 状态和耗时并通知订阅者，已结束的 job 不再更新
+func (e *baseEngine) updateJob(executionID, name, status, reason string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return
+	}
+
+	for i := range execution.Jobs {
+		job := &execution.Jobs[i]
+		if job.Name != name {
+			continue
+		}
+		if job.Status != StatusPending && job.Status != StatusRunning {
+			return
+		}
+
+		if status == StatusRunning {
+			job.Status = status
+			job.Reason = reason
+			job.StartTime = time.Now()
+		} else {
+			finishJob(job, status, reason, time.Now())
+		}
+
+		e.notify(execution)
+		return
+	}
+}
+
+// finishJob 结束 job，未运行的 step 被跳过，仍在运行的 step 随 job 结束
+func finishJob(job *JobExecution, status, reason string, now time.Time) {
+	job.Status = status
+	job.Reason = reason
+	job.EndTime = now
+	if !job.StartTime.IsZero() {
+		job.Duration = int64(now.Sub(job.StartTime).Seconds())
+	}
+	for j := range job.Steps {
+		switch job.Steps[j].Status {
+		case StatusPending:
+			job.Steps[j].Status = StatusSkipped
+		case StatusRunning:
+			job.Steps[j].Status = status
+		}
+	}
+}
+
+// jobDurations 获取本次运行的 job 的耗时（秒），用作阶段耗时指标，调用方需持有锁
+func jobDurations(execution *Execution) map[string]int64 {
+	durations := make(map[string]int64)
+	for _, job := range execution.Jobs {
+		if job.StartTime.IsZero() || job.ReusedFrom != "" {
+			continue
+		}
+		durations[job.Name] = job.Duration
+	}
+	return durations
+}
+
+// sleepContext 等待指定时长，期间被取消时返回 false
+func sleepContext(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -1,32 +1,85 @@
 package execution
 
 import (
+	"context"
 	"time"
 )
 
 // Status 执行状态
 const (
 	StatusPending   = "pending"
+	StatusQueued    = "queued"
 	StatusRunning   = "running"
 	StatusSuccess   = "success"
 	StatusFailed    = "failed"
 	StatusCancelled = "cancelled"
+	StatusTimedOut  = "timed_out"
+	StatusSkipped   = "skipped"
 )
 
 // Execution 执行记录
 type Execution struct {
-	ID           string                 `json:"id"`
-	ProjectID    string                 `json:"project_id"`
-	Platform     string                 `json:"platform"`
-	Status       string                 `json:"status"`
-	StartTime    time.Time              `json:"start_time"`
-	EndTime      time.Time              `json:"end_time"`
-	Duration     int64                  `json:"duration"`
-	TriggerType  string                 `json:"trigger_type"`
-	TriggerInfo  map[string]interface{} `json:"trigger_info"`
-	PlatformData map[string]interface{} `json:"platform_data"`
-	Metrics      Metrics                `json:"metrics"`
-	Logs         []LogEntry             `json:"logs,omitempty"`
+	ID               string                 `json:"id"`
+	ProjectID        string                 `json:"project_id"`
+	PipelineID       int                    `json:"pipeline_id,omitempty"`
+	PipelineRevision int                    `json:"pipeline_revision,omitempty"` // 配置不是来自已保存的管道配置时为 0
+	Platform         string                 `json:"platform"`
+	Status           string                 `json:"status"`
+	Priority         string                 `json:"priority,omitempty"`
+	Reason           string                 `json:"reason,omitempty"`   // 执行结束的原因，例如被并发组中更新的执行取代
+	RunID            string                 `json:"run_id"`             // 逻辑运行 ID，即第一次尝试的执行 ID，重新运行的执行与原执行相同
+	Attempt          int                    `json:"attempt"`            // 在逻辑运行中的尝试序号，从 1 开始
+	RerunOf          string                 `json:"rerun_of,omitempty"` // 重新运行的执行 ID
+	Debug            bool                   `json:"debug,omitempty"`    // 输出 debug 级别的日志
+	StartTime        time.Time              `json:"start_time"`
+	EndTime          time.Time              `json:"end_time"`
+	Duration         int64                  `json:"duration"`
+	TriggerType      string                 `json:"trigger_type"`
+	TriggerInfo      map[string]interface{} `json:"trigger_info"`
+	PlatformData     map[string]interface{} `json:"platform_data"`
+	Metrics          Metrics                `json:"metrics"`
+	Jobs             []JobExecution         `json:"jobs,omitempty"`
+	Logs             []LogEntry             `json:"logs,omitempty"`
+	Transitions      []Transition           `json:"transitions,omitempty"` // 状态变化记录，按时间顺序
+	Queue            *QueueStatus           `json:"queue,omitempty"`       // 排队中的执行在队列中的位置和预计启动时间
+}
+
+// JobExecution job 执行状态，按依赖关系的拓扑顺序排列
+type JobExecution struct {
+	Name      string    `json:"name"`
+	Needs     []string  `json:"needs,omitempty"`
+	Status    string    `json:"status"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Duration  int64     `json:"duration"` // 秒
+	Reason    string    `json:"reason,omitempty"`
+	// 矩阵实例所属的矩阵 job 名称和组合取值
+	Group  string                 `json:"group,omitempty"`
+	Matrix map[string]interface{} `json:"matrix,omitempty"`
+	Steps  []StepExecution        `json:"steps,omitempty"`
+	// job 结束时按配置的 outputs 计算的输出，下游 job 通过 needs.<job>.outputs 引用
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// 重新运行时复用了结果的 job 记录结果所属的执行 ID，此时 job 没有再次运行，输出沿用上一次尝试的输出
+	ReusedFrom string `json:"reused_from,omitempty"`
+}
+
+// StepExecution step 执行状态，重试的 step 记录每一次尝试，用于找出不稳定的 step
+type StepExecution struct {
+	Name            string        `json:"name"`
+	Status          string        `json:"status"`
+	ContinueOnError bool          `json:"continue_on_error,omitempty"` // 失败后 job 继续运行
+	Attempts        []StepAttempt `json:"attempts,omitempty"`
+}
+
+// StepAttempt step 的一次尝试
+type StepAttempt struct {
+	Attempt   int       `json:"attempt"` // 从 1 开始
+	Status    string    `json:"status"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Duration  int64     `json:"duration_ms"` // 毫秒
+	TimedOut  bool      `json:"timed_out,omitempty"`
+	Error     string    `json:"error,omitempty"`
 }
 
 // Metrics 执行指标
@@ -55,15 +108,28 @@ type LogEntry struct {
 
 // ExecutionOptions 执行选项
 type ExecutionOptions struct {
-	TotalDuration   int            `json:"total_duration"`
-	StageDurations  map[string]int `json:"stage_durations"`
-	Result          string         `json:"result"`
-	FailureStage    string         `json:"failure_stage"`
-	FailureReason   string         `json:"failure_reason"`
-	GenerateMetrics bool           `json:"generate_metrics"`
-	GenerateLogs    bool           `json:"generate_logs"`
-	ResourceUsage   ResourceUsage  `json:"resource_usage"`
-	CIConfigContent string         `json:"ci_config_content"` // CI 配置文件内容
+	TotalDuration    int                     `json:"total_duration"`
+	StageDurations   map[string]int          `json:"stage_durations"`
+	Result           string                  `json:"result"`
+	FailureStage     string                  `json:"failure_stage"`
+	FailureReason    string                  `json:"failure_reason"`
+	GenerateMetrics  bool                    `json:"generate_metrics"`
+	GenerateLogs     bool                    `json:"generate_logs"`
+	ResourceUsage    ResourceUsage           `json:"resource_usage"`
+	CIConfigContent  string                  `json:"ci_config_content"`      // CI 配置文件内容
+	WorkDir          string                  `json:"work_dir"`               // 本地执行时的工作目录（项目路径）
+	MaxParallel      int                     `json:"max_parallel"`           // 同时运行的最大 job 数，0 表示不限制
+	Repository       string                  `json:"repository"`             // 远程平台上的仓库路径，例如 owner/repo
+	Workflow         string                  `json:"workflow"`               // GitHub Actions workflow 文件名或 ID
+	Ref              string                  `json:"ref"`                    // 触发的分支或标签
+	Inputs           map[string]string       `json:"inputs,omitempty"`       // workflow_dispatch 输入参数
+	PipelineID       int                     `json:"pipeline_id"`            // 执行使用的已保存管道配置
+	PipelineRevision int                     `json:"pipeline_revision"`      // 执行使用的管道配置修订
+	TriggerInfo      map[string]interface{}  `json:"trigger_info,omitempty"` // 触发信息，例如 webhook 事件的 ref 和提交
+	Priority         string                  `json:"priority,omitempty"`     // 排队的优先级类别，为空时按触发类型确定
+	Debug            bool                    `json:"debug,omitempty"`        // 输出 debug 级别的日志
+	ReuseJobs        map[string]JobExecution `json:"reuse_jobs,omitempty"`   // 重新运行时复用结果、不再运行的 job，按 job 名称索引
+	RemoteRerun      *RemoteRerun            `json:"remote_rerun,omitempty"` // 远程平台部分重新运行时重试的 run 或 pipeline
 }
 
 // ResourceUsage 资源使用情况
@@ -72,13 +138,21 @@ type ResourceUsage struct {
 	MemoryUsage float64 `json:"memory_usage"`
 }
 
-// Engine 执行引擎接口
+// Engine 执行引擎接口。ctx 携带状态变化的操作者（见 WithActor）；
+// Execute 的 ctx 是执行的上级上下文，被取消时执行以其 Cause 为原因取消
 type Engine interface {
-	Execute(executionID string, options ExecutionOptions) error
-	Stop(executionID string) error
+	Execute(ctx context.Context, executionID string, options ExecutionOptions) error
+	Stop(ctx context.Context, executionID string) error
 	GetStatus(executionID string) (*Execution, error)
 }
 
+// Recorder 执行记录器，引擎在追加日志、执行状态变化和执行记录更新时持有锁回调，实现不应等待数据库写入
+type Recorder interface {
+	RecordLog(entry LogEntry)
+	RecordTransition(executionID string, transition Transition)
+	RecordExecution(execution *Execution)
+}
+
 // Manager 执行管理器接口
 type Manager interface {
 	CreateExecution(projectID, platform, triggerType string, options ExecutionOptions) (string, error)
@@ -86,5 +160,9 @@ type Manager interface {
 	StopExecution(executionID string) error
 	GetExecution(executionID string) (*Execution, error)
 	ListExecutions(projectID string, limit, offset int) ([]*Execution, error)
+	SubscribeExecution(executionID, lastLogID string) (*Subscription, error)
 	RegisterEngine(platform string, engine Engine)
+	SetQueueLimits(limits QueueLimits)
+	GetQueue() *QueueSnapshot
+	RerunExecution(executionID string, options RerunOptions) (string, error)
 }
@@ -0,0 +1,533 @@
+package execution
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateExpression 匹配字符串中的 ${{ <表达式> }}
+var templateExpression = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+// interpolate 计算字符串中的 ${{ }} 表达式并替换为结果，表达式无效时返回错误
+func interpolate(template string, context map[string]interface{}) (string, error) {
+	var evalErr error
+	result := templateExpression.ReplaceAllStringFunc(template, func(match string) string {
+		value, err := evaluate(templateExpression.FindStringSubmatch(match)[1], context)
+		if err != nil && evalErr == nil {
+			evalErr = err
+		}
+		return expressionString(value)
+	})
+	if evalErr != nil {
+		return "", evalErr
+	}
+	return result, nil
+}
+
+// isExpression 判断整个字符串是否为一个 ${{ }} 表达式
+func isExpression(value string) bool {
+	value = strings.TrimSpace(value)
+	match := templateExpression.FindStringIndex(value)
+	return match != nil && match[0] == 0 && match[1] == len(value)
+}
+
+// evaluateValue 计算配置项的值，整个值为一个 ${{ }} 表达式时返回表达式的结果，保留结果的类型，
+// 否则替换其中的表达式后返回字符串
+func evaluateValue(value string, context map[string]interface{}) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	if isExpression(value) {
+		return evaluate(templateExpression.FindStringSubmatch(value)[1], context)
+	}
+	return interpolate(value, context)
+}
+
+// evaluate 计算 GitHub Actions 语法的表达式，支持属性访问、字面量、比较、逻辑运算和
+// format、contains、startsWith、endsWith、join、toJSON 函数。与 GitHub Actions 相同，
+// 字符串比较和属性名不区分大小写，不存在的属性为 null
+func evaluate(expr string, context map[string]interface{}) (interface{}, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	parser := &expressionParser{tokens: tokens, context: context}
+	value, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q in expression %q", parser.tokens[parser.pos].text, strings.TrimSpace(expr))
+	}
+	return value, nil
+}
+
+// 表达式词法单元类型
+const (
+	tokenIdent = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+// token 表达式词法单元
+type token struct {
+	kind int
+	text string
+}
+
+// tokenize 将表达式拆分为词法单元
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			// 字符串字面量中的 '' 表示单引号
+			var builder strings.Builder
+			j := i + 1
+			for {
+				if j >= len(expr) {
+					return nil, fmt.Errorf("unterminated string in expression %q", strings.TrimSpace(expr))
+				}
+				if expr[j] == '\'' {
+					if j+1 < len(expr) && expr[j+1] == '\'' {
+						builder.WriteByte('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				builder.WriteByte(expr[j])
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: builder.String()})
+			i = j + 1
+		case c >= '0' && c <= '9' || (c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9'):
+			j := i + 1
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[i:j]})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '-' || expr[j] >= 'a' && expr[j] <= 'z' || expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[i:j]})
+			i = j
+		default:
+			operator := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ".", ","} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q in expression %q", c, strings.TrimSpace(expr))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator})
+			i += len(operator)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	return tokens, nil
+}
+
+// expressionParser 递归下降计算表达式
+type expressionParser struct {
+	tokens  []token
+	pos     int
+	context map[string]interface{}
+}
+
+// peek 判断下一个词法单元是否为指定的运算符
+func (p *expressionParser) peek(operator string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == operator
+}
+
+// expect 读取指定的运算符
+func (p *expressionParser) expect(operator string) error {
+	if !p.peek(operator) {
+		return fmt.Errorf("expected %q in expression", operator)
+	}
+	p.pos++
+	return nil
+}
+
+// parseOr 计算 ||，返回第一个为真的操作数或最后一个操作数
+func (p *expressionParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if !truthy(left) {
+			left = right
+		}
+	}
+	return left, nil
+}
+
+// parseAnd 计算 &&，返回第一个为假的操作数或最后一个操作数
+func (p *expressionParser) parseAnd() (interface{}, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if truthy(left) {
+			left = right
+		}
+	}
+	return left, nil
+}
+
+// parseComparison 计算比较运算
+func (p *expressionParser) parseComparison() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.peek(operator) {
+			continue
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch operator {
+		case "==":
+			return looseEqual(left, right), nil
+		case "!=":
+			return !looseEqual(left, right), nil
+		}
+		a, b := toNumber(left), toNumber(right)
+		switch operator {
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		default:
+			return a >= b, nil
+		}
+	}
+	return left, nil
+}
+
+// parseUnary 计算 ! 运算
+func (p *expressionParser) parseUnary() (interface{}, error) {
+	if p.peek("!") {
+		p.pos++
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(value), nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary 计算字面量、括号、函数调用和属性访问
+func (p *expressionParser) parsePrimary() (interface{}, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	current := p.tokens[p.pos]
+	p.pos++
+
+	switch current.kind {
+	case tokenString:
+		return current.text, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(current.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in expression", current.text)
+		}
+		return number, nil
+	case tokenOperator:
+		if current.text != "(" {
+			return nil, fmt.Errorf("unexpected %q in expression", current.text)
+		}
+		value, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return value, p.expect(")")
+	}
+
+	switch current.text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if p.peek("(") {
+		p.pos++
+		var args []interface{}
+		for !p.peek(")") {
+			if len(args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		p.pos++
+		return callFunction(current.text, args)
+	}
+
+	value := property(p.context, current.text)
+	for {
+		switch {
+		case p.peek("."):
+			p.pos++
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenIdent {
+				return nil, fmt.Errorf("expected property name after %q", ".")
+			}
+			value = property(value, p.tokens[p.pos].text)
+			p.pos++
+		case p.peek("["):
+			p.pos++
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if list, ok := value.([]interface{}); ok {
+				i := int(toNumber(index))
+				value = nil
+				if i >= 0 && i < len(list) {
+					value = list[i]
+				}
+			} else {
+				value = property(value, expressionString(index))
+			}
+		default:
+			return value, nil
+		}
+	}
+}
+
+// property 不区分大小写地读取对象属性，不存在时返回 nil
+func property(object interface{}, name string) interface{} {
+	var values map[string]interface{}
+	switch typed := object.(type) {
+	case map[string]interface{}:
+		values = typed
+	case map[string]string:
+		values = make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			values[k] = v
+		}
+	default:
+		return nil
+	}
+	if value, ok := values[name]; ok {
+		return value
+	}
+	for key, value := range values {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// callFunction 调用表达式函数
+func callFunction(name string, args []interface{}) (interface{}, error) {
+	arity := func(min, max int) error {
+		if len(args) < min || (max >= 0 && len(args) > max) {
+			return fmt.Errorf("wrong number of arguments to %s", name)
+		}
+		return nil
+	}
+
+	switch strings.ToLower(name) {
+	case "contains":
+		if err := arity(2, 2); err != nil {
+			return nil, err
+		}
+		if list, ok := args[0].([]interface{}); ok {
+			for _, item := range list {
+				if looseEqual(item, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(strings.ToLower(expressionString(args[0])), strings.ToLower(expressionString(args[1]))), nil
+	case "startswith":
+		if err := arity(2, 2); err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(strings.ToLower(expressionString(args[0])), strings.ToLower(expressionString(args[1]))), nil
+	case "endswith":
+		if err := arity(2, 2); err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(strings.ToLower(expressionString(args[0])), strings.ToLower(expressionString(args[1]))), nil
+	case "format":
+		if err := arity(1, -1); err != nil {
+			return nil, err
+		}
+		result := expressionString(args[0])
+		for i, arg := range args[1:] {
+			result = strings.ReplaceAll(result, "{"+strconv.Itoa(i)+"}", expressionString(arg))
+		}
+		return result, nil
+	case "join":
+		if err := arity(1, 2); err != nil {
+			return nil, err
+		}
+		separator := ","
+		if len(args) == 2 {
+			separator = expressionString(args[1])
+		}
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return expressionString(args[0]), nil
+		}
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = expressionString(item)
+		}
+		return strings.Join(items, separator), nil
+	case "tojson":
+		if err := arity(1, 1); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	default:
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+}
+
+// truthy 判断表达式的值是否为真，false、0、空字符串和 null 为假
+func truthy(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case float64:
+		return typed != 0 && !math.IsNaN(typed)
+	case int:
+		return typed != 0
+	case string:
+		return typed != ""
+	default:
+		return true
+	}
+}
+
+// looseEqual 按 GitHub Actions 的规则比较两个值：字符串不区分大小写，类型不同时转换为数字比较
+func looseEqual(a, b interface{}) bool {
+	switch left := a.(type) {
+	case string:
+		if right, ok := b.(string); ok {
+			return strings.EqualFold(left, right)
+		}
+	case bool:
+		if right, ok := b.(bool); ok {
+			return left == right
+		}
+	case nil:
+		if b == nil {
+			return true
+		}
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return toNumber(a) == toNumber(b)
+}
+
+// toNumber 将值转换为数字，无法转换的字符串为 NaN
+func toNumber(value interface{}) float64 {
+	switch typed := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if typed {
+			return 1
+		}
+		return 0
+	case float64:
+		return typed
+	case int:
+		return float64(typed)
+	case string:
+		trimmed := strings.TrimSpace(typed)
+		if trimmed == "" {
+			return 0
+		}
+		number, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return number
+	default:
+		return math.NaN()
+	}
+}
+
+// expressionString 将表达式的值转换为字符串，对象和数组转换为 JSON
+func expressionString(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case bool:
+		return strconv.FormatBool(typed)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(typed)
+	default:
+		data, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(data)
+	}
+}
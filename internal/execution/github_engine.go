@@ -1,36 +1,731 @@
 package execution
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 )
 
-// GitHubActionsEngine GitHub Actions 执行引擎
+// GitHub API 默认配置
+const (
+	defaultGitHubAPIURL        = "https://api.github.com"
+	defaultGitHubWorkflow      = "ci.yml"
+	defaultGitHubRef           = "main"
+	defaultGitHubPollInterval  = 10 * time.Second
+	defaultGitHubLookupTimeout = 2 * time.Minute
+	// githubClaimRetention 跟踪结束后 run 的关联保留的时间，之后触发的执行不会再关联到该 run
+	githubClaimRetention = 30 * time.Minute
+)
+
+// GitHubConfig GitHub Actions 执行引擎配置
+type GitHubConfig struct {
+	BaseURL       string        // API 地址，GitHub Enterprise 为 https://<host>/api/v3
+	Token         string        // 访问令牌，需要 actions 读写权限
+	PollInterval  time.Duration // 轮询 workflow run 状态的间隔
+	LookupTimeout time.Duration // 触发后查找 workflow run 的超时时间
+	HTTPClient    *http.Client
+	// CorrelationInput 触发时以执行 ID 为值传入的 workflow_dispatch 输入名称。workflow 需要声明该输入并在
+	// run-name 中引用，例如 run-name: ${{ inputs.orchestrator_execution_id }}，之后按 run 的标题关联执行；
+	// 为空时按触发时间关联
+	CorrelationInput string
+}
+
+// GitHubActionsEngine GitHub Actions 执行引擎，通过 REST API 触发、跟踪和取消 workflow run
 type GitHubActionsEngine struct {
-	// 这里可以添加 GitHub API 客户端等字段
+	*baseEngine
+	config  GitHubConfig
+	client  *http.Client
+	claimed map[int64]runClaim // 已关联到执行的 run ID -> 关联信息
+}
+
+// runClaim run 与执行的关联，跟踪结束后保留一段时间，避免之后触发的执行关联到已结束的 run
+type runClaim struct {
+	executionID string
+	expires     time.Time // 过期时间，零值表示仍在跟踪
 }
 
-// NewGitHubActionsEngine 创建 GitHub Actions 执行引擎实例
+// NewGitHubActionsEngine 创建 GitHub Actions 执行引擎实例，
+// API 地址、令牌和关联执行的输入名称从环境变量 GITHUB_API_URL、GITHUB_TOKEN 和 GITHUB_CORRELATION_INPUT 读取
 func NewGitHubActionsEngine() Engine {
-	return &GitHubActionsEngine{}
+	return NewGitHubActionsEngineWithConfig(GitHubConfig{
+		BaseURL:          os.Getenv("GITHUB_API_URL"),
+		Token:            os.Getenv("GITHUB_TOKEN"),
+		CorrelationInput: os.Getenv("GITHUB_CORRELATION_INPUT"),
+	})
+}
+
+// NewGitHubActionsEngineWithConfig 使用指定配置创建 GitHub Actions 执行引擎实例
+func NewGitHubActionsEngineWithConfig(config GitHubConfig) Engine {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultGitHubAPIURL
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultGitHubPollInterval
+	}
+	if config.LookupTimeout <= 0 {
+		config.LookupTimeout = defaultGitHubLookupTimeout
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &GitHubActionsEngine{
+		baseEngine: newBaseEngine(),
+		config:     config,
+		client:     client,
+		claimed:    make(map[int64]runClaim),
+	}
+}
+
+// githubRun workflow run 信息
+type githubRun struct {
+	ID           int64     `json:"id"`
+	RunNumber    int64     `json:"run_number"`
+	RunAttempt   int64     `json:"run_attempt"` // 尝试序号，在原 run 上重新运行时加 1
+	Status       string    `json:"status"`
+	Conclusion   string    `json:"conclusion"`
+	HTMLURL      string    `json:"html_url"`
+	HeadSHA      string    `json:"head_sha"`
+	DisplayTitle string    `json:"display_title"` // run-name 计算得到的标题
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// githubJob workflow run 中的 job 信息
+type githubJob struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	Conclusion  string    `json:"conclusion"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Execute 通过 workflow_dispatch 触发 workflow，并在后台跟踪 run 状态。
+// 部分重新运行时不触发新的 run，而是在上一次尝试的 run 上重新运行失败的 job 或指定的 job
+func (e *GitHubActionsEngine) Execute(ctx context.Context, executionID string, options ExecutionOptions) error {
+	if options.Repository == "" {
+		return fmt.Errorf("repository is required for GitHub Actions execution")
+	}
+	workflow := options.Workflow
+	if workflow == "" {
+		workflow = defaultGitHubWorkflow
+	}
+	ref := options.Ref
+	if ref == "" {
+		ref = defaultGitHubRef
+	}
+
+	e.mutex.Lock()
+	execution, exists := e.executions[executionID]
+	if !exists {
+		e.mutex.Unlock()
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	runCtx, cancel := e.startRun(ctx, executionID)
+	e.mutex.Unlock()
+
+	// 触发 workflow 或在原 run 上重新运行，lookup 查找要跟踪的 run
+	dispatchedAt := time.Now()
+	var lookup func(ctx context.Context) (*githubRun, error)
+	message := fmt.Sprintf("Dispatched workflow %s on %s@%s", workflow, options.Repository, ref)
+	if rerun := options.RemoteRerun; rerun != nil {
+		attempt, err := e.rerun(runCtx, options.Repository, rerun, options.Debug)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to rerun workflow run: %w", err)
+		}
+		lookup = func(ctx context.Context) (*githubRun, error) {
+			return e.findRerun(ctx, executionID, options.Repository, rerun.RunID, attempt)
+		}
+		message = fmt.Sprintf("Re-ran failed jobs of workflow run %d on %s", rerun.RunID, options.Repository)
+		if rerun.Mode == RerunJob {
+			message = fmt.Sprintf("Re-ran job %d of workflow run %d on %s", rerun.JobID, rerun.RunID, options.Repository)
+		}
+	} else {
+		body := map[string]interface{}{"ref": ref}
+		inputs := make(map[string]string, len(options.Inputs)+1)
+		for key, value := range options.Inputs {
+			inputs[key] = value
+		}
+		if e.config.CorrelationInput != "" {
+			inputs[e.config.CorrelationInput] = executionID
+		}
+		if len(inputs) > 0 {
+			body["inputs"] = inputs
+		}
+		path := fmt.Sprintf("/repos/%s/actions/workflows/%s/dispatches", options.Repository, url.PathEscape(workflow))
+		if err := e.request(runCtx, http.MethodPost, path, body, nil); err != nil {
+			cancel()
+			return fmt.Errorf("failed to dispatch workflow: %w", err)
+		}
+		lookup = func(ctx context.Context) (*githubRun, error) {
+			return e.findRun(ctx, executionID, options.Repository, workflow, ref, dispatchedAt)
+		}
+	}
+
+	// 更新状态为运行中，执行在创建期间被取消时不再跟踪
+	e.mutex.Lock()
+	if err := e.transition(execution, StatusRunning, actorFrom(ctx, ActorEngine), ""); err != nil {
+		e.mutex.Unlock()
+		cancel()
+		return err
+	}
+	execution.StartTime = dispatchedAt
+	if execution.PlatformData == nil {
+		execution.PlatformData = map[string]interface{}{}
+	}
+	execution.PlatformData["repository"] = options.Repository
+	execution.PlatformData["workflow"] = workflow
+	execution.PlatformData["ref"] = ref
+	e.appendLog(execution, LogEntry{
+		Level:   "info",
+		Stage:   "dispatch",
+		Message: message,
+	})
+	e.notify(execution)
+	e.mutex.Unlock()
+
+	// 异步跟踪 run 状态
+	go func() {
+		defer cancel()
+		e.track(runCtx, executionID, options.Repository, lookup)
+		e.stopped(runCtx, executionID, e.Cancel)
+	}()
+
+	return nil
+}
+
+// Stop 取消 workflow run
+func (e *GitHubActionsEngine) Stop(ctx context.Context, executionID string) error {
+	return e.Cancel(ctx, executionID, "Execution cancelled by user")
+}
+
+// Cancel 以指定原因取消运行中的执行，原因记录到执行日志和执行记录
+func (e *GitHubActionsEngine) Cancel(ctx context.Context, executionID, reason string) error {
+	e.mutex.RLock()
+	execution, exists := e.executions[executionID]
+	if !exists {
+		e.mutex.RUnlock()
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	if execution.Status != StatusRunning {
+		status := execution.Status
+		e.mutex.RUnlock()
+		return fmt.Errorf("execution is not running: %s", status)
+	}
+	repository, _ := execution.PlatformData["repository"].(string)
+	runID := platformDataInt(execution.PlatformData, "run_id")
+	e.mutex.RUnlock()
+
+	// run 已创建时通过 API 取消，否则只停止跟踪
+	if runID != 0 {
+		path := fmt.Sprintf("/repos/%s/actions/runs/%d/cancel", repository, runID)
+		if err := e.request(ctx, http.MethodPost, path, nil, nil); err != nil {
+			return fmt.Errorf("failed to cancel workflow run: %w", err)
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.cancelExecution(execution, actorFrom(ctx, ActorSystem), reason)
+}
+
+// track 通过 lookup 查找触发的 run 并轮询其状态，直到 run 结束或执行被取消
+func (e *GitHubActionsEngine) track(ctx context.Context, executionID, repository string, lookup func(ctx context.Context) (*githubRun, error)) {
+	run, err := lookup(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.finish(executionID, StatusFailed, fmt.Sprintf("failed to find workflow run: %v", err))
+		}
+		return
+	}
+	// 跟踪结束时执行已结束或被取消，run 的关联在保留时间后过期
+	defer e.releaseRun(run.ID, executionID)
+
+	e.mutex.Lock()
+	if execution, exists := e.executions[executionID]; exists {
+		execution.PlatformData["run_id"] = run.ID
+		execution.PlatformData["run_number"] = run.RunNumber
+		execution.PlatformData["run_attempt"] = run.RunAttempt
+		execution.PlatformData["html_url"] = run.HTMLURL
+		execution.PlatformData["head_sha"] = run.HeadSHA
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   "dispatch",
+			Message: fmt.Sprintf("Tracking workflow run #%d: %s", run.RunNumber, run.HTMLURL),
+		})
+		e.notify(execution)
+	}
+	e.mutex.Unlock()
+
+	downloaded := make(map[int64]bool)
+	for {
+		if err := e.poll(ctx, executionID, repository, run.ID, downloaded); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			e.addLog(executionID, "warn", "poll", fmt.Sprintf("Failed to poll workflow run: %v", err))
+		}
+
+		e.mutex.RLock()
+		status := ""
+		if execution, exists := e.executions[executionID]; exists {
+			status = execution.Status
+		}
+		e.mutex.RUnlock()
+		if IsFinished(status) {
+			return
+		}
+
+		if !sleepContext(ctx, e.config.PollInterval) {
+			return
+		}
+	}
+}
+
+// findRun 在触发后查找对应的 workflow run。配置了 CorrelationInput 时只关联标题包含执行 ID 的 run，
+// 否则关联触发后创建的最早的 run；已关联到其他执行的 run 会被忽略
+func (e *GitHubActionsEngine) findRun(ctx context.Context, executionID, repository, workflow, ref string, dispatchedAt time.Time) (*githubRun, error) {
+	deadline := time.Now().Add(e.config.LookupTimeout)
+	query := url.Values{}
+	query.Set("event", "workflow_dispatch")
+	query.Set("branch", ref)
+	query.Set("per_page", "20")
+	path := fmt.Sprintf("/repos/%s/actions/workflows/%s/runs?%s", repository, url.PathEscape(workflow), query.Encode())
+
+	for {
+		var result struct {
+			WorkflowRuns []githubRun `json:"workflow_runs"`
+		}
+		if err := e.request(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return nil, err
+		}
+
+		// 允许少量时钟偏差
+		since := dispatchedAt.Add(-10 * time.Second)
+		var found *githubRun
+		e.mutex.Lock()
+		e.expireClaims(time.Now())
+		for i := range result.WorkflowRuns {
+			run := &result.WorkflowRuns[i]
+			if run.CreatedAt.Before(since) {
+				continue
+			}
+			if e.config.CorrelationInput != "" && !strings.Contains(run.DisplayTitle, executionID) {
+				continue
+			}
+			if claim, claimed := e.claimed[run.ID]; claimed && claim.executionID != executionID {
+				continue
+			}
+			// 取最早创建的 run，多个执行同时触发时按顺序关联
+			if found == nil || run.CreatedAt.Before(found.CreatedAt) || (run.CreatedAt.Equal(found.CreatedAt) && run.ID < found.ID) {
+				found = run
+			}
+		}
+		if found != nil {
+			e.claimed[found.ID] = runClaim{executionID: executionID}
+		}
+		e.mutex.Unlock()
+
+		if found != nil {
+			return found, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no workflow run created within %s", e.config.LookupTimeout)
+		}
+		if !sleepContext(ctx, e.config.PollInterval) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// rerun 在原 run 上重新运行失败的 job 或指定的 job（及依赖它的 job），返回重新运行之前 run 的尝试序号
+func (e *GitHubActionsEngine) rerun(ctx context.Context, repository string, rerun *RemoteRerun, debug bool) (int64, error) {
+	var run githubRun
+	if err := e.request(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs/%d", repository, rerun.RunID), nil, &run); err != nil {
+		return 0, err
+	}
+
+	path := fmt.Sprintf("/repos/%s/actions/runs/%d/rerun-failed-jobs", repository, rerun.RunID)
+	if rerun.Mode == RerunJob {
+		path = fmt.Sprintf("/repos/%s/actions/jobs/%d/rerun", repository, rerun.JobID)
+	}
+	body := map[string]interface{}{"enable_debug_logging": debug}
+	if err := e.request(ctx, http.MethodPost, path, body, nil); err != nil {
+		return 0, err
+	}
+	return run.RunAttempt, nil
 }
 
-// Execute 执行 GitHub Actions 流程
-func (e *GitHubActionsEngine) Execute(executionID string, options ExecutionOptions) error {
-	// 这里实现与 GitHub API 的交互，触发 workflow
-	// 目前只是一个占位符实现
-	return fmt.Errorf("GitHub Actions engine not implemented yet")
+// findRerun 等待重新运行的 run 开始新的尝试并关联到执行，避免把上一次尝试的结果当作本次的结果
+func (e *GitHubActionsEngine) findRerun(ctx context.Context, executionID, repository string, runID, attempt int64) (*githubRun, error) {
+	deadline := time.Now().Add(e.config.LookupTimeout)
+	for {
+		var run githubRun
+		if err := e.request(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs/%d", repository, runID), nil, &run); err != nil {
+			return nil, err
+		}
+		if run.RunAttempt > attempt {
+			e.mutex.Lock()
+			e.claimed[run.ID] = runClaim{executionID: executionID}
+			e.mutex.Unlock()
+			return &run, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("workflow run %d did not start a new attempt within %s", runID, e.config.LookupTimeout)
+		}
+		if !sleepContext(ctx, e.config.PollInterval) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// rerunRunID 返回执行关联的 workflow run ID
+func (e *GitHubActionsEngine) rerunRunID(execution *Execution) int64 {
+	return platformDataInt(execution.PlatformData, "run_id")
+}
+
+// releaseRun 在跟踪结束时为 run 的关联设置过期时间。按触发时间关联时，保留期间触发的执行
+// 仍会忽略该 run，不会把已结束的 run 的结果当作自己的结果
+func (e *GitHubActionsEngine) releaseRun(runID int64, executionID string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if claim, claimed := e.claimed[runID]; claimed && claim.executionID == executionID {
+		claim.expires = time.Now().Add(githubClaimRetention)
+		e.claimed[runID] = claim
+	}
 }
 
-// Stop 停止执行
-func (e *GitHubActionsEngine) Stop(executionID string) error {
-	// 这里实现与 GitHub API 的交互，取消 workflow
-	// 目前只是一个占位符实现
-	return fmt.Errorf("GitHub Actions engine not implemented yet")
+// expireClaims 删除已过期的 run 关联，调用方需持有写锁
+func (e *GitHubActionsEngine) expireClaims(now time.Time) {
+	for runID, claim := range e.claimed {
+		if !claim.expires.IsZero() && now.After(claim.expires) {
+			delete(e.claimed, runID)
+		}
+	}
 }
 
-// GetStatus 获取执行状态
-func (e *GitHubActionsEngine) GetStatus(executionID string) (*Execution, error) {
-	// 这里实现与 GitHub API 的交互，获取 workflow 状态
-	// 目前只是一个占位符实现
-	return nil, fmt.Errorf("GitHub Actions engine not implemented yet")
+// poll 获取 run 和 job 状态，下载已结束 job 的日志，run 结束时完成执行
+func (e *GitHubActionsEngine) poll(ctx context.Context, executionID, repository string, runID int64, downloaded map[int64]bool) error {
+	var run githubRun
+	if err := e.request(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs/%d", repository, runID), nil, &run); err != nil {
+		return err
+	}
+
+	var result struct {
+		Jobs []githubJob `json:"jobs"`
+	}
+	if err := e.request(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs/%d/jobs?per_page=100", repository, runID), nil, &result); err != nil {
+		return err
+	}
+
+	e.addDebugLog(executionID, "poll", "", fmt.Sprintf("Workflow run %d is %s", runID, run.Status), map[string]interface{}{
+		"conclusion": run.Conclusion,
+		"jobs":       len(result.Jobs),
+	})
+	e.updateJobs(executionID, run, result.Jobs)
+
+	// 下载已结束 job 的日志
+	for _, job := range result.Jobs {
+		if job.Status != "completed" || downloaded[job.ID] {
+			continue
+		}
+		if err := e.downloadJobLogs(ctx, executionID, repository, job); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			e.addLog(executionID, "warn", job.Name, fmt.Sprintf("Failed to download logs for job %s: %v", job.Name, err))
+		}
+		downloaded[job.ID] = true
+	}
+
+	if run.Status == "completed" {
+		status := githubConclusionStatus(run.Conclusion)
+		reason := ""
+		if status != StatusSuccess {
+			reason = fmt.Sprintf("workflow run concluded with %s", run.Conclusion)
+		}
+		e.finish(executionID, status, reason)
+	}
+
+	return nil
+}
+
+// updateJobs 将 GitHub job 状态同步到执行记录
+func (e *GitHubActionsEngine) updateJobs(executionID string, run githubRun, jobs []githubJob) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists || IsFinished(execution.Status) {
+		return
+	}
+
+	jobIDs := make(map[string]interface{}, len(jobs))
+	execution.Jobs = make([]JobExecution, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs[job.Name] = job.ID
+
+		status := StatusPending
+		switch job.Status {
+		case "in_progress":
+			status = StatusRunning
+		case "completed":
+			status = githubConclusionStatus(job.Conclusion)
+		}
+
+		entry := JobExecution{
+			Name:      job.Name,
+			Status:    status,
+			StartTime: job.StartedAt,
+			EndTime:   job.CompletedAt,
+		}
+		if !job.StartedAt.IsZero() && !job.CompletedAt.IsZero() {
+			entry.Duration = int64(job.CompletedAt.Sub(job.StartedAt).Seconds())
+		}
+		if job.Status == "completed" && status != StatusSuccess {
+			entry.Reason = job.Conclusion
+		}
+		execution.Jobs = append(execution.Jobs, entry)
+	}
+
+	execution.PlatformData["job_ids"] = jobIDs
+	execution.PlatformData["run_status"] = run.Status
+	e.notify(execution)
+}
+
+// downloadJobLogs 下载 job 日志并逐行写入执行日志
+func (e *GitHubActionsEngine) downloadJobLogs(ctx context.Context, executionID, repository string, job githubJob) error {
+	req, err := e.newRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/actions/jobs/%d/logs", repository, job.ID), nil)
+	if err != nil {
+		return err
+	}
+
+	// 接口返回 302 跳转到日志下载地址，HTTP 客户端会自动跟随
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return githubError(resp)
+	}
+
+	for _, entry := range parseGitHubLogs(resp.Body, job.Name) {
+		e.addLogEntry(executionID, entry)
+	}
+	return nil
+}
+
+// finish 结束执行并记录指标
+func (e *GitHubActionsEngine) finish(executionID, status, reason string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.cancels, executionID)
+
+	execution, exists := e.executions[executionID]
+	if !exists || e.transition(execution, status, ActorEngine, reason) != nil {
+		return
+	}
+
+	switch status {
+	case StatusSuccess:
+		execution.Metrics.SuccessRate = 1.0
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   "complete",
+			Message: fmt.Sprintf("Execution completed successfully in %d seconds", execution.Duration),
+		})
+	case StatusCancelled:
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   "cancellation",
+			Message: "Workflow run was cancelled on GitHub",
+		})
+	default:
+		execution.Metrics.SuccessRate = 0.0
+		e.appendLog(execution, LogEntry{
+			Level:   "error",
+			Stage:   "complete",
+			Message: fmt.Sprintf("Execution failed: %s", reason),
+		})
+	}
+
+	execution.Metrics.TotalDuration = execution.Duration
+	execution.Metrics.StageDurations = jobDurations(execution)
+	e.notify(execution)
+}
+
+// request 发送 API 请求，result 不为 nil 时解析 JSON 响应
+func (e *GitHubActionsEngine) request(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	req, err := e.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return githubError(resp)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// newRequest 创建带认证信息的 API 请求
+func (e *GitHubActionsEngine) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.config.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if e.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// githubError 从错误响应中提取错误信息
+func githubError(resp *http.Response) error {
+	var result struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &result) == nil && result.Message != "" {
+		return fmt.Errorf("GitHub API %s: %s", resp.Status, result.Message)
+	}
+	return fmt.Errorf("GitHub API %s", resp.Status)
+}
+
+// githubConclusionStatus 将 run 或 job 的结论映射为执行状态
+func githubConclusionStatus(conclusion string) string {
+	switch conclusion {
+	case "success", "neutral":
+		return StatusSuccess
+	case "skipped":
+		return StatusSkipped
+	case "cancelled":
+		return StatusCancelled
+	case "timed_out":
+		return StatusTimedOut
+	default:
+		// failure、action_required、startup_failure、stale 均视为失败
+		return StatusFailed
+	}
+}
+
+// parseGitHubLogs 解析 job 日志，每行格式为 "<RFC3339 时间> <内容>"，
+// ##[group] 标记步骤开始，##[error] 和 ##[warning] 标记日志级别
+func parseGitHubLogs(reader io.Reader, jobName string) []LogEntry {
+	var entries []LogEntry
+	step := ""
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(scanner.Text(), "\ufeff")
+		entry := LogEntry{Level: "info", Stage: jobName, Message: line}
+
+		if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
+			if timestamp, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+				entry.Timestamp = timestamp
+				entry.Message = parts[1]
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(entry.Message, "##[group]"):
+			entry.Message = strings.TrimPrefix(entry.Message, "##[group]")
+			step = entry.Message
+		case strings.HasPrefix(entry.Message, "##[endgroup]"):
+			continue
+		case strings.HasPrefix(entry.Message, "##[error]"):
+			entry.Level = "error"
+			entry.Message = strings.TrimPrefix(entry.Message, "##[error]")
+		case strings.HasPrefix(entry.Message, "##[warning]"):
+			entry.Level = "warn"
+			entry.Message = strings.TrimPrefix(entry.Message, "##[warning]")
+		}
+
+		entry.Step = step
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// platformDataInt 读取平台数据中的整数，兼容从 JSON 恢复后的 float64
+func platformDataInt(data map[string]interface{}, key string) int64 {
+	switch v := data[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// RepositoryPath 从仓库地址中解析仓库路径（例如 owner/repo），支持 HTTPS 和 SSH 地址
+func RepositoryPath(repositoryURL string) (string, error) {
+	path := strings.TrimSpace(repositoryURL)
+	switch {
+	case strings.HasPrefix(path, "git@"):
+		// git@github.com:owner/repo.git
+		index := strings.Index(path, ":")
+		if index < 0 {
+			return "", fmt.Errorf("invalid repository url: %s", repositoryURL)
+		}
+		path = path[index+1:]
+	case strings.Contains(path, "://"):
+		parsed, err := url.Parse(path)
+		if err != nil {
+			return "", fmt.Errorf("invalid repository url: %s", repositoryURL)
+		}
+		path = parsed.Path
+	}
+
+	path = strings.Trim(strings.TrimSuffix(strings.TrimRight(path, "/"), ".git"), "/")
+	if strings.Count(path, "/") < 1 {
+		return "", fmt.Errorf("invalid repository url: %s", repositoryURL)
+	}
+	return path, nil
 }
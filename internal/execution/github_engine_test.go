@@ -0,0 +1,435 @@
+package execution
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitHub 模拟 GitHub Actions REST API
+type fakeGitHub struct {
+	mutex      sync.Mutex
+	dispatched bool
+	polls      int
+	running    bool // 为 true 时 run 一直处于运行中，直到被取消
+	cancelled  bool
+	inputs     map[string]interface{} // 最近一次触发的 workflow_dispatch 输入
+	dispatches int
+	attempts   int      // 在 run 上重新运行的次数，重新运行后 run 成功
+	reruns     []string // 重新运行请求的路径
+	debug      []bool   // 重新运行请求的 enable_debug_logging
+}
+
+func (f *fakeGitHub) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /repos/acme/app/actions/workflows/ci.yml/dispatches", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["ref"] != "main" {
+			t.Errorf("触发分支不匹配: %v", body["ref"])
+		}
+
+		f.mutex.Lock()
+		f.dispatched = true
+		f.dispatches++
+		f.inputs, _ = body["inputs"].(map[string]interface{})
+		f.mutex.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /repos/acme/app/actions/workflows/ci.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		runs := []map[string]interface{}{
+			// 触发之前创建的 run 不应被关联
+			{"id": 1, "run_number": 1, "status": "completed", "created_at": time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		}
+		if f.dispatched {
+			run := map[string]interface{}{"id": 42, "run_number": 7, "status": "queued", "html_url": "https://github.com/acme/app/actions/runs/42", "created_at": time.Now().Format(time.RFC3339)}
+			if executionID, ok := f.inputs["orchestrator_execution_id"].(string); ok {
+				// 按标题关联时，更早创建的其他 run 不应被关联
+				runs = append(runs, map[string]interface{}{"id": 41, "run_number": 6, "status": "queued", "display_title": "deploy", "created_at": time.Now().Add(-time.Second).Format(time.RFC3339)})
+				run["display_title"] = "CI " + executionID
+			}
+			runs = append(runs, run)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"workflow_runs": runs})
+	})
+
+	mux.HandleFunc("GET /repos/acme/app/actions/runs/42", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		f.polls++
+		run := map[string]interface{}{"id": 42, "run_number": 7, "run_attempt": 1 + f.attempts, "status": "in_progress"}
+		if f.cancelled {
+			run["status"] = "completed"
+			run["conclusion"] = "cancelled"
+		} else if f.polls > 1 && !f.running {
+			run["status"] = "completed"
+			run["conclusion"] = "failure"
+			if f.attempts > 0 {
+				run["conclusion"] = "success"
+			}
+		}
+		json.NewEncoder(w).Encode(run)
+	})
+
+	mux.HandleFunc("GET /repos/acme/app/actions/runs/42/jobs", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		started := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		completed := time.Now().UTC().Format(time.RFC3339)
+		test := map[string]interface{}{"id": 101, "name": "test", "status": "in_progress", "started_at": started}
+		if f.polls > 1 && !f.running {
+			test["status"] = "completed"
+			test["conclusion"] = "failure"
+			test["completed_at"] = completed
+			if f.attempts > 0 {
+				test["conclusion"] = "success"
+			}
+		}
+		jobs := []map[string]interface{}{
+			{"id": 100, "name": "build", "status": "completed", "conclusion": "success", "started_at": started, "completed_at": completed},
+			test,
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jobs": jobs})
+	})
+
+	mux.HandleFunc("GET /repos/acme/app/actions/jobs/100/logs", func(w http.ResponseWriter, r *http.Request) {
+		// 真实接口会跳转到日志下载地址
+		http.Redirect(w, r, "/download/100", http.StatusFound)
+	})
+	mux.HandleFunc("GET /download/100", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2024-01-01T00:00:00.0000000Z ##[group]Run go build ./...\n2024-01-01T00:00:01.0000000Z go: downloading modules\n2024-01-01T00:00:02.0000000Z ##[endgroup]\n"))
+	})
+	mux.HandleFunc("GET /repos/acme/app/actions/jobs/101/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2024-01-01T00:00:00.0000000Z ##[group]Run go test ./...\n2024-01-01T00:00:03.0000000Z ##[error]Process completed with exit code 1.\n"))
+	})
+
+	rerun := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			EnableDebugLogging bool `json:"enable_debug_logging"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		f.mutex.Lock()
+		f.attempts++
+		f.polls = 0
+		f.reruns = append(f.reruns, r.URL.Path)
+		f.debug = append(f.debug, body.EnableDebugLogging)
+		f.mutex.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}
+	mux.HandleFunc("POST /repos/acme/app/actions/runs/42/rerun-failed-jobs", rerun)
+	mux.HandleFunc("POST /repos/acme/app/actions/jobs/{job}/rerun", rerun)
+
+	mux.HandleFunc("POST /repos/acme/app/actions/runs/42/cancel", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		f.cancelled = true
+		f.mutex.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	return mux
+}
+
+func TestGitHubActionsEngine(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	engine := NewGitHubActionsEngineWithConfig(GitHubConfig{
+		BaseURL:      server.URL + "/",
+		Token:        "test-token",
+		PollInterval: 10 * time.Millisecond,
+	}).(*GitHubActionsEngine)
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("github_actions", engine)
+
+	executionID, err := manager.CreateExecution("1", "github_actions", "manual", ExecutionOptions{
+		Repository: "acme/app",
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusFailed {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusFailed, execution.Status)
+	}
+
+	if platformDataInt(execution.PlatformData, "run_id") != 42 {
+		t.Errorf("run ID 不匹配: %v", execution.PlatformData["run_id"])
+	}
+	jobIDs, _ := execution.PlatformData["job_ids"].(map[string]interface{})
+	if jobIDs["test"] != int64(101) {
+		t.Errorf("job ID 不匹配: %v", execution.PlatformData["job_ids"])
+	}
+
+	statuses := map[string]string{}
+	for _, job := range execution.Jobs {
+		statuses[job.Name] = job.Status
+	}
+	if statuses["build"] != StatusSuccess || statuses["test"] != StatusFailed {
+		t.Errorf("job 状态不匹配: %v", statuses)
+	}
+
+	// 检查 job 日志是否被下载并解析
+	var foundOutput, foundError bool
+	for _, log := range execution.Logs {
+		if log.Stage == "build" && log.Step == "Run go build ./..." && log.Message == "go: downloading modules" {
+			foundOutput = true
+		}
+		if log.Stage == "test" && log.Level == "error" && log.Message == "Process completed with exit code 1." {
+			foundError = true
+		}
+	}
+	if !foundOutput || !foundError {
+		t.Errorf("日志中未找到 job 输出: %v", execution.Logs)
+	}
+	waitForRunsReleased(t, engine)
+
+	// 之后触发的执行不能关联到已结束的 run
+	engine.config.LookupTimeout = 200 * time.Millisecond
+	nextID, err := manager.CreateExecution("1", "github_actions", "manual", ExecutionOptions{
+		Repository: "acme/app",
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(nextID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	next := waitForStatus(t, manager, nextID)
+	if next.Status != StatusFailed || platformDataInt(next.PlatformData, "run_id") != 0 {
+		t.Errorf("已结束的 run 不应被之后触发的执行关联: %s %v", next.Status, next.PlatformData["run_id"])
+	}
+
+	// 关联在保留时间后过期
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	engine.expireClaims(time.Now().Add(githubClaimRetention + time.Second))
+	if len(engine.claimed) != 0 {
+		t.Errorf("过期的 run 关联应被删除: %v", engine.claimed)
+	}
+}
+
+func TestGitHubActionsEngineCorrelationInput(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("github_actions", NewGitHubActionsEngineWithConfig(GitHubConfig{
+		BaseURL:          server.URL,
+		Token:            "test-token",
+		PollInterval:     10 * time.Millisecond,
+		CorrelationInput: "orchestrator_execution_id",
+	}))
+
+	executionID, err := manager.CreateExecution("1", "github_actions", "manual", ExecutionOptions{
+		Repository: "acme/app",
+		Inputs:     map[string]string{"environment": "staging"},
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if platformDataInt(execution.PlatformData, "run_id") != 42 {
+		t.Errorf("应关联标题包含执行 ID 的 run: %v", execution.PlatformData["run_id"])
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if fake.inputs["orchestrator_execution_id"] != executionID || fake.inputs["environment"] != "staging" {
+		t.Errorf("触发输入不匹配: %v", fake.inputs)
+	}
+}
+
+func TestGitHubActionsEngineRerun(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("github_actions", NewGitHubActionsEngineWithConfig(GitHubConfig{
+		BaseURL:      server.URL,
+		Token:        "test-token",
+		PollInterval: 10 * time.Millisecond,
+	}))
+
+	executionID, err := manager.CreateExecution("1", "github_actions", "manual", ExecutionOptions{
+		Repository: "acme/app",
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	if first := waitForStatus(t, manager, executionID); first.Status != StatusFailed {
+		t.Fatalf("第一次尝试应失败: %s", first.Status)
+	}
+
+	if _, err := manager.RerunExecution(executionID, RerunOptions{Mode: RerunJob, Job: "missing"}); err == nil {
+		t.Error("重新运行不存在的 job 应返回错误")
+	}
+
+	// 只重新运行失败的 job，在原 run 上重新运行，不触发新的 run
+	rerunID, err := manager.RerunExecution(executionID, RerunOptions{Mode: RerunFailed})
+	if err != nil {
+		t.Fatalf("重新运行失败的 job 失败: %v", err)
+	}
+	if err := manager.StartExecution(rerunID); err != nil {
+		t.Fatalf("启动重新运行的执行失败: %v", err)
+	}
+	second := waitForStatus(t, manager, rerunID)
+	if second.Status != StatusSuccess || second.Attempt != 2 {
+		t.Fatalf("重新运行应成功: %s %d %s", second.Status, second.Attempt, second.Reason)
+	}
+	if platformDataInt(second.PlatformData, "run_id") != 42 || platformDataInt(second.PlatformData, "run_attempt") != 2 {
+		t.Errorf("重新运行应跟踪原 run 的新尝试: %v %v", second.PlatformData["run_id"], second.PlatformData["run_attempt"])
+	}
+
+	// 重新运行指定的 job，开启调试日志
+	thirdID, err := manager.RerunExecution(rerunID, RerunOptions{Mode: RerunJob, Job: "build", Debug: true})
+	if err != nil {
+		t.Fatalf("重新运行指定的 job 失败: %v", err)
+	}
+	if err := manager.StartExecution(thirdID); err != nil {
+		t.Fatalf("启动重新运行的执行失败: %v", err)
+	}
+	if third := waitForStatus(t, manager, thirdID); third.Status != StatusSuccess || platformDataInt(third.PlatformData, "run_attempt") != 3 {
+		t.Errorf("重新运行指定的 job 应成功: %s %v", third.Status, third.PlatformData["run_attempt"])
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	expected := []string{"/repos/acme/app/actions/runs/42/rerun-failed-jobs", "/repos/acme/app/actions/jobs/100/rerun"}
+	if len(fake.reruns) != 2 || fake.reruns[0] != expected[0] || fake.reruns[1] != expected[1] {
+		t.Errorf("重新运行请求不匹配: %v", fake.reruns)
+	}
+	if fake.debug[0] || !fake.debug[1] {
+		t.Errorf("重新运行的调试日志设置不匹配: %v", fake.debug)
+	}
+	if fake.dispatches != 1 {
+		t.Errorf("部分重新运行不应触发新的 run: %d", fake.dispatches)
+	}
+}
+
+func TestGitHubActionsEngineStop(t *testing.T) {
+	fake := &fakeGitHub{running: true}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	engine := NewGitHubActionsEngineWithConfig(GitHubConfig{
+		BaseURL:      server.URL,
+		Token:        "test-token",
+		PollInterval: 10 * time.Millisecond,
+	}).(*GitHubActionsEngine)
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("github_actions", engine)
+
+	executionID, err := manager.CreateExecution("1", "github_actions", "manual", ExecutionOptions{
+		Repository: "acme/app",
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	// 等待关联到 workflow run 后再停止
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		execution, _ := manager.GetExecution(executionID)
+		if platformDataInt(execution.PlatformData, "run_id") == 42 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("未关联到 workflow run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := manager.StopExecution(executionID); err != nil {
+		t.Fatalf("停止执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusCancelled {
+		t.Errorf("执行状态不匹配: 期望 %s, 实际 %s", StatusCancelled, execution.Status)
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if !fake.cancelled {
+		t.Error("停止执行时未调用取消接口")
+	}
+	waitForRunsReleased(t, engine)
+}
+
+// waitForRunsReleased 等待执行结束后 run 的关联设置过期时间
+func waitForRunsReleased(t *testing.T, engine *GitHubActionsEngine) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		engine.mutex.RLock()
+		tracking := 0
+		for _, claim := range engine.claimed {
+			if claim.expires.IsZero() {
+				tracking++
+			}
+		}
+		claimed := len(engine.claimed)
+		engine.mutex.RUnlock()
+		if claimed > 0 && tracking == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("执行结束后 run 的关联未设置过期时间: %d/%d", tracking, claimed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRepositoryPath(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/acme/app":          "acme/app",
+		"https://github.com/acme/app.git":      "acme/app",
+		"git@github.com:acme/app.git":          "acme/app",
+		"https://gitlab.com/group/sub/project": "group/sub/project",
+	}
+	for url, expected := range cases {
+		path, err := RepositoryPath(url)
+		if err != nil || path != expected {
+			t.Errorf("仓库路径不匹配 (%s): 期望 %s, 实际 %s (%v)", url, expected, path, err)
+		}
+	}
+
+	if _, err := RepositoryPath("not-a-repository"); err == nil {
+		t.Error("无效的仓库地址应返回错误")
+	}
+}
@@ -0,0 +1,572 @@
+package execution
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GitLab API 默认配置
+const (
+	defaultGitLabAPIURL       = "https://gitlab.com/api/v4"
+	defaultGitLabRef          = "main"
+	defaultGitLabPollInterval = 10 * time.Second
+)
+
+// ansiEscape 匹配 job 日志中的 ANSI 控制序列
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// GitLabConfig GitLab CI 执行引擎配置
+type GitLabConfig struct {
+	BaseURL      string        // API 地址，自托管实例为 https://<host>/api/v4
+	Token        string        // 访问令牌，需要 api 权限
+	PollInterval time.Duration // 轮询 pipeline 状态的间隔
+	HTTPClient   *http.Client
+}
+
+// GitLabCIEngine GitLab CI 执行引擎，通过 REST API 创建、跟踪和取消 pipeline
+type GitLabCIEngine struct {
+	*baseEngine
+	config GitLabConfig
+	client *http.Client
+}
+
+// NewGitLabCIEngine 创建 GitLab CI 执行引擎实例，
+// API 地址和令牌从环境变量 GITLAB_API_URL 和 GITLAB_TOKEN 读取
+func NewGitLabCIEngine() Engine {
+	return NewGitLabCIEngineWithConfig(GitLabConfig{
+		BaseURL: os.Getenv("GITLAB_API_URL"),
+		Token:   os.Getenv("GITLAB_TOKEN"),
+	})
+}
+
+// NewGitLabCIEngineWithConfig 使用指定配置创建 GitLab CI 执行引擎实例
+func NewGitLabCIEngineWithConfig(config GitLabConfig) Engine {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultGitLabAPIURL
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultGitLabPollInterval
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &GitLabCIEngine{
+		baseEngine: newBaseEngine(),
+		config:     config,
+		client:     client,
+	}
+}
+
+// gitlabPipeline pipeline 信息
+type gitlabPipeline struct {
+	ID     int64  `json:"id"`
+	IID    int64  `json:"iid"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+	SHA    string `json:"sha"`
+	WebURL string `json:"web_url"`
+}
+
+// gitlabJob pipeline 中的 job 信息
+type gitlabJob struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Stage      string    `json:"stage"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// gitlabVariable 创建 pipeline 时传入的变量
+type gitlabVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Execute 在指定分支上创建 pipeline，并在后台跟踪 pipeline 状态。
+// 部分重新运行时不创建新的 pipeline，而是重试上一次尝试的 pipeline 中失败的 job 或指定的 job
+func (e *GitLabCIEngine) Execute(ctx context.Context, executionID string, options ExecutionOptions) error {
+	if options.Repository == "" {
+		return fmt.Errorf("repository is required for GitLab CI execution")
+	}
+	ref := options.Ref
+	if ref == "" {
+		ref = defaultGitLabRef
+	}
+
+	e.mutex.Lock()
+	execution, exists := e.executions[executionID]
+	if !exists {
+		e.mutex.Unlock()
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	runCtx, cancel := e.startRun(ctx, executionID)
+	e.mutex.Unlock()
+
+	startTime := time.Now()
+	var pipeline gitlabPipeline
+	action := "Created pipeline"
+	if rerun := options.RemoteRerun; rerun != nil {
+		if err := e.retry(runCtx, options.Repository, rerun, &pipeline); err != nil {
+			cancel()
+			return fmt.Errorf("failed to retry pipeline: %w", err)
+		}
+		action = "Retried failed jobs of pipeline"
+		if rerun.Mode == RerunJob {
+			action = fmt.Sprintf("Retried job %d of pipeline", rerun.JobID)
+		}
+	} else {
+		// 创建 pipeline，Inputs 作为 pipeline 变量传入，开启调试日志时设置 CI_DEBUG_TRACE 输出 job 的调试跟踪
+		body := map[string]interface{}{"ref": ref}
+		keys := make([]string, 0, len(options.Inputs))
+		for key := range options.Inputs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		variables := make([]gitlabVariable, 0, len(keys)+1)
+		for _, key := range keys {
+			variables = append(variables, gitlabVariable{Key: key, Value: options.Inputs[key]})
+		}
+		if options.Debug {
+			variables = append(variables, gitlabVariable{Key: "CI_DEBUG_TRACE", Value: "true"})
+		}
+		if len(variables) > 0 {
+			body["variables"] = variables
+		}
+
+		if err := e.request(runCtx, http.MethodPost, gitlabProjectPath(options.Repository)+"/pipeline", body, &pipeline); err != nil {
+			cancel()
+			return fmt.Errorf("failed to create pipeline: %w", err)
+		}
+	}
+
+	// 更新状态为运行中，执行在创建期间被取消时不再跟踪
+	e.mutex.Lock()
+	if err := e.transition(execution, StatusRunning, actorFrom(ctx, ActorEngine), ""); err != nil {
+		e.mutex.Unlock()
+		cancel()
+		return err
+	}
+	execution.StartTime = startTime
+	if execution.PlatformData == nil {
+		execution.PlatformData = map[string]interface{}{}
+	}
+	execution.PlatformData["repository"] = options.Repository
+	execution.PlatformData["ref"] = ref
+	execution.PlatformData["pipeline_id"] = pipeline.ID
+	execution.PlatformData["pipeline_iid"] = pipeline.IID
+	execution.PlatformData["web_url"] = pipeline.WebURL
+	execution.PlatformData["sha"] = pipeline.SHA
+	e.appendLog(execution, LogEntry{
+		Level:   "info",
+		Stage:   "dispatch",
+		Message: fmt.Sprintf("%s #%d on %s@%s: %s", action, pipeline.IID, options.Repository, ref, pipeline.WebURL),
+	})
+	e.notify(execution)
+	e.mutex.Unlock()
+
+	// 异步跟踪 pipeline 状态
+	go func() {
+		defer cancel()
+		e.track(runCtx, executionID, options.Repository, pipeline.ID)
+		e.stopped(runCtx, executionID, e.Cancel)
+	}()
+
+	return nil
+}
+
+// Stop 取消 pipeline
+func (e *GitLabCIEngine) Stop(ctx context.Context, executionID string) error {
+	return e.Cancel(ctx, executionID, "Execution cancelled by user")
+}
+
+// Cancel 以指定原因取消运行中的执行，原因记录到执行日志和执行记录
+func (e *GitLabCIEngine) Cancel(ctx context.Context, executionID, reason string) error {
+	e.mutex.RLock()
+	execution, exists := e.executions[executionID]
+	if !exists {
+		e.mutex.RUnlock()
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	if execution.Status != StatusRunning {
+		status := execution.Status
+		e.mutex.RUnlock()
+		return fmt.Errorf("execution is not running: %s", status)
+	}
+	repository, _ := execution.PlatformData["repository"].(string)
+	pipelineID := platformDataInt(execution.PlatformData, "pipeline_id")
+	e.mutex.RUnlock()
+
+	path := fmt.Sprintf("%s/pipelines/%d/cancel", gitlabProjectPath(repository), pipelineID)
+	if err := e.request(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to cancel pipeline: %w", err)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.cancelExecution(execution, actorFrom(ctx, ActorSystem), reason)
+}
+
+// retry 重试 pipeline 中失败的 job 或指定的 job，之后 pipeline 按依赖关系继续运行后续的 job。
+// 重试的 job 在同一 pipeline 中运行，pipeline 保存重试后的 pipeline 信息
+func (e *GitLabCIEngine) retry(ctx context.Context, repository string, rerun *RemoteRerun, pipeline *gitlabPipeline) error {
+	project := gitlabProjectPath(repository)
+	if rerun.Mode == RerunJob {
+		if err := e.request(ctx, http.MethodPost, fmt.Sprintf("%s/jobs/%d/retry", project, rerun.JobID), nil, nil); err != nil {
+			return err
+		}
+		return e.request(ctx, http.MethodGet, fmt.Sprintf("%s/pipelines/%d", project, rerun.RunID), nil, pipeline)
+	}
+	return e.request(ctx, http.MethodPost, fmt.Sprintf("%s/pipelines/%d/retry", project, rerun.RunID), nil, pipeline)
+}
+
+// rerunRunID 返回执行关联的 pipeline ID
+func (e *GitLabCIEngine) rerunRunID(execution *Execution) int64 {
+	return platformDataInt(execution.PlatformData, "pipeline_id")
+}
+
+// track 轮询 pipeline 状态，直到 pipeline 结束或执行被取消
+func (e *GitLabCIEngine) track(ctx context.Context, executionID, repository string, pipelineID int64) {
+	downloaded := make(map[int64]bool)
+	for {
+		if err := e.poll(ctx, executionID, repository, pipelineID, downloaded); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			e.addLog(executionID, "warn", "poll", fmt.Sprintf("Failed to poll pipeline: %v", err))
+		}
+
+		e.mutex.RLock()
+		status := ""
+		if execution, exists := e.executions[executionID]; exists {
+			status = execution.Status
+		}
+		e.mutex.RUnlock()
+		if IsFinished(status) {
+			return
+		}
+
+		if !sleepContext(ctx, e.config.PollInterval) {
+			return
+		}
+	}
+}
+
+// poll 获取 pipeline 和 job 状态，下载已结束 job 的日志，pipeline 结束时完成执行
+func (e *GitLabCIEngine) poll(ctx context.Context, executionID, repository string, pipelineID int64, downloaded map[int64]bool) error {
+	project := gitlabProjectPath(repository)
+
+	var pipeline gitlabPipeline
+	if err := e.request(ctx, http.MethodGet, fmt.Sprintf("%s/pipelines/%d", project, pipelineID), nil, &pipeline); err != nil {
+		return err
+	}
+
+	var jobs []gitlabJob
+	if err := e.request(ctx, http.MethodGet, fmt.Sprintf("%s/pipelines/%d/jobs?per_page=100", project, pipelineID), nil, &jobs); err != nil {
+		return err
+	}
+
+	// 接口按 job ID 倒序返回，按创建顺序展示
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	e.addDebugLog(executionID, "poll", "", fmt.Sprintf("Pipeline %d is %s", pipelineID, pipeline.Status), map[string]interface{}{
+		"jobs": len(jobs),
+	})
+	e.updateJobs(executionID, pipeline, jobs)
+
+	// 下载已结束 job 的日志
+	for _, job := range jobs {
+		if !gitlabJobFinished(job.Status) || downloaded[job.ID] {
+			continue
+		}
+		if job.Status != "skipped" {
+			if err := e.downloadJobLogs(ctx, executionID, project, job); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				e.addLog(executionID, "warn", job.Name, fmt.Sprintf("Failed to download logs for job %s: %v", job.Name, err))
+			}
+		}
+		downloaded[job.ID] = true
+	}
+
+	if gitlabPipelineFinished(pipeline.Status) {
+		status := gitlabStatus(pipeline.Status)
+		reason := ""
+		if status != StatusSuccess {
+			reason = fmt.Sprintf("pipeline finished with status %s", pipeline.Status)
+		}
+		e.finish(executionID, status, reason)
+	}
+
+	return nil
+}
+
+// updateJobs 将 GitLab job 状态同步到执行记录
+func (e *GitLabCIEngine) updateJobs(executionID string, pipeline gitlabPipeline, jobs []gitlabJob) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists || IsFinished(execution.Status) {
+		return
+	}
+
+	jobIDs := make(map[string]interface{}, len(jobs))
+	execution.Jobs = make([]JobExecution, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs[job.Name] = job.ID
+
+		entry := JobExecution{
+			Name:      job.Name,
+			Status:    gitlabStatus(job.Status),
+			StartTime: job.StartedAt,
+			EndTime:   job.FinishedAt,
+		}
+		if !job.StartedAt.IsZero() && !job.FinishedAt.IsZero() {
+			entry.Duration = int64(job.FinishedAt.Sub(job.StartedAt).Seconds())
+		}
+		if gitlabJobFinished(job.Status) && entry.Status != StatusSuccess {
+			entry.Reason = job.Status
+		}
+		execution.Jobs = append(execution.Jobs, entry)
+	}
+
+	execution.PlatformData["job_ids"] = jobIDs
+	execution.PlatformData["pipeline_status"] = pipeline.Status
+	e.notify(execution)
+}
+
+// downloadJobLogs 下载 job 日志并逐行写入执行日志
+func (e *GitLabCIEngine) downloadJobLogs(ctx context.Context, executionID, project string, job gitlabJob) error {
+	req, err := e.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/jobs/%d/trace", project, job.ID), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return gitlabError(resp)
+	}
+
+	for _, entry := range parseGitLabTrace(resp.Body, job.Name) {
+		e.addLogEntry(executionID, entry)
+	}
+	return nil
+}
+
+// finish 结束执行并记录指标
+func (e *GitLabCIEngine) finish(executionID, status, reason string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.cancels, executionID)
+
+	execution, exists := e.executions[executionID]
+	if !exists || e.transition(execution, status, ActorEngine, reason) != nil {
+		return
+	}
+
+	switch status {
+	case StatusSuccess:
+		execution.Metrics.SuccessRate = 1.0
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   "complete",
+			Message: fmt.Sprintf("Execution completed successfully in %d seconds", execution.Duration),
+		})
+	case StatusCancelled:
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   "cancellation",
+			Message: "Pipeline was cancelled on GitLab",
+		})
+	default:
+		execution.Metrics.SuccessRate = 0.0
+		e.appendLog(execution, LogEntry{
+			Level:   "error",
+			Stage:   "complete",
+			Message: fmt.Sprintf("Execution failed: %s", reason),
+		})
+	}
+
+	execution.Metrics.TotalDuration = execution.Duration
+	execution.Metrics.StageDurations = jobDurations(execution)
+	e.notify(execution)
+}
+
+// request 发送 API 请求，result 不为 nil 时解析 JSON 响应
+func (e *GitLabCIEngine) request(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	req, err := e.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return gitlabError(resp)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// newRequest 创建带认证信息的 API 请求
+func (e *GitLabCIEngine) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	// 项目路径中的 / 已编码为 %2F，需要保留原始编码
+	req, err := http.NewRequestWithContext(ctx, method, e.config.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if e.config.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", e.config.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// gitlabProjectPath 生成项目接口路径，项目路径需要 URL 编码（例如 group%2Fproject）
+func gitlabProjectPath(repository string) string {
+	return "/projects/" + url.PathEscape(repository)
+}
+
+// gitlabError 从错误响应中提取错误信息，message 可能是字符串或对象
+func gitlabError(resp *http.Response) error {
+	var result struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &result) == nil {
+		if result.Message != nil {
+			return fmt.Errorf("GitLab API %s: %v", resp.Status, result.Message)
+		}
+		if result.Error != "" {
+			return fmt.Errorf("GitLab API %s: %s", resp.Status, result.Error)
+		}
+	}
+	return fmt.Errorf("GitLab API %s", resp.Status)
+}
+
+// gitlabPipelineFinished 判断 pipeline 是否已结束
+func gitlabPipelineFinished(status string) bool {
+	switch status {
+	case "success", "failed", "canceled", "skipped":
+		return true
+	default:
+		return false
+	}
+}
+
+// gitlabJobFinished 判断 job 是否已结束
+func gitlabJobFinished(status string) bool {
+	return gitlabPipelineFinished(status)
+}
+
+// gitlabStatus 将 pipeline 或 job 状态映射为执行状态
+func gitlabStatus(status string) string {
+	switch status {
+	case "success":
+		return StatusSuccess
+	case "failed":
+		return StatusFailed
+	case "canceled":
+		return StatusCancelled
+	case "skipped", "manual":
+		return StatusSkipped
+	case "running":
+		return StatusRunning
+	default:
+		// created、waiting_for_resource、preparing、pending、scheduled 均视为等待中
+		return StatusPending
+	}
+}
+
+// parseGitLabTrace 解析 job 日志，去除 ANSI 控制序列，
+// section_start 标记步骤开始，ERROR: 和 WARNING: 开头的行标记日志级别
+func parseGitLabTrace(reader io.Reader, jobName string) []LogEntry {
+	var entries []LogEntry
+	step := ""
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// 折叠区块标记格式为 section_start:<时间戳>:<名称>[<选项>]\r\x1b[0K<内容>
+		for _, marker := range []string{"section_start:", "section_end:"} {
+			index := strings.Index(line, marker)
+			if index < 0 {
+				continue
+			}
+			rest := line[index+len(marker):]
+			end := strings.Index(rest, "\r")
+			if end < 0 {
+				end = len(rest)
+			}
+			header := rest[:end]
+			if marker == "section_start:" {
+				if parts := strings.SplitN(header, ":", 2); len(parts) == 2 {
+					step = strings.SplitN(parts[1], "[", 2)[0]
+				}
+			}
+			line = line[:index] + rest[end:]
+		}
+
+		line = strings.TrimSpace(ansiEscape.ReplaceAllString(strings.ReplaceAll(line, "\r", ""), ""))
+		if line == "" {
+			continue
+		}
+
+		entry := LogEntry{Level: "info", Stage: jobName, Step: step, Message: line}
+		switch {
+		case strings.HasPrefix(line, "ERROR:"):
+			entry.Level = "error"
+		case strings.HasPrefix(line, "WARNING:"):
+			entry.Level = "warn"
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
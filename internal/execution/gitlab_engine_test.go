@@ -0,0 +1,289 @@
+package execution
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitLab 模拟 GitLab pipeline REST API
+type fakeGitLab struct {
+	mutex     sync.Mutex
+	polls     int
+	running   bool // 为 true 时 pipeline 一直处于运行中，直到被取消
+	cancelled bool
+	variables []gitlabVariable
+	failing   bool     // 为 true 时 test job 在重试之前失败
+	created   int      // 创建 pipeline 的次数
+	retries   []string // 重试请求的路径
+}
+
+func (f *fakeGitLab) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/v4/projects/{project}/pipeline", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"401 Unauthorized"}`))
+			return
+		}
+		if !strings.Contains(r.URL.EscapedPath(), "group%2Fapp") {
+			t.Errorf("项目路径未编码: %s", r.URL.EscapedPath())
+		}
+
+		var body struct {
+			Ref       string           `json:"ref"`
+			Variables []gitlabVariable `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Ref != "develop" {
+			t.Errorf("触发分支不匹配: %s", body.Ref)
+		}
+
+		f.mutex.Lock()
+		f.variables = body.Variables
+		f.created++
+		f.mutex.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 500, "iid": 12, "status": "created", "sha": "abc123", "web_url": "https://gitlab.example.com/group/app/-/pipelines/500"})
+	})
+
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines/500", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		f.polls++
+		status := "running"
+		switch {
+		case f.cancelled:
+			status = "canceled"
+		case f.polls > 1 && !f.running:
+			status = "success"
+			if f.failing && len(f.retries) == 0 {
+				status = "failed"
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 500, "iid": 12, "status": status})
+	})
+
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines/500/jobs", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		started := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		finished := time.Now().UTC().Format(time.RFC3339)
+		test := map[string]interface{}{"id": 2, "name": "test", "stage": "test", "status": "running", "started_at": started}
+		if f.polls > 1 && !f.running {
+			test["status"] = "success"
+			test["finished_at"] = finished
+			if f.failing && len(f.retries) == 0 {
+				test["status"] = "failed"
+			}
+		}
+		// 接口按 job ID 倒序返回
+		jobs := []map[string]interface{}{
+			test,
+			{"id": 1, "name": "build", "stage": "build", "status": "success", "started_at": started, "finished_at": finished},
+		}
+		json.NewEncoder(w).Encode(jobs)
+	})
+
+	mux.HandleFunc("GET /api/v4/projects/{project}/jobs/1/trace", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\x1b[0KRunning with gitlab-runner 16.0\x1b[0;m\n" +
+			"section_start:1700000000:step_script\r\x1b[0K\x1b[0K\x1b[36;1mExecuting \"step_script\" stage of the job script\x1b[0;m\n" +
+			"\x1b[32;1m$ go build ./...\x1b[0;m\n" +
+			"WARNING: cache not found\n" +
+			"section_end:1700000001:step_script\r\x1b[0K\n"))
+	})
+	mux.HandleFunc("GET /api/v4/projects/{project}/jobs/2/trace", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("section_start:1700000000:step_script\r\x1b[0K$ go test ./...\nok\n"))
+	})
+
+	retry := func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		f.polls = 0
+		f.retries = append(f.retries, r.URL.EscapedPath())
+		f.mutex.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 500, "iid": 12, "status": "running"})
+	}
+	mux.HandleFunc("POST /api/v4/projects/{project}/pipelines/500/retry", retry)
+	mux.HandleFunc("POST /api/v4/projects/{project}/jobs/{job}/retry", retry)
+
+	mux.HandleFunc("POST /api/v4/projects/{project}/pipelines/500/cancel", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		f.cancelled = true
+		f.mutex.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 500, "status": "canceled"})
+	})
+
+	return mux
+}
+
+func TestGitLabCIEngine(t *testing.T) {
+	fake := &fakeGitLab{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("gitlab_ci", NewGitLabCIEngineWithConfig(GitLabConfig{
+		BaseURL:      server.URL + "/api/v4/",
+		Token:        "test-token",
+		PollInterval: 10 * time.Millisecond,
+	}))
+
+	executionID, err := manager.CreateExecution("1", "gitlab_ci", "manual", ExecutionOptions{
+		Repository: "group/app",
+		Ref:        "develop",
+		Inputs:     map[string]string{"DEPLOY": "false"},
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusSuccess {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusSuccess, execution.Status)
+	}
+
+	if platformDataInt(execution.PlatformData, "pipeline_id") != 500 {
+		t.Errorf("pipeline ID 不匹配: %v", execution.PlatformData["pipeline_id"])
+	}
+
+	fake.mutex.Lock()
+	if len(fake.variables) != 1 || fake.variables[0].Key != "DEPLOY" {
+		t.Errorf("pipeline 变量不匹配: %v", fake.variables)
+	}
+	fake.mutex.Unlock()
+
+	if len(execution.Jobs) != 2 || execution.Jobs[0].Name != "build" || execution.Jobs[1].Status != StatusSuccess {
+		t.Errorf("job 状态不匹配: %+v", execution.Jobs)
+	}
+
+	// 检查 job 日志是否被下载并解析
+	var foundCommand, foundWarning bool
+	for _, log := range execution.Logs {
+		if strings.Contains(log.Message, "\x1b") || strings.Contains(log.Message, "section_") {
+			t.Errorf("日志未清理控制序列: %q", log.Message)
+		}
+		if log.Stage == "build" && log.Step == "step_script" && log.Message == "$ go build ./..." {
+			foundCommand = true
+		}
+		if log.Stage == "build" && log.Level == "warn" && log.Message == "WARNING: cache not found" {
+			foundWarning = true
+		}
+	}
+	if !foundCommand || !foundWarning {
+		t.Errorf("日志中未找到 job 输出: %v", execution.Logs)
+	}
+}
+
+func TestGitLabCIEngineRerun(t *testing.T) {
+	fake := &fakeGitLab{failing: true}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("gitlab_ci", NewGitLabCIEngineWithConfig(GitLabConfig{
+		BaseURL:      server.URL + "/api/v4",
+		Token:        "test-token",
+		PollInterval: 10 * time.Millisecond,
+	}))
+
+	executionID, err := manager.CreateExecution("1", "gitlab_ci", "manual", ExecutionOptions{
+		Repository: "group/app",
+		Ref:        "develop",
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	if first := waitForStatus(t, manager, executionID); first.Status != StatusFailed {
+		t.Fatalf("第一次尝试应失败: %s", first.Status)
+	}
+
+	// 重试原 pipeline 中失败的 job，不创建新的 pipeline
+	rerunID, err := manager.RerunExecution(executionID, RerunOptions{Mode: RerunFailed})
+	if err != nil {
+		t.Fatalf("重新运行失败的 job 失败: %v", err)
+	}
+	if err := manager.StartExecution(rerunID); err != nil {
+		t.Fatalf("启动重新运行的执行失败: %v", err)
+	}
+	second := waitForStatus(t, manager, rerunID)
+	if second.Status != StatusSuccess || platformDataInt(second.PlatformData, "pipeline_id") != 500 {
+		t.Fatalf("重试 pipeline 应成功: %s %v %s", second.Status, second.PlatformData["pipeline_id"], second.Reason)
+	}
+
+	// 重试指定的 job
+	thirdID, err := manager.RerunExecution(rerunID, RerunOptions{Mode: RerunJob, Job: "build"})
+	if err != nil {
+		t.Fatalf("重新运行指定的 job 失败: %v", err)
+	}
+	if err := manager.StartExecution(thirdID); err != nil {
+		t.Fatalf("启动重新运行的执行失败: %v", err)
+	}
+	if third := waitForStatus(t, manager, thirdID); third.Status != StatusSuccess {
+		t.Errorf("重试指定的 job 应成功: %s %s", third.Status, third.Reason)
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	expected := []string{"/api/v4/projects/group%2Fapp/pipelines/500/retry", "/api/v4/projects/group%2Fapp/jobs/1/retry"}
+	if len(fake.retries) != 2 || fake.retries[0] != expected[0] || fake.retries[1] != expected[1] {
+		t.Errorf("重试请求不匹配: %v", fake.retries)
+	}
+	if fake.created != 1 {
+		t.Errorf("部分重新运行不应创建新的 pipeline: %d", fake.created)
+	}
+}
+
+func TestGitLabCIEngineStop(t *testing.T) {
+	fake := &fakeGitLab{running: true}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("gitlab_ci", NewGitLabCIEngineWithConfig(GitLabConfig{
+		BaseURL:      server.URL + "/api/v4",
+		Token:        "test-token",
+		PollInterval: 10 * time.Millisecond,
+	}))
+
+	executionID, err := manager.CreateExecution("1", "gitlab_ci", "manual", ExecutionOptions{
+		Repository: "group/app",
+		Ref:        "develop",
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	if err := manager.StopExecution(executionID); err != nil {
+		t.Fatalf("停止执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusCancelled {
+		t.Errorf("执行状态不匹配: 期望 %s, 实际 %s", StatusCancelled, execution.Status)
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if !fake.cancelled {
+		t.Error("停止执行时未调用取消接口")
+	}
+}
@@ -0,0 +1,495 @@
+package execution
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// localCIConfigFiles 本地执行时在项目目录中查找 CI 配置文件的顺序
+var localCIConfigFiles = []string{
+	".github/workflows/ci.yml",
+	".github/workflows/ci.yaml",
+	".mock/workflows/ci.yaml",
+	".mock/workflows/ci.yml",
+	"mock-ci.yml",
+}
+
+// StepResult 本地执行的步骤结果
+type StepResult struct {
+	Job      string `json:"job"`
+	Step     string `json:"step"`
+	Attempt  int    `json:"attempt,omitempty"` // 重试的步骤每次尝试一条结果
+	ExitCode int    `json:"exit_code"`
+	Duration int64  `json:"duration_ms"` // 毫秒
+	Skipped  bool   `json:"skipped,omitempty"`
+}
+
+// LocalEngine 本地 Shell 执行引擎，在项目目录中以子进程方式真实运行 run 步骤
+type LocalEngine struct {
+	*baseEngine
+}
+
+// NewLocalEngine 创建本地 Shell 执行引擎实例
+func NewLocalEngine() Engine {
+	return &LocalEngine{
+		baseEngine: newBaseEngine(),
+	}
+}
+
+// Execute 在本地执行 CI 流程
+func (e *LocalEngine) Execute(ctx context.Context, executionID string, options ExecutionOptions) error {
+	if options.WorkDir == "" {
+		return fmt.Errorf("work dir is required for local execution")
+	}
+	if info, err := os.Stat(options.WorkDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("work dir does not exist: %s", options.WorkDir)
+	}
+
+	content := options.CIConfigContent
+	if content == "" {
+		var err error
+		content, err = LoadLocalCIConfig(options.WorkDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	config, graph, err := parseCIConfig(content)
+	if err != nil {
+		return err
+	}
+	if len(config.Jobs) == 0 {
+		return fmt.Errorf("CI config has no jobs")
+	}
+
+	e.mutex.Lock()
+	execution, exists := e.executions[executionID]
+	if !exists {
+		e.mutex.Unlock()
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+
+	// 更新状态为运行中
+	if err := e.transition(execution, StatusRunning, actorFrom(ctx, ActorEngine), ""); err != nil {
+		e.mutex.Unlock()
+		return err
+	}
+	runCtx, cancel := e.startRun(ctx, executionID)
+	e.initJobs(execution, graph)
+	e.notify(execution)
+	e.mutex.Unlock()
+
+	// 异步执行
+	go func() {
+		defer cancel()
+		e.run(runCtx, executionID, graph, options)
+		e.stopped(runCtx, executionID, e.Cancel)
+	}()
+
+	return nil
+}
+
+// reusesJobs 本地执行在服务内运行 job 图，重新运行时可以复用 job 结果
+func (e *LocalEngine) reusesJobs() {}
+
+// run 按依赖关系执行所有 job，互不依赖的 job 并发运行
+func (e *LocalEngine) run(ctx context.Context, executionID string, graph *jobGraph, options ExecutionOptions) {
+	var results []StepResult
+	var resultsMutex sync.Mutex
+
+	failed := e.runJobGraph(ctx, executionID, graph, options, func(ctx context.Context, jobName string, job Job) error {
+		jobResults, err := e.runJob(ctx, executionID, jobName, job, options.WorkDir)
+
+		resultsMutex.Lock()
+		results = append(results, jobResults...)
+		resultsMutex.Unlock()
+
+		return err
+	})
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	e.finish(executionID, failed, results, options.WorkDir)
+}
+
+// runJob 依次执行 job 的所有步骤，返回步骤结果
+func (e *LocalEngine) runJob(ctx context.Context, executionID, jobName string, job Job, workDir string) ([]StepResult, error) {
+	var results []StepResult
+	jobStart := time.Now()
+	e.addLog(executionID, "info", jobName, fmt.Sprintf("Starting job %s", jobName))
+
+	// 环境变量和步骤中引用上游 job 输出和之前步骤输出的表达式在运行前替换，job 结束时计算 job 的输出
+	steps := map[string]interface{}{}
+	outputContext := jobContext(job)
+	outputContext["needs"] = e.needsContext(executionID, job)
+	outputContext["steps"] = steps
+	defer func() {
+		if ctx.Err() == nil {
+			e.recordJobOutputs(executionID, jobName, job, outputContext)
+		}
+	}()
+
+	env, err := substituteOutputsMap(job.Env, outputContext)
+	if err != nil {
+		e.addLog(executionID, "error", jobName, fmt.Sprintf("Failed to evaluate env of job %s: %v", jobName, err))
+		return results, fmt.Errorf("invalid env: %v", err)
+	}
+	job.Env = env
+
+	for i, step := range job.Steps {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+
+		stepName := stepDisplayName(step, i)
+
+		// 本地引擎无法运行 Action，只执行 run 步骤
+		if step.Run == "" {
+			e.addLogWithStep(executionID, "warn", jobName, stepName, fmt.Sprintf("Skipping step %s: local engine only runs 'run' steps (uses: %s)", stepName, step.Uses))
+			e.skipStep(executionID, jobName, i)
+			results = append(results, StepResult{Job: jobName, Step: stepName, Skipped: true})
+			continue
+		}
+
+		run, err := substituteOutputs(step.Run, outputContext)
+		if err == nil {
+			step.Env, err = substituteOutputsMap(step.Env, outputContext)
+		}
+		if err != nil {
+			e.addLogWithStep(executionID, "error", jobName, stepName, fmt.Sprintf("Failed to evaluate step %s: %v", stepName, err))
+			e.updateStep(executionID, jobName, i, func(state *StepExecution) {
+				state.Status = StatusFailed
+			})
+			return results, fmt.Errorf("step %s: %v", stepName, err)
+		}
+		step.Run = run
+
+		// 每次尝试记录一条步骤结果，步骤的输出取最后一次尝试写入 $GITHUB_OUTPUT 的内容
+		var outputs map[string]string
+		err = e.runStepAttempts(ctx, executionID, jobName, i, job, func(ctx context.Context, attempt int) error {
+			outputFile, err := os.CreateTemp("", "step-output-")
+			if err != nil {
+				return err
+			}
+			outputFile.Close()
+			defer os.Remove(outputFile.Name())
+
+			e.addLogWithStep(executionID, "info", jobName, stepName, fmt.Sprintf("Starting step: %s", stepName))
+			stepStart := time.Now()
+			exitCode, err := e.runStep(ctx, executionID, jobName, stepName, job, step, workDir, outputFile.Name())
+			elapsed := time.Since(stepStart)
+			var outputErr error
+			outputs, outputErr = readStepOutputs(outputFile.Name())
+			if err == nil && outputErr != nil {
+				err = fmt.Errorf("invalid $GITHUB_OUTPUT: %v", outputErr)
+			}
+			results = append(results, StepResult{
+				Job:      jobName,
+				Step:     stepName,
+				Attempt:  attempt,
+				ExitCode: exitCode,
+				Duration: elapsed.Milliseconds(),
+			})
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				e.addLogWithStep(executionID, "error", jobName, stepName, fmt.Sprintf("Step %s failed: %v", stepName, err))
+				return fmt.Errorf("step %s failed: %v", stepName, err)
+			}
+
+			e.addLogWithStep(executionID, "info", jobName, stepName, fmt.Sprintf("Completed step: %s in %.2f seconds", stepName, elapsed.Seconds()))
+			return nil
+		})
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		if step.ID != "" {
+			outcome, conclusion := StatusSuccess, StatusSuccess
+			if err != nil {
+				outcome, conclusion = "failure", "failure"
+				if step.ContinueOnError.enabled(jobContext(job)) {
+					conclusion = StatusSuccess
+				}
+			}
+			steps[step.ID] = map[string]interface{}{"outputs": outputs, "outcome": outcome, "conclusion": conclusion}
+		}
+		if err != nil {
+			if step.ContinueOnError.enabled(jobContext(job)) {
+				e.addLogWithStep(executionID, "warn", jobName, stepName, fmt.Sprintf("Step %s failed, continuing because continue-on-error is set: %v", stepName, err))
+				continue
+			}
+			return results, err
+		}
+	}
+
+	e.addLog(executionID, "info", jobName, fmt.Sprintf("Completed job %s in %d seconds", jobName, int64(time.Since(jobStart).Seconds())))
+	return results, nil
+}
+
+// recordJobOutputs 按 job 配置的 outputs 计算并记录 job 的输出，无法计算的输出记录警告后忽略
+func (e *LocalEngine) recordJobOutputs(executionID, jobName string, job Job, context map[string]interface{}) {
+	if len(job.Outputs) == 0 {
+		return
+	}
+
+	outputs := make(map[string]string, len(job.Outputs))
+	for name, value := range job.Outputs {
+		result, err := interpolate(value, context)
+		if err != nil {
+			e.addLog(executionID, "warn", jobName, fmt.Sprintf("Failed to evaluate output %s of job %s: %v", name, jobName, err))
+			continue
+		}
+		outputs[name] = result
+	}
+	e.setJobOutputs(executionID, jobName, outputs)
+}
+
+// runStep 以子进程运行单个 run 步骤，并将 stdout/stderr 逐行写入日志，步骤通过 outputFile 对应的 $GITHUB_OUTPUT 设置输出
+func (e *LocalEngine) runStep(ctx context.Context, executionID, jobName, stepName string, job Job, step Step, workDir, outputFile string) (int, error) {
+	dir := workDir
+	if step.WorkingDirectory != "" {
+		dir = filepath.Join(workDir, step.WorkingDirectory)
+	}
+
+	shell, args, cleanup, err := shellCommand(step.Shell, step.Run)
+	if err != nil {
+		return -1, err
+	}
+	defer cleanup()
+
+	cmd := exec.CommandContext(ctx, shell, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CI=true", "GITHUB_OUTPUT="+outputFile)
+	for key, value := range job.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	for key, value := range step.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	// 与 GitHub Actions 的调试日志相同，步骤可以据此输出更详细的信息
+	if e.debugEnabled(executionID) {
+		cmd.Env = append(cmd.Env, "RUNNER_DEBUG=1", "ACTIONS_STEP_DEBUG=true")
+	}
+	e.addDebugLog(executionID, jobName, stepName, fmt.Sprintf("Running step %s with %s", stepName, shell), map[string]interface{}{
+		"args":        args,
+		"working_dir": dir,
+		"env":         envNames(job.Env, step.Env),
+	})
+
+	stdout := &outputWriter{engine: e, executionID: executionID, jobName: jobName, stepName: stepName, stream: "stdout"}
+	stderr := &outputWriter{engine: e, executionID: executionID, jobName: jobName, stepName: stepName, stream: "stderr"}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// 停止或超时时终止步骤启动的所有进程
+	configureProcessGroup(cmd)
+	// 子进程结束或被终止后，仍持有输出管道的后台进程最多再等待 processWaitDelay，避免步骤一直阻塞
+	cmd.WaitDelay = processWaitDelay
+
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	err = cmd.Wait()
+	stdout.flush()
+	stderr.flush()
+
+	exitCode := 0
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	e.addDebugLog(executionID, jobName, stepName, fmt.Sprintf("Step %s exited with code %d", stepName, exitCode), map[string]interface{}{
+		"exit_code": exitCode,
+	})
+	if errors.Is(err, exec.ErrWaitDelay) {
+		e.addLogWithStep(executionID, "warn", jobName, stepName, "Step exited but background processes kept its output open")
+		err = nil
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitCode, fmt.Errorf("exit code %d", exitCode)
+		}
+		return exitCode, err
+	}
+
+	return exitCode, nil
+}
+
+// envNames 返回 job 和 step 设置的环境变量名称，调试日志中不记录变量的值
+func envNames(envs ...map[string]string) []string {
+	var names []string
+	for _, env := range envs {
+		for name := range env {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// processWaitDelay 子进程结束后等待输出管道关闭的最长时间
+const processWaitDelay = 5 * time.Second
+
+// maxOutputLine 单条输出日志的最大长度，更长的行被拆分
+const maxOutputLine = 1024 * 1024
+
+// outputWriter 将子进程输出逐行写入日志
+type outputWriter struct {
+	engine      *LocalEngine
+	executionID string
+	jobName     string
+	stepName    string
+	stream      string
+	buffer      []byte
+}
+
+// Write 缓存输出并写入其中完整的行
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buffer[:i])
+		w.buffer = w.buffer[i+1:]
+	}
+	if len(w.buffer) >= maxOutputLine {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+// flush 写入缓存中剩余的不完整的行
+func (w *outputWriter) flush() {
+	if len(w.buffer) > 0 {
+		w.emit(w.buffer)
+		w.buffer = nil
+	}
+}
+
+// emit 写入一行输出
+func (w *outputWriter) emit(line []byte) {
+	w.engine.addLogEntry(w.executionID, LogEntry{
+		Level:   "info",
+		Stage:   w.jobName,
+		Step:    w.stepName,
+		Message: string(bytes.TrimSuffix(line, []byte("\r"))),
+		Context: map[string]interface{}{"stream": w.stream},
+	})
+}
+
+// finish 按失败的 job 结束执行并记录指标，执行已被取消时不再修改
+func (e *LocalEngine) finish(executionID string, failed []string, results []StepResult, workDir string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.cancels, executionID)
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return
+	}
+	stage, reason := "complete", ""
+	if len(failed) > 0 {
+		stage, reason = failed[0], fmt.Sprintf("failed jobs: %s", strings.Join(failed, ", "))
+	}
+	if e.transition(execution, outcome(execution, failed), ActorEngine, reason) != nil {
+		return
+	}
+
+	if len(failed) == 0 {
+		execution.Metrics.SuccessRate = 1.0
+		e.appendLog(execution, LogEntry{
+			Level:   "info",
+			Stage:   stage,
+			Message: fmt.Sprintf("Execution completed successfully in %d seconds", execution.Duration),
+		})
+	} else {
+		execution.Metrics.SuccessRate = 0.0
+		e.appendLog(execution, LogEntry{
+			Level:   "error",
+			Stage:   stage,
+			Message: fmt.Sprintf("Failed at %s stage: %s", stage, reason),
+		})
+	}
+
+	execution.Metrics.TotalDuration = execution.Duration
+	execution.Metrics.StageDurations = jobDurations(execution)
+
+	if execution.PlatformData == nil {
+		execution.PlatformData = map[string]interface{}{}
+	}
+	execution.PlatformData["work_dir"] = workDir
+	execution.PlatformData["steps"] = results
+	e.notify(execution)
+}
+
+// LoadLocalCIConfig 按 localCIConfigFiles 的顺序从项目目录中读取 CI 配置文件
+func LoadLocalCIConfig(workDir string) (string, error) {
+	for _, name := range localCIConfigFiles {
+		content, err := os.ReadFile(filepath.Join(workDir, name))
+		if err == nil {
+			return string(content), nil
+		}
+	}
+	return "", fmt.Errorf("no CI config found in %s", workDir)
+}
+
+// shellCommand 根据 shell 字段构建执行命令，默认使用 sh -e
+func shellCommand(shell, script string) (string, []string, func(), error) {
+	noop := func() {}
+
+	switch shell {
+	case "bash":
+		return "bash", []string{"--noprofile", "--norc", "-eo", "pipefail", "-c", script}, noop, nil
+	case "", "sh":
+		return "sh", []string{"-e", "-c", script}, noop, nil
+	}
+
+	// 自定义 shell 按 GitHub Actions 约定将脚本写入临时文件，并用文件路径替换 {0}
+	if !strings.Contains(shell, "{0}") {
+		return shell, []string{"-c", script}, noop, nil
+	}
+
+	file, err := os.CreateTemp("", "cicd-step-*")
+	if err != nil {
+		return "", nil, noop, err
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+	if _, err := file.WriteString(script); err != nil {
+		file.Close()
+		cleanup()
+		return "", nil, noop, err
+	}
+	file.Close()
+
+	return "sh", []string{"-c", strings.ReplaceAll(shell, "{0}", file.Name())}, cleanup, nil
+}
+
+// stepDisplayName 获取步骤显示名称
+func stepDisplayName(step Step, index int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	if step.Uses != "" {
+		return step.Uses
+	}
+	if step.Run != "" {
+		return strings.SplitN(strings.TrimSpace(step.Run), "\n", 2)[0]
+	}
+	return fmt.Sprintf("step-%d", index+1)
+}
@@ -0,0 +1,273 @@
+package execution
+
+import (
+	"testing"
+	"time"
+)
+
+// waitForStatus 等待执行进入结束状态
+func waitForStatus(t *testing.T, manager Manager, executionID string) *Execution {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		execution, err := manager.GetExecution(executionID)
+		if err != nil {
+			t.Fatalf("获取执行详情失败: %v", err)
+		}
+		if execution.Status != StatusPending && execution.Status != StatusQueued && execution.Status != StatusRunning {
+			return execution
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("等待执行结束超时")
+	return nil
+}
+
+func TestLocalEngine(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	config := `name: CI
+on: [push]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+    - uses: actions/checkout@v4
+    - name: Greet
+      run: echo "hello from $GREETING"
+      env:
+        GREETING: local
+    - name: Fail
+      run: exit 3
+`
+
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusFailed {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusFailed, execution.Status)
+	}
+
+	// 检查子进程输出是否写入日志
+	found := false
+	for _, log := range execution.Logs {
+		if log.Step == "Greet" && log.Message == "hello from local" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("日志中未找到步骤输出")
+	}
+
+	// 检查退出码
+	results, ok := execution.PlatformData["steps"].([]StepResult)
+	if !ok || len(results) != 3 {
+		t.Fatalf("步骤结果不匹配: %v", execution.PlatformData["steps"])
+	}
+	if !results[0].Skipped {
+		t.Error("uses 步骤应被跳过")
+	}
+	if results[2].ExitCode != 3 {
+		t.Errorf("退出码不匹配: 期望 3, 实际 %d", results[2].ExitCode)
+	}
+}
+
+func TestLocalEngineJobGraph(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	config := `jobs:
+  lint:
+    steps:
+    - run: echo lint
+  build:
+    steps:
+    - run: exit 1
+  test:
+    needs: build
+    steps:
+    - run: echo test
+  deploy:
+    needs: [lint, test]
+    steps:
+    - run: echo deploy
+`
+
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+		MaxParallel:     2,
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusFailed {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusFailed, execution.Status)
+	}
+
+	expected := map[string]string{
+		"lint":   StatusSuccess,
+		"build":  StatusFailed,
+		"test":   StatusSkipped,
+		"deploy": StatusSkipped,
+	}
+	if len(execution.Jobs) != len(expected) {
+		t.Fatalf("job 数量不匹配: %v", execution.Jobs)
+	}
+	for _, job := range execution.Jobs {
+		if job.Status != expected[job.Name] {
+			t.Errorf("job %s 状态不匹配: 期望 %s, 实际 %s", job.Name, expected[job.Name], job.Status)
+		}
+	}
+}
+
+func TestCreateExecutionRejectsCycle(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("mock", NewMockEngine())
+
+	config := `jobs:
+  a:
+    needs: c
+  b:
+    needs: a
+  c:
+    needs: b
+`
+
+	_, err := manager.CreateExecution("1", "mock", "manual", ExecutionOptions{CIConfigContent: config})
+	if err == nil {
+		t.Fatal("循环依赖应返回错误")
+	}
+	if err.Error() != "job dependency cycle detected: a -> c -> b -> a" {
+		t.Errorf("错误信息不匹配: %v", err)
+	}
+}
+
+func TestLocalEngineMatrix(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	config := `jobs:
+  test:
+    strategy:
+      fail-fast: false
+      max-parallel: 2
+      matrix:
+        go: ["1.21", "1.22"]
+        os: [linux, darwin]
+        exclude:
+        - go: "1.22"
+          os: darwin
+        include:
+        - go: "1.22"
+          experimental: true
+        - go: "1.23"
+          os: windows
+    steps:
+    - run: test "${{ matrix.go }}-${{ matrix.os }}" != "1.21-darwin"
+  report:
+    needs: test
+    steps:
+    - run: echo report
+`
+
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+
+	expected := map[string]string{
+		"test (1.21, linux)":   StatusSuccess,
+		"test (1.21, darwin)":  StatusFailed,
+		"test (1.22, linux)":   StatusSuccess,
+		"test (1.23, windows)": StatusSuccess,
+		"report":               StatusSkipped,
+	}
+	if len(execution.Jobs) != len(expected) {
+		t.Fatalf("job 数量不匹配: %v", execution.Jobs)
+	}
+	for _, job := range execution.Jobs {
+		if job.Status != expected[job.Name] {
+			t.Errorf("job %s 状态不匹配: 期望 %s, 实际 %s", job.Name, expected[job.Name], job.Status)
+		}
+		if job.Name == "test (1.22, linux)" && job.Matrix["experimental"] != true {
+			t.Errorf("include 未合并到组合: %v", job.Matrix)
+		}
+	}
+}
+
+func TestReleaseExecution(t *testing.T) {
+	engine := NewLocalEngine().(*LocalEngine)
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", engine)
+
+	config := `jobs:
+  build:
+    steps:
+    - run: sleep 0.3
+`
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	// 运行尚未退出时释放的执行在退出后才从内存中移除
+	engine.ReleaseExecution(executionID)
+	if _, err := engine.GetStatus(executionID); err != nil {
+		t.Fatalf("运行中的执行不应立即移除: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := engine.GetStatus(executionID); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("运行退出后执行应从内存中移除")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 没有运行的执行立即移除
+	pendingID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{CIConfigContent: config})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	engine.ReleaseExecution(pendingID)
+	if _, err := engine.GetStatus(pendingID); err == nil {
+		t.Error("没有运行的执行应立即从内存中移除")
+	}
+}
@@ -1,37 +1,142 @@
 package execution
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
+
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
 
 	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
 )
 
+// executionRegistrar 需要预先登记执行记录的引擎
+type executionRegistrar interface {
+	RegisterExecution(execution *Execution)
+}
+
+// recorderSetter 支持设置执行记录器的引擎
+type recorderSetter interface {
+	SetRecorder(recorder Recorder)
+}
+
+// statusSetter 支持设置尚未运行的执行状态的引擎
+type statusSetter interface {
+	SetStatus(executionID, status, actor, message string) error
+}
+
+// executionCanceller 支持以指定原因取消执行的引擎
+type executionCanceller interface {
+	Cancel(ctx context.Context, executionID, reason string) error
+}
+
+// executionReleaser 支持释放已结束执行的内存记录的引擎
+type executionReleaser interface {
+	ReleaseExecution(executionID string)
+}
+
+// jobGroupSetter 支持 job 级并发组的引擎
+type jobGroupSetter interface {
+	SetJobGroups(groups *jobGroups)
+}
+
+// executionSubscriber 支持订阅执行事件的引擎
+type executionSubscriber interface {
+	Subscribe(executionID, lastLogID string) (*Subscription, error)
+}
+
 // ManagerImpl 执行管理器实现
 type ManagerImpl struct {
-	engines    map[string]Engine
-	executions map[string]*Execution
-	mutex      sync.RWMutex
+	engines       map[string]Engine
+	executions    map[string]*Execution
+	options       map[string]ExecutionOptions
+	executionRepo *repository.ExecutionRepository
+	metricRepo    *repository.MetricRepository
+	writer        *recordWriter // 后台按顺序保存引擎回调的日志、状态变化和执行记录
+	jobGroups     *jobGroups    // 所有引擎共享的 job 级并发组
+	mutex         sync.RWMutex
+
+	// 执行队列，queueMutex 只保护队列状态，持有期间不调用引擎，避免与引擎回调 RecordExecution 死锁
+	queue         []*queueEntry
+	running       map[string]*runningEntry
+	limits        QueueLimits
+	nextSeq       int64 // 执行记录只保存在内存中时的入队顺序
+	queueMutex    sync.Mutex
+	dispatchMutex sync.Mutex // 同一时间只有一个调度过程启动执行
 }
 
-// NewManager 创建执行管理器实例
-func NewManager() Manager {
-	return &ManagerImpl{
-		engines:    make(map[string]Engine),
-		executions: make(map[string]*Execution),
+// NewManager 创建执行管理器实例，仓库为 nil 时执行记录只保存在内存中，否则已结束的执行从数据库查询。
+// 默认不限制并发，上次服务停止时执行队列中的执行在注册引擎后由 SetQueueLimits 或下一次调度启动
+func NewManager(executionRepo *repository.ExecutionRepository, metricRepo *repository.MetricRepository) Manager {
+	manager := &ManagerImpl{
+		engines:       make(map[string]Engine),
+		executions:    make(map[string]*Execution),
+		options:       make(map[string]ExecutionOptions),
+		executionRepo: executionRepo,
+		metricRepo:    metricRepo,
+		running:       make(map[string]*runningEntry),
+		jobGroups:     newJobGroups(),
 	}
+	if executionRepo != nil {
+		manager.writer = newRecordWriter(executionRepo)
+	}
+
+	// 恢复排队的执行，再将其他未结束的执行标记为失败
+	manager.restoreQueue()
+	manager.recoverInterrupted()
+
+	return manager
 }
 
 // RegisterEngine 注册执行引擎
 func (m *ManagerImpl) RegisterEngine(platform string, engine Engine) {
+	// 从执行队列中取该平台排队的执行，不读取可能被其他引擎同时修改的执行状态
+	m.queueMutex.Lock()
+	queued := make([]string, 0, len(m.queue))
+	for _, entry := range m.queue {
+		if entry.platform == platform {
+			queued = append(queued, entry.executionID)
+		}
+	}
+	m.queueMutex.Unlock()
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	m.engines[platform] = engine
+
+	// 引擎的日志和状态变化回调到管理器进行持久化
+	if setter, ok := engine.(recorderSetter); ok {
+		setter.SetRecorder(m)
+	}
+	if setter, ok := engine.(jobGroupSetter); ok {
+		setter.SetJobGroups(m.jobGroups)
+	}
+
+	// 登记从执行队列恢复的该平台的执行
+	if registrar, ok := engine.(executionRegistrar); ok {
+		for _, executionID := range queued {
+			if execution, exists := m.executions[executionID]; exists {
+				registrar.RegisterExecution(execution)
+			}
+		}
+	}
 }
 
 // CreateExecution 创建新的执行
 func (m *ManagerImpl) CreateExecution(projectID, platform, triggerType string, options ExecutionOptions) (string, error) {
+	return m.createExecution(projectID, platform, triggerType, options, nil)
+}
+
+// createExecution 创建新的执行，previous 不为 nil 时新执行是 previous 所在逻辑运行的下一次尝试
+func (m *ManagerImpl) createExecution(projectID, platform, triggerType string, options ExecutionOptions, previous *Execution) (string, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -41,39 +146,112 @@ func (m *ManagerImpl) CreateExecution(projectID, platform, triggerType string, o
 		return "", fmt.Errorf("unsupported platform: %s", platform)
 	}
 
+	// 运行前校验 job 依赖关系，循环依赖或依赖不存在的 job 直接返回错误
+	if content := ciConfigContent(options); content != "" {
+		if _, _, err := parseCIConfig(content); err != nil {
+			return "", err
+		}
+	}
+
+	// 持久化时项目 ID 必须是数字
+	if m.executionRepo != nil {
+		if _, err := strconv.Atoi(projectID); err != nil {
+			return "", fmt.Errorf("invalid project id: %s", projectID)
+		}
+	}
+
+	priority := options.Priority
+	if priority == "" {
+		priority = PriorityFor(triggerType)
+	}
+	if !ValidPriority(priority) {
+		return "", fmt.Errorf("invalid priority: %s", priority)
+	}
+
+	triggerInfo := options.TriggerInfo
+	if triggerInfo == nil {
+		triggerInfo = map[string]interface{}{}
+	}
+
 	// 创建执行记录
 	executionID := uuid.New().String()
 	execution := &Execution{
-		ID:           executionID,
-		ProjectID:    projectID,
-		Platform:     platform,
-		Status:       StatusPending,
-		TriggerType:  triggerType,
-		TriggerInfo:  map[string]interface{}{},
-		PlatformData: map[string]interface{}{},
+		ID:               executionID,
+		ProjectID:        projectID,
+		PipelineID:       options.PipelineID,
+		PipelineRevision: options.PipelineRevision,
+		Platform:         platform,
+		Status:           StatusPending,
+		Priority:         priority,
+		RunID:            executionID,
+		Attempt:          1,
+		Debug:            options.Debug,
+		TriggerType:      triggerType,
+		TriggerInfo:      triggerInfo,
+		PlatformData:     map[string]interface{}{},
 		Metrics: Metrics{
 			StageDurations: make(map[string]int64),
 		},
 		Logs: []LogEntry{},
 	}
 
+	if previous != nil {
+		attempt, err := m.latestAttempt(previous.RunID)
+		if err != nil {
+			return "", err
+		}
+		execution.RunID = previous.RunID
+		execution.Attempt = attempt + 1
+		execution.RerunOf = previous.ID
+	}
+
+	// 并发组表达式在入队时计算，这里提前校验
+	if _, _, _, err := concurrencyGroup(execution, options); err != nil {
+		return "", err
+	}
+
+	// 持久化执行记录
+	if m.executionRepo != nil {
+		record, err := toExecutionModel(execution)
+		if err != nil {
+			return "", err
+		}
+		// 保存创建时的选项，服务重启后也能重新运行
+		data, err := json.Marshal(options)
+		if err != nil {
+			return "", err
+		}
+		record.Options = string(data)
+		if err := m.executionRepo.Create(record); err != nil {
+			return "", fmt.Errorf("failed to save execution: %w", err)
+		}
+	}
+
 	// 存储执行记录
 	m.executions[executionID] = execution
+	m.options[executionID] = options
 
 	// 注册到对应的引擎
-	if mockEngine, ok := m.engines[platform].(*MockEngine); ok {
-		mockEngine.RegisterExecution(execution)
+	if registrar, ok := m.engines[platform].(executionRegistrar); ok {
+		registrar.RegisterExecution(execution)
 	}
 
 	return executionID, nil
 }
 
-// StartExecution 开始执行
+// StartExecution 将执行加入执行队列，并发数未达到限制时立即启动。
+// 执行在本次调用中启动失败时返回错误，此时执行被标记为失败
 func (m *ManagerImpl) StartExecution(executionID string) error {
 	m.mutex.RLock()
 	execution, exists := m.executions[executionID]
 	if !exists {
 		m.mutex.RUnlock()
+		// 已结束的执行已从内存中移除
+		if m.executionRepo != nil {
+			if record, err := m.executionRepo.GetByID(executionID); err == nil {
+				return fmt.Errorf("execution is not in pending status: %s", record.Status)
+			}
+		}
 		return fmt.Errorf("execution not found: %s", executionID)
 	}
 
@@ -82,36 +260,377 @@ func (m *ManagerImpl) StartExecution(executionID string) error {
 		m.mutex.RUnlock()
 		return fmt.Errorf("engine not found for platform: %s", execution.Platform)
 	}
+	options := m.options[executionID]
 	m.mutex.RUnlock()
 
-	// 检查执行状态
-	if execution.Status != StatusPending {
-		return fmt.Errorf("execution is not in pending status: %s", execution.Status)
+	// 检查执行状态，执行记录由引擎并发修改，从引擎获取副本
+	current, err := engine.GetStatus(executionID)
+	if err != nil {
+		return err
+	}
+	if current.Status != StatusPending {
+		return fmt.Errorf("execution is not in pending status: %s", current.Status)
 	}
 
-	// 构建执行选项
-	options := ExecutionOptions{
-		TotalDuration:   10,
-		StageDurations:  nil,
-		Result:          StatusSuccess,
-		GenerateMetrics: true,
-		GenerateLogs:    true,
-		ResourceUsage: ResourceUsage{
+	// 使用创建执行时的选项，没有设置结果和资源使用情况时使用默认值
+	if options.Result == "" {
+		options.Result = StatusSuccess
+	}
+	if options.ResourceUsage == (ResourceUsage{}) {
+		options.ResourceUsage = ResourceUsage{
 			CpuUsage:    50.0,
 			MemoryUsage: 60.0,
-		},
+		}
+	}
+
+	if err := m.enqueue(execution, options); err != nil {
+		return err
+	}
+
+	return m.dispatch()[executionID]
+}
+
+// enqueue 保存启动选项并将执行加入执行队列。配置了 concurrency 时，同一并发组中排队的执行被取代，
+// cancel-in-progress 为真时运行中的执行被取消，否则新执行等待其结束
+func (m *ManagerImpl) enqueue(execution *Execution, options ExecutionOptions) error {
+	group, groupName, cancelInProgress, err := concurrencyGroup(execution, options)
+	if err != nil {
+		return err
+	}
+	entry := &queueEntry{
+		executionID: execution.ID,
+		projectID:   execution.ProjectID,
+		platform:    execution.Platform,
+		priority:    execution.Priority,
+		enqueuedAt:  time.Now(),
+		group:       group,
+		groupName:   groupName,
+	}
+
+	// 先保存队列记录再修改状态，重启时没有队列记录的排队执行会被标记为失败
+	if m.executionRepo != nil {
+		data, err := json.Marshal(options)
+		if err != nil {
+			return err
+		}
+		item := &models.QueuedExecution{ExecutionID: execution.ID, Options: string(data), EnqueuedAt: entry.enqueuedAt}
+		if err := m.executionRepo.Enqueue(item); err != nil {
+			return fmt.Errorf("failed to enqueue execution: %w", err)
+		}
+		entry.seq = int64(item.ID)
+	}
+
+	m.mutex.Lock()
+	m.options[execution.ID] = options
+	m.mutex.Unlock()
+
+	message := fmt.Sprintf("Execution queued with %s priority", execution.Priority)
+	if groupName != "" {
+		message += fmt.Sprintf(" in concurrency group %s", groupName)
+	}
+	if err := m.setStatus(execution, StatusQueued, ActorQueue, message); err != nil {
+		if m.executionRepo != nil {
+			m.executionRepo.Dequeue(execution.ID)
+		}
+		return err
+	}
+
+	m.queueMutex.Lock()
+	if entry.seq == 0 {
+		m.nextSeq++
+		entry.seq = m.nextSeq
+	}
+	superseded, running := m.groupMembers(entry.group)
+	m.queue = append(m.queue, entry)
+	m.queueMutex.Unlock()
+
+	// 并发组中最多一个执行等待，排队中的旧执行被新执行取代
+	for _, previous := range superseded {
+		if m.executionRepo != nil {
+			if err := m.executionRepo.Dequeue(previous.executionID); err != nil {
+				log.Printf("移除队列记录失败 (%s): %v", previous.executionID, err)
+			}
+		}
+		m.mutex.RLock()
+		queued := m.executions[previous.executionID]
+		m.mutex.RUnlock()
+		reason := fmt.Sprintf("Execution cancelled: superseded by execution %s in concurrency group %s", execution.ID, groupName)
+		if err := m.setStatus(queued, StatusCancelled, ActorQueue, reason); err != nil {
+			log.Printf("取消并发组 %s 中排队的执行失败 (%s): %v", groupName, previous.executionID, err)
+		}
+	}
+
+	if cancelInProgress {
+		for _, executionID := range running {
+			reason := fmt.Sprintf("Execution cancelled: execution %s in concurrency group %s started with cancel-in-progress", execution.ID, groupName)
+			if err := m.cancel(executionID, reason); err != nil {
+				log.Printf("取消并发组 %s 中运行的执行失败 (%s): %v", groupName, executionID, err)
+			}
+		}
 	}
 
-	// 启动执行
-	return engine.Execute(executionID, options)
+	return nil
 }
 
-// StopExecution 停止执行
+// groupMembers 移除并返回并发组中排队的执行，并返回组中运行中的执行，调用方需持有 queueMutex
+func (m *ManagerImpl) groupMembers(group string) ([]*queueEntry, []string) {
+	if group == "" {
+		return nil, nil
+	}
+
+	var superseded []*queueEntry
+	queue := m.queue[:0]
+	for _, entry := range m.queue {
+		if entry.group == group {
+			superseded = append(superseded, entry)
+			continue
+		}
+		queue = append(queue, entry)
+	}
+	m.queue = queue
+
+	var running []string
+	for executionID, entry := range m.running {
+		if entry.group == group {
+			running = append(running, executionID)
+		}
+	}
+	return superseded, running
+}
+
+// cancel 以指定原因取消运行中的执行，引擎不支持指定原因时直接停止
+func (m *ManagerImpl) cancel(executionID, reason string) error {
+	m.mutex.RLock()
+	execution, exists := m.executions[executionID]
+	var engine Engine
+	if exists {
+		engine = m.engines[execution.Platform]
+	}
+	m.mutex.RUnlock()
+
+	if engine == nil {
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	ctx := WithActor(context.Background(), ActorQueue)
+	if canceller, ok := engine.(executionCanceller); ok {
+		return canceller.Cancel(ctx, executionID, reason)
+	}
+	return engine.Stop(ctx, executionID)
+}
+
+// ciConfigContent 获取执行使用的 CI 配置内容，没有提供时读取工作目录中的配置文件
+func ciConfigContent(options ExecutionOptions) string {
+	content := options.CIConfigContent
+	if content == "" && options.WorkDir != "" {
+		content, _ = LoadLocalCIConfig(options.WorkDir)
+	}
+	return content
+}
+
+// concurrencyGroup 计算执行的 workflow 级并发组，返回项目内的并发组、并发组名称和是否取消运行中的执行。
+// 没有配置 concurrency 时并发组为空
+func concurrencyGroup(execution *Execution, options ExecutionOptions) (string, string, bool, error) {
+	content := ciConfigContent(options)
+	if content == "" {
+		return "", "", false, nil
+	}
+
+	var config CIConfig
+	if err := yaml.Unmarshal([]byte(content), &config); err != nil || config.Concurrency == nil {
+		return "", "", false, nil
+	}
+
+	name, cancelInProgress, err := config.Concurrency.resolve(expressionContext(execution, options, config.Name, nil))
+	if err != nil || name == "" {
+		return "", "", false, err
+	}
+	return groupKey(execution.ProjectID, name), name, cancelInProgress, nil
+}
+
+// dispatch 按优先级和并发限制依次启动队列中可以启动的执行，返回启动失败的执行的错误
+func (m *ManagerImpl) dispatch() map[string]error {
+	m.dispatchMutex.Lock()
+	defer m.dispatchMutex.Unlock()
+
+	errs := make(map[string]error)
+	for {
+		m.mutex.RLock()
+		engines := make(map[string]bool, len(m.engines))
+		for platform := range m.engines {
+			engines[platform] = true
+		}
+		m.mutex.RUnlock()
+
+		m.queueMutex.Lock()
+		index := nextEntry(m.queue, newQueueCounts(m.running), m.limits, engines)
+		if index < 0 {
+			m.queueMutex.Unlock()
+			return errs
+		}
+		entry := m.queue[index]
+		m.queue = append(m.queue[:index], m.queue[index+1:]...)
+		m.running[entry.executionID] = &runningEntry{projectID: entry.projectID, platform: entry.platform, group: entry.group, startedAt: time.Now()}
+		m.queueMutex.Unlock()
+
+		if err := m.startQueued(entry.executionID); err != nil {
+			errs[entry.executionID] = err
+		}
+	}
+}
+
+// startQueued 启动从队列中取出的执行，启动失败时释放并发名额并将执行标记为失败
+func (m *ManagerImpl) startQueued(executionID string) error {
+	m.mutex.RLock()
+	execution := m.executions[executionID]
+	engine := m.engines[execution.Platform]
+	options := m.options[executionID]
+	m.mutex.RUnlock()
+
+	err := engine.Execute(WithActor(context.Background(), ActorQueue), executionID, options)
+
+	// 引擎已将状态持久化为运行中后再删除队列记录，重启时运行中的执行会被标记为失败
+	if m.executionRepo != nil {
+		if dequeueErr := m.executionRepo.Dequeue(executionID); dequeueErr != nil {
+			log.Printf("移除队列记录失败 (%s): %v", executionID, dequeueErr)
+		}
+	}
+
+	if err != nil {
+		m.release(executionID)
+		m.setStatus(execution, StatusFailed, ActorSystem, "Failed to start execution: "+err.Error())
+	}
+	return err
+}
+
+// release 释放执行占用的并发名额，返回执行是否由队列启动
+func (m *ManagerImpl) release(executionID string) bool {
+	m.queueMutex.Lock()
+	defer m.queueMutex.Unlock()
+
+	if _, exists := m.running[executionID]; !exists {
+		return false
+	}
+	delete(m.running, executionID)
+	return true
+}
+
+// removeQueued 将执行从队列中移除，返回执行是否在队列中
+func (m *ManagerImpl) removeQueued(executionID string) bool {
+	m.queueMutex.Lock()
+	defer m.queueMutex.Unlock()
+
+	for i, entry := range m.queue {
+		if entry.executionID == executionID {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// setStatus 通过引擎设置尚未运行的执行的状态，引擎不支持时直接修改并持久化。
+// 不合法的状态变化返回 TransitionError，例如执行已被取消
+func (m *ManagerImpl) setStatus(execution *Execution, status, actor, message string) error {
+	m.mutex.RLock()
+	engine := m.engines[execution.Platform]
+	m.mutex.RUnlock()
+
+	if setter, ok := engine.(statusSetter); ok {
+		err := setter.SetStatus(execution.ID, status, actor, message)
+		if err == nil || IsTransitionError(err) {
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	change, err := applyTransition(execution, status, actor, message)
+	snapshot := snapshotExecution(execution)
+	m.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	m.RecordTransition(execution.ID, change)
+	m.RecordExecution(snapshot)
+	return nil
+}
+
+// SetQueueLimits 设置并发限制并按新的限制启动队列中可以启动的执行
+func (m *ManagerImpl) SetQueueLimits(limits QueueLimits) {
+	m.queueMutex.Lock()
+	m.limits = limits
+	m.queueMutex.Unlock()
+
+	m.resumeQueue()
+}
+
+// resumeQueue 启动队列中可以启动的执行，启动失败的执行已被标记为失败，只记录日志
+func (m *ManagerImpl) resumeQueue() {
+	for executionID, err := range m.dispatch() {
+		log.Printf("启动排队的执行失败 (%s): %v", executionID, err)
+	}
+}
+
+// GetQueue 获取执行队列的状态，排队中的执行按预计的启动顺序排列
+func (m *ManagerImpl) GetQueue() *QueueSnapshot {
+	m.mutex.RLock()
+	engines := make(map[string]bool, len(m.engines))
+	for platform := range m.engines {
+		engines[platform] = true
+	}
+	m.mutex.RUnlock()
+
+	m.queueMutex.Lock()
+	queue := append([]*queueEntry(nil), m.queue...)
+	running := make(map[string]*runningEntry, len(m.running))
+	for executionID, entry := range m.running {
+		running[executionID] = entry
+	}
+	limits := m.limits
+	m.queueMutex.Unlock()
+
+	// 同一项目和平台的估计耗时只查询一次
+	durations := make(map[string]time.Duration)
+	duration := func(projectID, platform string) time.Duration {
+		key := projectID + "/" + platform
+		if _, ok := durations[key]; !ok {
+			durations[key] = m.estimatedDuration(projectID, platform)
+		}
+		return durations[key]
+	}
+
+	counts := newQueueCounts(running)
+	return &QueueSnapshot{
+		Limits:            limits,
+		Running:           counts.total,
+		RunningByProject:  counts.projects,
+		RunningByPlatform: counts.platforms,
+		Queued:            estimateQueue(queue, running, limits, engines, duration, time.Now()),
+	}
+}
+
+// findQueueStatus 获取排队中的执行在队列中的状态，执行不在队列中时返回 nil
+func findQueueStatus(queue *QueueSnapshot, executionID string) *QueueStatus {
+	for _, status := range queue.Queued {
+		if status.ExecutionID == executionID {
+			return status
+		}
+	}
+	return nil
+}
+
+// StopExecution 停止执行，排队中的执行直接从队列中移除并标记为已取消，
+// 运行中的执行由引擎取消正在运行的 job 和子进程
 func (m *ManagerImpl) StopExecution(executionID string) error {
 	m.mutex.RLock()
 	execution, exists := m.executions[executionID]
 	if !exists {
 		m.mutex.RUnlock()
+		// 已结束的执行已从内存中移除
+		if m.executionRepo != nil {
+			if record, err := m.executionRepo.GetByID(executionID); err == nil {
+				return fmt.Errorf("execution is not running: %s", record.Status)
+			}
+		}
 		return fmt.Errorf("execution not found: %s", executionID)
 	}
 
@@ -122,8 +641,17 @@ func (m *ManagerImpl) StopExecution(executionID string) error {
 	}
 	m.mutex.RUnlock()
 
+	if m.removeQueued(executionID) {
+		if m.executionRepo != nil {
+			if err := m.executionRepo.Dequeue(executionID); err != nil {
+				return fmt.Errorf("failed to dequeue execution: %w", err)
+			}
+		}
+		return m.setStatus(execution, StatusCancelled, ActorUser, "Execution cancelled while queued")
+	}
+
 	// 停止执行
-	return engine.Stop(executionID)
+	return engine.Stop(WithActor(context.Background(), ActorUser), executionID)
 }
 
 // GetExecution 获取执行详情
@@ -132,6 +660,10 @@ func (m *ManagerImpl) GetExecution(executionID string) (*Execution, error) {
 	execution, exists := m.executions[executionID]
 	if !exists {
 		m.mutex.RUnlock()
+		// 不在内存中的历史执行从数据库加载
+		if m.executionRepo != nil {
+			return m.loadExecution(executionID, true)
+		}
 		return nil, fmt.Errorf("execution not found: %s", executionID)
 	}
 
@@ -142,23 +674,140 @@ func (m *ManagerImpl) GetExecution(executionID string) (*Execution, error) {
 	}
 	m.mutex.RUnlock()
 
-	// 从引擎获取最新状态
-	return engine.GetStatus(executionID)
+	// 从引擎获取最新状态，执行在此期间结束并从内存中移除时从数据库加载
+	current, err := engine.GetStatus(executionID)
+	if err != nil {
+		if m.executionRepo != nil {
+			return m.loadExecution(executionID, true)
+		}
+		return nil, err
+	}
+	if current.Status == StatusQueued {
+		current.Queue = findQueueStatus(m.GetQueue(), executionID)
+	}
+	return current, nil
 }
 
-// ListExecutions 列出项目的执行历史
-func (m *ManagerImpl) ListExecutions(projectID string, limit, offset int) ([]*Execution, error) {
+// listedExecution 获取执行列表中的执行记录（不含日志）。执行记录在后台写入数据库，
+// 仍在内存中的执行从引擎获取最新状态，其他执行从数据库加载
+func (m *ManagerImpl) listedExecution(executionID string) (*Execution, error) {
 	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	var engine Engine
+	if execution, exists := m.executions[executionID]; exists {
+		engine = m.engines[execution.Platform]
+	}
+	m.mutex.RUnlock()
 
-	// 过滤出指定项目的执行
-	var executions []*Execution
-	for _, execution := range m.executions {
-		if execution.ProjectID == projectID {
+	if engine != nil {
+		if current, err := engine.GetStatus(executionID); err == nil {
+			current.Logs = nil
+			return current, nil
+		}
+	}
+	return m.loadExecution(executionID, false)
+}
+
+// SubscribeExecution 订阅执行的日志和状态事件，lastLogID 用于断线续传。
+// 引擎不支持订阅或执行已不在内存中时，只返回已有日志，事件通道立即关闭
+func (m *ManagerImpl) SubscribeExecution(executionID, lastLogID string) (*Subscription, error) {
+	m.mutex.RLock()
+	execution, exists := m.executions[executionID]
+	var engine Engine
+	if exists {
+		engine = m.engines[execution.Platform]
+	}
+	m.mutex.RUnlock()
+
+	if subscriber, ok := engine.(executionSubscriber); ok {
+		// 执行在此期间结束并从内存中移除时从数据库加载
+		subscription, err := subscriber.Subscribe(executionID, lastLogID)
+		if err == nil || m.executionRepo == nil {
+			return subscription, err
+		}
+	}
+
+	current, err := m.GetExecution(executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	close(events)
+	return &Subscription{
+		Execution: snapshotExecution(current),
+		Backlog:   logsAfter(current.Logs, lastLogID),
+		Events:    events,
+	}, nil
+}
+
+// ListExecutions 列出项目的执行历史，按开始时间倒序
+func (m *ManagerImpl) ListExecutions(projectID string, limit, offset int) ([]*Execution, error) {
+	if m.executionRepo != nil {
+		id, err := strconv.Atoi(projectID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project id: %s", projectID)
+		}
+
+		records, err := m.executionRepo.ListByProjectID(id, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		var queue *QueueSnapshot
+		executions := make([]*Execution, 0, len(records))
+		for _, record := range records {
+			execution, err := m.listedExecution(record.ID)
+			if err != nil {
+				return nil, err
+			}
+			if execution.Status == StatusQueued {
+				if queue == nil {
+					queue = m.GetQueue()
+				}
+				execution.Queue = findQueueStatus(queue, execution.ID)
+			}
 			executions = append(executions, execution)
 		}
+		return executions, nil
 	}
 
+	// 过滤出指定项目的执行，执行记录由引擎并发修改，从引擎获取副本
+	type candidate struct {
+		executionID string
+		engine      Engine
+	}
+	var candidates []candidate
+	m.mutex.RLock()
+	for executionID, execution := range m.executions {
+		if execution.ProjectID == projectID {
+			candidates = append(candidates, candidate{executionID: executionID, engine: m.engines[execution.Platform]})
+		}
+	}
+	m.mutex.RUnlock()
+
+	var queue *QueueSnapshot
+	executions := make([]*Execution, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.engine == nil {
+			continue
+		}
+		execution, err := candidate.engine.GetStatus(candidate.executionID)
+		if err != nil {
+			continue
+		}
+		if execution.Status == StatusQueued {
+			if queue == nil {
+				queue = m.GetQueue()
+			}
+			execution.Queue = findQueueStatus(queue, execution.ID)
+		}
+		executions = append(executions, execution)
+	}
+
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].StartTime.After(executions[j].StartTime)
+	})
+
 	// 应用分页
 	if offset >= len(executions) {
 		return []*Execution{}, nil
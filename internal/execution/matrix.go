@@ -0,0 +1,315 @@
+package execution
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// matrixExpression 匹配 ${{ matrix.<key> }} 表达式
+var matrixExpression = regexp.MustCompile(`\$\{\{\s*matrix\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// Strategy job 策略配置
+type Strategy struct {
+	Matrix      Matrix `yaml:"matrix,omitempty"`
+	FailFast    *bool  `yaml:"fail-fast,omitempty"`
+	MaxParallel int    `yaml:"max-parallel,omitempty"`
+}
+
+// Matrix 矩阵配置，保留维度的声明顺序用于生成 job 实例名称
+type Matrix struct {
+	Axes    []MatrixAxis
+	Include []map[string]interface{}
+	Exclude []map[string]interface{}
+	defined bool
+}
+
+// MatrixAxis 矩阵维度
+type MatrixAxis struct {
+	Name   string
+	Values []interface{}
+}
+
+// UnmarshalYAML 解析矩阵配置，include 和 exclude 之外的键均为矩阵维度
+func (m *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: strategy.matrix must be a mapping", value.Line)
+	}
+
+	m.defined = true
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key := value.Content[i].Value
+		node := value.Content[i+1]
+
+		switch key {
+		case "include":
+			if err := node.Decode(&m.Include); err != nil {
+				return fmt.Errorf("line %d: invalid matrix include: %w", node.Line, err)
+			}
+		case "exclude":
+			if err := node.Decode(&m.Exclude); err != nil {
+				return fmt.Errorf("line %d: invalid matrix exclude: %w", node.Line, err)
+			}
+		default:
+			var values []interface{}
+			if err := node.Decode(&values); err != nil {
+				return fmt.Errorf("line %d: matrix axis %s must be a list", node.Line, key)
+			}
+			m.Axes = append(m.Axes, MatrixAxis{Name: key, Values: values})
+		}
+	}
+
+	return nil
+}
+
+// IsZero 判断是否配置了矩阵
+func (m Matrix) IsZero() bool {
+	return !m.defined
+}
+
+// combinations 按 GitHub Actions 规则展开矩阵组合：
+// 先计算维度的笛卡尔积并去除 exclude 匹配的组合，再将 include 合并到不会覆盖原始维度值的组合中，
+// 无法合并的 include 作为新的组合追加
+func (m Matrix) combinations() []map[string]interface{} {
+	combos := []map[string]interface{}{}
+	if len(m.Axes) > 0 {
+		combos = append(combos, map[string]interface{}{})
+	}
+	for _, axis := range m.Axes {
+		var next []map[string]interface{}
+		for _, combo := range combos {
+			for _, value := range axis.Values {
+				expanded := make(map[string]interface{}, len(combo)+1)
+				for k, v := range combo {
+					expanded[k] = v
+				}
+				expanded[axis.Name] = value
+				next = append(next, expanded)
+			}
+		}
+		combos = next
+	}
+
+	// 去除 exclude 匹配的组合
+	var filtered []map[string]interface{}
+	for _, combo := range combos {
+		excluded := false
+		for _, exclude := range m.Exclude {
+			if matchesAll(combo, exclude) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, combo)
+		}
+	}
+
+	// 合并 include
+	axisNames := make(map[string]bool, len(m.Axes))
+	for _, axis := range m.Axes {
+		axisNames[axis.Name] = true
+	}
+	base := len(filtered)
+	for _, include := range m.Include {
+		merged := false
+		for _, combo := range filtered[:base] {
+			if !compatible(combo, include, axisNames) {
+				continue
+			}
+			for k, v := range include {
+				combo[k] = v
+			}
+			merged = true
+		}
+		if !merged {
+			extra := make(map[string]interface{}, len(include))
+			for k, v := range include {
+				extra[k] = v
+			}
+			filtered = append(filtered, extra)
+		}
+	}
+
+	return filtered
+}
+
+// matchesAll 判断组合是否包含 pattern 中的所有键值
+func matchesAll(combo, pattern map[string]interface{}) bool {
+	for k, v := range pattern {
+		if fmt.Sprint(combo[k]) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// compatible 判断 include 是否可以合并到组合中，即不会覆盖组合中原始维度的值
+func compatible(combo, include map[string]interface{}, axisNames map[string]bool) bool {
+	for k, v := range include {
+		if !axisNames[k] {
+			continue
+		}
+		if fmt.Sprint(combo[k]) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matrixGroup 矩阵 job 的展开信息
+type matrixGroup struct {
+	legs        []string
+	failFast    bool
+	maxParallel int
+}
+
+// expandMatrixJobs 将配置了矩阵的 job 展开为具体的 job 实例，needs 引用矩阵 job 时依赖其所有实例。
+// 返回展开后的 job 和矩阵分组信息
+func expandMatrixJobs(jobs map[string]Job) (map[string]Job, map[string]*matrixGroup, error) {
+	expanded := make(map[string]Job, len(jobs))
+	groups := make(map[string]*matrixGroup)
+	legsOf := make(map[string][]string, len(jobs))
+
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		job := jobs[name]
+		if job.Strategy.Matrix.IsZero() {
+			expanded[name] = job
+			legsOf[name] = []string{name}
+			continue
+		}
+
+		combos := job.Strategy.Matrix.combinations()
+		if len(combos) == 0 {
+			return nil, nil, fmt.Errorf("job %s matrix has no combinations", name)
+		}
+
+		group := &matrixGroup{
+			failFast:    job.Strategy.FailFast == nil || *job.Strategy.FailFast,
+			maxParallel: job.Strategy.MaxParallel,
+		}
+		for _, combo := range combos {
+			legName := matrixLegName(name, job.Strategy.Matrix, combo)
+			if _, exists := expanded[legName]; exists {
+				return nil, nil, fmt.Errorf("job %s matrix has duplicate combination %s", name, legName)
+			}
+			expanded[legName] = applyMatrix(job, name, combo)
+			group.legs = append(group.legs, legName)
+		}
+		groups[name] = group
+		legsOf[name] = group.legs
+	}
+
+	// 将对矩阵 job 的依赖替换为对其所有实例的依赖
+	for legName, job := range expanded {
+		if len(job.Needs) == 0 {
+			continue
+		}
+		var needs StringList
+		for _, need := range job.Needs {
+			legs, exists := legsOf[need]
+			if !exists {
+				needs = append(needs, need)
+				continue
+			}
+			needs = append(needs, legs...)
+		}
+		job.Needs = needs
+		expanded[legName] = job
+	}
+
+	return expanded, groups, nil
+}
+
+// matrixLegName 生成矩阵实例名称，例如 test (1.21, ubuntu-latest)
+func matrixLegName(name string, matrix Matrix, combo map[string]interface{}) string {
+	var values []string
+	seen := make(map[string]bool, len(combo))
+	for _, axis := range matrix.Axes {
+		if value, ok := combo[axis.Name]; ok {
+			values = append(values, fmt.Sprint(value))
+			seen[axis.Name] = true
+		}
+	}
+
+	// include 新增的组合没有原始维度，使用其余键的值
+	if len(values) == 0 {
+		keys := make([]string, 0, len(combo))
+		for key := range combo {
+			if !seen[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values = append(values, fmt.Sprint(combo[key]))
+		}
+	}
+
+	return fmt.Sprintf("%s (%s)", name, strings.Join(values, ", "))
+}
+
+// applyMatrix 生成矩阵实例，替换 ${{ matrix.* }} 表达式
+func applyMatrix(job Job, name string, combo map[string]interface{}) Job {
+	leg := job
+	leg.Strategy = Strategy{}
+	leg.MatrixJob = name
+	leg.MatrixValues = combo
+	leg.RunsOn = substituteMatrix(job.RunsOn, combo)
+	leg.Env = substituteMatrixMap(job.Env, combo)
+
+	leg.Steps = make([]Step, len(job.Steps))
+	for i, step := range job.Steps {
+		step.Name = substituteMatrix(step.Name, combo)
+		step.Run = substituteMatrix(step.Run, combo)
+		step.Env = substituteMatrixMap(step.Env, combo)
+		if step.With != nil {
+			with := make(WithData, len(step.With))
+			for k, v := range step.With {
+				if s, ok := v.(string); ok {
+					v = substituteMatrix(s, combo)
+				}
+				with[k] = v
+			}
+			step.With = with
+		}
+		leg.Steps[i] = step
+	}
+
+	return leg
+}
+
+// substituteMatrix 替换字符串中的 ${{ matrix.* }} 表达式，未定义的键替换为空字符串
+func substituteMatrix(s string, combo map[string]interface{}) string {
+	if !strings.Contains(s, "matrix.") {
+		return s
+	}
+	return matrixExpression.ReplaceAllStringFunc(s, func(expr string) string {
+		key := matrixExpression.FindStringSubmatch(expr)[1]
+		if value, ok := combo[key]; ok {
+			return fmt.Sprint(value)
+		}
+		return ""
+	})
+}
+
+// substituteMatrixMap 替换 map 值中的 ${{ matrix.* }} 表达式
+func substituteMatrixMap(values map[string]string, combo map[string]interface{}) map[string]string {
+	if values == nil {
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		result[k] = substituteMatrix(v, combo)
+	}
+	return result
+}
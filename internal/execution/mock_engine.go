@@ -1,33 +1,55 @@
 package execution
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
-	"sync"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
 )
 
+// mockDefaultStages 没有 CI 配置时模拟的默认阶段，按顺序依次依赖
+var mockDefaultStages = []string{"init", "build", "test", "deploy"}
+
 // CIConfig CI 配置结构
 type CIConfig struct {
-	Jobs map[string]Job `yaml:"jobs"`
+	Name        string         `yaml:"name,omitempty"`
+	Concurrency *Concurrency   `yaml:"concurrency,omitempty"`
+	Jobs        map[string]Job `yaml:"jobs"`
 }
 
 // Job 任务结构
 type Job struct {
-	RunsOn    string    `yaml:"runs-on,omitempty"`
-	Resources Resources `yaml:"resources,omitempty"`
-	Steps     []Step    `yaml:"steps,omitempty"`
+	RunsOn      string            `yaml:"runs-on,omitempty"`
+	Needs       StringList        `yaml:"needs,omitempty"`
+	Strategy    Strategy          `yaml:"strategy,omitempty"`
+	Concurrency *Concurrency      `yaml:"concurrency,omitempty"`
+	Timeout     Minutes           `yaml:"timeout-minutes,omitempty"` // job 的超时时间，没有配置时为 DefaultJobTimeout
+	Env         map[string]string `yaml:"env,omitempty"`
+	Outputs     map[string]string `yaml:"outputs,omitempty"` // job 的输出，可以引用 steps.<id>.outputs
+	Resources   Resources         `yaml:"resources,omitempty"`
+	Steps       []Step            `yaml:"steps,omitempty"`
+
+	// 矩阵展开后的实例所属的矩阵 job 名称和组合取值
+	MatrixJob    string                 `yaml:"-"`
+	MatrixValues map[string]interface{} `yaml:"-"`
 }
 
 // Step 步骤结构
 type Step struct {
-	Name string   `yaml:"name"`
-	Run  string   `yaml:"run"`
-	Uses string   `yaml:"uses,omitempty"`
-	With WithData `yaml:"with,omitempty"`
+	ID               string            `yaml:"id,omitempty"` // 同一 job 中的后续步骤通过 steps.<id> 引用
+	Name             string            `yaml:"name"`
+	Run              string            `yaml:"run"`
+	Uses             string            `yaml:"uses,omitempty"`
+	With             WithData          `yaml:"with,omitempty"`
+	Env              map[string]string `yaml:"env,omitempty"`
+	Shell            string            `yaml:"shell,omitempty"`
+	WorkingDirectory string            `yaml:"working-directory,omitempty"`
+	Timeout          Minutes           `yaml:"timeout-minutes,omitempty"`   // 每次尝试的超时时间，没有配置时不限制
+	ContinueOnError  Flag              `yaml:"continue-on-error,omitempty"` // 失败后 job 继续运行
+	Retry            *RetryPolicy      `yaml:"retry,omitempty"`             // 失败后自动重试，没有配置时不重试
 }
 
 // WithData 步骤参数结构
@@ -47,19 +69,23 @@ type ResourceLimit struct {
 
 // MockEngine Mock CI 执行引擎
 type MockEngine struct {
-	executions map[string]*Execution
-	mutex      sync.RWMutex
+	*baseEngine
 }
 
 // NewMockEngine 创建 Mock CI 执行引擎实例
 func NewMockEngine() Engine {
 	return &MockEngine{
-		executions: make(map[string]*Execution),
+		baseEngine: newBaseEngine(),
 	}
 }
 
 // Execute 执行模拟 CI 流程
-func (e *MockEngine) Execute(executionID string, options ExecutionOptions) error {
+func (e *MockEngine) Execute(ctx context.Context, executionID string, options ExecutionOptions) error {
+	graph, err := mockJobGraph(options.CIConfigContent)
+	if err != nil {
+		return err
+	}
+
 	e.mutex.Lock()
 	execution, exists := e.executions[executionID]
 	if !exists {
@@ -68,18 +94,51 @@ func (e *MockEngine) Execute(executionID string, options ExecutionOptions) error
 	}
 
 	// 更新状态为运行中
-	execution.Status = StatusRunning
-	execution.StartTime = time.Now()
+	if err := e.transition(execution, StatusRunning, actorFrom(ctx, ActorEngine), ""); err != nil {
+		e.mutex.Unlock()
+		return err
+	}
+	runCtx, cancel := e.startRun(ctx, executionID)
+	e.initJobs(execution, graph)
+	e.notify(execution)
 	e.mutex.Unlock()
 
 	// 异步执行模拟流程
 	go func() {
-		e.simulateExecution(executionID, options)
+		defer cancel()
+		e.simulateExecution(runCtx, executionID, graph, options)
+		e.stopped(runCtx, executionID, e.Cancel)
 	}()
 
 	return nil
 }
 
+// reusesJobs 模拟执行在服务内运行 job 图，重新运行时可以复用 job 结果
+func (e *MockEngine) reusesJobs() {}
+
+// mockJobGraph 构建模拟执行的 job 依赖图，没有 CI 配置或配置中没有 job 时使用默认阶段
+func mockJobGraph(ciConfigContent string) (*jobGraph, error) {
+	if ciConfigContent != "" {
+		config, graph, err := parseCIConfig(ciConfigContent)
+		if err != nil {
+			return nil, err
+		}
+		if len(config.Jobs) > 0 {
+			return graph, nil
+		}
+	}
+
+	jobs := make(map[string]Job, len(mockDefaultStages))
+	for i, stage := range mockDefaultStages {
+		job := Job{}
+		if i > 0 {
+			job.Needs = StringList{mockDefaultStages[i-1]}
+		}
+		jobs[stage] = job
+	}
+	return buildJobGraph(jobs)
+}
+
 // parseMemory 解析内存字符串为字节数
 func parseMemory(memoryStr string) int64 {
 	if memoryStr == "" {
@@ -117,269 +176,178 @@ func parseCPU(cpu interface{}) float64 {
 	}
 }
 
-// Stop 停止执行
-func (e *MockEngine) Stop(executionID string) error {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	execution, exists := e.executions[executionID]
-	if !exists {
-		return fmt.Errorf("execution not found: %s", executionID)
-	}
+// simulateExecution 按依赖关系模拟运行所有 job
+func (e *MockEngine) simulateExecution(ctx context.Context, executionID string, graph *jobGraph, options ExecutionOptions) {
+	failed := e.runJobGraph(ctx, executionID, graph, options, func(ctx context.Context, name string, job Job) error {
+		return e.simulateJob(ctx, executionID, name, job, jobDuration(name, job, len(graph.jobs), options), options)
+	})
 
-	if execution.Status != StatusRunning {
-		return fmt.Errorf("execution is not running: %s", execution.Status)
+	// 已被停止
+	if ctx.Err() != nil {
+		return
 	}
 
-	// 更新状态为已取消
-	execution.Status = StatusCancelled
-	execution.EndTime = time.Now()
-	execution.Duration = int64(time.Since(execution.StartTime).Seconds())
-
-	// 添加取消日志
-	if execution.Logs == nil {
-		execution.Logs = []LogEntry{}
+	if len(failed) > 0 {
+		reason := options.FailureReason
+		if len(failed) > 1 || reason == "" {
+			reason = fmt.Sprintf("failed jobs: %s", strings.Join(failed, ", "))
+		}
+		e.failExecution(executionID, failed, reason, options.CIConfigContent)
+		return
 	}
-	execution.Logs = append(execution.Logs, LogEntry{
-		ID:          uuid.New().String(),
-		ExecutionID: executionID,
-		Timestamp:   time.Now(),
-		Level:       "info",
-		Stage:       "cancellation",
-		Message:     "Execution cancelled by user",
-	})
 
-	return nil
+	// 执行成功完成
+	e.completeExecution(executionID, options)
 }
 
-// GetStatus 获取执行状态
-func (e *MockEngine) GetStatus(executionID string) (*Execution, error) {
-	e.mutex.RLock()
-	defer e.mutex.RUnlock()
-
-	execution, exists := e.executions[executionID]
-	if !exists {
-		return nil, fmt.Errorf("execution not found: %s", executionID)
+// jobDuration 模拟的 job 时长。StageDurations 可以按 job 名称或矩阵 job 名称设置秒数，
+// 没有设置时将 TotalDuration 平均分配给每个 job，都没有设置时返回 -1，由各步骤随机决定时长
+func jobDuration(name string, job Job, jobCount int, options ExecutionOptions) time.Duration {
+	if seconds, ok := options.StageDurations[name]; ok {
+		return time.Duration(seconds) * time.Second
 	}
-
-	// 返回执行的副本，避免并发修改问题
-	copy := *execution
-	if execution.Logs != nil {
-		copy.Logs = make([]LogEntry, len(execution.Logs))
-		for i, log := range execution.Logs {
-			copy.Logs[i] = log
-		}
+	if seconds, ok := options.StageDurations[job.MatrixJob]; ok && job.MatrixJob != "" {
+		return time.Duration(seconds) * time.Second
 	}
-
-	return &copy, nil
+	if options.TotalDuration > 0 && jobCount > 0 {
+		return time.Duration(options.TotalDuration) * time.Second / time.Duration(jobCount)
+	}
+	return -1
 }
 
-// RegisterExecution 注册执行记录
-func (e *MockEngine) RegisterExecution(execution *Execution) {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	e.executions[execution.ID] = execution
-}
+// simulateJob 模拟运行单个 job 的所有 step，duration 为负数时使用随机时长
+func (e *MockEngine) simulateJob(ctx context.Context, executionID, name string, job Job, duration time.Duration, options ExecutionOptions) error {
+	// 添加 job 开始日志
+	e.addLog(executionID, "info", name, fmt.Sprintf("Starting %s stage", name))
+	start := time.Now()
+
+	// 失败阶段可以是 job 名称，也可以是矩阵 job 名称（所有实例都失败）
+	shouldFail := options.Result == StatusFailed && (options.FailureStage == name || (job.MatrixJob != "" && options.FailureStage == job.MatrixJob))
+	simulated := "random"
+	if duration >= 0 {
+		simulated = duration.String()
+	}
+	e.addDebugLog(executionID, name, "", fmt.Sprintf("Simulating job %s", name), map[string]interface{}{
+		"duration":      simulated,
+		"fail":          shouldFail,
+		"failure_stage": options.FailureStage,
+	})
+	failure := fmt.Errorf("simulated failure")
+	if options.FailureReason != "" {
+		failure = fmt.Errorf("%s", options.FailureReason)
+	}
 
-// simulateExecution 模拟执行流程
-func (e *MockEngine) simulateExecution(executionID string, options ExecutionOptions) {
-	// 模拟阶段执行
-	stages := []string{"init", "build", "test", "deploy", "complete"}
-	stageEndTimes := make(map[string]time.Time)
-
-	for _, stage := range stages {
-		// 检查是否已被停止
-		e.mutex.RLock()
-		currentExecution := e.executions[executionID]
-		if currentExecution.Status == StatusCancelled {
-			e.mutex.RUnlock()
-			return
+	if len(job.Steps) == 0 {
+		// 没有 step 的 job 直接模拟执行时长
+		if duration < 0 {
+			duration = time.Duration(1+rand.Intn(3)) * time.Second // 1-3 秒
 		}
-		e.mutex.RUnlock()
-
-		// 添加阶段开始日志
-		e.addLog(executionID, "info", stage, fmt.Sprintf("Starting %s stage", stage))
-
-		// 模拟阶段执行
-		duration := 1 + rand.Intn(3) // 1-4 秒
-
-		// 如果是build或test阶段，执行多个step
-		if (stage == "build" || stage == "test") && options.CIConfigContent != "" {
-			// 解析CI配置
-			var config CIConfig
-			err := yaml.Unmarshal([]byte(options.CIConfigContent), &config)
-			if err == nil && len(config.Jobs) > 0 {
-				// 执行第一个job的所有steps
-				for _, job := range config.Jobs {
-					if len(job.Steps) > 0 {
-						// 执行每个step
-						for i, step := range job.Steps {
-							// 添加step开始日志
-							e.addLogWithStep(executionID, "info", stage, fmt.Sprintf("step-%d", i+1), fmt.Sprintf("Starting step: %s", step.Name))
-
-							// 模拟step执行
-							stepDuration := 1 + rand.Intn(2) // 1-3 秒
-							time.Sleep(time.Duration(stepDuration) * time.Second)
-
-							// 添加step完成日志
-							e.addLogWithStep(executionID, "info", stage, fmt.Sprintf("step-%d", i+1), fmt.Sprintf("Completed step: %s in %d seconds", step.Name, stepDuration))
-
-							// 检查是否需要模拟失败
-							if options.Result == StatusFailed && options.FailureStage == stage {
-								e.failExecution(executionID, stage, options.FailureReason, options.CIConfigContent)
-								return
-							}
-						}
-						continue
-					}
-				}
-			}
-		} else {
-			// 普通阶段执行
-			time.Sleep(time.Duration(duration) * time.Second)
+		if !sleepContext(ctx, duration) {
+			return ctx.Err()
 		}
-
-		stageEndTimes[stage] = time.Now()
-
-		// 检查是否需要模拟失败
-		if options.Result == StatusFailed && options.FailureStage == stage {
-			e.failExecution(executionID, stage, options.FailureReason, options.CIConfigContent)
-			return
+		if shouldFail {
+			return failure
 		}
-
-		// 添加阶段完成日志
-		e.addLog(executionID, "info", stage, fmt.Sprintf("Completed %s stage in %d seconds", stage, duration))
 	}
 
-	// 执行成功完成
-	e.completeExecution(executionID, stageEndTimes, options)
-}
-
-// addLogWithStep 添加带步骤信息的日志条目
-func (e *MockEngine) addLogWithStep(executionID, level, stage, step, message string) {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
+	for i, step := range job.Steps {
+		stepName := fmt.Sprintf("step-%d", i+1)
 
-	execution, exists := e.executions[executionID]
-	if !exists {
-		return
-	}
-
-	if execution.Logs == nil {
-		execution.Logs = []LogEntry{}
-	}
+		// 模拟 step 执行，设置了 job 时长时平均分配给每个 step
+		stepDuration := time.Duration(1+rand.Intn(2)) * time.Second // 1-2 秒
+		if duration >= 0 {
+			stepDuration = duration / time.Duration(len(job.Steps))
+		}
 
-	execution.Logs = append(execution.Logs, LogEntry{
-		ID:          uuid.New().String(),
-		ExecutionID: executionID,
-		Timestamp:   time.Now(),
-		Level:       level,
-		Stage:       stage,
-		Step:        step,
-		Message:     message,
-	})
-}
+		err := e.runStepAttempts(ctx, executionID, name, i, job, func(ctx context.Context, attempt int) error {
+			// 添加 step 开始日志
+			e.addLogWithStep(executionID, "info", name, stepName, fmt.Sprintf("Starting step: %s", step.Name))
 
-// addLog 添加日志条目
-func (e *MockEngine) addLog(executionID, level, stage, message string) {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
+			if !sleepContext(ctx, stepDuration) {
+				return ctx.Err()
+			}
 
-	execution, exists := e.executions[executionID]
-	if !exists {
-		return
-	}
+			// 添加 step 完成日志
+			e.addLogWithStep(executionID, "info", name, stepName, fmt.Sprintf("Completed step: %s in %d seconds", step.Name, int64(stepDuration.Seconds())))
 
-	if execution.Logs == nil {
-		execution.Logs = []LogEntry{}
+			// 检查是否需要模拟失败
+			if shouldFail {
+				e.addLogWithStep(executionID, "error", name, stepName, fmt.Sprintf("Step %s failed: %v", step.Name, failure))
+				return failure
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if step.ContinueOnError.enabled(jobContext(job)) {
+				e.addLogWithStep(executionID, "warn", name, stepName, fmt.Sprintf("Step %s failed, continuing because continue-on-error is set: %v", step.Name, err))
+				continue
+			}
+			return err
+		}
 	}
 
-	execution.Logs = append(execution.Logs, LogEntry{
-		ID:          uuid.New().String(),
-		ExecutionID: executionID,
-		Timestamp:   time.Now(),
-		Level:       level,
-		Stage:       stage,
-		Message:     message,
-	})
+	// 添加 job 完成日志
+	e.addLog(executionID, "info", name, fmt.Sprintf("Completed %s stage in %d seconds", name, int64(time.Since(start).Seconds())))
+	return nil
 }
 
-// failExecution 模拟执行失败
-func (e *MockEngine) failExecution(executionID, stage, reason string, ciConfigContent string) {
+// failExecution 模拟执行失败，失败的 job 都超时时执行为 timed_out。执行已被取消时不再修改
+func (e *MockEngine) failExecution(executionID string, failed []string, reason string, ciConfigContent string) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	delete(e.cancels, executionID)
+
 	execution, exists := e.executions[executionID]
-	if !exists {
+	if !exists || e.transition(execution, outcome(execution, failed), ActorEngine, reason) != nil {
 		return
 	}
-
-	// 更新状态为失败
-	execution.Status = StatusFailed
-	execution.EndTime = time.Now()
-	execution.Duration = int64(time.Since(execution.StartTime).Seconds())
+	stage := failed[0]
 
 	// 添加失败日志
-	if execution.Logs == nil {
-		execution.Logs = []LogEntry{}
-	}
-	execution.Logs = append(execution.Logs, LogEntry{
-		ID:          uuid.New().String(),
-		ExecutionID: executionID,
-		Timestamp:   time.Now(),
-		Level:       "error",
-		Stage:       stage,
-		Message:     fmt.Sprintf("Failed at %s stage: %s", stage, reason),
+	e.appendLog(execution, LogEntry{
+		Level:   "error",
+		Stage:   stage,
+		Message: fmt.Sprintf("Failed at %s stage: %s", stage, reason),
 	})
 
 	// 生成失败指标
 	e.generateMetrics(execution, false, ciConfigContent)
+	e.notify(execution)
 }
 
-// completeExecution 模拟执行成功完成
-func (e *MockEngine) completeExecution(executionID string, stageEndTimes map[string]time.Time, options ExecutionOptions) {
+// completeExecution 模拟执行成功完成，执行已被取消时不再修改
+func (e *MockEngine) completeExecution(executionID string, options ExecutionOptions) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	delete(e.cancels, executionID)
+
 	execution, exists := e.executions[executionID]
-	if !exists {
+	if !exists || e.transition(execution, StatusSuccess, ActorEngine, "") != nil {
 		return
 	}
 
-	// 更新状态为成功
-	execution.Status = StatusSuccess
-	execution.EndTime = time.Now()
-	execution.Duration = int64(time.Since(execution.StartTime).Seconds())
-
 	// 添加完成日志
-	if execution.Logs == nil {
-		execution.Logs = []LogEntry{}
-	}
-	execution.Logs = append(execution.Logs, LogEntry{
-		ID:          uuid.New().String(),
-		ExecutionID: executionID,
-		Timestamp:   time.Now(),
-		Level:       "info",
-		Stage:       "complete",
-		Message:     fmt.Sprintf("Execution completed successfully in %d seconds", execution.Duration),
+	e.appendLog(execution, LogEntry{
+		Level:   "info",
+		Stage:   "complete",
+		Message: fmt.Sprintf("Execution completed successfully in %d seconds", execution.Duration),
 	})
 
 	// 生成成功指标
 	e.generateMetrics(execution, true, options.CIConfigContent)
+	e.notify(execution)
 }
 
 // generateMetrics 生成执行指标
 func (e *MockEngine) generateMetrics(execution *Execution, success bool, ciConfigContent string) {
 	// 基础指标
 	execution.Metrics.TotalDuration = execution.Duration
-	execution.Metrics.StageDurations = map[string]int64{
-		"init":     2,
-		"build":    3,
-		"test":     3,
-		"deploy":   1,
-		"complete": 1,
-	}
+	execution.Metrics.StageDurations = jobDurations(execution)
 
 	// 成功/失败率
 	if success {
@@ -0,0 +1,84 @@
+package execution
+
+import (
+	"testing"
+)
+
+func TestMockEngineOptions(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("mock", NewMockEngine())
+
+	config := `jobs:
+  build:
+    steps:
+    - name: Build
+      run: go build ./...
+  test:
+    needs: build
+    steps:
+    - name: Test
+      run: go test ./...
+`
+
+	// 创建执行时的结果、失败阶段和时长应传递给引擎
+	executionID, err := manager.CreateExecution("1", "mock", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		Result:          StatusFailed,
+		FailureStage:    "test",
+		FailureReason:   "tests failed",
+		StageDurations:  map[string]int{"build": 0, "test": 0},
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusFailed {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusFailed, execution.Status)
+	}
+	for _, job := range execution.Jobs {
+		if job.Name == "build" && job.Status != StatusSuccess {
+			t.Errorf("build 应成功: %+v", job)
+		}
+	}
+	last := execution.Logs[len(execution.Logs)-1]
+	if last.Stage != "test" || last.Message != "Failed at test stage: tests failed" {
+		t.Errorf("失败日志不匹配: %+v", last)
+	}
+
+	// 没有设置结果时默认成功
+	executionID, err = manager.CreateExecution("1", "mock", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		StageDurations:  map[string]int{"build": 0, "test": 0},
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	if execution := waitForStatus(t, manager, executionID); execution.Status != StatusSuccess {
+		t.Errorf("执行状态不匹配: 期望 %s, 实际 %s", StatusSuccess, execution.Status)
+	}
+}
+
+func TestJobDuration(t *testing.T) {
+	job := Job{MatrixJob: "test"}
+	options := ExecutionOptions{TotalDuration: 12, StageDurations: map[string]int{"build": 3, "test": 5}}
+
+	if d := jobDuration("build", Job{}, 4, options); d.Seconds() != 3 {
+		t.Errorf("应使用 job 的时长: %v", d)
+	}
+	if d := jobDuration("test (go-1.22)", job, 4, options); d.Seconds() != 5 {
+		t.Errorf("应使用矩阵 job 的时长: %v", d)
+	}
+	if d := jobDuration("deploy", Job{}, 4, options); d.Seconds() != 3 {
+		t.Errorf("应平均分配总时长: %v", d)
+	}
+	if d := jobDuration("deploy", Job{}, 4, ExecutionOptions{}); d >= 0 {
+		t.Errorf("没有设置时长时应返回负数: %v", d)
+	}
+}
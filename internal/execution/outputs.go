@@ -0,0 +1,145 @@
+package execution
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// outputExpression 匹配字符串中引用 needs 或 steps 上下文的 ${{ }} 表达式
+var outputExpression = regexp.MustCompile(`\$\{\{\s*((?i:needs|steps)\..*?)\}\}`)
+
+// substituteOutputs 替换字符串中引用 needs 和 steps 上下文的表达式，其他表达式保持不变
+func substituteOutputs(s string, context map[string]interface{}) (string, error) {
+	if !strings.Contains(s, "${{") {
+		return s, nil
+	}
+
+	var evalErr error
+	result := outputExpression.ReplaceAllStringFunc(s, func(match string) string {
+		value, err := evaluate(outputExpression.FindStringSubmatch(match)[1], context)
+		if err != nil && evalErr == nil {
+			evalErr = err
+		}
+		return expressionString(value)
+	})
+	if evalErr != nil {
+		return "", evalErr
+	}
+	return result, nil
+}
+
+// substituteOutputsMap 替换 map 中每个值引用 needs 和 steps 上下文的表达式
+func substituteOutputsMap(values map[string]string, context map[string]interface{}) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		substituted, err := substituteOutputs(value, context)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		result[key] = substituted
+	}
+	return result, nil
+}
+
+// readStepOutputs 读取步骤写入 $GITHUB_OUTPUT 文件的输出，支持 name=value 和
+// name<<DELIMITER 多行两种格式
+func readStepOutputs(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	outputs := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, delimiter, ok := strings.Cut(line, "<<"); ok && !strings.Contains(name, "=") {
+			var lines []string
+			closed := false
+			for scanner.Scan() {
+				if scanner.Text() == delimiter {
+					closed = true
+					break
+				}
+				lines = append(lines, scanner.Text())
+			}
+			if !closed {
+				return nil, fmt.Errorf("output %s is missing delimiter %s", name, delimiter)
+			}
+			outputs[name] = strings.Join(lines, "\n")
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok {
+			outputs[name] = value
+		} else if strings.TrimSpace(line) != "" {
+			return nil, fmt.Errorf("invalid output line: %s", line)
+		}
+	}
+	return outputs, scanner.Err()
+}
+
+// needsContext 构建 job 表达式中的 needs 上下文，包含上游 job 的状态和输出。重新运行时复用的 job
+// 带有上一次尝试的输出；矩阵 job 以矩阵 job 名称引用，合并所有实例的输出，任一实例未成功时 result 为该实例的状态
+func (e *baseEngine) needsContext(executionID string, job Job) map[string]interface{} {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	needs := make(map[string]interface{}, len(job.Needs))
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return needs
+	}
+
+	upstream := make(map[string]bool, len(job.Needs))
+	for _, name := range job.Needs {
+		upstream[name] = true
+	}
+	for _, state := range execution.Jobs {
+		if !upstream[state.Name] {
+			continue
+		}
+		name := state.Name
+		if state.Group != "" {
+			name = state.Group
+		}
+		need, ok := needs[name].(map[string]interface{})
+		if !ok {
+			need = map[string]interface{}{"result": state.Status, "outputs": map[string]interface{}{}}
+			needs[name] = need
+		}
+		if state.Status != StatusSuccess {
+			need["result"] = state.Status
+		}
+		outputs := need["outputs"].(map[string]interface{})
+		for key, value := range state.Outputs {
+			outputs[key] = value
+		}
+	}
+	return needs
+}
+
+// setJobOutputs 记录 job 的输出并通知订阅者
+func (e *baseEngine) setJobOutputs(executionID, name string, outputs map[string]string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return
+	}
+	for i := range execution.Jobs {
+		if execution.Jobs[i].Name == name {
+			execution.Jobs[i].Outputs = outputs
+			e.notify(execution)
+			return
+		}
+	}
+}
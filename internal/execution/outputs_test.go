@@ -0,0 +1,108 @@
+package execution
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadStepOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	content := "version=1.2.3\nempty=\nnotes<<EOF\nline 1\nline 2\nEOF\n\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := readStepOutputs(path)
+	if err != nil {
+		t.Fatalf("读取步骤输出失败: %v", err)
+	}
+	if outputs["version"] != "1.2.3" || outputs["empty"] != "" || outputs["notes"] != "line 1\nline 2" || len(outputs) != 3 {
+		t.Errorf("步骤输出不匹配: %q", outputs)
+	}
+
+	// 多行输出缺少结束分隔符和无效的行返回错误
+	for _, invalid := range []string{"notes<<EOF\nline 1\n", "version\n"} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readStepOutputs(path); err == nil {
+			t.Errorf("%q 应返回错误", invalid)
+		}
+	}
+}
+
+func TestSubstituteOutputs(t *testing.T) {
+	context := map[string]interface{}{
+		"needs": map[string]interface{}{
+			"build": map[string]interface{}{"result": StatusSuccess, "outputs": map[string]interface{}{"version": "1.0"}},
+		},
+		"steps": map[string]interface{}{},
+	}
+
+	// 只替换 needs 和 steps 上下文的表达式，其他表达式保留给 shell 或原样输出
+	result, err := substituteOutputs("v${{ needs.build.outputs.version }} ${{needs.build.result}} ${{ steps.missing.outputs.x }}|${{ github.sha }}", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "v1.0 success |${{ github.sha }}" {
+		t.Errorf("替换结果不匹配: %q", result)
+	}
+
+	if _, err := substituteOutputs("${{ needs.build.outputs. }}", context); err == nil {
+		t.Error("无效的表达式应返回错误")
+	}
+}
+
+func TestLocalEngineOutputs(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	// 矩阵 job 的输出合并后以矩阵 job 名称引用，job 的 env 也可以引用上游 job 的输出
+	config := `jobs:
+  build:
+    outputs:
+      version: ${{ steps.version.outputs.value }}
+    steps:
+    - id: version
+      run: echo "value=1.0" >> "$GITHUB_OUTPUT"
+  test:
+    needs: build
+    strategy:
+      matrix:
+        os: [linux, darwin]
+    outputs:
+      os: ${{ matrix.os }}
+    steps:
+    - run: echo "testing ${{ needs.build.outputs.version }}"
+  release:
+    needs: [build, test]
+    env:
+      VERSION: ${{ needs.build.outputs.version }}
+    steps:
+    - run: echo "$VERSION ${{ needs.test.outputs.os != '' }} ${{ needs.test.result }}"
+`
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusSuccess {
+		t.Fatalf("执行应成功: %s %s", execution.Status, execution.Reason)
+	}
+	for _, job := range execution.Jobs {
+		if job.Group == "test" && job.Outputs["os"] != job.Matrix["os"] {
+			t.Errorf("矩阵实例 %s 的输出不匹配: %v", job.Name, job.Outputs)
+		}
+	}
+	if !hasLog(execution, "testing 1.0") || !hasLog(execution, "1.0 true success") {
+		t.Error("下游 job 应读取上游矩阵 job 的输出")
+	}
+}
@@ -0,0 +1,447 @@
+package execution
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"ci-cd-orchestrator/internal/models"
+)
+
+// 持久化到 metrics 表的指标名称
+const (
+	metricTotalDuration  = "total_duration"
+	metricSuccessRate    = "success_rate"
+	metricCpuUsage       = "cpu_usage"
+	metricMemoryUsage    = "memory_usage"
+	metricTestCoverage   = "test_coverage"
+	metricBuildSize      = "build_size"
+	metricDeploymentTime = "deployment_time"
+)
+
+// RecordLog 持久化引擎追加的日志条目。引擎持有锁时回调，日志放入缓冲后由后台按批写入
+func (m *ManagerImpl) RecordLog(entry LogEntry) {
+	if m.executionRepo == nil {
+		return
+	}
+
+	record := &models.ExecutionLog{
+		ID:          entry.ID,
+		ExecutionID: entry.ExecutionID,
+		Timestamp:   entry.Timestamp,
+		Level:       entry.Level,
+		Stage:       entry.Stage,
+		Step:        entry.Step,
+		Message:     entry.Message,
+	}
+	if len(entry.Context) > 0 {
+		data, err := json.Marshal(entry.Context)
+		if err == nil {
+			record.Context = string(data)
+		}
+	}
+
+	m.writer.addLog(record)
+}
+
+// RecordTransition 持久化执行的状态变化。引擎持有锁时回调，状态变化放入缓冲后由后台写入
+func (m *ManagerImpl) RecordTransition(executionID string, transition Transition) {
+	if m.executionRepo == nil {
+		return
+	}
+
+	record := &models.ExecutionTransition{
+		ExecutionID: executionID,
+		FromStatus:  transition.From,
+		ToStatus:    transition.To,
+		Actor:       transition.Actor,
+		Reason:      transition.Reason,
+		Timestamp:   transition.Timestamp,
+	}
+	m.writer.add(func() {
+		if err := m.executionRepo.AddTransition(record); err != nil {
+			log.Printf("保存执行状态变化失败 (%s): %v", executionID, err)
+		}
+	})
+}
+
+// RecordExecution 持久化执行状态。引擎持有锁时回调，传入的执行为快照，放入缓冲后由后台保存。
+// 由队列启动的执行结束时释放并发名额，在后台启动等待中的执行
+func (m *ManagerImpl) RecordExecution(execution *Execution) {
+	if IsFinished(execution.Status) && m.release(execution.ID) {
+		go m.resumeQueue()
+	}
+
+	if m.executionRepo == nil {
+		return
+	}
+
+	m.writer.add(func() { m.saveExecution(execution) })
+}
+
+// saveExecution 保存执行记录，执行结束时同时保存阶段耗时和指标，保存后在后台将执行从内存中移除
+func (m *ManagerImpl) saveExecution(execution *Execution) {
+	record, err := toExecutionModel(execution)
+	if err != nil {
+		log.Printf("序列化执行记录失败 (%s): %v", execution.ID, err)
+		return
+	}
+
+	if err := m.executionRepo.Update(record); err != nil {
+		log.Printf("保存执行记录失败 (%s): %v", execution.ID, err)
+		return
+	}
+
+	if !IsFinished(execution.Status) {
+		return
+	}
+	// 在写入过程中调用，移除执行需要等待之后追加的记录写入
+	defer func() { go m.evict(execution.ID) }()
+
+	if err := m.executionRepo.SaveStageDurations(execution.ID, execution.Metrics.StageDurations); err != nil {
+		log.Printf("保存阶段耗时失败 (%s): %v", execution.ID, err)
+	}
+
+	if m.metricRepo == nil {
+		return
+	}
+
+	if err := m.metricRepo.DeleteByExecutionID(execution.ID); err != nil {
+		log.Printf("清理执行指标失败 (%s): %v", execution.ID, err)
+		return
+	}
+	for name, value := range metricValues(execution.Metrics) {
+		metric := &models.Metric{
+			ExecutionID: execution.ID,
+			Name:        name,
+			Value:       value,
+		}
+		if err := m.metricRepo.Create(metric); err != nil {
+			log.Printf("保存执行指标失败 (%s): %v", execution.ID, err)
+			return
+		}
+	}
+}
+
+// evict 将已结束并保存到数据库的执行从管理器和引擎的内存中移除，之后的查询从数据库加载。
+// 移除前等待缓冲中的记录写入，从数据库加载的执行包含完整的日志和状态变化
+func (m *ManagerImpl) evict(executionID string) {
+	m.writer.flush()
+
+	m.mutex.Lock()
+	execution, exists := m.executions[executionID]
+	var engine Engine
+	if exists {
+		engine = m.engines[execution.Platform]
+		delete(m.executions, executionID)
+		delete(m.options, executionID)
+	}
+	m.mutex.Unlock()
+
+	if releaser, ok := engine.(executionReleaser); ok {
+		releaser.ReleaseExecution(executionID)
+	}
+}
+
+// loadExecution 从数据库加载执行记录，withLogs 为 true 时同时加载日志
+func (m *ManagerImpl) loadExecution(executionID string, withLogs bool) (*Execution, error) {
+	record, err := m.executionRepo.GetByID(executionID)
+	if err != nil {
+		return nil, fmt.Errorf("execution not found: %s", executionID)
+	}
+
+	execution, err := fromExecutionModel(record)
+	if err != nil {
+		return nil, err
+	}
+
+	stageDurations, err := m.executionRepo.GetStageDurations(executionID)
+	if err != nil {
+		return nil, err
+	}
+	execution.Metrics.StageDurations = stageDurations
+
+	transitions, err := m.executionRepo.GetTransitions(executionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range transitions {
+		execution.Transitions = append(execution.Transitions, Transition{
+			From:      record.FromStatus,
+			To:        record.ToStatus,
+			Actor:     record.Actor,
+			Reason:    record.Reason,
+			Timestamp: record.Timestamp,
+		})
+	}
+
+	if m.metricRepo != nil {
+		metrics, err := m.metricRepo.GetByExecutionID(executionID)
+		if err != nil {
+			return nil, err
+		}
+		applyMetricValues(&execution.Metrics, metrics)
+	}
+
+	if withLogs {
+		logs, err := m.executionRepo.GetLogs(executionID)
+		if err != nil {
+			return nil, err
+		}
+		execution.Logs = make([]LogEntry, 0, len(logs))
+		for _, record := range logs {
+			entry := LogEntry{
+				ID:          record.ID,
+				ExecutionID: record.ExecutionID,
+				Timestamp:   record.Timestamp,
+				Level:       record.Level,
+				Stage:       record.Stage,
+				Step:        record.Step,
+				Message:     record.Message,
+			}
+			if record.Context != "" {
+				json.Unmarshal([]byte(record.Context), &entry.Context)
+			}
+			execution.Logs = append(execution.Logs, entry)
+		}
+	}
+
+	return execution, nil
+}
+
+// interruptedReason 服务重启时仍未结束的执行的失败原因
+const interruptedReason = "Execution interrupted by server restart"
+
+// recoverInterrupted 将服务停止时仍未结束的执行标记为失败，已从执行队列恢复的执行除外
+func (m *ManagerImpl) recoverInterrupted() {
+	if m.executionRepo == nil {
+		return
+	}
+
+	records, err := m.executionRepo.GetByStatus(StatusPending, StatusQueued, StatusRunning)
+	if err != nil {
+		log.Printf("加载未结束的执行失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	interrupted := 0
+	for _, record := range records {
+		if _, restored := m.executions[record.ID]; restored {
+			continue
+		}
+		duration := 0
+		if !record.StartTime.IsZero() {
+			duration = int(now.Sub(record.StartTime).Seconds())
+		}
+		if err := m.executionRepo.UpdateStatus(record.ID, StatusFailed, now, duration); err != nil {
+			log.Printf("更新执行状态失败 (%s): %v", record.ID, err)
+			continue
+		}
+		m.executionRepo.AddTransition(&models.ExecutionTransition{
+			ExecutionID: record.ID,
+			FromStatus:  record.Status,
+			ToStatus:    StatusFailed,
+			Actor:       ActorSystem,
+			Reason:      interruptedReason,
+			Timestamp:   now,
+		})
+		m.executionRepo.AddLog(&models.ExecutionLog{
+			ExecutionID: record.ID,
+			Timestamp:   now,
+			Level:       "error",
+			Stage:       "recovery",
+			Message:     interruptedReason,
+		})
+		interrupted++
+	}
+
+	if interrupted > 0 {
+		log.Printf("已将 %d 个中断的执行标记为失败", interrupted)
+	}
+}
+
+// restoreQueue 恢复服务停止时执行队列中等待启动的执行，按入队顺序重新排队。
+// 恢复的执行在对应平台的引擎注册后登记到引擎，在下一次调度时启动
+func (m *ManagerImpl) restoreQueue() {
+	if m.executionRepo == nil {
+		return
+	}
+
+	items, err := m.executionRepo.GetQueue()
+	if err != nil {
+		log.Printf("加载执行队列失败: %v", err)
+		return
+	}
+
+	for _, item := range items {
+		execution, err := m.loadExecution(item.ExecutionID, true)
+		if err != nil || execution.Status != StatusQueued {
+			// 执行已被删除或已经启动
+			m.executionRepo.Dequeue(item.ExecutionID)
+			continue
+		}
+
+		var options ExecutionOptions
+		if err := json.Unmarshal([]byte(item.Options), &options); err != nil {
+			log.Printf("解析排队执行的选项失败 (%s): %v", item.ExecutionID, err)
+			m.executionRepo.Dequeue(item.ExecutionID)
+			continue
+		}
+
+		// 并发组按保存的选项重新计算，配置无法计算时不再限制
+		group, groupName, _, err := concurrencyGroup(execution, options)
+		if err != nil {
+			log.Printf("计算排队执行的并发组失败 (%s): %v", item.ExecutionID, err)
+		}
+
+		m.executions[execution.ID] = execution
+		m.options[execution.ID] = options
+		m.queue = append(m.queue, &queueEntry{
+			seq:         int64(item.ID),
+			executionID: execution.ID,
+			projectID:   execution.ProjectID,
+			platform:    execution.Platform,
+			priority:    execution.Priority,
+			enqueuedAt:  item.EnqueuedAt,
+			group:       group,
+			groupName:   groupName,
+		})
+	}
+
+	if len(m.queue) > 0 {
+		log.Printf("已恢复 %d 个排队的执行", len(m.queue))
+	}
+}
+
+// IsFinished 判断执行状态是否为结束状态
+func IsFinished(status string) bool {
+	switch status {
+	case StatusSuccess, StatusFailed, StatusCancelled, StatusTimedOut, StatusSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// toExecutionModel 将执行记录转换为数据库模型
+func toExecutionModel(execution *Execution) (*models.Execution, error) {
+	projectID, _ := strconv.Atoi(execution.ProjectID)
+
+	triggerInfo, err := json.Marshal(execution.TriggerInfo)
+	if err != nil {
+		return nil, err
+	}
+	platformData, err := json.Marshal(execution.PlatformData)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := json.Marshal(execution.Jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Execution{
+		ID:               execution.ID,
+		ProjectID:        projectID,
+		PipelineID:       execution.PipelineID,
+		PipelineRevision: execution.PipelineRevision,
+		Platform:         execution.Platform,
+		Status:           execution.Status,
+		Priority:         execution.Priority,
+		Reason:           execution.Reason,
+		RunID:            execution.RunID,
+		Attempt:          execution.Attempt,
+		RerunOf:          execution.RerunOf,
+		Debug:            execution.Debug,
+		TriggerType:      execution.TriggerType,
+		TriggerInfo:      string(triggerInfo),
+		PlatformData:     string(platformData),
+		Jobs:             string(jobs),
+		StartTime:        execution.StartTime,
+		EndTime:          execution.EndTime,
+		Duration:         int(execution.Duration),
+	}, nil
+}
+
+// fromExecutionModel 将数据库模型转换为执行记录
+func fromExecutionModel(record *models.Execution) (*Execution, error) {
+	execution := &Execution{
+		ID:               record.ID,
+		ProjectID:        strconv.Itoa(record.ProjectID),
+		PipelineID:       record.PipelineID,
+		PipelineRevision: record.PipelineRevision,
+		Platform:         record.Platform,
+		Status:           record.Status,
+		Priority:         record.Priority,
+		Reason:           record.Reason,
+		RunID:            record.RunID,
+		Attempt:          record.Attempt,
+		RerunOf:          record.RerunOf,
+		Debug:            record.Debug,
+		StartTime:        record.StartTime,
+		EndTime:          record.EndTime,
+		Duration:         int64(record.Duration),
+		TriggerType:      record.TriggerType,
+		TriggerInfo:      map[string]interface{}{},
+		PlatformData:     map[string]interface{}{},
+		Metrics: Metrics{
+			StageDurations: make(map[string]int64),
+		},
+	}
+
+	if record.TriggerInfo != "" {
+		if err := json.Unmarshal([]byte(record.TriggerInfo), &execution.TriggerInfo); err != nil {
+			return nil, fmt.Errorf("invalid trigger info: %w", err)
+		}
+	}
+	if record.PlatformData != "" {
+		if err := json.Unmarshal([]byte(record.PlatformData), &execution.PlatformData); err != nil {
+			return nil, fmt.Errorf("invalid platform data: %w", err)
+		}
+	}
+	if record.Jobs != "" {
+		if err := json.Unmarshal([]byte(record.Jobs), &execution.Jobs); err != nil {
+			return nil, fmt.Errorf("invalid jobs: %w", err)
+		}
+	}
+
+	return execution, nil
+}
+
+// metricValues 将执行指标展开为名称-数值对
+func metricValues(metrics Metrics) map[string]float64 {
+	return map[string]float64{
+		metricTotalDuration:  float64(metrics.TotalDuration),
+		metricSuccessRate:    metrics.SuccessRate,
+		metricCpuUsage:       metrics.CpuUsage,
+		metricMemoryUsage:    metrics.MemoryUsage,
+		metricTestCoverage:   metrics.TestCoverage,
+		metricBuildSize:      float64(metrics.BuildSize),
+		metricDeploymentTime: float64(metrics.DeploymentTime),
+	}
+}
+
+// applyMetricValues 将数据库中的指标写回执行指标
+func applyMetricValues(metrics *Metrics, records []*models.Metric) {
+	for _, record := range records {
+		switch record.Name {
+		case metricTotalDuration:
+			metrics.TotalDuration = int64(record.Value)
+		case metricSuccessRate:
+			metrics.SuccessRate = record.Value
+		case metricCpuUsage:
+			metrics.CpuUsage = record.Value
+		case metricMemoryUsage:
+			metrics.MemoryUsage = record.Value
+		case metricTestCoverage:
+			metrics.TestCoverage = record.Value
+		case metricBuildSize:
+			metrics.BuildSize = int64(record.Value)
+		case metricDeploymentTime:
+			metrics.DeploymentTime = int64(record.Value)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package execution
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/pkg/migration"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRecordWriterDoesNotBlockEngine(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "cicd.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	if err := migration.Run(db); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	executionRepo := repository.NewExecutionRepository(db)
+	manager := NewManager(executionRepo, repository.NewMetricRepository(db))
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	config := `jobs:
+  build:
+    steps:
+    - run: sleep 0.3
+    - run: echo done
+`
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	// 锁定数据库，执行记录在后台等待写入，执行照常运行并结束
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("锁定数据库失败: %v", err)
+	}
+
+	start := time.Now()
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusSuccess {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusSuccess, execution.Status)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("数据库被锁定时执行不应等待写入: %s", elapsed)
+	}
+
+	if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		t.Fatalf("解锁数据库失败: %v", err)
+	}
+
+	// 解锁后执行记录、状态变化和日志按顺序写入，执行从内存中移除后从数据库加载
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		manager.(*ManagerImpl).mutex.RLock()
+		_, inMemory := manager.(*ManagerImpl).executions[executionID]
+		manager.(*ManagerImpl).mutex.RUnlock()
+		if !inMemory {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("执行结束后未保存并从内存中移除")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	stored, err := manager.GetExecution(executionID)
+	if err != nil {
+		t.Fatalf("从数据库加载执行失败: %v", err)
+	}
+	if stored.Status != StatusSuccess || len(stored.Transitions) != len(execution.Transitions) || !hasLog(stored, "done") {
+		t.Errorf("保存的执行记录不完整: %s %v %d", stored.Status, stored.Transitions, len(stored.Logs))
+	}
+}
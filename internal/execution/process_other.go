@@ -0,0 +1,8 @@
+//go:build !unix
+
+package execution
+
+import "os/exec"
+
+// configureProcessGroup 不支持进程组的平台上取消时只终止步骤的 shell 进程
+func configureProcessGroup(cmd *exec.Cmd) {}
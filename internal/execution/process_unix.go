@@ -0,0 +1,16 @@
+//go:build unix
+
+package execution
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configureProcessGroup 让步骤在独立的进程组中运行，取消时终止整个进程组，包括步骤启动的后台子进程
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
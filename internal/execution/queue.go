@@ -0,0 +1,307 @@
+package execution
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 排队的优先级类别，按从高到低的顺序启动
+const (
+	PriorityRelease     = "release"      // 标签和发布
+	PriorityPullRequest = "pull_request" // pull request 和 merge request
+	PriorityDefault     = "default"      // 推送、手动触发等其他执行
+	PriorityScheduled   = "scheduled"    // 定时任务
+)
+
+// priorityRanks 优先级类别的排序，数值越大越先启动
+var priorityRanks = map[string]int{
+	PriorityRelease:     3,
+	PriorityPullRequest: 2,
+	PriorityDefault:     1,
+	PriorityScheduled:   0,
+}
+
+// 队列中的执行正在等待的限制
+const (
+	WaitingGlobal   = "global"            // 全局并发数已满
+	WaitingProject  = "project"           // 项目并发数已满
+	WaitingPlatform = "platform"          // 平台并发数已满
+	WaitingEngine   = "engine"            // 平台的引擎尚未注册
+	WaitingGroup    = "concurrency_group" // 并发组中有运行中的执行
+)
+
+// DefaultEstimatedDuration 没有已结束的历史执行时估计的执行耗时
+const DefaultEstimatedDuration = 5 * time.Minute
+
+// estimateHistory 估计执行耗时时参考的最近已结束执行数量
+const estimateHistory = 20
+
+// ValidPriority 判断优先级类别是否有效
+func ValidPriority(priority string) bool {
+	_, ok := priorityRanks[priority]
+	return ok
+}
+
+// PriorityFor 根据触发类型确定默认的优先级类别
+func PriorityFor(triggerType string) string {
+	switch triggerType {
+	case "tag", "release":
+		return PriorityRelease
+	case "pull_request", "merge_request":
+		return PriorityPullRequest
+	case "schedule":
+		return PriorityScheduled
+	default:
+		return PriorityDefault
+	}
+}
+
+// QueueLimits 同时运行的执行数量限制，0 表示不限制
+type QueueLimits struct {
+	Global     int            `json:"global"`
+	PerProject int            `json:"per_project"`
+	Platforms  map[string]int `json:"platforms,omitempty"` // 每个平台的限制，没有设置的平台不限制
+}
+
+// QueueStatus 排队中的执行在队列中的状态
+type QueueStatus struct {
+	ExecutionID        string    `json:"execution_id"`
+	ProjectID          string    `json:"project_id"`
+	Platform           string    `json:"platform"`
+	Priority           string    `json:"priority"`
+	Position           int       `json:"position"` // 预计的启动顺序，从 1 开始
+	EnqueuedAt         time.Time `json:"enqueued_at"`
+	EstimatedStartTime time.Time `json:"estimated_start_time,omitzero"` // 无法估计时为空，例如引擎尚未注册
+	WaitingFor         string    `json:"waiting_for,omitempty"`         // 当前等待的限制
+	ConcurrencyGroup   string    `json:"concurrency_group,omitempty"`   // 配置中 concurrency 计算得到的并发组
+}
+
+// QueueSnapshot 执行队列的当前状态
+type QueueSnapshot struct {
+	Limits            QueueLimits    `json:"limits"`
+	Running           int            `json:"running"`
+	RunningByProject  map[string]int `json:"running_by_project"`
+	RunningByPlatform map[string]int `json:"running_by_platform"`
+	Queued            []*QueueStatus `json:"queued"`
+}
+
+// queueEntry 队列中等待启动的执行
+type queueEntry struct {
+	seq         int64 // 入队顺序
+	executionID string
+	projectID   string
+	platform    string
+	priority    string
+	enqueuedAt  time.Time
+	group       string // 项目内的并发组，为空表示没有配置 concurrency
+	groupName   string
+}
+
+// runningEntry 由队列启动、尚未结束的执行
+type runningEntry struct {
+	projectID string
+	platform  string
+	group     string
+	startedAt time.Time
+}
+
+// queueCounts 正在运行的执行数量
+type queueCounts struct {
+	total     int
+	projects  map[string]int
+	platforms map[string]int
+	groups    map[string]int
+}
+
+// newQueueCounts 统计正在运行的执行数量
+func newQueueCounts(running map[string]*runningEntry) *queueCounts {
+	counts := &queueCounts{projects: map[string]int{}, platforms: map[string]int{}, groups: map[string]int{}}
+	for _, entry := range running {
+		counts.add(entry.projectID, entry.platform, entry.group)
+	}
+	return counts
+}
+
+// add 增加一个正在运行的执行
+func (c *queueCounts) add(projectID, platform, group string) {
+	c.total++
+	c.projects[projectID]++
+	c.platforms[platform]++
+	if group != "" {
+		c.groups[group]++
+	}
+}
+
+// remove 减少一个正在运行的执行
+func (c *queueCounts) remove(projectID, platform, group string) {
+	c.total--
+	c.projects[projectID]--
+	c.platforms[platform]--
+	if group != "" {
+		c.groups[group]--
+	}
+}
+
+// waitingFor 返回执行因达到哪个并发限制或因并发组中有运行中的执行而不能启动，可以启动时返回空字符串
+func (l QueueLimits) waitingFor(counts *queueCounts, entry *queueEntry) string {
+	projectID, platform := entry.projectID, entry.platform
+	if entry.group != "" && counts.groups[entry.group] > 0 {
+		return WaitingGroup
+	}
+	if l.Global > 0 && counts.total >= l.Global {
+		return WaitingGlobal
+	}
+	if l.PerProject > 0 && counts.projects[projectID] >= l.PerProject {
+		return WaitingProject
+	}
+	if limit := l.Platforms[platform]; limit > 0 && counts.platforms[platform] >= limit {
+		return WaitingPlatform
+	}
+	return ""
+}
+
+// nextEntry 选出队列中下一个启动的执行，返回其下标，没有可以启动的执行时返回 -1。
+// 优先级类别高的先启动；同一类别中正在运行的执行最少的项目先启动，避免积压大量执行的项目让其他项目一直等待；
+// 仍相同时先入队的先启动。达到并发限制或引擎未注册的执行不会阻塞其后可以启动的执行
+func nextEntry(queue []*queueEntry, counts *queueCounts, limits QueueLimits, engines map[string]bool) int {
+	best := -1
+	for i, entry := range queue {
+		if !engines[entry.platform] || limits.waitingFor(counts, entry) != "" {
+			continue
+		}
+		if best < 0 || before(entry, queue[best], counts) {
+			best = i
+		}
+	}
+	return best
+}
+
+// before 判断 a 是否应在 b 之前启动
+func before(a, b *queueEntry, counts *queueCounts) bool {
+	if rankA, rankB := priorityRanks[a.priority], priorityRanks[b.priority]; rankA != rankB {
+		return rankA > rankB
+	}
+	if runningA, runningB := counts.projects[a.projectID], counts.projects[b.projectID]; runningA != runningB {
+		return runningA < runningB
+	}
+	return a.seq < b.seq
+}
+
+// estimateQueue 模拟队列的调度，估计每个排队执行的启动顺序和启动时间。
+// 正在运行的执行按 duration 估计的耗时结束，已超过估计耗时的执行视为即将结束
+func estimateQueue(queue []*queueEntry, running map[string]*runningEntry, limits QueueLimits, engines map[string]bool,
+	duration func(projectID, platform string) time.Duration, now time.Time) []*QueueStatus {
+	type finish struct {
+		at        time.Time
+		projectID string
+		platform  string
+		group     string
+	}
+
+	counts := newQueueCounts(running)
+	var finishes []finish
+	for _, entry := range running {
+		at := entry.startedAt.Add(duration(entry.projectID, entry.platform))
+		if at.Before(now) {
+			at = now
+		}
+		finishes = append(finishes, finish{at: at, projectID: entry.projectID, platform: entry.platform, group: entry.group})
+	}
+
+	statuses := make(map[string]*QueueStatus, len(queue))
+	for _, entry := range queue {
+		status := &QueueStatus{
+			ExecutionID:      entry.executionID,
+			ProjectID:        entry.projectID,
+			Platform:         entry.platform,
+			Priority:         entry.priority,
+			EnqueuedAt:       entry.enqueuedAt,
+			WaitingFor:       limits.waitingFor(counts, entry),
+			ConcurrencyGroup: entry.groupName,
+		}
+		if !engines[entry.platform] {
+			status.WaitingFor = WaitingEngine
+		}
+		statuses[entry.executionID] = status
+	}
+
+	remaining := append([]*queueEntry(nil), queue...)
+	result := make([]*QueueStatus, 0, len(queue))
+	at := now
+	for len(remaining) > 0 {
+		if i := nextEntry(remaining, counts, limits, engines); i >= 0 {
+			entry := remaining[i]
+			remaining = append(remaining[:i], remaining[i+1:]...)
+
+			status := statuses[entry.executionID]
+			status.EstimatedStartTime = at
+			result = append(result, status)
+
+			counts.add(entry.projectID, entry.platform, entry.group)
+			finishes = append(finishes, finish{at: at.Add(duration(entry.projectID, entry.platform)), projectID: entry.projectID, platform: entry.platform, group: entry.group})
+			continue
+		}
+
+		// 没有可以启动的执行时等待最早结束的执行
+		if len(finishes) == 0 {
+			break
+		}
+		sort.Slice(finishes, func(i, j int) bool { return finishes[i].at.Before(finishes[j].at) })
+		at = finishes[0].at
+		counts.remove(finishes[0].projectID, finishes[0].platform, finishes[0].group)
+		finishes = finishes[1:]
+	}
+
+	// 无法启动的执行按入队顺序排在最后
+	for _, entry := range remaining {
+		result = append(result, statuses[entry.executionID])
+	}
+	for i, status := range result {
+		status.Position = i + 1
+	}
+	return result
+}
+
+// estimatedDuration 估计项目在平台上一次执行的耗时，使用最近已结束执行的平均耗时
+func (m *ManagerImpl) estimatedDuration(projectID, platform string) time.Duration {
+	if m.executionRepo != nil {
+		id, _ := strconv.Atoi(projectID)
+		if average, err := m.executionRepo.GetAverageDuration(id, platform, estimateHistory); err == nil && average > 0 {
+			return time.Duration(average * float64(time.Second))
+		}
+		return DefaultEstimatedDuration
+	}
+
+	// 执行记录只保存在内存中时使用内存中已结束的执行，状态从引擎读取，避免与引擎的修改并发
+	m.mutex.RLock()
+	var candidates []string
+	for executionID, execution := range m.executions {
+		if execution.ProjectID == projectID && execution.Platform == platform {
+			candidates = append(candidates, executionID)
+		}
+	}
+	engine := m.engines[platform]
+	m.mutex.RUnlock()
+
+	var total time.Duration
+	count := 0
+	for _, executionID := range candidates {
+		if engine == nil {
+			break
+		}
+		execution, err := engine.GetStatus(executionID)
+		if err != nil || execution.StartTime.IsZero() || execution.EndTime.IsZero() {
+			continue
+		}
+		if execution.Status != StatusSuccess && execution.Status != StatusFailed {
+			continue
+		}
+		total += execution.EndTime.Sub(execution.StartTime)
+		count++
+	}
+	if count == 0 || total <= 0 {
+		return DefaultEstimatedDuration
+	}
+	return total / time.Duration(count)
+}
@@ -0,0 +1,151 @@
+package execution
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextEntry(t *testing.T) {
+	engines := map[string]bool{"mock": true, "local": true}
+	queue := []*queueEntry{
+		{seq: 1, executionID: "a1", projectID: "a", platform: "mock", priority: PriorityScheduled},
+		{seq: 2, executionID: "a2", projectID: "a", platform: "mock", priority: PriorityDefault},
+		{seq: 3, executionID: "b1", projectID: "b", platform: "mock", priority: PriorityDefault},
+		{seq: 4, executionID: "c1", projectID: "c", platform: "local", priority: PriorityRelease},
+		{seq: 5, executionID: "d1", projectID: "d", platform: "gitlab_ci", priority: PriorityRelease},
+	}
+	pick := func(counts *queueCounts, limits QueueLimits) string {
+		if i := nextEntry(queue, counts, limits, engines); i >= 0 {
+			return queue[i].executionID
+		}
+		return ""
+	}
+
+	// 优先级最高的先启动，引擎未注册的执行被跳过
+	counts := newQueueCounts(nil)
+	if got := pick(counts, QueueLimits{}); got != "c1" {
+		t.Errorf("应先启动 release 优先级的执行: %s", got)
+	}
+
+	// 达到平台限制的执行不阻塞其他平台
+	counts.add("x", "local", "")
+	if got := pick(counts, QueueLimits{Platforms: map[string]int{"local": 1}}); got != "a2" {
+		t.Errorf("local 平台已满时应启动 a2: %s", got)
+	}
+
+	// 同一优先级中正在运行的执行较少的项目先启动
+	counts.add("a", "mock", "")
+	if got := pick(counts, QueueLimits{Platforms: map[string]int{"local": 1}}); got != "b1" {
+		t.Errorf("项目 a 已有运行中的执行时应先启动项目 b: %s", got)
+	}
+
+	// 达到项目限制
+	if got := pick(counts, QueueLimits{PerProject: 1, Platforms: map[string]int{"local": 1}}); got != "b1" {
+		t.Errorf("项目 a 达到限制时应启动 b1: %s", got)
+	}
+
+	// 达到全局限制时不启动
+	if got := pick(counts, QueueLimits{Global: 2}); got != "" {
+		t.Errorf("达到全局限制时不应启动执行: %s", got)
+	}
+}
+
+func TestEstimateQueue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	engines := map[string]bool{"mock": true}
+	running := map[string]*runningEntry{
+		"r1": {projectID: "a", platform: "mock", startedAt: now.Add(-time.Minute)},
+	}
+	queue := []*queueEntry{
+		{seq: 1, executionID: "q1", projectID: "a", platform: "mock", priority: PriorityScheduled},
+		{seq: 2, executionID: "q2", projectID: "b", platform: "mock", priority: PriorityPullRequest},
+		{seq: 3, executionID: "q3", projectID: "c", platform: "gitlab_ci", priority: PriorityRelease},
+	}
+	duration := func(projectID, platform string) time.Duration { return 5 * time.Minute }
+
+	statuses := estimateQueue(queue, running, QueueLimits{Global: 1}, engines, duration, now)
+	expected := []struct {
+		id         string
+		start      time.Time
+		waitingFor string
+	}{
+		{"q2", now.Add(4 * time.Minute), WaitingGlobal},
+		{"q1", now.Add(9 * time.Minute), WaitingGlobal},
+		{"q3", time.Time{}, WaitingEngine},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("队列长度不匹配: %d", len(statuses))
+	}
+	for i, want := range expected {
+		got := statuses[i]
+		if got.ExecutionID != want.id || got.Position != i+1 || !got.EstimatedStartTime.Equal(want.start) || got.WaitingFor != want.waitingFor {
+			t.Errorf("第 %d 个排队执行不匹配: %+v", i+1, got)
+		}
+	}
+}
+
+func TestManagerQueue(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("mock", NewMockEngine())
+	manager.SetQueueLimits(QueueLimits{Global: 1})
+
+	config := `jobs:
+  build:
+    steps:
+    - run: make
+`
+	start := func(triggerType string) string {
+		t.Helper()
+		executionID, err := manager.CreateExecution("1", "mock", triggerType, ExecutionOptions{
+			CIConfigContent: config,
+			StageDurations:  map[string]int{"build": 1},
+		})
+		if err != nil {
+			t.Fatalf("创建执行失败: %v", err)
+		}
+		if err := manager.StartExecution(executionID); err != nil {
+			t.Fatalf("启动执行失败: %v", err)
+		}
+		return executionID
+	}
+
+	first := start("manual")
+	scheduled := start("schedule")
+	cancelled := start("push")
+	release := start("tag")
+
+	current, err := manager.GetExecution(release)
+	if err != nil {
+		t.Fatalf("获取执行详情失败: %v", err)
+	}
+	if current.Status != StatusQueued || current.Priority != PriorityRelease || current.Queue == nil || current.Queue.Position != 1 {
+		t.Errorf("release 执行应排在队列第一位: %+v, %+v", current, current.Queue)
+	}
+
+	// 排队中的执行停止时直接取消
+	if err := manager.StopExecution(cancelled); err != nil {
+		t.Fatalf("停止排队的执行失败: %v", err)
+	}
+	if queue := manager.GetQueue(); queue.Running != 1 || len(queue.Queued) != 2 {
+		t.Errorf("队列状态不匹配: %+v", queue)
+	}
+
+	results := map[string]*Execution{}
+	for _, executionID := range []string{first, scheduled, cancelled, release} {
+		results[executionID] = waitForStatus(t, manager, executionID)
+	}
+	if results[cancelled].Status != StatusCancelled {
+		t.Errorf("排队中停止的执行应已取消: %s", results[cancelled].Status)
+	}
+	for _, executionID := range []string{first, scheduled, release} {
+		if results[executionID].Status != StatusSuccess {
+			t.Errorf("执行 %s 应成功: %s", executionID, results[executionID].Status)
+		}
+	}
+
+	// 全局限制为 1 时依次运行，release 优先于 schedule
+	if results[release].StartTime.Before(results[first].EndTime) || results[scheduled].StartTime.Before(results[release].EndTime) {
+		t.Errorf("执行顺序不匹配: first %v-%v, release %v-%v, scheduled %v",
+			results[first].StartTime, results[first].EndTime, results[release].StartTime, results[release].EndTime, results[scheduled].StartTime)
+	}
+}
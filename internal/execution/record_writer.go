@@ -0,0 +1,102 @@
+package execution
+
+import (
+	"log"
+	"sync"
+
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+)
+
+// pendingWrite 等待写入数据库的记录，log 为空时调用 write 保存其他记录
+type pendingWrite struct {
+	log   *models.ExecutionLog
+	write func()
+}
+
+// recordWriter 在后台按追加顺序保存执行日志、状态变化和执行记录。引擎持有锁回调记录器时只把记录放入缓冲，
+// 不等待数据库写入，数据库缓慢或被锁定时不会阻塞正在运行的执行；连续的日志按批写入
+type recordWriter struct {
+	repo       *repository.ExecutionRepository
+	pending    []pendingWrite
+	writing    bool       // 是否有后台写入过程在运行
+	mutex      sync.Mutex // 保护 pending 和 writing
+	writeMutex sync.Mutex // 同一时间只有一批记录写入数据库，保证记录按追加顺序保存
+}
+
+// newRecordWriter 创建执行记录的后台写入器
+func newRecordWriter(repo *repository.ExecutionRepository) *recordWriter {
+	return &recordWriter{repo: repo}
+}
+
+// addLog 将日志放入缓冲
+func (w *recordWriter) addLog(record *models.ExecutionLog) {
+	w.enqueue(pendingWrite{log: record})
+}
+
+// add 将保存记录的操作放入缓冲，在之前追加的记录保存后调用
+func (w *recordWriter) add(write func()) {
+	w.enqueue(pendingWrite{write: write})
+}
+
+// enqueue 将记录放入缓冲，没有后台写入过程时启动一个，缓冲写空后写入过程退出
+func (w *recordWriter) enqueue(item pendingWrite) {
+	w.mutex.Lock()
+	w.pending = append(w.pending, item)
+	start := !w.writing
+	w.writing = true
+	w.mutex.Unlock()
+
+	if start {
+		go w.run()
+	}
+}
+
+// run 持续写入缓冲中的记录直到缓冲为空
+func (w *recordWriter) run() {
+	for {
+		w.mutex.Lock()
+		if len(w.pending) == 0 {
+			w.writing = false
+			w.mutex.Unlock()
+			return
+		}
+		w.mutex.Unlock()
+
+		w.flush()
+	}
+}
+
+// flush 将缓冲中的记录写入数据库，返回时调用前追加的记录都已保存。
+// 保存记录的操作在写入过程中调用，不能再调用 flush
+func (w *recordWriter) flush() {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	w.mutex.Lock()
+	batch := w.pending
+	w.pending = nil
+	w.mutex.Unlock()
+
+	var logs []*models.ExecutionLog
+	for _, item := range batch {
+		if item.log != nil {
+			logs = append(logs, item.log)
+			continue
+		}
+		w.saveLogs(logs)
+		logs = nil
+		item.write()
+	}
+	w.saveLogs(logs)
+}
+
+// saveLogs 将一批日志写入数据库
+func (w *recordWriter) saveLogs(logs []*models.ExecutionLog) {
+	if len(logs) == 0 {
+		return
+	}
+	if err := w.repo.AddLogs(logs); err != nil {
+		log.Printf("保存 %d 条执行日志失败 (%s): %v", len(logs), logs[0].ExecutionID, err)
+	}
+}
@@ -0,0 +1,232 @@
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 重新运行的模式
+const (
+	RerunAll    = "all"    // 重新运行所有 job
+	RerunFailed = "failed" // 只重新运行未成功的 job，成功的 job 复用上一次尝试的结果
+	RerunJob    = "job"    // 重新运行指定的 job 和依赖它的 job，其他 job 复用上一次尝试的结果
+)
+
+// RerunOptions 重新运行执行的选项
+type RerunOptions struct {
+	Mode  string `json:"mode"`  // 重新运行的模式，为空时为 all
+	Job   string `json:"job"`   // mode 为 job 时重新运行的 job，矩阵 job 名称表示其所有实例
+	Debug bool   `json:"debug"` // 输出 debug 级别的日志
+}
+
+// ErrRerunUnsupported 执行的平台不支持该重新运行模式
+var ErrRerunUnsupported = errors.New("rerun mode is not supported")
+
+// RemoteRerun 远程平台部分重新运行时，在上一次尝试的 run 或 pipeline 上重试，不创建新的 run 或 pipeline
+type RemoteRerun struct {
+	Mode  string `json:"mode"`             // failed 或 job
+	RunID int64  `json:"run_id"`           // GitHub Actions 的 workflow run ID 或 GitLab CI 的 pipeline ID
+	JobID int64  `json:"job_id,omitempty"` // mode 为 job 时重新运行的 job 在平台上的 ID
+}
+
+// jobReuser 在服务内运行 job 图的引擎，重新运行时可以复用上一次尝试中 job 的结果
+type jobReuser interface {
+	reusesJobs()
+}
+
+// remoteRerunner 远程平台的引擎，job 由平台运行，部分重新运行时通过平台的重试接口在原 run 或 pipeline 上进行
+type remoteRerunner interface {
+	// rerunRunID 返回执行在平台上的 run 或 pipeline ID，没有时返回 0
+	rerunRunID(execution *Execution) int64
+}
+
+// ValidRerunMode 判断重新运行的模式是否有效
+func ValidRerunMode(mode string) bool {
+	switch mode {
+	case RerunAll, RerunFailed, RerunJob:
+		return true
+	default:
+		return false
+	}
+}
+
+// RerunExecution 为已结束的执行创建重新运行的执行，由 StartExecution 启动。新执行使用相同的管道配置修订、
+// 触发信息和执行选项，作为同一逻辑运行的下一次尝试；不重新运行的 job 复用上一次尝试的结果
+func (m *ManagerImpl) RerunExecution(executionID string, rerun RerunOptions) (string, error) {
+	if rerun.Mode == "" {
+		rerun.Mode = RerunAll
+	}
+	if !ValidRerunMode(rerun.Mode) {
+		return "", fmt.Errorf("invalid rerun mode: %s", rerun.Mode)
+	}
+	if rerun.Mode == RerunJob && rerun.Job == "" {
+		return "", fmt.Errorf("job is required when rerunning a single job")
+	}
+
+	previous, err := m.GetExecution(executionID)
+	if err != nil {
+		return "", err
+	}
+	if !IsFinished(previous.Status) {
+		return "", fmt.Errorf("execution is not finished: %s", previous.Status)
+	}
+
+	m.mutex.RLock()
+	engine, exists := m.engines[previous.Platform]
+	m.mutex.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("engine not found for platform: %s", previous.Platform)
+	}
+
+	options, err := m.executionOptions(executionID)
+	if err != nil {
+		return "", err
+	}
+	options.ReuseJobs = nil
+	options.RemoteRerun = nil
+	switch engine := engine.(type) {
+	case jobReuser:
+		if options.ReuseJobs, err = reusedJobs(previous, rerun); err != nil {
+			return "", err
+		}
+	case remoteRerunner:
+		if rerun.Mode != RerunAll {
+			if options.RemoteRerun, err = remoteRerun(previous, rerun, engine.rerunRunID(previous)); err != nil {
+				return "", err
+			}
+		}
+	default:
+		if rerun.Mode != RerunAll {
+			return "", fmt.Errorf("%w: platform %s only supports rerunning all jobs", ErrRerunUnsupported, previous.Platform)
+		}
+	}
+	options.Debug = rerun.Debug
+	options.TriggerInfo = previous.TriggerInfo
+	options.Priority = previous.Priority
+
+	return m.createExecution(previous.ProjectID, previous.Platform, previous.TriggerType, options, previous)
+}
+
+// executionOptions 获取创建执行时的选项，不在内存中时从数据库加载
+func (m *ManagerImpl) executionOptions(executionID string) (ExecutionOptions, error) {
+	m.mutex.RLock()
+	options, exists := m.options[executionID]
+	m.mutex.RUnlock()
+	if exists {
+		return options, nil
+	}
+
+	if m.executionRepo == nil {
+		return ExecutionOptions{}, fmt.Errorf("execution not found: %s", executionID)
+	}
+	data, err := m.executionRepo.GetOptions(executionID)
+	if err != nil {
+		return ExecutionOptions{}, fmt.Errorf("execution not found: %s", executionID)
+	}
+	if data == "" {
+		// 新增重新运行之前创建的执行没有保存选项
+		return ExecutionOptions{}, fmt.Errorf("execution %s has no saved options and cannot be rerun", executionID)
+	}
+	if err := json.Unmarshal([]byte(data), &options); err != nil {
+		return ExecutionOptions{}, fmt.Errorf("invalid execution options: %w", err)
+	}
+	return options, nil
+}
+
+// latestAttempt 获取逻辑运行中最大的尝试序号，调用方需持有锁
+func (m *ManagerImpl) latestAttempt(runID string) (int, error) {
+	latest := 0
+	for _, execution := range m.executions {
+		if execution.RunID == runID && execution.Attempt > latest {
+			latest = execution.Attempt
+		}
+	}
+
+	if m.executionRepo != nil {
+		attempt, err := m.executionRepo.GetLatestAttempt(runID)
+		if err != nil {
+			return 0, err
+		}
+		if attempt > latest {
+			latest = attempt
+		}
+	}
+	return latest, nil
+}
+
+// remoteRerun 确定远程平台上重试的 run 或 pipeline 和 job。重新运行单个 job 时按名称匹配平台上的 job，
+// 矩阵 job 在平台上是各自独立的 job，需要指定实例的名称
+func remoteRerun(previous *Execution, rerun RerunOptions, runID int64) (*RemoteRerun, error) {
+	if runID == 0 {
+		return nil, fmt.Errorf("%w: execution %s has no run on platform %s", ErrRerunUnsupported, previous.ID, previous.Platform)
+	}
+
+	switch rerun.Mode {
+	case RerunFailed:
+		for _, job := range previous.Jobs {
+			if job.Status != StatusSuccess {
+				return &RemoteRerun{Mode: rerun.Mode, RunID: runID}, nil
+			}
+		}
+		return nil, fmt.Errorf("execution %s has no failed jobs", previous.ID)
+	default:
+		jobIDs, _ := previous.PlatformData["job_ids"].(map[string]interface{})
+		jobID := platformDataInt(jobIDs, rerun.Job)
+		if jobID == 0 {
+			return nil, fmt.Errorf("job not found: %s", rerun.Job)
+		}
+		return &RemoteRerun{Mode: rerun.Mode, RunID: runID, JobID: jobID}, nil
+	}
+}
+
+// reusedJobs 按重新运行的模式确定复用上一次尝试结果的 job。只重新运行指定的 job 时，
+// 依赖它的下游 job 也重新运行，其他 job 无论成功与否都保留上一次尝试的结果
+func reusedJobs(previous *Execution, rerun RerunOptions) (map[string]JobExecution, error) {
+	if rerun.Mode == RerunAll {
+		return nil, nil
+	}
+
+	rerunJobs := make(map[string]bool)
+	switch rerun.Mode {
+	case RerunFailed:
+		for _, job := range previous.Jobs {
+			if job.Status != StatusSuccess {
+				rerunJobs[job.Name] = true
+			}
+		}
+		if len(rerunJobs) == 0 {
+			return nil, fmt.Errorf("execution %s has no failed jobs", previous.ID)
+		}
+	case RerunJob:
+		for _, job := range previous.Jobs {
+			if job.Name == rerun.Job || job.Group == rerun.Job {
+				rerunJobs[job.Name] = true
+			}
+		}
+		if len(rerunJobs) == 0 {
+			return nil, fmt.Errorf("job not found: %s", rerun.Job)
+		}
+		// Jobs 按拓扑顺序排列，上游 job 先于下游 job 确定
+		for _, job := range previous.Jobs {
+			for _, need := range job.Needs {
+				if rerunJobs[need] {
+					rerunJobs[job.Name] = true
+				}
+			}
+		}
+	}
+
+	reuse := make(map[string]JobExecution, len(previous.Jobs)-len(rerunJobs))
+	for _, job := range previous.Jobs {
+		if rerunJobs[job.Name] {
+			continue
+		}
+		// 多次复用的结果仍指向实际运行 job 的执行
+		if job.ReusedFrom == "" {
+			job.ReusedFrom = previous.ID
+		}
+		reuse[job.Name] = job
+	}
+	return reuse, nil
+}
@@ -0,0 +1,155 @@
+package execution
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRerunExecution(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	// build 记录运行次数并输出版本号；test 第一次失败、之后成功，deploy 依赖 build 和 test
+	config := `jobs:
+  build:
+    outputs:
+      version: ${{ steps.version.outputs.value }}
+    steps:
+    - run: echo x >> builds
+    - id: version
+      run: echo "value=1.$(wc -l < builds)" >> "$GITHUB_OUTPUT"
+  test:
+    needs: build
+    steps:
+    - name: flaky
+      run: |
+        echo x >> tests
+        [ $(wc -l < tests) -ge 2 ]
+  deploy:
+    needs: [build, test]
+    steps:
+    - run: echo "debug=$RUNNER_DEBUG"
+    - run: echo "version=${{ needs.build.outputs.version }}"
+`
+	workDir := t.TempDir()
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         workDir,
+		TriggerInfo:     map[string]interface{}{"ref": "main"},
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	first := waitForStatus(t, manager, executionID)
+	if first.Status != StatusFailed || first.RunID != executionID || first.Attempt != 1 {
+		t.Fatalf("第一次尝试应失败: %s %s %d", first.Status, first.RunID, first.Attempt)
+	}
+
+	// 不存在的 job 和无效的模式不能重新运行
+	if _, err := manager.RerunExecution(executionID, RerunOptions{Mode: RerunJob, Job: "missing"}); err == nil {
+		t.Error("重新运行不存在的 job 应返回错误")
+	}
+	if _, err := manager.RerunExecution(executionID, RerunOptions{Mode: "some"}); err == nil {
+		t.Error("无效的重新运行模式应返回错误")
+	}
+
+	// 只重新运行失败的 job，build 复用第一次尝试的结果
+	rerunID, err := manager.RerunExecution(executionID, RerunOptions{Mode: RerunFailed, Debug: true})
+	if err != nil {
+		t.Fatalf("重新运行失败的 job 失败: %v", err)
+	}
+	if err := manager.StartExecution(rerunID); err != nil {
+		t.Fatalf("启动重新运行的执行失败: %v", err)
+	}
+	second := waitForStatus(t, manager, rerunID)
+	if second.Status != StatusSuccess {
+		t.Fatalf("重新运行应成功: %s %s", second.Status, second.Reason)
+	}
+	if second.RunID != executionID || second.Attempt != 2 || second.RerunOf != executionID || !second.Debug {
+		t.Errorf("重新运行应为同一逻辑运行的第二次尝试: %s %d %s", second.RunID, second.Attempt, second.RerunOf)
+	}
+	if second.TriggerType != first.TriggerType || second.TriggerInfo["ref"] != "main" {
+		t.Errorf("重新运行应使用原执行的触发信息: %s %v", second.TriggerType, second.TriggerInfo)
+	}
+
+	jobs := map[string]JobExecution{}
+	for _, job := range second.Jobs {
+		jobs[job.Name] = job
+	}
+	if build := jobs["build"]; build.Status != StatusSuccess || build.ReusedFrom != executionID || len(build.Steps) != 2 || build.Outputs["version"] != "1.1" {
+		t.Errorf("build 应复用第一次尝试的结果: %+v", build)
+	}
+	if jobs["test"].ReusedFrom != "" || jobs["deploy"].Status != StatusSuccess {
+		t.Errorf("失败和被跳过的 job 应重新运行: %+v", second.Jobs)
+	}
+	if _, ok := second.Metrics.StageDurations["build"]; ok {
+		t.Errorf("复用的 job 不计入本次的阶段耗时: %v", second.Metrics.StageDurations)
+	}
+	if builds, _ := os.ReadFile(filepath.Join(workDir, "builds")); strings.Count(string(builds), "x") != 1 {
+		t.Errorf("复用的 job 不应再次运行: %q", builds)
+	}
+	// 重新运行的 job 读取复用的 job 在上一次尝试中的输出
+	if !hasLog(second, "version=1.1") {
+		t.Error("重新运行的 deploy 应读取复用的 build 的输出")
+	}
+
+	// 开启调试日志时输出 debug 级别的日志，步骤可以读取 RUNNER_DEBUG
+	debug := func(execution *Execution) (int, bool) {
+		count, runnerDebug := 0, false
+		for _, entry := range execution.Logs {
+			if entry.Level == "debug" {
+				count++
+			}
+			runnerDebug = runnerDebug || entry.Message == "debug=1"
+		}
+		return count, runnerDebug
+	}
+	if count, _ := debug(first); count != 0 {
+		t.Errorf("没有开启调试日志的执行不应输出 debug 日志: %d", count)
+	}
+	if count, runnerDebug := debug(second); count == 0 || !runnerDebug {
+		t.Errorf("开启调试日志的执行应输出 debug 日志并设置 RUNNER_DEBUG: %d %v", count, runnerDebug)
+	}
+
+	// 只重新运行 deploy，上游 job 复用的结果指向实际运行它们的执行
+	thirdID, err := manager.RerunExecution(rerunID, RerunOptions{Mode: RerunJob, Job: "deploy"})
+	if err != nil {
+		t.Fatalf("重新运行单个 job 失败: %v", err)
+	}
+	if err := manager.StartExecution(thirdID); err != nil {
+		t.Fatalf("启动重新运行的执行失败: %v", err)
+	}
+	third := waitForStatus(t, manager, thirdID)
+	if third.Status != StatusSuccess || third.Attempt != 3 || third.RunID != executionID || third.Debug {
+		t.Fatalf("第三次尝试不匹配: %s %d %s %v", third.Status, third.Attempt, third.RunID, third.Debug)
+	}
+	reused := map[string]string{}
+	for _, job := range third.Jobs {
+		reused[job.Name] = job.ReusedFrom
+	}
+	if reused["build"] != executionID || reused["test"] != rerunID || reused["deploy"] != "" {
+		t.Errorf("复用的结果来源不匹配: %v", reused)
+	}
+	if !hasLog(third, "version=1.1") {
+		t.Error("多次复用的 build 的输出应保留")
+	}
+
+	if _, err := manager.RerunExecution(thirdID, RerunOptions{Mode: RerunFailed}); err == nil {
+		t.Error("没有失败的 job 时不能只重新运行失败的 job")
+	}
+}
+
+// hasLog 判断执行日志中是否有指定内容的日志
+func hasLog(execution *Execution, message string) bool {
+	for _, entry := range execution.Logs {
+		if entry.Message == message {
+			return true
+		}
+	}
+	return false
+}
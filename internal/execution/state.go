@@ -0,0 +1,147 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 状态变化的发起者
+const (
+	ActorUser   = "user"   // 通过 API 停止执行的用户
+	ActorQueue  = "queue"  // 执行队列和并发组
+	ActorEngine = "engine" // 执行引擎根据运行结果
+	ActorSystem = "system" // 服务自身，例如启动失败或服务重启
+)
+
+// Transition 执行状态的一次变化
+type Transition struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// transitions 执行状态机中合法的状态变化，结束状态不能再变化
+var transitions = map[string][]string{
+	StatusPending: {StatusQueued, StatusRunning, StatusFailed, StatusCancelled, StatusSkipped},
+	StatusQueued:  {StatusRunning, StatusFailed, StatusCancelled, StatusSkipped},
+	StatusRunning: {StatusSuccess, StatusFailed, StatusCancelled, StatusTimedOut, StatusSkipped},
+}
+
+// CanTransition 判断执行能否从状态 from 变为状态 to
+func CanTransition(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError 不合法的状态变化，例如已取消的执行被标记为失败
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal status transition from %s to %s", e.From, e.To)
+}
+
+// IsTransitionError 判断错误是否为不合法的状态变化
+func IsTransitionError(err error) bool {
+	var transitionErr *TransitionError
+	return errors.As(err, &transitionErr)
+}
+
+// applyTransition 按状态机修改执行状态并记录状态变化。进入运行状态时记录开始时间，
+// 进入结束状态时记录结束时间和耗时，reason 作为结束原因。调用方需持有执行记录的写锁
+func applyTransition(execution *Execution, status, actor, reason string) (Transition, error) {
+	if !CanTransition(execution.Status, status) {
+		return Transition{}, &TransitionError{From: execution.Status, To: status}
+	}
+
+	now := time.Now()
+	change := Transition{From: execution.Status, To: status, Actor: actor, Reason: reason, Timestamp: now}
+	execution.Transitions = append(execution.Transitions, change)
+	execution.Status = status
+
+	switch {
+	case status == StatusRunning:
+		execution.StartTime = now
+	case IsFinished(status):
+		execution.EndTime = now
+		if !execution.StartTime.IsZero() {
+			execution.Duration = int64(now.Sub(execution.StartTime).Seconds())
+		}
+		execution.Reason = reason
+	}
+	return change, nil
+}
+
+// transition 按状态机修改执行状态，并通过记录器持久化状态变化，调用方需持有写锁
+func (e *baseEngine) transition(execution *Execution, status, actor, reason string) error {
+	change, err := applyTransition(execution, status, actor, reason)
+	if err != nil {
+		return err
+	}
+	if e.recorder != nil {
+		e.recorder.RecordTransition(execution.ID, change)
+	}
+	return nil
+}
+
+// actorKey 上下文中操作者的键
+type actorKey struct{}
+
+// WithActor 返回携带操作者的上下文，引擎将其记录为状态变化的发起者
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom 获取上下文中的操作者，没有设置时返回 fallback
+func actorFrom(ctx context.Context, fallback string) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return fallback
+}
+
+// cancellation 执行被 Cancel 取消的原因和操作者，作为执行上下文的 Cause 传递给运行中的 job 和子进程
+type cancellation struct {
+	actor  string
+	reason string
+}
+
+func (c *cancellation) Error() string {
+	return c.reason
+}
+
+// cancelReason 获取执行上下文被取消的原因
+func cancelReason(ctx context.Context) string {
+	if cause := context.Cause(ctx); cause != nil && cause != context.Canceled {
+		return cause.Error()
+	}
+	return "execution cancelled"
+}
+
+// outcome 根据失败的 job 确定执行的结束状态，失败的 job 都因超时被取消时为 timed_out，调用方需持有锁
+func outcome(execution *Execution, failed []string) string {
+	if len(failed) == 0 {
+		return StatusSuccess
+	}
+
+	statuses := make(map[string]string, len(execution.Jobs))
+	for _, job := range execution.Jobs {
+		statuses[job.Name] = job.Status
+	}
+	for _, name := range failed {
+		if statuses[name] != StatusTimedOut {
+			return StatusFailed
+		}
+	}
+	return StatusTimedOut
+}
@@ -0,0 +1,216 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		legal    bool
+	}{
+		{StatusPending, StatusQueued, true},
+		{StatusQueued, StatusRunning, true},
+		{StatusQueued, StatusCancelled, true},
+		{StatusRunning, StatusTimedOut, true},
+		{StatusRunning, StatusSkipped, true},
+		{StatusQueued, StatusSuccess, false},
+		{StatusCancelled, StatusFailed, false},
+		{StatusCancelled, StatusSuccess, false},
+		{StatusSuccess, StatusRunning, false},
+		{StatusTimedOut, StatusCancelled, false},
+	}
+	for _, tt := range tests {
+		execution := &Execution{ID: "e1", Status: tt.from}
+		_, err := applyTransition(execution, tt.to, ActorEngine, "")
+		if legal := err == nil; legal != tt.legal {
+			t.Errorf("%s -> %s: 期望合法 %v, 实际错误 %v", tt.from, tt.to, tt.legal, err)
+		}
+		if err != nil && (!IsTransitionError(err) || execution.Status != tt.from || len(execution.Transitions) != 0) {
+			t.Errorf("不合法的状态变化不应修改执行: %+v, %v", execution, err)
+		}
+	}
+
+	execution := &Execution{ID: "e1", Status: StatusQueued}
+	applyTransition(execution, StatusRunning, ActorQueue, "")
+	change, err := applyTransition(execution, StatusCancelled, ActorUser, "stopped")
+	if err != nil {
+		t.Fatalf("状态变化失败: %v", err)
+	}
+	if change.From != StatusRunning || change.To != StatusCancelled || change.Actor != ActorUser || change.Timestamp.IsZero() {
+		t.Errorf("状态变化记录不匹配: %+v", change)
+	}
+	if len(execution.Transitions) != 2 || execution.Reason != "stopped" || execution.StartTime.IsZero() || execution.EndTime.IsZero() {
+		t.Errorf("执行记录不匹配: %+v", execution)
+	}
+}
+
+func TestStopLocalExecution(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	// 后台进程持有输出管道，只终止 shell 时步骤要等 processWaitDelay 才结束
+	config := `jobs:
+  build:
+    steps:
+    - name: hang
+      run: |
+        sleep 30 &
+        echo started
+        wait
+  deploy:
+    needs: build
+    steps:
+    - run: echo deploy
+`
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	waitFor(t, manager, executionID, func(execution *Execution) bool {
+		for _, entry := range execution.Logs {
+			if entry.Message == "started" {
+				return true
+			}
+		}
+		return false
+	})
+
+	stopped := time.Now()
+	if err := manager.StopExecution(executionID); err != nil {
+		t.Fatalf("停止执行失败: %v", err)
+	}
+
+	// 步骤的进程组被终止后，步骤立即结束
+	execution := waitFor(t, manager, executionID, func(execution *Execution) bool {
+		return len(execution.Jobs[0].Steps[0].Attempts) > 0
+	})
+	if elapsed := time.Since(stopped); elapsed > 3*time.Second {
+		t.Errorf("停止执行应终止步骤启动的所有进程: 步骤耗时 %s", elapsed)
+	}
+
+	if execution.Status != StatusCancelled || execution.Reason != "Execution cancelled by user" {
+		t.Fatalf("执行应已取消: %s %q", execution.Status, execution.Reason)
+	}
+	for _, job := range execution.Jobs {
+		if job.Status != StatusCancelled || job.Reason != execution.Reason {
+			t.Errorf("job 应随执行取消: %+v", job)
+		}
+	}
+
+	var path []string
+	for _, change := range execution.Transitions {
+		path = append(path, change.From+"->"+change.To+"@"+change.Actor)
+	}
+	want := "pending->queued@queue running->cancelled@user"
+	if got := strings.Join([]string{path[0], path[len(path)-1]}, " "); len(path) != 3 || got != want {
+		t.Errorf("状态变化记录不匹配: %v", path)
+	}
+
+	// 已取消的执行不会被运行结果覆盖
+	time.Sleep(200 * time.Millisecond)
+	if current, _ := manager.GetExecution(executionID); current.Status != StatusCancelled || len(current.Transitions) != 3 {
+		t.Errorf("已取消的执行不应再变化: %s %+v", current.Status, current.Transitions)
+	}
+	if err := manager.StopExecution(executionID); err == nil {
+		t.Error("已结束的执行不能再次停止")
+	}
+}
+
+func TestExecuteContextCancelled(t *testing.T) {
+	engine := NewMockEngine()
+	engine.(executionRegistrar).RegisterExecution(&Execution{ID: "e1", Status: StatusPending})
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	if err := engine.Execute(ctx, "e1", ExecutionOptions{StageDurations: map[string]int{"build": 5}}); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	cancel(errors.New("server shutting down"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		execution, _ := engine.GetStatus("e1")
+		if IsFinished(execution.Status) {
+			last := execution.Transitions[len(execution.Transitions)-1]
+			if execution.Status != StatusCancelled || !strings.Contains(execution.Reason, "server shutting down") || last.Actor != ActorSystem {
+				t.Errorf("上级上下文取消时执行应以其原因取消: %s %q %+v", execution.Status, execution.Reason, last)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("等待执行结束超时")
+}
+
+// waitFor 等待执行满足条件
+func waitFor(t *testing.T, manager Manager, executionID string, done func(execution *Execution) bool) *Execution {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		execution, err := manager.GetExecution(executionID)
+		if err != nil {
+			t.Fatalf("获取执行详情失败: %v", err)
+		}
+		if done(execution) {
+			return execution
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("等待执行超时")
+	return nil
+}
+
+func TestListExecutionsReturnsCopies(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	config := `jobs:
+  build:
+    steps:
+    - run: sleep 0.2
+`
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+
+	// 引擎运行执行的同时列出和修改执行，在 -race 下检查没有数据竞争
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			executions, err := manager.ListExecutions("1", 10, 0)
+			if err != nil || len(executions) != 1 {
+				t.Errorf("列出执行失败: %v %d", err, len(executions))
+				return
+			}
+			executions[0].Status = "modified"
+			executions[0].Jobs = nil
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+	<-done
+
+	if execution := waitForStatus(t, manager, executionID); execution.Status != StatusSuccess || len(execution.Jobs) != 1 {
+		t.Errorf("修改列出的执行不应影响执行记录: %s %d", execution.Status, len(execution.Jobs))
+	}
+}
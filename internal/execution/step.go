@@ -0,0 +1,361 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 重试策略的默认值和上限
+const (
+	DefaultRetryDelay    = time.Second // 第一次重试前的等待时间
+	DefaultRetryBackoff  = 2.0         // 每次重试等待时间的倍数
+	DefaultRetryMaxDelay = time.Minute // 重试等待时间的上限
+	MaxRetryAttempts     = 10          // 包含第一次在内的最多尝试次数
+)
+
+// DefaultJobTimeout 没有配置 timeout-minutes 时 job 的超时时间，与 GitHub Actions 相同
+const DefaultJobTimeout = 360 * time.Minute
+
+// RetryPolicy step 失败后的自动重试策略，是本系统对 workflow 的扩展。
+// 可以写成最多尝试次数，例如 retry: 3，也可以写成包含等待时间的映射
+type RetryPolicy struct {
+	MaxAttempts     int     `yaml:"max-attempts"`      // 包含第一次在内的最多尝试次数
+	DelaySeconds    float64 `yaml:"delay-seconds"`     // 第一次重试前的等待时间（秒）
+	Backoff         float64 `yaml:"backoff"`           // 每次重试等待时间的倍数
+	MaxDelaySeconds float64 `yaml:"max-delay-seconds"` // 重试等待时间的上限（秒）
+}
+
+// UnmarshalYAML 解析重试次数或重试策略映射，并校验取值范围
+func (p *RetryPolicy) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if err := value.Decode(&p.MaxAttempts); err != nil {
+			return fmt.Errorf("line %d: retry must be a number of attempts or a mapping", value.Line)
+		}
+	} else {
+		type plain RetryPolicy
+		if err := value.Decode((*plain)(p)); err != nil {
+			return err
+		}
+	}
+
+	if p.MaxAttempts < 1 || p.MaxAttempts > MaxRetryAttempts {
+		return fmt.Errorf("line %d: retry max-attempts must be between 1 and %d", value.Line, MaxRetryAttempts)
+	}
+	if p.DelaySeconds < 0 || p.MaxDelaySeconds < 0 || p.Backoff < 0 {
+		return fmt.Errorf("line %d: retry delay-seconds, backoff and max-delay-seconds must not be negative", value.Line)
+	}
+	return nil
+}
+
+// attempts 返回最多尝试次数，没有配置重试时为 1
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// delay 返回第 attempt 次尝试失败后、下一次尝试前的等待时间，按倍数递增且不超过上限
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	base, backoff, max := DefaultRetryDelay, DefaultRetryBackoff, DefaultRetryMaxDelay
+	if p.DelaySeconds > 0 {
+		base = seconds(p.DelaySeconds)
+	}
+	if p.Backoff > 0 {
+		backoff = p.Backoff
+	}
+	if p.MaxDelaySeconds > 0 {
+		max = seconds(p.MaxDelaySeconds)
+	}
+
+	delay := float64(base) * math.Pow(backoff, float64(attempt-1))
+	if delay > float64(max) {
+		return max
+	}
+	return time.Duration(delay)
+}
+
+// seconds 将秒数转换为时长
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// Minutes 以分钟为单位的时长配置，例如 timeout-minutes，也可以写成 ${{ }} 表达式
+type Minutes string
+
+// UnmarshalYAML 解析非负的分钟数或表达式
+func (m *Minutes) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: timeout-minutes must be a number", value.Line)
+	}
+	if !isExpression(value.Value) {
+		if number, err := strconv.ParseFloat(value.Value, 64); err != nil || number < 0 {
+			return fmt.Errorf("line %d: timeout-minutes must be a non-negative number", value.Line)
+		}
+	}
+	*m = Minutes(value.Value)
+	return nil
+}
+
+// duration 计算时长，没有配置时返回 0；表达式无效或结果不是非负数时返回 0 和错误，由调用方决定使用的默认值
+func (m Minutes) duration(context map[string]interface{}) (time.Duration, error) {
+	if m == "" {
+		return 0, nil
+	}
+	value, err := evaluateValue(string(m), context)
+	if err != nil {
+		return 0, err
+	}
+	number := toNumber(value)
+	if math.IsNaN(number) || number < 0 {
+		return 0, fmt.Errorf("%s evaluated to %q, expected a non-negative number", m, expressionString(value))
+	}
+	return time.Duration(number * float64(time.Minute)), nil
+}
+
+// Flag 布尔配置项，例如 continue-on-error，也可以写成 ${{ }} 表达式
+type Flag string
+
+// UnmarshalYAML 解析布尔值或表达式
+func (f *Flag) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: expected a boolean", value.Line)
+	}
+	if !isExpression(value.Value) {
+		if _, err := strconv.ParseBool(value.Value); err != nil {
+			return fmt.Errorf("line %d: expected a boolean, got %q", value.Line, value.Value)
+		}
+	}
+	*f = Flag(value.Value)
+	return nil
+}
+
+// value 计算布尔值，没有配置时为 false
+func (f Flag) value(context map[string]interface{}) (bool, error) {
+	raw := strings.TrimSpace(string(f))
+	if raw == "" {
+		return false, nil
+	}
+	if isExpression(raw) {
+		result, err := evaluateValue(raw, context)
+		if err != nil {
+			return false, err
+		}
+		return truthy(result), nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%q must be a boolean", raw)
+	}
+	return enabled, nil
+}
+
+// enabled 计算布尔值，表达式无效时为 false
+func (f Flag) enabled(context map[string]interface{}) bool {
+	enabled, _ := f.value(context)
+	return enabled
+}
+
+// jobContext 计算 job 和 step 配置项中表达式的上下文，矩阵实例可以引用 matrix
+func jobContext(job Job) map[string]interface{} {
+	matrix := job.MatrixValues
+	if matrix == nil {
+		matrix = map[string]interface{}{}
+	}
+	return map[string]interface{}{"matrix": matrix}
+}
+
+// timeoutError job 或 step 超过 timeout-minutes
+type timeoutError struct {
+	kind    string // job 或 step
+	name    string
+	timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s %s timed out after %s", e.kind, e.name, e.timeout)
+}
+
+// withTimeout 为 job 或 step 创建超时上下文，超时时上下文的 Cause 为 timeoutError，timeout 为 0 时不限制
+func withTimeout(ctx context.Context, kind, name string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, &timeoutError{kind: kind, name: name, timeout: timeout})
+}
+
+// timedOut 上下文因超时结束时返回 timeoutError，否则返回 nil
+func timedOut(ctx context.Context) *timeoutError {
+	var timeout *timeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return timeout
+	}
+	return nil
+}
+
+// stepAttempt 运行 step 的一次尝试，attempt 从 1 开始
+type stepAttempt func(ctx context.Context, attempt int) error
+
+// runStepAttempts 运行 job 的第 index 个 step。每次尝试受 timeout-minutes 限制，配置了 retry 时失败的尝试
+// 按退避时间重试，每次尝试都记录到 job 的 step 状态中。返回最后一次尝试的错误，
+// continue-on-error 只影响记录的状态，是否继续运行 job 由调用方决定
+func (e *baseEngine) runStepAttempts(ctx context.Context, executionID, jobName string, index int, job Job, run stepAttempt) error {
+	step := job.Steps[index]
+	name := stepDisplayName(step, index)
+	maxAttempts := step.Retry.attempts()
+	timeout, err := step.Timeout.duration(jobContext(job))
+	if err != nil {
+		e.addLogWithStep(executionID, "warn", jobName, name, fmt.Sprintf("Invalid timeout-minutes for step %s, the step is only limited by the job timeout: %v", name, err))
+	}
+
+	e.updateStep(executionID, jobName, index, func(state *StepExecution) {
+		state.Status = StatusRunning
+	})
+
+	for attempt := 1; ; attempt++ {
+		e.addDebugLog(executionID, jobName, name, fmt.Sprintf("Step %s attempt %d/%d", name, attempt, maxAttempts), map[string]interface{}{
+			"attempt":           attempt,
+			"timeout":           timeout.String(),
+			"continue_on_error": step.ContinueOnError.enabled(jobContext(job)),
+		})
+		attemptCtx, cancel := withTimeout(ctx, "step", name, timeout)
+		start := time.Now()
+		err := run(attemptCtx, attempt)
+		timedOut := timedOut(attemptCtx)
+		cancel()
+
+		record := StepAttempt{
+			Attempt:   attempt,
+			Status:    StatusSuccess,
+			StartTime: start,
+			EndTime:   time.Now(),
+			Duration:  time.Since(start).Milliseconds(),
+		}
+		switch {
+		case ctx.Err() != nil:
+			record.Status = StatusCancelled
+			err = ctx.Err()
+		case timedOut != nil:
+			record.Status = StatusFailed
+			record.TimedOut = true
+			err = timedOut
+		case err != nil:
+			record.Status = StatusFailed
+		}
+		if err != nil && record.Status == StatusFailed {
+			record.Error = err.Error()
+		}
+		if record.TimedOut {
+			e.addLogEntry(executionID, LogEntry{
+				Level:   "error",
+				Stage:   jobName,
+				Step:    name,
+				Message: fmt.Sprintf("Step %s cancelled: timed out after %s", name, timeout),
+				Context: map[string]interface{}{"attempt": attempt},
+			})
+		}
+
+		finished := err == nil || ctx.Err() != nil || attempt >= maxAttempts
+		e.updateStep(executionID, jobName, index, func(state *StepExecution) {
+			state.Attempts = append(state.Attempts, record)
+			if finished {
+				state.Status = record.Status
+			}
+		})
+		if finished {
+			return err
+		}
+
+		delay := step.Retry.delay(attempt)
+		e.addLogEntry(executionID, LogEntry{
+			Level:   "warn",
+			Stage:   jobName,
+			Step:    name,
+			Message: fmt.Sprintf("Step %s failed on attempt %d/%d: %v; retrying in %s", name, attempt, maxAttempts, err, delay),
+			Context: map[string]interface{}{"attempt": attempt},
+		})
+		if !sleepContext(ctx, delay) {
+			e.updateStep(executionID, jobName, index, func(state *StepExecution) {
+				state.Status = StatusCancelled
+			})
+			return ctx.Err()
+		}
+	}
+}
+
+// skipStep 将 step 标记为已跳过，例如本地引擎无法运行的 Action
+func (e *baseEngine) skipStep(executionID, jobName string, index int) {
+	e.updateStep(executionID, jobName, index, func(state *StepExecution) {
+		state.Status = StatusSkipped
+	})
+}
+
+// updateStep 修改 job 中第 index 个 step 的状态并通知订阅者
+func (e *baseEngine) updateStep(executionID, jobName string, index int, update func(state *StepExecution)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	execution, exists := e.executions[executionID]
+	if !exists {
+		return
+	}
+	for i := range execution.Jobs {
+		job := &execution.Jobs[i]
+		if job.Name != jobName || index >= len(job.Steps) {
+			continue
+		}
+		update(&job.Steps[index])
+		e.notify(execution)
+		return
+	}
+}
+
+// initSteps 根据 job 配置初始化 step 状态
+func initSteps(job Job) []StepExecution {
+	if len(job.Steps) == 0 {
+		return nil
+	}
+	context := jobContext(job)
+	steps := make([]StepExecution, len(job.Steps))
+	for i, step := range job.Steps {
+		steps[i] = StepExecution{
+			Name:            stepDisplayName(step, i),
+			Status:          StatusPending,
+			ContinueOnError: step.ContinueOnError.enabled(context),
+		}
+	}
+	return steps
+}
+
+// copyJobs 深拷贝 job 状态，包括 step 和每次尝试的记录
+func copyJobs(jobs []JobExecution) []JobExecution {
+	if jobs == nil {
+		return nil
+	}
+	copied := make([]JobExecution, len(jobs))
+	copy(copied, jobs)
+	for i := range copied {
+		if jobs[i].Outputs != nil {
+			copied[i].Outputs = make(map[string]string, len(jobs[i].Outputs))
+			for key, value := range jobs[i].Outputs {
+				copied[i].Outputs[key] = value
+			}
+		}
+		if copied[i].Steps == nil {
+			continue
+		}
+		copied[i].Steps = make([]StepExecution, len(jobs[i].Steps))
+		copy(copied[i].Steps, jobs[i].Steps)
+		for j := range copied[i].Steps {
+			copied[i].Steps[j].Attempts = append([]StepAttempt(nil), jobs[i].Steps[j].Attempts...)
+		}
+	}
+	return copied
+}
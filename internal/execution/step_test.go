@@ -0,0 +1,200 @@
+package execution
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var step Step
+	if err := yaml.Unmarshal([]byte("run: make\nretry: 3\n"), &step); err != nil || step.Retry.attempts() != 3 {
+		t.Fatalf("解析重试次数失败: %v, %+v", err, step.Retry)
+	}
+
+	step = Step{}
+	content := "run: make\nretry:\n  max-attempts: 5\n  delay-seconds: 2\n  backoff: 3\n  max-delay-seconds: 10\n"
+	if err := yaml.Unmarshal([]byte(content), &step); err != nil {
+		t.Fatalf("解析重试策略失败: %v", err)
+	}
+	for attempt, want := range map[int]time.Duration{1: 2 * time.Second, 2: 6 * time.Second, 3: 10 * time.Second} {
+		if got := step.Retry.delay(attempt); got != want {
+			t.Errorf("第 %d 次尝试后的等待时间不匹配: 期望 %s, 实际 %s", attempt, want, got)
+		}
+	}
+	if (&RetryPolicy{MaxAttempts: 2}).delay(1) != DefaultRetryDelay {
+		t.Error("没有配置等待时间时应使用默认值")
+	}
+	if (*RetryPolicy)(nil).attempts() != 1 {
+		t.Error("没有配置重试时只尝试一次")
+	}
+
+	// timeout-minutes 和 continue-on-error 可以引用矩阵
+	step = Step{}
+	content = "run: make\ntimeout-minutes: ${{ matrix.timeout }}\ncontinue-on-error: ${{ matrix.experimental }}\n"
+	if err := yaml.Unmarshal([]byte(content), &step); err != nil {
+		t.Fatalf("解析表达式配置失败: %v", err)
+	}
+	context := jobContext(Job{MatrixValues: map[string]interface{}{"timeout": 2, "experimental": true}})
+	if got, err := step.Timeout.duration(context); err != nil || got != 2*time.Minute {
+		t.Errorf("表达式超时时间不匹配: %s %v", got, err)
+	}
+	// 没有配置时不限制，表达式结果不是非负数时返回错误
+	if got, err := (Minutes("")).duration(context); err != nil || got != 0 {
+		t.Errorf("没有配置的超时时间不匹配: %s %v", got, err)
+	}
+	for _, invalid := range []Minutes{"${{ matrix.missing.x }}x", "${{ matrix.experimental && 'soon' }}", "${{ -1 }}", "${{ matrix.( }}"} {
+		if _, err := invalid.duration(context); err == nil {
+			t.Errorf("%s 应返回错误", invalid)
+		}
+	}
+	if !step.ContinueOnError.enabled(context) || step.ContinueOnError.enabled(jobContext(Job{})) {
+		t.Error("表达式 continue-on-error 计算结果不匹配")
+	}
+
+	for _, invalid := range []string{"retry: 0", "retry: 11", "retry: {max-attempts: 2, delay-seconds: -1}", "retry: often", "timeout-minutes: -1", "continue-on-error: maybe"} {
+		if err := yaml.Unmarshal([]byte("run: make\n"+invalid+"\n"), &Step{}); err == nil {
+			t.Errorf("无效的重试配置应返回错误: %s", invalid)
+		}
+	}
+}
+
+func TestLocalEngineStepRetries(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	// flaky 第一次失败、第二次成功；lint 失败但 continue-on-error 让 job 继续
+	config := `jobs:
+  build:
+    steps:
+    - name: flaky
+      run: |
+        n=$(cat attempts 2>/dev/null || echo 0)
+        n=$((n+1))
+        echo $n > attempts
+        [ "$n" -ge 2 ]
+      retry:
+        max-attempts: 3
+        delay-seconds: 0.05
+    - name: lint
+      run: exit 2
+      continue-on-error: true
+    - name: done
+      run: echo done
+`
+
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusSuccess {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusSuccess, execution.Status)
+	}
+
+	steps := execution.Jobs[0].Steps
+	if len(steps) != 3 {
+		t.Fatalf("step 状态数量不匹配: %+v", steps)
+	}
+	flaky := steps[0]
+	if flaky.Status != StatusSuccess || len(flaky.Attempts) != 2 || flaky.Attempts[0].Status != StatusFailed || flaky.Attempts[1].Status != StatusSuccess {
+		t.Errorf("重试的 step 应记录每次尝试: %+v", flaky)
+	}
+	if steps[1].Status != StatusFailed || !steps[1].ContinueOnError || len(steps[1].Attempts) != 1 {
+		t.Errorf("continue-on-error 的 step 应标记为失败: %+v", steps[1])
+	}
+	if steps[2].Status != StatusSuccess {
+		t.Errorf("continue-on-error 之后的 step 应继续运行: %+v", steps[2])
+	}
+
+	results, _ := execution.PlatformData["steps"].([]StepResult)
+	if len(results) != 4 || results[0].Attempt != 1 || results[1].Attempt != 2 {
+		t.Errorf("每次尝试应记录一条步骤结果: %+v", results)
+	}
+}
+
+func TestLocalEngineTimeouts(t *testing.T) {
+	manager := NewManager(nil, nil)
+	manager.RegisterEngine("local", NewLocalEngine())
+
+	// 超时的 step 被终止并按重试策略再尝试一次；job 超时时终止正在运行的 step
+	config := `jobs:
+  step-timeout:
+    steps:
+    - name: hang
+      run: exec sleep 30
+      timeout-minutes: 0.005
+      retry: {max-attempts: 2, delay-seconds: 0.05}
+    - name: after
+      run: echo after
+  job-timeout:
+    timeout-minutes: 0.005
+    steps:
+    - name: hang
+      run: exec sleep 30
+  invalid-timeout:
+    timeout-minutes: ${{ 'soon' }}
+    steps:
+    - run: echo ok
+`
+
+	executionID, err := manager.CreateExecution("1", "local", "manual", ExecutionOptions{
+		CIConfigContent: config,
+		WorkDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建执行失败: %v", err)
+	}
+	if err := manager.StartExecution(executionID); err != nil {
+		t.Fatalf("启动执行失败: %v", err)
+	}
+
+	start := time.Now()
+	execution := waitForStatus(t, manager, executionID)
+	if execution.Status != StatusFailed {
+		t.Fatalf("执行状态不匹配: 期望 %s, 实际 %s", StatusFailed, execution.Status)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("超时的 step 应被终止: 执行耗时 %s", elapsed)
+	}
+
+	jobs := map[string]JobExecution{}
+	for _, job := range execution.Jobs {
+		jobs[job.Name] = job
+	}
+
+	stepTimeout := jobs["step-timeout"]
+	hang := stepTimeout.Steps[0]
+	if stepTimeout.Status != StatusFailed || len(hang.Attempts) != 2 || !hang.Attempts[0].TimedOut || !hang.Attempts[1].TimedOut {
+		t.Errorf("超时的 step 应记录每次超时的尝试: %+v", stepTimeout)
+	}
+	if stepTimeout.Steps[1].Status != StatusSkipped {
+		t.Errorf("失败后未运行的 step 应被跳过: %+v", stepTimeout.Steps[1])
+	}
+
+	jobTimeout := jobs["job-timeout"]
+	if jobTimeout.Status != StatusTimedOut || !strings.Contains(jobTimeout.Reason, "timed out") || jobTimeout.Steps[0].Status != StatusCancelled {
+		t.Errorf("超时的 job 应为 timed_out 并取消正在运行的 step: %+v", jobTimeout)
+	}
+
+	// 无效的超时表达式记录警告，job 使用默认的超时时间运行
+	if jobs["invalid-timeout"].Status != StatusSuccess {
+		t.Errorf("超时表达式无效的 job 应使用默认超时时间运行: %+v", jobs["invalid-timeout"])
+	}
+	warned := false
+	for _, entry := range execution.Logs {
+		warned = warned || (entry.Level == "warn" && strings.Contains(entry.Message, "Invalid timeout-minutes for job invalid-timeout") && strings.Contains(entry.Message, DefaultJobTimeout.String()))
+	}
+	if !warned {
+		t.Error("超时表达式无效时应记录警告")
+	}
+}
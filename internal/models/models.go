@@ -27,31 +27,95 @@ type TechStack struct {
 
 // Pipeline 管道配置模型
 type Pipeline struct {
-	ID        int       `json:"id"`
-	ProjectID int       `json:"project_id"`
-	Platform  string    `json:"platform"`
-	Config    string    `json:"config"` // YAML 格式
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              int       `json:"id"`
+	ProjectID       int       `json:"project_id"`
+	Platform        string    `json:"platform"`
+	Config          string    `json:"config"`                     // YAML 格式
+	TemplateID      int       `json:"template_id,omitempty"`      // 生成配置使用的模板，手动编写的配置为 0
+	TemplateVersion int       `json:"template_version,omitempty"` // 生成配置使用的模板版本
+	Revision        int       `json:"revision"`                   // 当前修订版本号，每次修改递增
+	Filename        string    `json:"filename,omitempty"`         // 项目目录中配置文件的相对路径
+	DriftStatus     string    `json:"drift_status,omitempty"`     // 与项目目录中配置文件的最近一次比较结果
+	DriftCheckedAt  time.Time `json:"drift_checked_at,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// PipelineRevision 管道配置的修订版本，保存每次修改后的完整配置
+type PipelineRevision struct {
+	ID              int       `json:"id"`
+	PipelineID      int       `json:"pipeline_id"`
+	Revision        int       `json:"revision"`
+	Config          string    `json:"config"`
+	TemplateID      int       `json:"template_id,omitempty"`
+	TemplateVersion int       `json:"template_version,omitempty"`
+	Source          string    `json:"source"` // generated、manual、optimization 或 rollback
+	Reason          string    `json:"reason"`
+	Author          string    `json:"author"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 // Execution 执行历史模型
 type Execution struct {
-	ID         int       `json:"id"`
-	ProjectID  int       `json:"project_id"`
-	PipelineID int       `json:"pipeline_id"`
-	Status     string    `json:"status"`
-	StartTime  time.Time `json:"start_time"`
-	EndTime    time.Time `json:"end_time"`
-	Duration   int       `json:"duration"` // 秒
-	Logs       string    `json:"logs"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID           string    `json:"id"` // UUID
+	ProjectID    int       `json:"project_id"`
+	PipelineID   int       `json:"pipeline_id"`
+	PipelineRevision int   `json:"pipeline_revision"` // 执行时管道配置的修订版本
+	Platform     string    `json:"platform"`
+	Status       string    `json:"status"`
+	Priority     string    `json:"priority"` // 排队的优先级类别
+	Reason       string    `json:"reason"`   // 执行被取消或启动失败的原因
+	RunID        string    `json:"run_id"`   // 逻辑运行 ID，即第一次尝试的执行 ID
+	Attempt      int       `json:"attempt"`  // 在逻辑运行中的尝试序号，从 1 开始
+	RerunOf      string    `json:"rerun_of"` // 重新运行的执行 ID
+	Debug        bool      `json:"debug"`    // 是否输出调试日志
+	Options      string    `json:"options"`  // JSON 格式，创建执行时的选项，只在创建时写入，用于重新运行
+	TriggerType  string    `json:"trigger_type"`
+	TriggerInfo  string    `json:"trigger_info"`  // JSON 格式
+	PlatformData string    `json:"platform_data"` // JSON 格式
+	Jobs         string    `json:"jobs"`          // JSON 格式，各 job 的状态和耗时
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Duration     int       `json:"duration"` // 秒
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ExecutionLog 执行日志模型
+type ExecutionLog struct {
+	ID          string    `json:"id"` // UUID
+	ExecutionID string    `json:"execution_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Level       string    `json:"level"`
+	Stage       string    `json:"stage"`
+	Step        string    `json:"step"`
+	Message     string    `json:"message"`
+	Context     string    `json:"context"` // JSON 格式
+}
+
+// ExecutionTransition 执行状态变化模型
+type ExecutionTransition struct {
+	ID          int       `json:"id"`
+	ExecutionID string    `json:"execution_id"`
+	FromStatus  string    `json:"from_status"`
+	ToStatus    string    `json:"to_status"`
+	Actor       string    `json:"actor"`
+	Reason      string    `json:"reason"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// QueuedExecution 执行队列中等待启动的执行
+type QueuedExecution struct {
+	ID          int       `json:"id"` // 入队顺序
+	ExecutionID string    `json:"execution_id"`
+	Options     string    `json:"options"` // JSON 格式
+	EnqueuedAt  time.Time `json:"enqueued_at"`
 }
 
 // Metric 指标模型
 type Metric struct {
 	ID          int       `json:"id"`
-	ExecutionID int       `json:"execution_id"`
+	ExecutionID string    `json:"execution_id"`
 	Name        string    `json:"name"`
 	Value       float64   `json:"value"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -67,3 +131,63 @@ type Optimization struct {
 	Applied     bool      `json:"applied"`
 	CreatedAt   time.Time `json:"created_at"`
 }
+
+// SecurityFinding 管道配置安全检查发现的问题
+type SecurityFinding struct {
+	ID         int       `json:"id"`
+	ProjectID  int       `json:"project_id"`
+	PipelineID int       `json:"pipeline_id"`
+	Rule       string    `json:"rule"`
+	Severity   string    `json:"severity"`
+	Job        string    `json:"job"`
+	Line       int       `json:"line"`
+	Column     int       `json:"column"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SecurityRuleSetting 项目的安全检查规则开关，没有记录的规则默认启用
+type SecurityRuleSetting struct {
+	ProjectID int       `json:"project_id"`
+	Rule      string    `json:"rule"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery 收到的 webhook 投递，保存原始负载用于查看和重新投递
+type WebhookDelivery struct {
+	ID           int       `json:"id"`
+	Provider     string    `json:"provider"`    // github、gitlab 或 gitea
+	DeliveryID   string    `json:"delivery_id"` // 代码托管平台生成的投递 ID
+	Event        string    `json:"event"`       // 请求头中的事件名称
+	Platform     string    `json:"platform"`    // 触发的执行平台
+	Payload      string    `json:"payload"`
+	Status       string    `json:"status"` // processed、ignored、unmatched 或 failed
+	Message      string    `json:"message,omitempty"`
+	ExecutionIDs []string  `json:"execution_ids"`
+	Duplicates   int       `json:"duplicates"`   // 重复投递被忽略的次数
+	Redeliveries int       `json:"redeliveries"` // 重新投递的次数
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Schedule 定时执行任务，来自管道配置的 on.schedule 或通过 API 创建
+type Schedule struct {
+	ID              int       `json:"id"`
+	ProjectID       int       `json:"project_id"`
+	PipelineID      int       `json:"pipeline_id,omitempty"` // 来自管道配置时为该管道配置的 ID
+	Source          string    `json:"source"`                // pipeline 或 api
+	Name            string    `json:"name"`
+	Cron            string    `json:"cron"`
+	Timezone        string    `json:"timezone"` // cron 表达式使用的时区，例如 Asia/Shanghai
+	Platform        string    `json:"platform"` // 触发的执行平台
+	Ref             string    `json:"ref,omitempty"`
+	CatchUp         string    `json:"catch_up"` // 错过的触发时间的处理策略：skip、latest 或 all
+	Enabled         bool      `json:"enabled"`
+	NextRunAt       time.Time `json:"next_run_at,omitzero"`
+	LastRunAt       time.Time `json:"last_run_at,omitzero"` // 最近一次触发的计划时间
+	LastExecutionID string    `json:"last_execution_id,omitempty"`
+	LastError       string    `json:"last_error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -2,11 +2,17 @@ package repository
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"ci-cd-orchestrator/internal/models"
+
+	"github.com/google/uuid"
 )
 
+// executionColumns 执行历史查询字段
+const executionColumns = `id, project_id, pipeline_id, pipeline_revision, platform, status, priority, reason, run_id, attempt, rerun_of, debug, trigger_type, trigger_info, platform_data, jobs, start_time, end_time, duration, created_at, updated_at`
+
 // ExecutionRepository 执行历史仓库
 type ExecutionRepository struct {
 	db *sql.DB
@@ -17,36 +23,55 @@ func NewExecutionRepository(db *sql.DB) *ExecutionRepository {
 	return &ExecutionRepository{db: db}
 }
 
-// Create 创建执行历史
+// Create 创建执行历史，未指定 ID 时自动生成 UUID
 func (r *ExecutionRepository) Create(execution *models.Execution) error {
 	query := `
-		INSERT INTO executions (project_id, pipeline_id, status, start_time, end_time, duration, logs, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO executions (id, project_id, pipeline_id, pipeline_revision, platform, status, priority, reason, run_id, attempt, rerun_of, debug, options, trigger_type, trigger_info, platform_data, jobs, start_time, end_time, duration, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	if execution.ID == "" {
+		execution.ID = uuid.New().String()
+	}
+	if execution.RunID == "" {
+		execution.RunID = execution.ID
+	}
+	if execution.Attempt == 0 {
+		execution.Attempt = 1
+	}
+
 	now := time.Now()
-	result, err := r.db.Exec(
+	_, err := r.db.Exec(
 		query,
+		execution.ID,
 		execution.ProjectID,
-		execution.PipelineID,
+		nullableInt(execution.PipelineID),
+		nullableInt(execution.PipelineRevision),
+		execution.Platform,
 		execution.Status,
+		nullString(execution.Priority),
+		nullString(execution.Reason),
+		execution.RunID,
+		execution.Attempt,
+		nullString(execution.RerunOf),
+		execution.Debug,
+		nullString(execution.Options),
+		execution.TriggerType,
+		execution.TriggerInfo,
+		execution.PlatformData,
+		execution.Jobs,
 		execution.StartTime,
 		execution.EndTime,
 		execution.Duration,
-		execution.Logs,
+		now,
 		now,
 	)
 	if err != nil {
 		return err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
-
-	execution.ID = int(id)
 	execution.CreatedAt = now
+	execution.UpdatedAt = now
 
 	return nil
 }
@@ -54,79 +79,511 @@ func (r *ExecutionRepository) Create(execution *models.Execution) error {
 // GetByProjectID 根据项目 ID 获取执行历史
 func (r *ExecutionRepository) GetByProjectID(projectID int) ([]*models.Execution, error) {
 	query := `
-		SELECT id, project_id, pipeline_id, status, start_time, end_time, duration, logs, created_at
+		SELECT ` + executionColumns + `
 		FROM executions
 		WHERE project_id = ?
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, projectID)
+	return r.query(query, projectID)
+}
+
+// ListByProjectID 分页获取项目的执行历史，按创建时间倒序
+func (r *ExecutionRepository) ListByProjectID(projectID, limit, offset int) ([]*models.Execution, error) {
+	query := `
+		SELECT ` + executionColumns + `
+		FROM executions
+		WHERE project_id = ?
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	return r.query(query, projectID, limit, offset)
+}
+
+// GetByStatus 获取处于指定状态的执行历史
+func (r *ExecutionRepository) GetByStatus(statuses ...string) ([]*models.Execution, error) {
+	if len(statuses) == 0 {
+		return []*models.Execution{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	query := `
+		SELECT ` + executionColumns + `
+		FROM executions
+		WHERE status IN (` + placeholders + `)
+		ORDER BY created_at
+	`
+
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+
+	return r.query(query, args...)
+}
+
+// GetByID 根据 ID 获取执行历史
+func (r *ExecutionRepository) GetByID(id string) (*models.Execution, error) {
+	query := `
+		SELECT ` + executionColumns + `
+		FROM executions
+		WHERE id = ?
+	`
+
+	return scanExecution(r.db.QueryRow(query, id))
+}
+
+// Update 更新执行历史
+func (r *ExecutionRepository) Update(execution *models.Execution) error {
+	query := `
+		UPDATE executions
+		SET pipeline_id = ?, pipeline_revision = ?, status = ?, priority = ?, reason = ?, trigger_type = ?, trigger_info = ?, platform_data = ?, jobs = ?, start_time = ?, end_time = ?, duration = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	now := time.Now()
+	_, err := r.db.Exec(
+		query,
+		nullableInt(execution.PipelineID),
+		nullableInt(execution.PipelineRevision),
+		execution.Status,
+		nullString(execution.Priority),
+		nullString(execution.Reason),
+		execution.TriggerType,
+		execution.TriggerInfo,
+		execution.PlatformData,
+		execution.Jobs,
+		execution.StartTime,
+		execution.EndTime,
+		execution.Duration,
+		now,
+		execution.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	execution.UpdatedAt = now
+	return nil
+}
+
+// GetOptions 获取创建执行时保存的选项（JSON 格式），没有保存时返回空字符串
+func (r *ExecutionRepository) GetOptions(id string) (string, error) {
+	var options sql.NullString
+	if err := r.db.QueryRow(`SELECT options FROM executions WHERE id = ?`, id).Scan(&options); err != nil {
+		return "", err
+	}
+	return options.String, nil
+}
+
+// GetLatestAttempt 获取逻辑运行中最大的尝试序号，逻辑运行不存在时返回 0
+func (r *ExecutionRepository) GetLatestAttempt(runID string) (int, error) {
+	query := `
+		SELECT COALESCE(MAX(COALESCE(attempt, 1)), 0)
+		FROM executions
+		WHERE run_id = ? OR id = ?
+	`
+
+	var attempt int
+	err := r.db.QueryRow(query, runID, runID).Scan(&attempt)
+	return attempt, err
+}
+
+// UpdateStatus 更新执行状态
+func (r *ExecutionRepository) UpdateStatus(id string, status string, endTime time.Time, duration int) error {
+	query := `
+		UPDATE executions
+		SET status = ?, end_time = ?, duration = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.Exec(query, status, endTime, duration, time.Now(), id)
+	return err
+}
+
+// AddLog 添加执行日志
+func (r *ExecutionRepository) AddLog(log *models.ExecutionLog) error {
+	query := `
+		INSERT INTO execution_logs (id, execution_id, timestamp, level, stage, step, message, context)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if log.ID == "" {
+		log.ID = uuid.New().String()
+	}
+
+	_, err := r.db.Exec(query, log.ID, log.ExecutionID, log.Timestamp, log.Level, log.Stage, log.Step, log.Message, log.Context)
+	return err
+}
+
+// AddLogs 在同一事务中批量添加执行日志，保持传入的顺序
+func (r *ExecutionRepository) AddLogs(logs []*models.ExecutionLog) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO execution_logs (id, execution_id, timestamp, level, stage, step, message, context)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, log := range logs {
+		if log.ID == "" {
+			log.ID = uuid.New().String()
+		}
+		if _, err := stmt.Exec(log.ID, log.ExecutionID, log.Timestamp, log.Level, log.Stage, log.Step, log.Message, log.Context); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLogs 获取执行日志，按时间顺序
+func (r *ExecutionRepository) GetLogs(executionID string) ([]*models.ExecutionLog, error) {
+	query := `
+		SELECT id, execution_id, timestamp, level, stage, step, message, context
+		FROM execution_logs
+		WHERE execution_id = ?
+		ORDER BY timestamp, rowid
+	`
+
+	rows, err := r.db.Query(query, executionID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var executions []*models.Execution
+	var logs []*models.ExecutionLog
 	for rows.Next() {
-		var execution models.Execution
+		var log models.ExecutionLog
+		var stage, step, context sql.NullString
 		err := rows.Scan(
-			&execution.ID,
-			&execution.ProjectID,
-			&execution.PipelineID,
-			&execution.Status,
-			&execution.StartTime,
-			&execution.EndTime,
-			&execution.Duration,
-			&execution.Logs,
-			&execution.CreatedAt,
+			&log.ID,
+			&log.ExecutionID,
+			&log.Timestamp,
+			&log.Level,
+			&stage,
+			&step,
+			&log.Message,
+			&context,
 		)
 		if err != nil {
 			return nil, err
 		}
-		executions = append(executions, &execution)
+		log.Stage = stage.String
+		log.Step = step.String
+		log.Context = context.String
+		logs = append(logs, &log)
 	}
 
-	return executions, nil
+	return logs, rows.Err()
 }
 
-// GetByID 根据 ID 获取执行历史
-func (r *ExecutionRepository) GetByID(id int) (*models.Execution, error) {
+// AddTransition 添加执行状态变化记录
+func (r *ExecutionRepository) AddTransition(transition *models.ExecutionTransition) error {
+	query := `
+		INSERT INTO execution_transitions (execution_id, from_status, to_status, actor, reason, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, transition.ExecutionID, transition.FromStatus, transition.ToStatus, transition.Actor, transition.Reason, transition.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	transition.ID = int(id)
+	return nil
+}
+
+// GetTransitions 获取执行的状态变化记录，按发生顺序
+func (r *ExecutionRepository) GetTransitions(executionID string) ([]*models.ExecutionTransition, error) {
 	query := `
-		SELECT id, project_id, pipeline_id, status, start_time, end_time, duration, logs, created_at
+		SELECT id, execution_id, from_status, to_status, actor, reason, timestamp
+		FROM execution_transitions
+		WHERE execution_id = ?
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []*models.ExecutionTransition
+	for rows.Next() {
+		var transition models.ExecutionTransition
+		var reason sql.NullString
+		err := rows.Scan(
+			&transition.ID,
+			&transition.ExecutionID,
+			&transition.FromStatus,
+			&transition.ToStatus,
+			&transition.Actor,
+			&reason,
+			&transition.Timestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transition.Reason = reason.String
+		transitions = append(transitions, &transition)
+	}
+
+	return transitions, rows.Err()
+}
+
+// SaveStageDurations 保存执行的阶段耗时，覆盖已有记录
+func (r *ExecutionRepository) SaveStageDurations(executionID string, durations map[string]int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM execution_stages WHERE execution_id = ?`, executionID); err != nil {
+		return err
+	}
+
+	for stage, duration := range durations {
+		if _, err := tx.Exec(`INSERT INTO execution_stages (execution_id, stage, duration) VALUES (?, ?, ?)`, executionID, stage, duration); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetStageDurations 获取执行的阶段耗时
+func (r *ExecutionRepository) GetStageDurations(executionID string) (map[string]int64, error) {
+	rows, err := r.db.Query(`SELECT stage, duration FROM execution_stages WHERE execution_id = ?`, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	durations := make(map[string]int64)
+	for rows.Next() {
+		var stage string
+		var duration int64
+		if err := rows.Scan(&stage, &duration); err != nil {
+			return nil, err
+		}
+		durations[stage] = duration
+	}
+
+	return durations, rows.Err()
+}
+
+// Enqueue 将执行加入执行队列，返回的队列记录 ID 表示入队顺序
+func (r *ExecutionRepository) Enqueue(item *models.QueuedExecution) error {
+	if item.EnqueuedAt.IsZero() {
+		item.EnqueuedAt = time.Now()
+	}
+
+	result, err := r.db.Exec(`INSERT INTO execution_queue (execution_id, options, enqueued_at) VALUES (?, ?, ?)`,
+		item.ExecutionID, item.Options, item.EnqueuedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	item.ID = int(id)
+	return nil
+}
+
+// Dequeue 从执行队列中移除执行
+func (r *ExecutionRepository) Dequeue(executionID string) error {
+	_, err := r.db.Exec(`DELETE FROM execution_queue WHERE execution_id = ?`, executionID)
+	return err
+}
+
+// GetQueue 获取执行队列中的所有执行，按入队顺序
+func (r *ExecutionRepository) GetQueue() ([]*models.QueuedExecution, error) {
+	rows, err := r.db.Query(`SELECT id, execution_id, options, enqueued_at FROM execution_queue ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.QueuedExecution
+	for rows.Next() {
+		var item models.QueuedExecution
+		if err := rows.Scan(&item.ID, &item.ExecutionID, &item.Options, &item.EnqueuedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetAverageDuration 获取项目在指定平台上最近 limit 次已结束执行的平均耗时（秒），没有已结束的执行时返回 0
+func (r *ExecutionRepository) GetAverageDuration(projectID int, platform string, limit int) (float64, error) {
+	query := `
+		SELECT AVG(duration) FROM (
+			SELECT duration
+			FROM executions
+			WHERE project_id = ? AND platform = ? AND status IN ('success', 'failed', 'timed_out') AND duration IS NOT NULL
+			ORDER BY created_at DESC
+			LIMIT ?
+		)
+	`
+
+	var average sql.NullFloat64
+	if err := r.db.QueryRow(query, projectID, platform, limit).Scan(&average); err != nil {
+		return 0, err
+	}
+	return average.Float64, nil
+}
+
+// RevisionStats 管道配置某个修订的执行统计
+type RevisionStats struct {
+	Revision        int     `json:"revision"`
+	Executions      int     `json:"executions"`
+	Successes       int     `json:"successes"`
+	SuccessRate     float64 `json:"success_rate"`
+	AverageDuration float64 `json:"average_duration"` // 秒，只统计已结束的执行
+}
+
+// GetRevisionStats 按修订统计管道配置的执行次数、成功率和平均耗时，以修订号为键
+func (r *ExecutionRepository) GetRevisionStats(pipelineID int) (map[int]*RevisionStats, error) {
+	query := `
+		SELECT pipeline_revision,
+			COUNT(*),
+			SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END),
+			AVG(CASE WHEN status IN ('success', 'failed', 'cancelled', 'timed_out') THEN duration END)
 		FROM executions
-		WHERE id = ?
+		WHERE pipeline_id = ? AND pipeline_revision IS NOT NULL
+		GROUP BY pipeline_revision
 	`
 
-	row := r.db.QueryRow(query, id)
+	rows, err := r.db.Query(query, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[int]*RevisionStats)
+	for rows.Next() {
+		var item RevisionStats
+		var duration sql.NullFloat64
+		if err := rows.Scan(&item.Revision, &item.Executions, &item.Successes, &duration); err != nil {
+			return nil, err
+		}
+		if item.Executions > 0 {
+			item.SuccessRate = float64(item.Successes) / float64(item.Executions)
+		}
+		item.AverageDuration = duration.Float64
+		stats[item.Revision] = &item
+	}
+
+	return stats, rows.Err()
+}
+
+// query 执行查询并扫描执行历史列表
+func (r *ExecutionRepository) query(query string, args ...interface{}) ([]*models.Execution, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []*models.Execution
+	for rows.Next() {
+		execution, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, execution)
+	}
+
+	return executions, rows.Err()
+}
 
+// rowScanner 兼容 *sql.Row 和 *sql.Rows 的扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanExecution 扫描单条执行历史
+func scanExecution(row rowScanner) (*models.Execution, error) {
 	var execution models.Execution
+	var pipelineID, pipelineRevision sql.NullInt64
+	var priority, reason, runID, rerunOf, triggerType, triggerInfo, platformData, jobs sql.NullString
+	var attempt, duration sql.NullInt64
+	var debug sql.NullBool
 	err := row.Scan(
 		&execution.ID,
 		&execution.ProjectID,
-		&execution.PipelineID,
+		&pipelineID,
+		&pipelineRevision,
+		&execution.Platform,
 		&execution.Status,
+		&priority,
+		&reason,
+		&runID,
+		&attempt,
+		&rerunOf,
+		&debug,
+		&triggerType,
+		&triggerInfo,
+		&platformData,
+		&jobs,
 		&execution.StartTime,
 		&execution.EndTime,
-		&execution.Duration,
-		&execution.Logs,
+		&duration,
 		&execution.CreatedAt,
+		&execution.UpdatedAt,
 	)
-
 	if err != nil {
 		return nil, err
 	}
 
+	execution.PipelineID = int(pipelineID.Int64)
+	execution.PipelineRevision = int(pipelineRevision.Int64)
+	execution.Priority = priority.String
+	execution.Reason = reason.String
+	execution.RerunOf = rerunOf.String
+	execution.Debug = debug.Bool
+	execution.TriggerType = triggerType.String
+	execution.TriggerInfo = triggerInfo.String
+	execution.PlatformData = platformData.String
+	execution.Jobs = jobs.String
+	execution.Duration = int(duration.Int64)
+
+	// 新增重新运行之前创建的执行自成一个逻辑运行
+	execution.RunID = runID.String
+	if execution.RunID == "" {
+		execution.RunID = execution.ID
+	}
+	execution.Attempt = int(attempt.Int64)
+	if execution.Attempt == 0 {
+		execution.Attempt = 1
+	}
+
 	return &execution, nil
 }
 
-// UpdateStatus 更新执行状态
-func (r *ExecutionRepository) UpdateStatus(id int, status string, endTime time.Time, duration int, logs string) error {
-	query := `
-		UPDATE executions
-		SET status = ?, end_time = ?, duration = ?, logs = ?
-		WHERE id = ?
-	`
-
-	_, err := r.db.Exec(query, status, endTime, duration, logs, id)
-	return err
+// nullableInt 将 0 转换为 NULL
+func nullableInt(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
 }
@@ -42,7 +42,7 @@ func (r *MetricRepository) Create(metric *models.Metric) error {
 }
 
 // GetByExecutionID 根据执行 ID 获取指标
-func (r *MetricRepository) GetByExecutionID(executionID int) ([]*models.Metric, error) {
+func (r *MetricRepository) GetByExecutionID(executionID string) ([]*models.Metric, error) {
 	query := `
 		SELECT id, execution_id, name, value, created_at
 		FROM metrics
@@ -108,3 +108,10 @@ func (r *MetricRepository) GetByProjectID(projectID int) ([]*models.Metric, erro
 
 	return metrics, nil
 }
+
+// DeleteByExecutionID 根据执行 ID 删除指标
+func (r *MetricRepository) DeleteByExecutionID(executionID string) error {
+	query := `DELETE FROM metrics WHERE execution_id = ?`
+	_, err := r.db.Exec(query, executionID)
+	return err
+}
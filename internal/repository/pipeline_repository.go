@@ -2,11 +2,32 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"ci-cd-orchestrator/internal/models"
 )
 
+// 管道配置修订的来源
+const (
+	PipelineSourceGenerated    = "generated"    // 由模板生成
+	PipelineSourceManual       = "manual"       // 手动编辑
+	PipelineSourceOptimization = "optimization" // 应用优化建议
+	PipelineSourceRollback     = "rollback"     // 回滚到历史修订
+	PipelineSourceDisk         = "disk"         // 采用项目目录中的配置文件
+)
+
+// ErrPipelineExists 项目已有同平台的管道配置
+var ErrPipelineExists = errors.New("pipeline for this project and platform already exists")
+
+// PipelineChange 管道配置修改的来源、原因和作者，记录在新修订中
+type PipelineChange struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+	Author string `json:"author"`
+}
+
 // PipelineRepository 管道配置仓库
 type PipelineRepository struct {
 	db *sql.DB
@@ -17,15 +38,29 @@ func NewPipelineRepository(db *sql.DB) *PipelineRepository {
 	return &PipelineRepository{db: db}
 }
 
-// Create 创建管道配置
-func (r *PipelineRepository) Create(pipeline *models.Pipeline) error {
+// Create 创建管道配置，同时保存为修订 1。每个项目每个平台只有一个管道配置，已存在时返回 ErrPipelineExists
+func (r *PipelineRepository) Create(pipeline *models.Pipeline, change PipelineChange) error {
 	query := `
-		INSERT INTO pipelines (project_id, platform, config, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO pipelines (project_id, platform, config, template_id, template_version, revision, filename, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM pipelines WHERE project_id = ? AND platform = ?)", pipeline.ProjectID, pipeline.Platform).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrPipelineExists
+	}
+
 	now := time.Now()
-	result, err := r.db.Exec(query, pipeline.ProjectID, pipeline.Platform, pipeline.Config, now, now)
+	result, err := tx.Exec(query, pipeline.ProjectID, pipeline.Platform, pipeline.Config, nullInt(pipeline.TemplateID), nullInt(pipeline.TemplateVersion), 1, nullString(pipeline.Filename), now, now)
 	if err != nil {
 		return err
 	}
@@ -36,16 +71,20 @@ func (r *PipelineRepository) Create(pipeline *models.Pipeline) error {
 	}
 
 	pipeline.ID = int(id)
+	pipeline.Revision = 1
 	pipeline.CreatedAt = now
 	pipeline.UpdatedAt = now
 
-	return nil
+	if err := insertRevision(tx, pipeline, change); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetByProjectID 根据项目 ID 获取管道配置
 func (r *PipelineRepository) GetByProjectID(projectID int) ([]*models.Pipeline, error) {
 	query := `
-		SELECT id, project_id, platform, config, created_at, updated_at
+		SELECT id, project_id, platform, config, template_id, template_version, revision, filename, drift_status, drift_checked_at, created_at, updated_at
 		FROM pipelines
 		WHERE project_id = ?
 	`
@@ -58,73 +97,359 @@ func (r *PipelineRepository) GetByProjectID(projectID int) ([]*models.Pipeline,
 
 	var pipelines []*models.Pipeline
 	for rows.Next() {
-		var pipeline models.Pipeline
-		err := rows.Scan(
-			&pipeline.ID,
-			&pipeline.ProjectID,
-			&pipeline.Platform,
-			&pipeline.Config,
-			&pipeline.CreatedAt,
-			&pipeline.UpdatedAt,
-		)
+		pipeline, err := scanPipeline(rows)
 		if err != nil {
 			return nil, err
 		}
-		pipelines = append(pipelines, &pipeline)
+		pipelines = append(pipelines, pipeline)
 	}
 
 	return pipelines, nil
 }
 
+// GetByProjectAndPlatform 获取项目指定平台的管道配置，不存在时返回 sql.ErrNoRows。
+// 唯一索引创建之前遗留的重复配置中取最后创建的一个
+func (r *PipelineRepository) GetByProjectAndPlatform(projectID int, platform string) (*models.Pipeline, error) {
+	query := `
+		SELECT id, project_id, platform, config, template_id, template_version, revision, filename, drift_status, drift_checked_at, created_at, updated_at
+		FROM pipelines
+		WHERE project_id = ? AND platform = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	return scanPipeline(r.db.QueryRow(query, projectID, platform))
+}
+
 // GetByID 根据 ID 获取管道配置
 func (r *PipelineRepository) GetByID(id int) (*models.Pipeline, error) {
 	query := `
-		SELECT id, project_id, platform, config, created_at, updated_at
+		SELECT id, project_id, platform, config, template_id, template_version, revision, filename, drift_status, drift_checked_at, created_at, updated_at
 		FROM pipelines
 		WHERE id = ?
 	`
 
-	row := r.db.QueryRow(query, id)
+	return scanPipeline(r.db.QueryRow(query, id))
+}
 
-	var pipeline models.Pipeline
-	err := row.Scan(
-		&pipeline.ID,
-		&pipeline.ProjectID,
-		&pipeline.Platform,
-		&pipeline.Config,
-		&pipeline.CreatedAt,
-		&pipeline.UpdatedAt,
-	)
+// GetByTemplateID 获取由指定模板生成的管道配置
+func (r *PipelineRepository) GetByTemplateID(templateID int) ([]*models.Pipeline, error) {
+	query := `
+		SELECT id, project_id, platform, config, template_id, template_version, revision, filename, drift_status, drift_checked_at, created_at, updated_at
+		FROM pipelines
+		WHERE template_id = ?
+		ORDER BY template_version, project_id
+	`
 
+	rows, err := r.db.Query(query, templateID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &pipeline, nil
+	var pipelines []*models.Pipeline
+	for rows.Next() {
+		pipeline, err := scanPipeline(rows)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return pipelines, rows.Err()
+}
+
+// Update 更新管道配置并保存为新修订。配置和模板信息都没有变化时不产生新修订
+func (r *PipelineRepository) Update(pipeline *models.Pipeline, change PipelineChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updatePipeline(tx, pipeline, change); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Update 更新管道配置
-func (r *PipelineRepository) Update(pipeline *models.Pipeline) error {
+// updatePipeline 在事务中更新管道配置并保存新修订
+func updatePipeline(tx *sql.Tx, pipeline *models.Pipeline, change PipelineChange) error {
+	current, err := scanPipeline(tx.QueryRow(`
+		SELECT id, project_id, platform, config, template_id, template_version, revision, filename, drift_status, drift_checked_at, created_at, updated_at
+		FROM pipelines
+		WHERE id = ?
+	`, pipeline.ID))
+	if err != nil {
+		return err
+	}
+
+	if pipeline.Filename == "" {
+		pipeline.Filename = current.Filename
+	}
+
+	if current.Config == pipeline.Config && current.TemplateID == pipeline.TemplateID && current.TemplateVersion == pipeline.TemplateVersion {
+		pipeline.Revision = current.Revision
+		pipeline.UpdatedAt = current.UpdatedAt
+		if pipeline.Filename == current.Filename {
+			return nil
+		}
+		_, err := tx.Exec(`UPDATE pipelines SET filename = ? WHERE id = ?`, nullString(pipeline.Filename), pipeline.ID)
+		return err
+	}
+
 	query := `
 		UPDATE pipelines
-		SET config = ?, updated_at = ?
+		SET config = ?, template_id = ?, template_version = ?, revision = ?, filename = ?, updated_at = ?
 		WHERE id = ?
 	`
 
 	now := time.Now()
-	_, err := r.db.Exec(query, pipeline.Config, now, pipeline.ID)
+	_, err = tx.Exec(query, pipeline.Config, nullInt(pipeline.TemplateID), nullInt(pipeline.TemplateVersion), current.Revision+1, nullString(pipeline.Filename), now, pipeline.ID)
 	if err != nil {
 		return err
 	}
 
+	pipeline.Revision = current.Revision + 1
 	pipeline.UpdatedAt = now
+	return insertRevision(tx, pipeline, change)
+}
+
+// insertRevision 将管道配置的当前内容保存为修订
+func insertRevision(tx *sql.Tx, pipeline *models.Pipeline, change PipelineChange) error {
+	query := `
+		INSERT INTO pipeline_revisions (pipeline_id, revision, config, template_id, template_version, source, reason, author, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if change.Source == "" {
+		change.Source = PipelineSourceManual
+	}
 
-	return nil
+	_, err := tx.Exec(query, pipeline.ID, pipeline.Revision, pipeline.Config, nullInt(pipeline.TemplateID), nullInt(pipeline.TemplateVersion), change.Source, change.Reason, change.Author, pipeline.UpdatedAt)
+	return err
 }
 
-// Delete 删除管道配置
-func (r *PipelineRepository) Delete(id int) error {
-	query := `DELETE FROM pipelines WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+// GetAll 获取所有管道配置
+func (r *PipelineRepository) GetAll() ([]*models.Pipeline, error) {
+	query := `
+		SELECT id, project_id, platform, config, template_id, template_version, revision, filename, drift_status, drift_checked_at, created_at, updated_at
+		FROM pipelines
+		ORDER BY project_id, id
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pipelines []*models.Pipeline
+	for rows.Next() {
+		pipeline, err := scanPipeline(rows)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return pipelines, rows.Err()
+}
+
+// UpdateDriftStatus 保存管道配置与项目目录中配置文件的比较结果，不产生新修订
+func (r *PipelineRepository) UpdateDriftStatus(id int, status string, checkedAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE pipelines SET drift_status = ?, drift_checked_at = ? WHERE id = ?`, status, checkedAt, id)
 	return err
 }
+
+// GetDriftedPlatforms 获取最近一次比较结果为 statuses 之一的管道配置，返回项目 ID 到平台列表的映射
+func (r *PipelineRepository) GetDriftedPlatforms(statuses ...string) (map[int][]string, error) {
+	if len(statuses) == 0 {
+		return map[int][]string{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	query := `
+		SELECT project_id, platform
+		FROM pipelines
+		WHERE drift_status IN (` + placeholders + `)
+		ORDER BY project_id, platform
+	`
+
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	platforms := make(map[int][]string)
+	for rows.Next() {
+		var projectID int
+		var platform string
+		if err := rows.Scan(&projectID, &platform); err != nil {
+			return nil, err
+		}
+		platforms[projectID] = append(platforms[projectID], platform)
+	}
+
+	return platforms, rows.Err()
+}
+
+// Delete 删除管道配置及其修订
+func (r *PipelineRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM pipeline_revisions WHERE pipeline_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM pipelines WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListRevisions 获取管道配置的所有修订，按修订号从新到旧排序
+func (r *PipelineRepository) ListRevisions(pipelineID int) ([]*models.PipelineRevision, error) {
+	query := `
+		SELECT id, pipeline_id, revision, config, template_id, template_version, source, reason, author, created_at
+		FROM pipeline_revisions
+		WHERE pipeline_id = ?
+		ORDER BY revision DESC
+	`
+
+	rows, err := r.db.Query(query, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*models.PipelineRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision 获取管道配置的指定修订
+func (r *PipelineRepository) GetRevision(pipelineID, revision int) (*models.PipelineRevision, error) {
+	query := `
+		SELECT id, pipeline_id, revision, config, template_id, template_version, source, reason, author, created_at
+		FROM pipeline_revisions
+		WHERE pipeline_id = ? AND revision = ?
+	`
+
+	return scanRevision(r.db.QueryRow(query, pipelineID, revision))
+}
+
+// Rollback 将管道配置回滚到指定修订。历史修订不会被删除，回滚后的配置保存为新修订
+func (r *PipelineRepository) Rollback(pipelineID, revision int, change PipelineChange) (*models.Pipeline, error) {
+	target, err := r.GetRevision(pipelineID, revision)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := r.GetByID(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline.Config = target.Config
+	pipeline.TemplateID = target.TemplateID
+	pipeline.TemplateVersion = target.TemplateVersion
+
+	if change.Source == "" {
+		change.Source = PipelineSourceRollback
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := updatePipeline(tx, pipeline, change); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// scanPipeline 读取一行管道配置
+func scanPipeline(row interface{ Scan(dest ...any) error }) (*models.Pipeline, error) {
+	var pipeline models.Pipeline
+	var templateID, templateVersion sql.NullInt64
+	var filename, driftStatus sql.NullString
+	var driftCheckedAt sql.NullTime
+	err := row.Scan(
+		&pipeline.ID,
+		&pipeline.ProjectID,
+		&pipeline.Platform,
+		&pipeline.Config,
+		&templateID,
+		&templateVersion,
+		&pipeline.Revision,
+		&filename,
+		&driftStatus,
+		&driftCheckedAt,
+		&pipeline.CreatedAt,
+		&pipeline.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.TemplateID = int(templateID.Int64)
+	pipeline.TemplateVersion = int(templateVersion.Int64)
+	pipeline.Filename = filename.String
+	pipeline.DriftStatus = driftStatus.String
+	pipeline.DriftCheckedAt = driftCheckedAt.Time
+
+	return &pipeline, nil
+}
+
+// scanRevision 读取一行管道配置修订
+func scanRevision(row interface{ Scan(dest ...any) error }) (*models.PipelineRevision, error) {
+	var revision models.PipelineRevision
+	var templateID, templateVersion sql.NullInt64
+	var reason, author sql.NullString
+	err := row.Scan(
+		&revision.ID,
+		&revision.PipelineID,
+		&revision.Revision,
+		&revision.Config,
+		&templateID,
+		&templateVersion,
+		&revision.Source,
+		&reason,
+		&author,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	revision.TemplateID = int(templateID.Int64)
+	revision.TemplateVersion = int(templateVersion.Int64)
+	revision.Reason = reason.String
+	revision.Author = author.String
+
+	return &revision, nil
+}
+
+// nullInt 将 0 保存为 NULL
+func nullInt(value int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(value), Valid: value != 0}
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"testing"
 	"time"
@@ -203,7 +205,7 @@ func TestPipelineRepository(t *testing.T) {
 		Config:    `name: Test CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2\n      - run: go test ./...`,
 	}
 
-	err = repo.Create(pipeline)
+	err = repo.Create(pipeline, PipelineChange{})
 	if err != nil {
 		t.Fatalf("创建管道配置失败: %v", err)
 	}
@@ -232,9 +234,24 @@ func TestPipelineRepository(t *testing.T) {
 		t.Errorf("管道配置平台不匹配: 期望 %s, 实际 %s", pipeline.Platform, getPipeline.Platform)
 	}
 
+	// 测试根据项目和平台获取管道配置
+	platformPipeline, err := repo.GetByProjectAndPlatform(project.ID, pipeline.Platform)
+	if err != nil || platformPipeline.ID != pipeline.ID {
+		t.Fatalf("根据项目和平台获取管道配置失败: %v", err)
+	}
+	if _, err := repo.GetByProjectAndPlatform(project.ID, "GitLab CI"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("平台没有管道配置时应返回 sql.ErrNoRows: %v", err)
+	}
+
+	// 测试同一项目同一平台不能重复创建管道配置
+	duplicate := &models.Pipeline{ProjectID: project.ID, Platform: pipeline.Platform, Config: pipeline.Config}
+	if err := repo.Create(duplicate, PipelineChange{}); !errors.Is(err, ErrPipelineExists) {
+		t.Errorf("重复创建管道配置应返回 ErrPipelineExists: %v", err)
+	}
+
 	// 测试更新管道配置
 	pipeline.Config = `name: Updated Test CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2\n      - run: go test ./...\n      - run: go build ./...`
-	err = repo.Update(pipeline)
+	err = repo.Update(pipeline, PipelineChange{})
 	if err != nil {
 		t.Fatalf("更新管道配置失败: %v", err)
 	}
@@ -263,6 +280,106 @@ func TestPipelineRepository(t *testing.T) {
 	projectRepo.Delete(project.ID)
 }
 
+func TestPipelineRevisions(t *testing.T) {
+	projectRepo := NewProjectRepository(testDB)
+	project := &models.Project{Name: "修订测试项目", RepositoryURL: "https://github.com/test/revisions"}
+	if err := projectRepo.Create(project); err != nil {
+		t.Fatalf("创建项目失败: %v", err)
+	}
+	defer projectRepo.Delete(project.ID)
+
+	repo := NewPipelineRepository(testDB)
+	pipeline := &models.Pipeline{ProjectID: project.ID, Platform: "github_actions", Config: "v1", TemplateID: 1, TemplateVersion: 1}
+	if err := repo.Create(pipeline, PipelineChange{Source: PipelineSourceGenerated, Reason: "generated", Author: "system"}); err != nil {
+		t.Fatalf("创建管道配置失败: %v", err)
+	}
+	defer repo.Delete(pipeline.ID)
+	if pipeline.Revision != 1 {
+		t.Fatalf("新建的管道配置修订号为 %d，期望 1", pipeline.Revision)
+	}
+
+	// 配置没有变化时不产生新修订
+	if err := repo.Update(pipeline, PipelineChange{}); err != nil {
+		t.Fatalf("更新管道配置失败: %v", err)
+	}
+	if pipeline.Revision != 1 {
+		t.Fatalf("配置未变化时修订号变为 %d", pipeline.Revision)
+	}
+
+	pipeline.Config = "v2"
+	if err := repo.Update(pipeline, PipelineChange{Reason: "edit", Author: "alice"}); err != nil {
+		t.Fatalf("更新管道配置失败: %v", err)
+	}
+	pipeline.Config = "v3"
+	if err := repo.Update(pipeline, PipelineChange{Source: PipelineSourceOptimization, Reason: "cache deps"}); err != nil {
+		t.Fatalf("更新管道配置失败: %v", err)
+	}
+	if pipeline.Revision != 3 {
+		t.Fatalf("修订号为 %d，期望 3", pipeline.Revision)
+	}
+
+	revisions, err := repo.ListRevisions(pipeline.ID)
+	if err != nil {
+		t.Fatalf("获取修订失败: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].Revision != 3 || revisions[2].Revision != 1 {
+		t.Fatalf("修订列表不正确: %+v", revisions)
+	}
+	if revisions[1].Source != PipelineSourceManual || revisions[1].Author != "alice" || revisions[1].Reason != "edit" {
+		t.Errorf("未指定来源的修改应记为 manual: %+v", revisions[1])
+	}
+	if revisions[0].Source != PipelineSourceOptimization || revisions[2].TemplateID != 1 {
+		t.Errorf("修订的来源或模板信息不正确: %+v %+v", revisions[0], revisions[2])
+	}
+
+	// 回滚保存为新修订，历史修订保留
+	rolledBack, err := repo.Rollback(pipeline.ID, 1, PipelineChange{Author: "bob"})
+	if err != nil {
+		t.Fatalf("回滚失败: %v", err)
+	}
+	if rolledBack.Revision != 4 || rolledBack.Config != "v1" {
+		t.Fatalf("回滚后的配置不正确: %+v", rolledBack)
+	}
+	revision, err := repo.GetRevision(pipeline.ID, 4)
+	if err != nil {
+		t.Fatalf("获取修订失败: %v", err)
+	}
+	if revision.Source != PipelineSourceRollback || revision.Config != "v1" {
+		t.Errorf("回滚产生的修订不正确: %+v", revision)
+	}
+	if _, err := repo.Rollback(pipeline.ID, 99, PipelineChange{}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("回滚到不存在的修订应返回 sql.ErrNoRows，实际 %v", err)
+	}
+
+	// 按修订统计执行情况
+	executionRepo := NewExecutionRepository(testDB)
+	for _, execution := range []*models.Execution{
+		{Status: "success", PipelineRevision: 2, Duration: 10},
+		{Status: "failed", PipelineRevision: 2, Duration: 30},
+		{Status: "running", PipelineRevision: 3},
+	} {
+		execution.ProjectID = project.ID
+		execution.PipelineID = pipeline.ID
+		execution.Platform = "mock"
+		if err := executionRepo.Create(execution); err != nil {
+			t.Fatalf("创建执行历史失败: %v", err)
+		}
+	}
+	stats, err := executionRepo.GetRevisionStats(pipeline.ID)
+	if err != nil {
+		t.Fatalf("统计修订执行情况失败: %v", err)
+	}
+	if stats[2] == nil || stats[2].Executions != 2 || stats[2].SuccessRate != 0.5 || stats[2].AverageDuration != 20 {
+		t.Errorf("修订 2 的统计不正确: %+v", stats[2])
+	}
+	if stats[3] == nil || stats[3].Executions != 1 || stats[3].AverageDuration != 0 {
+		t.Errorf("修订 3 的统计不正确: %+v", stats[3])
+	}
+	if _, ok := stats[1]; ok {
+		t.Error("没有执行的修订不应出现在统计中")
+	}
+}
+
 func TestExecutionRepository(t *testing.T) {
 	// 先创建一个项目和管道配置
 	projectRepo := NewProjectRepository(testDB)
@@ -284,7 +401,7 @@ func TestExecutionRepository(t *testing.T) {
 		Config:    `name: Test CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2\n      - run: go test ./...`,
 	}
 
-	err = pipelineRepo.Create(pipeline)
+	err = pipelineRepo.Create(pipeline, PipelineChange{})
 	if err != nil {
 		t.Fatalf("创建管道配置失败: %v", err)
 	}
@@ -295,13 +412,14 @@ func TestExecutionRepository(t *testing.T) {
 	// 测试创建执行历史
 	now := time.Now()
 	execution := &models.Execution{
-		ProjectID:  project.ID,
-		PipelineID: pipeline.ID,
-		Status:     "success",
-		StartTime:  now.Add(-time.Minute),
-		EndTime:    now,
-		Duration:   60,
-		Logs:       "Test passed",
+		ProjectID:    project.ID,
+		PipelineID:   pipeline.ID,
+		Platform:     "mock",
+		Status:       "running",
+		TriggerType:  "manual",
+		TriggerInfo:  `{"ref":"refs/heads/main"}`,
+		PlatformData: `{}`,
+		StartTime:    now.Add(-time.Minute),
 	}
 
 	err = repo.Create(execution)
@@ -309,7 +427,7 @@ func TestExecutionRepository(t *testing.T) {
 		t.Fatalf("创建执行历史失败: %v", err)
 	}
 
-	if execution.ID == 0 {
+	if execution.ID == "" {
 		t.Fatal("执行历史 ID 未设置")
 	}
 
@@ -333,13 +451,135 @@ func TestExecutionRepository(t *testing.T) {
 		t.Errorf("执行历史状态不匹配: 期望 %s, 实际 %s", execution.Status, getExecution.Status)
 	}
 
+	if getExecution.TriggerInfo != execution.TriggerInfo {
+		t.Errorf("执行触发信息不匹配: 期望 %s, 实际 %s", execution.TriggerInfo, getExecution.TriggerInfo)
+	}
+
+	// 测试按状态获取执行历史
+	running, err := repo.GetByStatus("running")
+	if err != nil {
+		t.Fatalf("按状态获取执行历史失败: %v", err)
+	}
+
+	if len(running) == 0 {
+		t.Fatal("运行中的执行历史列表为空")
+	}
+
+	// 测试执行日志
+	err = repo.AddLog(&models.ExecutionLog{
+		ExecutionID: execution.ID,
+		Timestamp:   now,
+		Level:       "info",
+		Stage:       "build",
+		Message:     "Test passed",
+	})
+	if err != nil {
+		t.Fatalf("添加执行日志失败: %v", err)
+	}
+
+	logs, err := repo.GetLogs(execution.ID)
+	if err != nil {
+		t.Fatalf("获取执行日志失败: %v", err)
+	}
+
+	if len(logs) != 1 || logs[0].Message != "Test passed" {
+		t.Errorf("执行日志不匹配: %v", logs)
+	}
+
+	// 测试批量添加执行日志，时间相同的日志保持添加顺序
+	err = repo.AddLogs([]*models.ExecutionLog{
+		{ExecutionID: execution.ID, Timestamp: now, Level: "info", Stage: "deploy", Message: "first"},
+		{ExecutionID: execution.ID, Timestamp: now, Level: "info", Stage: "deploy", Message: "second"},
+	})
+	if err != nil {
+		t.Fatalf("批量添加执行日志失败: %v", err)
+	}
+
+	logs, err = repo.GetLogs(execution.ID)
+	if err != nil {
+		t.Fatalf("获取执行日志失败: %v", err)
+	}
+
+	if len(logs) != 3 || logs[1].Message != "first" || logs[2].Message != "second" {
+		t.Errorf("批量添加的执行日志不匹配: %v", logs)
+	}
+
+	// 测试状态变化记录
+	for _, transition := range []*models.ExecutionTransition{
+		{ExecutionID: execution.ID, FromStatus: "pending", ToStatus: "queued", Actor: "queue", Timestamp: now},
+		{ExecutionID: execution.ID, FromStatus: "queued", ToStatus: "running", Actor: "queue", Timestamp: now},
+		{ExecutionID: execution.ID, FromStatus: "running", ToStatus: "cancelled", Actor: "user", Reason: "stopped", Timestamp: now},
+	} {
+		if err := repo.AddTransition(transition); err != nil || transition.ID == 0 {
+			t.Fatalf("添加状态变化记录失败: %v", err)
+		}
+	}
+
+	transitions, err := repo.GetTransitions(execution.ID)
+	if err != nil {
+		t.Fatalf("获取状态变化记录失败: %v", err)
+	}
+
+	if len(transitions) != 3 || transitions[0].ToStatus != "queued" || transitions[2].Actor != "user" || transitions[2].Reason != "stopped" {
+		t.Errorf("状态变化记录不匹配: %+v", transitions)
+	}
+
+	// 测试重新运行的执行属于原执行的逻辑运行
+	if getExecution.RunID != execution.ID || getExecution.Attempt != 1 {
+		t.Errorf("执行应自成一个逻辑运行: %s %d", getExecution.RunID, getExecution.Attempt)
+	}
+	rerun := &models.Execution{
+		ProjectID:    project.ID,
+		Platform:     "mock",
+		Status:       "pending",
+		RunID:        execution.ID,
+		Attempt:      2,
+		RerunOf:      execution.ID,
+		Debug:        true,
+		Options:      `{"result":"success"}`,
+		TriggerType:  "manual",
+		TriggerInfo:  `{}`,
+		PlatformData: `{}`,
+	}
+	if err := repo.Create(rerun); err != nil {
+		t.Fatalf("创建重新运行的执行失败: %v", err)
+	}
+
+	getRerun, err := repo.GetByID(rerun.ID)
+	if err != nil || getRerun.RunID != execution.ID || getRerun.Attempt != 2 || getRerun.RerunOf != execution.ID || !getRerun.Debug {
+		t.Errorf("重新运行的执行不匹配: %+v, %v", getRerun, err)
+	}
+	if options, err := repo.GetOptions(rerun.ID); err != nil || options != rerun.Options {
+		t.Errorf("执行选项不匹配: %q, %v", options, err)
+	}
+	if attempt, err := repo.GetLatestAttempt(execution.ID); err != nil || attempt != 2 {
+		t.Errorf("逻辑运行的最新尝试序号不匹配: %d, %v", attempt, err)
+	}
+	if attempt, err := repo.GetLatestAttempt("missing"); err != nil || attempt != 0 {
+		t.Errorf("不存在的逻辑运行尝试序号应为 0: %d, %v", attempt, err)
+	}
+
+	// 测试阶段耗时
+	err = repo.SaveStageDurations(execution.ID, map[string]int64{"build": 3, "test": 5})
+	if err != nil {
+		t.Fatalf("保存阶段耗时失败: %v", err)
+	}
+
+	stages, err := repo.GetStageDurations(execution.ID)
+	if err != nil {
+		t.Fatalf("获取阶段耗时失败: %v", err)
+	}
+
+	if stages["test"] != 5 {
+		t.Errorf("阶段耗时不匹配: 期望 5, 实际 %d", stages["test"])
+	}
+
 	// 测试更新执行状态
 	newStatus := "failed"
 	newEndTime := time.Now()
 	newDuration := 120
-	newLogs := "Test failed"
 
-	err = repo.UpdateStatus(execution.ID, newStatus, newEndTime, newDuration, newLogs)
+	err = repo.UpdateStatus(execution.ID, newStatus, newEndTime, newDuration)
 	if err != nil {
 		t.Fatalf("更新执行状态失败: %v", err)
 	}
@@ -378,7 +618,7 @@ func TestMetricRepository(t *testing.T) {
 		Config:    `name: Test CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2\n      - run: go test ./...`,
 	}
 
-	err = pipelineRepo.Create(pipeline)
+	err = pipelineRepo.Create(pipeline, PipelineChange{})
 	if err != nil {
 		t.Fatalf("创建管道配置失败: %v", err)
 	}
@@ -388,11 +628,11 @@ func TestMetricRepository(t *testing.T) {
 	execution := &models.Execution{
 		ProjectID:  project.ID,
 		PipelineID: pipeline.ID,
+		Platform:   "mock",
 		Status:     "success",
 		StartTime:  now.Add(-time.Minute),
 		EndTime:    now,
 		Duration:   60,
-		Logs:       "Test passed",
 	}
 
 	err = executionRepo.Create(execution)
@@ -516,3 +756,520 @@ func TestOptimizationRepository(t *testing.T) {
 	// 清理项目
 	projectRepo.Delete(project.ID)
 }
+
+func TestSecurityRepositories(t *testing.T) {
+	// 先创建一个项目和管道配置
+	projectRepo := NewProjectRepository(testDB)
+	project := &models.Project{
+		Name:          "测试项目",
+		Description:   "这是一个测试项目",
+		RepositoryURL: "https://github.com/test/test",
+	}
+
+	err := projectRepo.Create(project)
+	if err != nil {
+		t.Fatalf("创建项目失败: %v", err)
+	}
+
+	pipelineRepo := NewPipelineRepository(testDB)
+	pipeline := &models.Pipeline{
+		ProjectID: project.ID,
+		Platform:  "github_actions",
+		Config:    `name: Test CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2`,
+	}
+	if err := pipelineRepo.Create(pipeline, PipelineChange{}); err != nil {
+		t.Fatalf("创建管道配置失败: %v", err)
+	}
+
+	findingRepo := NewSecurityFindingRepository(testDB)
+
+	// 测试保存检查结果
+	findings := []*models.SecurityFinding{
+		{ProjectID: project.ID, Rule: "unpinned-action", Severity: "warning", Job: "test", Line: 7, Column: 15, Message: "unpinned"},
+		{ProjectID: project.ID, Rule: "missing-permissions", Severity: "warning", Job: "test", Line: 4, Column: 3, Message: "missing"},
+	}
+	if err := findingRepo.ReplaceForPipeline(pipeline.ID, findings); err != nil {
+		t.Fatalf("保存安全检查结果失败: %v", err)
+	}
+	if findings[0].ID == 0 || findings[0].PipelineID != pipeline.ID {
+		t.Fatal("安全检查结果 ID 未设置")
+	}
+
+	// 测试按条件查询，结果按位置排序
+	listed, err := findingRepo.List(SecurityFindingFilter{ProjectID: project.ID})
+	if err != nil {
+		t.Fatalf("查询安全检查结果失败: %v", err)
+	}
+	if len(listed) != 2 || listed[0].Rule != "missing-permissions" {
+		t.Fatalf("安全检查结果不匹配: %+v", listed)
+	}
+
+	listed, err = findingRepo.List(SecurityFindingFilter{PipelineID: pipeline.ID, Rule: "unpinned-action"})
+	if err != nil {
+		t.Fatalf("查询安全检查结果失败: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Job != "test" {
+		t.Fatalf("按规则查询安全检查结果不匹配: %+v", listed)
+	}
+
+	// 测试替换检查结果
+	if err := findingRepo.ReplaceForPipeline(pipeline.ID, nil); err != nil {
+		t.Fatalf("替换安全检查结果失败: %v", err)
+	}
+	listed, err = findingRepo.List(SecurityFindingFilter{PipelineID: pipeline.ID})
+	if err != nil {
+		t.Fatalf("查询安全检查结果失败: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Fatalf("替换后仍有旧的安全检查结果: %+v", listed)
+	}
+
+	// 测试规则开关
+	ruleRepo := NewSecurityRuleRepository(testDB)
+	if err := ruleRepo.Set(&models.SecurityRuleSetting{ProjectID: project.ID, Rule: "unpinned-action", Enabled: false}); err != nil {
+		t.Fatalf("设置规则开关失败: %v", err)
+	}
+	if err := ruleRepo.Set(&models.SecurityRuleSetting{ProjectID: project.ID, Rule: "hardcoded-secret", Enabled: false}); err != nil {
+		t.Fatalf("设置规则开关失败: %v", err)
+	}
+	if err := ruleRepo.Set(&models.SecurityRuleSetting{ProjectID: project.ID, Rule: "hardcoded-secret", Enabled: true}); err != nil {
+		t.Fatalf("更新规则开关失败: %v", err)
+	}
+
+	disabled, err := ruleRepo.DisabledRules(project.ID)
+	if err != nil {
+		t.Fatalf("获取关闭的规则失败: %v", err)
+	}
+	if len(disabled) != 1 || !disabled["unpinned-action"] {
+		t.Fatalf("关闭的规则不匹配: %v", disabled)
+	}
+
+	// 清理项目
+	pipelineRepo.Delete(pipeline.ID)
+	projectRepo.Delete(project.ID)
+}
+
+func TestTemplateRepository(t *testing.T) {
+	repo := NewTemplateRepository(testDB)
+
+	// 测试创建带参数的模板
+	template := &Template{
+		Platform:   "github_actions",
+		Language:   "Go",
+		Framework:  "test-framework",
+		Content:    "go-version: '[[ .go_version ]]'",
+		Filename:   ".github/workflows/ci.yml",
+		ConfigType: "yaml",
+		Parameters: []TemplateParameter{
+			{Name: "go_version", Type: "string", Default: "1.22", Source: "language_version"},
+			{Name: "os", Type: "string", Default: "ubuntu-latest", Enum: []interface{}{"ubuntu-latest", "macos-latest"}},
+		},
+	}
+	if err := repo.Create(template, TemplateChange{Author: "alice", Note: "initial"}); err != nil {
+		t.Fatalf("创建模板失败: %v", err)
+	}
+	if template.ID == 0 || template.Version != 1 {
+		t.Fatalf("模板 ID 或版本未设置: %+v", template)
+	}
+
+	// 测试获取模板，参数定义完整保存
+	getTemplate, err := repo.GetByID(template.ID)
+	if err != nil {
+		t.Fatalf("获取模板失败: %v", err)
+	}
+	if len(getTemplate.Parameters) != 2 || getTemplate.Parameters[0].Default != "1.22" || len(getTemplate.Parameters[1].Enum) != 2 {
+		t.Fatalf("模板参数不匹配: %+v", getTemplate.Parameters)
+	}
+
+	// 测试更新模板，清空参数
+	template.Parameters = nil
+	template.Content = "go-version: '1.22'"
+	if err := repo.Update(template, TemplateChange{Author: "bob", Note: "pin go version"}); err != nil {
+		t.Fatalf("更新模板失败: %v", err)
+	}
+	getTemplate, err = repo.GetByPlatformAndLanguage("github_actions", "Go", "test-framework")
+	if err != nil {
+		t.Fatalf("获取模板失败: %v", err)
+	}
+	if getTemplate.ID != template.ID || getTemplate.Parameters != nil || getTemplate.Version != 2 {
+		t.Fatalf("更新后的模板不匹配: %+v", getTemplate)
+	}
+
+	// 测试版本历史，每次修改保存为新版本
+	versions, err := repo.ListVersions(template.ID)
+	if err != nil {
+		t.Fatalf("获取模板版本失败: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[0].Author != "bob" || versions[1].Note != "initial" {
+		t.Fatalf("模板版本不匹配: %+v", versions)
+	}
+	version, err := repo.GetVersion(template.ID, 1)
+	if err != nil {
+		t.Fatalf("获取模板版本失败: %v", err)
+	}
+	if version.Content != "go-version: '[[ .go_version ]]'" || len(version.Parameters) != 2 {
+		t.Fatalf("模板版本内容不匹配: %+v", version)
+	}
+	if _, err := repo.GetVersion(template.ID, 3); err == nil {
+		t.Fatal("获取不存在的版本应返回错误")
+	}
+
+	// 测试回滚，回滚结果保存为新版本
+	rolledBack, err := repo.Rollback(template.ID, 1, TemplateChange{Author: "alice", Note: "rollback"})
+	if err != nil {
+		t.Fatalf("回滚模板失败: %v", err)
+	}
+	if rolledBack.Version != 3 || rolledBack.Content != version.Content || len(rolledBack.Parameters) != 2 {
+		t.Fatalf("回滚后的模板不匹配: %+v", rolledBack)
+	}
+	if versions, _ := repo.ListVersions(template.ID); len(versions) != 3 {
+		t.Fatalf("回滚后应有 3 个版本，实际为 %d", len(versions))
+	}
+
+	// 测试按模板查找管道配置
+	projectRepo := NewProjectRepository(testDB)
+	project := &models.Project{Name: "template-project", Path: "/tmp/template-project"}
+	if err := projectRepo.Create(project); err != nil {
+		t.Fatalf("创建项目失败: %v", err)
+	}
+	pipelineRepo := NewPipelineRepository(testDB)
+	pipeline := &models.Pipeline{ProjectID: project.ID, Platform: "github_actions", Config: "name: CI", TemplateID: template.ID, TemplateVersion: 2}
+	if err := pipelineRepo.Create(pipeline, PipelineChange{}); err != nil {
+		t.Fatalf("创建管道失败: %v", err)
+	}
+	pipelines, err := pipelineRepo.GetByTemplateID(template.ID)
+	if err != nil {
+		t.Fatalf("获取管道失败: %v", err)
+	}
+	if len(pipelines) != 1 || pipelines[0].ID != pipeline.ID || pipelines[0].TemplateVersion != 2 {
+		t.Fatalf("管道的模板信息不匹配: %+v", pipelines)
+	}
+	pipelineRepo.Delete(pipeline.ID)
+	projectRepo.Delete(project.ID)
+
+	// 测试片段：按名称获取，不参与按平台和语言的模板选择
+	fragment := &Template{
+		Name:       "test-base",
+		Kind:       TemplateKindFragment,
+		Platform:   "github_actions",
+		Language:   "Go",
+		Framework:  "test-fragment",
+		Content:    "[[ block \"steps\" . ]][[ end ]]",
+		ConfigType: "yaml",
+	}
+	if err := repo.Create(fragment, TemplateChange{Author: "alice"}); err != nil {
+		t.Fatalf("创建片段失败: %v", err)
+	}
+	getTemplate, err = repo.GetByName("test-base")
+	if err != nil {
+		t.Fatalf("按名称获取模板失败: %v", err)
+	}
+	if getTemplate.ID != fragment.ID || getTemplate.Kind != TemplateKindFragment {
+		t.Fatalf("片段不匹配: %+v", getTemplate)
+	}
+	if _, err := repo.GetByPlatformAndLanguage("github_actions", "Go", "test-fragment"); err == nil {
+		t.Fatal("片段不应参与模板选择")
+	}
+	if err := repo.Create(&Template{Name: "test-base", Kind: TemplateKindFragment, Content: "x"}, TemplateChange{}); err == nil {
+		t.Fatal("模板名称应唯一")
+	}
+
+	// 测试继承关系随版本保存，未命名的模板类型默认为 pipeline
+	template.Extends = "test-base"
+	template.Content = "[[ define \"steps\" ]]test[[ end ]]"
+	if err := repo.Update(template, TemplateChange{Author: "bob"}); err != nil {
+		t.Fatalf("更新模板失败: %v", err)
+	}
+	getTemplate, err = repo.GetByID(template.ID)
+	if err != nil {
+		t.Fatalf("获取模板失败: %v", err)
+	}
+	if getTemplate.Extends != "test-base" || getTemplate.Kind != TemplateKindPipeline || getTemplate.Name != "" {
+		t.Fatalf("模板继承关系不匹配: %+v", getTemplate)
+	}
+	if version, err := repo.GetVersion(template.ID, getTemplate.Version); err != nil || version.Extends != "test-base" {
+		t.Fatalf("版本的继承关系不匹配: %+v, %v", version, err)
+	}
+	rolledBack, err = repo.Rollback(template.ID, 1, TemplateChange{})
+	if err != nil || rolledBack.Extends != "" {
+		t.Fatalf("回滚后的继承关系不匹配: %+v, %v", rolledBack, err)
+	}
+
+	// 测试导入：新建的模板保存历史版本，覆盖的模板保存为新版本
+	created := &Template{Name: "test-imported", Kind: TemplateKindFragment, Content: "v2", Version: 2}
+	history := []*TemplateVersion{
+		{Version: 1, Content: "v1", Author: "alice", Note: "first", CreatedAt: time.Now().Add(-time.Hour)},
+		{Version: 2, Content: "v2", Author: "bob", Note: "second", CreatedAt: time.Now()},
+	}
+	fragment.Content = "overwritten"
+	imports := []*TemplateImport{{Template: created, Versions: history}, {Template: fragment}}
+	if err := repo.Import(imports, TemplateChange{Author: "ops", Note: "imported"}); err != nil {
+		t.Fatalf("导入模板失败: %v", err)
+	}
+	versions, err = repo.ListVersions(created.ID)
+	if err != nil || len(versions) != 2 || versions[0].Note != "second" || versions[1].Author != "alice" {
+		t.Fatalf("导入的历史版本不匹配: %+v, %v", versions, err)
+	}
+	getTemplate, err = repo.GetByID(fragment.ID)
+	if err != nil || getTemplate.Content != "overwritten" || getTemplate.Version != fragment.Version {
+		t.Fatalf("覆盖的模板不匹配: %+v, %v", getTemplate, err)
+	}
+	if version, err := repo.GetVersion(fragment.ID, fragment.Version); err != nil || version.Author != "ops" {
+		t.Fatalf("覆盖的版本不匹配: %+v, %v", version, err)
+	}
+
+	// 任何一个模板写入失败时全部回滚
+	failed := []*TemplateImport{
+		{Template: &Template{Name: "test-rolled-back", Kind: TemplateKindFragment, Content: "x"}},
+		{Template: &Template{ID: 999999, Kind: TemplateKindFragment, Content: "x"}},
+	}
+	if err := repo.Import(failed, TemplateChange{}); err == nil {
+		t.Fatal("覆盖不存在的模板应失败")
+	}
+	if _, err := repo.GetByName("test-rolled-back"); err != sql.ErrNoRows {
+		t.Fatalf("导入失败后模板应回滚: %v", err)
+	}
+
+	// 清理模板
+	if err := repo.Delete(template.ID); err != nil {
+		t.Fatalf("删除模板失败: %v", err)
+	}
+	if err := repo.Delete(fragment.ID); err != nil {
+		t.Fatalf("删除片段失败: %v", err)
+	}
+	if err := repo.Delete(created.ID); err != nil {
+		t.Fatalf("删除导入的模板失败: %v", err)
+	}
+}
+
+func TestWebhookDeliveryRepository(t *testing.T) {
+	repo := NewWebhookDeliveryRepository(testDB)
+
+	// 测试数据库在多次运行之间保留，投递 ID 需要唯一
+	deliveryID := fmt.Sprintf("delivery-%d", time.Now().UnixNano())
+	delivery := &models.WebhookDelivery{
+		Provider:   "github",
+		DeliveryID: deliveryID,
+		Event:      "push",
+		Platform:   "mock",
+		Payload:    `{"ref":"refs/heads/main"}`,
+	}
+
+	created, err := repo.Create(delivery)
+	if err != nil || !created {
+		t.Fatalf("保存投递失败: %v", err)
+	}
+	if delivery.ID == 0 || delivery.Status != DeliveryStatusReceived {
+		t.Fatalf("投递 ID 或状态未设置: %+v", delivery)
+	}
+
+	// 相同平台的相同投递 ID 只保存一次
+	created, err = repo.Create(&models.WebhookDelivery{Provider: "github", DeliveryID: deliveryID, Event: "push", Platform: "mock", Payload: "{}"})
+	if err != nil || created {
+		t.Fatalf("重复的投递不应保存: %v", err)
+	}
+	other := &models.WebhookDelivery{Provider: "gitea", DeliveryID: deliveryID, Event: "push", Platform: "mock", Payload: "{}"}
+	if created, err := repo.Create(other); err != nil || !created {
+		t.Fatalf("不同平台的投递应保存: %v", err)
+	}
+
+	delivery.Status = DeliveryStatusProcessed
+	delivery.ExecutionIDs = []string{"e1", "e2"}
+	delivery.Redeliveries = 1
+	if err := repo.UpdateResult(delivery); err != nil {
+		t.Fatalf("保存处理结果失败: %v", err)
+	}
+
+	got, err := repo.GetByID(delivery.ID)
+	if err != nil {
+		t.Fatalf("获取投递失败: %v", err)
+	}
+	if got.Status != DeliveryStatusProcessed || len(got.ExecutionIDs) != 2 || got.Duplicates != 1 || got.Redeliveries != 1 || got.Payload != delivery.Payload {
+		t.Errorf("投递不匹配: %+v", got)
+	}
+
+	listed, err := repo.List("gitea", 10, 0)
+	if err != nil {
+		t.Fatalf("获取投递列表失败: %v", err)
+	}
+	if len(listed) == 0 || listed[0].ID != other.ID {
+		t.Errorf("按平台过滤的投递列表不匹配: %+v", listed)
+	}
+}
+
+func TestScheduleRepository(t *testing.T) {
+	projectRepo := NewProjectRepository(testDB)
+	project := &models.Project{Name: "定时任务项目", RepositoryURL: "https://github.com/test/schedule"}
+	if err := projectRepo.Create(project); err != nil {
+		t.Fatalf("创建项目失败: %v", err)
+	}
+	pipelineRepo := NewPipelineRepository(testDB)
+	pipeline := &models.Pipeline{ProjectID: project.ID, Platform: "github_actions", Config: "on: push"}
+	if err := pipelineRepo.Create(pipeline, PipelineChange{}); err != nil {
+		t.Fatalf("创建管道配置失败: %v", err)
+	}
+
+	repo := NewScheduleRepository(testDB)
+	next := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)
+	schedule := &models.Schedule{
+		ProjectID:  project.ID,
+		PipelineID: pipeline.ID,
+		Source:     ScheduleSourcePipeline,
+		Name:       "nightly",
+		Cron:       "0 2 * * *",
+		Timezone:   "UTC",
+		Platform:   "mock",
+		CatchUp:    "latest",
+		Enabled:    true,
+		NextRunAt:  next,
+	}
+	created, err := repo.Create(schedule)
+	if err != nil || !created || schedule.ID == 0 {
+		t.Fatalf("创建定时任务失败: %v", err)
+	}
+
+	// 同一个管道配置的相同 cron 表达式只创建一次
+	duplicate := *schedule
+	if created, err := repo.Create(&duplicate); err != nil || created {
+		t.Fatalf("重复的定时任务不应创建: %v", err)
+	}
+
+	// 只有下次触发时间仍为预期值时才能推进，模拟两个实例同时触发
+	later := next.Add(24 * time.Hour)
+	if claimed, err := repo.Claim(schedule.ID, next, later); err != nil || !claimed {
+		t.Fatalf("推进下次触发时间失败: %v", err)
+	}
+	if claimed, err := repo.Claim(schedule.ID, next, later); err != nil || claimed {
+		t.Fatalf("已推进的触发时间不应再次推进: %v", err)
+	}
+
+	if err := repo.RecordRun(schedule.ID, next, "exec-1", ""); err != nil {
+		t.Fatalf("保存触发结果失败: %v", err)
+	}
+	got, err := repo.GetByID(schedule.ID)
+	if err != nil {
+		t.Fatalf("获取定时任务失败: %v", err)
+	}
+	if !got.NextRunAt.Equal(later) || !got.LastRunAt.Equal(next) || got.LastExecutionID != "exec-1" || got.PipelineID != pipeline.ID {
+		t.Errorf("定时任务不匹配: %+v", got)
+	}
+
+	// 读取的下次触发时间可以继续用于推进
+	if claimed, err := repo.Claim(schedule.ID, got.NextRunAt, later.Add(24*time.Hour)); err != nil || !claimed {
+		t.Fatalf("用读取的触发时间推进失败: %v", err)
+	}
+
+	schedules, err := repo.GetByProjectID(project.ID)
+	if err != nil || len(schedules) != 1 {
+		t.Fatalf("获取项目的定时任务失败: %v, %d", err, len(schedules))
+	}
+
+	// 管道配置中仍存在的 cron 表达式对应的定时任务被保留
+	existing, err := repo.DeletePipelineSchedulesExcept(pipeline.ID, []string{"0 2 * * *"})
+	if err != nil || !existing["0 2 * * *"] {
+		t.Fatalf("定时任务应被保留: %v, %v", err, existing)
+	}
+
+	// 管道配置中移除的 cron 表达式对应的定时任务被删除
+	existing, err = repo.DeletePipelineSchedulesExcept(pipeline.ID, []string{"0 3 * * *"})
+	if err != nil {
+		t.Fatalf("删除定时任务失败: %v", err)
+	}
+	if len(existing) != 0 {
+		t.Errorf("不应保留定时任务: %v", existing)
+	}
+	if _, err := repo.GetByID(schedule.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("定时任务应已删除: %v", err)
+	}
+}
+
+func TestExecutionQueue(t *testing.T) {
+	projectRepo := NewProjectRepository(testDB)
+	project := &models.Project{Name: "执行队列项目", RepositoryURL: "https://github.com/test/queue"}
+	if err := projectRepo.Create(project); err != nil {
+		t.Fatalf("创建项目失败: %v", err)
+	}
+
+	repo := NewExecutionRepository(testDB)
+	now := time.Now()
+	var ids []string
+	for i, duration := range []int{10, 20, 600} {
+		execution := &models.Execution{
+			ProjectID:   project.ID,
+			Platform:    "mock",
+			Status:      "success",
+			Priority:    "release",
+			TriggerType: "tag",
+			StartTime:   now.Add(time.Duration(i) * time.Minute),
+			Duration:    duration,
+		}
+		if i == 2 {
+			// 排队中的执行不参与耗时估计
+			execution.Status = "queued"
+		}
+		if err := repo.Create(execution); err != nil {
+			t.Fatalf("创建执行历史失败: %v", err)
+		}
+		ids = append(ids, execution.ID)
+	}
+
+	got, err := repo.GetByID(ids[0])
+	if err != nil || got.Priority != "release" {
+		t.Fatalf("执行的优先级不匹配: %v, %+v", err, got)
+	}
+
+	// 取消原因随执行状态一起保存
+	got.Status = "cancelled"
+	got.Reason = "superseded by a newer execution"
+	if err := repo.Update(got); err != nil {
+		t.Fatalf("更新执行历史失败: %v", err)
+	}
+	if got, err := repo.GetByID(ids[0]); err != nil || got.Reason != "superseded by a newer execution" {
+		t.Fatalf("执行的取消原因不匹配: %v, %+v", err, got)
+	}
+	got.Status = "success"
+	got.Reason = ""
+	if err := repo.Update(got); err != nil {
+		t.Fatalf("更新执行历史失败: %v", err)
+	}
+
+	average, err := repo.GetAverageDuration(project.ID, "mock", 20)
+	if err != nil || average != 15 {
+		t.Errorf("平均耗时不匹配: %v, %v", average, err)
+	}
+
+	// 按入队顺序返回
+	for _, id := range []string{ids[2], ids[1]} {
+		if err := repo.Enqueue(&models.QueuedExecution{ExecutionID: id, Options: `{"result":"success"}`}); err != nil {
+			t.Fatalf("加入执行队列失败: %v", err)
+		}
+	}
+	if err := repo.Enqueue(&models.QueuedExecution{ExecutionID: ids[2], Options: "{}"}); err == nil {
+		t.Error("同一个执行不应重复入队")
+	}
+
+	queue, err := repo.GetQueue()
+	if err != nil {
+		t.Fatalf("获取执行队列失败: %v", err)
+	}
+	var order []string
+	for _, item := range queue {
+		if item.ExecutionID == ids[1] || item.ExecutionID == ids[2] {
+			order = append(order, item.ExecutionID)
+		}
+	}
+	if len(order) != 2 || order[0] != ids[2] || order[1] != ids[1] {
+		t.Errorf("执行队列顺序不匹配: %v", order)
+	}
+
+	if err := repo.Dequeue(ids[2]); err != nil {
+		t.Fatalf("移除队列记录失败: %v", err)
+	}
+	queue, _ = repo.GetQueue()
+	for _, item := range queue {
+		if item.ExecutionID == ids[2] {
+			t.Error("移除的执行仍在队列中")
+		}
+	}
+	repo.Dequeue(ids[1])
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"ci-cd-orchestrator/internal/models"
+)
+
+// 定时任务的来源
+const (
+	ScheduleSourcePipeline = "pipeline" // 管道配置的 on.schedule，随管道配置同步
+	ScheduleSourceAPI      = "api"      // 通过 API 创建
+)
+
+// ScheduleRepository 定时任务仓库
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+// NewScheduleRepository 创建定时任务仓库实例
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+// scheduleColumns 查询定时任务的列，与 scanSchedule 的顺序一致
+const scheduleColumns = `id, project_id, pipeline_id, source, name, cron, timezone, platform, ref, catch_up, enabled,
+	next_run_at, last_run_at, last_execution_id, last_error, created_at, updated_at`
+
+// Create 创建定时任务。来自管道配置的定时任务已存在相同的 cron 表达式时不创建并返回 false
+func (r *ScheduleRepository) Create(schedule *models.Schedule) (bool, error) {
+	query := `
+		INSERT OR IGNORE INTO schedules (project_id, pipeline_id, source, name, cron, timezone, platform, ref, catch_up, enabled, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, schedule.ProjectID, nullInt(schedule.PipelineID), schedule.Source, schedule.Name, schedule.Cron, schedule.Timezone,
+		schedule.Platform, nullString(schedule.Ref), schedule.CatchUp, schedule.Enabled, nullTime(schedule.NextRunAt), now, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	schedule.ID = int(id)
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	return true, nil
+}
+
+// Update 保存定时任务的设置和下次触发时间
+func (r *ScheduleRepository) Update(schedule *models.Schedule) error {
+	query := `
+		UPDATE schedules
+		SET name = ?, cron = ?, timezone = ?, platform = ?, ref = ?, catch_up = ?, enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, schedule.Name, schedule.Cron, schedule.Timezone, schedule.Platform, nullString(schedule.Ref),
+		schedule.CatchUp, schedule.Enabled, nullTime(schedule.NextRunAt), now, schedule.ID); err != nil {
+		return err
+	}
+	schedule.UpdatedAt = now
+	return nil
+}
+
+// Delete 删除定时任务
+func (r *ScheduleRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM schedules WHERE id = ?`, id)
+	return err
+}
+
+// GetByID 根据 ID 获取定时任务
+func (r *ScheduleRepository) GetByID(id int) (*models.Schedule, error) {
+	return scanSchedule(r.db.QueryRow(`SELECT `+scheduleColumns+` FROM schedules WHERE id = ?`, id))
+}
+
+// GetByProjectID 获取项目的所有定时任务
+func (r *ScheduleRepository) GetByProjectID(projectID int) ([]*models.Schedule, error) {
+	return r.query(`SELECT `+scheduleColumns+` FROM schedules WHERE project_id = ? ORDER BY id`, projectID)
+}
+
+// ListEnabled 获取所有启用的定时任务
+func (r *ScheduleRepository) ListEnabled() ([]*models.Schedule, error) {
+	return r.query(`SELECT ` + scheduleColumns + ` FROM schedules WHERE enabled = 1 ORDER BY id`)
+}
+
+// query 查询定时任务列表
+func (r *ScheduleRepository) query(query string, args ...any) ([]*models.Schedule, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*models.Schedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// DeletePipelineSchedulesExcept 删除管道配置中不再存在的 cron 表达式对应的定时任务，返回保留的定时任务的 cron 表达式
+func (r *ScheduleRepository) DeletePipelineSchedulesExcept(pipelineID int, crons []string) (map[string]bool, error) {
+	schedules, err := r.query(`SELECT `+scheduleColumns+` FROM schedules WHERE pipeline_id = ?`, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{}
+	for _, cron := range crons {
+		keep[cron] = true
+	}
+	existing := map[string]bool{}
+	for _, schedule := range schedules {
+		if keep[schedule.Cron] {
+			existing[schedule.Cron] = true
+			continue
+		}
+		if err := r.Delete(schedule.ID); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// DeleteOrphans 删除项目已删除或管道配置已删除的定时任务，返回删除的数量
+func (r *ScheduleRepository) DeleteOrphans() (int, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM schedules
+		WHERE project_id NOT IN (SELECT id FROM projects)
+			OR (pipeline_id IS NOT NULL AND pipeline_id NOT IN (SELECT id FROM pipelines))
+	`)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// Claim 将下次触发时间从 expected 推进到 next，只有下次触发时间仍为 expected 时才会修改。
+// 多个实例或重启前后同时处理同一个触发时间时只有一个能成功，成功时返回 true
+func (r *ScheduleRepository) Claim(id int, expected, next time.Time) (bool, error) {
+	result, err := r.db.Exec(`UPDATE schedules SET next_run_at = ?, updated_at = ? WHERE id = ? AND next_run_at IS ?`,
+		nullTime(next), time.Now(), id, nullTime(expected))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// RecordRun 保存最近一次触发的计划时间、启动的执行和错误信息
+func (r *ScheduleRepository) RecordRun(id int, runAt time.Time, executionID, runError string) error {
+	_, err := r.db.Exec(`UPDATE schedules SET last_run_at = ?, last_execution_id = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		nullTime(runAt), nullString(executionID), nullString(runError), time.Now(), id)
+	return err
+}
+
+// scanSchedule 扫描一行定时任务数据
+func scanSchedule(row interface{ Scan(dest ...any) error }) (*models.Schedule, error) {
+	var schedule models.Schedule
+	var pipelineID sql.NullInt64
+	var ref, lastExecutionID, lastError sql.NullString
+	var nextRunAt, lastRunAt sql.NullTime
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.ProjectID,
+		&pipelineID,
+		&schedule.Source,
+		&schedule.Name,
+		&schedule.Cron,
+		&schedule.Timezone,
+		&schedule.Platform,
+		&ref,
+		&schedule.CatchUp,
+		&schedule.Enabled,
+		&nextRunAt,
+		&lastRunAt,
+		&lastExecutionID,
+		&lastError,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	schedule.PipelineID = int(pipelineID.Int64)
+	schedule.Ref = ref.String
+	schedule.NextRunAt = nextRunAt.Time
+	schedule.LastRunAt = lastRunAt.Time
+	schedule.LastExecutionID = lastExecutionID.String
+	schedule.LastError = lastError.String
+
+	return &schedule, nil
+}
+
+// nullTime 将零值时间转换为 NULL，其余时间统一保存为 UTC，保证比较时格式一致
+func nullTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value.UTC(), Valid: !value.IsZero()}
+}
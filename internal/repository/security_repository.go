@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"ci-cd-orchestrator/internal/models"
+)
+
+// SecurityFindingRepository 安全检查结果仓库
+type SecurityFindingRepository struct {
+	db *sql.DB
+}
+
+// NewSecurityFindingRepository 创建安全检查结果仓库实例
+func NewSecurityFindingRepository(db *sql.DB) *SecurityFindingRepository {
+	return &SecurityFindingRepository{db: db}
+}
+
+// SecurityFindingFilter 安全检查结果查询条件，零值表示不过滤
+type SecurityFindingFilter struct {
+	ProjectID  int
+	PipelineID int
+	Rule       string
+	Severity   string
+}
+
+// ReplaceForPipeline 用新的检查结果替换管道配置已有的检查结果
+func (r *SecurityFindingRepository) ReplaceForPipeline(pipelineID int, findings []*models.SecurityFinding) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM security_findings WHERE pipeline_id = ?`, pipelineID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO security_findings (project_id, pipeline_id, rule, severity, job, line, "column", message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	for _, finding := range findings {
+		finding.PipelineID = pipelineID
+		result, err := tx.Exec(
+			query,
+			finding.ProjectID,
+			finding.PipelineID,
+			finding.Rule,
+			finding.Severity,
+			finding.Job,
+			finding.Line,
+			finding.Column,
+			finding.Message,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		finding.ID = int(id)
+		finding.CreatedAt = now
+	}
+
+	return tx.Commit()
+}
+
+// List 按条件查询安全检查结果，按管道配置和位置排序
+func (r *SecurityFindingRepository) List(filter SecurityFindingFilter) ([]*models.SecurityFinding, error) {
+	query := `
+		SELECT id, project_id, pipeline_id, rule, severity, job, line, "column", message, created_at
+		FROM security_findings
+		WHERE 1 = 1
+	`
+	var args []interface{}
+	if filter.ProjectID > 0 {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.PipelineID > 0 {
+		query += ` AND pipeline_id = ?`
+		args = append(args, filter.PipelineID)
+	}
+	if filter.Rule != "" {
+		query += ` AND rule = ?`
+		args = append(args, filter.Rule)
+	}
+	if filter.Severity != "" {
+		query += ` AND severity = ?`
+		args = append(args, filter.Severity)
+	}
+	query += ` ORDER BY pipeline_id, line, "column", id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*models.SecurityFinding
+	for rows.Next() {
+		var finding models.SecurityFinding
+		var job sql.NullString
+		err := rows.Scan(
+			&finding.ID,
+			&finding.ProjectID,
+			&finding.PipelineID,
+			&finding.Rule,
+			&finding.Severity,
+			&job,
+			&finding.Line,
+			&finding.Column,
+			&finding.Message,
+			&finding.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		finding.Job = job.String
+		findings = append(findings, &finding)
+	}
+
+	return findings, rows.Err()
+}
+
+// SecurityRuleRepository 安全检查规则开关仓库
+type SecurityRuleRepository struct {
+	db *sql.DB
+}
+
+// NewSecurityRuleRepository 创建安全检查规则开关仓库实例
+func NewSecurityRuleRepository(db *sql.DB) *SecurityRuleRepository {
+	return &SecurityRuleRepository{db: db}
+}
+
+// GetByProjectID 获取项目的规则开关
+func (r *SecurityRuleRepository) GetByProjectID(projectID int) ([]*models.SecurityRuleSetting, error) {
+	query := `
+		SELECT project_id, rule, enabled, updated_at
+		FROM security_rule_settings
+		WHERE project_id = ?
+		ORDER BY rule
+	`
+
+	rows, err := r.db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []*models.SecurityRuleSetting
+	for rows.Next() {
+		var setting models.SecurityRuleSetting
+		if err := rows.Scan(&setting.ProjectID, &setting.Rule, &setting.Enabled, &setting.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, &setting)
+	}
+
+	return settings, rows.Err()
+}
+
+// DisabledRules 获取项目中被关闭的规则
+func (r *SecurityRuleRepository) DisabledRules(projectID int) (map[string]bool, error) {
+	settings, err := r.GetByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]bool)
+	for _, setting := range settings {
+		if !setting.Enabled {
+			disabled[setting.Rule] = true
+		}
+	}
+	return disabled, nil
+}
+
+// Set 设置项目的规则开关
+func (r *SecurityRuleRepository) Set(setting *models.SecurityRuleSetting) error {
+	query := `
+		INSERT INTO security_rule_settings (project_id, rule, enabled, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (project_id, rule) DO UPDATE SET enabled = excluded.enabled, updated_at = excluded.updated_at
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, setting.ProjectID, setting.Rule, setting.Enabled, now); err != nil {
+		return err
+	}
+
+	setting.UpdatedAt = now
+	return nil
+}
@@ -2,32 +2,91 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+// 模板类型
+const (
+	TemplateKindPipeline = "pipeline" // 可按技术栈和平台选择、生成管道配置的模板
+	TemplateKindFragment = "fragment" // 只能被其他模板继承或引用的片段
+)
+
 // Template 模板模型
 type Template struct {
-	ID         int       `json:"id"`
-	Platform   string    `json:"platform"`
-	Language   string    `json:"language"`
-	Framework  string    `json:"framework"`
-	Content    string    `json:"content"`
-	Filename   string    `json:"filename"`
-	ConfigType string    `json:"config_type"`
-	IsBuiltin  bool      `json:"is_builtin"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         int                 `json:"id"`
+	Name       string              `json:"name,omitempty"` // 唯一名称，被继承或引用时使用
+	Kind       string              `json:"kind"`
+	Extends    string              `json:"extends,omitempty"` // 继承的基础模板名称
+	Platform   string              `json:"platform"`
+	Language   string              `json:"language"`
+	Framework  string              `json:"framework"`
+	Content    string              `json:"content"`
+	Filename   string              `json:"filename"`
+	ConfigType string              `json:"config_type"`
+	Parameters []TemplateParameter `json:"parameters"`
+	Version    int                 `json:"version"` // 当前版本号，每次修改递增
+	IsBuiltin  bool                `json:"is_builtin"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// TemplateVersion 模板的不可变版本，保存修改后模板的完整内容
+type TemplateVersion struct {
+	ID         int                 `json:"id"`
+	TemplateID int                 `json:"template_id"`
+	Version    int                 `json:"version"`
+	Extends    string              `json:"extends,omitempty"`
+	Platform   string              `json:"platform"`
+	Language   string              `json:"language"`
+	Framework  string              `json:"framework"`
+	Content    string              `json:"content"`
+	Filename   string              `json:"filename"`
+	ConfigType string              `json:"config_type"`
+	Parameters []TemplateParameter `json:"parameters"`
+	Author     string              `json:"author"`
+	Note       string              `json:"note"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// TemplateChange 模板修改的作者和说明，记录在新版本中
+type TemplateChange struct {
+	Author string `json:"author"`
+	Note   string `json:"note"`
+}
+
+// TemplateImport 导入的模板。Template.ID 不为 0 时覆盖该模板，修改保存为新版本；
+// 否则创建新模板，并原样保存 Versions 中的历史版本，Template.Version 为当前版本号
+type TemplateImport struct {
+	Template *Template
+	Versions []*TemplateVersion
+}
+
+// TemplateParameter 模板参数定义
+type TemplateParameter struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"` // string、number 或 boolean
+	Description string        `json:"description,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Required    bool          `json:"required,omitempty"`
+	Source      string        `json:"source,omitempty"` // 从技术栈自动填充的字段
 }
 
 // TemplateRepository 模板仓库接口
 type TemplateRepository interface {
-	Create(template *Template) error
+	Create(template *Template, change TemplateChange) error
 	GetByID(id int) (*Template, error)
+	GetByName(name string) (*Template, error)
 	GetAll() ([]*Template, error)
-	Update(template *Template) error
+	Update(template *Template, change TemplateChange) error
 	Delete(id int) error
 	GetByPlatformAndLanguage(platform string, language, framework string) (*Template, error)
 	ResetBuiltinTemplates() error
+	ListVersions(templateID int) ([]*TemplateVersion, error)
+	GetVersion(templateID, version int) (*TemplateVersion, error)
+	Rollback(templateID, version int, change TemplateChange) (*Template, error)
+	Import(imports []*TemplateImport, change TemplateChange) error
 }
 
 // TemplateRepositoryImpl 模板仓库实现
@@ -42,15 +101,29 @@ func NewTemplateRepository(db *sql.DB) TemplateRepository {
 	}
 }
 
-// Create 创建模板
-func (r *TemplateRepositoryImpl) Create(template *Template) error {
+// Create 创建模板，同时保存为版本 1
+func (r *TemplateRepositoryImpl) Create(template *Template, change TemplateChange) error {
 	query := `
-		INSERT INTO templates (platform, language, framework, content, filename, config_type, is_builtin, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO templates (name, kind, extends, platform, language, framework, content, filename, config_type, parameters, version, is_builtin, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	if template.Kind == "" {
+		template.Kind = TemplateKindPipeline
+	}
+	parameters, err := encodeParameters(template.Parameters)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	now := time.Now()
-	result, err := r.db.Exec(query, template.Platform, template.Language, template.Framework, template.Content, template.Filename, template.ConfigType, template.IsBuiltin, now, now)
+	result, err := tx.Exec(query, nullString(template.Name), template.Kind, nullString(template.Extends), template.Platform, template.Language, template.Framework, template.Content, template.Filename, template.ConfigType, parameters, 1, template.IsBuiltin, now, now)
 	if err != nil {
 		return err
 	}
@@ -61,48 +134,44 @@ func (r *TemplateRepositoryImpl) Create(template *Template) error {
 	}
 
 	template.ID = int(id)
+	template.Version = 1
 	template.CreatedAt = now
 	template.UpdatedAt = now
 
-	return nil
+	if err := insertVersion(tx, template, parameters, change); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetByID 根据ID获取模板
 func (r *TemplateRepositoryImpl) GetByID(id int) (*Template, error) {
 	query := `
-		SELECT id, platform, language, framework, content, filename, config_type, is_builtin, created_at, updated_at
+		SELECT id, name, kind, extends, platform, language, framework, content, filename, config_type, parameters, version, is_builtin, created_at, updated_at
 		FROM templates
 		WHERE id = ?
 	`
 
-	row := r.db.QueryRow(query, id)
+	return scanTemplate(r.db.QueryRow(query, id))
+}
 
-	var template Template
-	err := row.Scan(
-		&template.ID,
-		&template.Platform,
-		&template.Language,
-		&template.Framework,
-		&template.Content,
-		&template.Filename,
-		&template.ConfigType,
-		&template.IsBuiltin,
-		&template.CreatedAt,
-		&template.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
+// GetByName 根据名称获取模板
+func (r *TemplateRepositoryImpl) GetByName(name string) (*Template, error) {
+	query := `
+		SELECT id, name, kind, extends, platform, language, framework, content, filename, config_type, parameters, version, is_builtin, created_at, updated_at
+		FROM templates
+		WHERE name = ?
+	`
 
-	return &template, nil
+	return scanTemplate(r.db.QueryRow(query, name))
 }
 
 // GetAll 获取所有模板
 func (r *TemplateRepositoryImpl) GetAll() ([]*Template, error) {
 	query := `
-		SELECT id, platform, language, framework, content, filename, config_type, is_builtin, created_at, updated_at
+		SELECT id, name, kind, extends, platform, language, framework, content, filename, config_type, parameters, version, is_builtin, created_at, updated_at
 		FROM templates
-		ORDER BY is_builtin DESC, platform, language, framework
+		ORDER BY is_builtin DESC, kind DESC, platform, language, framework
 	`
 
 	rows, err := r.db.Query(query)
@@ -113,44 +182,72 @@ func (r *TemplateRepositoryImpl) GetAll() ([]*Template, error) {
 
 	var templates []*Template
 	for rows.Next() {
-		var template Template
-		err := rows.Scan(
-			&template.ID,
-			&template.Platform,
-			&template.Language,
-			&template.Framework,
-			&template.Content,
-			&template.Filename,
-			&template.ConfigType,
-			&template.IsBuiltin,
-			&template.CreatedAt,
-			&template.UpdatedAt,
-		)
+		template, err := scanTemplate(rows)
 		if err != nil {
 			return nil, err
 		}
-		templates = append(templates, &template)
+		templates = append(templates, template)
 	}
 
 	return templates, nil
 }
 
-// Update 更新模板
-func (r *TemplateRepositoryImpl) Update(template *Template) error {
+// Update 更新模板，修改后的内容保存为新版本
+func (r *TemplateRepositoryImpl) Update(template *Template, change TemplateChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := update(tx, template, change); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// update 在事务中更新模板并保存新版本
+func update(tx *sql.Tx, template *Template, change TemplateChange) error {
+	var version int
+	if err := tx.QueryRow("SELECT version FROM templates WHERE id = ?", template.ID).Scan(&version); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE templates
-		SET platform = ?, language = ?, framework = ?, content = ?, filename = ?, config_type = ?, updated_at = ?
+		SET name = ?, kind = ?, extends = ?, platform = ?, language = ?, framework = ?, content = ?, filename = ?, config_type = ?, parameters = ?, version = ?, updated_at = ?
 		WHERE id = ?
 	`
 
+	parameters, err := encodeParameters(template.Parameters)
+	if err != nil {
+		return err
+	}
+
+	if template.Kind == "" {
+		template.Kind = TemplateKindPipeline
+	}
+
 	now := time.Now()
-	_, err := r.db.Exec(query, template.Platform, template.Language, template.Framework, template.Content, template.Filename, template.ConfigType, now, template.ID)
+	_, err = tx.Exec(query, nullString(template.Name), template.Kind, nullString(template.Extends), template.Platform, template.Language, template.Framework, template.Content, template.Filename, template.ConfigType, parameters, version+1, now, template.ID)
 	if err != nil {
 		return err
 	}
 
+	template.Version = version + 1
 	template.UpdatedAt = now
-	return nil
+	return insertVersion(tx, template, parameters, change)
+}
+
+// insertVersion 将模板的当前内容保存为版本
+func insertVersion(tx *sql.Tx, template *Template, parameters sql.NullString, change TemplateChange) error {
+	query := `
+		INSERT INTO template_versions (template_id, version, extends, platform, language, framework, content, filename, config_type, parameters, author, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := tx.Exec(query, template.ID, template.Version, nullString(template.Extends), template.Platform, template.Language, template.Framework, template.Content, template.Filename, template.ConfigType, parameters, change.Author, change.Note, template.UpdatedAt)
+	return err
 }
 
 // Delete 删除模板
@@ -166,32 +263,202 @@ func (r *TemplateRepositoryImpl) Delete(id int) error {
 		return nil // 内置模板不可删除
 	}
 
-	query := `DELETE FROM templates WHERE id = ?`
-	_, err = r.db.Exec(query, id)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM template_versions WHERE template_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM templates WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetByPlatformAndLanguage 根据平台、语言和框架获取模板
 func (r *TemplateRepositoryImpl) GetByPlatformAndLanguage(platform string, language, framework string) (*Template, error) {
 	query := `
-		SELECT id, platform, language, framework, content, filename, config_type, is_builtin, created_at, updated_at
+		SELECT id, name, kind, extends, platform, language, framework, content, filename, config_type, parameters, version, is_builtin, created_at, updated_at
 		FROM templates
-		WHERE platform = ? AND language = ? AND framework = ?
-		ORDER BY is_builtin DESC
+		WHERE platform = ? AND language = ? AND framework = ? AND kind = 'pipeline'
+		ORDER BY is_builtin DESC, id
 		LIMIT 1
 	`
 
-	row := r.db.QueryRow(query, platform, language, framework)
+	return scanTemplate(r.db.QueryRow(query, platform, language, framework))
+}
+
+// ResetBuiltinTemplates 重置内置模板
+func (r *TemplateRepositoryImpl) ResetBuiltinTemplates() error {
+	// 这里将在后续实现，用于重置内置模板到默认状态
+	return nil
+}
+
+// ListVersions 获取模板的所有版本，按版本号从新到旧排序
+func (r *TemplateRepositoryImpl) ListVersions(templateID int) ([]*TemplateVersion, error) {
+	query := `
+		SELECT id, template_id, version, extends, platform, language, framework, content, filename, config_type, parameters, author, note, created_at
+		FROM template_versions
+		WHERE template_id = ?
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.Query(query, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*TemplateVersion
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetVersion 获取模板的指定版本
+func (r *TemplateRepositoryImpl) GetVersion(templateID, version int) (*TemplateVersion, error) {
+	query := `
+		SELECT id, template_id, version, extends, platform, language, framework, content, filename, config_type, parameters, author, note, created_at
+		FROM template_versions
+		WHERE template_id = ? AND version = ?
+	`
+
+	return scanVersion(r.db.QueryRow(query, templateID, version))
+}
+
+// Rollback 将模板回滚到指定版本。历史版本不会被删除，回滚后的内容保存为新版本
+func (r *TemplateRepositoryImpl) Rollback(templateID, version int, change TemplateChange) (*Template, error) {
+	target, err := r.GetVersion(templateID, version)
+	if err != nil {
+		return nil, err
+	}
+	template, err := r.GetByID(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Extends = target.Extends
+	template.Platform = target.Platform
+	template.Language = target.Language
+	template.Framework = target.Framework
+	template.Content = target.Content
+	template.Filename = target.Filename
+	template.ConfigType = target.ConfigType
+	template.Parameters = target.Parameters
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := update(tx, template, change); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Import 在一个事务中导入模板，任何一个模板写入失败时全部回滚
+func (r *TemplateRepositoryImpl) Import(imports []*TemplateImport, change TemplateChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, imported := range imports {
+		if imported.Template.ID != 0 {
+			if err := update(tx, imported.Template, change); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := insertImported(tx, imported); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertImported 在事务中创建导入的模板并保存其历史版本
+func insertImported(tx *sql.Tx, imported *TemplateImport) error {
+	template := imported.Template
+	query := `
+		INSERT INTO templates (name, kind, extends, platform, language, framework, content, filename, config_type, parameters, version, is_builtin, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if template.Kind == "" {
+		template.Kind = TemplateKindPipeline
+	}
+	if template.Version == 0 {
+		template.Version = 1
+	}
+	parameters, err := encodeParameters(template.Parameters)
+	if err != nil {
+		return err
+	}
 
+	now := time.Now()
+	result, err := tx.Exec(query, nullString(template.Name), template.Kind, nullString(template.Extends), template.Platform, template.Language, template.Framework, template.Content, template.Filename, template.ConfigType, parameters, template.Version, false, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	template.ID = int(id)
+	template.IsBuiltin = false
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	versionQuery := `
+		INSERT INTO template_versions (template_id, version, extends, platform, language, framework, content, filename, config_type, parameters, author, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for _, version := range imported.Versions {
+		parameters, err := encodeParameters(version.Parameters)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(versionQuery, template.ID, version.Version, nullString(version.Extends), version.Platform, version.Language, version.Framework, version.Content, version.Filename, version.ConfigType, parameters, version.Author, version.Note, version.CreatedAt); err != nil {
+			return err
+		}
+		version.TemplateID = template.ID
+	}
+	return nil
+}
+
+// scanTemplate 读取一行模板
+func scanTemplate(row interface{ Scan(dest ...any) error }) (*Template, error) {
 	var template Template
+	var name, extends, parameters sql.NullString
 	err := row.Scan(
 		&template.ID,
+		&name,
+		&template.Kind,
+		&extends,
 		&template.Platform,
 		&template.Language,
 		&template.Framework,
 		&template.Content,
 		&template.Filename,
 		&template.ConfigType,
+		&parameters,
+		&template.Version,
 		&template.IsBuiltin,
 		&template.CreatedAt,
 		&template.UpdatedAt,
@@ -199,12 +466,73 @@ func (r *TemplateRepositoryImpl) GetByPlatformAndLanguage(platform string, langu
 	if err != nil {
 		return nil, err
 	}
+	template.Name = name.String
+	template.Extends = extends.String
+	if template.Parameters, err = decodeParameters(parameters); err != nil {
+		return nil, err
+	}
 
 	return &template, nil
 }
 
-// ResetBuiltinTemplates 重置内置模板
-func (r *TemplateRepositoryImpl) ResetBuiltinTemplates() error {
-	// 这里将在后续实现，用于重置内置模板到默认状态
-	return nil
+// scanVersion 读取一行模板版本
+func scanVersion(row interface{ Scan(dest ...any) error }) (*TemplateVersion, error) {
+	var version TemplateVersion
+	var extends, parameters, author, note sql.NullString
+	err := row.Scan(
+		&version.ID,
+		&version.TemplateID,
+		&version.Version,
+		&extends,
+		&version.Platform,
+		&version.Language,
+		&version.Framework,
+		&version.Content,
+		&version.Filename,
+		&version.ConfigType,
+		&parameters,
+		&author,
+		&note,
+		&version.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	version.Extends = extends.String
+	version.Author = author.String
+	version.Note = note.String
+	if version.Parameters, err = decodeParameters(parameters); err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
+
+// encodeParameters 将参数定义编码为 JSON，没有参数时保存为 NULL
+func encodeParameters(parameters []TemplateParameter) (sql.NullString, error) {
+	if len(parameters) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// nullString 空字符串保存为 NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// decodeParameters 解析 JSON 格式的参数定义
+func decodeParameters(data sql.NullString) ([]TemplateParameter, error) {
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var parameters []TemplateParameter
+	if err := json.Unmarshal([]byte(data.String), &parameters); err != nil {
+		return nil, err
+	}
+	return parameters, nil
 }
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"ci-cd-orchestrator/internal/models"
+)
+
+// webhook 投递的处理结果
+const (
+	DeliveryStatusReceived  = "received"  // 已保存，尚未处理
+	DeliveryStatusProcessed = "processed" // 已为匹配的项目启动执行
+	DeliveryStatusIgnored   = "ignored"   // 事件不触发执行
+	DeliveryStatusUnmatched = "unmatched" // 没有项目的仓库地址与事件匹配
+	DeliveryStatusFiltered  = "filtered"  // 匹配的项目的管道配置都不在该事件上触发
+	DeliveryStatusFailed    = "failed"    // 负载无法解析或所有执行都启动失败
+)
+
+// WebhookDeliveryRepository webhook 投递仓库
+type WebhookDeliveryRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookDeliveryRepository 创建 webhook 投递仓库实例
+func NewWebhookDeliveryRepository(db *sql.DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Create 保存投递。同一平台的投递 ID 已存在时不保存，只增加已有投递的重复次数并返回 false
+func (r *WebhookDeliveryRepository) Create(delivery *models.WebhookDelivery) (bool, error) {
+	if delivery.Status == "" {
+		delivery.Status = DeliveryStatusReceived
+	}
+	executionIDs, err := json.Marshal(delivery.ExecutionIDs)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT OR IGNORE INTO webhook_deliveries (provider, delivery_id, event, platform, payload, status, message, execution_ids, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, delivery.Provider, delivery.DeliveryID, delivery.Event, delivery.Platform, delivery.Payload, delivery.Status, nullString(delivery.Message), string(executionIDs), now, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		_, err := r.db.Exec(`UPDATE webhook_deliveries SET duplicates = duplicates + 1 WHERE provider = ? AND delivery_id = ?`, delivery.Provider, delivery.DeliveryID)
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	delivery.ID = int(id)
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	return true, nil
+}
+
+// UpdateResult 保存投递的处理结果和重新投递次数
+func (r *WebhookDeliveryRepository) UpdateResult(delivery *models.WebhookDelivery) error {
+	executionIDs, err := json.Marshal(delivery.ExecutionIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, message = ?, execution_ids = ?, redeliveries = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, delivery.Status, nullString(delivery.Message), string(executionIDs), delivery.Redeliveries, now, delivery.ID); err != nil {
+		return err
+	}
+	delivery.UpdatedAt = now
+	return nil
+}
+
+// GetByID 根据 ID 获取投递
+func (r *WebhookDeliveryRepository) GetByID(id int) (*models.WebhookDelivery, error) {
+	query := `
+		SELECT id, provider, delivery_id, event, platform, payload, status, message, execution_ids, duplicates, redeliveries, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE id = ?
+	`
+
+	return scanDelivery(r.db.QueryRow(query, id))
+}
+
+// List 获取投递，按接收时间从新到旧排序，provider 为空时不过滤
+func (r *WebhookDeliveryRepository) List(provider string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT id, provider, delivery_id, event, platform, payload, status, message, execution_ids, duplicates, redeliveries, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE ? = '' OR provider = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, provider, provider, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// scanDelivery 扫描一行投递数据
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var message, executionIDs sql.NullString
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.Provider,
+		&delivery.DeliveryID,
+		&delivery.Event,
+		&delivery.Platform,
+		&delivery.Payload,
+		&delivery.Status,
+		&message,
+		&executionIDs,
+		&delivery.Duplicates,
+		&delivery.Redeliveries,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Message = message.String
+	if executionIDs.String != "" {
+		if err := json.Unmarshal([]byte(executionIDs.String), &delivery.ExecutionIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return &delivery, nil
+}
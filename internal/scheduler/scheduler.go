@@ -0,0 +1,299 @@
+// Package scheduler 按 cron 表达式定时启动执行。
+//
+// 定时任务来自已保存的管道配置中的 on.schedule，或通过 API 创建。每个定时任务在数据库中保存下次触发时间，
+// 触发前先用比较并交换的方式推进下次触发时间，因此重启或多个服务实例同时运行时同一个触发时间只会启动一次执行
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/model"
+	"ci-cd-orchestrator/internal/execution"
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/internal/trigger"
+	"ci-cd-orchestrator/pkg/cron"
+)
+
+// 错过的触发时间的处理策略，服务停止或检查间隔较长时触发时间可能已经过去
+const (
+	CatchUpSkip   = "skip"   // 不补充错过的触发，只在延迟不超过宽限时间时触发
+	CatchUpLatest = "latest" // 只补充最近一次错过的触发
+	CatchUpAll    = "all"    // 补充每一次错过的触发，最多 MaxCatchUpRuns 次
+)
+
+// MaxCatchUpRuns catch_up 为 all 时一次最多补充的触发次数，超过时只补充最近的触发
+const MaxCatchUpRuns = 10
+
+// TriggerType 定时启动的执行的触发类型
+const TriggerType = "schedule"
+
+// PipelineTimezone 管道配置 on.schedule 的 cron 表达式使用的时区，与 GitHub Actions 相同
+const PipelineTimezone = "UTC"
+
+// ValidCatchUp 判断错过触发的处理策略是否有效
+func ValidCatchUp(catchUp string) bool {
+	return catchUp == CatchUpSkip || catchUp == CatchUpLatest || catchUp == CatchUpAll
+}
+
+// NextRun 计算 cron 表达式在指定时区中 after 之后的下次触发时间，表达式或时区无效时返回错误
+func NextRun(expr, timezone string, after time.Time) (time.Time, error) {
+	schedule, location, err := parse(expr, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return next(schedule, location, after)
+}
+
+// parse 解析 cron 表达式和时区
+func parse(expr, timezone string) (*cron.Schedule, *time.Location, error) {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	return schedule, location, nil
+}
+
+// next 计算 after 之后的下次触发时间，不会再触发时返回错误
+func next(schedule *cron.Schedule, location *time.Location, after time.Time) (time.Time, error) {
+	t := schedule.Next(after.In(location))
+	if t.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", schedule)
+	}
+	return t, nil
+}
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	projectRepo  *repository.ProjectRepository
+	pipelineRepo *repository.PipelineRepository
+	scheduleRepo *repository.ScheduleRepository
+	runner       *trigger.Runner
+	grace        time.Duration // 触发延迟不超过该时间时不算错过
+}
+
+// NewScheduler 创建定时任务调度器实例
+func NewScheduler(manager execution.Manager, projectRepo *repository.ProjectRepository, pipelineRepo *repository.PipelineRepository, scheduleRepo *repository.ScheduleRepository) *Scheduler {
+	return &Scheduler{
+		projectRepo:  projectRepo,
+		pipelineRepo: pipelineRepo,
+		scheduleRepo: scheduleRepo,
+		runner:       trigger.NewRunner(manager, pipelineRepo),
+		grace:        time.Minute,
+	}
+}
+
+// Run 立即检查一次定时任务，之后每隔 interval 检查一次，直到 stop 被关闭。
+// 检查间隔内到期的触发延迟最多 interval，因此宽限时间为 interval 加一分钟
+func (s *Scheduler) Run(interval time.Duration, stop <-chan struct{}) {
+	s.grace = interval + time.Minute
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if started, err := s.Tick(time.Now()); err != nil {
+			log.Printf("检查定时任务失败: %v", err)
+		} else if started > 0 {
+			log.Printf("定时任务启动了 %d 个执行", started)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Tick 同步管道配置中的定时任务，触发到期的定时任务，返回启动的执行数量。
+// 同步失败时记录日志，仍然触发已保存的定时任务
+func (s *Scheduler) Tick(now time.Time) (int, error) {
+	if err := s.SyncPipelines(now); err != nil {
+		log.Printf("同步管道配置中的定时任务失败: %v", err)
+	}
+
+	schedules, err := s.scheduleRepo.ListEnabled()
+	if err != nil {
+		return 0, err
+	}
+
+	started := 0
+	for _, schedule := range schedules {
+		if schedule.NextRunAt.After(now) {
+			continue
+		}
+
+		runs, upcoming, err := Plan(schedule, now, s.grace)
+		if err != nil {
+			log.Printf("定时任务 %d 无效: %v", schedule.ID, err)
+			continue
+		}
+
+		// 只有成功推进下次触发时间的实例启动执行
+		claimed, err := s.scheduleRepo.Claim(schedule.ID, schedule.NextRunAt, upcoming)
+		if err != nil {
+			return started, err
+		}
+		if !claimed || len(runs) == 0 {
+			continue
+		}
+		started += s.fire(schedule, runs)
+	}
+	return started, nil
+}
+
+// Plan 按错过触发的处理策略计算到 now 为止需要触发的计划时间，以及 now 之后的下次触发时间。
+// 没有下次触发时间的定时任务只计算下次触发时间
+func Plan(schedule *models.Schedule, now time.Time, grace time.Duration) ([]time.Time, time.Time, error) {
+	expr, location, err := parse(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	upcoming, err := next(expr, location, now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if schedule.NextRunAt.IsZero() || schedule.NextRunAt.After(now) {
+		return nil, upcoming, nil
+	}
+
+	// 错过的触发时间，最多保留最近的 MaxCatchUpRuns 个
+	var missed []time.Time
+	for due := schedule.NextRunAt; !due.IsZero() && !due.After(now); due = expr.Next(due.In(location)) {
+		missed = append(missed, due)
+		if len(missed) > MaxCatchUpRuns {
+			missed = missed[1:]
+		}
+	}
+
+	latest := missed[len(missed)-1]
+	switch schedule.CatchUp {
+	case CatchUpAll:
+		return missed, upcoming, nil
+	case CatchUpSkip:
+		if now.Sub(latest) > grace {
+			return nil, upcoming, nil
+		}
+	}
+	return []time.Time{latest}, upcoming, nil
+}
+
+// fire 按计划时间启动执行并保存最近一次触发的结果，返回启动的执行数量
+func (s *Scheduler) fire(schedule *models.Schedule, runs []time.Time) int {
+	last := runs[len(runs)-1]
+	project, err := s.projectRepo.GetByID(schedule.ProjectID)
+	if err != nil {
+		s.record(schedule, last, "", fmt.Errorf("failed to get project: %w", err))
+		return 0
+	}
+
+	started := 0
+	for _, runAt := range runs {
+		executionID, err := s.runner.Start(project, trigger.Request{
+			Platform: schedule.Platform,
+			Type:     TriggerType,
+			Info: map[string]interface{}{
+				"schedule_id":  schedule.ID,
+				"source":       schedule.Source,
+				"cron":         schedule.Cron,
+				"timezone":     schedule.Timezone,
+				"scheduled_at": runAt.Format(time.RFC3339),
+			},
+			Ref:        schedule.Ref,
+			PipelineID: schedule.PipelineID,
+		})
+		if executionID != "" {
+			started++
+		}
+		s.record(schedule, runAt, executionID, err)
+	}
+	return started
+}
+
+// record 保存定时任务最近一次触发的结果
+func (s *Scheduler) record(schedule *models.Schedule, runAt time.Time, executionID string, runErr error) {
+	message := ""
+	if runErr != nil {
+		message = runErr.Error()
+		log.Printf("定时任务 %d 启动执行失败: %v", schedule.ID, runErr)
+	}
+	if err := s.scheduleRepo.RecordRun(schedule.ID, runAt, executionID, message); err != nil {
+		log.Printf("保存定时任务 %d 的触发结果失败: %v", schedule.ID, err)
+	}
+}
+
+// SyncPipelines 根据已保存的管道配置中的 on.schedule 创建定时任务，删除管道配置中已移除的定时任务。
+// 无法解析的管道配置保留已有的定时任务，已有定时任务的启用状态等设置不会被覆盖
+func (s *Scheduler) SyncPipelines(now time.Time) error {
+	pipelines, err := s.pipelineRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, pipeline := range pipelines {
+		parsed, _, err := model.Parse(common.Platform(pipeline.Platform), pipeline.Config)
+		if err != nil {
+			continue
+		}
+
+		var crons []string
+		nextRuns := map[string]time.Time{}
+		for _, t := range parsed.Triggers {
+			if t.Event != model.EventSchedule || t.Cron == "" {
+				continue
+			}
+			nextRun, err := NextRun(t.Cron, PipelineTimezone, now)
+			if err != nil {
+				log.Printf("管道配置 %d 的定时触发 %q 无效: %v", pipeline.ID, t.Cron, err)
+				continue
+			}
+			crons = append(crons, t.Cron)
+			nextRuns[t.Cron] = nextRun
+		}
+
+		existing, err := s.scheduleRepo.DeletePipelineSchedulesExcept(pipeline.ID, crons)
+		if err != nil {
+			return err
+		}
+		for _, expr := range crons {
+			if existing[expr] {
+				continue
+			}
+			_, err := s.scheduleRepo.Create(&models.Schedule{
+				ProjectID:  pipeline.ProjectID,
+				PipelineID: pipeline.ID,
+				Source:     repository.ScheduleSourcePipeline,
+				Name:       fmt.Sprintf("%s on.schedule", pipeline.Platform),
+				Cron:       expr,
+				Timezone:   PipelineTimezone,
+				Platform:   ExecutionPlatform(pipeline.Platform),
+				CatchUp:    CatchUpLatest,
+				Enabled:    true,
+				NextRunAt:  nextRuns[expr],
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = s.scheduleRepo.DeleteOrphans()
+	return err
+}
+
+// ExecutionPlatform 管道配置的定时任务默认使用的执行平台。GitHub Actions 在仓库中自行运行 on.schedule，
+// 在本地再运行一次会在服务器上执行 workflow 中的命令，因此默认在 mock 平台上模拟执行该 workflow，
+// 需要在本地运行时将定时任务的平台修改为 local；其他平台与管道配置的平台相同
+func ExecutionPlatform(pipelinePlatform string) string {
+	if pipelinePlatform == string(common.PlatformGitHubActions) {
+		return "mock"
+	}
+	return pipelinePlatform
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"ci-cd-orchestrator/internal/models"
+)
+
+func TestNextRun(t *testing.T) {
+	after := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	next, err := NextRun("0 2 * * *", "Asia/Shanghai", after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 上海时间 3 月 1 日 18:30 之后的 2:00 为 3 月 2 日 2:00，即 UTC 3 月 1 日 18:00
+	if expected := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("下次触发时间为 %s，期望 %s", next, expected)
+	}
+
+	if _, err := NextRun("0 2 * *", "UTC", after); err == nil {
+		t.Error("无效的 cron 表达式应返回错误")
+	}
+	if _, err := NextRun("0 2 * * *", "Mars/Base", after); err == nil {
+		t.Error("无效的时区应返回错误")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	hour := func(h int) time.Time { return time.Date(2026, 3, 1, h, 0, 0, 0, time.UTC) }
+	grace := 2 * time.Minute
+
+	tests := []struct {
+		name      string
+		catchUp   string
+		nextRunAt time.Time
+		now       time.Time
+		runs      []time.Time
+	}{
+		{"未到期", CatchUpLatest, hour(5), hour(4), nil},
+		{"按时触发", CatchUpSkip, hour(5), hour(5).Add(30 * time.Second), []time.Time{hour(5)}},
+		{"skip 不补充错过的触发", CatchUpSkip, hour(1), hour(5).Add(10 * time.Minute), nil},
+		{"skip 在宽限时间内触发最近一次", CatchUpSkip, hour(1), hour(5).Add(time.Minute), []time.Time{hour(5)}},
+		{"latest 补充最近一次", CatchUpLatest, hour(1), hour(5).Add(10 * time.Minute), []time.Time{hour(5)}},
+		{"all 补充每一次", CatchUpAll, hour(1), hour(3).Add(10 * time.Minute), []time.Time{hour(1), hour(2), hour(3)}},
+		{"没有下次触发时间", CatchUpAll, time.Time{}, hour(5), nil},
+	}
+
+	for _, test := range tests {
+		schedule := &models.Schedule{Cron: "0 * * * *", Timezone: "UTC", CatchUp: test.catchUp, NextRunAt: test.nextRunAt}
+		runs, next, err := Plan(schedule, test.now, grace)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(runs, test.runs) {
+			t.Errorf("%s: 触发时间为 %v，期望 %v", test.name, runs, test.runs)
+		}
+		if !next.After(test.now) || next.Sub(test.now) > time.Hour {
+			t.Errorf("%s: 下次触发时间 %s 不正确", test.name, next)
+		}
+	}
+
+	// all 最多补充 MaxCatchUpRuns 次，保留最近的触发
+	schedule := &models.Schedule{Cron: "* * * * *", Timezone: "UTC", CatchUp: CatchUpAll, NextRunAt: hour(1)}
+	runs, _, err := Plan(schedule, hour(2), grace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != MaxCatchUpRuns || !runs[len(runs)-1].Equal(hour(2)) {
+		t.Errorf("补充的触发为 %v", runs)
+	}
+}
+
+func TestExecutionPlatform(t *testing.T) {
+	// GitHub Actions 的 on.schedule 默认在 mock 平台上模拟执行，不在服务器上运行 workflow 中的命令
+	tests := map[string]string{
+		"github_actions": "mock",
+		"gitlab_ci":      "gitlab_ci",
+		"mock":           "mock",
+	}
+	for pipelinePlatform, expected := range tests {
+		if platform := ExecutionPlatform(pipelinePlatform); platform != expected {
+			t.Errorf("%s 管道配置的定时任务平台为 %s，期望 %s", pipelinePlatform, platform, expected)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 各语言声明版本的方式
+var (
+	goDirective         = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+(?:\.\d+)?)\s*$`)
+	mavenJavaVersion    = regexp.MustCompile(`<(?:maven\.compiler\.release|maven\.compiler\.source|java\.version|release)>\s*([\d.]+)\s*<`)
+	gradleToolchain     = regexp.MustCompile(`JavaLanguageVersion\.of\(\s*(\d+)\s*\)`)
+	gradleCompatibility = regexp.MustCompile(`sourceCompatibility\s*=\s*(?:JavaVersion\.VERSION_([\d_]+)|['"]?([\d.]+)['"]?)`)
+	requiresPython      = regexp.MustCompile(`(?m)^requires-python\s*=\s*["'][^\d"']*(\d+\.\d+)`)
+	rustChannel         = regexp.MustCompile(`(?m)^channel\s*=\s*["']([^"']+)["']`)
+	versionNumber       = regexp.MustCompile(`\d+(?:\.\d+)*`)
+)
+
+// DetectLanguageVersion 根据项目文件检测语言版本，检测不到时返回空字符串。
+// 同一种版本文件出现多次时使用目录层级最浅的那个
+func DetectLanguageVersion(language string, files []string) string {
+	files = append([]string(nil), files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return strings.Count(filepath.ToSlash(files[i]), "/") < strings.Count(filepath.ToSlash(files[j]), "/")
+	})
+
+	var detectors map[string]func(content string) string
+	var order []string
+	switch language {
+	case "Go":
+		order = []string{"go.mod"}
+		detectors = map[string]func(string) string{"go.mod": firstSubmatch(goDirective)}
+	case "Java":
+		order = []string{"pom.xml", "build.gradle", "build.gradle.kts"}
+		detectors = map[string]func(string) string{
+			"pom.xml":          javaVersion(firstSubmatch(mavenJavaVersion)),
+			"build.gradle":     gradleJavaVersion,
+			"build.gradle.kts": gradleJavaVersion,
+		}
+	case "Python":
+		order = []string{".python-version", "runtime.txt", "pyproject.toml"}
+		detectors = map[string]func(string) string{
+			".python-version": pythonVersion,
+			"runtime.txt":     pythonVersion,
+			"pyproject.toml":  firstSubmatch(requiresPython),
+		}
+	case "JavaScript":
+		order = []string{".nvmrc", ".node-version", "package.json"}
+		detectors = map[string]func(string) string{
+			".nvmrc":        nodeVersion,
+			".node-version": nodeVersion,
+			"package.json":  packageNodeVersion,
+		}
+	case "Rust":
+		order = []string{"rust-toolchain.toml", "rust-toolchain"}
+		detectors = map[string]func(string) string{
+			"rust-toolchain.toml": firstSubmatch(rustChannel),
+			"rust-toolchain":      rustToolchain,
+		}
+	default:
+		return ""
+	}
+
+	for _, name := range order {
+		for _, file := range files {
+			if strings.ToLower(filepath.Base(file)) != name {
+				continue
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			if version := detectors[name](string(content)); version != "" {
+				return version
+			}
+		}
+	}
+	return ""
+}
+
+// firstSubmatch 返回正则第一个分组的匹配结果
+func firstSubmatch(pattern *regexp.Regexp) func(string) string {
+	return func(content string) string {
+		if match := pattern.FindStringSubmatch(content); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+}
+
+// javaVersion 将 1.8 形式的旧版本号转换为 8
+func javaVersion(detect func(string) string) func(string) string {
+	return func(content string) string {
+		return strings.TrimPrefix(detect(content), "1.")
+	}
+}
+
+// gradleJavaVersion 从 Gradle 构建脚本中获取 Java 版本，优先使用 toolchain
+func gradleJavaVersion(content string) string {
+	if match := gradleToolchain.FindStringSubmatch(content); match != nil {
+		return match[1]
+	}
+	if match := gradleCompatibility.FindStringSubmatch(content); match != nil {
+		version := match[2]
+		if match[1] != "" {
+			version = strings.ReplaceAll(match[1], "_", ".")
+		}
+		return strings.TrimPrefix(version, "1.")
+	}
+	return ""
+}
+
+// pythonVersion 从 .python-version 或 runtime.txt（python-3.11.4）中获取主次版本号
+func pythonVersion(content string) string {
+	return majorMinor(versionNumber.FindString(firstLine(content)))
+}
+
+// nodeVersion 从 .nvmrc 或 .node-version 中获取主版本号，lts/* 等别名无法确定版本
+func nodeVersion(content string) string {
+	return major(versionNumber.FindString(strings.TrimPrefix(firstLine(content), "v")))
+}
+
+// packageNodeVersion 从 package.json 的 engines.node 中获取最低主版本号
+func packageNodeVersion(content string) string {
+	var pkg struct {
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return ""
+	}
+	return major(versionNumber.FindString(pkg.Engines.Node))
+}
+
+// rustToolchain 旧格式的 rust-toolchain 文件只包含 channel
+func rustToolchain(content string) string {
+	line := firstLine(content)
+	if strings.Contains(line, "[") {
+		return firstSubmatch(rustChannel)(content)
+	}
+	return line
+}
+
+// firstLine 返回第一个非空行
+func firstLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// majorMinor 截取版本号的主次版本，例如 3.11.4 → 3.11
+func majorMinor(version string) string {
+	parts := strings.Split(version, ".")
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, ".")
+}
+
+// major 截取版本号的主版本，例如 20.11.1 → 20
+func major(version string) string {
+	if index := strings.Index(version, "."); index >= 0 {
+		return version[:index]
+	}
+	return version
+}
@@ -72,6 +72,14 @@ func NewFileScanner() Scanner {
 			"build.gradle",
 			"Cargo.toml",
 			"setup.py",
+			"build.gradle.kts",
+			"pyproject.toml",
+			"runtime.txt",
+			"rust-toolchain",
+			"rust-toolchain.toml",
+			".nvmrc",
+			".node-version",
+			".python-version",
 		},
 	}
 }
@@ -8,12 +8,13 @@ import (
 
 // TechStack 技术栈信息
 type TechStack struct {
-	Language      string            `json:"language"`
-	Framework     string            `json:"framework"`
-	BuildTool     string            `json:"build_tool"`
-	TestFramework string            `json:"test_framework"`
-	Dependencies  map[string]string `json:"dependencies"`
-	Files         []string          `json:"files"`
+	Language        string            `json:"language"`
+	LanguageVersion string            `json:"language_version"`
+	Framework       string            `json:"framework"`
+	BuildTool       string            `json:"build_tool"`
+	TestFramework   string            `json:"test_framework"`
+	Dependencies    map[string]string `json:"dependencies"`
+	Files           []string          `json:"files"`
 }
 
 // Result 技术栈识别结果
@@ -72,6 +73,7 @@ func (r *recognizerImpl) Recognize(projectPath string) (*Result, error) {
 	result.TechStack.Framework = framework
 	result.TechStack.BuildTool = buildTool
 	result.TechStack.TestFramework = testFramework
+	result.TechStack.LanguageVersion = analyzer.DetectLanguageVersion(language, files)
 
 	// 3. 依赖分析
 	analyzer := analyzer.NewDependencyAnalyzer()
@@ -0,0 +1,141 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gitTimeout 计算变更文件时单个 git 命令的超时时间
+const gitTimeout = 30 * time.Second
+
+// ErrNoBaseCommit 事件没有可以比较的基准提交，例如新建分支的 push
+var ErrNoBaseCommit = errors.New("no base commit to compare with")
+
+// LoadChangedFiles 在项目目录的本地 git 仓库中计算事件变更的文件。push 比较推送前后的提交，
+// pull request 比较目标分支与源提交的合并基准，目标分支依次尝试本地分支和 origin 远程分支
+func (e *Event) LoadChangedFiles(dir string) error {
+	var bases []string
+	switch e.Type {
+	case EventPush:
+		if strings.Trim(e.Before, "0") != "" {
+			bases = []string{e.Before}
+		}
+	case EventPullRequest:
+		if e.BaseRef != "" {
+			bases = []string{e.BaseRef, "origin/" + e.BaseRef}
+		}
+	}
+	if len(bases) == 0 || e.SHA == "" {
+		return ErrNoBaseCommit
+	}
+
+	var lastErr error
+	for _, base := range bases {
+		files, err := ChangedFiles(dir, base, e.SHA)
+		if err == nil {
+			e.ChangedFiles = files
+			return nil
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// ChangedFiles 计算本地 git 仓库中 to 相对于 from 和 to 的合并基准变更的文件，重命名的文件同时包含新旧路径。
+// from 和 to 来自请求，必须是完整的提交 SHA 或合法的引用名称，解析为提交后再比较，避免被 git 当作选项
+func ChangedFiles(dir, from, to string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	fromSHA, err := resolveCommit(ctx, dir, from)
+	if err != nil {
+		return nil, err
+	}
+	toSHA, err := resolveCommit(ctx, dir, to)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "diff", "--name-only", "--no-renames", "-z", fromSHA+"..."+toSHA, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git diff %s...%s failed: %s", from, to, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	files := []string{}
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// resolveCommit 校验修订并解析为提交 SHA
+func resolveCommit(ctx context.Context, dir, revision string) (string, error) {
+	if !validRevision(revision) {
+		return "", fmt.Errorf("invalid git revision: %q", revision)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--verify", "--quiet", "--end-of-options", revision+"^{commit}")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("unknown git revision: %s", revision)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// validRevision 判断修订是否为 40 或 64 位十六进制的提交 SHA，或符合 git check-ref-format 规则的引用名称。
+// 以 - 开头的值会被 git 当作选项，一律拒绝
+func validRevision(revision string) bool {
+	if revision == "" || strings.HasPrefix(revision, "-") {
+		return false
+	}
+	if (len(revision) == 40 || len(revision) == 64) && isHex(revision) {
+		return true
+	}
+	return validRefName(revision)
+}
+
+// isHex 判断字符串是否只包含十六进制字符
+func isHex(value string) bool {
+	for _, c := range value {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// validRefName 按 git check-ref-format 的规则校验引用名称，允许只有一级的名称（例如 main）
+func validRefName(name string) bool {
+	if name == "@" || strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") || strings.HasPrefix(name, "/") {
+		return false
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, "//") || strings.Contains(name, "@{") {
+		return false
+	}
+	for _, c := range name {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(" ~^:?*[\\", c) {
+			return false
+		}
+	}
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package trigger
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ci-cd-orchestrator/internal/cicd/common"
+	"ci-cd-orchestrator/internal/cicd/model"
+)
+
+// 判断触发条件的事件类型，tag 为推送标签，其余与管道模型的触发事件相同
+const (
+	EventPush        = model.EventPush
+	EventTag         = "tag"
+	EventPullRequest = model.EventPullRequest
+	EventSchedule    = model.EventSchedule
+	EventManual      = model.EventManual
+)
+
+var (
+	// ErrUnsupportedEvent 事件类型不是 push、tag、pull_request、schedule 或 manual
+	ErrUnsupportedEvent = errors.New("unsupported event type")
+	// ErrNotTriggered 事件不满足管道配置的触发条件
+	ErrNotTriggered = errors.New("event does not match pipeline triggers")
+)
+
+// Event 判断触发条件使用的事件
+type Event struct {
+	Type         string   `json:"type"`               // push、tag、pull_request、schedule 或 manual
+	Ref          string   `json:"ref"`                // 完整的 ref，例如 refs/heads/main 或 refs/tags/v1.0.0，pull request 为源分支
+	BaseRef      string   `json:"base_ref,omitempty"` // pull request 的目标分支名称，分支过滤匹配该分支
+	Before       string   `json:"before,omitempty"`   // push 之前的提交，用于计算变更的文件
+	SHA          string   `json:"sha,omitempty"`      // 触发的提交
+	ChangedFiles []string `json:"changed_files"`      // 变更的文件，nil 表示未知，此时不检查路径过滤
+}
+
+// Decision 触发条件的判断结果
+type Decision struct {
+	Fires    bool            `json:"fires"`
+	Reason   string          `json:"reason"`
+	Warnings []model.Warning `json:"warnings,omitempty"` // 解析配置时无法转换的部分，可能影响判断结果
+}
+
+// EvaluateConfig 将指定平台的配置解析为管道模型后判断事件是否触发执行，解析警告随结果返回
+func EvaluateConfig(platform, content string, event Event) (*Decision, error) {
+	pipeline, warnings, err := model.Parse(common.Platform(ConfigPlatform(platform)), content)
+	if err != nil {
+		return nil, err
+	}
+	decision, err := Evaluate(pipeline, event)
+	if err != nil {
+		return nil, err
+	}
+	decision.Warnings = warnings
+	return decision, nil
+}
+
+// Evaluate 判断事件是否满足管道的触发条件，同类事件的任一触发器满足即触发。
+// 过滤规则与 GitHub Actions 相同：
+//   - branches 和 branches-ignore 匹配 push 的分支或 pull request 的目标分支
+//   - tags 和 tags-ignore 匹配推送的标签
+//   - 只设置了分支过滤时推送标签不触发，只设置了标签过滤时推送分支不触发
+//   - paths 和 paths-ignore 只对分支和 pull request 生效，至少一个变更的文件未被排除时触发
+func Evaluate(pipeline *model.Pipeline, event Event) (*Decision, error) {
+	modelEvent, ref, err := normalizeEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	for _, trigger := range pipeline.Triggers {
+		if trigger.Event != modelEvent {
+			continue
+		}
+		if reason := checkTrigger(trigger, event, ref); reason != "" {
+			reasons = append(reasons, reason)
+			continue
+		}
+		return &Decision{Fires: true, Reason: fmt.Sprintf("%s trigger matches", modelEvent)}, nil
+	}
+
+	if len(reasons) == 0 {
+		return &Decision{Reason: fmt.Sprintf("pipeline has no %s trigger", modelEvent)}, nil
+	}
+	return &Decision{Reason: strings.Join(reasons, "; ")}, nil
+}
+
+// normalizeEvent 返回事件对应的管道模型触发事件和完整的 ref，分支或标签名称会补全 refs/heads/ 或 refs/tags/ 前缀
+func normalizeEvent(event Event) (string, string, error) {
+	ref := event.Ref
+	switch event.Type {
+	case EventPush:
+		if !strings.HasPrefix(ref, "refs/") {
+			ref = "refs/heads/" + ref
+		}
+		return model.EventPush, ref, nil
+	case EventTag:
+		if !strings.HasPrefix(ref, "refs/") {
+			ref = "refs/tags/" + ref
+		}
+		return model.EventPush, ref, nil
+	case EventPullRequest:
+		return model.EventPullRequest, "refs/heads/" + event.BaseRef, nil
+	case EventSchedule, EventManual:
+		return event.Type, ref, nil
+	default:
+		return "", "", fmt.Errorf("%w: %q", ErrUnsupportedEvent, event.Type)
+	}
+}
+
+// checkTrigger 检查单个触发器的过滤条件，满足时返回空字符串，否则返回不触发的原因
+func checkTrigger(trigger model.Trigger, event Event, ref string) string {
+	if trigger.Event != model.EventPush && trigger.Event != model.EventPullRequest {
+		return ""
+	}
+
+	branchFiltered := len(trigger.Branches) > 0 || len(trigger.BranchesIgnore) > 0
+	tagFiltered := len(trigger.Tags) > 0 || len(trigger.TagsIgnore) > 0
+	if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+		switch {
+		case len(trigger.Tags) > 0 && !matchFilter(trigger.Tags, tag):
+			return fmt.Sprintf("tag %s does not match tags filter", tag)
+		case len(trigger.TagsIgnore) > 0 && matchFilter(trigger.TagsIgnore, tag):
+			return fmt.Sprintf("tag %s matches tags-ignore filter", tag)
+		case !tagFiltered && branchFiltered:
+			return fmt.Sprintf("tag %s does not trigger because only branch filters are set", tag)
+		}
+		// 推送标签时不检查路径过滤
+		return ""
+	}
+
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok {
+		return fmt.Sprintf("unsupported ref %s", ref)
+	}
+	switch {
+	case len(trigger.Branches) > 0 && !matchFilter(trigger.Branches, branch):
+		return fmt.Sprintf("branch %s does not match branches filter", branch)
+	case len(trigger.BranchesIgnore) > 0 && matchFilter(trigger.BranchesIgnore, branch):
+		return fmt.Sprintf("branch %s matches branches-ignore filter", branch)
+	case !branchFiltered && tagFiltered && trigger.Event == model.EventPush:
+		return fmt.Sprintf("branch %s does not trigger because only tag filters are set", branch)
+	}
+
+	return checkPaths(trigger, event.ChangedFiles)
+}
+
+// checkPaths 检查路径过滤，变更的文件未知时视为满足
+func checkPaths(trigger model.Trigger, files []string) string {
+	if files == nil || (len(trigger.Paths) == 0 && len(trigger.PathsIgnore) == 0) {
+		return ""
+	}
+	for _, file := range files {
+		if len(trigger.Paths) > 0 && !matchFilter(trigger.Paths, file) {
+			continue
+		}
+		if len(trigger.PathsIgnore) > 0 && matchFilter(trigger.PathsIgnore, file) {
+			continue
+		}
+		return ""
+	}
+	if len(trigger.Paths) > 0 {
+		return "no changed file matches paths filter"
+	}
+	return "all changed files match paths-ignore filter"
+}
+
+// matchFilter 按顺序匹配过滤模式，以 ! 开头的模式排除之前匹配的值，最后一个匹配的模式决定结果
+func matchFilter(patterns []string, value string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if MatchPattern(strings.TrimPrefix(pattern, "!"), value) {
+			matched = !negated
+		}
+	}
+	return matched
+}
+
+// MatchPattern 用 GitHub Actions 的过滤模式匹配分支、标签或文件路径，模式必须匹配整个值：
+//   - * 匹配除 / 以外的任意字符，** 匹配包括 / 在内的任意字符，**/ 可以匹配零层目录
+//   - ? 匹配零个或一个前一字符，+ 匹配一个或多个前一字符
+//   - [] 匹配列出的一个字符或范围，例如 [0-9]
+//   - \ 转义后一个特殊字符
+//
+// 无效的模式不匹配任何值
+func MatchPattern(pattern, value string) bool {
+	expression, err := compilePattern(pattern)
+	if err != nil {
+		return false
+	}
+	return expression.MatchString(value)
+}
+
+// compilePattern 将过滤模式转换为正则表达式
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	chars := []rune(pattern)
+	var builder strings.Builder
+	builder.WriteString("^")
+
+	// quantifiable 表示前一个元素是字符或字符组，可以被 ? 和 + 修饰
+	quantifiable := false
+	for i := 0; i < len(chars); i++ {
+		switch char := chars[i]; char {
+		case '*':
+			if i+1 < len(chars) && chars[i+1] == '*' {
+				i++
+				if i+1 < len(chars) && chars[i+1] == '/' {
+					i++
+					builder.WriteString("(?:.*/)?")
+				} else {
+					builder.WriteString(".*")
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+			quantifiable = false
+		case '?', '+':
+			if quantifiable {
+				builder.WriteRune(char)
+			} else {
+				builder.WriteString(regexp.QuoteMeta(string(char)))
+			}
+			quantifiable = false
+		case '[':
+			end := strings.IndexRune(string(chars[i+1:]), ']')
+			if end <= 0 {
+				builder.WriteString(`\[`)
+				quantifiable = true
+				continue
+			}
+			class := []rune(string(chars[i+1:])[:end])
+			builder.WriteString("[" + strings.ReplaceAll(string(class), `\`, `\\`) + "]")
+			i += len(class) + 1
+			quantifiable = true
+		case '\\':
+			if i+1 < len(chars) {
+				i++
+			}
+			builder.WriteString(regexp.QuoteMeta(string(chars[i])))
+			quantifiable = true
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+			quantifiable = true
+		}
+	}
+
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
@@ -0,0 +1,242 @@
+package trigger
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ci-cd-orchestrator/internal/cicd/model"
+)
+
+func TestMatchPattern(t *testing.T) {
+	// GitHub Actions 过滤模式速查表中的示例
+	tests := []struct {
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"feature/*", []string{"feature/my-branch"}, []string{"feature/a/b", "feature"}},
+		{"feature/**", []string{"feature/beta-a/my-branch", "feature/x"}, []string{"features/x"}},
+		{"*", []string{"main", "releases"}, []string{"releases/v1"}},
+		{"**", []string{"all/the/branches", "main"}, nil},
+		{"v2*", []string{"v2", "v2.0", "v2.9"}, []string{"v1.0"}},
+		{"v[12].[0-9]+.[0-9]+", []string{"v1.10.1", "v2.0.0"}, []string{"v3.0.0", "v1.x.1"}},
+		{"*.jsx?", []string{"page.js", "page.jsx"}, []string{"page.jsxx", "src/page.js"}},
+		{"*.js", []string{"app.js", "index.js"}, []string{"js/index.js"}},
+		{"**.js", []string{"index.js", "js/index.js", "src/js/app.js"}, []string{"app.ts"}},
+		{"docs/*", []string{"docs/README.md", "docs/file.txt"}, []string{"docs/mona/octocat.txt"}},
+		{"docs/**", []string{"docs/README.md", "docs/mona/octocat.txt"}, []string{"README.md"}},
+		{"docs/**/*.md", []string{"docs/README.md", "docs/mona/hello-world.md", "docs/a/markdown/guide.md"}, []string{"docs/file.txt"}},
+		{"**/docs/**", []string{"docs/hello.md", "dir/docs/my-file.txt", "space/docs/plan/space.doc"}, []string{"mydocs/a.md"}},
+		{"**/README.md", []string{"README.md", "js/README.md"}, []string{"README.mdx"}},
+		{"**/*src/**", []string{"a/src/app.js", "my-src/code/js/app.js"}, []string{"src.js"}},
+		{"**/*-post.md", []string{"my-post.md", "path/their-post.md"}, []string{"post.md"}},
+		{"**/migrate-*.sql", []string{"migrate-10909.sql", "db/migrate-v1.0.sql", "db/sept/migrate-v1.sql"}, []string{"db/migrate.sql"}},
+		{`releases/\*`, []string{"releases/*"}, []string{"releases/v1"}},
+	}
+
+	for _, test := range tests {
+		for _, value := range test.matches {
+			if !MatchPattern(test.pattern, value) {
+				t.Errorf("%s 应匹配 %s", test.pattern, value)
+			}
+		}
+		for _, value := range test.misses {
+			if MatchPattern(test.pattern, value) {
+				t.Errorf("%s 不应匹配 %s", test.pattern, value)
+			}
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	pipeline, _, err := model.ParseGitHubActions(`
+on:
+  push:
+    branches: [main, 'releases/**', '!releases/**-alpha']
+    tags: ['v*']
+    paths: ['**.go', '!docs/**']
+  pull_request:
+    branches-ignore: ['experimental/**']
+    paths-ignore: ['**.md']
+  workflow_dispatch:
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: go build ./...
+`)
+	if err != nil {
+		t.Fatalf("解析 workflow 失败: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event Event
+		fires bool
+	}{
+		{"匹配的分支", Event{Type: EventPush, Ref: "refs/heads/main"}, true},
+		{"分支名称自动补全前缀", Event{Type: EventPush, Ref: "releases/v1"}, true},
+		{"否定模式排除的分支", Event{Type: EventPush, Ref: "refs/heads/releases/v1-alpha"}, false},
+		{"不匹配的分支", Event{Type: EventPush, Ref: "refs/heads/dev"}, false},
+		{"匹配的标签不检查路径", Event{Type: EventTag, Ref: "refs/tags/v1.0.0", ChangedFiles: []string{"README.md"}}, true},
+		{"push 事件中的标签", Event{Type: EventPush, Ref: "refs/tags/v2"}, true},
+		{"不匹配的标签", Event{Type: EventTag, Ref: "release-1"}, false},
+		{"变更了匹配路径的文件", Event{Type: EventPush, Ref: "main", ChangedFiles: []string{"README.md", "cmd/main.go"}}, true},
+		{"只变更了排除路径的文件", Event{Type: EventPush, Ref: "main", ChangedFiles: []string{"docs/example.go"}}, false},
+		{"没有变更文件", Event{Type: EventPush, Ref: "main", ChangedFiles: []string{}}, false},
+		{"pull request 目标分支", Event{Type: EventPullRequest, Ref: "refs/heads/experimental/x", BaseRef: "main"}, true},
+		{"pull request 忽略的目标分支", Event{Type: EventPullRequest, BaseRef: "experimental/x"}, false},
+		{"pull request 只变更了忽略的文件", Event{Type: EventPullRequest, BaseRef: "main", ChangedFiles: []string{"README.md", "docs/a.md"}}, false},
+		{"手动触发", Event{Type: EventManual}, true},
+		{"没有定时触发器", Event{Type: EventSchedule}, false},
+	}
+
+	for _, test := range tests {
+		decision, err := Evaluate(pipeline, test.event)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if decision.Fires != test.fires {
+			t.Errorf("%s: 触发结果为 %v，期望 %v（%s）", test.name, decision.Fires, test.fires, decision.Reason)
+		}
+	}
+
+	if _, err := Evaluate(pipeline, Event{Type: "release"}); err == nil {
+		t.Error("不支持的事件类型应返回错误")
+	}
+}
+
+func TestEvaluateRefFilters(t *testing.T) {
+	tests := []struct {
+		trigger model.Trigger
+		ref     string
+		fires   bool
+	}{
+		// 只设置分支过滤时推送标签不触发，只设置标签过滤时推送分支不触发
+		{model.Trigger{Event: model.EventPush, Branches: []string{"**"}}, "refs/tags/v1", false},
+		{model.Trigger{Event: model.EventPush, BranchesIgnore: []string{"dev"}}, "refs/tags/v1", false},
+		{model.Trigger{Event: model.EventPush, Tags: []string{"*"}}, "refs/heads/main", false},
+		{model.Trigger{Event: model.EventPush, TagsIgnore: []string{"v*"}}, "refs/heads/main", false},
+		// tags-ignore 排除匹配的标签
+		{model.Trigger{Event: model.EventPush, TagsIgnore: []string{"v*-rc*"}}, "refs/tags/v1-rc1", false},
+		{model.Trigger{Event: model.EventPush, TagsIgnore: []string{"v*-rc*"}}, "refs/tags/v1", true},
+		{model.Trigger{Event: model.EventPush, Branches: []string{"main"}, TagsIgnore: []string{"v*"}}, "refs/heads/main", true},
+		{model.Trigger{Event: model.EventPush, Branches: []string{"main"}, TagsIgnore: []string{"v*"}}, "refs/tags/release", true},
+		// 没有分支和标签过滤时都触发
+		{model.Trigger{Event: model.EventPush}, "refs/tags/v1", true},
+		{model.Trigger{Event: model.EventPush, Paths: []string{"src/**"}}, "refs/heads/main", true},
+	}
+
+	for _, test := range tests {
+		pipeline := &model.Pipeline{Triggers: []model.Trigger{test.trigger}}
+		decision, err := Evaluate(pipeline, Event{Type: EventPush, Ref: test.ref})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision.Fires != test.fires {
+			t.Errorf("%+v %s: 触发结果为 %v，期望 %v（%s）", test.trigger, test.ref, decision.Fires, test.fires, decision.Reason)
+		}
+	}
+}
+
+func TestEvaluateConfigWarnings(t *testing.T) {
+	config := `
+on:
+  push:
+    tags-ignore: ['v*-rc*']
+  pull_request:
+    types: [opened]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: go build ./...
+`
+	decision, err := EvaluateConfig("github_actions", config, Event{Type: EventPush, Ref: "refs/tags/v1-rc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Fires {
+		t.Errorf("tags-ignore 排除的标签不应触发: %s", decision.Reason)
+	}
+	if len(decision.Warnings) == 0 {
+		t.Error("解析配置的警告应随判断结果返回")
+	}
+}
+
+func TestLoadChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git 不可用")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git %v 失败: %v", args, err)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write("README.md", "hello")
+	write("src/old.go", "package src")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	before := git("rev-parse", "HEAD")
+
+	git("checkout", "-q", "-b", "feature")
+	write("src/app.go", "package src")
+	git("mv", "src/old.go", "src/new.go")
+	git("add", "-A")
+	git("commit", "-q", "-m", "change")
+	after := git("rev-parse", "HEAD")
+
+	expected := []string{"src/app.go", "src/new.go", "src/old.go"}
+	push := Event{Type: EventPush, Before: before, SHA: after}
+	if err := push.LoadChangedFiles(dir); err != nil || !reflect.DeepEqual(push.ChangedFiles, expected) {
+		t.Errorf("push 的变更文件为 %v，期望 %v: %v", push.ChangedFiles, expected, err)
+	}
+
+	pr := Event{Type: EventPullRequest, BaseRef: "main", SHA: after}
+	if err := pr.LoadChangedFiles(dir); err != nil || !reflect.DeepEqual(pr.ChangedFiles, expected) {
+		t.Errorf("pull request 的变更文件为 %v，期望 %v: %v", pr.ChangedFiles, expected, err)
+	}
+
+	created := Event{Type: EventPush, Before: "0000000000000000000000000000000000000000", SHA: after}
+	if err := created.LoadChangedFiles(dir); err != ErrNoBaseCommit || created.ChangedFiles != nil {
+		t.Errorf("新建分支应无法计算变更文件: %v", err)
+	}
+
+	// 请求中的修订不能被 git 当作选项
+	output := filepath.Join(t.TempDir(), "output")
+	for _, revision := range []string{"--output=" + output, "-p", "main..feature", "HEAD~1", "main:README.md", ""} {
+		if _, err := ChangedFiles(dir, revision, after); err == nil {
+			t.Errorf("无效的修订应返回错误: %q", revision)
+		}
+		if _, err := ChangedFiles(dir, before, revision); err == nil {
+			t.Errorf("无效的修订应返回错误: %q", revision)
+		}
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("修订不应作为 git 选项写入文件: %v", err)
+	}
+	if _, err := ChangedFiles(dir, "missing", after); err == nil {
+		t.Error("不存在的引用应返回错误")
+	}
+	if files, err := ChangedFiles(dir, "refs/heads/main", "feature"); err != nil || !reflect.DeepEqual(files, expected) {
+		t.Errorf("引用名称的变更文件为 %v，期望 %v: %v", files, expected, err)
+	}
+}
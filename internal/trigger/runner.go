@@ -0,0 +1,249 @@
+// Package trigger 在 webhook 事件、定时任务等触发来源到达时确定执行使用的 CI 配置并启动执行
+package trigger
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ci-cd-orchestrator/internal/execution"
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+)
+
+// 执行使用的 CI 配置来源
+const (
+	ConfigSourceRevision   = "revision"   // 请求指定的管道配置修订
+	ConfigSourcePipeline   = "pipeline"   // 已保存的管道配置的当前修订
+	ConfigSourceFile       = "file"       // 项目目录中的配置文件
+	ConfigSourceDefault    = "default"    // 没有找到配置，模拟执行使用默认阶段
+	ConfigSourceRepository = "repository" // 远程平台运行仓库中的 workflow
+)
+
+var (
+	// ErrEmptyProjectPath 本地执行的项目没有设置路径
+	ErrEmptyProjectPath = errors.New("project path is empty")
+	// ErrNoCIConfig 本地执行既没有已保存的管道配置，项目目录中也没有 CI 配置文件
+	ErrNoCIConfig = errors.New("no stored pipeline or CI config file found")
+)
+
+// Config 执行使用的 CI 配置及其对应的已保存管道配置修订
+type Config struct {
+	Content  string
+	Source   string
+	Pipeline *models.Pipeline // 配置不是来自已保存的管道配置时为 nil
+	Revision int
+}
+
+// PipelineID 配置对应的已保存管道配置 ID，没有时为 0
+func (c *Config) PipelineID() int {
+	if c.Pipeline == nil {
+		return 0
+	}
+	return c.Pipeline.ID
+}
+
+// ConfigPlatform 执行平台使用的已保存配置的平台，本地执行运行 GitHub Actions 格式的 workflow
+func ConfigPlatform(platform string) string {
+	if platform == "local" {
+		return "github_actions"
+	}
+	return platform
+}
+
+// configPlatforms 执行平台可以使用的已保存配置的平台，按优先顺序排列。模拟执行优先使用 mock 平台的配置，
+// 没有时模拟 GitHub Actions workflow，例如定时任务在 mock 平台上模拟执行 GitHub Actions 的 on.schedule
+func configPlatforms(platform string) []string {
+	if platform == "mock" {
+		return []string{"mock", "github_actions"}
+	}
+	return []string{ConfigPlatform(platform)}
+}
+
+// IsRemote 判断执行平台是否运行代码托管平台仓库中的 workflow
+func IsRemote(platform string) bool {
+	return platform == "github_actions" || platform == "gitlab_ci"
+}
+
+// findPipeline 按 configPlatforms 的顺序获取项目已保存的管道配置，不存在时返回 nil
+func findPipeline(pipelineRepo *repository.PipelineRepository, projectID int, platform string) (*models.Pipeline, error) {
+	for _, configPlatform := range configPlatforms(platform) {
+		pipeline, err := pipelineRepo.GetByProjectAndPlatform(projectID, configPlatform)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		return pipeline, err
+	}
+	return nil, nil
+}
+
+// ResolveConfig 确定执行使用的 CI 配置，依次为指定的修订、已保存的管道配置和项目目录中的配置文件。
+// 远程平台运行仓库中的 workflow，只记录该平台已保存配置的当前修订。
+// 指定的修订或其管道配置不存在时返回 sql.ErrNoRows
+func ResolveConfig(pipelineRepo *repository.PipelineRepository, project *models.Project, platform string, revision int) (*Config, error) {
+	pipeline, err := findPipeline(pipelineRepo, project.ID, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	if platform != "local" && platform != "mock" {
+		config := &Config{Source: ConfigSourceRepository, Pipeline: pipeline}
+		if pipeline != nil {
+			config.Revision = pipeline.Revision
+		}
+		return config, nil
+	}
+
+	if revision != 0 {
+		if pipeline == nil {
+			return nil, sql.ErrNoRows
+		}
+		target, err := pipelineRepo.GetRevision(pipeline.ID, revision)
+		if err != nil {
+			return nil, err
+		}
+		return &Config{Content: target.Config, Source: ConfigSourceRevision, Pipeline: pipeline, Revision: target.Revision}, nil
+	}
+
+	if pipeline != nil {
+		return &Config{Content: pipeline.Config, Source: ConfigSourcePipeline, Pipeline: pipeline, Revision: pipeline.Revision}, nil
+	}
+
+	if project.Path != "" {
+		if content, err := execution.LoadLocalCIConfig(project.Path); err == nil {
+			return &Config{Content: content, Source: ConfigSourceFile}, nil
+		}
+	}
+	return &Config{Source: ConfigSourceDefault}, nil
+}
+
+// Request 触发执行的请求
+type Request struct {
+	Platform string                 // 执行平台
+	Type     string                 // 触发类型，例如 push、pull_request、tag 或 schedule
+	Info     map[string]interface{} // 记录到执行的触发信息
+	Ref      string                 // 完整的 ref，远程平台在该分支或标签上运行 workflow
+	Event    *Event                 // 判断触发条件的事件，为 nil 时不判断
+	// 使用的已保存管道配置，例如来自管道配置的定时任务在 mock 或 local 平台上运行该配置；为 0 时按执行平台查找
+	PipelineID int
+}
+
+// Runner 在项目上启动被触发的执行
+type Runner struct {
+	manager      execution.Manager
+	pipelineRepo *repository.PipelineRepository
+}
+
+// NewRunner 创建触发执行器实例
+func NewRunner(manager execution.Manager, pipelineRepo *repository.PipelineRepository) *Runner {
+	return &Runner{
+		manager:      manager,
+		pipelineRepo: pipelineRepo,
+	}
+}
+
+// Start 按 ResolveConfig 的顺序确定 CI 配置，在项目上创建并启动执行，返回执行 ID。
+// 请求带有事件时先判断配置的触发条件，不满足时返回 ErrNotTriggered
+func (r *Runner) Start(project *models.Project, req Request) (string, error) {
+	options := execution.ExecutionOptions{
+		GenerateMetrics: true,
+		GenerateLogs:    true,
+		TriggerInfo:     req.Info,
+	}
+
+	// 本地执行需要项目路径作为工作目录，远程平台需要项目的仓库地址
+	switch {
+	case req.Platform == "local":
+		if project.Path == "" {
+			return "", ErrEmptyProjectPath
+		}
+		options.WorkDir = project.Path
+	case IsRemote(req.Platform):
+		repositoryPath, err := execution.RepositoryPath(project.RepositoryURL)
+		if err != nil {
+			return "", err
+		}
+		options.Repository = repositoryPath
+		options.Ref = shortRef(req.Ref)
+	}
+
+	config, err := r.resolveConfig(project, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve CI config: %w", err)
+	}
+	if req.Platform == "local" && config.Content == "" {
+		return "", ErrNoCIConfig
+	}
+	if req.Event != nil {
+		if err := checkTriggers(project, req, config); err != nil {
+			return "", err
+		}
+	}
+	options.CIConfigContent = config.Content
+	options.PipelineID = config.PipelineID()
+	options.PipelineRevision = config.Revision
+
+	executionID, err := r.manager.CreateExecution(strconv.Itoa(project.ID), req.Platform, req.Type, options)
+	if err != nil {
+		return "", err
+	}
+	if err := r.manager.StartExecution(executionID); err != nil {
+		return executionID, err
+	}
+	return executionID, nil
+}
+
+// resolveConfig 确定请求使用的 CI 配置，请求指定了管道配置且在 mock 或 local 平台上运行时使用该配置的当前修订，
+// 否则按 ResolveConfig 的顺序确定
+func (r *Runner) resolveConfig(project *models.Project, req Request) (*Config, error) {
+	if req.PipelineID == 0 || IsRemote(req.Platform) {
+		return ResolveConfig(r.pipelineRepo, project, req.Platform, 0)
+	}
+
+	pipeline, err := r.pipelineRepo.GetByID(req.PipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.ProjectID != project.ID {
+		return nil, fmt.Errorf("pipeline %d does not belong to project %d", pipeline.ID, project.ID)
+	}
+	return &Config{Content: pipeline.Config, Source: ConfigSourcePipeline, Pipeline: pipeline, Revision: pipeline.Revision}, nil
+}
+
+// checkTriggers 判断事件是否满足 CI 配置的触发条件，没有可以判断的配置时视为满足。
+// 事件没有变更的文件时在项目目录中计算，无法计算时不检查路径过滤
+func checkTriggers(project *models.Project, req Request, config *Config) error {
+	content := config.Content
+	if content == "" && config.Pipeline != nil {
+		content = config.Pipeline.Config
+	}
+	if content == "" {
+		return nil
+	}
+
+	event := *req.Event
+	if event.ChangedFiles == nil && project.Path != "" {
+		event.LoadChangedFiles(project.Path)
+	}
+	decision, err := EvaluateConfig(req.Platform, content, event)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate triggers: %w", err)
+	}
+	if !decision.Fires {
+		return fmt.Errorf("%w: %s", ErrNotTriggered, decision.Reason)
+	}
+	return nil
+}
+
+// shortRef 去掉 ref 的 refs/heads/ 或 refs/tags/ 前缀
+func shortRef(ref string) string {
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		return branch
+	}
+	if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+		return tag
+	}
+	return ref
+}
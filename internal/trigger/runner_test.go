@@ -0,0 +1,67 @@
+package trigger
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"ci-cd-orchestrator/internal/models"
+	"ci-cd-orchestrator/internal/repository"
+	"ci-cd-orchestrator/pkg/migration"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB 创建迁移后的临时数据库
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "cicd.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migration.Run(db); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+	return db
+}
+
+func TestResolveConfigMockUsesGitHubPipeline(t *testing.T) {
+	db := openTestDB(t)
+	projectRepo := repository.NewProjectRepository(db)
+	pipelineRepo := repository.NewPipelineRepository(db)
+
+	project := &models.Project{Name: "app", Path: t.TempDir()}
+	if err := projectRepo.Create(project); err != nil {
+		t.Fatalf("创建项目失败: %v", err)
+	}
+	github := &models.Pipeline{ProjectID: project.ID, Platform: "github_actions", Config: "jobs:\n  build:\n    steps:\n    - run: echo github\n"}
+	if err := pipelineRepo.Create(github, repository.PipelineChange{Source: repository.PipelineSourceManual}); err != nil {
+		t.Fatalf("创建管道配置失败: %v", err)
+	}
+
+	// 只有 GitHub Actions 配置时，模拟执行和本地执行都使用该配置
+	for _, platform := range []string{"mock", "local"} {
+		config, err := ResolveConfig(pipelineRepo, project, platform, 0)
+		if err != nil {
+			t.Fatalf("%s: 确定 CI 配置失败: %v", platform, err)
+		}
+		if config.Source != ConfigSourcePipeline || config.PipelineID() != github.ID {
+			t.Errorf("%s: 应使用 GitHub Actions 管道配置: %s %d", platform, config.Source, config.PipelineID())
+		}
+	}
+
+	// 有 mock 配置时模拟执行优先使用 mock 配置
+	mock := &models.Pipeline{ProjectID: project.ID, Platform: "mock", Config: "jobs:\n  build:\n    steps:\n    - run: echo mock\n"}
+	if err := pipelineRepo.Create(mock, repository.PipelineChange{Source: repository.PipelineSourceManual}); err != nil {
+		t.Fatalf("创建管道配置失败: %v", err)
+	}
+	config, err := ResolveConfig(pipelineRepo, project, "mock", 0)
+	if err != nil {
+		t.Fatalf("确定 CI 配置失败: %v", err)
+	}
+	if config.PipelineID() != mock.ID {
+		t.Errorf("模拟执行应优先使用 mock 配置: %d", config.PipelineID())
+	}
+}
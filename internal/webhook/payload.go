@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// githubRepository GitHub 和 Gitea 负载中的仓库
+type githubRepository struct {
+	FullName string `json:"full_name"`
+	CloneURL string `json:"clone_url"`
+	HTMLURL  string `json:"html_url"`
+	SSHURL   string `json:"ssh_url"`
+	GitURL   string `json:"git_url"`
+}
+
+// urls 仓库的各种地址
+func (r githubRepository) urls() []string {
+	return nonEmpty(r.CloneURL, r.HTMLURL, r.SSHURL, r.GitURL)
+}
+
+// githubUser GitHub 和 Gitea 负载中的用户，Gitea 的 pusher 使用 username
+type githubUser struct {
+	Login    string `json:"login"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// name 用户名
+func (u githubUser) name() string {
+	for _, name := range []string{u.Login, u.Username, u.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// githubPush GitHub 和 Gitea 的 push 事件负载
+type githubPush struct {
+	Ref        string           `json:"ref"`
+	Before     string           `json:"before"`
+	After      string           `json:"after"`
+	Deleted    bool             `json:"deleted"`
+	Repository githubRepository `json:"repository"`
+	Pusher     githubUser       `json:"pusher"`
+	Sender     githubUser       `json:"sender"`
+}
+
+// githubPullRequest GitHub 和 Gitea 的 pull_request 事件负载
+type githubPullRequest struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		User githubUser `json:"user"`
+	} `json:"pull_request"`
+	Repository githubRepository `json:"repository"`
+}
+
+// parseGitHub 解析 GitHub 事件，pull request 只有 opened、synchronize 和 reopened 触发执行
+func parseGitHub(event string, body []byte) (*Event, error) {
+	return parseGitHubStyle(event, body, "opened", "synchronize", "reopened")
+}
+
+// parseGitea 解析 Gitea 事件，负载格式与 GitHub 相同，但 pull request 推送新提交的动作为 synchronized
+func parseGitea(event string, body []byte) (*Event, error) {
+	return parseGitHubStyle(event, body, "opened", "synchronized", "reopened")
+}
+
+// parseGitHubStyle 解析 GitHub 格式的 push 和 pull_request 事件，actions 为触发执行的 pull request 动作
+func parseGitHubStyle(event string, body []byte, actions ...string) (*Event, error) {
+	switch event {
+	case "push":
+		var payload githubPush
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid push payload: %w", err)
+		}
+		if payload.Deleted {
+			return nil, ErrIgnoredEvent
+		}
+		parsed, err := refEvent(payload.Ref, payload.After)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Before = payload.Before
+		parsed.Author = payload.Pusher.name()
+		if parsed.Author == "" {
+			parsed.Author = payload.Sender.name()
+		}
+		parsed.Repository = payload.Repository.FullName
+		parsed.RepositoryURLs = payload.Repository.urls()
+		return parsed, nil
+
+	case "pull_request":
+		var payload githubPullRequest
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid pull_request payload: %w", err)
+		}
+		if !contains(actions, payload.Action) {
+			return nil, ErrIgnoredEvent
+		}
+		return &Event{
+			Type:           EventPullRequest,
+			Action:         payload.Action,
+			Ref:            "refs/heads/" + payload.PullRequest.Head.Ref,
+			BaseRef:        payload.PullRequest.Base.Ref,
+			SHA:            payload.PullRequest.Head.SHA,
+			Author:         payload.PullRequest.User.name(),
+			PRNumber:       payload.Number,
+			Repository:     payload.Repository.FullName,
+			RepositoryURLs: payload.Repository.urls(),
+		}, nil
+	}
+
+	// ping 等其他事件
+	return nil, ErrIgnoredEvent
+}
+
+// gitlabProject GitLab 负载中的项目
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	GitHTTPURL        string `json:"git_http_url"`
+	GitSSHURL         string `json:"git_ssh_url"`
+}
+
+// urls 项目的各种地址
+func (p gitlabProject) urls() []string {
+	return nonEmpty(p.GitHTTPURL, p.WebURL, p.GitSSHURL)
+}
+
+// gitlabPush GitLab 的 push 和 tag_push 事件负载
+type gitlabPush struct {
+	Ref          string        `json:"ref"`
+	Before       string        `json:"before"`
+	After        string        `json:"after"`
+	UserUsername string        `json:"user_username"`
+	Project      gitlabProject `json:"project"`
+}
+
+// gitlabMergeRequest GitLab 的 merge_request 事件负载
+type gitlabMergeRequest struct {
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project          gitlabProject `json:"project"`
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Action       string `json:"action"`
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		OldRev       string `json:"oldrev"`
+		LastCommit   struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+// parseGitLab 解析 GitLab 事件。merge request 只有 open、reopen 和推送了新提交的 update 触发执行
+func parseGitLab(event string, body []byte) (*Event, error) {
+	switch event {
+	case "Push Hook", "Tag Push Hook":
+		var payload gitlabPush
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid push payload: %w", err)
+		}
+		parsed, err := refEvent(payload.Ref, payload.After)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Before = payload.Before
+		parsed.Author = payload.UserUsername
+		parsed.Repository = payload.Project.PathWithNamespace
+		parsed.RepositoryURLs = payload.Project.urls()
+		return parsed, nil
+
+	case "Merge Request Hook":
+		var payload gitlabMergeRequest
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid merge request payload: %w", err)
+		}
+		attributes := payload.ObjectAttributes
+		switch {
+		case attributes.Action == "open" || attributes.Action == "reopen":
+		case attributes.Action == "update" && attributes.OldRev != "":
+		default:
+			return nil, ErrIgnoredEvent
+		}
+		return &Event{
+			Type:           EventPullRequest,
+			Action:         attributes.Action,
+			Ref:            "refs/heads/" + attributes.SourceBranch,
+			BaseRef:        attributes.TargetBranch,
+			SHA:            attributes.LastCommit.ID,
+			Author:         payload.User.Username,
+			PRNumber:       attributes.IID,
+			Repository:     payload.Project.PathWithNamespace,
+			RepositoryURLs: payload.Project.urls(),
+		}, nil
+	}
+
+	return nil, ErrIgnoredEvent
+}
+
+// contains 判断切片中是否包含指定的值
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
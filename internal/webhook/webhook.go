@@ -0,0 +1,224 @@
+// Package webhook 验证并解析 GitHub、GitLab 和 Gitea 的 webhook 请求。
+//
+// 每个代码托管平台的签名方式、事件请求头和负载格式不同，解析后统一为 Event，
+// 只保留触发执行需要的 push、pull request 和 tag 事件
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 代码托管平台
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea"
+)
+
+// 事件类型，与执行的触发类型相同
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventTag         = "tag"
+)
+
+var (
+	// ErrInvalidSignature 签名或令牌与配置的密钥不匹配
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrIgnoredEvent 事件不会触发执行，例如 ping、删除分支或关闭 pull request
+	ErrIgnoredEvent = errors.New("event does not trigger executions")
+)
+
+// Event 解析后的 webhook 事件
+type Event struct {
+	Provider       string   `json:"provider"`
+	Type           string   `json:"type"`                // push、pull_request 或 tag
+	Action         string   `json:"action,omitempty"`    // pull request 的动作，例如 opened
+	Ref            string   `json:"ref"`                 // 完整的 ref，pull request 为源分支，例如 refs/heads/feature
+	BaseRef        string   `json:"base_ref,omitempty"`  // pull request 的目标分支名称
+	Before         string   `json:"before,omitempty"`    // push 之前的提交，新建分支时全为 0
+	SHA            string   `json:"sha"`                 // 触发的提交
+	Author         string   `json:"author"`              // 推送者或 pull request 的作者
+	PRNumber       int      `json:"pr_number,omitempty"` // pull request 编号，GitLab 为 merge request 的 iid
+	Repository     string   `json:"repository"`          // 仓库路径，例如 owner/repo
+	RepositoryURLs []string `json:"repository_urls"`     // 负载中仓库的各种地址，用于匹配项目
+}
+
+// TriggerInfo 记录到执行的触发信息
+func (e *Event) TriggerInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"provider":   e.Provider,
+		"ref":        e.Ref,
+		"sha":        e.SHA,
+		"author":     e.Author,
+		"repository": e.Repository,
+	}
+	if e.Type == EventPullRequest {
+		info["pr_number"] = e.PRNumber
+		info["action"] = e.Action
+		info["base_ref"] = e.BaseRef
+	}
+	return info
+}
+
+// Provider 代码托管平台的 webhook 格式
+type Provider struct {
+	Name            string
+	EventHeader     string // 事件名称所在的请求头
+	DeliveryHeader  string // 投递 ID 所在的请求头
+	SignatureHeader string // 签名或令牌所在的请求头
+	verify          func(secret, signature string, body []byte) bool
+	parse           func(event string, body []byte) (*Event, error)
+}
+
+// providers 支持的代码托管平台
+var providers = map[string]*Provider{
+	ProviderGitHub: {
+		Name:            ProviderGitHub,
+		EventHeader:     "X-GitHub-Event",
+		DeliveryHeader:  "X-GitHub-Delivery",
+		SignatureHeader: "X-Hub-Signature-256",
+		verify: func(secret, signature string, body []byte) bool {
+			hexSignature, ok := strings.CutPrefix(signature, "sha256=")
+			return ok && verifyHMAC(secret, hexSignature, body)
+		},
+		parse: parseGitHub,
+	},
+	ProviderGitea: {
+		Name:            ProviderGitea,
+		EventHeader:     "X-Gitea-Event",
+		DeliveryHeader:  "X-Gitea-Delivery",
+		SignatureHeader: "X-Gitea-Signature",
+		verify:          verifyHMAC,
+		parse:           parseGitea,
+	},
+	ProviderGitLab: {
+		Name:            ProviderGitLab,
+		EventHeader:     "X-Gitlab-Event",
+		DeliveryHeader:  "X-Gitlab-Event-UUID",
+		SignatureHeader: "X-Gitlab-Token",
+		// GitLab 不对负载签名，而是原样发送配置的令牌
+		verify: func(secret, token string, body []byte) bool {
+			return subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+		},
+		parse: parseGitLab,
+	},
+}
+
+// GetProvider 获取代码托管平台的 webhook 格式
+func GetProvider(name string) (*Provider, bool) {
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+// Verify 用密钥验证请求的签名，密钥为空时拒绝所有请求
+func (p *Provider) Verify(secret string, header http.Header, body []byte) error {
+	signature := header.Get(p.SignatureHeader)
+	if secret == "" || signature == "" || !p.verify(secret, signature, body) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Parse 解析事件负载，不触发执行的事件返回 ErrIgnoredEvent
+func (p *Provider) Parse(event string, body []byte) (*Event, error) {
+	parsed, err := p.parse(event, body)
+	if err != nil {
+		return nil, err
+	}
+	parsed.Provider = p.Name
+	return parsed, nil
+}
+
+// verifyHMAC 比较十六进制编码的 HMAC-SHA256 签名
+func verifyHMAC(secret, signature string, body []byte) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(expected, mac.Sum(nil))
+}
+
+// NormalizeRepositoryURL 将仓库地址规范化为小写的 host/owner/repo，用于比较 HTTPS、SSH 等不同形式的地址。
+// 无法识别时返回空字符串
+func NormalizeRepositoryURL(repositoryURL string) string {
+	value := strings.TrimSpace(repositoryURL)
+	if value == "" {
+		return ""
+	}
+
+	var host, path string
+	switch {
+	case strings.Contains(value, "://"):
+		parsed, err := url.Parse(value)
+		if err != nil {
+			return ""
+		}
+		host, path = parsed.Hostname(), parsed.Path
+	case strings.Contains(value, ":") && !strings.Contains(strings.SplitN(value, ":", 2)[0], "/"):
+		// git@github.com:owner/repo.git
+		host, path, _ = strings.Cut(value, ":")
+		if index := strings.LastIndex(host, "@"); index >= 0 {
+			host = host[index+1:]
+		}
+	default:
+		// github.com/owner/repo
+		host, path, _ = strings.Cut(value, "/")
+	}
+
+	path = strings.Trim(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/")
+	if host == "" || !strings.Contains(path, "/") {
+		return ""
+	}
+	return strings.ToLower(host + "/" + path)
+}
+
+// MatchRepository 判断项目的仓库地址是否为事件的仓库
+func (e *Event) MatchRepository(repositoryURL string) bool {
+	normalized := NormalizeRepositoryURL(repositoryURL)
+	if normalized == "" {
+		return false
+	}
+	for _, candidate := range e.RepositoryURLs {
+		if NormalizeRepositoryURL(candidate) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// refEvent 根据推送的 ref 构造 push 或 tag 事件，删除分支或标签时返回 ErrIgnoredEvent
+func refEvent(ref, sha string) (*Event, error) {
+	if strings.Trim(sha, "0") == "" {
+		return nil, ErrIgnoredEvent
+	}
+	switch {
+	case strings.HasPrefix(ref, "refs/tags/"):
+		return &Event{Type: EventTag, Ref: ref, SHA: sha}, nil
+	case strings.HasPrefix(ref, "refs/heads/"):
+		return &Event{Type: EventPush, Ref: ref, SHA: sha}, nil
+	default:
+		return nil, fmt.Errorf("unsupported ref: %s", ref)
+	}
+}
+
+// nonEmpty 返回非空的字符串
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	tests := []struct {
+		provider  string
+		header    string
+		signature string
+		valid     bool
+	}{
+		{ProviderGitHub, "X-Hub-Signature-256", "sha256=" + sign("secret", body), true},
+		{ProviderGitHub, "X-Hub-Signature-256", sign("secret", body), false}, // 缺少 sha256= 前缀
+		{ProviderGitHub, "X-Hub-Signature-256", "sha256=" + sign("other", body), false},
+		{ProviderGitea, "X-Gitea-Signature", sign("secret", body), true},
+		{ProviderGitea, "X-Gitea-Signature", "not-hex", false},
+		{ProviderGitLab, "X-Gitlab-Token", "secret", true},
+		{ProviderGitLab, "X-Gitlab-Token", "secret2", false},
+	}
+
+	for _, test := range tests {
+		provider, _ := GetProvider(test.provider)
+		header := http.Header{}
+		header.Set(test.header, test.signature)
+		if err := provider.Verify("secret", header, body); (err == nil) != test.valid {
+			t.Errorf("%s %q: 验证结果不正确: %v", test.provider, test.signature, err)
+		}
+	}
+
+	// 没有配置密钥时拒绝所有请求
+	provider, _ := GetProvider(ProviderGitLab)
+	header := http.Header{}
+	header.Set("X-Gitlab-Token", "")
+	if err := provider.Verify("", header, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("没有密钥时应拒绝请求: %v", err)
+	}
+}
+
+func TestParseGitHub(t *testing.T) {
+	provider, _ := GetProvider(ProviderGitHub)
+
+	push := `{"ref":"refs/heads/main","before":"9f8e7d","after":"abc123","deleted":false,
+		"repository":{"full_name":"octo/app","clone_url":"https://github.com/octo/app.git","ssh_url":"git@github.com:octo/app.git"},
+		"pusher":{"name":"alice"}}`
+	event, err := provider.Parse("push", []byte(push))
+	if err != nil {
+		t.Fatalf("解析 push 失败: %v", err)
+	}
+	expected := &Event{
+		Provider: ProviderGitHub, Type: EventPush, Ref: "refs/heads/main", Before: "9f8e7d", SHA: "abc123", Author: "alice",
+		Repository: "octo/app", RepositoryURLs: []string{"https://github.com/octo/app.git", "git@github.com:octo/app.git"},
+	}
+	if !reflect.DeepEqual(event, expected) {
+		t.Errorf("push 事件不正确:\n实际 %+v\n期望 %+v", event, expected)
+	}
+
+	event, err = provider.Parse("push", []byte(`{"ref":"refs/tags/v1.0.0","after":"def456","repository":{"full_name":"octo/app"},"sender":{"login":"bob"}}`))
+	if err != nil || event.Type != EventTag || event.Author != "bob" {
+		t.Errorf("tag 事件不正确: %+v, %v", event, err)
+	}
+
+	pr := `{"action":"synchronize","number":42,"pull_request":{"head":{"ref":"feature","sha":"f00"},"base":{"ref":"main"},"user":{"login":"carol"}},
+		"repository":{"full_name":"octo/app","html_url":"https://github.com/octo/app"}}`
+	event, err = provider.Parse("pull_request", []byte(pr))
+	if err != nil {
+		t.Fatalf("解析 pull_request 失败: %v", err)
+	}
+	info := event.TriggerInfo()
+	if event.Type != EventPullRequest || info["pr_number"] != 42 || info["ref"] != "refs/heads/feature" || info["base_ref"] != "main" || info["sha"] != "f00" || info["author"] != "carol" {
+		t.Errorf("pull_request 触发信息不正确: %+v", info)
+	}
+
+	// 不触发执行的事件
+	for _, test := range []struct{ event, body string }{
+		{"ping", `{"zen":"hello"}`},
+		{"push", `{"ref":"refs/heads/old","after":"0000000000000000000000000000000000000000","deleted":true}`},
+		{"pull_request", `{"action":"closed","number":1}`},
+	} {
+		if _, err := provider.Parse(test.event, []byte(test.body)); !errors.Is(err, ErrIgnoredEvent) {
+			t.Errorf("%s 事件应被忽略: %v", test.event, err)
+		}
+	}
+}
+
+func TestParseGitLabAndGitea(t *testing.T) {
+	gitlab, _ := GetProvider(ProviderGitLab)
+
+	event, err := gitlab.Parse("Tag Push Hook", []byte(`{"object_kind":"tag_push","ref":"refs/tags/v2","after":"aaa","user_username":"dave",
+		"project":{"path_with_namespace":"group/app","git_http_url":"https://gitlab.com/group/app.git"}}`))
+	if err != nil || event.Type != EventTag || event.Author != "dave" || event.Repository != "group/app" {
+		t.Errorf("GitLab tag 事件不正确: %+v, %v", event, err)
+	}
+
+	mr := `{"object_kind":"merge_request","user":{"username":"erin"},"project":{"path_with_namespace":"group/app"},
+		"object_attributes":{"iid":7,"action":"%s","source_branch":"fix","target_branch":"main","oldrev":"%s","last_commit":{"id":"bbb"}}}`
+	event, err = gitlab.Parse("Merge Request Hook", []byte(fmt.Sprintf(mr, "update", "ccc")))
+	if err != nil || event.PRNumber != 7 || event.BaseRef != "main" || event.SHA != "bbb" {
+		t.Errorf("GitLab merge request 事件不正确: %+v, %v", event, err)
+	}
+	// 只修改标题等属性的 update 不触发执行
+	if _, err := gitlab.Parse("Merge Request Hook", []byte(fmt.Sprintf(mr, "update", ""))); !errors.Is(err, ErrIgnoredEvent) {
+		t.Errorf("没有新提交的 update 应被忽略: %v", err)
+	}
+
+	gitea, _ := GetProvider(ProviderGitea)
+	event, err = gitea.Parse("push", []byte(`{"ref":"refs/heads/dev","after":"ddd","repository":{"full_name":"team/app"},"pusher":{"username":"frank"}}`))
+	if err != nil || event.Author != "frank" || event.Provider != ProviderGitea {
+		t.Errorf("Gitea push 事件不正确: %+v, %v", event, err)
+	}
+	if _, err := gitea.Parse("pull_request", []byte(`{"action":"synchronized","number":3,"pull_request":{"head":{"ref":"x","sha":"eee"},"base":{"ref":"main"}}}`)); err != nil {
+		t.Errorf("Gitea synchronized 应触发执行: %v", err)
+	}
+}
+
+func TestMatchRepository(t *testing.T) {
+	event := &Event{RepositoryURLs: []string{"https://github.com/Octo/App.git", "git@github.com:Octo/App.git"}}
+	for _, url := range []string{
+		"https://github.com/octo/app",
+		"http://github.com/octo/app/",
+		"git@github.com:octo/app.git",
+		"ssh://git@github.com:22/octo/app.git",
+		"github.com/octo/app",
+	} {
+		if !event.MatchRepository(url) {
+			t.Errorf("%s 应匹配", url)
+		}
+	}
+	for _, url := range []string{"", "https://gitlab.com/octo/app", "https://github.com/octo/app2", "octo"} {
+		if event.MatchRepository(url) {
+			t.Errorf("%s 不应匹配", url)
+		}
+	}
+}
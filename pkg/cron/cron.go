@@ -0,0 +1,187 @@
+// Package cron 解析标准的 5 段 cron 表达式（分 时 日 月 周）并计算触发时间
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field cron 表达式中的一段
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+// fields 5 段 cron 表达式的定义，周日可以写成 0 或 7
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}},
+}
+
+// Schedule 解析后的 cron 表达式
+type Schedule struct {
+	expr    string
+	minute  uint64
+	hour    uint64
+	day     uint64
+	month   uint64
+	weekday uint64
+	// 与 Vixie cron 相同，日和周都不以 * 开头（*/n 视为不限制）时，满足任意一个即可触发
+	dayRestricted     bool
+	weekdayRestricted bool
+}
+
+// Parse 解析 cron 表达式，支持 *、列表、范围、步长以及月份和星期的英文缩写
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday), got %d", len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		value, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = value
+	}
+
+	schedule := &Schedule{
+		expr:              expr,
+		minute:            bits[0],
+		hour:              bits[1],
+		day:               bits[2],
+		month:             bits[3],
+		weekday:           bits[4],
+		dayRestricted:     !strings.HasPrefix(parts[2], "*"),
+		weekdayRestricted: !strings.HasPrefix(parts[4], "*"),
+	}
+	// 7 和 0 都表示周日
+	if schedule.weekday&(1<<7) != 0 {
+		schedule.weekday |= 1
+	}
+	return schedule, nil
+}
+
+// parseField 解析单段表达式，返回取值的位图
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(expr, ",") {
+		if item == "" {
+			return 0, fmt.Errorf("invalid %s field %q: empty list item", f.name, expr)
+		}
+
+		rangeExpr, step := item, 1
+		if index := strings.Index(item, "/"); index >= 0 {
+			rangeExpr = item[:index]
+			value, err := strconv.Atoi(item[index+1:])
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("invalid %s field %q: step must be a positive number", f.name, expr)
+			}
+			step = value
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangeExpr == "*":
+		case strings.Contains(rangeExpr, "-"):
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if start, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid %s field %q: range start is greater than end", f.name, expr)
+			}
+		default:
+			value, err := parseValue(rangeExpr, f)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			// 5/15 表示从 5 开始每 15 个单位
+			if step == 1 {
+				end = value
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// parseValue 解析单个数值或英文缩写
+func parseValue(value string, f field) (int, error) {
+	if number, ok := f.names[strings.ToUpper(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", f.name, value)
+	}
+	if number < f.min || number > f.max {
+		return 0, fmt.Errorf("%s value %d out of range %d-%d", f.name, number, f.min, f.max)
+	}
+	return number, nil
+}
+
+// String 返回原始表达式
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next 返回 t 之后（不含 t）的下一个触发时间，精确到分钟，使用 t 的时区。
+// 表达式在未来 5 年内都不会触发时（例如 2 月 30 日）返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay 判断日期是否匹配日和周，两者都有限制时满足任意一个即可
+func (s *Schedule) matchDay(t time.Time) bool {
+	day := s.day&(1<<uint(t.Day())) != 0
+	weekday := s.weekday&(1<<uint(t.Weekday())) != 0
+	if s.dayRestricted && s.weekdayRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
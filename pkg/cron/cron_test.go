@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1,,2 * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("表达式 %q 应解析失败", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC) // 周三
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"30 9 * * MON-FRI", time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 FEB *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 5", time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)}, // 日和周满足任意一个
+		{"0 0 */1 * 1", time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)}, // 以 * 开头的日不限制，需要同时满足周
+		{"0 0 1 * */3", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, // 以 * 开头的周不限制，下一个周日、周三或周六的 1 日
+		{"5/20 10 * * *", time.Date(2024, 1, 31, 10, 25, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", test.expr, err)
+		}
+		if next := schedule.Next(base); !next.Equal(test.want) {
+			t.Errorf("%q 的下一次触发时间为 %v，期望 %v", test.expr, next, test.want)
+		}
+	}
+
+	if schedule, _ := Parse("0 0 30 2 *"); !schedule.Next(base).IsZero() {
+		t.Error("不会触发的表达式应返回零值")
+	}
+}
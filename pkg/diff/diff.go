@@ -0,0 +1,172 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operation 行的变化类型
+type Operation int
+
+// 支持的变化类型
+const (
+	Equal Operation = iota
+	Delete
+	Insert
+)
+
+// Line 差异中的一行
+type Line struct {
+	Op   Operation
+	Text string
+}
+
+// contextLines unified 格式中每个变化前后保留的上下文行数
+const contextLines = 3
+
+// Lines 按最长公共子序列比较两段文本，返回逐行差异
+func Lines(a, b string) []Line {
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] 为 x[i:] 和 y[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, Line{Op: Equal, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, Line{Op: Delete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, Line{Op: Insert, Text: y[j]})
+	}
+	return lines
+}
+
+// Unified 生成 unified 格式的差异，两段文本相同时返回空字符串
+func Unified(a, b, fromName, toName string) string {
+	lines := Lines(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(lines); {
+		// 找到下一个变化
+		first := start
+		for first < len(lines) && lines[first].Op == Equal {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// 相邻变化之间的相同行不超过两倍上下文时合并为一个 hunk
+		begin := max(first-contextLines, start)
+		end := first
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == Equal {
+				next++
+			}
+			if next == len(lines) || next-end > 2*contextLines {
+				end = min(end+contextLines, len(lines))
+				break
+			}
+			end = next
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&out, lines, begin, end)
+		start = end
+	}
+	return out.String()
+}
+
+// writeHunk 输出 lines[begin:end] 组成的 hunk
+func writeHunk(out *strings.Builder, lines []Line, begin, end int) {
+	// 计算 hunk 在两段文本中的起始行号（从 1 开始）
+	fromLine, toLine := 1, 1
+	for _, line := range lines[:begin] {
+		if line.Op != Insert {
+			fromLine++
+		}
+		if line.Op != Delete {
+			toLine++
+		}
+	}
+	fromCount, toCount := 0, 0
+	for _, line := range lines[begin:end] {
+		if line.Op != Insert {
+			fromCount++
+		}
+		if line.Op != Delete {
+			toCount++
+		}
+	}
+	// 范围为空时行号指向前一行
+	if fromCount == 0 {
+		fromLine--
+	}
+	if toCount == 0 {
+		toLine--
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, line := range lines[begin:end] {
+		switch line.Op {
+		case Equal:
+			out.WriteString(" ")
+		case Delete:
+			out.WriteString("-")
+		case Insert:
+			out.WriteString("+")
+		}
+		out.WriteString(line.Text)
+		out.WriteString("\n")
+	}
+}
+
+// hunkRange 格式化 hunk 的行范围，只有一行时省略行数
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines 按行分割文本，末尾的换行符不产生空行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
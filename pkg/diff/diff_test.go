@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	lines := Lines("a\nb\nc\n", "a\nc\nd\n")
+	expected := []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "d"}}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %v, got %v", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		line := "line " + string(rune('a'+i-1))
+		a = append(a, line)
+		switch i {
+		case 2:
+			b = append(b, "changed b")
+		case 18:
+			// 删除第 18 行
+		default:
+			b = append(b, line)
+		}
+	}
+	b = append(b, "appended")
+
+	expected := `--- v1
++++ v2
+@@ -1,5 +1,5 @@
+ line a
+-line b
++changed b
+ line c
+ line d
+ line e
+@@ -15,6 +15,6 @@
+ line o
+ line p
+ line q
+-line r
+ line s
+ line t
++appended
+`
+	got := Unified(strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n", "v1", "v2")
+	if got != expected {
+		t.Errorf("unexpected diff:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	if got := Unified("same\n", "same\n", "v1", "v2"); got != "" {
+		t.Errorf("expected empty diff, got %q", got)
+	}
+	if got := Unified("", "new\n", "v1", "v2"); got != "--- v1\n+++ v2\n@@ -0,0 +1 @@\n+new\n" {
+		t.Errorf("unexpected diff for new file: %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package migration
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -28,6 +29,11 @@ func Run(db *sql.DB) error {
 		}
 	}
 
+	// 升级旧版表结构
+	if err := upgradeLegacySchema(db); err != nil {
+		return err
+	}
+
 	// 分割 SQL 语句
 	statements := strings.Split(string(schema), ";")
 
@@ -44,6 +50,299 @@ func Run(db *sql.DB) error {
 		}
 	}
 
+	// 为已存在的表补充新增列
+	if err := ensureColumns(db); err != nil {
+		return err
+	}
+
+	// 为新增列创建索引
+	if err := ensureIndexes(db); err != nil {
+		return err
+	}
+
+	// 限制每个项目每个平台只有一个管道配置
+	if err := ensurePipelinePlatformIndex(db); err != nil {
+		return err
+	}
+
+	// 为没有版本记录的模板补充初始版本
+	if err := backfillTemplateVersions(db); err != nil {
+		return err
+	}
+
+	// 为没有修订记录的管道配置补充初始修订
+	if err := backfillPipelineRevisions(db); err != nil {
+		return err
+	}
+
 	log.Println("数据库迁移成功")
 	return nil
 }
+
+// column 表的新增列定义
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addedColumns 表创建后新增的列，CREATE TABLE IF NOT EXISTS 不会为已存在的表添加这些列
+var addedColumns = []column{
+	{table: "executions", name: "jobs", definition: "TEXT"},
+	{table: "templates", name: "parameters", definition: "TEXT"},
+	{table: "templates", name: "version", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "templates", name: "name", definition: "TEXT"},
+	{table: "templates", name: "kind", definition: "TEXT NOT NULL DEFAULT 'pipeline'"},
+	{table: "templates", name: "extends", definition: "TEXT"},
+	{table: "template_versions", name: "extends", definition: "TEXT"},
+	{table: "pipelines", name: "template_id", definition: "INTEGER"},
+	{table: "pipelines", name: "template_version", definition: "INTEGER"},
+	{table: "pipelines", name: "revision", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "executions", name: "pipeline_revision", definition: "INTEGER"},
+	{table: "pipelines", name: "filename", definition: "TEXT"},
+	{table: "pipelines", name: "drift_status", definition: "TEXT"},
+	{table: "pipelines", name: "drift_checked_at", definition: "TIMESTAMP"},
+	{table: "executions", name: "priority", definition: "TEXT"},
+	{table: "executions", name: "reason", definition: "TEXT"},
+	{table: "executions", name: "run_id", definition: "TEXT"},
+	{table: "executions", name: "attempt", definition: "INTEGER"},
+	{table: "executions", name: "rerun_of", definition: "TEXT"},
+	{table: "executions", name: "debug", definition: "BOOLEAN DEFAULT 0"},
+	{table: "executions", name: "options", definition: "TEXT"},
+}
+
+// ensureColumns 为已存在的表添加缺失的列
+func ensureColumns(db *sql.DB) error {
+	for _, c := range addedColumns {
+		columnType, err := columnType(db, c.table, c.name)
+		if err != nil {
+			return err
+		}
+		if columnType != "" {
+			continue
+		}
+
+		if _, err := db.Exec("ALTER TABLE " + c.table + " ADD COLUMN " + c.name + " " + c.definition); err != nil {
+			return err
+		}
+		log.Printf("已为 %s 表添加 %s 列", c.table, c.name)
+	}
+
+	return nil
+}
+
+// addedIndexes 新增列上的索引，需要在补充列之后创建，因此不能放在 schema.sql 中
+var addedIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_pipelines_template_id ON pipelines(template_id)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_templates_name ON templates(name)",
+	"CREATE INDEX IF NOT EXISTS idx_executions_pipeline_revision ON executions(pipeline_id, pipeline_revision)",
+	"CREATE INDEX IF NOT EXISTS idx_executions_run_id ON executions(run_id)",
+}
+
+// ensureIndexes 创建新增列上的索引
+func ensureIndexes(db *sql.DB) error {
+	for _, stmt := range addedIndexes {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensurePipelinePlatformIndex 在 pipelines(project_id, platform) 上创建唯一索引。
+// 已有重复的管道配置时不删除数据，只记录日志并跳过，查询时使用最后创建的配置，新建时由仓库拒绝重复
+func ensurePipelinePlatformIndex(db *sql.DB) error {
+	var duplicates int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT 1 FROM pipelines GROUP BY project_id, platform HAVING COUNT(*) > 1
+		)
+	`).Scan(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		log.Printf("有 %d 组项目和平台相同的管道配置，跳过创建唯一索引，删除重复配置后重启服务即可创建", duplicates)
+		return nil
+	}
+
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_pipelines_project_platform ON pipelines(project_id, platform)")
+	return err
+}
+
+// backfillTemplateVersions 将没有版本记录的模板（版本管理之前创建的模板）的当前内容保存为其当前版本
+func backfillTemplateVersions(db *sql.DB) error {
+	result, err := db.Exec(`
+		INSERT INTO template_versions (template_id, version, extends, platform, language, framework, content, filename, config_type, parameters, author, note, created_at)
+		SELECT id, version, extends, platform, language, framework, content, filename, config_type, parameters, 'system', 'initial version', updated_at
+		FROM templates t
+		WHERE NOT EXISTS (SELECT 1 FROM template_versions v WHERE v.template_id = t.id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if count, err := result.RowsAffected(); err == nil && count > 0 {
+		log.Printf("已为 %d 个模板补充初始版本", count)
+	}
+	return nil
+}
+
+// backfillPipelineRevisions 将没有修订记录的管道配置（修订历史之前保存的配置）的当前内容保存为其当前修订
+func backfillPipelineRevisions(db *sql.DB) error {
+	result, err := db.Exec(`
+		INSERT INTO pipeline_revisions (pipeline_id, revision, config, template_id, template_version, source, reason, author, created_at)
+		SELECT id, revision, config, template_id, template_version,
+			CASE WHEN template_id IS NULL THEN 'manual' ELSE 'generated' END, 'initial revision', 'system', updated_at
+		FROM pipelines p
+		WHERE NOT EXISTS (SELECT 1 FROM pipeline_revisions r WHERE r.pipeline_id = p.id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if count, err := result.RowsAffected(); err == nil && count > 0 {
+		log.Printf("已为 %d 个管道配置补充初始修订", count)
+	}
+	return nil
+}
+
+// legacyUpgradeStatements 将旧版 executions/metrics 表迁移为以文本 UUID 为主键的表结构。
+// 先建新表并复制数据，删除旧表后再重命名新表，metrics 新表的外键随重命名指向新的 executions 表；
+// 旧版保存在 logs 列中的日志整体作为一条执行日志保留，为空的开始和结束时间以创建时间填充
+var legacyUpgradeStatements = []string{
+	`CREATE TABLE executions_new (
+		id TEXT PRIMARY KEY,
+		project_id INTEGER NOT NULL,
+		pipeline_id INTEGER,
+		pipeline_revision INTEGER,
+		platform TEXT NOT NULL,
+		status TEXT NOT NULL,
+		priority TEXT,
+		reason TEXT,
+		run_id TEXT,
+		attempt INTEGER,
+		rerun_of TEXT,
+		debug BOOLEAN DEFAULT 0,
+		options TEXT,
+		trigger_type TEXT,
+		trigger_info TEXT,
+		platform_data TEXT,
+		jobs TEXT,
+		start_time TIMESTAMP,
+		end_time TIMESTAMP,
+		duration INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+	)`,
+	`INSERT INTO executions_new (id, project_id, pipeline_id, platform, status, run_id, attempt, trigger_type, start_time, end_time, duration, created_at, updated_at)
+		SELECT CAST(e.id AS TEXT), e.project_id, e.pipeline_id,
+			COALESCE((SELECT p.platform FROM pipelines p WHERE p.id = e.pipeline_id), 'mock'),
+			e.status, CAST(e.id AS TEXT), 1, 'manual', COALESCE(e.start_time, e.created_at),
+			COALESCE(e.end_time, e.start_time, e.created_at), e.duration, e.created_at, e.created_at
+		FROM executions e`,
+	`CREATE TABLE metrics_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		execution_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		value REAL NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (execution_id) REFERENCES executions_new(id) ON DELETE CASCADE
+	)`,
+	`INSERT INTO metrics_new (id, execution_id, name, value, created_at)
+		SELECT id, CAST(execution_id AS TEXT), name, value, created_at FROM metrics`,
+	`CREATE TABLE execution_logs_new (
+		id TEXT PRIMARY KEY,
+		execution_id TEXT NOT NULL,
+		timestamp TIMESTAMP NOT NULL,
+		level TEXT NOT NULL,
+		stage TEXT,
+		step TEXT,
+		message TEXT NOT NULL,
+		context TEXT,
+		FOREIGN KEY (execution_id) REFERENCES executions_new(id) ON DELETE CASCADE
+	)`,
+	`INSERT INTO execution_logs_new (id, execution_id, timestamp, level, stage, message)
+		SELECT lower(hex(randomblob(16))), CAST(id AS TEXT), COALESCE(end_time, start_time, created_at), 'info', 'legacy', logs
+		FROM executions WHERE logs IS NOT NULL AND logs != ''`,
+	`DROP TABLE metrics`,
+	`DROP TABLE executions`,
+	`ALTER TABLE executions_new RENAME TO executions`,
+	`ALTER TABLE metrics_new RENAME TO metrics`,
+	`ALTER TABLE execution_logs_new RENAME TO execution_logs`,
+}
+
+// upgradeLegacySchema 升级旧版表结构
+// 旧版 executions 表使用自增整数主键，无法保存 UUID 执行 ID。在一个事务中将旧表迁移为新表结构，
+// 执行 ID 转为文本保留，关联的指标和日志一并迁移
+func upgradeLegacySchema(db *sql.DB) error {
+	idType, err := columnType(db, "executions", "id")
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(idType, "INTEGER") {
+		return nil
+	}
+
+	// 旧版数据库中没有 execution_logs 表，已存在时无法迁移旧版日志
+	logsType, err := columnType(db, "execution_logs", "id")
+	if err != nil {
+		return err
+	}
+	if logsType != "" {
+		return fmt.Errorf("cannot upgrade legacy executions table: execution_logs table already exists")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range legacyUpgradeStatements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to upgrade legacy executions table: %w", err)
+		}
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM executions").Scan(&count); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("已将旧版 executions/metrics 表迁移为新表结构，保留 %d 条执行记录", count)
+	return nil
+}
+
+// columnType 获取表中指定列的类型，表或列不存在时返回空字符串
+func columnType(db *sql.DB, table, column string) (string, error) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name         string
+			columnType   string
+			notNull      int
+			defaultValue sql.NullString
+			primaryKey   int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &primaryKey); err != nil {
+			return "", err
+		}
+		if name == column {
+			return columnType, nil
+		}
+	}
+
+	return "", rows.Err()
+}
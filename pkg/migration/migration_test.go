@@ -0,0 +1,175 @@
+package migration
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"ci-cd-orchestrator/internal/repository"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// legacySchema 旧版使用自增整数执行 ID 的表结构
+const legacySchema = `
+CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    path TEXT NOT NULL,
+    description TEXT,
+    repository_url TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE pipelines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL,
+    platform TEXT NOT NULL,
+    config TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+CREATE TABLE executions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL,
+    pipeline_id INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    start_time TIMESTAMP,
+    end_time TIMESTAMP,
+    duration INTEGER,
+    logs TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines(id) ON DELETE CASCADE
+);
+CREATE TABLE metrics (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    execution_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    value REAL NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_executions_project_id ON executions(project_id);
+CREATE INDEX idx_metrics_execution_id ON metrics(execution_id);
+INSERT INTO projects (id, name, path) VALUES (1, 'app', '/tmp/app');
+INSERT INTO pipelines (id, project_id, platform, config) VALUES (1, 1, 'github_actions', 'jobs: {}');
+INSERT INTO executions (id, project_id, pipeline_id, status, start_time, end_time, duration, logs)
+    VALUES (7, 1, 1, 'success', '2024-01-01 10:00:00', '2024-01-01 10:02:00', 120, 'build ok');
+INSERT INTO executions (id, project_id, pipeline_id, status) VALUES (8, 1, 1, 'failed');
+INSERT INTO metrics (execution_id, name, value) VALUES (7, 'duration', 120);
+INSERT INTO metrics (execution_id, name, value) VALUES (8, 'duration', 30);
+`
+
+func TestRunUpgradesLegacySchema(t *testing.T) {
+	// 开启外键约束，确认删除旧表时不会级联删除已迁移的数据
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "legacy.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(legacySchema); err != nil {
+		t.Fatalf("创建旧版表结构失败: %v", err)
+	}
+
+	if err := Run(db); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	if idType, err := columnType(db, "executions", "id"); err != nil || idType != "TEXT" {
+		t.Fatalf("executions.id 应迁移为 TEXT: %q %v", idType, err)
+	}
+
+	repo := repository.NewExecutionRepository(db)
+	execution, err := repo.GetByID("7")
+	if err != nil {
+		t.Fatalf("迁移后获取旧版执行失败: %v", err)
+	}
+	if execution.Status != "success" || execution.Duration != 120 || execution.Platform != "github_actions" {
+		t.Errorf("旧版执行记录不匹配: %+v", execution)
+	}
+	if executions, err := repo.GetByProjectID(1); err != nil || len(executions) != 2 {
+		t.Errorf("旧版执行记录应全部保留: %d %v", len(executions), err)
+	}
+
+	logs, err := repo.GetLogs("7")
+	if err != nil || len(logs) != 1 || logs[0].Message != "build ok" {
+		t.Errorf("旧版日志应保留为执行日志: %v %v", logs, err)
+	}
+
+	var metrics int
+	if err := db.QueryRow("SELECT COUNT(*) FROM metrics WHERE execution_id IN ('7', '8')").Scan(&metrics); err != nil || metrics != 2 {
+		t.Errorf("旧版指标应保留: %d %v", metrics, err)
+	}
+
+	// 新表结构上再次迁移不改变数据
+	if err := Run(db); err != nil {
+		t.Fatalf("再次迁移失败: %v", err)
+	}
+	if executions, err := repo.GetByProjectID(1); err != nil || len(executions) != 2 {
+		t.Errorf("再次迁移后执行记录不匹配: %d %v", len(executions), err)
+	}
+}
+
+func TestRunPipelinePlatformIndex(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "cicd.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := Run(db); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	// 模拟唯一索引之前遗留的重复配置
+	if _, err := db.Exec("DROP INDEX idx_pipelines_project_platform"); err != nil {
+		t.Fatalf("删除唯一索引失败: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO projects (id, name, path) VALUES (1, 'app', '/tmp/app');
+		INSERT INTO pipelines (id, project_id, platform, config) VALUES (1, 1, 'github_actions', 'old');
+		INSERT INTO pipelines (id, project_id, platform, config) VALUES (2, 1, 'github_actions', 'new');
+	`); err != nil {
+		t.Fatalf("创建重复的管道配置失败: %v", err)
+	}
+
+	// 有重复配置时迁移成功但不创建索引，不删除数据
+	if err := Run(db); err != nil {
+		t.Fatalf("有重复配置时迁移失败: %v", err)
+	}
+	if indexExists(t, db, "idx_pipelines_project_platform") {
+		t.Error("有重复配置时不应创建唯一索引")
+	}
+	pipeline, err := repository.NewPipelineRepository(db).GetByProjectAndPlatform(1, "github_actions")
+	if err != nil || pipeline.ID != 2 {
+		t.Errorf("重复配置中应取最后创建的配置: %+v %v", pipeline, err)
+	}
+
+	// 删除重复配置后再次迁移创建索引
+	if _, err := db.Exec("DELETE FROM pipelines WHERE id = 1"); err != nil {
+		t.Fatalf("删除重复配置失败: %v", err)
+	}
+	if err := Run(db); err != nil {
+		t.Fatalf("再次迁移失败: %v", err)
+	}
+	if !indexExists(t, db, "idx_pipelines_project_platform") {
+		t.Error("没有重复配置时应创建唯一索引")
+	}
+	if _, err := db.Exec("INSERT INTO pipelines (project_id, platform, config) VALUES (1, 'github_actions', 'dup')"); err == nil {
+		t.Error("唯一索引应拒绝重复的管道配置")
+	}
+}
+
+// indexExists 检查索引是否存在
+func indexExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", name).Scan(&count); err != nil {
+		t.Fatalf("查询索引失败: %v", err)
+	}
+	return count > 0
+}
@@ -0,0 +1,291 @@
+// Package websocket 提供服务端推送所需的最小 WebSocket（RFC 6455）实现
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 帧操作码
+const (
+	OpText   = 0x1
+	OpBinary = 0x2
+	OpClose  = 0x8
+	OpPing   = 0x9
+	OpPong   = 0xA
+)
+
+// 关闭状态码
+const (
+	CloseNormal    = 1000
+	CloseGoingAway = 1001
+)
+
+// acceptGUID 握手时用于计算 Sec-WebSocket-Accept 的固定 GUID
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxFrameSize 客户端帧的最大长度，服务端只接收控制帧和少量文本
+const maxFrameSize = 1 << 20
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn WebSocket 连接
+type Conn struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+	closed     bool
+}
+
+// IsUpgradeRequest 判断请求是否为 WebSocket 升级请求
+func IsUpgradeRequest(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// CheckOrigin 判断请求的 Origin 是否允许建立连接。没有 Origin 请求头的非浏览器客户端、与请求的 Host 相同的来源
+// 和 allowedOrigins 中的来源（例如 https://ci.example.com，不区分大小写）允许连接，阻止其他网页发起跨站 WebSocket 连接
+func CheckOrigin(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return strings.EqualFold(parsed.Host, r.Host)
+}
+
+// Upgrade 校验来源后完成 WebSocket 握手并接管底层连接，允许的来源见 CheckOrigin
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("websocket: method %s not allowed", r.Method)
+	}
+	if !IsUpgradeRequest(r) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return nil, errors.New("websocket: not a websocket upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket Version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	if !CheckOrigin(r, allowedOrigins) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket: origin %s not allowed", r.Header.Get("Origin"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: netConn, reader: rw.Reader}, nil
+}
+
+// WriteText 发送文本消息
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OpText, data)
+}
+
+// Ping 发送 ping 控制帧
+func (c *Conn) Ping() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+// ReadMessage 读取一条完整的数据消息，自动应答 ping，收到 close 帧时返回 ErrClosed
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var opcode int
+	var message []byte
+
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpClose:
+			c.writeClose(CloseNormal)
+			return 0, nil, ErrClosed
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case 0:
+			// 延续帧
+		default:
+			opcode = op
+			message = message[:0]
+		}
+
+		if len(message)+len(payload) > maxFrameSize {
+			c.writeClose(1009)
+			return 0, nil, errors.New("websocket: message too large")
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+// Close 发送 close 帧并关闭连接
+func (c *Conn) Close() error {
+	c.writeClose(CloseNormal)
+	return c.conn.Close()
+}
+
+// writeClose 发送 close 帧
+func (c *Conn) writeClose(code int) {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	c.writeFrame(OpClose, payload)
+
+	c.writeMutex.Lock()
+	c.closed = true
+	c.writeMutex.Unlock()
+}
+
+// writeFrame 写入单个未掩码的帧，服务端发送的帧不需要掩码
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	header := []byte{0x80 | byte(opcode)}
+	length := len(payload)
+	switch {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame 读取单个客户端帧并去除掩码
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := int(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > maxFrameSize {
+		return false, 0, nil, errors.New("websocket: frame too large")
+	}
+	// 客户端发送的帧必须带掩码
+	if !masked {
+		return false, 0, nil, errors.New("websocket: client frame is not masked")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// acceptKey 根据客户端 key 计算 Sec-WebSocket-Accept
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// headerContains 判断逗号分隔的请求头中是否包含指定值（不区分大小写）
+func headerContains(header http.Header, name, value string) bool {
+	for _, field := range header.Values(name) {
+		for _, part := range strings.Split(field, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
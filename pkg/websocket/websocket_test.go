@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 第 1.3 节的示例
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept 不匹配: %s", got)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{name: "无 Origin 的非浏览器客户端", origin: "", want: true},
+		{name: "同源", origin: "http://ci.example.com:8080", want: true},
+		{name: "同源不区分大小写", origin: "http://CI.example.com:8080", want: true},
+		{name: "跨站", origin: "https://evil.example.net", want: false},
+		{name: "端口不同", origin: "http://ci.example.com:9090", want: false},
+		{name: "允许的来源", origin: "https://dashboard.example.com", allowed: []string{"https://dashboard.example.com/"}, want: true},
+		{name: "无效的 Origin", origin: "null", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://ci.example.com:8080/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := CheckOrigin(r, tt.allowed); got != tt.want {
+				t.Errorf("CheckOrigin(%q) = %v, 期望 %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+// testClient 测试用的 WebSocket 客户端，发送带掩码的帧
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dial 连接测试服务器并完成握手，返回握手响应
+func dial(t *testing.T, server *httptest.Server, origin string) (*testClient, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET /ws HTTP/1.1\r\n" +
+		"Host: " + strings.TrimPrefix(server.URL, "http://") + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	if origin != "" {
+		request += "Origin: " + origin + "\r\n"
+	}
+	if _, err := conn.Write([]byte(request + "\r\n")); err != nil {
+		t.Fatalf("发送握手请求失败: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("读取握手响应失败: %v", err)
+	}
+	return &testClient{conn: conn, reader: reader}, resp
+}
+
+// writeFrame 发送带掩码的帧
+func (c *testClient) writeFrame(t *testing.T, fin bool, opcode int, payload []byte) {
+	t.Helper()
+
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("发送帧失败: %v", err)
+	}
+}
+
+// readFrame 读取服务端发送的帧，服务端帧不带掩码
+func (c *testClient) readFrame(t *testing.T) (int, []byte) {
+	t.Helper()
+
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		t.Fatalf("读取帧失败: %v", err)
+	}
+	if head[0]&0x80 == 0 {
+		t.Fatal("服务端帧应设置 FIN")
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("服务端帧不应带掩码")
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.reader, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("读取帧内容失败: %v", err)
+	}
+	return int(head[0] & 0x0F), payload
+}
+
+// newEchoServer 创建将收到的文本消息原样发回的测试服务器，连接结束时将 ReadMessage 的错误发送到 done
+func newEchoServer(t *testing.T, allowedOrigins []string) (*httptest.Server, chan error) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, allowedOrigins)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				done <- err
+				return
+			}
+			if err := conn.WriteText(message); err != nil {
+				done <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, done
+}
+
+func TestConn(t *testing.T) {
+	server, done := newEchoServer(t, nil)
+	client, resp := dial(t, server, server.URL)
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("握手状态码不匹配: %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept 不匹配: %s", accept)
+	}
+
+	// 带掩码的单帧消息
+	client.writeFrame(t, true, OpText, []byte("hello"))
+	if opcode, payload := client.readFrame(t); opcode != OpText || string(payload) != "hello" {
+		t.Errorf("回显的消息不匹配: %d %q", opcode, payload)
+	}
+
+	// 分片消息之间插入 ping，服务端先应答 pong，再合并分片
+	client.writeFrame(t, false, OpText, []byte("frag"))
+	client.writeFrame(t, true, OpPing, []byte("beat"))
+	client.writeFrame(t, false, 0, []byte("men"))
+	client.writeFrame(t, true, 0, []byte("ted"))
+	if opcode, payload := client.readFrame(t); opcode != OpPong || string(payload) != "beat" {
+		t.Errorf("ping 应答不匹配: %d %q", opcode, payload)
+	}
+	if opcode, payload := client.readFrame(t); opcode != OpText || string(payload) != "fragmented" {
+		t.Errorf("分片消息不匹配: %d %q", opcode, payload)
+	}
+
+	// 超过 125 字节的消息使用扩展长度
+	long := strings.Repeat("x", 300)
+	client.writeFrame(t, true, OpText, []byte(long))
+	if _, payload := client.readFrame(t); string(payload) != long {
+		t.Errorf("长消息不匹配: %d 字节", len(payload))
+	}
+
+	// close 帧得到 close 应答，服务端的 ReadMessage 返回 ErrClosed
+	client.writeFrame(t, true, OpClose, []byte{0x03, 0xE8})
+	opcode, payload := client.readFrame(t)
+	if opcode != OpClose || binary.BigEndian.Uint16(payload) != CloseNormal {
+		t.Errorf("close 应答不匹配: %d %v", opcode, payload)
+	}
+	if err := <-done; !errors.Is(err, ErrClosed) {
+		t.Errorf("收到 close 帧后应返回 ErrClosed: %v", err)
+	}
+}
+
+func TestConnRejectsUnmaskedFrame(t *testing.T) {
+	server, done := newEchoServer(t, nil)
+	client, _ := dial(t, server, "")
+
+	if _, err := client.conn.Write([]byte{0x81, 0x02, 'h', 'i'}); err != nil {
+		t.Fatalf("发送帧失败: %v", err)
+	}
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "not masked") {
+		t.Errorf("未掩码的客户端帧应被拒绝: %v", err)
+	}
+}
+
+func TestUpgradeRejectsCrossSiteOrigin(t *testing.T) {
+	server, done := newEchoServer(t, []string{"https://dashboard.example.com"})
+
+	_, resp := dial(t, server, "https://evil.example.net")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("跨站来源应被拒绝: %d", resp.StatusCode)
+	}
+	if err := <-done; err == nil {
+		t.Error("跨站来源的握手应返回错误")
+	}
+
+	_, resp = dial(t, server, "https://dashboard.example.com")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("允许的来源应完成握手: %d", resp.StatusCode)
+	}
+}
@@ -30,30 +30,107 @@ CREATE TABLE IF NOT EXISTS pipelines (
     project_id INTEGER NOT NULL,
     platform TEXT NOT NULL, -- GitHub Actions 或 Mock
     config TEXT NOT NULL, -- YAML 格式存储配置
+    template_id INTEGER, -- 生成配置使用的模板
+    template_version INTEGER, -- 生成配置使用的模板版本
+    revision INTEGER NOT NULL DEFAULT 1, -- 当前修订版本号
+    filename TEXT, -- 项目目录中配置文件的相对路径
+    drift_status TEXT, -- 与项目目录中配置文件的最近一次比较结果：in_sync、drifted、missing 或 unknown
+    drift_checked_at TIMESTAMP, -- 最近一次比较的时间
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
 );
 
+-- 管道配置修订历史表，每次修改保存一个不可修改的修订版本
+CREATE TABLE IF NOT EXISTS pipeline_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pipeline_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    config TEXT NOT NULL,
+    template_id INTEGER,
+    template_version INTEGER,
+    source TEXT NOT NULL, -- generated, manual, optimization, rollback
+    reason TEXT,
+    author TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pipeline_id, revision),
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines(id) ON DELETE CASCADE
+);
+
 -- 执行历史表
 CREATE TABLE IF NOT EXISTS executions (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT PRIMARY KEY, -- UUID
     project_id INTEGER NOT NULL,
-    pipeline_id INTEGER NOT NULL,
-    status TEXT NOT NULL, -- pending, running, success, failed
+    pipeline_id INTEGER, -- 关联的管道配置（可为空）
+    pipeline_revision INTEGER, -- 执行时管道配置的修订版本
+    platform TEXT NOT NULL,
+    status TEXT NOT NULL, -- pending, queued, running, success, failed, cancelled
+    priority TEXT, -- 排队的优先级类别
+    reason TEXT, -- 执行被取消或启动失败的原因
+    run_id TEXT, -- 逻辑运行 ID，即第一次尝试的执行 ID
+    attempt INTEGER, -- 在逻辑运行中的尝试序号
+    rerun_of TEXT, -- 重新运行的执行 ID
+    debug BOOLEAN DEFAULT 0, -- 是否输出调试日志
+    options TEXT, -- JSON 格式存储创建执行时的选项
+    trigger_type TEXT,
+    trigger_info TEXT, -- JSON 格式存储触发信息
+    platform_data TEXT, -- JSON 格式存储平台数据
+    jobs TEXT, -- JSON 格式存储各 job 的状态和耗时
     start_time TIMESTAMP,
     end_time TIMESTAMP,
     duration INTEGER, -- 执行时长（秒）
-    logs TEXT, -- 执行日志
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
-    FOREIGN KEY (pipeline_id) REFERENCES pipelines(id) ON DELETE CASCADE
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- 执行日志表
+CREATE TABLE IF NOT EXISTS execution_logs (
+    id TEXT PRIMARY KEY, -- UUID
+    execution_id TEXT NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    level TEXT NOT NULL,
+    stage TEXT,
+    step TEXT,
+    message TEXT NOT NULL,
+    context TEXT, -- JSON 格式存储上下文
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+-- 执行状态变化表，记录每次状态变化的时间和发起者
+CREATE TABLE IF NOT EXISTS execution_transitions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    execution_id TEXT NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL, -- user、queue、engine 或 system
+    reason TEXT,
+    timestamp TIMESTAMP NOT NULL,
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+-- 执行阶段耗时表
+CREATE TABLE IF NOT EXISTS execution_stages (
+    execution_id TEXT NOT NULL,
+    stage TEXT NOT NULL,
+    duration INTEGER NOT NULL, -- 阶段耗时（秒）
+    PRIMARY KEY (execution_id, stage),
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+-- 执行队列表，保存等待启动的执行，服务重启后按入队顺序恢复
+CREATE TABLE IF NOT EXISTS execution_queue (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- 入队顺序
+    execution_id TEXT NOT NULL UNIQUE,
+    options TEXT NOT NULL, -- JSON 格式存储启动执行的选项
+    enqueued_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
 );
 
 -- 指标表
 CREATE TABLE IF NOT EXISTS metrics (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    execution_id INTEGER NOT NULL,
+    execution_id TEXT NOT NULL,
     name TEXT NOT NULL,
     value REAL NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -75,23 +152,122 @@ CREATE TABLE IF NOT EXISTS optimizations (
 -- 模板表
 CREATE TABLE IF NOT EXISTS templates (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT, -- 唯一名称，被其他模板继承或引用时使用
+    kind TEXT NOT NULL DEFAULT 'pipeline', -- pipeline 或 fragment
+    extends TEXT, -- 继承的基础模板名称
     platform TEXT NOT NULL,
     language TEXT,
     framework TEXT,
     content TEXT NOT NULL,
     filename TEXT NOT NULL,
     config_type TEXT NOT NULL,
+    parameters TEXT, -- JSON 格式存储模板参数定义
+    version INTEGER NOT NULL DEFAULT 1, -- 当前版本号
     is_builtin BOOLEAN DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 模板版本表，每次修改模板都保存一个不可变的版本
+CREATE TABLE IF NOT EXISTS template_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    extends TEXT,
+    platform TEXT NOT NULL,
+    language TEXT,
+    framework TEXT,
+    content TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    config_type TEXT NOT NULL,
+    parameters TEXT,
+    author TEXT,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (template_id, version),
+    FOREIGN KEY (template_id) REFERENCES templates(id) ON DELETE CASCADE
+);
+
+-- 安全检查结果表
+CREATE TABLE IF NOT EXISTS security_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL,
+    pipeline_id INTEGER NOT NULL,
+    rule TEXT NOT NULL,
+    severity TEXT NOT NULL, -- error 或 warning
+    job TEXT,
+    line INTEGER,
+    "column" INTEGER,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines(id) ON DELETE CASCADE
+);
+
+-- 安全检查规则开关表（没有记录的规则默认启用）
+CREATE TABLE IF NOT EXISTS security_rule_settings (
+    project_id INTEGER NOT NULL,
+    rule TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, rule),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- webhook 投递表，按代码托管平台和投递 ID 去重
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    provider TEXT NOT NULL, -- github, gitlab, gitea
+    delivery_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL, -- processed, ignored, unmatched, failed
+    message TEXT,
+    execution_ids TEXT, -- JSON 格式存储触发的执行
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    redeliveries INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, delivery_id)
+);
+
+-- 定时任务表，来自管道配置 on.schedule 的定时任务按管道配置和 cron 表达式去重
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL,
+    pipeline_id INTEGER,
+    source TEXT NOT NULL, -- pipeline 或 api
+    name TEXT NOT NULL,
+    cron TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    platform TEXT NOT NULL,
+    ref TEXT,
+    catch_up TEXT NOT NULL DEFAULT 'latest', -- skip, latest, all
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    last_execution_id TEXT,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pipeline_id, cron),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines(id) ON DELETE CASCADE
+);
+
 -- 创建索引
 CREATE INDEX IF NOT EXISTS idx_tech_stacks_project_id ON tech_stacks(project_id);
 CREATE INDEX IF NOT EXISTS idx_pipelines_project_id ON pipelines(project_id);
 CREATE INDEX IF NOT EXISTS idx_executions_project_id ON executions(project_id);
 CREATE INDEX IF NOT EXISTS idx_executions_pipeline_id ON executions(pipeline_id);
+CREATE INDEX IF NOT EXISTS idx_executions_status ON executions(status);
+CREATE INDEX IF NOT EXISTS idx_execution_logs_execution_id ON execution_logs(execution_id);
+CREATE INDEX IF NOT EXISTS idx_execution_transitions_execution_id ON execution_transitions(execution_id);
 CREATE INDEX IF NOT EXISTS idx_metrics_execution_id ON metrics(execution_id);
 CREATE INDEX IF NOT EXISTS idx_optimizations_project_id ON optimizations(project_id);
 CREATE INDEX IF NOT EXISTS idx_templates_platform ON templates(platform);
 CREATE INDEX IF NOT EXISTS idx_templates_language ON templates(language);
+CREATE INDEX IF NOT EXISTS idx_security_findings_project_id ON security_findings(project_id);
+CREATE INDEX IF NOT EXISTS idx_security_findings_pipeline_id ON security_findings(pipeline_id);
+CREATE INDEX IF NOT EXISTS idx_schedules_project_id ON schedules(project_id);